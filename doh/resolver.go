@@ -0,0 +1,301 @@
+// Package doh resolves host names with DNS over HTTPS, for networks whose resolver blocks
+// or poisons the names being requested. A Resolver plugs into an http.Transport through its
+// DialContext, which is how the WithResolver options of the search and link_preview
+// packages use it, so proxies still apply on top of it.
+package doh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// CloudflareURL is the JSON API of the resolver of Cloudflare. It is given by address so
+	// that reaching it does not depend on the local resolver.
+	CloudflareURL = "https://1.1.1.1/dns-query"
+
+	// GoogleURL is the JSON API of Google Public DNS, given by address like CloudflareURL.
+	GoogleURL = "https://8.8.8.8/resolve"
+
+	// DefaultTimeout bounds a request to the DoH provider.
+	DefaultTimeout = 5 * time.Second
+)
+
+// Types of the DNS records looked up, and the statuses of the answers.
+const (
+	typeA    = 1
+	typeAAAA = 28
+
+	statusNoError  = 0
+	statusNXDomain = 3
+)
+
+// Resolver looks up host names with the JSON API of a DoH provider, such as CloudflareURL
+// or GoogleURL, and caches the answers for as long as their TTL. Names the provider fails
+// to resolve, because it cannot be reached or answers with an error, are looked up with
+// Fallback instead. A name the provider says does not exist is not.
+//
+// The fields must be set before the resolver is used. A Resolver is safe for concurrent use.
+type Resolver struct {
+	// URL is the JSON API of the DoH provider, queried with the name and type parameters.
+	// Default: CloudflareURL.
+	URL string
+
+	// Client makes the requests to the provider. Default: a client with DefaultTimeout.
+	Client *http.Client
+
+	// Fallback looks up the names the provider fails to resolve.
+	// Default: net.DefaultResolver.
+	Fallback *net.Resolver
+
+	// Dialer connects to the addresses resolved by DialContext. Default: a net.Dialer with
+	// the timeouts of http.DefaultTransport.
+	Dialer *net.Dialer
+
+	mu    sync.Mutex
+	cache map[string]cached
+	now   func() time.Time
+}
+
+type cached struct {
+	addrs   []string
+	expires time.Time
+}
+
+// NewResolver returns a Resolver querying the JSON API at providerURL, such as
+// CloudflareURL or GoogleURL.
+func NewResolver(providerURL string) (*Resolver, error) {
+	u, err := url.Parse(providerURL)
+	if err != nil {
+		return nil, fmt.Errorf("doh: provider url: %w", err)
+	}
+	if u.Scheme != "https" && u.Scheme != "http" || u.Host == "" {
+		return nil, fmt.Errorf("doh: provider url %q is not an HTTP URL", providerURL)
+	}
+	return &Resolver{URL: providerURL}, nil
+}
+
+// DialContext connects to address on the named network like net.Dialer.DialContext, with
+// the host of address looked up by LookupHost. The addresses are tried in turn until one
+// connects. It can be set as the DialContext of an http.Transport.
+func (r *Resolver) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := r.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var firstErr error
+	for _, addr := range addrs {
+		ip := net.ParseIP(addr)
+		if ip == nil || strings.HasSuffix(network, "4") && ip.To4() == nil || strings.HasSuffix(network, "6") && ip.To4() != nil {
+			continue
+		}
+		conn, err := r.dialer().DialContext(ctx, network, net.JoinHostPort(addr, port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if firstErr == nil {
+		firstErr = &net.DNSError{Err: "no suitable address found", Name: host}
+	}
+	return nil, firstErr
+}
+
+// LookupHost returns the IPv4 and IPv6 addresses of host, from the cache while their TTL
+// lasts. An IP address is returned as is.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+	name := strings.ToLower(strings.TrimSuffix(host, "."))
+
+	r.mu.Lock()
+	entry, ok := r.cache[name]
+	r.mu.Unlock()
+	if ok && r.clock().Before(entry.expires) {
+		return append([]string(nil), entry.addrs...), nil
+	}
+
+	addrs, ttl, err := r.query(ctx, name)
+	if errors.Is(err, errNotFound) {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return r.fallback().LookupHost(ctx, host)
+	}
+
+	r.mu.Lock()
+	if r.cache == nil {
+		r.cache = map[string]cached{}
+	}
+	r.cache[name] = cached{addrs: addrs, expires: r.clock().Add(ttl)}
+	r.mu.Unlock()
+	return append([]string(nil), addrs...), nil
+}
+
+// errNotFound is returned by query when the provider says the name does not exist.
+var errNotFound = errors.New("doh: no such host")
+
+// query looks up the A and AAAA records of name at once. It returns their addresses and
+// the shortest TTL of the answers.
+func (r *Resolver) query(ctx context.Context, name string) ([]string, time.Duration, error) {
+	type answer struct {
+		addrs []string
+		ttl   time.Duration
+		err   error
+	}
+	answers := make([]answer, 2)
+	var wg sync.WaitGroup
+	for i, recordType := range []int{typeA, typeAAAA} {
+		i, recordType := i, recordType
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a := &answers[i]
+			a.addrs, a.ttl, a.err = r.queryType(ctx, name, recordType)
+		}()
+	}
+	wg.Wait()
+
+	var addrs []string
+	var ttl time.Duration
+	for _, a := range answers {
+		if a.err != nil {
+			continue
+		}
+		addrs = append(addrs, a.addrs...)
+		if len(a.addrs) > 0 && (ttl == 0 || a.ttl < ttl) {
+			ttl = a.ttl
+		}
+	}
+	switch {
+	case len(addrs) > 0:
+		return addrs, ttl, nil
+	case answers[0].err != nil:
+		return nil, 0, answers[0].err
+	case answers[1].err != nil:
+		return nil, 0, answers[1].err
+	}
+	return nil, 0, errNotFound
+}
+
+// response is an answer of the JSON API of a DoH provider.
+type response struct {
+	Status int `json:"Status"`
+	Answer []struct {
+		Type int    `json:"type"`
+		TTL  int64  `json:"TTL"`
+		Data string `json:"data"`
+	} `json:"Answer"`
+}
+
+// queryType looks up the records of recordType of name.
+func (r *Resolver) queryType(ctx context.Context, name string, recordType int) ([]string, time.Duration, error) {
+	providerURL := r.URL
+	if providerURL == "" {
+		providerURL = CloudflareURL
+	}
+	u, err := url.Parse(providerURL)
+	if err != nil {
+		return nil, 0, fmt.Errorf("doh: provider url: %w", err)
+	}
+	q := u.Query()
+	q.Set("name", name)
+	q.Set("type", fmt.Sprint(recordType))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/dns-json")
+	resp, err := r.client().Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("doh: query %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("doh: query %s: status %d", name, resp.StatusCode)
+	}
+
+	var answer response
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&answer); err != nil {
+		return nil, 0, fmt.Errorf("doh: query %s: %w", name, err)
+	}
+	switch answer.Status {
+	case statusNoError:
+	case statusNXDomain:
+		return nil, 0, errNotFound
+	default:
+		return nil, 0, fmt.Errorf("doh: query %s: dns status %d", name, answer.Status)
+	}
+
+	var addrs []string
+	var ttl time.Duration
+	for _, record := range answer.Answer {
+		if record.Type != recordType || net.ParseIP(record.Data) == nil {
+			continue
+		}
+		addrs = append(addrs, record.Data)
+		if d := time.Duration(record.TTL) * time.Second; len(addrs) == 1 || d < ttl {
+			ttl = d
+		}
+	}
+	return addrs, ttl, nil
+}
+
+func (r *Resolver) client() *http.Client {
+	if r.Client != nil {
+		return r.Client
+	}
+	return defaultClient
+}
+
+// defaultClient makes the requests to the provider when Resolver.Client is not set.
+var defaultClient = &http.Client{Timeout: DefaultTimeout}
+
+func (r *Resolver) fallback() *net.Resolver {
+	if r.Fallback != nil {
+		return r.Fallback
+	}
+	return net.DefaultResolver
+}
+
+func (r *Resolver) dialer() *net.Dialer {
+	if r.Dialer != nil {
+		return r.Dialer
+	}
+	return defaultDialer
+}
+
+// defaultDialer has the timeouts of the dialer of http.DefaultTransport.
+var defaultDialer = &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+func (r *Resolver) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
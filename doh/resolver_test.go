@@ -0,0 +1,169 @@
+package doh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeProvider is a DoH provider answering from records, keyed by name and type. Names
+// without records do not exist. It keeps the queries it received.
+type fakeProvider struct {
+	mu      sync.Mutex
+	records map[string][]record
+	queries []string
+	status  int
+}
+
+type record struct {
+	Type int    `json:"type"`
+	TTL  int64  `json:"TTL"`
+	Data string `json:"data"`
+}
+
+func newFakeProvider(t *testing.T, records map[string][]record) (*fakeProvider, *httptest.Server) {
+	p := &fakeProvider{records: records}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, recordType := r.URL.Query().Get("name"), r.URL.Query().Get("type")
+		p.mu.Lock()
+		p.queries = append(p.queries, name+" "+recordType)
+		status := p.status
+		p.mu.Unlock()
+		if status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+		if r.Header.Get("Accept") != "application/dns-json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		answer := map[string]any{"Status": statusNXDomain}
+		if all, ok := p.records[name]; ok {
+			var answers []record
+			for _, rec := range all {
+				if strconv.Itoa(rec.Type) == recordType {
+					answers = append(answers, rec)
+				}
+			}
+			answer = map[string]any{"Status": statusNoError, "Answer": answers}
+		}
+		json.NewEncoder(w).Encode(answer)
+	}))
+	t.Cleanup(server.Close)
+	return p, server
+}
+
+func (p *fakeProvider) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.queries)
+}
+
+func TestNewResolver(t *testing.T) {
+	r, err := NewResolver(GoogleURL)
+	assert.NoError(t, err)
+	assert.Equal(t, GoogleURL, r.URL)
+
+	_, err = NewResolver("1.1.1.1")
+	assert.Error(t, err)
+}
+
+func TestLookupHost(t *testing.T) {
+	p, server := newFakeProvider(t, map[string][]record{
+		"example.test": {{typeA, 300, "192.0.2.1"}, {typeA, 60, "192.0.2.2"}, {typeAAAA, 120, "2001:db8::1"}, {5, 300, "alias.test."}},
+	})
+	r, _ := NewResolver(server.URL + "/dns-query")
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	addrs, err := r.LookupHost(context.Background(), "Example.test.")
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"192.0.2.1", "192.0.2.2", "2001:db8::1"}, addrs)
+	assert.ElementsMatch(t, []string{"example.test 1", "example.test 28"}, p.queries)
+
+	// Cached for the shortest TTL
+	now = now.Add(59 * time.Second)
+	_, err = r.LookupHost(context.Background(), "example.test")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, p.count())
+
+	now = now.Add(time.Second)
+	_, err = r.LookupHost(context.Background(), "example.test")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, p.count())
+
+	addrs, err = r.LookupHost(context.Background(), "192.0.2.9")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"192.0.2.9"}, addrs)
+	assert.Equal(t, 4, p.count())
+}
+
+func TestLookupHostNotFound(t *testing.T) {
+	_, server := newFakeProvider(t, nil)
+	r, _ := NewResolver(server.URL)
+	// A fallback would fail the test, the provider's answer being final
+	r.Fallback = &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+		t.Error("fallback used")
+		return nil, errors.New("no fallback")
+	}}
+
+	_, err := r.LookupHost(context.Background(), "missing.test")
+
+	var dnsErr *net.DNSError
+	assert.True(t, errors.As(err, &dnsErr))
+	assert.True(t, dnsErr.IsNotFound)
+}
+
+func TestLookupHostFallback(t *testing.T) {
+	p, server := newFakeProvider(t, nil)
+	p.status = http.StatusServiceUnavailable
+	r, _ := NewResolver(server.URL)
+	var fallbacks int32
+	r.Fallback = &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+		atomic.AddInt32(&fallbacks, 1)
+		return nil, errors.New("system resolver")
+	}}
+
+	_, err := r.LookupHost(context.Background(), "blocked.test")
+
+	assert.Error(t, err)
+	assert.Equal(t, 2, p.count())
+	assert.Greater(t, atomic.LoadInt32(&fallbacks), int32(0))
+}
+
+func TestDialContext(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "host "+r.Host)
+	}))
+	defer target.Close()
+	_, port, _ := net.SplitHostPort(target.Listener.Addr().String())
+
+	p, server := newFakeProvider(t, map[string][]record{
+		"www.google.test": {{typeAAAA, 60, "::1"}, {typeA, 60, "127.0.0.1"}},
+	})
+	r, _ := NewResolver(server.URL)
+	client := &http.Client{Transport: &http.Transport{DialContext: r.DialContext}}
+
+	resp, err := client.Get("http://www.google.test:" + port + "/")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	assert.Equal(t, "host www.google.test:"+port, string(body))
+	assert.Equal(t, 2, p.count())
+}
@@ -0,0 +1,230 @@
+// Package feeds discovers and parses the RSS and Atom feeds of a site.
+package feeds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/charset"
+)
+
+// ErrNotFeed is returned by ParseFeed for documents that are neither RSS nor Atom.
+var ErrNotFeed = errors.New("feeds: not an RSS or Atom feed")
+
+const (
+	// DefaultTimeout bounds a single request of a Client without HTTPClient.
+	DefaultTimeout = 30 * time.Second
+
+	// DefaultMaxBodySize is how much of a page or feed is read.
+	DefaultMaxBodySize = 5 << 20
+)
+
+// defaultUserAgent is sent with every request unless the Client sets another one.
+const defaultUserAgent = "GoScraper"
+
+// feedTypes are the link types of the feeds announced by a page.
+var feedTypes = map[string]bool{
+	"application/rss+xml":  true,
+	"application/atom+xml": true,
+	"application/rdf+xml":  true,
+}
+
+// FallbackPaths are probed by DiscoverFeeds when a page announces no feed.
+var FallbackPaths = []string{"/feed", "/rss", "/rss.xml", "/atom.xml", "/feed.xml", "/index.xml"}
+
+// Feed is a parsed RSS or Atom feed.
+type Feed struct {
+	Title       string    `json:"title"`
+	Link        string    `json:"link,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Updated     time.Time `json:"updated"`
+	Items       []Item    `json:"items"`
+}
+
+// Item is an entry of a feed.
+type Item struct {
+	Title string `json:"title"`
+	Link  string `json:"link,omitempty"`
+
+	// Published is zero when the feed has no date for the item or when it is written in a
+	// layout ParseFeed doesn't know. Atom entries without a published date use updated.
+	Published time.Time `json:"published"`
+
+	// Description is the summary of the item, or its full content when the feed has no
+	// summary. It is usually HTML.
+	Description string `json:"description,omitempty"`
+
+	Author string `json:"author,omitempty"`
+	GUID   string `json:"guid,omitempty"`
+}
+
+// Client discovers and fetches feeds. The zero value is ready to use.
+type Client struct {
+
+	// HTTPClient is used for every request. If nil, a client with DefaultTimeout is used.
+	HTTPClient *http.Client
+
+	// UserAgent is sent with every request. Default: "GoScraper".
+	UserAgent string
+}
+
+// DefaultClient is the Client used by DiscoverFeeds and ParseFeed.
+var DefaultClient = &Client{}
+
+// DiscoverFeeds returns the feeds of the page at pageURL using DefaultClient.
+func DiscoverFeeds(ctx context.Context, pageURL string) ([]string, error) {
+	return DefaultClient.DiscoverFeeds(ctx, pageURL)
+}
+
+// ParseFeed fetches and parses the feed at feedURL using DefaultClient.
+func ParseFeed(ctx context.Context, feedURL string) (*Feed, error) {
+	return DefaultClient.ParseFeed(ctx, feedURL)
+}
+
+// DiscoverFeeds returns the absolute URLs of the feeds announced by the page at pageURL
+// with <link rel="alternate">, in the order of the page. When the page announces none,
+// the FallbackPaths of its site are probed with HEAD requests and those answering with a
+// feed content type are returned instead.
+func (c *Client) DiscoverFeeds(ctx context.Context, pageURL string) ([]string, error) {
+	resp, err := c.get(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, DefaultMaxBodySize), resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		return nil, fmt.Errorf("feeds: parse html: %w", err)
+	}
+
+	base := resp.Request.URL
+	if href, ok := doc.Find("base[href]").Attr("href"); ok {
+		if u, err := base.Parse(strings.TrimSpace(href)); err == nil {
+			base = u
+		}
+	}
+
+	var feeds []string
+	seen := map[string]bool{}
+	doc.Find("link[rel][href]").Each(func(i int, link *goquery.Selection) {
+		rel := strings.Fields(strings.ToLower(link.AttrOr("rel", "")))
+		contentType := strings.ToLower(strings.TrimSpace(link.AttrOr("type", "")))
+		if !contains(rel, "alternate") || !feedTypes[contentType] {
+			return
+		}
+		u, err := base.Parse(strings.TrimSpace(link.AttrOr("href", "")))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return
+		}
+		u.Fragment = ""
+		if feed := u.String(); !seen[feed] {
+			seen[feed] = true
+			feeds = append(feeds, feed)
+		}
+	})
+	if len(feeds) > 0 {
+		return feeds, nil
+	}
+
+	for _, path := range FallbackPaths {
+		feed := (&url.URL{Scheme: resp.Request.URL.Scheme, Host: resp.Request.URL.Host, Path: path}).String()
+		if c.probe(ctx, feed) {
+			feeds = append(feeds, feed)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return feeds, nil
+}
+
+// probe reports whether feedURL answers a HEAD request with a feed content type.
+func (c *Client) probe(ctx context.Context, feedURL string) bool {
+	resp, err := c.do(ctx, http.MethodHead, feedURL)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK && isFeedContentType(resp.Header.Get("Content-Type"))
+}
+
+// isFeedContentType reports whether header is a content type feeds are served with.
+func isFeedContentType(header string) bool {
+	contentType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return false
+	}
+	return feedTypes[contentType] || contentType == "application/xml" || contentType == "text/xml"
+}
+
+// ParseFeed fetches and parses the RSS 2.0, RSS 1.0 or Atom feed at feedURL. Relative
+// links are resolved against the URL of the feed, after redirects.
+func (c *Client) ParseFeed(ctx context.Context, feedURL string) (*Feed, error) {
+	resp, err := c.get(ctx, feedURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return Parse(io.LimitReader(resp.Body, DefaultMaxBodySize), resp.Request.URL)
+}
+
+// get makes a GET request for rawURL and fails unless it succeeds with status 200.
+func (c *Client) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	resp, err := c.do(ctx, http.MethodGet, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("feeds: fetching %s: %s", rawURL, resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *Client) do(ctx context.Context, method, rawURL string) (*http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("feeds: unsupported URL scheme %q", u.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	userAgent := c.UserAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	client := c.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+	return client.Do(req)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
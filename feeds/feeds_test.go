@@ -0,0 +1,156 @@
+package feeds
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func openFixture(t *testing.T, name string) *os.File {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestParseRSS(t *testing.T) {
+	base, _ := url.Parse("https://news.example.com/feed")
+
+	feed, err := Parse(openFixture(t, "rss.xml"), base)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "City Desk", feed.Title)
+	assert.Equal(t, "https://news.example.com/", feed.Link)
+	assert.Equal(t, "Local news from the city", feed.Description)
+	assert.Equal(t, time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC), feed.Updated.UTC())
+	assert.Len(t, feed.Items, 2)
+
+	item := feed.Items[0]
+	assert.Equal(t, "Bike lanes approved", item.Title)
+	assert.Equal(t, "https://news.example.com/2024/03/bike-lanes", item.Link)
+	assert.Equal(t, "Jane Doe", item.Author)
+	assert.Equal(t, "news-1234", item.GUID)
+	assert.Equal(t, "<p>The council voted on Tuesday night.</p>", item.Description)
+	assert.Equal(t, time.Date(2024, 3, 5, 9, 30, 0, 0, time.UTC), item.Published.UTC())
+
+	item = feed.Items[1]
+	assert.Equal(t, "https://news.example.com/2024/03/library", item.Link)
+	assert.Equal(t, "desk@news.example.com (City Desk)", item.Author)
+	assert.Equal(t, "<p>Open until 9pm.</p>", item.Description)
+	// Ruby date
+	assert.Equal(t, time.Date(2024, 3, 4, 23, 0, 0, 0, time.UTC), item.Published.UTC())
+}
+
+func TestParseAtom(t *testing.T) {
+	base, _ := url.Parse("https://blog.example.org/atom.xml")
+
+	feed, err := Parse(openFixture(t, "atom.xml"), base)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Go Notes", feed.Title)
+	assert.Equal(t, "https://blog.example.org/", feed.Link)
+	assert.Equal(t, "Notes on <b>Go</b>", feed.Description)
+	assert.Len(t, feed.Items, 2)
+
+	item := feed.Items[0]
+	assert.Equal(t, "Generics in practice", item.Title)
+	assert.Equal(t, "https://blog.example.org/posts/generics", item.Link)
+	assert.Equal(t, "Ian, Robert", item.Author)
+	assert.Equal(t, "Type parameters in practice.", item.Description)
+	assert.Equal(t, time.Date(2024, 2, 10, 11, 0, 0, 0, time.UTC), item.Published.UTC())
+
+	// No published date, no author of its own and xhtml content
+	item = feed.Items[1]
+	assert.Equal(t, time.Date(2024, 1, 5, 9, 0, 0, 0, time.UTC), item.Published)
+	assert.Equal(t, "Rob", item.Author)
+	assert.Contains(t, item.Description, "<p>Handle them.</p>")
+}
+
+func TestParseNotFeed(t *testing.T) {
+	_, err := Parse(strings.NewReader("<html><body>Hello</body></html>"), nil)
+
+	assert.ErrorIs(t, err, ErrNotFeed)
+}
+
+func TestParseDate(t *testing.T) {
+	want := time.Date(2024, 3, 5, 9, 30, 0, 0, time.UTC)
+	tests := []string{
+		"Tue, 05 Mar 2024 09:30:00 +0000",
+		"Tue, 5 Mar 2024 09:30:00 GMT",
+		"Tue, 5 Mar 2024 09:30 +0000",
+		"5 Mar 2024 09:30:00 +0000",
+		"2024-03-05T10:30:00+01:00",
+		"2024-03-05T09:30:00.000Z",
+		"Tue Mar 05 09:30:00 +0000 2024",
+		"  Tue, 05 Mar 2024\n 09:30:00 +0000 ",
+	}
+
+	for _, value := range tests {
+		assert.True(t, want.Equal(parseDate(value)), value)
+	}
+	assert.True(t, parseDate("last Tuesday").IsZero())
+}
+
+func TestDiscoverFeeds(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/article":
+			fmt.Fprint(w, `<html><head>
+<link rel="alternate" type="application/rss+xml" href="/feed">
+<link rel="alternate" type="application/atom+xml" href="https://example.com/atom.xml">
+<link rel="alternate" hreflang="de" href="/de/article">
+<link rel="alternate" type="application/rss+xml" href="/feed#dup">
+</head><body></body></html>`)
+		case "/bare":
+			fmt.Fprint(w, `<html><head><title>No feeds</title></head></html>`)
+		case "/rss.xml":
+			w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		case "/feed":
+			// A HTML page
+			w.Header().Set("Content-Type", "text/html")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	feeds, err := DiscoverFeeds(context.Background(), server.URL+"/article")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{server.URL + "/feed", "https://example.com/atom.xml"}, feeds)
+
+	feeds, err = DiscoverFeeds(context.Background(), server.URL+"/bare")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{server.URL + "/rss.xml"}, feeds)
+
+	_, err = DiscoverFeeds(context.Background(), server.URL+"/missing")
+	assert.Error(t, err)
+}
+
+func TestParseFeed(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "atom.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/atom+xml")
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	feed, err := ParseFeed(context.Background(), server.URL+"/atom.xml")
+
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/posts/generics", feed.Items[0].Link)
+}
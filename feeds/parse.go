@@ -0,0 +1,270 @@
+package feeds
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+type rssDocument struct {
+	Channel rssChannel `xml:"channel"`
+	// RSS 1.0 puts the items next to the channel rather than in it
+	Items []rssItem `xml:"item"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Links         []rssLink `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	PubDate       string    `xml:"pubDate"`
+	Date          string    `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Items         []rssItem `xml:"item"`
+}
+
+// rssLink is a link element of RSS or, in RSS feeds that also use the Atom namespace,
+// an atom:link, which has the URL in href.
+type rssLink struct {
+	Href  string `xml:"href,attr"`
+	Rel   string `xml:"rel,attr"`
+	Value string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string    `xml:"title"`
+	Links       []rssLink `xml:"link"`
+	Description string    `xml:"description"`
+	Content     string    `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Author      string    `xml:"author"`
+	Creator     string    `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	PubDate     string    `xml:"pubDate"`
+	Date        string    `xml:"http://purl.org/dc/elements/1.1/ date"`
+	GUID        string    `xml:"guid"`
+}
+
+type atomFeed struct {
+	Title    atomText     `xml:"title"`
+	Subtitle atomText     `xml:"subtitle"`
+	Links    []atomLink   `xml:"link"`
+	Updated  string       `xml:"updated"`
+	Authors  []atomPerson `xml:"author"`
+	Entries  []atomEntry  `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title     atomText     `xml:"title"`
+	Links     []atomLink   `xml:"link"`
+	Published string       `xml:"published"`
+	Updated   string       `xml:"updated"`
+	Summary   atomText     `xml:"summary"`
+	Content   atomText     `xml:"content"`
+	Authors   []atomPerson `xml:"author"`
+	ID        string       `xml:"id"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+// atomText is an Atom text construct. The markup of xhtml content is kept as is.
+type atomText struct {
+	Type     string `xml:"type,attr"`
+	Chardata string `xml:",chardata"`
+	InnerXML string `xml:",innerxml"`
+}
+
+func (t atomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.InnerXML)
+	}
+	return strings.TrimSpace(t.Chardata)
+}
+
+// Parse parses an RSS 2.0, RSS 1.0 or Atom feed read from r. Relative links are resolved
+// against base, which may be nil.
+func Parse(r io.Reader, base *url.URL) (*Feed, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReaderLabel
+	// Feeds in the wild are full of HTML entities such as &nbsp;
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return nil, ErrNotFeed
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "rss", "RDF":
+			var doc rssDocument
+			if err := decoder.DecodeElement(&doc, &start); err != nil {
+				return nil, err
+			}
+			return doc.feed(base), nil
+		case "feed":
+			var doc atomFeed
+			if err := decoder.DecodeElement(&doc, &start); err != nil {
+				return nil, err
+			}
+			return doc.feed(base), nil
+		default:
+			return nil, ErrNotFeed
+		}
+	}
+}
+
+func (doc *rssDocument) feed(base *url.URL) *Feed {
+	channel := doc.Channel
+	feed := &Feed{
+		Title:       strings.TrimSpace(channel.Title),
+		Link:        resolve(base, rssHref(channel.Links)),
+		Description: strings.TrimSpace(channel.Description),
+		Updated:     parseDate(firstNonEmpty(channel.LastBuildDate, channel.PubDate, channel.Date)),
+		Items:       []Item{},
+	}
+	for _, item := range append(channel.Items, doc.Items...) {
+		feed.Items = append(feed.Items, Item{
+			Title:       strings.TrimSpace(item.Title),
+			Link:        resolve(base, rssHref(item.Links)),
+			Published:   parseDate(firstNonEmpty(item.PubDate, item.Date)),
+			Description: strings.TrimSpace(firstNonEmpty(item.Description, item.Content)),
+			Author:      strings.TrimSpace(firstNonEmpty(item.Creator, item.Author)),
+			GUID:        strings.TrimSpace(item.GUID),
+		})
+	}
+	return feed
+}
+
+// rssHref returns the text of the first RSS link, or the href of the first alternate
+// atom:link.
+func rssHref(links []rssLink) string {
+	for _, link := range links {
+		if value := strings.TrimSpace(link.Value); value != "" {
+			return value
+		}
+	}
+	for _, link := range links {
+		if link.Href != "" && (link.Rel == "" || link.Rel == "alternate") {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+func (doc *atomFeed) feed(base *url.URL) *Feed {
+	feed := &Feed{
+		Title:       doc.Title.String(),
+		Link:        resolve(base, atomHref(doc.Links)),
+		Description: doc.Subtitle.String(),
+		Updated:     parseDate(doc.Updated),
+		Items:       []Item{},
+	}
+	for _, entry := range doc.Entries {
+		authors := entry.Authors
+		if len(authors) == 0 {
+			authors = doc.Authors
+		}
+		var names []string
+		for _, author := range authors {
+			if name := strings.TrimSpace(author.Name); name != "" {
+				names = append(names, name)
+			}
+		}
+
+		feed.Items = append(feed.Items, Item{
+			Title:       entry.Title.String(),
+			Link:        resolve(base, atomHref(entry.Links)),
+			Published:   parseDate(firstNonEmpty(entry.Published, entry.Updated)),
+			Description: firstNonEmpty(entry.Summary.String(), entry.Content.String()),
+			Author:      strings.Join(names, ", "),
+			GUID:        strings.TrimSpace(entry.ID),
+		})
+	}
+	return feed
+}
+
+// atomHref returns the href of the alternate link, which is the default relation.
+func atomHref(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+func resolve(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if base == nil || href == "" {
+		return href
+	}
+	u, err := base.Parse(href)
+	if err != nil {
+		return href
+	}
+	return u.String()
+}
+
+// dateLayouts are tried in order to parse the dates of a feed: RFC 822 and its many
+// variations for RSS, RFC 3339 for Atom and Dublin Core.
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RubyDate,
+	time.UnixDate,
+	time.ANSIC,
+	time.RFC850,
+}
+
+func parseDate(value string) time.Time {
+	value = strings.Join(strings.Fields(value), " ")
+	if value == "" {
+		return time.Time{}
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
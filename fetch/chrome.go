@@ -0,0 +1,189 @@
+//go:build chromedp
+
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// chromeNames are looked up in PATH when ChromeFetcher.ExecPath is not set.
+var chromeNames = []string{
+	"headless-shell",
+	"headless_shell",
+	"chromium",
+	"chromium-browser",
+	"google-chrome",
+	"google-chrome-stable",
+	"chrome",
+}
+
+// browser is the Chrome instance shared by the fetches of a ChromeFetcher.
+type browser struct {
+	mu          sync.Mutex
+	ctx         context.Context
+	cancel      context.CancelFunc
+	cancelAlloc context.CancelFunc
+}
+
+// Fetch loads rawURL in a new tab and returns its DOM once WaitSelector is visible or the
+// network went idle.
+func (f *ChromeFetcher) Fetch(ctx context.Context, rawURL string) (*Page, error) {
+	if err := checkURL(rawURL); err != nil {
+		return nil, err
+	}
+	browserCtx, err := f.start()
+	if err != nil {
+		return nil, err
+	}
+
+	tabCtx, cancelTab := chromedp.NewContext(browserCtx)
+	defer cancelTab()
+	tabCtx, cancelTimeout := context.WithTimeout(tabCtx, f.timeout())
+	defer cancelTimeout()
+
+	// Cancelling the caller's ctx closes the tab
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancelTab()
+		case <-done:
+		}
+	}()
+
+	idle := newNetworkTracker()
+	chromedp.ListenTarget(tabCtx, idle.handle)
+
+	wait := chromedp.ActionFunc(func(ctx context.Context) error {
+		return idle.wait(ctx, f.networkIdle())
+	})
+	if f.WaitSelector != "" {
+		wait = chromedp.ActionFunc(func(ctx context.Context) error {
+			return chromedp.WaitVisible(f.WaitSelector, chromedp.ByQuery).Do(ctx)
+		})
+	}
+
+	var location, html string
+	err = chromedp.Run(tabCtx,
+		network.Enable(),
+		chromedp.Navigate(rawURL),
+		wait,
+		chromedp.Location(&location),
+		chromedp.OuterHTML("html", &html, chromedp.ByQuery),
+	)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("fetch: rendering %s: %w", rawURL, err)
+	}
+	return &Page{URL: location, HTML: html}, nil
+}
+
+// start launches the browser unless it runs already and returns its context.
+func (f *ChromeFetcher) start() (context.Context, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.ctx != nil {
+		return f.ctx, nil
+	}
+
+	execPath := f.ExecPath
+	if execPath == "" {
+		for _, name := range chromeNames {
+			if path, err := exec.LookPath(name); err == nil {
+				execPath = path
+				break
+			}
+		}
+		if execPath == "" {
+			return nil, fmt.Errorf("%w: no Chrome or Chromium found in PATH", ErrBrowserUnavailable)
+		}
+	}
+
+	opts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.ExecPath(execPath))
+	if f.UserAgent != "" {
+		opts = append(opts, chromedp.UserAgent(f.UserAgent))
+	}
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), opts...)
+	ctx, cancel := chromedp.NewContext(allocCtx)
+
+	// Running no action starts the browser, so that a broken install fails here
+	launchCtx, cancelLaunch := context.WithTimeout(ctx, f.timeout())
+	defer cancelLaunch()
+	if err := chromedp.Run(launchCtx); err != nil {
+		cancel()
+		cancelAlloc()
+		return nil, fmt.Errorf("%w: starting %s: %v", ErrBrowserUnavailable, execPath, err)
+	}
+
+	f.ctx, f.cancel, f.cancelAlloc = ctx, cancel, cancelAlloc
+	return ctx, nil
+}
+
+// Close stops the browser. A later Fetch starts a new one.
+func (f *ChromeFetcher) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.ctx == nil {
+		return nil
+	}
+	f.cancel()
+	f.cancelAlloc()
+	f.ctx, f.cancel, f.cancelAlloc = nil, nil, nil
+	return nil
+}
+
+// networkTracker counts the requests of a tab in flight.
+type networkTracker struct {
+	mu       sync.Mutex
+	inflight map[network.RequestID]bool
+	last     time.Time
+}
+
+func newNetworkTracker() *networkTracker {
+	return &networkTracker{inflight: map[network.RequestID]bool{}, last: time.Now()}
+}
+
+func (t *networkTracker) handle(ev interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		t.inflight[ev.RequestID] = true
+	case *network.EventLoadingFinished:
+		delete(t.inflight, ev.RequestID)
+	case *network.EventLoadingFailed:
+		delete(t.inflight, ev.RequestID)
+	default:
+		return
+	}
+	t.last = time.Now()
+}
+
+// wait returns once no request has been in flight for idle.
+func (t *networkTracker) wait(ctx context.Context, idle time.Duration) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		t.mu.Lock()
+		quiet := len(t.inflight) == 0 && time.Since(t.last) >= idle
+		t.mu.Unlock()
+		if quiet {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
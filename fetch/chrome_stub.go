@@ -0,0 +1,21 @@
+//go:build !chromedp
+
+package fetch
+
+import (
+	"context"
+	"fmt"
+)
+
+// browser is empty without the chromedp build tag.
+type browser struct{}
+
+// Fetch fails with ErrBrowserUnavailable: rendering needs the chromedp build tag.
+func (f *ChromeFetcher) Fetch(ctx context.Context, rawURL string) (*Page, error) {
+	return nil, fmt.Errorf("%w: built without the chromedp build tag", ErrBrowserUnavailable)
+}
+
+// Close does nothing.
+func (f *ChromeFetcher) Close() error {
+	return nil
+}
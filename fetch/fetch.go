@@ -0,0 +1,179 @@
+// Package fetch downloads pages for the scrapers and extractors of this module, either
+// over plain HTTP or rendered by a headless browser for pages that need JavaScript.
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// DefaultTimeout bounds a fetch when ctx has no earlier deadline.
+	DefaultTimeout = 30 * time.Second
+
+	// DefaultMaxBodySize is how much of a page HTTPFetcher reads.
+	DefaultMaxBodySize = 5 << 20
+
+	// DefaultNetworkIdle is how long ChromeFetcher waits without network activity before
+	// taking the DOM of a page.
+	DefaultNetworkIdle = 500 * time.Millisecond
+)
+
+// defaultUserAgent is sent with every request unless the fetcher sets another one.
+const defaultUserAgent = "GoScraper"
+
+// ErrBrowserUnavailable is returned by ChromeFetcher when no browser can be started: Chrome
+// is not installed, or the module was built without the chromedp build tag.
+var ErrBrowserUnavailable = errors.New("fetch: headless browser unavailable")
+
+// Page is a fetched HTML page.
+type Page struct {
+
+	// URL is the final URL of the page, after redirects.
+	URL string
+
+	// HTML is the markup of the page in UTF-8. For a rendered page it is the DOM after
+	// scripts ran, serialized.
+	HTML string
+}
+
+// Fetcher fetches the HTML of pages.
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*Page, error)
+}
+
+// DefaultRenderer is used by the helpers of this module that render pages when asked to,
+// such as link_preview.Scraper with RenderJS. Chrome is only started by its first Fetch.
+var DefaultRenderer Fetcher = NewChromeFetcher()
+
+// HTTPFetcher fetches pages with a plain GET request, without running their scripts. The
+// zero value is ready to use.
+type HTTPFetcher struct {
+
+	// Client is used for the requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// UserAgent is sent with every request. Default: "GoScraper".
+	UserAgent string
+
+	// MaxBodySize caps how many bytes of a page are read. Default: DefaultMaxBodySize.
+	MaxBodySize int64
+}
+
+// Fetch requests rawURL and returns its body decoded to UTF-8. Statuses of 400 and above
+// are errors.
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) (*Page, error) {
+	if err := checkURL(rawURL); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	userAgent := f.UserAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("fetch: %s: %s", rawURL, resp.Status)
+	}
+
+	maxBodySize := f.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+	body, err := charset.NewReader(io.LimitReader(resp.Body, maxBodySize), resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	return &Page{URL: resp.Request.URL.String(), HTML: string(b)}, nil
+}
+
+// ChromeFetcher renders pages in headless Chrome and returns their DOM once scripts ran.
+// It needs the module to be built with the chromedp build tag and Chrome or Chromium to be
+// installed; otherwise Fetch fails with ErrBrowserUnavailable.
+//
+// The browser is started by the first Fetch and shared by the later ones, each of which
+// opens a tab of its own. A ChromeFetcher is safe for concurrent use.
+type ChromeFetcher struct {
+
+	// Timeout bounds the navigation and the wait for the page to settle.
+	// Default: DefaultTimeout.
+	Timeout time.Duration
+
+	// WaitSelector, if set, is waited for to be visible before the DOM is taken. Otherwise
+	// the page is taken once the network has been idle for NetworkIdle.
+	WaitSelector string
+
+	// NetworkIdle is how long no request may be in flight for the page to count as loaded.
+	// Default: DefaultNetworkIdle.
+	NetworkIdle time.Duration
+
+	// UserAgent overrides the user agent of the browser.
+	UserAgent string
+
+	// ExecPath is the Chrome binary to run. Default: the first of the usual names of Chrome
+	// and Chromium found in PATH.
+	ExecPath string
+
+	browser
+}
+
+// NewChromeFetcher returns a ChromeFetcher with the default settings. No browser is started
+// until Fetch is called.
+func NewChromeFetcher() *ChromeFetcher {
+	return &ChromeFetcher{}
+}
+
+func (f *ChromeFetcher) timeout() time.Duration {
+	if f.Timeout > 0 {
+		return f.Timeout
+	}
+	return DefaultTimeout
+}
+
+func (f *ChromeFetcher) networkIdle() time.Duration {
+	if f.NetworkIdle > 0 {
+		return f.NetworkIdle
+	}
+	return DefaultNetworkIdle
+}
+
+// checkURL fails for anything but absolute http and https URLs.
+func checkURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("fetch: unsupported URL scheme %q", u.Scheme)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPFetcher(t *testing.T) {
+	var agent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/latin1", http.StatusFound)
+		case "/latin1":
+			agent = r.Header.Get("User-Agent")
+			w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+			w.Write([]byte("<html><body>Caf\xe9</body></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	page, err := (&HTTPFetcher{}).Fetch(context.Background(), server.URL+"/old")
+
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/latin1", page.URL)
+	assert.Equal(t, "<html><body>Café</body></html>", page.HTML)
+	assert.Equal(t, "GoScraper", agent)
+
+	_, err = (&HTTPFetcher{}).Fetch(context.Background(), server.URL+"/missing")
+	assert.ErrorContains(t, err, "404")
+}
+
+func TestHTTPFetcherMaxBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html><body>0123456789</body></html>"))
+	}))
+	defer server.Close()
+
+	page, err := (&HTTPFetcher{MaxBodySize: 16}).Fetch(context.Background(), server.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><body>0123", page.HTML)
+}
+
+func TestFetchUnsupportedScheme(t *testing.T) {
+	_, err := (&HTTPFetcher{}).Fetch(context.Background(), "ftp://example.com/")
+	assert.ErrorContains(t, err, "unsupported URL scheme")
+
+	_, err = NewChromeFetcher().Fetch(context.Background(), "file:///etc/passwd")
+	assert.Error(t, err)
+}
+
+func TestChromeFetcherUnavailable(t *testing.T) {
+	f := &ChromeFetcher{ExecPath: "/nonexistent/chrome"}
+	defer f.Close()
+
+	_, err := f.Fetch(context.Background(), "https://example.com/")
+
+	assert.ErrorIs(t, err, ErrBrowserUnavailable)
+}
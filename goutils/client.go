@@ -0,0 +1,309 @@
+// Package goutils ties the search, link_preview, store and markdown packages together
+// behind a Client, so that searching, previewing, extracting articles and converting pages
+// to markdown share one HTTP configuration:
+//
+//	client := goutils.New(goutils.WithProxyPool(pool), goutils.WithRateLimit(1, 2))
+//	results, err := client.Search(ctx, "golang")
+//	article, err := client.ExtractArticle(ctx, results[0].URL)
+//	md, err := client.URLToMarkdown(ctx, results[0].URL, &markdown.Option{MainContent: true})
+package goutils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/propro-productions/go-utils/doh"
+	"github.com/propro-productions/go-utils/link_preview"
+	"github.com/propro-productions/go-utils/markdown"
+	"github.com/propro-productions/go-utils/metrics"
+	"github.com/propro-productions/go-utils/proxy"
+	"github.com/propro-productions/go-utils/search"
+	"github.com/propro-productions/go-utils/store"
+	"golang.org/x/time/rate"
+)
+
+// Limiter delays requests per host. search.Limiter and link_preview.Limiter have the same
+// method, so a *search.HostLimiter limits both.
+type Limiter interface {
+	Wait(ctx context.Context, host string) error
+}
+
+// Logger receives debug messages about the requests made. search.Logger and
+// link_preview.Logger have the same method.
+type Logger interface {
+	Debug(msg string, args ...any)
+}
+
+// Client makes searches, previews, article extractions and markdown conversions with one
+// configuration. It is safe for concurrent use.
+type Client struct {
+	httpClient   *http.Client
+	userAgent    string
+	timeout      time.Duration
+	proxyAddr    string
+	proxyPool    *proxy.Pool
+	resolver     *doh.Resolver
+	limiter      Limiter
+	searchCache  search.Cache
+	previewCache link_preview.PreviewCache
+	logger       Logger
+	metrics      metrics.Metrics
+	ignoreRobots bool
+
+	// previewer caches the previews made without overrides
+	previewer *link_preview.CachedPreviewer
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient makes every request with client, which is never modified.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) { c.httpClient = client }
+}
+
+// WithTransport makes every request with a client using transport.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) { c.httpClient = &http.Client{Transport: transport} }
+}
+
+// WithUserAgent sets the User-Agent sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) { c.userAgent = userAgent }
+}
+
+// WithTimeout bounds every search request and every preview, extraction and conversion.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) { c.timeout = timeout }
+}
+
+// WithProxy sends every request through the proxy at proxyAddr.
+func WithProxy(proxyAddr string) Option {
+	return func(c *Client) { c.proxyAddr = proxyAddr }
+}
+
+// WithProxyPool sends every request through the next proxy of pool. It takes precedence
+// over WithProxy.
+func WithProxyPool(pool *proxy.Pool) Option {
+	return func(c *Client) { c.proxyPool = pool }
+}
+
+// WithResolver looks up the hosts of every request, and the proxies, with resolver.
+func WithResolver(resolver *doh.Resolver) Option {
+	return func(c *Client) { c.resolver = resolver }
+}
+
+// WithLimiter delays every request with limiter.
+func WithLimiter(limiter Limiter) Option {
+	return func(c *Client) { c.limiter = limiter }
+}
+
+// WithRateLimit allows perSecond requests per second to every host, with bursts of burst.
+func WithRateLimit(perSecond rate.Limit, burst int) Option {
+	return func(c *Client) { c.limiter = search.NewHostLimiter(perSecond, burst) }
+}
+
+// WithSearchCache keeps search results in cache.
+func WithSearchCache(cache search.Cache) Option {
+	return func(c *Client) { c.searchCache = cache }
+}
+
+// WithPreviewCache keeps previews in cache.
+func WithPreviewCache(cache link_preview.PreviewCache) Option {
+	return func(c *Client) { c.previewCache = cache }
+}
+
+// WithLogger sends debug messages about the requests made to logger.
+func WithLogger(logger Logger) Option {
+	return func(c *Client) { c.logger = logger }
+}
+
+// WithMetrics reports the searches, previews, cache lookups and conversions made to m.
+func WithMetrics(m metrics.Metrics) Option {
+	return func(c *Client) { c.metrics = m }
+}
+
+// WithIgnoreRobots skips the robots.txt check of previews, extractions and conversions.
+func WithIgnoreRobots() Option {
+	return func(c *Client) { c.ignoreRobots = true }
+}
+
+// New returns a Client configured by opts. Settings that opts leave alone have the
+// defaults of the package doing the work.
+func New(opts ...Option) *Client {
+	c := &Client{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.previewCache != nil {
+		c.previewer = link_preview.NewCachedPreviewer(c.previewCache, c.previewOptions(nil))
+	}
+	return c
+}
+
+// Search searches Google for term, falling back to the other engines of search.EngineOrder
+// when it is blocked. The fields of opts, of which only the first is used, override the
+// configuration of the client.
+func (c *Client) Search(ctx context.Context, term string, opts ...search.SearchOptions) ([]search.Result, error) {
+	return search.Search(ctx, term, search.EngineGoogle, c.searchOptions(opts))
+}
+
+// Preview returns the preview of the page at rawURL. The fields of opts, of which only the
+// first is used, override the configuration of the client.
+func (c *Client) Preview(ctx context.Context, rawURL string, opts ...link_preview.ScraperOptions) (link_preview.Preview, error) {
+	if c.previewCache != nil {
+		previewer := c.previewer
+		if len(opts) > 0 {
+			previewer = link_preview.NewCachedPreviewer(c.previewCache, c.previewOptions(opts))
+		}
+		return previewer.Preview(ctx, rawURL)
+	}
+
+	scraper, err := c.previewOptions(opts).Scraper(rawURL)
+	if err != nil {
+		return link_preview.Preview{}, err
+	}
+	doc, err := scraper.GetLinkPreviewItemsContext(ctx)
+	if err != nil {
+		return link_preview.Preview{}, err
+	}
+	return doc.Metadata, nil
+}
+
+// ExtractArticle returns the content of the main article of the page at rawURL, as found by
+// store.ExtractMainContent, with its URL set to that of the page after redirects. The
+// fields of opts, of which only the first is used, override the configuration of the
+// client.
+func (c *Client) ExtractArticle(ctx context.Context, rawURL string, opts ...link_preview.ScraperOptions) (*store.ExtractedContent, error) {
+	doc, err := c.fetchDocument(ctx, rawURL, c.previewOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	content, err := store.ExtractMainContent(doc)
+	if err != nil {
+		return nil, fmt.Errorf("goutils: %s: %w", doc.Url, err)
+	}
+	content.URL = doc.Url.String()
+	return content, nil
+}
+
+// URLToMarkdown returns the markdown of the page at rawURL, converted by
+// markdown.ConvertDocument with option, which may be nil. The page is fetched by the
+// client, rendered first with option.RenderJS. The fields of opts, of which only the first
+// is used, override the configuration of the client.
+func (c *Client) URLToMarkdown(ctx context.Context, rawURL string, option *markdown.Option, opts ...link_preview.ScraperOptions) (string, error) {
+	scraperOpts := c.previewOptions(opts)
+	if option != nil && option.RenderJS {
+		scraperOpts.RenderJS = true
+	}
+	doc, err := c.fetchDocument(ctx, rawURL, scraperOpts)
+	if err != nil {
+		return "", err
+	}
+	if c.metrics != nil && (option == nil || option.Metrics == nil) {
+		option = option.Clone()
+		if option == nil {
+			option = &markdown.Option{}
+		}
+		option.Metrics = c.metrics
+	}
+	return markdown.ConvertDocument(doc, option)
+}
+
+// fetchDocument fetches and parses the page at rawURL, with the URL after redirects.
+func (c *Client) fetchDocument(ctx context.Context, rawURL string, opts link_preview.ScraperOptions) (*goquery.Document, error) {
+	scraper, err := opts.Scraper(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	page, err := scraper.FetchDocument(ctx)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := goquery.NewDocumentFromReader(&page.Body)
+	if err != nil {
+		return nil, fmt.Errorf("goutils: parse html: %w", err)
+	}
+	doc.Url = scraper.Url
+	return doc, nil
+}
+
+// searchOptions returns the first of opts, or the zero value, with the settings it leaves
+// alone taken from the client.
+func (c *Client) searchOptions(opts []search.SearchOptions) search.SearchOptions {
+	var opt search.SearchOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.HTTPClient == nil {
+		opt.HTTPClient = c.httpClient
+	}
+	if opt.UserAgent == "" {
+		opt.UserAgent = c.userAgent
+	}
+	if opt.Timeout <= 0 {
+		opt.Timeout = c.timeout
+	}
+	if opt.ProxyAddr == "" && opt.ProxyPool == nil && len(opt.Proxies) == 0 {
+		opt.ProxyAddr, opt.ProxyPool = c.proxyAddr, c.proxyPool
+	}
+	if opt.Resolver == nil {
+		opt.Resolver = c.resolver
+	}
+	if opt.Limiter == nil && c.limiter != nil {
+		opt.Limiter = c.limiter
+	}
+	if opt.Cache == nil {
+		opt.Cache = c.searchCache
+	}
+	if opt.Logger == nil && c.logger != nil {
+		opt.Logger = c.logger
+	}
+	if opt.Metrics == nil && c.metrics != nil {
+		opt.Metrics = c.metrics
+	}
+	return opt
+}
+
+// previewOptions is like searchOptions for previews. A zero MaxRedirect is
+// link_preview.DefaultMaxRedirect.
+func (c *Client) previewOptions(opts []link_preview.ScraperOptions) link_preview.ScraperOptions {
+	var opt link_preview.ScraperOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.HTTPClient == nil {
+		opt.HTTPClient = c.httpClient
+	}
+	if opt.UserAgent == "" {
+		opt.UserAgent = c.userAgent
+	}
+	if opt.Timeout <= 0 {
+		opt.Timeout = c.timeout
+	}
+	if opt.ProxyAddr == "" && opt.ProxyPool == nil {
+		opt.ProxyAddr, opt.ProxyPool = c.proxyAddr, c.proxyPool
+	}
+	if opt.Resolver == nil {
+		opt.Resolver = c.resolver
+	}
+	if opt.Limiter == nil && c.limiter != nil {
+		opt.Limiter = c.limiter
+	}
+	if opt.Logger == nil && c.logger != nil {
+		opt.Logger = c.logger
+	}
+	if opt.Metrics == nil && c.metrics != nil {
+		opt.Metrics = c.metrics
+	}
+	if c.ignoreRobots {
+		opt.IgnoreRobots = true
+	}
+	if opt.MaxRedirect == 0 {
+		opt.MaxRedirect = link_preview.DefaultMaxRedirect
+	}
+	return opt
+}
@@ -0,0 +1,160 @@
+package goutils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/propro-productions/go-utils/link_preview"
+	"github.com/propro-productions/go-utils/markdown"
+	"github.com/propro-productions/go-utils/search"
+	"github.com/propro-productions/go-utils/store"
+	"github.com/stretchr/testify/assert"
+)
+
+const resultsPage = `<html><body><div id="search"><div id="rso">
+<div class="g">
+  <div class="yuRUbf"><a href="https://news.example.com/article"><h3>Transit Budget Approved</h3></a></div>
+  <div class="VwiC3b"><span>The council voted to increase funding for public transit.</span></div>
+</div>
+</div></div></body></html>`
+
+const articlePage = `<html><head>
+<title>Transit Budget Approved | News</title>
+<meta property="og:title" content="Transit Budget Approved">
+<meta property="og:description" content="The council voted to increase funding for public transit.">
+</head><body>
+<nav><a href="/">Home</a> <a href="/local">Local</a></nav>
+<article>
+<h1>Transit Budget Approved</h1>
+<p>The city council voted seven to two on Tuesday night to approve a budget that increases funding for public transit.</p>
+<p>The plan adds three new bus routes connecting the eastern neighborhoods to the downtown business district, and extends service hours on the light rail line until two in the morning on weekends.</p>
+<p>Supporters said the investment was overdue. Residents have waited years for reliable service, and this budget finally delivers it, said the council member who sponsored the proposal.</p>
+<p>Opponents argued that the increase would require cuts to road maintenance and parks, and warned that the city could face a shortfall if sales tax revenue falls short of projections.</p>
+<p>The transit agency expects to hire about forty new drivers, mechanics and dispatchers before the new routes open in the spring. Fares will remain unchanged, for now, for at least another year.</p>
+</article>
+<footer>Copyright News</footer>
+</body></html>`
+
+type rewriteTransport struct {
+	target   *url.URL
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func (rt *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.mu.Lock()
+	rt.requests = append(rt.requests, req)
+	rt.mu.Unlock()
+
+	r := req.Clone(req.Context())
+	r.URL.Scheme = rt.target.Scheme
+	r.URL.Host = rt.target.Host
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	resp.Request = req
+	return resp, nil
+}
+
+// newTestTransport serves the results and article pages and returns a transport that
+// routes every request to them.
+func newTestTransport(t *testing.T) *rewriteTransport {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+		switch r.URL.Path {
+		case "/search":
+			w.Write([]byte(resultsPage))
+		case "/article":
+			w.Write([]byte(articlePage))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	target, _ := url.Parse(server.URL)
+	return &rewriteTransport{target: target}
+}
+
+func TestClientPipeline(t *testing.T) {
+	rt := newTestTransport(t)
+	client := New(WithTransport(rt), WithUserAgent("test-agent"), WithRateLimit(100, 10), WithIgnoreRobots())
+	ctx := context.Background()
+
+	results, err := client.Search(ctx, "transit budget")
+	assert.NoError(t, err)
+	if !assert.Len(t, results, 1) {
+		return
+	}
+	assert.Equal(t, "https://news.example.com/article", results[0].URL)
+	assert.Equal(t, "Transit Budget Approved", results[0].Title)
+
+	preview, err := client.Preview(ctx, results[0].URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "Transit Budget Approved", preview.Title)
+
+	article, err := client.ExtractArticle(ctx, results[0].URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "https://news.example.com/article", article.URL)
+	if assert.NotEmpty(t, article.Blocks) {
+		assert.Equal(t, store.BlockHeading, article.Blocks[0].Kind)
+		assert.Equal(t, "Transit Budget Approved", article.Blocks[0].Text)
+	}
+
+	md, err := client.URLToMarkdown(ctx, results[0].URL, &markdown.Option{MainContent: true})
+	assert.NoError(t, err)
+	assert.Contains(t, md, "# Transit Budget Approved")
+	assert.Contains(t, md, "three new bus routes")
+	assert.NotContains(t, md, "Copyright News")
+
+	for _, req := range rt.requests {
+		assert.Equal(t, "test-agent", req.Header.Get("User-Agent"), req.URL.String())
+	}
+}
+
+func TestClientOverrides(t *testing.T) {
+	rt := newTestTransport(t)
+	client := New(WithTransport(rt), WithUserAgent("test-agent"), WithIgnoreRobots())
+	ctx := context.Background()
+
+	_, err := client.Search(ctx, "transit budget", search.SearchOptions{UserAgent: "search-agent"})
+	assert.NoError(t, err)
+	_, err = client.Preview(ctx, "https://news.example.com/article", link_preview.ScraperOptions{UserAgent: "preview-agent"})
+	assert.NoError(t, err)
+
+	var agents []string
+	for _, req := range rt.requests {
+		if req.URL.Path == "/search" || req.URL.Path == "/article" {
+			agents = append(agents, req.Header.Get("User-Agent"))
+		}
+	}
+	assert.Equal(t, []string{"search-agent", "preview-agent"}, agents)
+}
+
+func TestClientPreviewCache(t *testing.T) {
+	rt := newTestTransport(t)
+	client := New(WithTransport(rt), WithPreviewCache(link_preview.NewMemoryCache(10)), WithIgnoreRobots())
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		preview, err := client.Preview(ctx, "https://news.example.com/article")
+		assert.NoError(t, err)
+		assert.Equal(t, "Transit Budget Approved", preview.Title)
+	}
+
+	var fetches int
+	for _, req := range rt.requests {
+		if req.URL.Path == "/article" {
+			fetches++
+		}
+	}
+	assert.Equal(t, 1, fetches)
+}
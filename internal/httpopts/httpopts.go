@@ -0,0 +1,230 @@
+// Package httpopts holds the HTTP settings shared by the search and link_preview packages,
+// and builds their clients, so that both send requests the same way.
+package httpopts
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/propro-productions/go-utils/doh"
+	"github.com/propro-productions/go-utils/metrics"
+	"github.com/propro-productions/go-utils/proxy"
+)
+
+// Logger receives debug messages. search.Logger and link_preview.Logger have the same method.
+type Logger interface {
+	Debug(msg string, args ...any)
+}
+
+// Options are the settings the With functions set. A zero field leaves the setting of the
+// package applying the options unchanged.
+type Options struct {
+	UserAgent  string
+	Timeout    time.Duration
+	ProxyAddr  string
+	ProxyPool  *proxy.Pool
+	Resolver   *doh.Resolver
+	HTTPClient *http.Client
+	Logger     Logger
+	Metrics    metrics.Metrics
+}
+
+// Option sets one of the Options. search.Option and link_preview.Option are aliases of it,
+// so options can be built once and passed to both packages.
+type Option func(*Options)
+
+// Apply returns the options set by opts.
+func Apply(opts []Option) Options {
+	var o Options
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return o
+}
+
+// WithUserAgent sets the User-Agent sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(o *Options) { o.UserAgent = userAgent }
+}
+
+// WithTimeout bounds every request, including reading its body.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *Options) { o.Timeout = timeout }
+}
+
+// WithProxy sends every request through the proxy at proxyAddr.
+func WithProxy(proxyAddr string) Option {
+	return func(o *Options) { o.ProxyAddr = proxyAddr }
+}
+
+// WithProxyPool sends every request through the next proxy of pool.
+func WithProxyPool(pool *proxy.Pool) Option {
+	return func(o *Options) { o.ProxyPool = pool }
+}
+
+// WithResolver looks up the hosts requested, and the proxies, with resolver.
+func WithResolver(resolver *doh.Resolver) Option {
+	return func(o *Options) { o.Resolver = resolver }
+}
+
+// WithHTTPClient makes requests with client.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *Options) { o.HTTPClient = client }
+}
+
+// WithLogger sends debug messages about the requests made to logger.
+func WithLogger(logger Logger) Option {
+	return func(o *Options) { o.Logger = logger }
+}
+
+// WithMetrics reports the requests made to m.
+func WithMetrics(m metrics.Metrics) Option {
+	return func(o *Options) { o.Metrics = m }
+}
+
+// NewClient returns the client to make requests with: o.HTTPClient, or else a new client
+// with o.Timeout, sending requests through o.ProxyPool or o.ProxyAddr when set. The pool
+// takes precedence over the address. With o.Resolver, connections are dialed with its
+// DialContext, to the proxy when one is set.
+//
+// A provided client is never modified. When a proxy or resolver is set, its transport is
+// cloned, which fails for any transport but an *http.Transport. The clones of
+// http.DefaultTransport for a proxy address alone are shared by the clients returned for
+// it, up to maxSharedTransports addresses, so that clients created for every request still
+// reuse their connections. Any other clone belongs to the client returned.
+func NewClient(o Options) (*http.Client, error) {
+	client := &http.Client{Timeout: o.Timeout}
+	if o.HTTPClient != nil {
+		if o.ProxyAddr == "" && o.ProxyPool == nil && o.Resolver == nil {
+			return o.HTTPClient, nil
+		}
+		c := *o.HTTPClient
+		client = &c
+	}
+
+	option := "ProxyAddr"
+	switch {
+	case o.Resolver != nil:
+		option = "Resolver"
+	case o.ProxyPool != nil:
+		option = "ProxyPool"
+	case o.ProxyAddr == "":
+		return client, nil
+	}
+	base, err := baseTransport(client.Transport, option)
+	if err != nil {
+		return nil, err
+	}
+
+	if !Owned(o) {
+		client.Transport, err = sharedTransport(o.ProxyAddr)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+	client.Transport, err = ConfigureTransport(base.Clone(), o)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// Owned reports whether the client NewClient returns for o has a transport of its own,
+// which the caller should close the idle connections of once done with the client.
+func Owned(o Options) bool {
+	if o.Resolver != nil || o.ProxyPool != nil {
+		return true
+	}
+	if o.ProxyAddr == "" || o.HTTPClient == nil {
+		return false
+	}
+	return o.HTTPClient.Transport != nil && o.HTTPClient.Transport != http.DefaultTransport
+}
+
+// ConfigureTransport sets o.Resolver and o.ProxyAddr on transport, which is modified, and
+// returns it, or a RoundTripper sending its requests through o.ProxyPool when set. It is
+// for callers owning a transport of their own, which NewClient would clone.
+func ConfigureTransport(transport *http.Transport, o Options) (http.RoundTripper, error) {
+	if o.Resolver != nil {
+		transport.DialContext = o.Resolver.DialContext
+	}
+	switch {
+	case o.ProxyPool != nil:
+		return o.ProxyPool.Transport(transport), nil
+	case o.ProxyAddr != "":
+		proxyURL, err := url.Parse(o.ProxyAddr)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return transport, nil
+}
+
+// maxSharedTransports is how many proxy addresses NewClient keeps a transport for. The
+// transport of the address used least recently is closed and dropped for another one.
+const maxSharedTransports = 16
+
+// sharedTransports are the clones of http.DefaultTransport NewClient shares by proxy
+// address, the address used most recently first.
+var sharedTransports struct {
+	sync.Mutex
+	addrs      []string
+	transports map[string]*http.Transport
+}
+
+// sharedTransport returns the clone of http.DefaultTransport sending requests through the
+// proxy at proxyAddr.
+func sharedTransport(proxyAddr string) (*http.Transport, error) {
+	shared := &sharedTransports
+	shared.Lock()
+	defer shared.Unlock()
+
+	if transport, ok := shared.transports[proxyAddr]; ok {
+		for i, addr := range shared.addrs {
+			if addr == proxyAddr {
+				copy(shared.addrs[1:i+1], shared.addrs[:i])
+				shared.addrs[0] = proxyAddr
+				break
+			}
+		}
+		return transport, nil
+	}
+
+	rt, err := ConfigureTransport(http.DefaultTransport.(*http.Transport).Clone(), Options{ProxyAddr: proxyAddr})
+	if err != nil {
+		return nil, err
+	}
+	transport := rt.(*http.Transport)
+	if shared.transports == nil {
+		shared.transports = map[string]*http.Transport{}
+	}
+	if len(shared.addrs) == maxSharedTransports {
+		oldest := shared.addrs[len(shared.addrs)-1]
+		shared.transports[oldest].CloseIdleConnections()
+		delete(shared.transports, oldest)
+		shared.addrs = shared.addrs[:len(shared.addrs)-1]
+	}
+	shared.addrs = append([]string{proxyAddr}, shared.addrs...)
+	shared.transports[proxyAddr] = transport
+	return transport, nil
+}
+
+// baseTransport returns rt as the *http.Transport the option is applied to, or an error
+// for another kind of RoundTripper. nil means http.DefaultTransport.
+func baseTransport(rt http.RoundTripper, option string) (*http.Transport, error) {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	transport, ok := rt.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("cannot apply %s to transport of type %T, configure the proxy on the transport instead", option, rt)
+	}
+	return transport, nil
+}
@@ -0,0 +1,86 @@
+package httpopts
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/propro-productions/go-utils/doh"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClientSharesTransport(t *testing.T) {
+	first, err := NewClient(Options{ProxyAddr: "http://127.0.0.1:3128"})
+	assert.NoError(t, err)
+	second, err := NewClient(Options{ProxyAddr: "http://127.0.0.1:3128"})
+	assert.NoError(t, err)
+	other, err := NewClient(Options{ProxyAddr: "http://127.0.0.1:3129"})
+	assert.NoError(t, err)
+
+	assert.NotSame(t, first, second)
+	assert.Same(t, first.Transport, second.Transport)
+	assert.NotSame(t, first.Transport, other.Transport)
+	assert.NotSame(t, http.DefaultTransport, first.Transport)
+	assert.False(t, Owned(Options{ProxyAddr: "http://127.0.0.1:3128"}))
+	assert.False(t, Owned(Options{HTTPClient: &http.Client{Transport: &http.Transport{}}}))
+}
+
+func TestNewClientSharesTransportsUpToLimit(t *testing.T) {
+	first, err := NewClient(Options{ProxyAddr: "http://127.0.0.1:4000"})
+	assert.NoError(t, err)
+	for i := 1; i <= maxSharedTransports; i++ {
+		_, err := NewClient(Options{ProxyAddr: fmt.Sprintf("http://127.0.0.1:%d", 4000+i)})
+		assert.NoError(t, err)
+	}
+
+	sharedTransports.Lock()
+	assert.Len(t, sharedTransports.transports, maxSharedTransports)
+	assert.Len(t, sharedTransports.addrs, maxSharedTransports)
+	_, ok := sharedTransports.transports["http://127.0.0.1:4000"]
+	sharedTransports.Unlock()
+	assert.False(t, ok)
+
+	again, err := NewClient(Options{ProxyAddr: "http://127.0.0.1:4000"})
+	assert.NoError(t, err)
+	assert.NotSame(t, first.Transport, again.Transport)
+}
+
+func TestNewClientOwnTransportIsNotKept(t *testing.T) {
+	sharedTransports.Lock()
+	shared := len(sharedTransports.transports)
+	sharedTransports.Unlock()
+
+	resolver, err := doh.NewResolver("https://dns.example/dns-query")
+	assert.NoError(t, err)
+
+	// The transport of a provided client, or with a resolver, belongs to the client returned
+	collected := make(chan struct{}, 2)
+	for _, o := range []Options{
+		{ProxyAddr: "http://127.0.0.1:3128", HTTPClient: &http.Client{Transport: &http.Transport{}}},
+		{ProxyAddr: "http://127.0.0.1:3128", Resolver: resolver},
+	} {
+		assert.True(t, Owned(o))
+		client, err := NewClient(o)
+		assert.NoError(t, err)
+		runtime.SetFinalizer(client.Transport.(*http.Transport), func(*http.Transport) { collected <- struct{}{} })
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for n := 0; n < 2; {
+		runtime.GC()
+		select {
+		case <-collected:
+			n++
+		case <-time.After(10 * time.Millisecond):
+			if time.Now().After(deadline) {
+				t.Fatal("Transport of a discarded client was not collected")
+			}
+		}
+	}
+
+	sharedTransports.Lock()
+	assert.Len(t, sharedTransports.transports, shared)
+	sharedTransports.Unlock()
+}
@@ -0,0 +1,210 @@
+// Package langdetect tells the language of a text from its script and letter trigrams,
+// without network calls or cgo. It backs store.DetectLanguage and the language filter of
+// the search package.
+package langdetect
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// trigramProfileSize is how many of the most frequent trigrams make up a profile.
+const trigramProfileSize = 300
+
+// minDetectLetters is how many letters a text needs for its language to be detected.
+const minDetectLetters = 20
+
+// script is the writing system of a letter, as far as language detection tells them apart.
+type script int
+
+const (
+	scriptOther script = iota
+	scriptLatin
+	scriptCyrillic
+	scriptArabic
+	scriptDevanagari
+	scriptHangul
+	scriptKana
+	scriptHan
+)
+
+func scriptOf(r rune) script {
+	switch {
+	case r < 0x80 || unicode.Is(unicode.Latin, r):
+		return scriptLatin
+	case unicode.Is(unicode.Cyrillic, r):
+		return scriptCyrillic
+	case unicode.Is(unicode.Arabic, r):
+		return scriptArabic
+	case unicode.Is(unicode.Devanagari, r):
+		return scriptDevanagari
+	case unicode.Is(unicode.Hangul, r):
+		return scriptHangul
+	case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+		return scriptKana
+	case unicode.Is(unicode.Han, r):
+		return scriptHan
+	}
+	return scriptOther
+}
+
+// languageProfile is the trigram profile of the sample of a language.
+type languageProfile struct {
+	code   string
+	script script
+	ranks  map[string]int
+}
+
+var (
+	languageProfilesOnce sync.Once
+	languageProfiles     []languageProfile
+)
+
+// profiles returns the trigram profiles of languageSamples, sorted by language code.
+func profiles() []languageProfile {
+	languageProfilesOnce.Do(func() {
+		for code, sample := range languageSamples {
+			languageProfiles = append(languageProfiles, languageProfile{
+				code:   code,
+				script: dominantScript(scriptCounts(sample)),
+				ranks:  trigramRanks(sample),
+			})
+		}
+		sort.Slice(languageProfiles, func(i, j int) bool { return languageProfiles[i].code < languageProfiles[j].code })
+	})
+	return languageProfiles
+}
+
+// Detect returns the ISO 639-1 code of the language text is written in, or "" if it is too
+// short to tell or in none of the languages known: Arabic, Chinese, Dutch, English, French,
+// German, Hindi, Indonesian, Italian, Japanese, Korean, Persian, Polish, Portuguese,
+// Russian, Spanish, Swedish, Turkish, Ukrainian and Vietnamese.
+//
+// The script of the text settles the language where only one of them is written in it,
+// with a confidence of 1. Otherwise the frequencies of the letter trigrams of the text are
+// compared with those of a sample of every language written in that script, and the
+// closest one wins. The confidence is then how much closer it is than the runner-up, from
+// 0 for a tie to 1, so short texts of closely related languages get a low one.
+func Detect(text string) (lang string, confidence float64) {
+	counts := scriptCounts(text)
+	letters := 0
+	for _, n := range counts {
+		letters += n
+	}
+	if letters < minDetectLetters {
+		return "", 0
+	}
+
+	switch dominant := dominantScript(counts); dominant {
+	case scriptHangul:
+		return "ko", 1
+	case scriptDevanagari:
+		return "hi", 1
+	case scriptHan, scriptKana:
+		// Japanese mixes kana in with the characters it shares with Chinese
+		if counts[scriptKana]*10 >= counts[scriptKana]+counts[scriptHan] {
+			return "ja", 1
+		}
+		return "zh", 1
+	case scriptOther:
+		return "", 0
+	default:
+		return closestLanguage(trigramRanks(text), dominant)
+	}
+}
+
+// closestLanguage returns the language of the given script whose profile is closest to
+// ranks, by the sum of the differences of the ranks of every trigram, and how much closer
+// it is than the runner-up.
+func closestLanguage(ranks map[string]int, s script) (string, float64) {
+	best, bestDistance, second := "", -1, -1
+	for _, profile := range profiles() {
+		if profile.script != s {
+			continue
+		}
+		distance := 0
+		for trigram, rank := range ranks {
+			if other, ok := profile.ranks[trigram]; ok {
+				distance += abs(rank - other)
+			} else {
+				distance += trigramProfileSize
+			}
+		}
+		switch {
+		case bestDistance < 0 || distance < bestDistance:
+			best, bestDistance, second = profile.code, distance, bestDistance
+		case second < 0 || distance < second:
+			second = distance
+		}
+	}
+	if second <= 0 {
+		return best, 1
+	}
+	return best, float64(second-bestDistance) / float64(second)
+}
+
+// scriptCounts counts the letters of text by script.
+func scriptCounts(text string) map[script]int {
+	counts := map[script]int{}
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			counts[scriptOf(r)]++
+		}
+	}
+	return counts
+}
+
+// dominantScript returns the script with the most letters. Ties go to the first script.
+func dominantScript(counts map[script]int) script {
+	dominant := scriptOther
+	for s := scriptLatin; s <= scriptHan; s++ {
+		if counts[s] > counts[dominant] {
+			dominant = s
+		}
+	}
+	return dominant
+}
+
+// trigramRanks returns the rank of the trigramProfileSize most frequent trigrams of the
+// words of text, padded with a space on both sides. Trigrams as frequent as each other are
+// ranked in byte order.
+func trigramRanks(text string) map[string]int {
+	counts := map[string]int{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsMark(r)
+	}) {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			counts[string(runes[i:i+3])]++
+		}
+	}
+
+	trigrams := make([]string, 0, len(counts))
+	for trigram := range counts {
+		trigrams = append(trigrams, trigram)
+	}
+	sort.Slice(trigrams, func(i, j int) bool {
+		if counts[trigrams[i]] != counts[trigrams[j]] {
+			return counts[trigrams[i]] > counts[trigrams[j]]
+		}
+		return trigrams[i] < trigrams[j]
+	})
+	if len(trigrams) > trigramProfileSize {
+		trigrams = trigrams[:trigramProfileSize]
+	}
+
+	ranks := make(map[string]int, len(trigrams))
+	for i, trigram := range trigrams {
+		ranks[trigram] = i
+	}
+	return ranks
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,24 @@
+package langdetect
+
+// languageSamples are the texts the trigram profiles of Detect are built from, one
+// per language written in a script that several languages share. They say the same thing
+// in every language, so that the profiles differ by language rather than by topic.
+// Chinese, Japanese, Korean and Hindi are told apart by their script alone.
+var languageSamples = map[string]string{
+	"ar": `لقد غير التطور السريع للإنترنت الطريقة التي يعيش ويعمل بها الناس. يقضي معظمنا اليوم جزءا كبيرا من اليوم أمام الشاشة، وكثير من الأشياء التي كنا نقوم بها شخصيا في الماضي أصبحت تتم الآن عبر الإنترنت. هذا ليس أمرا سيئا دائما، ولكن من المهم أن نفكر فيما فقدناه وفيما كسبناه. عندما نقرأ الأخبار، يجب أن نسأل من أين تأتي المعلومات ومن كتبها. الأطفال الذين يكبرون مع هذه الأدوات يتعلمون بسرعة كبيرة، وسيكون لديهم مهارات لم يمتلكها آباؤهم أبدا. وفي الوقت نفسه، يجب أن نعلمهم كيف يستخدمونها بحذر، لأن هناك أيضا الكثير من المعلومات الخاطئة التي يصعب التعرف عليها.`,
+	"de": `Die schnelle Entwicklung des Internets hat die Art und Weise verändert, wie Menschen leben und arbeiten. Die meisten von uns verbringen heute einen großen Teil des Tages vor einem Bildschirm, und viele Dinge, die wir früher persönlich erledigt haben, werden jetzt online gemacht. Das ist nicht immer schlecht, aber es ist wichtig, darüber nachzudenken, was wir verloren und was wir gewonnen haben. Wenn wir die Nachrichten lesen, sollten wir fragen, woher die Informationen kommen und wer sie geschrieben hat. Kinder, die mit diesen Werkzeugen aufwachsen, lernen sehr schnell, und sie werden Fähigkeiten haben, die ihre Eltern nie hatten. Gleichzeitig müssen sie lernen, sie mit Vorsicht zu benutzen, weil es auch viele falsche Informationen gibt, die schwer zu erkennen sind.`,
+	"en": `The quick development of the internet has changed the way that people live and work. Most of us now spend a large part of the day in front of a screen, and many of the things we used to do in person are done online. This is not always a bad thing, but it is important to think about what we have lost and what we have gained. When we read the news, we should ask where the information comes from and who wrote it. Children who grow up with these tools learn very quickly, and they will have skills that their parents never had. At the same time, they need to be taught how to use them with care, because there is also a lot of false information out there which is hard to recognize.`,
+	"es": `El rápido desarrollo de internet ha cambiado la forma en que las personas viven y trabajan. La mayoría de nosotros pasamos hoy una gran parte del día delante de una pantalla, y muchas de las cosas que antes hacíamos en persona ahora se hacen en línea. Esto no siempre es algo malo, pero es importante pensar en lo que hemos perdido y en lo que hemos ganado. Cuando leemos las noticias, deberíamos preguntarnos de dónde viene la información y quién la escribió. Los niños que crecen con estas herramientas aprenden muy rápido, y tendrán habilidades que sus padres nunca tuvieron. Al mismo tiempo, hay que enseñarles a usarlas con cuidado, porque también hay mucha información falsa que es difícil de reconocer.`,
+	"fa": `پیشرفت سریع اینترنت شیوه زندگی و کار مردم را تغییر داده است. بیشتر ما امروز بخش بزرگی از روز را جلوی صفحه نمایش می‌گذرانیم، و بسیاری از کارهایی که قبلا به صورت حضوری انجام می‌دادیم اکنون به صورت آنلاین انجام می‌شود. این همیشه چیز بدی نیست، اما مهم است که به آنچه از دست داده‌ایم و آنچه به دست آورده‌ایم فکر کنیم. وقتی اخبار را می‌خوانیم، باید بپرسیم که این اطلاعات از کجا می‌آید و چه کسی آن را نوشته است. کودکانی که با این ابزارها بزرگ می‌شوند خیلی سریع یاد می‌گیرند، و مهارت‌هایی خواهند داشت که پدر و مادرشان هرگز نداشتند. در عین حال، باید به آنها یاد بدهیم که چگونه با دقت از آنها استفاده کنند، چون اطلاعات نادرست زیادی هم وجود دارد که تشخیص آنها سخت است.`,
+	"fr": `Le développement rapide d'internet a changé la manière dont les gens vivent et travaillent. La plupart d'entre nous passent aujourd'hui une grande partie de la journée devant un écran, et beaucoup de choses que nous faisions autrefois en personne se font maintenant en ligne. Ce n'est pas toujours une mauvaise chose, mais il est important de réfléchir à ce que nous avons perdu et à ce que nous avons gagné. Quand nous lisons les nouvelles, nous devrions nous demander d'où vient l'information et qui l'a écrite. Les enfants qui grandissent avec ces outils apprennent très vite, et ils auront des compétences que leurs parents n'ont jamais eues. En même temps, il faut leur apprendre à les utiliser avec prudence, parce qu'il y a aussi beaucoup de fausses informations qui sont difficiles à reconnaître.`,
+	"id": `Perkembangan internet yang cepat telah mengubah cara orang hidup dan bekerja. Sebagian besar dari kita sekarang menghabiskan banyak waktu setiap hari di depan layar, dan banyak hal yang dulu kita lakukan secara langsung sekarang dilakukan secara daring. Ini tidak selalu merupakan hal yang buruk, tetapi penting untuk memikirkan apa yang telah kita kehilangan dan apa yang telah kita dapatkan. Ketika kita membaca berita, kita harus bertanya dari mana informasi itu berasal dan siapa yang menulisnya. Anak-anak yang tumbuh dengan alat-alat ini belajar dengan sangat cepat, dan mereka akan memiliki keterampilan yang tidak pernah dimiliki oleh orang tua mereka. Pada saat yang sama, mereka perlu diajari untuk menggunakannya dengan hati-hati, karena ada juga banyak informasi palsu yang sulit untuk dikenali.`,
+	"it": `Il rapido sviluppo di internet ha cambiato il modo in cui le persone vivono e lavorano. La maggior parte di noi passa oggi una grande parte della giornata davanti a uno schermo, e molte delle cose che prima facevamo di persona adesso si fanno online. Questo non è sempre un male, ma è importante pensare a quello che abbiamo perso e a quello che abbiamo guadagnato. Quando leggiamo le notizie, dovremmo chiederci da dove viene l'informazione e chi l'ha scritta. I bambini che crescono con questi strumenti imparano molto velocemente, e avranno delle capacità che i loro genitori non hanno mai avuto. Allo stesso tempo, bisogna insegnare loro a usarli con attenzione, perché ci sono anche molte informazioni false che sono difficili da riconoscere.`,
+	"nl": `De snelle ontwikkeling van het internet heeft de manier veranderd waarop mensen leven en werken. De meesten van ons brengen tegenwoordig een groot deel van de dag door voor een scherm, en veel dingen die we vroeger persoonlijk deden, gebeuren nu online. Dat is niet altijd slecht, maar het is belangrijk om na te denken over wat we verloren hebben en wat we gewonnen hebben. Als we het nieuws lezen, moeten we ons afvragen waar de informatie vandaan komt en wie het geschreven heeft. Kinderen die met deze middelen opgroeien, leren heel snel, en zij zullen vaardigheden hebben die hun ouders nooit hadden. Tegelijkertijd moeten ze leren om er voorzichtig mee om te gaan, omdat er ook veel valse informatie is die moeilijk te herkennen is.`,
+	"pl": `Szybki rozwój internetu zmienił sposób, w jaki ludzie żyją i pracują. Większość z nas spędza dziś dużą część dnia przed ekranem, a wiele rzeczy, które kiedyś robiliśmy osobiście, załatwia się teraz przez sieć. Nie zawsze jest to coś złego, ale ważne jest, aby zastanowić się, co straciliśmy i co zyskaliśmy. Kiedy czytamy wiadomości, powinniśmy pytać, skąd pochodzi informacja i kto ją napisał. Dzieci, które dorastają z tymi narzędziami, uczą się bardzo szybko i będą miały umiejętności, których ich rodzice nigdy nie mieli. Jednocześnie trzeba je nauczyć, jak z nich korzystać ostrożnie, ponieważ jest też dużo fałszywych informacji, które są trudne do rozpoznania.`,
+	"pt": `O rápido desenvolvimento da internet mudou a forma como as pessoas vivem e trabalham. A maioria de nós passa hoje uma grande parte do dia em frente de uma tela, e muitas das coisas que antes fazíamos pessoalmente agora são feitas online. Isso nem sempre é uma coisa ruim, mas é importante pensar no que perdemos e no que ganhamos. Quando lemos as notícias, devemos perguntar de onde vem a informação e quem a escreveu. As crianças que crescem com essas ferramentas aprendem muito rápido, e terão habilidades que os seus pais nunca tiveram. Ao mesmo tempo, é preciso ensiná-las a usá-las com cuidado, porque também existe muita informação falsa que é difícil de reconhecer. Não é fácil, mas também não é impossível.`,
+	"ru": `Быстрое развитие интернета изменило то, как люди живут и работают. Большинство из нас сегодня проводит большую часть дня перед экраном, и многие вещи, которые раньше мы делали лично, теперь делаются через сеть. Это не всегда плохо, но важно подумать о том, что мы потеряли и что мы получили. Когда мы читаем новости, мы должны спрашивать, откуда пришла информация и кто её написал. Дети, которые растут с этими инструментами, учатся очень быстро, и у них будут навыки, которых никогда не было у их родителей. В то же время их нужно научить пользоваться ими осторожно, потому что есть также много ложной информации, которую трудно распознать.`,
+	"sv": `Den snabba utvecklingen av internet har förändrat sättet som människor lever och arbetar på. De flesta av oss tillbringar i dag en stor del av dagen framför en skärm, och många saker som vi tidigare gjorde personligen görs nu på nätet. Det är inte alltid något dåligt, men det är viktigt att tänka på vad vi har förlorat och vad vi har vunnit. När vi läser nyheterna borde vi fråga oss varifrån informationen kommer och vem som har skrivit den. Barn som växer upp med de här verktygen lär sig mycket snabbt, och de kommer att ha färdigheter som deras föräldrar aldrig hade. Samtidigt måste de lära sig att använda dem med försiktighet, eftersom det också finns mycket falsk information som är svår att känna igen.`,
+	"tr": `İnternetin hızlı gelişimi, insanların yaşama ve çalışma biçimini değiştirdi. Çoğumuz bugün günün büyük bir bölümünü bir ekranın önünde geçiriyoruz ve eskiden yüz yüze yaptığımız birçok şey artık internet üzerinden yapılıyor. Bu her zaman kötü bir şey değil, ama neyi kaybettiğimizi ve neyi kazandığımızı düşünmek önemlidir. Haberleri okurken bilginin nereden geldiğini ve onu kimin yazdığını sormalıyız. Bu araçlarla büyüyen çocuklar çok hızlı öğreniyorlar ve anne babalarının hiçbir zaman sahip olmadığı becerilere sahip olacaklar. Aynı zamanda onlara bu araçları dikkatli kullanmayı öğretmek gerekiyor, çünkü tanınması zor olan çok fazla yanlış bilgi de var.`,
+	"uk": `Швидкий розвиток інтернету змінив те, як люди живуть і працюють. Більшість із нас сьогодні проводить велику частину дня перед екраном, і багато речей, які раніше ми робили особисто, тепер робляться через мережу. Це не завжди погано, але важливо подумати про те, що ми втратили і що ми отримали. Коли ми читаємо новини, ми повинні запитувати, звідки прийшла інформація і хто її написав. Діти, які ростуть із цими інструментами, навчаються дуже швидко, і в них будуть навички, яких ніколи не мали їхні батьки. Водночас їх потрібно навчити користуватися ними обережно, тому що є також багато неправдивої інформації, яку важко розпізнати.`,
+	"vi": `Sự phát triển nhanh chóng của internet đã thay đổi cách mọi người sống và làm việc. Phần lớn chúng ta ngày nay dành một phần lớn thời gian trong ngày trước màn hình, và nhiều việc mà trước đây chúng ta làm trực tiếp thì bây giờ được làm trên mạng. Điều này không phải lúc nào cũng xấu, nhưng điều quan trọng là phải suy nghĩ về những gì chúng ta đã mất và những gì chúng ta đã có được. Khi đọc tin tức, chúng ta nên hỏi thông tin đến từ đâu và ai đã viết nó. Trẻ em lớn lên với những công cụ này học rất nhanh, và các em sẽ có những kỹ năng mà cha mẹ của các em chưa bao giờ có. Đồng thời, các em cần được dạy cách sử dụng chúng một cách cẩn thận, vì cũng có rất nhiều thông tin sai rất khó nhận ra.`,
+}
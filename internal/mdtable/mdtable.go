@@ -0,0 +1,64 @@
+// Package mdtable writes markdown tables. It is shared by the markdown converter and the
+// exporters of the search package, which cannot import each other.
+package mdtable
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Write writes rows as a table whose first row is the header. Columns are padded to the
+// width of their widest cell, and rows shorter than the longest one get empty cells.
+// Cells must be on a single line with "|" already escaped.
+func Write(w io.Writer, rows [][]string) {
+	if len(rows) == 0 {
+		return
+	}
+
+	maxcol := 0
+	for _, cols := range rows {
+		if len(cols) > maxcol {
+			maxcol = len(cols)
+		}
+	}
+	// The delimiter row needs at least three dashes per column.
+	widths := make([]int, maxcol)
+	for i := range widths {
+		widths[i] = 3
+	}
+	for _, cols := range rows {
+		for i := 0; i < maxcol; i++ {
+			if i < len(cols) {
+				width := runewidth.StringWidth(cols[i])
+				if widths[i] < width {
+					widths[i] = width
+				}
+			}
+		}
+	}
+	for i, cols := range rows {
+		for j := 0; j < maxcol; j++ {
+			fmt.Fprint(w, "| ")
+			if j < len(cols) {
+				width := runewidth.StringWidth(cols[j])
+				fmt.Fprint(w, cols[j])
+				fmt.Fprint(w, strings.Repeat(" ", widths[j]-width))
+			} else {
+				fmt.Fprint(w, strings.Repeat(" ", widths[j]))
+			}
+			fmt.Fprint(w, " ")
+		}
+		fmt.Fprint(w, "|\n")
+		if i == 0 {
+			for j := 0; j < maxcol; j++ {
+				fmt.Fprint(w, "| ")
+				fmt.Fprint(w, strings.Repeat("-", widths[j]))
+				fmt.Fprint(w, " ")
+			}
+			fmt.Fprint(w, "|\n")
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package link_preview
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"strings"
+)
+
+// applyAlternate fills the fields doc lacks from the AMP or canonical version of the page,
+// when the page has no og:image or no description. At most one extra page is fetched, and
+// that page's own canonical and AMP links are not followed, so that pages pointing at each
+// other cannot loop. A failed fetch leaves doc as it is.
+func (scraper *Scraper) applyAlternate(ctx context.Context, doc *Document) {
+	if !strings.HasPrefix(doc.contentType, "text/html") && doc.contentType != "application/xhtml+xml" {
+		return
+	}
+	if len(doc.images.meta) > 0 && doc.Metadata.Description != "" {
+		return
+	}
+	alternate := scraper.alternateURL(doc.Metadata)
+	if alternate == nil || scraper.MaxRedirect <= 0 {
+		return
+	}
+	scraper.MaxRedirect--
+
+	// getDocument requests scraper.Url and records it in RedirectChain, while the scraper and
+	// its chain must keep describing the page
+	previous, previousFragment, chain := scraper.Url, scraper.EscapedFragmentUrl, len(scraper.RedirectChain)
+	scraper.Url, scraper.EscapedFragmentUrl = alternate, nil
+	adoc, err := scraper.getDocument(ctx)
+	fetched := scraper.Url
+	scraper.Url, scraper.EscapedFragmentUrl = previous, previousFragment
+	scraper.RedirectChain = scraper.RedirectChain[:chain]
+	if err != nil {
+		scraper.logger().Debug("alternate failed", "url", alternate.String(), "err", err)
+		return
+	}
+	if !strings.HasPrefix(adoc.contentType, "text/html") && adoc.contentType != "application/xhtml+xml" {
+		return
+	}
+	metadata, images, err := parsePreview(bytes.NewReader(adoc.Body.Bytes()), fetched)
+	if err != nil {
+		scraper.logger().Debug("alternate failed", "url", fetched.String(), "err", err)
+		return
+	}
+
+	mergePreview(&doc.Metadata, metadata, len(doc.images.meta) == 0 && len(images.meta) > 0)
+	doc.images.meta = append(doc.images.meta, images.meta...)
+	doc.images.page = append(doc.images.page, images.page...)
+}
+
+// alternateURL returns the AMP or else the canonical URL of the page described by p, if it
+// points to another page that has not been requested yet.
+func (scraper *Scraper) alternateURL(p Preview) *url.URL {
+	for _, ref := range []string{p.AMPURL, p.CanonicalURL} {
+		if ref == "" || ref == p.URL || scraper.visited(ref) {
+			continue
+		}
+		u, err := url.Parse(ref)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		return u
+	}
+	return nil
+}
+
+// mergePreview fills the empty fields of p from alternate and records their source in
+// p.FieldSources. With preferImage the image of alternate, an og:image, replaces the
+// image p fell back to.
+func mergePreview(p *Preview, alternate Preview, preferImage bool) {
+	fill := func(name string, field *string, value string) {
+		if *field == "" && value != "" {
+			*field = value
+			p.setSource(name, alternate.URL)
+		}
+	}
+	fill("Title", &p.Title, alternate.Title)
+	fill("Description", &p.Description, alternate.Description)
+	fill("SiteName", &p.SiteName, alternate.SiteName)
+	fill("Type", &p.Type, alternate.Type)
+	fill("TwitterCard", &p.TwitterCard, alternate.TwitterCard)
+	fill("TwitterImage", &p.TwitterImage, alternate.TwitterImage)
+	fill("OEmbedURL", &p.OEmbedURL, alternate.OEmbedURL)
+
+	if alternate.Image != "" && (p.Image == "" || preferImage) {
+		p.Image, p.ImageWidth, p.ImageHeight = alternate.Image, alternate.ImageWidth, alternate.ImageHeight
+		p.setSource("Image", alternate.URL)
+	}
+}
+
+func (p *Preview) setSource(field, url string) {
+	if p.FieldSources == nil {
+		p.FieldSources = map[string]string{}
+	}
+	p.FieldSources[field] = url
+}
@@ -0,0 +1,138 @@
+package link_preview
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// alternateServer serves pages by path and counts the requests for each
+type alternateServer struct {
+	mu       sync.Mutex
+	pages    map[string]string
+	requests map[string]int
+}
+
+func (s *alternateServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests[r.URL.Path]++
+	s.mu.Unlock()
+	page, ok := s.pages[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	w.Write([]byte(page))
+}
+
+func newAlternateServer(pages map[string]string) *alternateServer {
+	return &alternateServer{pages: pages, requests: map[string]int{}}
+}
+
+func TestFetchAlternateAMP(t *testing.T) {
+	pages := newAlternateServer(map[string]string{
+		"/article": `<html><head><title>Full article</title>
+<link rel="amphtml" href="/article/amp"></head><body><p>Text</p></body></html>`,
+		"/article/amp": `<html><head><title>AMP article</title>
+<meta property="og:description" content="The AMP description">
+<meta property="og:image" content="/cover.jpg">
+<meta property="og:image:width" content="1200">
+<link rel="canonical" href="/article"></head><body></body></html>`,
+	})
+	server := createMockServer(pages.ServeHTTP)
+	defer server.Close()
+
+	scraper, err := NewScraper(server.URL + "/article")
+	assert.NoError(t, err)
+	scraper.FetchAlternate = true
+	doc, err := scraper.GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/article", doc.Metadata.URL)
+	assert.Equal(t, server.URL+"/article/amp", doc.Metadata.AMPURL)
+	assert.Equal(t, "Full article", doc.Metadata.Title)
+	assert.Equal(t, "The AMP description", doc.Metadata.Description)
+	assert.Equal(t, server.URL+"/cover.jpg", doc.Metadata.Image)
+	assert.Equal(t, 1200, doc.Metadata.ImageWidth)
+	assert.Equal(t, map[string]string{
+		"Description": server.URL + "/article/amp",
+		"Image":       server.URL + "/article/amp",
+	}, doc.Metadata.FieldSources)
+	assert.Equal(t, []string{server.URL + "/article"}, doc.RedirectChain)
+	assert.Equal(t, 1, pages.requests["/article/amp"])
+}
+
+func TestFetchAlternateLoop(t *testing.T) {
+	pages := newAlternateServer(map[string]string{
+		"/a": `<html><head><title>A</title><link rel="amphtml" href="/b"></head><body></body></html>`,
+		"/b": `<html><head><title>B</title><link rel="amphtml" href="/a"><link rel="canonical" href="/a"></head><body></body></html>`,
+	})
+	server := createMockServer(pages.ServeHTTP)
+	defer server.Close()
+
+	scraper, err := NewScraper(server.URL + "/a")
+	assert.NoError(t, err)
+	scraper.FetchAlternate = true
+	doc, err := scraper.GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "A", doc.Metadata.Title)
+	assert.Empty(t, doc.Metadata.FieldSources)
+	assert.Equal(t, 1, pages.requests["/a"])
+	assert.Equal(t, 1, pages.requests["/b"])
+}
+
+func TestFetchAlternateDisabled(t *testing.T) {
+	pages := newAlternateServer(map[string]string{
+		"/a": `<html><head><title>A</title><link rel="amphtml" href="/b"></head><body></body></html>`,
+		"/b": `<html><head><meta name="description" content="B"></head><body></body></html>`,
+	})
+	server := createMockServer(pages.ServeHTTP)
+	defer server.Close()
+
+	doc, err := GetLinkPreviewItems(server.URL+"/a", 10)
+
+	assert.NoError(t, err)
+	assert.Empty(t, doc.Metadata.Description)
+	assert.Zero(t, pages.requests["/b"])
+}
+
+func TestFetchAlternateRedirectBudget(t *testing.T) {
+	pages := newAlternateServer(map[string]string{
+		"/a": `<html><head><title>A</title><link rel="amphtml" href="/b"></head><body></body></html>`,
+		"/b": `<html><head><meta name="description" content="B"></head><body></body></html>`,
+	})
+	server := createMockServer(pages.ServeHTTP)
+	defer server.Close()
+
+	scraper, err := NewScraper(server.URL + "/a")
+	assert.NoError(t, err)
+	scraper.MaxRedirect = 0
+	scraper.FetchAlternate = true
+	doc, err := scraper.GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.Empty(t, doc.Metadata.Description)
+	assert.Zero(t, pages.requests["/b"])
+}
+
+func TestFetchAlternateFailure(t *testing.T) {
+	pages := newAlternateServer(map[string]string{
+		"/a": `<html><head><title>A</title><link rel="amphtml" href="/missing"></head><body></body></html>`,
+	})
+	server := createMockServer(pages.ServeHTTP)
+	defer server.Close()
+
+	scraper, err := NewScraper(server.URL + "/a")
+	assert.NoError(t, err)
+	scraper.FetchAlternate = true
+	doc, err := scraper.GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "A", doc.Metadata.Title)
+	assert.Equal(t, server.URL+"/a", doc.Metadata.URL)
+	assert.Equal(t, 1, pages.requests["/missing"])
+}
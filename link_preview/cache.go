@@ -0,0 +1,359 @@
+package link_preview
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/propro-productions/go-utils/doh"
+	"github.com/propro-productions/go-utils/fetch"
+	"github.com/propro-productions/go-utils/metrics"
+	"github.com/propro-productions/go-utils/proxy"
+)
+
+const (
+	// DefaultCacheTTL is how long a preview is cached by a CachedPreviewer.
+	DefaultCacheTTL = time.Hour
+
+	// DefaultErrorTTL is how long a failed preview is cached by a CachedPreviewer.
+	DefaultErrorTTL = time.Minute
+)
+
+// CacheEntry is a cached preview, or the error previewing the URL failed with.
+type CacheEntry struct {
+	Preview Preview
+	Err     error
+}
+
+// PreviewCache stores previews by URL. Implementations must be safe for concurrent use.
+type PreviewCache interface {
+
+	// Get returns the entry stored for key, if it exists and has not expired.
+	Get(key string) (CacheEntry, bool)
+
+	// Set stores entry for key for the duration of ttl.
+	Set(key string, entry CacheEntry, ttl time.Duration)
+}
+
+// ScraperOptions configures the scrapers created by a Previewer or a CachedPreviewer.
+// The fields have the same meaning as the fields of Scraper.
+type ScraperOptions struct {
+	MaxRedirect           int
+	MaxRefreshDelay       time.Duration
+	IgnoreRobots          bool
+	Limiter               Limiter
+	Timeout               time.Duration
+	RequestTimeout        time.Duration
+	MaxBodySize           int64
+	ValidateImages        bool
+	FetchAlternate        bool
+	MaxFaviconSize        int
+	InlineFavicon         bool
+	MaxInlineFaviconBytes int64
+	MinImageBytes         int64
+	UserAgent             string
+	Headers               map[string]string
+	CookieJar             http.CookieJar
+	ProxyAddr             string
+	ProxyPool             *proxy.Pool
+	Resolver              *doh.Resolver
+	HTTPClient            *http.Client
+	Logger                Logger
+	Metrics               metrics.Metrics
+	RenderJS              bool
+	Renderer              fetch.Fetcher
+	ForceRefresh          bool
+}
+
+// Scraper returns a Scraper for rawURL configured by opts.
+func (opts ScraperOptions) Scraper(rawURL string) (*Scraper, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return opts.scraper(u), nil
+}
+
+func (opts ScraperOptions) scraper(u *url.URL) *Scraper {
+	return &Scraper{
+		Url:                   u,
+		MaxRedirect:           opts.MaxRedirect,
+		MaxRefreshDelay:       opts.MaxRefreshDelay,
+		IgnoreRobots:          opts.IgnoreRobots,
+		Limiter:               opts.Limiter,
+		Timeout:               opts.Timeout,
+		RequestTimeout:        opts.RequestTimeout,
+		MaxBodySize:           opts.MaxBodySize,
+		ValidateImages:        opts.ValidateImages,
+		FetchAlternate:        opts.FetchAlternate,
+		MaxFaviconSize:        opts.MaxFaviconSize,
+		InlineFavicon:         opts.InlineFavicon,
+		MaxInlineFaviconBytes: opts.MaxInlineFaviconBytes,
+		MinImageBytes:         opts.MinImageBytes,
+		UserAgent:             opts.UserAgent,
+		Headers:               opts.Headers,
+		CookieJar:             opts.CookieJar,
+		ProxyAddr:             opts.ProxyAddr,
+		ProxyPool:             opts.ProxyPool,
+		Resolver:              opts.Resolver,
+		HTTPClient:            opts.HTTPClient,
+		Logger:                opts.Logger,
+		Metrics:               opts.Metrics,
+		RenderJS:              opts.RenderJS,
+		Renderer:              opts.Renderer,
+		ForceRefresh:          opts.ForceRefresh,
+	}
+}
+
+// CachedPreviewer previews URLs through a PreviewCache. Concurrent calls for the same URL
+// share a single fetch. It is safe for concurrent use.
+type CachedPreviewer struct {
+
+	// TTL is how long a preview is cached. Default: DefaultCacheTTL.
+	TTL time.Duration
+
+	// ErrorTTL is how long a failure is cached, so that dead hosts are not retried on
+	// every call. A negative value disables caching of failures. Default: DefaultErrorTTL.
+	ErrorTTL time.Duration
+
+	cache PreviewCache
+	opts  ScraperOptions
+
+	// previewer creates the scrapers, sharing its client. It is nil if the client could not
+	// be created, the scrapers then failing with the same error.
+	previewer *Previewer
+
+	mu    sync.Mutex
+	calls map[string]*previewCall
+}
+
+// previewCall is a fetch in progress that other callers wait for.
+type previewCall struct {
+	done  chan struct{}
+	entry CacheEntry
+
+	// canceled reports that the caller fetching gave up, so that entry says nothing about
+	// the URL to the callers waiting.
+	canceled bool
+}
+
+// NewCachedPreviewer returns a CachedPreviewer that stores previews in cache and fetches
+// them with scrapers configured by opts, sharing a client like the scrapers of a
+// Previewer. Unlike a Previewer, it runs the site rules registered at the time of each
+// preview.
+func NewCachedPreviewer(cache PreviewCache, opts ScraperOptions) *CachedPreviewer {
+	previewer, err := newPreviewer(opts)
+	if err == nil {
+		previewer.rules = nil
+	} else {
+		previewer = nil
+	}
+	return &CachedPreviewer{cache: cache, opts: opts, calls: map[string]*previewCall{}, previewer: previewer}
+}
+
+// Preview returns the preview of link from the cache, or fetches and caches it.
+// Cancellation of ctx is not cached.
+func (p *CachedPreviewer) Preview(ctx context.Context, link string) (Preview, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return Preview{}, err
+	}
+	key := u.String()
+
+	if entry, ok := p.cache.Get(key); ok {
+		p.count(metrics.CacheHits)
+		return entry.Preview, entry.Err
+	}
+	p.count(metrics.CacheMisses)
+	return p.fetch(ctx, u, nil)
+}
+
+// Refresh fetches the preview of link again and caches it, for callers keeping previews up
+// to date on a schedule. When a preview of link is cached, it is revalidated: it is returned
+// as is while fresh by the Cache-Control max-age of the page, unless ForceRefresh is set in
+// the ScraperOptions, and otherwise requested with its ETag and Last-Modified, so that an
+// unchanged page is not downloaded and parsed again.
+func (p *CachedPreviewer) Refresh(ctx context.Context, link string) (Preview, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return Preview{}, err
+	}
+
+	var previous *Preview
+	if entry, ok := p.cache.Get(u.String()); ok && entry.Err == nil {
+		previous = &entry.Preview
+	}
+	return p.fetch(ctx, u, previous)
+}
+
+// fetch previews u, revalidating previous if set, and caches the result. Concurrent calls
+// for the same URL share a single fetch.
+func (p *CachedPreviewer) fetch(ctx context.Context, u *url.URL, previous *Preview) (Preview, error) {
+	key := u.String()
+
+	p.mu.Lock()
+	for {
+		call, ok := p.calls[key]
+		if !ok {
+			break
+		}
+		p.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return Preview{}, ctx.Err()
+		}
+		// Fetch again rather than fail with the cancellation of another caller
+		if !call.canceled || ctx.Err() != nil {
+			return call.entry.Preview, call.entry.Err
+		}
+		p.mu.Lock()
+	}
+	call := &previewCall{done: make(chan struct{})}
+	p.calls[key] = call
+	p.mu.Unlock()
+
+	scraper := p.scraper(u)
+	scraper.Previous = previous
+	doc, err := scraper.GetLinkPreviewItemsContext(ctx)
+	if err != nil {
+		call.entry.Err = err
+	} else {
+		call.entry.Preview = doc.Metadata
+	}
+	// A failure caused by the caller giving up says nothing about the URL.
+	call.canceled = ctx.Err() != nil
+	if !call.canceled {
+		p.store(key, call.entry)
+	}
+
+	p.mu.Lock()
+	delete(p.calls, key)
+	p.mu.Unlock()
+	close(call.done)
+
+	return call.entry.Preview, call.entry.Err
+}
+
+// CloseIdleConnections closes the connections kept alive by the client shared by the
+// scrapers of the CachedPreviewer.
+func (p *CachedPreviewer) CloseIdleConnections() {
+	if p.previewer != nil {
+		p.previewer.CloseIdleConnections()
+	}
+}
+
+func (p *CachedPreviewer) scraper(u *url.URL) *Scraper {
+	if p.previewer != nil {
+		return p.previewer.scraper(u)
+	}
+	return p.opts.scraper(u)
+}
+
+// count counts a lookup of the cache as name, metrics.CacheHits or metrics.CacheMisses.
+func (p *CachedPreviewer) count(name string) {
+	if p.opts.Metrics != nil {
+		metrics.Count(p.opts.Metrics, name, 1, "cache", "preview")
+	}
+}
+
+func (p *CachedPreviewer) store(key string, entry CacheEntry) {
+	if entry.Err == nil {
+		ttl := p.TTL
+		if ttl <= 0 {
+			ttl = DefaultCacheTTL
+		}
+		p.cache.Set(key, entry, ttl)
+		return
+	}
+
+	if p.ErrorTTL < 0 {
+		return
+	}
+	ttl := p.ErrorTTL
+	if ttl == 0 {
+		ttl = DefaultErrorTTL
+	}
+	p.cache.Set(key, entry, ttl)
+}
+
+// MemoryCache is an in-memory PreviewCache that evicts the least recently used entry once
+// it holds its maximum number of entries. It is safe for concurrent use.
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	entries    map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key     string
+	entry   CacheEntry
+	expires time.Time
+}
+
+// NewMemoryCache returns a MemoryCache holding at most maxEntries previews.
+// A maxEntries of 0 means no limit.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+// Get returns the entry stored for key, if it exists and has not expired.
+func (c *MemoryCache) Get(key string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return CacheEntry{}, false
+	}
+	e := el.Value.(*memoryEntry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		c.remove(el)
+		return CacheEntry{}, false
+	}
+	c.ll.MoveToFront(el)
+	return e.entry, true
+}
+
+// Set stores entry for key. A ttl of 0 or less means the entry never expires.
+func (c *MemoryCache) Set(key string, entry CacheEntry, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+
+	if el, ok := c.entries[key]; ok {
+		c.ll.MoveToFront(el)
+		e := el.Value.(*memoryEntry)
+		e.entry, e.expires = entry, expires
+		return
+	}
+
+	c.entries[key] = c.ll.PushFront(&memoryEntry{key: key, entry: entry, expires: expires})
+	if c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		c.remove(c.ll.Back())
+	}
+}
+
+// Len returns the number of cached entries, including expired ones not yet evicted.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *MemoryCache) remove(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.entries, el.Value.(*memoryEntry).key)
+}
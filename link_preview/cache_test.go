@@ -0,0 +1,214 @@
+package link_preview
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryCacheEviction(t *testing.T) {
+	c := NewMemoryCache(2)
+	c.Set("a", CacheEntry{Preview: Preview{Title: "a"}}, time.Minute)
+	c.Set("b", CacheEntry{Preview: Preview{Title: "b"}}, time.Minute)
+	c.Get("a")
+	c.Set("c", CacheEntry{Preview: Preview{Title: "c"}}, time.Minute)
+
+	_, ok := c.Get("b")
+	assert.False(t, ok)
+	entry, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "a", entry.Preview.Title)
+	assert.Equal(t, 2, c.Len())
+
+	c.Set("d", CacheEntry{}, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	_, ok = c.Get("d")
+	assert.False(t, ok)
+}
+
+func TestCachedPreviewerCoalesces(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&requests, 1)
+		<-release
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Shared</title></head></html>`))
+	})
+	defer server.Close()
+
+	previewer := NewCachedPreviewer(NewMemoryCache(10), ScraperOptions{MaxRedirect: 10})
+
+	var wg sync.WaitGroup
+	titles := make([]string, 10)
+	for i := range titles {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p, err := previewer.Preview(context.Background(), server.URL+"/page")
+			assert.NoError(t, err)
+			titles[i] = p.Title
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	p, err := previewer.Preview(context.Background(), server.URL+"/page")
+	assert.NoError(t, err)
+	assert.Equal(t, "Shared", p.Title)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	for _, title := range titles {
+		assert.Equal(t, "Shared", title)
+	}
+}
+
+func TestCachedPreviewerCachesErrors(t *testing.T) {
+	var requests int32
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/pdf")
+	})
+	defer server.Close()
+
+	previewer := NewCachedPreviewer(NewMemoryCache(10), ScraperOptions{MaxRedirect: 10})
+	previewer.ErrorTTL = 20 * time.Millisecond
+
+	for i := 0; i < 3; i++ {
+		_, err := previewer.Preview(context.Background(), server.URL+"/doc.pdf")
+		assert.ErrorIs(t, err, ErrNotHTML)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	time.Sleep(30 * time.Millisecond)
+	_, err := previewer.Preview(context.Background(), server.URL+"/doc.pdf")
+	assert.ErrorIs(t, err, ErrNotHTML)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestCachedPreviewerSkipsCancelled(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Page</title></head></html>`))
+	})
+	defer server.Close()
+
+	cache := NewMemoryCache(10)
+	previewer := NewCachedPreviewer(cache, ScraperOptions{MaxRedirect: 10})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := previewer.Preview(ctx, server.URL)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestCachedPreviewerRetriesCancelledFetch(t *testing.T) {
+	var requests int32
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		if atomic.AddInt32(&requests, 1) == 1 {
+			started <- struct{}{}
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Page</title></head></html>`))
+	})
+	defer server.Close()
+	defer close(release)
+
+	previewer := NewCachedPreviewer(NewMemoryCache(10), ScraperOptions{MaxRedirect: 10})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := previewer.Preview(ctx, server.URL+"/page")
+		leaderErr <- err
+	}()
+	<-started
+
+	waiter := make(chan Preview, 1)
+	go func() {
+		p, err := previewer.Preview(context.Background(), server.URL+"/page")
+		assert.NoError(t, err)
+		waiter <- p
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	assert.ErrorIs(t, <-leaderErr, context.Canceled)
+	assert.Equal(t, "Page", (<-waiter).Title)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+// recordingMetrics records the counts and timings it receives by name and labels.
+type recordingMetrics struct {
+	mu     sync.Mutex
+	counts map[string]int64
+	timed  map[string]int
+}
+
+func (m *recordingMetrics) Count(name string, delta int64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[fmt.Sprint(name, labels)] += delta
+}
+
+func (m *recordingMetrics) Timing(name string, d time.Duration, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timed[fmt.Sprint(name, labels)]++
+}
+
+func TestCachedPreviewerMetrics(t *testing.T) {
+	body := `<html><head><title>Page</title></head></html>`
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(body))
+	})
+	defer server.Close()
+
+	m := &recordingMetrics{counts: map[string]int64{}, timed: map[string]int{}}
+	previewer := NewCachedPreviewer(NewMemoryCache(10), ScraperOptions{MaxRedirect: 10, Metrics: m})
+
+	for i := 0; i < 2; i++ {
+		_, err := previewer.Preview(context.Background(), server.URL+"/page")
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, map[string]int64{
+		"cache.hits[cache preview]":   1,
+		"cache.misses[cache preview]": 1,
+		"preview.bytes[]":             int64(len(body)),
+	}, m.counts)
+	assert.Equal(t, map[string]int{"preview.duration[status ok]": 1}, m.timed)
+}
@@ -0,0 +1,138 @@
+package link_preview
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/saintfish/chardet"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// maxCharsetPrescan is how much of a page is searched for a <meta> charset declaration.
+// Browsers stop at 1024 bytes, but many pages declare it later in the head.
+const maxCharsetPrescan = 64 << 10
+
+// determineEncoding picks the encoding of an HTML page. In order of precedence it uses a
+// byte order mark, the charset of the Content-Type header, a <meta charset> or http-equiv
+// declaration, and finally sniffs the content.
+func determineEncoding(content []byte, contentType string) encoding.Encoding {
+	if e := bomEncoding(content); e != nil {
+		return e
+	}
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		if e, _ := charset.Lookup(params["charset"]); e != nil {
+			return e
+		}
+	}
+	if e := metaEncoding(content); e != nil {
+		return e
+	}
+	return sniffEncoding(content)
+}
+
+func bomEncoding(content []byte) encoding.Encoding {
+	switch {
+	case bytes.HasPrefix(content, []byte("\xef\xbb\xbf")):
+		return unicode.UTF8
+	case bytes.HasPrefix(content, []byte("\xfe\xff")):
+		return unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	case bytes.HasPrefix(content, []byte("\xff\xfe")):
+		return unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	}
+	return nil
+}
+
+// metaEncoding returns the encoding declared by a <meta> tag in the head of content.
+func metaEncoding(content []byte) encoding.Encoding {
+	if len(content) > maxCharsetPrescan {
+		content = content[:maxCharsetPrescan]
+	}
+
+	t := html.NewTokenizer(bytes.NewReader(content))
+	for {
+		switch t.Next() {
+		case html.ErrorToken:
+			return nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := t.Token()
+			switch token.Data {
+			case "body":
+				return nil
+			case "meta":
+				if e := metaTagEncoding(token); e != nil {
+					return e
+				}
+			}
+		}
+	}
+}
+
+func metaTagEncoding(token html.Token) encoding.Encoding {
+	var httpEquiv, content, label string
+	for _, attr := range token.Attr {
+		switch cleanStr(attr.Key) {
+		case "charset":
+			label = attr.Val
+		case "http-equiv":
+			httpEquiv = cleanStr(attr.Val)
+		case "content":
+			content = attr.Val
+		}
+	}
+	if label == "" && httpEquiv == "content-type" {
+		if _, params, err := mime.ParseMediaType(content); err == nil {
+			label = params["charset"]
+		}
+	}
+	if label == "" {
+		return nil
+	}
+
+	e, name := charset.Lookup(label)
+	// A page that could be read to find the tag cannot be in UTF-16; see the HTML spec.
+	if strings.HasPrefix(name, "utf-16") {
+		return unicode.UTF8
+	}
+	return e
+}
+
+// sniffEncoding guesses the encoding of a page without a declared charset.
+func sniffEncoding(content []byte) encoding.Encoding {
+	if utf8.Valid(content) {
+		return unicode.UTF8
+	}
+	if result, err := chardet.NewHtmlDetector().DetectBest(content); err == nil {
+		if e, _ := charset.Lookup(result.Charset); e != nil {
+			return e
+		}
+	}
+	return charmap.Windows1252
+}
+
+func convertUTF8(content io.Reader, contentType string) (bytes.Buffer, error) {
+	buff := bytes.Buffer{}
+	raw, err := io.ReadAll(content)
+	if err != nil {
+		return buff, err
+	}
+
+	e := determineEncoding(raw, contentType)
+	if e == unicode.UTF8 {
+		// Drop the byte order mark, the content is used as is.
+		buff.Write(bytes.TrimPrefix(raw, []byte("\xef\xbb\xbf")))
+		return buff, nil
+	}
+
+	_, err = io.Copy(&buff, e.NewDecoder().Reader(bytes.NewReader(raw)))
+	if err != nil {
+		return buff, err
+	}
+	return buff, nil
+}
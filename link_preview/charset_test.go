@@ -0,0 +1,63 @@
+package link_preview
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestGetLinkPreviewItemsCharsets(t *testing.T) {
+	const (
+		russianTitle       = "Новости технологий — главная страница"
+		russianDescription = "Последние новости о программировании, языке Go и разработке программного обеспечения в России и мире."
+	)
+	tests := []struct {
+		name        string
+		fixture     string
+		contentType string
+		title       string
+		description string
+	}{
+		{"header charset", "windows1251.html", "text/html; charset=windows-1251", russianTitle, russianDescription},
+		{"sniffed", "windows1251.html", "text/html", russianTitle, russianDescription},
+		{"meta http-equiv", "shift_jis.html", "text/html", "技術ニュース｜トップページ", "プログラミング言語Goとソフトウェア開発に関する最新のニュースをお届けします。"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			assert.NoError(t, err)
+			server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write(content)
+			})
+			defer server.Close()
+
+			doc, err := GetLinkPreviewItems(server.URL, 10)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.title, doc.Metadata.Title)
+			assert.Equal(t, tt.description, doc.Metadata.Description)
+			assert.Equal(t, tt.title, doc.Preview.Title)
+		})
+	}
+}
+
+func TestDetermineEncoding(t *testing.T) {
+	decode := func(content []byte, contentType string) string {
+		out, err := determineEncoding(content, contentType).NewDecoder().Bytes(content)
+		assert.NoError(t, err)
+		return string(out)
+	}
+
+	assert.Equal(t, unicode.UTF8, determineEncoding([]byte("\xef\xbb\xbf<p>hi</p>"), "text/html; charset=windows-1251"))
+	assert.Equal(t, "<p>Го</p>", decode([]byte("<p>\xc3\xee</p>"), "text/html; charset=windows-1251"))
+	assert.Equal(t, `<meta charset="shift_jis"><p>技術</p>`, decode([]byte("<meta charset=\"shift_jis\"><p>\x8bZ\x8fp</p>"), "text/html"))
+	assert.Equal(t, unicode.UTF8, determineEncoding([]byte(`<meta charset="utf-16"><p>hi</p>`), "text/html"))
+	assert.Equal(t, unicode.UTF8, determineEncoding([]byte("<p>héllo</p>"), "text/html"))
+	assert.Nil(t, metaEncoding([]byte(`<body><meta charset="shift_jis"></body>`)))
+}
@@ -0,0 +1,85 @@
+package link_preview
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fresh reports whether p may be used at now without asking the server, its Cache-Control
+// max-age not having passed.
+func (p *Preview) fresh(now time.Time) bool {
+	return !p.Expires.IsZero() && now.Before(p.Expires)
+}
+
+// conditionalHeader returns the If-None-Match and If-Modified-Since headers to request
+// rawURL with, from the validators of Scraper.Previous, or nil.
+func (scraper *Scraper) conditionalHeader(rawURL string) http.Header {
+	p := scraper.Previous
+	if p == nil || p.URL != "" && p.URL != rawURL {
+		return nil
+	}
+	header := http.Header{}
+	if p.ETag != "" {
+		header.Set("If-None-Match", p.ETag)
+	}
+	if p.LastModified != "" {
+		header.Set("If-Modified-Since", p.LastModified)
+	}
+	if len(header) == 0 {
+		return nil
+	}
+	return header
+}
+
+// notModified returns Scraper.Previous marked as revalidated by a 304 response with header,
+// received at now.
+func (scraper *Scraper) notModified(header http.Header, now time.Time) *Document {
+	p := *scraper.Previous
+	p.Revalidated = true
+	setCaching(&p, header, now)
+	scraper.logger().Debug("not modified", "url", p.URL)
+	return &Document{Preview: DocumentPreview{Link: p.URL}, Metadata: p, contentType: p.ContentType, notModified: true}
+}
+
+// setCaching sets the caching fields of p from the header of the response for it, received
+// at now. The validators p has are kept when the response does not repeat them.
+func setCaching(p *Preview, header http.Header, now time.Time) {
+	if etag := header.Get("ETag"); etag != "" {
+		p.ETag = etag
+	}
+	if lastModified := header.Get("Last-Modified"); lastModified != "" {
+		p.LastModified = lastModified
+	}
+	p.FetchedAt = now
+	p.Expires = time.Time{}
+	if maxAge, ok := maxAge(header.Get("Cache-Control")); ok {
+		// Age is how long the response was kept by caches on the way
+		age, _ := strconv.Atoi(strings.TrimSpace(header.Get("Age")))
+		if maxAge -= time.Duration(age) * time.Second; maxAge > 0 {
+			p.Expires = now.Add(maxAge)
+		}
+	}
+}
+
+// maxAge returns the max-age of a Cache-Control header, and false when it has none or asks
+// for every use to be revalidated with no-cache or no-store.
+func maxAge(cacheControl string) (time.Duration, bool) {
+	var maxAge time.Duration
+	found := false
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-cache", "no-store":
+			return 0, false
+		case "max-age":
+			seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err != nil || seconds < 0 {
+				return 0, false
+			}
+			maxAge, found = time.Duration(seconds)*time.Second, true
+		}
+	}
+	return maxAge, found
+}
@@ -0,0 +1,140 @@
+package link_preview
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// conditionalServer serves a page with an ETag and cacheControl, answering 304 to a request
+// with its ETag. It counts the page requests and the 304 answered.
+func conditionalServer(t *testing.T, cacheControl string) (*httptest.Server, *int32, *int32) {
+	var requests, notModified int32
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Header().Set("Cache-Control", cacheControl)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Page</title></head></html>`))
+	})
+	t.Cleanup(server.Close)
+	return server, &requests, &notModified
+}
+
+func TestPreviewCaching(t *testing.T) {
+	server, _, _ := conditionalServer(t, "public, max-age=60")
+
+	scraper, err := NewScraper(server.URL + "/page")
+	assert.NoError(t, err)
+	doc, err := scraper.GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	p := doc.Metadata
+	assert.Equal(t, `"v1"`, p.ETag)
+	assert.Equal(t, "Mon, 02 Jan 2006 15:04:05 GMT", p.LastModified)
+	assert.WithinDuration(t, time.Now(), p.FetchedAt, time.Minute)
+	assert.WithinDuration(t, p.FetchedAt.Add(time.Minute), p.Expires, time.Second)
+	assert.False(t, p.Revalidated)
+}
+
+func TestPreviewRevalidated(t *testing.T) {
+	server, requests, notModified := conditionalServer(t, "no-cache")
+	previous := Preview{URL: server.URL + "/page", Title: "Kept", ContentType: "text/html", ETag: `"v1"`}
+
+	scraper, err := NewScraper(server.URL + "/page")
+	assert.NoError(t, err)
+	scraper.Previous = &previous
+	doc, err := scraper.GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+	assert.Equal(t, int32(1), atomic.LoadInt32(notModified))
+	assert.True(t, doc.Metadata.Revalidated)
+	assert.Equal(t, "Kept", doc.Metadata.Title)
+	assert.Equal(t, "Mon, 02 Jan 2006 15:04:05 GMT", doc.Metadata.LastModified)
+	assert.False(t, doc.Metadata.FetchedAt.IsZero())
+	assert.True(t, doc.Metadata.Expires.IsZero())
+	assert.Equal(t, []string{server.URL + "/page"}, doc.RedirectChain)
+}
+
+func TestPreviewFresh(t *testing.T) {
+	server, requests, notModified := conditionalServer(t, "max-age=60")
+	previous := Preview{URL: server.URL + "/page", Title: "Kept", ETag: `"v1"`, Expires: time.Now().Add(time.Minute)}
+
+	scraper, err := NewScraper(server.URL + "/page")
+	assert.NoError(t, err)
+	scraper.Previous = &previous
+	doc, err := scraper.GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Kept", doc.Metadata.Title)
+	assert.Equal(t, int32(0), atomic.LoadInt32(requests))
+
+	scraper, err = NewScraper(server.URL + "/page")
+	assert.NoError(t, err)
+	scraper.Previous = &previous
+	scraper.ForceRefresh = true
+	doc, err = scraper.GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.True(t, doc.Metadata.Revalidated)
+	assert.Equal(t, int32(1), atomic.LoadInt32(notModified))
+}
+
+func TestCachedPreviewerRefresh(t *testing.T) {
+	server, requests, notModified := conditionalServer(t, "max-age=0")
+	previewer := NewCachedPreviewer(NewMemoryCache(10), ScraperOptions{MaxRedirect: 10})
+
+	p, err := previewer.Preview(context.Background(), server.URL+"/page")
+	assert.NoError(t, err)
+	assert.Equal(t, "Page", p.Title)
+
+	p, err = previewer.Refresh(context.Background(), server.URL+"/page")
+	assert.NoError(t, err)
+	assert.Equal(t, "Page", p.Title)
+	assert.True(t, p.Revalidated)
+	assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+	assert.Equal(t, int32(1), atomic.LoadInt32(notModified))
+
+	// The revalidated preview is cached
+	p, err = previewer.Preview(context.Background(), server.URL+"/page")
+	assert.NoError(t, err)
+	assert.True(t, p.Revalidated)
+	assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+}
+
+func TestMaxAge(t *testing.T) {
+	tests := []struct {
+		header string
+		maxAge time.Duration
+		ok     bool
+	}{
+		{"max-age=300", 5 * time.Minute, true},
+		{`public, MAX-AGE="60"`, time.Minute, true},
+		{"max-age=60, no-cache", 0, false},
+		{"no-store", 0, false},
+		{"private", 0, false},
+		{"max-age=abc", 0, false},
+		{"", 0, false},
+	}
+	for _, test := range tests {
+		maxAge, ok := maxAge(test.header)
+		assert.Equal(t, test.maxAge, maxAge, test.header)
+		assert.Equal(t, test.ok, ok, test.header)
+	}
+}
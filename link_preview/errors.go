@@ -0,0 +1,154 @@
+package link_preview
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// ErrorKind classifies why a preview failed.
+type ErrorKind int
+
+const (
+	// KindOther is a failure of no other kind, such as a cancelled context.
+	KindOther ErrorKind = iota
+
+	// KindDNS is a host name that could not be resolved.
+	KindDNS
+
+	// KindTLS is a TLS handshake that failed, usually on an invalid certificate.
+	KindTLS
+
+	// KindTimeout is a request or preview that took longer than its timeout.
+	KindTimeout
+
+	// KindConnectionRefused is a host that refused the connection.
+	KindConnectionRefused
+
+	// KindHTTPStatus is a page answered with a status of 400 or above, given by StatusCode.
+	KindHTTPStatus
+
+	// KindTooLarge is a response the transport refused to read in full, such as with a
+	// *http.MaxBytesError. Pages over MaxBodySize are truncated rather than failed.
+	KindTooLarge
+
+	// KindNotHTML is a response that is neither HTML nor an image, wrapping ErrNotHTML.
+	KindNotHTML
+
+	// KindRedirectLoop is a redirect back to a URL already requested, or past MaxRedirect,
+	// wrapping ErrRedirectLoop or ErrTooManyRedirects.
+	KindRedirectLoop
+)
+
+var errorKindNames = map[ErrorKind]string{
+	KindOther:             "other",
+	KindDNS:               "dns",
+	KindTLS:               "tls",
+	KindTimeout:           "timeout",
+	KindConnectionRefused: "connection refused",
+	KindHTTPStatus:        "http status",
+	KindTooLarge:          "too large",
+	KindNotHTML:           "not html",
+	KindRedirectLoop:      "redirect loop",
+}
+
+func (k ErrorKind) String() string {
+	if name, ok := errorKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("ErrorKind(%d)", int(k))
+}
+
+// PreviewError describes a failed fetch of a preview. Err is one of the sentinel errors of
+// this package or the underlying transport error, and is matched by errors.Is and errors.As.
+type PreviewError struct {
+
+	// Kind of the failure.
+	Kind ErrorKind
+
+	// URL is the URL whose request failed.
+	URL string
+
+	// StatusCode of the response, with KindHTTPStatus.
+	StatusCode int
+
+	Err error
+}
+
+func (e *PreviewError) Error() string {
+	return e.URL + ": " + e.Err.Error()
+}
+
+func (e *PreviewError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent reports whether fetching the URL again is unlikely to succeed: DNS and TLS
+// failures, pages that are not HTML or redirect in a loop, and 4xx statuses other than 408
+// and 429.
+func (e *PreviewError) Permanent() bool {
+	switch e.Kind {
+	case KindDNS, KindTLS, KindTooLarge, KindNotHTML, KindRedirectLoop:
+		return true
+	case KindHTTPStatus:
+		return e.StatusCode >= 400 && e.StatusCode < 500 &&
+			e.StatusCode != http.StatusRequestTimeout && e.StatusCode != http.StatusTooManyRequests
+	}
+	return false
+}
+
+// previewError returns err as a PreviewError for rawURL, of the kind found by errorKind.
+// A PreviewError is returned as is.
+func previewError(rawURL string, err error) error {
+	var previewErr *PreviewError
+	if errors.As(err, &previewErr) {
+		return err
+	}
+	return &PreviewError{Kind: errorKind(err), URL: rawURL, Err: err}
+}
+
+// statusError returns the PreviewError of a page answered with statusCode.
+func statusError(rawURL string, statusCode int) error {
+	return &PreviewError{Kind: KindHTTPStatus, URL: rawURL, StatusCode: statusCode,
+		Err: fmt.Errorf("%w %d", ErrHTTPStatus, statusCode)}
+}
+
+// errorKind classifies err by the errors it wraps.
+func errorKind(err error) ErrorKind {
+	var dnsErr *net.DNSError
+	var maxBytesErr *http.MaxBytesError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrNotHTML):
+		return KindNotHTML
+	case errors.Is(err, ErrRedirectLoop), errors.Is(err, ErrTooManyRedirects):
+		return KindRedirectLoop
+	case errors.As(err, &dnsErr) && !dnsErr.IsTimeout:
+		return KindDNS
+	case isTLSError(err):
+		return KindTLS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return KindTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return KindConnectionRefused
+	case errors.As(err, &maxBytesErr):
+		return KindTooLarge
+	}
+	return KindOther
+}
+
+// isTLSError reports whether err is a failed TLS handshake or certificate verification.
+func isTLSError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &verifyErr) || errors.As(err, &recordErr) ||
+		errors.As(err, &unknownAuthorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
@@ -0,0 +1,119 @@
+package link_preview
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{"dns", &url.Error{Op: "Get", URL: "http://example.invalid", Err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}}}, KindDNS},
+		{"dns timeout", &net.DNSError{Err: "i/o timeout", IsTimeout: true}, KindTimeout},
+		{"unknown authority", &url.Error{Op: "Get", Err: x509.UnknownAuthorityError{}}, KindTLS},
+		{"hostname", x509.HostnameError{Host: "example.com"}, KindTLS},
+		{"deadline", &url.Error{Op: "Get", Err: context.DeadlineExceeded}, KindTimeout},
+		{"read timeout", fmt.Errorf("read body: %w", os.ErrDeadlineExceeded), KindTimeout},
+		{"refused", &url.Error{Op: "Get", Err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}, KindConnectionRefused},
+		{"too large", &http.MaxBytesError{Limit: 10}, KindTooLarge},
+		{"not html", fmt.Errorf("video/mp4: %w", ErrNotHTML), KindNotHTML},
+		{"too many redirects", ErrTooManyRedirects, KindRedirectLoop},
+		{"canceled", context.Canceled, KindOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, errorKind(tt.err))
+		})
+	}
+}
+
+func TestPreviewErrorPermanent(t *testing.T) {
+	assert.True(t, (&PreviewError{Kind: KindTLS}).Permanent())
+	assert.True(t, (&PreviewError{Kind: KindHTTPStatus, StatusCode: http.StatusNotFound}).Permanent())
+	assert.False(t, (&PreviewError{Kind: KindHTTPStatus, StatusCode: http.StatusTooManyRequests}).Permanent())
+	assert.False(t, (&PreviewError{Kind: KindHTTPStatus, StatusCode: http.StatusServiceUnavailable}).Permanent())
+	assert.False(t, (&PreviewError{Kind: KindTimeout}).Permanent())
+	assert.Equal(t, "connection refused", KindConnectionRefused.String())
+}
+
+// previewErr returns the PreviewError of a preview of rawURL, failing the test without one.
+func previewErr(t *testing.T, rawURL string) *PreviewError {
+	t.Helper()
+	u, _ := url.Parse(rawURL)
+	_, err := (&Scraper{Url: u, MaxRedirect: 10, IgnoreRobots: true}).GetLinkPreviewItemsContext(context.Background())
+
+	var previewErr *PreviewError
+	if !assert.True(t, errors.As(err, &previewErr), "%v", err) {
+		t.FailNow()
+	}
+	return previewErr
+}
+
+func TestPreviewErrorTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	err := previewErr(t, server.URL)
+
+	assert.Equal(t, KindTLS, err.Kind)
+	assert.Equal(t, server.URL, err.URL)
+}
+
+func TestPreviewErrorConnectionRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	assert.Equal(t, KindConnectionRefused, previewErr(t, "http://"+addr+"/").Kind)
+}
+
+func TestPreviewErrorHTTPStatus(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/gone", http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusGone)
+		w.Write([]byte(`<html><head><title>Gone</title></head></html>`))
+	})
+	defer server.Close()
+
+	err := previewErr(t, server.URL+"/moved")
+
+	assert.Equal(t, KindHTTPStatus, err.Kind)
+	assert.Equal(t, http.StatusGone, err.StatusCode)
+	assert.Equal(t, server.URL+"/gone", err.URL)
+	assert.ErrorIs(t, err, ErrHTTPStatus)
+	assert.True(t, err.Permanent())
+}
+
+func TestPreviewErrorRedirectLoop(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/", http.StatusFound)
+	})
+	defer server.Close()
+
+	err := previewErr(t, server.URL+"/")
+
+	assert.Equal(t, KindRedirectLoop, err.Kind)
+	assert.ErrorIs(t, err, ErrRedirectLoop)
+}
@@ -0,0 +1,170 @@
+package link_preview
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultMaxFaviconSize is the default for Scraper.MaxFaviconSize.
+	DefaultMaxFaviconSize = 256
+
+	// DefaultMaxInlineFaviconBytes is the default for Scraper.MaxInlineFaviconBytes.
+	DefaultMaxInlineFaviconBytes = 16 << 10
+)
+
+// favicon is an icon declared by a <link> of the page, once for every size it lists.
+type favicon struct {
+	href string
+	// width and height are 0 when the link declares no size, or "any".
+	width, height int
+	// touch is set for rel="apple-touch-icon", which is only used when no rel="icon" fits.
+	touch bool
+}
+
+// parseFavicons returns the icons of a <link> with the given sizes attribute.
+func parseFavicons(href, sizes string, touch bool) []favicon {
+	var icons []favicon
+	for _, size := range strings.Fields(strings.ToLower(sizes)) {
+		w, h, ok := strings.Cut(size, "x")
+		width, werr := strconv.Atoi(w)
+		height, herr := strconv.Atoi(h)
+		if ok && werr == nil && herr == nil && width > 0 && height > 0 {
+			icons = append(icons, favicon{href: href, width: width, height: height, touch: touch})
+		}
+	}
+	if len(icons) == 0 {
+		icons = append(icons, favicon{href: href, touch: touch})
+	}
+	return icons
+}
+
+// chooseFavicon returns the largest square icon no larger than maxSize. Without one, it
+// falls back to the first icon of unknown size, rel="icon" before rel="apple-touch-icon",
+// then to the smallest larger square icon and at last to the first icon.
+func chooseFavicon(icons []favicon, maxSize int) (href string, size int) {
+	if len(icons) == 0 {
+		return "", 0
+	}
+	best := -1
+	for i, icon := range icons {
+		if icon.width == icon.height && icon.width > 0 && icon.width <= maxSize &&
+			(best < 0 || icon.width > icons[best].width) {
+			best = i
+		}
+	}
+	if best < 0 {
+		for _, touch := range []bool{false, true} {
+			for i, icon := range icons {
+				if best < 0 && icon.width == 0 && icon.touch == touch {
+					best = i
+				}
+			}
+		}
+	}
+	if best < 0 {
+		for i, icon := range icons {
+			if icon.width == icon.height && (best < 0 || icon.width < icons[best].width) {
+				best = i
+			}
+		}
+	}
+	if best < 0 {
+		best = 0
+	}
+	return icons[best].href, icons[best].width
+}
+
+// applyFavicon chooses the favicon of the page among the icons it declares up to
+// MaxFaviconSize. When it declares none, /favicon.ico is kept, with ValidateImages only if a
+// HEAD request finds it. With InlineFavicon the icon is downloaded into FaviconData.
+func (scraper *Scraper) applyFavicon(ctx context.Context, doc *Document) {
+	if strings.HasPrefix(doc.contentType, "image/") {
+		return
+	}
+	p := &doc.Metadata
+	if len(doc.images.icons) > 0 {
+		maxSize := scraper.MaxFaviconSize
+		if maxSize <= 0 {
+			maxSize = DefaultMaxFaviconSize
+		}
+		p.FaviconURL, p.FaviconSize = chooseFavicon(doc.images.icons, maxSize)
+	} else if scraper.ValidateImages && p.FaviconURL != "" && !scraper.faviconExists(ctx, p.FaviconURL) {
+		p.FaviconURL = ""
+	}
+
+	if scraper.InlineFavicon && p.FaviconURL != "" {
+		p.FaviconData = scraper.inlineFavicon(ctx, p.FaviconURL)
+	}
+}
+
+// faviconExists reports whether a HEAD request for src succeeds with something other than
+// a page. Servers label icons with all kinds of types, so any other type is accepted.
+func (scraper *Scraper) faviconExists(ctx context.Context, src string) bool {
+	requestTimeout := scraper.RequestTimeout
+	if requestTimeout <= 0 {
+		requestTimeout = DefaultRequestTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	client, err := scraper.newClient(true)
+	if err != nil {
+		return false
+	}
+	resp, err := scraper.request(ctx, client, "HEAD", src, nil)
+	if err != nil {
+		scraper.logger().Debug("favicon failed", "url", src, "err", err)
+		return false
+	}
+	resp.Body.Close()
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return resp.StatusCode == http.StatusOK && mediaType != "text/html"
+}
+
+// inlineFavicon returns src as a data: URL, or "" if it cannot be fetched, is not an image
+// or is larger than MaxInlineFaviconBytes.
+func (scraper *Scraper) inlineFavicon(ctx context.Context, src string) string {
+	requestTimeout := scraper.RequestTimeout
+	if requestTimeout <= 0 {
+		requestTimeout = DefaultRequestTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	maxBytes := scraper.MaxInlineFaviconBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxInlineFaviconBytes
+	}
+	client, err := scraper.newClient(true)
+	if err != nil {
+		return ""
+	}
+	resp, err := scraper.request(ctx, client, "GET", src, nil)
+	if err != nil {
+		scraper.logger().Debug("favicon failed", "url", src, "err", err)
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ContentLength > maxBytes {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil || int64(len(body)) > maxBytes || len(body) == 0 {
+		return ""
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mediaType, "image/") {
+		mediaType = http.DetectContentType(body)
+		if !strings.HasPrefix(mediaType, "image/") {
+			return ""
+		}
+	}
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(body)
+}
@@ -0,0 +1,139 @@
+package link_preview
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChooseFavicon(t *testing.T) {
+	icons := append(append(append(
+		parseFavicons("/favicon-16.png", "16x16", false),
+		parseFavicons("/favicon.png", "32x32 64X64", false)...),
+		parseFavicons("/apple-touch-icon.png", "180x180", true)...),
+		parseFavicons("/wide.png", "300x150", false)...)
+
+	tests := []struct {
+		name     string
+		icons    []favicon
+		maxSize  int
+		wantHref string
+		wantSize int
+	}{
+		{"largest square", icons, 256, "/apple-touch-icon.png", 180},
+		{"up to max size", icons, 64, "/favicon.png", 64},
+		{"unknown size", append(icons[3:], parseFavicons("/touch.png", "", true)[0], parseFavicons("/icon.svg", "any", false)[0]), 16, "/icon.svg", 0},
+		{"smallest larger", icons[3:], 16, "/apple-touch-icon.png", 180},
+		{"not square", icons[4:], 16, "/wide.png", 300},
+		{"none", nil, 16, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			href, size := chooseFavicon(tt.icons, tt.maxSize)
+
+			assert.Equal(t, tt.wantHref, href)
+			assert.Equal(t, tt.wantSize, size)
+		})
+	}
+}
+
+const faviconPage = `<html><head><title>Icons</title>
+<link rel="shortcut icon" href="/favicon.ico">
+<link rel="icon" type="image/png" sizes="32x32" href="/icons/32.png">
+<link rel="apple-touch-icon" sizes="180x180" href="/icons/180.png">
+</head><body></body></html>`
+
+func TestScraperFavicon(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(faviconPage))
+	})
+	defer server.Close()
+
+	scraper, err := NewScraper(server.URL + "/")
+	assert.NoError(t, err)
+	doc, err := scraper.GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/icons/180.png", doc.Metadata.FaviconURL)
+	assert.Equal(t, 180, doc.Metadata.FaviconSize)
+	assert.Empty(t, doc.Metadata.FaviconData)
+
+	scraper, _ = NewScraper(server.URL + "/")
+	scraper.MaxFaviconSize = 64
+	doc, err = scraper.GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/icons/32.png", doc.Metadata.FaviconURL)
+	assert.Equal(t, 32, doc.Metadata.FaviconSize)
+}
+
+func TestScraperFaviconFallback(t *testing.T) {
+	for _, exists := range []bool{true, false} {
+		var heads int
+		server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/favicon.ico":
+				heads++
+				if !exists {
+					http.NotFound(w, r)
+					return
+				}
+				w.Header().Set("Content-Type", "image/vnd.microsoft.icon")
+			default:
+				w.Header().Set("Content-Type", "text/html")
+				w.Write([]byte(`<html><head><title>No icons</title></head><body></body></html>`))
+			}
+		})
+
+		scraper, err := NewScraper(server.URL + "/page")
+		assert.NoError(t, err)
+		scraper.ValidateImages = true
+		doc, err := scraper.GetLinkPreviewItems()
+		server.Close()
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, heads)
+		if exists {
+			assert.Equal(t, server.URL+"/favicon.ico", doc.Metadata.FaviconURL)
+		} else {
+			assert.Empty(t, doc.Metadata.FaviconURL)
+		}
+		assert.Zero(t, doc.Metadata.FaviconSize)
+	}
+}
+
+func TestScraperInlineFavicon(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/icons/32.png", "/icons/180.png":
+			// No type, so that it is sniffed
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(png)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(faviconPage))
+		}
+	})
+	defer server.Close()
+
+	scraper, err := NewScraper(server.URL + "/")
+	assert.NoError(t, err)
+	scraper.InlineFavicon = true
+	doc, err := scraper.GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "data:image/png;base64,iVBORw0KGgoAAAANSUhEUg==", doc.Metadata.FaviconData)
+
+	scraper, _ = NewScraper(server.URL + "/")
+	scraper.InlineFavicon = true
+	scraper.MaxInlineFaviconBytes = 8
+	doc, err = scraper.GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/icons/180.png", doc.Metadata.FaviconURL)
+	assert.Empty(t, doc.Metadata.FaviconData)
+}
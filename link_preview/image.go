@@ -0,0 +1,130 @@
+package link_preview
+
+import (
+	"bytes"
+	"context"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultMinImageBytes is the default for Scraper.MinImageBytes. It rejects tracking pixels
+// and spacers, which are a few dozen bytes.
+const DefaultMinImageBytes = 1024
+
+// imageHeaderSize is how much of an image is requested to decode its dimensions.
+const imageHeaderSize = 64 << 10
+
+// imageInfo describes a validated image.
+type imageInfo struct {
+	contentType   string
+	width, height int
+}
+
+// selectImage replaces the preview image with the best valid candidate.
+func (scraper *Scraper) selectImage(ctx context.Context, doc *Document) {
+	p := &doc.Metadata
+	p.Image, p.ImageWidth, p.ImageHeight, p.ImageContentType = "", 0, 0, ""
+
+	for _, src := range doc.images.meta {
+		if info, ok := scraper.probeImage(ctx, src); ok {
+			p.Image, p.ImageWidth, p.ImageHeight, p.ImageContentType = src, info.width, info.height, info.contentType
+			return
+		}
+	}
+
+	for _, src := range doc.images.page {
+		info, ok := scraper.probeImage(ctx, src)
+		if ok && (p.Image == "" || info.width*info.height > p.ImageWidth*p.ImageHeight) {
+			p.Image, p.ImageWidth, p.ImageHeight, p.ImageContentType = src, info.width, info.height, info.contentType
+		}
+	}
+}
+
+// probeImage checks that src is an image larger than MinImageBytes and, if its format is
+// known, at least minImageSize pixels wide and high.
+//
+// The type and size are taken from a HEAD request when the server supports it; the
+// dimensions are decoded from the first bytes of the image fetched with a ranged GET.
+func (scraper *Scraper) probeImage(ctx context.Context, src string) (imageInfo, bool) {
+	requestTimeout := scraper.RequestTimeout
+	if requestTimeout <= 0 {
+		requestTimeout = DefaultRequestTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	minBytes := scraper.MinImageBytes
+	if minBytes <= 0 {
+		minBytes = DefaultMinImageBytes
+	}
+	client, err := scraper.newClient(true)
+	if err != nil {
+		return imageInfo{}, false
+	}
+
+	if resp, err := scraper.request(ctx, client, "HEAD", src, nil); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			if !isImage(resp.Header) || (resp.ContentLength >= 0 && resp.ContentLength <= minBytes) {
+				return imageInfo{}, false
+			}
+		}
+	}
+
+	resp, err := scraper.request(ctx, client, "GET", src, http.Header{"Range": {"bytes=0-" + strconv.Itoa(imageHeaderSize-1)}})
+	if err != nil {
+		return imageInfo{}, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return imageInfo{}, false
+	}
+	if !isImage(resp.Header) {
+		return imageInfo{}, false
+	}
+	if size := imageSize(resp); size >= 0 && size <= minBytes {
+		return imageInfo{}, false
+	}
+
+	info := imageInfo{}
+	info.contentType, _, _ = mime.ParseMediaType(resp.Header.Get("Content-Type"))
+
+	head, err := io.ReadAll(io.LimitReader(resp.Body, imageHeaderSize))
+	if err != nil {
+		return imageInfo{}, false
+	}
+	// Formats without a registered decoder, such as WebP, are accepted without dimensions.
+	if config, _, err := image.DecodeConfig(bytes.NewReader(head)); err == nil {
+		if config.Width < minImageSize || config.Height < minImageSize {
+			return imageInfo{}, false
+		}
+		info.width, info.height = config.Width, config.Height
+	}
+	return info, true
+}
+
+func isImage(header http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && strings.HasPrefix(mediaType, "image/")
+}
+
+// imageSize returns the full size of the image from Content-Range or Content-Length, or -1.
+func imageSize(resp *http.Response) int64 {
+	if contentRange := resp.Header.Get("Content-Range"); contentRange != "" {
+		if i := strings.LastIndex(contentRange, "/"); i >= 0 {
+			if size, err := strconv.ParseInt(contentRange[i+1:], 10, 64); err == nil {
+				return size
+			}
+		}
+		return -1
+	}
+	return resp.ContentLength
+}
@@ -0,0 +1,110 @@
+package link_preview
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// noisePNG encodes a random image so that it does not compress below DefaultMinImageBytes.
+func noisePNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	r := rand.New(rand.NewSource(1))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(r.Intn(256))
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newImageServer(t *testing.T, page string) *httptest.Server {
+	// A 1x1 transparent GIF, as used for tracking pixels.
+	pixel := []byte("GIF89a\x01\x00\x01\x00\x80\x00\x00\x00\x00\x00\xff\xff\xff!\xf9\x04\x01\x00\x00\x00\x00,\x00\x00\x00\x00\x01\x00\x01\x00\x00\x02\x02D\x01\x00;")
+	files := map[string][]byte{
+		"/pixel.gif":  pixel,
+		"/small.png":  noisePNG(t, 50, 50),
+		"/medium.png": noisePNG(t, 200, 150),
+		"/big.png":    noisePNG(t, 300, 200),
+	}
+
+	return createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(page))
+			return
+		}
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		// ServeContent answers HEAD and Range requests.
+		http.ServeContent(w, r, r.URL.Path, time.Time{}, bytes.NewReader(data))
+	})
+}
+
+func TestValidateImagesPicksLargestPageImage(t *testing.T) {
+	server := newImageServer(t, `<html><head>
+<meta property="og:image" content="/missing.png">
+<meta name="twitter:image" content="/pixel.gif">
+</head><body>
+<img src="/small.png"><img src="/medium.png"><img src="/big.png"><img src="/pixel.gif">
+</body></html>`)
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL + "/")
+	doc, err := (&Scraper{Url: u, MaxRedirect: 10, ValidateImages: true}).GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/big.png", doc.Metadata.Image)
+	assert.Equal(t, 300, doc.Metadata.ImageWidth)
+	assert.Equal(t, 200, doc.Metadata.ImageHeight)
+	assert.Equal(t, "image/png", doc.Metadata.ImageContentType)
+}
+
+func TestValidateImagesPrefersOpenGraph(t *testing.T) {
+	server := newImageServer(t, `<html><head>
+<meta property="og:image" content="/medium.png">
+<meta property="og:image:width" content="1200">
+</head><body><img src="/big.png"></body></html>`)
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL + "/")
+	doc, err := (&Scraper{Url: u, MaxRedirect: 10, ValidateImages: true}).GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/medium.png", doc.Metadata.Image)
+	// The decoded size replaces the declared one.
+	assert.Equal(t, 200, doc.Metadata.ImageWidth)
+	assert.Equal(t, 150, doc.Metadata.ImageHeight)
+}
+
+func TestValidateImagesRejectsAll(t *testing.T) {
+	server := newImageServer(t, `<html><head>
+<meta property="og:image" content="/pixel.gif">
+</head><body><img src="/missing.jpg"><img src="/small.png"></body></html>`)
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL + "/")
+	doc, err := (&Scraper{Url: u, MaxRedirect: 10, ValidateImages: true}).GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.Empty(t, doc.Metadata.Image)
+	assert.Zero(t, doc.Metadata.ImageWidth)
+
+	// Without validation the tracking pixel is used as is.
+	doc, err = GetLinkPreviewItems(server.URL+"/", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/pixel.gif", doc.Metadata.Image)
+}
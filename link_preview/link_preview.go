@@ -2,15 +2,24 @@ package link_preview
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
-
+	"time"
+
+	"github.com/propro-productions/go-utils/doh"
+	"github.com/propro-productions/go-utils/fetch"
+	"github.com/propro-productions/go-utils/internal/httpopts"
+	"github.com/propro-productions/go-utils/metrics"
+	"github.com/propro-productions/go-utils/proxy"
+	"github.com/propro-productions/go-utils/robots"
 	"golang.org/x/net/html"
-	"golang.org/x/net/html/charset"
 )
 
 var (
@@ -18,15 +27,202 @@ var (
 	fragmentRegexp         = regexp.MustCompile("#!(.*)")
 )
 
+var (
+	// ErrNotHTML is returned for responses that are neither HTML nor an image.
+	ErrNotHTML = errors.New("not an HTML document")
+
+	// ErrRedirectLoop is returned when a redirect leads back to a URL already requested.
+	ErrRedirectLoop = errors.New("redirect loop")
+
+	// ErrTooManyRedirects is returned when following a redirect would exceed MaxRedirect.
+	ErrTooManyRedirects = errors.New("too many redirects")
+
+	// ErrHTTPStatus is returned for pages answered with a status of 400 or above.
+	ErrHTTPStatus = errors.New("HTTP error status")
+)
+
+// defaultUserAgent is sent with every request unless the Scraper sets another one.
+const defaultUserAgent = "GoScraper"
+
+const (
+	// DefaultTimeout bounds a whole preview, including redirects and canonical refetches.
+	DefaultTimeout = 30 * time.Second
+
+	// DefaultRequestTimeout bounds a single request, including reading its body.
+	DefaultRequestTimeout = 10 * time.Second
+
+	// DefaultMaxBodySize is how much of a page is read by default.
+	DefaultMaxBodySize = 5 << 20
+
+	// DefaultMaxRedirect is the MaxRedirect of the scrapers returned by NewScraper.
+	DefaultMaxRedirect = 10
+)
+
+// Limiter rate limits requests per host. *search.HostLimiter implements it.
+type Limiter interface {
+	Wait(ctx context.Context, host string) error
+}
+
+// Logger receives debug messages about the requests made by a Scraper. args alternate
+// between keys and values. Any search.Logger, such as search.StdLogger, implements it, and
+// so does a *slog.Logger.
+type Logger interface {
+	Debug(msg string, args ...any)
+}
+
+// DefaultLogger is used when Scraper.Logger is nil. It discards every message.
+var DefaultLogger Logger = nopLogger{}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...any) {}
+
+// Scraper previews a single URL. It holds the state of the preview, such as RedirectChain
+// and the remaining MaxRedirect, so it must not be used by several goroutines at once nor
+// reused for another preview. A Previewer previews any number of URLs concurrently.
 type Scraper struct {
 	Url                *url.URL
 	EscapedFragmentUrl *url.URL
-	MaxRedirect        int
+
+	// MaxRedirect is how many times the scraper may move on to another URL, counting
+	// HTTP redirects, meta refreshes and script redirects as well as canonical and escaped
+	// fragment refetches.
+	MaxRedirect int
+
+	// MaxRefreshDelay is the longest delay of a <meta http-equiv="refresh"> that is followed
+	// like a redirect. Pages that only set window.location in a script are always followed.
+	// Default: DefaultMaxRefreshDelay.
+	MaxRefreshDelay time.Duration
+
+	// RedirectChain lists every URL requested, in order, including the targets of meta
+	// refreshes and script redirects. The last entry is the final URL.
+	RedirectChain []string
+
+	// IgnoreRobots skips the robots.txt check. By default a URL disallowed for
+	// the scraper's user agent fails with robots.ErrDisallowed.
+	IgnoreRobots bool
+
+	// Limiter, if set, is waited on before every page request.
+	Limiter Limiter
+
+	// Timeout bounds the whole preview. Default: DefaultTimeout.
+	Timeout time.Duration
+
+	// RequestTimeout bounds each request including the time to read its body, so that a
+	// server trickling a response cannot hold the scraper. Default: DefaultRequestTimeout.
+	RequestTimeout time.Duration
+
+	// MaxBodySize caps how many bytes of a page are read; the rest is ignored.
+	// Default: DefaultMaxBodySize.
+	MaxBodySize int64
+
+	// ValidateImages requests the candidate images of the page and picks the first of the
+	// image of the structured data, og:image and twitter:image that is a real image, or
+	// else the largest <img>.
+	// Metadata.Image is left empty if no candidate is valid. It also checks that
+	// /favicon.ico exists when the page declares no icon.
+	ValidateImages bool
+
+	// FetchAlternate fetches the AMP version of a page, or else its canonical page, when the
+	// page has no og:image or no description, and fills the missing fields from it. Only
+	// one such request is made and it counts against MaxRedirect.
+	FetchAlternate bool
+
+	// MaxFaviconSize is the size in pixels up to which larger icons are preferred for
+	// Metadata.FaviconURL. Larger icons are only used when the page declares no other.
+	// Default: DefaultMaxFaviconSize.
+	MaxFaviconSize int
+
+	// InlineFavicon downloads the favicon into Metadata.FaviconData, for clients that
+	// cannot load it from the site.
+	InlineFavicon bool
+
+	// MaxInlineFaviconBytes is the size up to which a favicon is inlined with InlineFavicon.
+	// Default: DefaultMaxInlineFaviconBytes.
+	MaxInlineFaviconBytes int64
+
+	// MinImageBytes is the size an image must exceed to be valid when ValidateImages is set.
+	// Default: DefaultMinImageBytes.
+	MinImageBytes int64
+
+	// UserAgent is sent with every request and used to match robots.txt groups.
+	// It takes precedence over a User-Agent in Headers. Default: "GoScraper".
+	UserAgent string
+
+	// Headers are added to every request, including redirects, oEmbed and image requests.
+	Headers map[string]string
+
+	// CookieJar, if set, stores cookies set by responses and sends them with later requests.
+	CookieJar http.CookieJar
+
+	// ProxyAddr sets a proxy address all requests are sent through, e.g. "http://127.0.0.1:8080".
+	ProxyAddr string
+
+	// ProxyPool, if set, hands out the proxy of every request, leaving out the proxies that
+	// keep failing. It takes precedence over ProxyAddr.
+	ProxyPool *proxy.Pool
+
+	// Resolver, if set, looks up the hosts requested, and the proxies, with DNS over HTTPS.
+	// robots.txt is fetched by robots.DefaultChecker, whose HTTPClient can be given a
+	// transport dialing with Resolver.DialContext too.
+	Resolver *doh.Resolver
+
+	// HTTPClient, if set, makes the requests. It is never modified: the proxy, cookie jar
+	// and redirect handling of the scraper are applied to a copy.
+	HTTPClient *http.Client
+
+	// Logger receives debug messages about the requests made. Default: DefaultLogger.
+	Logger Logger
+
+	// Metrics, if set, receives the duration of every preview and the size of the pages
+	// previewed, see package metrics.
+	Metrics metrics.Metrics
+
+	// Previous is a preview of the page made before, such as one persisted by the caller.
+	// Its ETag and LastModified are sent with the request for its URL, and when the server
+	// answers that the page has not changed, Previous is returned marked Revalidated instead
+	// of parsing the page again. Until its Expires, Previous is returned without a request.
+	Previous *Preview
+
+	// ForceRefresh requests the page even when Previous is still fresh. The request is
+	// still conditional.
+	ForceRefresh bool
+
+	// RenderJS renders HTML pages with Renderer after fetching them, so that the preview is
+	// read from the DOM once scripts ran. Single-page apps that serve an empty shell need it.
+	RenderJS bool
+
+	// Renderer renders pages when RenderJS is set. Default: fetch.DefaultRenderer, which
+	// needs the chromedp build tag and Chrome to be installed.
+	Renderer fetch.Fetcher
+
+	// siteRules are the site rules of the Previewer that created the scraper, or nil for
+	// the registered ones.
+	siteRules []siteRule
 }
 
 type Document struct {
 	Body    bytes.Buffer
 	Preview DocumentPreview
+
+	// RedirectChain lists every URL requested for the document, ending with its final URL.
+	RedirectChain []string
+
+	// Metadata holds the OpenGraph and Twitter card data of the final document.
+	Metadata Preview
+
+	// contentType is the media type of the response, e.g. "text/html".
+	contentType string
+
+	// header is the header of the response, fetchedAt when it was received.
+	header    http.Header
+	fetchedAt time.Time
+
+	// notModified is set when the page was not modified since Scraper.Previous.
+	notModified bool
+
+	// images are the candidates for Metadata.Image.
+	images imageCandidates
 }
 
 type DocumentPreview struct {
@@ -42,22 +238,95 @@ type DocumentPreview struct {
 // how can speed up the process?
 // let me think about it for 10 minutes
 func GetLinkPreviewItems(uri string, maxRedirect int) (*Document, error) {
+	return GetLinkPreviewItemsContext(context.Background(), uri, maxRedirect)
+}
+
+// GetLinkPreviewItemsContext is like GetLinkPreviewItems but aborts when ctx is done.
+func GetLinkPreviewItemsContext(ctx context.Context, uri string, maxRedirect int) (*Document, error) {
 	u, err := url.Parse(uri)
 	if err != nil {
 		return nil, err
 	}
-	return (&Scraper{Url: u, MaxRedirect: maxRedirect}).GetLinkPreviewItems()
+	return (&Scraper{Url: u, MaxRedirect: maxRedirect}).GetLinkPreviewItemsContext(ctx)
 }
 
 func (scraper *Scraper) GetLinkPreviewItems() (*Document, error) {
-	doc, err := scraper.getDocument()
+	return scraper.GetLinkPreviewItemsContext(context.Background())
+}
+
+// GetLinkPreviewItemsContext fetches and parses the page, aborting when ctx is done
+// or the scraper's Timeout has passed. A failed fetch, including a page answered with a
+// status of 400 or above, is reported as a *PreviewError.
+func (scraper *Scraper) GetLinkPreviewItemsContext(ctx context.Context) (doc *Document, err error) {
+	timeout := scraper.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if scraper.Metrics != nil {
+		start := time.Now()
+		defer func() {
+			status := metrics.StatusOK
+			if err != nil {
+				status = metrics.StatusError
+			}
+			metrics.Timing(scraper.Metrics, metrics.PreviewDuration, time.Since(start), "status", status)
+		}()
+	}
+
+	if p := scraper.Previous; p != nil && !scraper.ForceRefresh && p.fresh(time.Now()) {
+		scraper.logger().Debug("preview fresh", "url", p.URL, "expires", p.Expires)
+		return &Document{Preview: DocumentPreview{Link: p.URL}, Metadata: *p, contentType: p.ContentType}, nil
+	}
+
+	doc, err = scraper.getDocument(ctx)
 	if err != nil {
 		return nil, err
 	}
-	err = scraper.parseDocument(doc)
+	if doc.notModified {
+		doc.RedirectChain = append([]string(nil), scraper.RedirectChain...)
+		return doc, nil
+	}
+	metrics.Count(scraper.Metrics, metrics.PreviewBytes, int64(doc.Body.Len()))
+	err = scraper.parseDocument(ctx, doc)
 	if err != nil {
 		return nil, err
 	}
+	setCaching(&doc.Metadata, doc.header, doc.fetchedAt)
+	if scraper.FetchAlternate {
+		scraper.applyAlternate(ctx, doc)
+	}
+	scraper.applyOEmbed(ctx, &doc.Metadata)
+	scraper.applyFavicon(ctx, doc)
+	if scraper.ValidateImages {
+		scraper.selectImage(ctx, doc)
+	}
+	doc.RedirectChain = append([]string(nil), scraper.RedirectChain...)
+	return doc, nil
+}
+
+// FetchDocument fetches the page like GetLinkPreviewItemsContext, following redirects and
+// client redirects and checking robots.txt, but does not parse its preview: Body holds
+// the HTML as UTF-8 and RedirectChain the URLs requested. It is for callers that process
+// the page themselves. Responses that are not HTML, images included, fail with ErrNotHTML.
+func (scraper *Scraper) FetchDocument(ctx context.Context) (*Document, error) {
+	timeout := scraper.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	doc, err := scraper.getDocument(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if doc.contentType != "text/html" && doc.contentType != "application/xhtml+xml" {
+		return nil, previewError(scraper.Url.String(), fmt.Errorf("%s: %w", doc.contentType, ErrNotHTML))
+	}
+	doc.RedirectChain = append([]string(nil), scraper.RedirectChain...)
 	return doc, nil
 }
 
@@ -111,8 +380,14 @@ func (scraper *Scraper) toFragmentUrl() error {
 	return nil
 }
 
-func (scraper *Scraper) getDocument() (*Document, error) {
-	scraper.MaxRedirect -= 1
+func (scraper *Scraper) getDocument(ctx context.Context) (*Document, error) {
+	requestTimeout := scraper.RequestTimeout
+	if requestTimeout <= 0 {
+		requestTimeout = DefaultRequestTimeout
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
 	if strings.Contains(scraper.Url.String(), "#!") {
 		scraper.toFragmentUrl()
 	}
@@ -120,47 +395,247 @@ func (scraper *Scraper) getDocument() (*Document, error) {
 		scraper.EscapedFragmentUrl = scraper.Url
 	}
 
-	req, err := http.NewRequest("GET", scraper.getUrl(), nil)
+	resp, err := scraper.fetch(reqCtx, scraper.getUrl())
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Add("User-Agent", "GoScraper")
+	defer resp.Body.Close()
 
-	resp, err := http.DefaultClient.Do(req)
-	if resp != nil {
-		defer resp.Body.Close()
+	if resp.Request.URL.String() != scraper.getUrl() {
+		scraper.EscapedFragmentUrl = nil
+		scraper.Url = resp.Request.URL
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, statusError(scraper.Url.String(), resp.StatusCode)
+	}
+	if resp.StatusCode == http.StatusNotModified && scraper.Previous != nil {
+		return scraper.notModified(resp.Header, time.Now()), nil
+	}
+
+	// A missing Content-Type is treated as HTML.
+	contentType := "text/html"
+	if header := resp.Header.Get("content-type"); header != "" {
+		contentType, _, err = mime.ParseMediaType(header)
+		if err != nil {
+			return nil, previewError(scraper.Url.String(), ErrNotHTML)
+		}
+	}
+
+	switch {
+	case contentType == "text/html" || contentType == "application/xhtml+xml":
+	case strings.HasPrefix(contentType, "image/"):
+		// Images are previewed as themselves without reading the body.
+		link := scraper.Url.String()
+		return &Document{
+			Preview:     DocumentPreview{Link: link, Name: scraper.Url.Host, Images: []string{link}},
+			Metadata:    Preview{URL: link, Image: link, SiteName: scraper.Url.Host, ContentType: contentType},
+			contentType: contentType,
+			header:      resp.Header,
+			fetchedAt:   time.Now(),
+		}, nil
+	default:
+		return nil, previewError(scraper.Url.String(), fmt.Errorf("%s: %w", contentType, ErrNotHTML))
+	}
+
+	maxBodySize := scraper.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxBodySize
 	}
+	b, err := convertUTF8(io.LimitReader(resp.Body, maxBodySize), resp.Header.Get("content-type"))
+	if err != nil {
+		return nil, previewError(scraper.Url.String(), err)
+	}
+	doc := &Document{Body: b, Preview: DocumentPreview{Link: scraper.Url.String()}, contentType: contentType, header: resp.Header, fetchedAt: time.Now()}
+	if next, err := scraper.followClientRedirect(ctx, doc); err != nil || next != doc {
+		return next, err
+	}
+
+	// Rendering is bounded by the preview's Timeout only, a browser being slower than a request
+	if scraper.RenderJS {
+		page, err := scraper.renderer().Fetch(ctx, scraper.Url.String())
+		if err != nil {
+			return nil, previewError(scraper.Url.String(), fmt.Errorf("rendering: %w", err))
+		}
+		scraper.logger().Debug("rendered", "url", scraper.Url.String(), "bytes", len(page.HTML))
+		doc.Body.Reset()
+		doc.Body.WriteString(page.HTML)
+	}
+
+	return doc, nil
+}
+
+func (scraper *Scraper) renderer() fetch.Fetcher {
+	if scraper.Renderer != nil {
+		return scraper.Renderer
+	}
+	return fetch.DefaultRenderer
+}
+
+// fetch requests rawURL and follows redirects itself, so that every hop is recorded in
+// RedirectChain, checked against robots.txt and counted against MaxRedirect. Its errors
+// are PreviewErrors.
+func (scraper *Scraper) fetch(ctx context.Context, rawURL string) (*http.Response, error) {
+	client, err := scraper.newClient(false)
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.Request.URL.String() != scraper.getUrl() {
-		scraper.EscapedFragmentUrl = nil
-		scraper.Url = resp.Request.URL
+	visited := map[string]bool{}
+	for {
+		visited[rawURL] = true
+		scraper.RedirectChain = append(scraper.RedirectChain, rawURL)
+
+		resp, err := scraper.request(ctx, client, "GET", rawURL, scraper.conditionalHeader(rawURL))
+		if err != nil {
+			return nil, previewError(rawURL, err)
+		}
+
+		location := resp.Header.Get("Location")
+		if !isRedirect(resp.StatusCode) || location == "" {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		next, err := resp.Request.URL.Parse(location)
+		if err != nil {
+			return nil, previewError(rawURL, err)
+		}
+		rawURL = next.String()
+		scraper.logger().Debug("redirect", "from", resp.Request.URL.String(), "to", rawURL, "status", resp.StatusCode)
+		if visited[rawURL] {
+			return nil, previewError(rawURL, ErrRedirectLoop)
+		}
+		if scraper.MaxRedirect <= 0 {
+			return nil, previewError(rawURL, ErrTooManyRedirects)
+		}
+		scraper.MaxRedirect--
 	}
-	b, err := convertUTF8(resp.Body, resp.Header.Get("content-type"))
+}
+
+// newClient returns a client using the scraper's cookie jar, proxy and resolver. Unless followRedirects
+// is set, redirect responses are returned to the caller.
+func (scraper *Scraper) newClient(followRedirects bool) (*http.Client, error) {
+	client, err := httpopts.NewClient(httpopts.Options{
+		ProxyAddr:  scraper.ProxyAddr,
+		ProxyPool:  scraper.ProxyPool,
+		Resolver:   scraper.Resolver,
+		HTTPClient: scraper.HTTPClient,
+	})
 	if err != nil {
 		return nil, err
 	}
-	doc := &Document{Body: b, Preview: DocumentPreview{Link: scraper.Url.String()}}
 
-	return doc, nil
+	// The jar and redirect policy are set on a copy, leaving a provided client alone
+	c := *client
+	client = &c
+	if scraper.CookieJar != nil {
+		client.Jar = scraper.CookieJar
+	}
+	if !followRedirects {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	return client, nil
 }
 
-func convertUTF8(content io.Reader, contentType string) (bytes.Buffer, error) {
-	buff := bytes.Buffer{}
-	content, err := charset.NewReader(content, contentType)
+// userAgent returns the User-Agent sent with requests.
+func (scraper *Scraper) userAgent() string {
+	if scraper.UserAgent != "" {
+		return scraper.UserAgent
+	}
+	for key, value := range scraper.Headers {
+		if http.CanonicalHeaderKey(key) == "User-Agent" && value != "" {
+			return value
+		}
+	}
+	return defaultUserAgent
+}
+
+// request makes a single request for rawURL with the scraper's user agent and any extra
+// header, after checking robots.txt and waiting for the limiter.
+func (scraper *Scraper) request(ctx context.Context, client *http.Client, method, rawURL string, header http.Header) (*http.Response, error) {
+	if !scraper.IgnoreRobots {
+		allowed, err := robots.DefaultChecker.AllowedContext(ctx, scraper.userAgent(), rawURL)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			scraper.logger().Debug("disallowed by robots.txt", "url", rawURL, "user_agent", scraper.userAgent())
+			return nil, fmt.Errorf("%s: %w", rawURL, robots.ErrDisallowed)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
 	if err != nil {
-		return buff, err
+		return nil, err
+	}
+	if scraper.Limiter != nil {
+		if err := scraper.Limiter.Wait(ctx, req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+	for key, value := range scraper.Headers {
+		req.Header.Set(key, value)
 	}
-	_, err = io.Copy(&buff, content)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("User-Agent", scraper.userAgent())
+
+	scraper.logger().Debug("preview request", "method", method, "url", rawURL)
+	resp, err := client.Do(req)
 	if err != nil {
-		return buff, err
+		return nil, err
 	}
-	return buff, nil
+	scraper.logger().Debug("preview response", "method", method, "url", rawURL, "status", resp.StatusCode)
+	return resp, nil
 }
 
-func (scraper *Scraper) parseDocument(doc *Document) error {
+// logger returns the Logger of the scraper.
+func (scraper *Scraper) logger() Logger {
+	if scraper.Logger != nil {
+		return scraper.Logger
+	}
+	return DefaultLogger
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// visited reports whether rawURL has already been requested.
+func (scraper *Scraper) visited(rawURL string) bool {
+	for _, u := range scraper.RedirectChain {
+		if u == rawURL {
+			return true
+		}
+	}
+	return false
+}
+
+func (scraper *Scraper) parseDocument(ctx context.Context, doc *Document) error {
+	if strings.HasPrefix(doc.contentType, "image/") {
+		return nil
+	}
+
+	metadata, images, err := parsePreview(bytes.NewReader(doc.Body.Bytes()), scraper.Url)
+	if err != nil {
+		return err
+	}
+	doc.images = images
+	for _, warning := range metadata.Warnings {
+		scraper.logger().Debug("structured data skipped", "url", scraper.Url.String(), "warning", warning)
+	}
+	metadata.ContentType = doc.contentType
+	doc.Metadata = metadata
+	scraper.applySiteRules(doc)
+
 	t := html.NewTokenizer(&doc.Body)
 	var ogImage bool
 	var headPassed bool
@@ -251,16 +726,10 @@ func (scraper *Scraper) parseDocument(doc *Document) error {
 				doc.Preview.Link = content
 			case "og:image":
 				ogImage = true
-				ogImgUrl, err := url.Parse(content)
+				ogImgUrl, err := scraper.Url.Parse(content)
 				if err != nil {
 					return err
 				}
-				if !ogImgUrl.IsAbs() {
-					ogImgUrl, err = url.Parse(fmt.Sprintf("%s://%s%s", scraper.Url.Scheme, scraper.Url.Host, ogImgUrl.Path))
-					if err != nil {
-						return err
-					}
-				}
 
 				doc.Preview.Images = []string{ogImgUrl.String()}
 
@@ -278,46 +747,37 @@ func (scraper *Scraper) parseDocument(doc *Document) error {
 		case "img":
 			for _, attr := range token.Attr {
 				if cleanStr(attr.Key) == "src" {
-					imgUrl, err := url.Parse(attr.Val)
+					imgUrl, err := scraper.Url.Parse(attr.Val)
 					if err != nil {
 						return err
 					}
-					if !imgUrl.IsAbs() {
-						doc.Preview.Images = append(doc.Preview.Images, fmt.Sprintf("%s://%s%s", scraper.Url.Scheme, scraper.Url.Host, imgUrl.Path))
-					} else {
-						doc.Preview.Images = append(doc.Preview.Images, attr.Val)
-					}
+					doc.Preview.Images = append(doc.Preview.Images, imgUrl.String())
 
 				}
 			}
 		}
 
-		if hasCanonical && headPassed && scraper.MaxRedirect > 0 {
-			if !canonicalUrl.IsAbs() {
-				absCanonical, err := url.Parse(fmt.Sprintf("%s://%s%s", scraper.Url.Scheme, scraper.Url.Host, canonicalUrl.Path))
-				if err != nil {
-					return err
-				}
-				canonicalUrl = absCanonical
-			}
-			scraper.Url = canonicalUrl
+		if hasCanonical && headPassed && scraper.MaxRedirect > 0 && !scraper.visited(scraper.Url.ResolveReference(canonicalUrl).String()) {
+			scraper.MaxRedirect--
+			scraper.Url = scraper.Url.ResolveReference(canonicalUrl)
 			scraper.EscapedFragmentUrl = nil
-			fdoc, err := scraper.getDocument()
+			fdoc, err := scraper.getDocument(ctx)
 			if err != nil {
 				return err
 			}
 			*doc = *fdoc
-			return scraper.parseDocument(doc)
+			return scraper.parseDocument(ctx, doc)
 		}
 
 		if hasFragment && headPassed && scraper.MaxRedirect > 0 {
+			scraper.MaxRedirect--
 			scraper.toFragmentUrl()
-			fdoc, err := scraper.getDocument()
+			fdoc, err := scraper.getDocument(ctx)
 			if err != nil {
 				return err
 			}
 			*doc = *fdoc
-			return scraper.parseDocument(doc)
+			return scraper.parseDocument(ctx, doc)
 		}
 
 		if len(doc.Preview.Title) > 0 && len(doc.Preview.Description) > 0 && ogImage && headPassed {
@@ -1,13 +1,20 @@
 package link_preview
 
 import (
+	"context"
 	"github.com/PuerkitoBio/goquery"
 	"net/http"
+	"net/http/cookiejar"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/propro-productions/go-utils/fetch"
+	"github.com/propro-productions/go-utils/proxy"
+	"github.com/propro-productions/go-utils/robots"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -74,7 +81,7 @@ func TestGetDocument(t *testing.T) {
 	link := server.URL
 	url, _ := url.Parse(link)
 	scraper := &Scraper{Url: url, MaxRedirect: 10}
-	doc, err := scraper.getDocument()
+	doc, err := scraper.getDocument(context.Background())
 
 	assert.NoError(t, err)
 	assert.NotNil(t, doc)
@@ -85,7 +92,7 @@ func TestGetDocumentFail(t *testing.T) {
 	link := "http://invalidurl.com"
 	url, _ := url.Parse(link)
 	scraper := &Scraper{Url: url, MaxRedirect: 10}
-	_, err := scraper.getDocument()
+	_, err := scraper.getDocument(context.Background())
 
 	assert.Error(t, err)
 }
@@ -102,3 +109,341 @@ func TestParseDocument(t *testing.T) {
 	//
 	//assert.Equal(t, "Test Page", document.PageInfo.PageTitle)
 }
+
+func TestGetDocumentRespectsRobots(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: GoScraper\nDisallow: /private\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Private Page</title></head></html>`))
+	})
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL + "/private")
+
+	_, err := (&Scraper{Url: u, MaxRedirect: 10}).getDocument(context.Background())
+	assert.ErrorIs(t, err, robots.ErrDisallowed)
+
+	doc, err := (&Scraper{Url: u, MaxRedirect: 10, IgnoreRobots: true}).getDocument(context.Background())
+	assert.NoError(t, err)
+	assert.NotNil(t, doc)
+}
+
+type hostRecorder []string
+
+func (h *hostRecorder) Wait(ctx context.Context, host string) error {
+	*h = append(*h, host)
+	return nil
+}
+
+func TestGetDocumentUsesLimiter(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Test Page</title></head></html>`))
+	})
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	limiter := &hostRecorder{}
+	_, err := (&Scraper{Url: u, MaxRedirect: 10, Limiter: limiter}).getDocument(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, hostRecorder{"127.0.0.1"}, *limiter)
+}
+
+func TestGetLinkPreviewItemsContextCancel(t *testing.T) {
+	release := make(chan struct{})
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		<-release
+	})
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := GetLinkPreviewItemsContext(ctx, server.URL, 10)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestGetLinkPreviewItemsRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// Send the head of the page and then stall.
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Slow`))
+		w.(http.Flusher).Flush()
+		<-release
+	})
+	defer server.Close()
+	defer close(release)
+
+	u, _ := url.Parse(server.URL)
+	scraper := &Scraper{Url: u, MaxRedirect: 10, RequestTimeout: 50 * time.Millisecond}
+
+	start := time.Now()
+	_, err := scraper.GetLinkPreviewItemsContext(context.Background())
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestGetLinkPreviewItemsRejectsNonHTML(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "video/mp4")
+		w.Write(make([]byte, 1024))
+	})
+	defer server.Close()
+
+	_, err := GetLinkPreviewItems(server.URL+"/movie.mp4", 10)
+
+	assert.ErrorIs(t, err, ErrNotHTML)
+}
+
+func TestGetLinkPreviewItemsImage(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG\r\n\x1a\n"))
+	})
+	defer server.Close()
+
+	doc, err := GetLinkPreviewItems(server.URL+"/cat.png", 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", doc.Metadata.ContentType)
+	assert.Equal(t, server.URL+"/cat.png", doc.Metadata.Image)
+	assert.Equal(t, []string{server.URL + "/cat.png"}, doc.Preview.Images)
+	assert.Zero(t, doc.Body.Len())
+}
+
+func TestGetDocumentLimitsBody(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>Big</title></head><body>`))
+		w.Write([]byte(strings.Repeat("<p>filler</p>", 10000)))
+	})
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	doc, err := (&Scraper{Url: u, MaxRedirect: 10, MaxBodySize: 1024}).getDocument(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1024, doc.Body.Len())
+}
+
+func TestGetLinkPreviewItemsRedirectChain(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+		case "/b":
+			w.Header().Set("Location", "c/")
+			w.WriteHeader(http.StatusFound)
+		case "/c/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Final</title></head><body><img src="logo.png"></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	defer server.Close()
+
+	doc, err := GetLinkPreviewItems(server.URL+"/a", 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{server.URL + "/a", server.URL + "/b", server.URL + "/c/"}, doc.RedirectChain)
+	assert.Equal(t, server.URL+"/c/", doc.Preview.Link)
+	assert.Equal(t, []string{server.URL + "/c/logo.png"}, doc.Preview.Images)
+}
+
+func TestGetLinkPreviewItemsRedirectLoop(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/a", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	defer server.Close()
+
+	_, err := GetLinkPreviewItems(server.URL+"/a", 10)
+
+	assert.ErrorIs(t, err, ErrRedirectLoop)
+}
+
+func TestGetLinkPreviewItemsTooManyRedirects(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		http.Redirect(w, r, "/"+strconv.Itoa(n+1), http.StatusFound)
+	})
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL + "/0")
+	scraper := &Scraper{Url: u, MaxRedirect: 3}
+	_, err := scraper.GetLinkPreviewItems()
+
+	assert.ErrorIs(t, err, ErrTooManyRedirects)
+	assert.Len(t, scraper.RedirectChain, 4)
+}
+
+func TestScraperHeadersAndCookies(t *testing.T) {
+	var agents, languages, cookies []string
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		agents = append(agents, r.Header.Get("User-Agent"))
+		languages = append(languages, r.Header.Get("Accept-Language"))
+		if c, err := r.Cookie("session"); err == nil {
+			cookies = append(cookies, c.Value)
+		} else {
+			cookies = append(cookies, "")
+		}
+
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+			http.Redirect(w, r, "/feed", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Feed</title></head></html>`))
+	})
+	defer server.Close()
+
+	jar, _ := cookiejar.New(nil)
+	u, _ := url.Parse(server.URL + "/login")
+	scraper := &Scraper{
+		Url:         u,
+		MaxRedirect: 10,
+		UserAgent:   "facebookexternalhit/1.1",
+		Headers:     map[string]string{"Accept-Language": "de", "User-Agent": "ignored"},
+		CookieJar:   jar,
+	}
+	doc, err := scraper.GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Feed", doc.Metadata.Title)
+	assert.Equal(t, []string{"facebookexternalhit/1.1", "facebookexternalhit/1.1"}, agents)
+	assert.Equal(t, []string{"de", "de"}, languages)
+	assert.Equal(t, []string{"", "abc"}, cookies)
+}
+
+func TestScraperDefaultUserAgent(t *testing.T) {
+	var agent string
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		agent = r.Header.Get("User-Agent")
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Page</title></head></html>`))
+	})
+	defer server.Close()
+
+	_, err := GetLinkPreviewItems(server.URL, 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "GoScraper", agent)
+}
+
+func TestScraperProxy(t *testing.T) {
+	var proxied []string
+	proxy := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Through proxy</title></head></html>`))
+	})
+	defer proxy.Close()
+
+	u, _ := url.Parse("http://preview.invalid/page")
+	doc, err := (&Scraper{Url: u, MaxRedirect: 10, ProxyAddr: proxy.URL, IgnoreRobots: true}).GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Through proxy", doc.Metadata.Title)
+	assert.Equal(t, []string{"http://preview.invalid/page"}, proxied)
+}
+
+type fakeRenderer struct {
+	html string
+	urls []string
+}
+
+func (r *fakeRenderer) Fetch(ctx context.Context, rawURL string) (*fetch.Page, error) {
+	r.urls = append(r.urls, rawURL)
+	return &fetch.Page{URL: rawURL, HTML: r.html}, nil
+}
+
+func TestScraperRenderJS(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Loading…</title></head><body><div id="app"></div></body></html>`))
+	})
+	defer server.Close()
+	renderer := &fakeRenderer{html: `<html><head><title>Rendered</title><meta property="og:description" content="From the DOM"></head><body></body></html>`}
+
+	u, _ := url.Parse(server.URL + "/app")
+	doc, err := (&Scraper{Url: u, MaxRedirect: 10, RenderJS: true, Renderer: renderer}).GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Rendered", doc.Metadata.Title)
+	assert.Equal(t, "From the DOM", doc.Metadata.Description)
+	assert.Equal(t, []string{server.URL + "/app"}, renderer.urls)
+}
+
+func TestScraperRenderJSUnavailable(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Shell</title></head></html>`))
+	})
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	_, err := (&Scraper{Url: u, MaxRedirect: 10, RenderJS: true, Renderer: &fetch.ChromeFetcher{ExecPath: "/nonexistent/chrome"}}).GetLinkPreviewItems()
+
+	assert.ErrorIs(t, err, fetch.ErrBrowserUnavailable)
+}
+
+func TestScraperProxyPool(t *testing.T) {
+	var proxied []string
+	good := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Through pool</title></head></html>`))
+	})
+	defer good.Close()
+	dead := createMockServer(func(w http.ResponseWriter, r *http.Request) {})
+	dead.Close()
+	pool, _ := proxy.NewPool(dead.URL, good.URL)
+	pool.MaxFailures = 1
+
+	u, _ := url.Parse("http://preview.invalid/page")
+	scraper := func() *Scraper {
+		return &Scraper{Url: u, MaxRedirect: 10, ProxyPool: pool, ProxyAddr: "http://ignored.invalid:1", IgnoreRobots: true}
+	}
+	_, err := scraper().GetLinkPreviewItems()
+	assert.Error(t, err)
+	doc, err := scraper().GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Through pool", doc.Metadata.Title)
+	assert.Equal(t, []string{"http://preview.invalid/page"}, proxied)
+	assert.False(t, pool.Status()[0].Healthy)
+}
@@ -0,0 +1,85 @@
+package link_preview
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// oEmbed is the subset of an oEmbed response used for previews.
+//
+// See: https://oembed.com/#section2.3
+type oEmbed struct {
+	Type         string `json:"type"`
+	Title        string `json:"title"`
+	AuthorName   string `json:"author_name"`
+	ProviderName string `json:"provider_name"`
+	HTML         string `json:"html"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+// fetchOEmbed requests the oEmbed document at rawURL with the scraper's limits.
+func (scraper *Scraper) fetchOEmbed(ctx context.Context, rawURL string) (*oEmbed, error) {
+	requestTimeout := scraper.RequestTimeout
+	if requestTimeout <= 0 {
+		requestTimeout = DefaultRequestTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	client, err := scraper.newClient(true)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := scraper.request(ctx, client, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oembed %s: status %d", rawURL, resp.StatusCode)
+	}
+
+	maxBodySize := scraper.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+	embed := &oEmbed{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBodySize)).Decode(embed); err != nil {
+		return nil, fmt.Errorf("oembed %s: %w", rawURL, err)
+	}
+	return embed, nil
+}
+
+// applyOEmbed fetches the oEmbed data announced by the document, if any, and adds it to the
+// preview. Errors are ignored so that the HTML-derived preview is kept.
+func (scraper *Scraper) applyOEmbed(ctx context.Context, p *Preview) {
+	if p.OEmbedURL == "" {
+		return
+	}
+	embed, err := scraper.fetchOEmbed(ctx, p.OEmbedURL)
+	if err != nil {
+		scraper.logger().Debug("oembed failed", "url", p.OEmbedURL, "err", err)
+		return
+	}
+
+	p.EmbedType = embed.Type
+	p.EmbedHTML = embed.HTML
+	p.AuthorName = embed.AuthorName
+	p.ProviderName = embed.ProviderName
+	p.ThumbnailURL = embed.ThumbnailURL
+	if p.Title == "" {
+		p.Title = embed.Title
+	}
+	if p.Image == "" {
+		if embed.Type == "photo" && embed.URL != "" {
+			p.Image = embed.URL
+		} else {
+			p.Image = embed.ThumbnailURL
+		}
+	}
+}
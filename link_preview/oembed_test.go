@@ -0,0 +1,72 @@
+package link_preview
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const oEmbedPage = `<html><head>
+<title>Gopher video</title>
+<meta property="og:title" content="Gopher video">
+<link rel="alternate" type="application/json+oembed" href="/oembed?url=%2Fwatch&format=json" title="Gopher video">
+</head><body></body></html>`
+
+func TestGetLinkPreviewItemsOEmbed(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/watch":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(oEmbedPage))
+		case "/oembed":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"type":"video","version":"1.0","title":"Gopher video","author_name":"The Go Team","provider_name":"GoTube","thumbnail_url":"https://img.example.com/thumb.jpg","html":"<iframe src=\"https://example.com/embed/1\"></iframe>","width":480,"height":"270"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	defer server.Close()
+
+	doc, err := GetLinkPreviewItems(server.URL+"/watch", 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/oembed?url=%2Fwatch&format=json", doc.Metadata.OEmbedURL)
+	assert.Equal(t, "video", doc.Metadata.EmbedType)
+	assert.Equal(t, `<iframe src="https://example.com/embed/1"></iframe>`, doc.Metadata.EmbedHTML)
+	assert.Equal(t, "The Go Team", doc.Metadata.AuthorName)
+	assert.Equal(t, "GoTube", doc.Metadata.ProviderName)
+	assert.Equal(t, "https://img.example.com/thumb.jpg", doc.Metadata.ThumbnailURL)
+	assert.Equal(t, "https://img.example.com/thumb.jpg", doc.Metadata.Image)
+}
+
+func TestGetLinkPreviewItemsOEmbedFailure(t *testing.T) {
+	for name, handler := range map[string]http.HandlerFunc{
+		"not found": http.NotFound,
+		"bad json": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"type":`))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/watch":
+					w.Header().Set("Content-Type", "text/html")
+					w.Write([]byte(oEmbedPage))
+				case "/oembed":
+					handler(w, r)
+				default:
+					http.NotFound(w, r)
+				}
+			})
+			defer server.Close()
+
+			doc, err := GetLinkPreviewItems(server.URL+"/watch", 10)
+
+			assert.NoError(t, err)
+			assert.Equal(t, "Gopher video", doc.Metadata.Title)
+			assert.Empty(t, doc.Metadata.EmbedType)
+			assert.Empty(t, doc.Metadata.EmbedHTML)
+		})
+	}
+}
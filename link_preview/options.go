@@ -0,0 +1,98 @@
+package link_preview
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/propro-productions/go-utils/doh"
+	"github.com/propro-productions/go-utils/internal/httpopts"
+	"github.com/propro-productions/go-utils/metrics"
+	"github.com/propro-productions/go-utils/proxy"
+)
+
+// Option sets how the requests of a Scraper are made. It is the same type as search.Option,
+// so a set of options can be built once and used for searches and previews alike.
+type Option = httpopts.Option
+
+// WithUserAgent sets Scraper.UserAgent.
+func WithUserAgent(userAgent string) Option {
+	return httpopts.WithUserAgent(userAgent)
+}
+
+// WithTimeout sets Scraper.RequestTimeout, which bounds every request.
+func WithTimeout(timeout time.Duration) Option {
+	return httpopts.WithTimeout(timeout)
+}
+
+// WithProxy sets Scraper.ProxyAddr.
+func WithProxy(proxyAddr string) Option {
+	return httpopts.WithProxy(proxyAddr)
+}
+
+// WithProxyPool sets Scraper.ProxyPool.
+func WithProxyPool(pool *proxy.Pool) Option {
+	return httpopts.WithProxyPool(pool)
+}
+
+// WithResolver sets Scraper.Resolver.
+func WithResolver(resolver *doh.Resolver) Option {
+	return httpopts.WithResolver(resolver)
+}
+
+// WithHTTPClient sets Scraper.HTTPClient.
+func WithHTTPClient(client *http.Client) Option {
+	return httpopts.WithHTTPClient(client)
+}
+
+// WithLogger sets Scraper.Logger.
+func WithLogger(logger Logger) Option {
+	return httpopts.WithLogger(logger)
+}
+
+// WithMetrics sets Scraper.Metrics.
+func WithMetrics(m metrics.Metrics) Option {
+	return httpopts.WithMetrics(m)
+}
+
+// NewScraper returns a Scraper for rawURL with DefaultMaxRedirect, configured by opts. The
+// other fields can be set on the result.
+func NewScraper(rawURL string, opts ...Option) (*Scraper, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	scraper := &Scraper{Url: u, MaxRedirect: DefaultMaxRedirect}
+	scraper.Apply(opts...)
+	return scraper, nil
+}
+
+// Apply sets the fields of the scraper chosen by opts. Settings that opts leave alone keep
+// their value.
+func (scraper *Scraper) Apply(opts ...Option) {
+	h := httpopts.Apply(opts)
+	if h.UserAgent != "" {
+		scraper.UserAgent = h.UserAgent
+	}
+	if h.Timeout > 0 {
+		scraper.RequestTimeout = h.Timeout
+	}
+	if h.ProxyAddr != "" {
+		scraper.ProxyAddr = h.ProxyAddr
+	}
+	if h.ProxyPool != nil {
+		scraper.ProxyPool = h.ProxyPool
+	}
+	if h.Resolver != nil {
+		scraper.Resolver = h.Resolver
+	}
+	if h.HTTPClient != nil {
+		scraper.HTTPClient = h.HTTPClient
+	}
+	if h.Logger != nil {
+		scraper.Logger = h.Logger
+	}
+	if h.Metrics != nil {
+		scraper.Metrics = h.Metrics
+	}
+}
@@ -0,0 +1,67 @@
+package link_preview
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/propro-productions/go-utils/doh"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewScraper(t *testing.T) {
+	provided := &http.Client{Timeout: time.Second}
+
+	scraper, err := NewScraper("https://example.com/page", WithUserAgent("agent"), WithTimeout(time.Second), WithProxy("http://10.0.0.1:8080"), WithHTTPClient(provided))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/page", scraper.Url.String())
+	assert.Equal(t, DefaultMaxRedirect, scraper.MaxRedirect)
+	assert.Equal(t, "agent", scraper.UserAgent)
+	assert.Equal(t, time.Second, scraper.RequestTimeout)
+	assert.Equal(t, "http://10.0.0.1:8080", scraper.ProxyAddr)
+
+	client, err := scraper.newClient(false)
+	assert.NoError(t, err)
+	assert.NotSame(t, provided, client)
+	assert.Nil(t, provided.CheckRedirect)
+	assert.Nil(t, provided.Transport)
+	assert.Equal(t, time.Second, client.Timeout)
+	proxyURL, _ := client.Transport.(*http.Transport).Proxy(&http.Request{})
+	assert.Equal(t, "10.0.0.1:8080", proxyURL.Host)
+
+	_, err = NewScraper("://bad")
+	assert.Error(t, err)
+}
+
+func TestScraperResolver(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Resolved ` + r.Host + `</title></head></html>`))
+	})
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	var queries []string
+	provider := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query().Get("name")+" "+r.URL.Query().Get("type"))
+		if r.URL.Query().Get("type") == "1" {
+			w.Write([]byte(`{"Status":0,"Answer":[{"type":1,"TTL":300,"data":"127.0.0.1"}]}`))
+			return
+		}
+		w.Write([]byte(`{"Status":0}`))
+	})
+	defer provider.Close()
+	resolver, err := doh.NewResolver(provider.URL + "/dns-query")
+	assert.NoError(t, err)
+
+	scraper, err := NewScraper("http://blocked.test:"+port+"/page", WithResolver(resolver))
+	assert.NoError(t, err)
+	scraper.IgnoreRobots = true
+	doc, err := scraper.GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Resolved blocked.test:"+port, doc.Metadata.Title)
+	assert.ElementsMatch(t, []string{"blocked.test 1", "blocked.test 28"}, queries)
+}
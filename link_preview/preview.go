@@ -0,0 +1,306 @@
+package link_preview
+
+import (
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Preview holds the social metadata of a page. URLs are absolute, resolved against
+// the final (post-redirect) URL of the document.
+type Preview struct {
+	// URL is the final URL the document was fetched from.
+	URL string
+
+	// ContentType is the media type of the response, e.g. "text/html" or "image/png".
+	// For images, the preview holds only URL, Image, SiteName and ContentType.
+	ContentType string
+
+	// Title, Description and Image are read from the main article, product or recipe of the
+	// structured data of the page first, then from OpenGraph and Twitter cards, and then from
+	// the HTML.
+	Title       string
+	Description string
+
+	// Image falls back to og:image, twitter:image and then to the first large <img>.
+	Image string
+	// ImageWidth and ImageHeight are og:image:width and og:image:height, or the size
+	// attributes of the fallback <img>. They are 0 when unknown.
+	ImageWidth  int
+	ImageHeight int
+	// ImageContentType is the media type of Image. It is only set with Scraper.ValidateImages.
+	ImageContentType string
+
+	SiteName string
+	Type     string
+
+	TwitterCard  string
+	TwitterImage string
+
+	CanonicalURL string
+
+	// FaviconURL is the icon of the site: the largest square icon declared by the page up to
+	// Scraper.MaxFaviconSize or, if it declares none, /favicon.ico. With
+	// Scraper.ValidateImages, /favicon.ico is only kept when the server has it.
+	FaviconURL string
+	// FaviconSize is the declared width of FaviconURL in pixels, 0 when unknown.
+	FaviconSize int
+	// FaviconData is FaviconURL as a data: URL. It is only set with Scraper.InlineFavicon,
+	// for icons no larger than Scraper.MaxInlineFaviconBytes.
+	FaviconData string
+
+	// AMPURL is the AMP version of the page, announced by <link rel="amphtml">.
+	AMPURL string
+
+	// OEmbedURL is the JSON oEmbed endpoint announced by the page, if any.
+	OEmbedURL string
+
+	// The following fields are filled from the oEmbed response. They are empty when the
+	// page has no oEmbed endpoint or it could not be fetched.
+	EmbedType    string // "video", "photo", "rich" or "link"
+	EmbedHTML    string
+	AuthorName   string
+	ProviderName string
+	ThumbnailURL string
+
+	// The following fields are filled by the site rules of RegisterSiteRule.
+	Duration time.Duration // The length of a video, from the YouTube rule
+	Stars    int           // The stars of a repository, from the GitHub rule
+	// Extra holds what the rules of other sites find, such as a price.
+	Extra map[string]string
+
+	// The following fields describe the HTTP caching of the page, so that a preview kept by
+	// the caller can be passed back as Scraper.Previous to be revalidated.
+	ETag         string    // The ETag header of the response
+	LastModified string    // The Last-Modified header of the response
+	FetchedAt    time.Time // When the page was fetched or revalidated
+	Expires      time.Time // When the page stops being fresh by its Cache-Control max-age, zero if it must be revalidated
+	// Revalidated is set when the server answered that the page had not changed since
+	// Scraper.Previous, which was returned instead of parsing the page again.
+	Revalidated bool
+
+	// StructuredData lists the schema.org items of the page, from its JSON-LD scripts then
+	// from its microdata. Article, Product and Recipe read the common types.
+	StructuredData []StructuredData
+
+	// Warnings lists what could not be read on the page without failing the preview, such
+	// as malformed JSON-LD.
+	Warnings []string
+
+	// FieldSources maps the fields filled from the AMP or canonical version of the page with
+	// Scraper.FetchAlternate, e.g. "Image", to the URL of that version. The other fields come
+	// from URL.
+	FieldSources map[string]string
+}
+
+const (
+	// minParagraphLength is the length a <p> needs to be used as the description.
+	minParagraphLength = 40
+	// minImageSize is the smallest declared width or height of an <img> used as the image.
+	minImageSize = 100
+)
+
+// maxPageImages bounds how many <img> elements are considered as image candidates.
+const maxPageImages = 10
+
+// imageCandidates are the images a preview may use, in order of preference.
+type imageCandidates struct {
+	// meta holds the image of the structured data, og:image and twitter:image.
+	meta []string
+	// page holds the <img> elements of the body that are not declared small, in document order.
+	page []string
+	// icons are the favicons declared by the page, in document order.
+	icons []favicon
+}
+
+// parsePreview extracts a Preview from the HTML in r. base is the URL of the document.
+func parsePreview(r io.Reader, base *url.URL) (Preview, imageCandidates, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return Preview{}, imageCandidates{}, err
+	}
+	p := Preview{URL: base.String()}
+
+	// A <base href> changes how relative URLs in the document resolve.
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if u, err := base.Parse(strings.TrimSpace(href)); err == nil {
+			base = u
+		}
+	}
+	resolve := func(ref string) string {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			return ""
+		}
+		u, err := base.Parse(ref)
+		if err != nil {
+			return ""
+		}
+		return u.String()
+	}
+
+	meta := map[string]string{}
+
+	doc.Find("meta").Each(func(i int, s *goquery.Selection) {
+		key, ok := s.Attr("property")
+		if !ok {
+			key, _ = s.Attr("name")
+		}
+		key = cleanStr(key)
+		content, _ := s.Attr("content")
+		content = strings.TrimSpace(content)
+		if key == "" || content == "" {
+			return
+		}
+
+		// Pages may list several images; the size tags describe the og:image before them.
+		switch key {
+		case "og:image", "og:image:url", "og:image:secure_url":
+			if p.Image != "" {
+				return
+			}
+			p.Image = resolve(content)
+		case "og:image:width":
+			if p.ImageWidth == 0 {
+				p.ImageWidth, _ = strconv.Atoi(content)
+			}
+		case "og:image:height":
+			if p.ImageHeight == 0 {
+				p.ImageHeight, _ = strconv.Atoi(content)
+			}
+		}
+
+		if _, ok := meta[key]; !ok {
+			meta[key] = content
+		}
+	})
+
+	p.Title = firstNonEmpty(meta["og:title"], meta["twitter:title"], strings.TrimSpace(doc.Find("title").First().Text()))
+	p.Description = firstNonEmpty(meta["og:description"], meta["twitter:description"], meta["description"])
+	p.SiteName = firstNonEmpty(meta["og:site_name"], base.Host)
+	p.Type = meta["og:type"]
+	p.TwitterCard = meta["twitter:card"]
+	p.TwitterImage = resolve(firstNonEmpty(meta["twitter:image"], meta["twitter:image:src"]))
+
+	// Structured data describes the page more reliably than OpenGraph
+	p.StructuredData, p.Warnings = parseStructuredData(doc, resolve)
+	var structuredImage string
+	if item, ok := mainStructuredItem(p.StructuredData); ok {
+		props := item.Properties
+		p.Title = firstNonEmpty(textValue(props["headline"]), textValue(props["name"]), p.Title)
+		p.Description = firstNonEmpty(textValue(props["description"]), p.Description)
+		structuredImage = resolve(imageValue(props["image"]))
+	}
+
+	if p.Description == "" {
+		doc.Find("body p").EachWithBreak(func(i int, s *goquery.Selection) bool {
+			text := strings.Join(strings.Fields(s.Text()), " ")
+			if len(text) >= minParagraphLength {
+				p.Description = text
+				return false
+			}
+			return true
+		})
+	}
+
+	ogImage := p.Image
+	if structuredImage != "" && structuredImage != p.Image {
+		p.Image, p.ImageWidth, p.ImageHeight = structuredImage, 0, 0
+	}
+
+	var images imageCandidates
+	for _, image := range []string{p.Image, ogImage, p.TwitterImage} {
+		if image != "" && !containsString(images.meta, image) {
+			images.meta = append(images.meta, image)
+		}
+	}
+	page := pageImages(doc, resolve)
+	for _, image := range page {
+		images.page = append(images.page, image.src)
+	}
+
+	if p.Image == "" && p.TwitterImage != "" {
+		p.Image = p.TwitterImage
+		p.ImageWidth, p.ImageHeight = 0, 0
+	}
+	if p.Image == "" && len(page) > 0 {
+		p.Image, p.ImageWidth, p.ImageHeight = page[0].src, page[0].width, page[0].height
+	}
+
+	doc.Find("link[rel]").Each(func(i int, s *goquery.Selection) {
+		href := resolve(s.AttrOr("href", ""))
+		if href == "" {
+			return
+		}
+		for _, rel := range strings.Fields(cleanStr(s.AttrOr("rel", ""))) {
+			switch rel {
+			case "canonical":
+				p.CanonicalURL = firstNonEmpty(p.CanonicalURL, href)
+			case "amphtml":
+				p.AMPURL = firstNonEmpty(p.AMPURL, href)
+			case "icon", "apple-touch-icon", "apple-touch-icon-precomposed":
+				images.icons = append(images.icons, parseFavicons(href, s.AttrOr("sizes", ""), rel != "icon")...)
+			case "alternate":
+				switch cleanStr(s.AttrOr("type", "")) {
+				case "application/json+oembed", "text/json+oembed":
+					p.OEmbedURL = firstNonEmpty(p.OEmbedURL, href)
+				}
+			}
+		}
+	})
+	p.FaviconURL, p.FaviconSize = chooseFavicon(images.icons, DefaultMaxFaviconSize)
+	if p.FaviconURL == "" {
+		p.FaviconURL = resolve("/favicon.ico")
+	}
+
+	return p, images, nil
+}
+
+type pageImage struct {
+	src           string
+	width, height int
+}
+
+// pageImages returns up to maxPageImages <img> elements of the body that are not declared
+// smaller than minImageSize.
+func pageImages(doc *goquery.Document, resolve func(string) string) []pageImage {
+	var images []pageImage
+	doc.Find("body img[src]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		ref := strings.TrimSpace(s.AttrOr("src", ""))
+		if ref == "" || strings.HasPrefix(ref, "data:") {
+			return true
+		}
+		w, _ := strconv.Atoi(s.AttrOr("width", ""))
+		h, _ := strconv.Atoi(s.AttrOr("height", ""))
+		if (w > 0 && w < minImageSize) || (h > 0 && h < minImageSize) {
+			return true
+		}
+		if src := resolve(ref); src != "" {
+			images = append(images, pageImage{src: src, width: w, height: h})
+		}
+		return len(images) < maxPageImages
+	})
+	return images
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package link_preview
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePreviewOpenGraph(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post")
+	page := `<html><head>
+<title>Fallback title</title>
+<meta property="og:title" content="OG Title">
+<meta property="og:description" content="OG description">
+<meta property="og:image" content="/images/cover.png">
+<meta property="og:image:width" content="1200">
+<meta property="og:image:height" content="630">
+<meta property="og:image" content="/images/second.png">
+<meta property="og:image:width" content="64">
+<meta property="og:site_name" content="Example">
+<meta property="og:type" content="article">
+<meta name="twitter:card" content="summary_large_image">
+<meta name="twitter:image" content="https://cdn.example.com/card.png">
+<link rel="canonical" href="/blog/post">
+<link rel="apple-touch-icon" href="/apple-touch-icon.png">
+<link rel="shortcut icon" href="static/favicon.png">
+</head><body><p>This paragraph is long enough to be a description but must not be used.</p></body></html>`
+
+	p, _, err := parsePreview(strings.NewReader(page), base)
+
+	assert.NoError(t, err)
+	assert.Equal(t, Preview{
+		URL:          "https://example.com/blog/post",
+		Title:        "OG Title",
+		Description:  "OG description",
+		Image:        "https://example.com/images/cover.png",
+		ImageWidth:   1200,
+		ImageHeight:  630,
+		SiteName:     "Example",
+		Type:         "article",
+		TwitterCard:  "summary_large_image",
+		TwitterImage: "https://cdn.example.com/card.png",
+		CanonicalURL: "https://example.com/blog/post",
+		FaviconURL:   "https://example.com/blog/static/favicon.png",
+	}, p)
+}
+
+func TestParsePreviewFallbacks(t *testing.T) {
+	base, _ := url.Parse("https://example.com/docs/")
+	page := `<html><head><title> Plain title </title></head><body>
+<p>Short intro.</p>
+<p>The first paragraph that is long enough to describe the page is used as its description.</p>
+<img src="data:image/gif;base64,R0lGODlhAQABAAAAACw=">
+<img src="/icons/logo.png" width="32" height="32">
+<img src="hero.jpg" width="800" height="400">
+</body></html>`
+
+	p, _, err := parsePreview(strings.NewReader(page), base)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Plain title", p.Title)
+	assert.Equal(t, "The first paragraph that is long enough to describe the page is used as its description.", p.Description)
+	assert.Equal(t, "https://example.com/docs/hero.jpg", p.Image)
+	assert.Equal(t, 800, p.ImageWidth)
+	assert.Equal(t, 400, p.ImageHeight)
+	assert.Equal(t, "example.com", p.SiteName)
+	assert.Equal(t, "https://example.com/favicon.ico", p.FaviconURL)
+	assert.Empty(t, p.CanonicalURL)
+}
+
+func TestParsePreviewTwitterImageFallback(t *testing.T) {
+	base, _ := url.Parse("https://example.com/")
+	page := `<html><head><meta name="twitter:image:src" content="/card.png"></head><body><img src="/other.png"></body></html>`
+
+	p, _, err := parsePreview(strings.NewReader(page), base)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/card.png", p.Image)
+	assert.Equal(t, "https://example.com/card.png", p.TwitterImage)
+}
+
+func TestGetLinkPreviewItemsMetadataAfterRedirect(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/articles/new", http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><meta property="og:title" content="Moved"><meta property="og:image" content="cover.png"></head><body></body></html>`))
+	})
+	defer server.Close()
+
+	doc, err := GetLinkPreviewItems(server.URL+"/old", 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Moved", doc.Metadata.Title)
+	assert.Equal(t, server.URL+"/articles/new", doc.Metadata.URL)
+	assert.Equal(t, server.URL+"/articles/cover.png", doc.Metadata.Image)
+}
@@ -0,0 +1,165 @@
+package link_preview
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/propro-productions/go-utils/internal/httpopts"
+)
+
+const (
+	// DefaultMaxIdleConns is the size of the pool of idle connections of a Previewer.
+	DefaultMaxIdleConns = 256
+
+	// DefaultMaxIdleConnsPerHost is how many idle connections a Previewer keeps to each host.
+	DefaultMaxIdleConnsPerHost = 16
+
+	// DefaultIdleConnTimeout is how long a Previewer keeps an idle connection open.
+	DefaultIdleConnTimeout = 90 * time.Second
+)
+
+// PreviewerOptions configures a Previewer.
+type PreviewerOptions struct {
+	ScraperOptions
+
+	// Cache, if set, stores the previews like a CachedPreviewer does.
+	Cache PreviewCache
+
+	// TTL and ErrorTTL are the fields of the CachedPreviewer using Cache.
+	TTL      time.Duration
+	ErrorTTL time.Duration
+}
+
+// Previewer previews URLs with the options it was created with. Unlike a Scraper, it keeps
+// no state between previews: every call to Preview uses a Scraper of its own, and all of
+// them share the http.Client of the Previewer, so that connections to a host are kept
+// alive and reused. A Previewer is safe for concurrent use.
+//
+// The options are copied when the Previewer is created, and so are the site rules
+// registered at that time: changing them later does not affect the Previewer.
+type Previewer struct {
+	opts   ScraperOptions
+	client *http.Client
+	rules  []siteRule
+
+	// cached previews through opts.Cache, if set.
+	cached *CachedPreviewer
+}
+
+// NewPreviewer returns a Previewer configured by opts. Unless opts.HTTPClient is set, the
+// requests are made by a client with its own transport, pooling up to
+// DefaultMaxIdleConnsPerHost connections per host. The proxy, resolver and cookie jar of
+// opts are set on that client, or on a copy of opts.HTTPClient, once.
+func NewPreviewer(opts PreviewerOptions) (*Previewer, error) {
+	p, err := newPreviewer(opts.ScraperOptions)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Cache != nil {
+		p.cached = &CachedPreviewer{
+			TTL:       opts.TTL,
+			ErrorTTL:  opts.ErrorTTL,
+			cache:     opts.Cache,
+			opts:      p.opts,
+			calls:     map[string]*previewCall{},
+			previewer: p,
+		}
+	}
+	return p, nil
+}
+
+func newPreviewer(opts ScraperOptions) (*Previewer, error) {
+	client, err := newPreviewerClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Headers != nil {
+		headers := make(map[string]string, len(opts.Headers))
+		for key, value := range opts.Headers {
+			headers[key] = value
+		}
+		opts.Headers = headers
+	}
+	return &Previewer{opts: opts, client: client, rules: registeredSiteRules()}, nil
+}
+
+// newPreviewerClient returns the client shared by the scrapers of a Previewer. Without a
+// client in opts, its transport is its own, so that CloseIdleConnections releases it.
+func newPreviewerClient(opts ScraperOptions) (*http.Client, error) {
+	var client *http.Client
+	if opts.HTTPClient == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.MaxIdleConns = DefaultMaxIdleConns
+		transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+		transport.IdleConnTimeout = DefaultIdleConnTimeout
+		rt, err := httpopts.ConfigureTransport(transport, httpopts.Options{
+			ProxyAddr: opts.ProxyAddr,
+			ProxyPool: opts.ProxyPool,
+			Resolver:  opts.Resolver,
+		})
+		if err != nil {
+			return nil, err
+		}
+		client = &http.Client{Transport: rt}
+	} else {
+		var err error
+		client, err = httpopts.NewClient(httpopts.Options{
+			ProxyAddr:  opts.ProxyAddr,
+			ProxyPool:  opts.ProxyPool,
+			Resolver:   opts.Resolver,
+			HTTPClient: opts.HTTPClient,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c := *client
+	if opts.CookieJar != nil {
+		c.Jar = opts.CookieJar
+	}
+	return &c, nil
+}
+
+// Preview returns the preview of link, from the cache if the Previewer has one.
+func (p *Previewer) Preview(ctx context.Context, link string) (Preview, error) {
+	if p.cached != nil {
+		return p.cached.Preview(ctx, link)
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return Preview{}, err
+	}
+	doc, err := p.scraper(u).GetLinkPreviewItemsContext(ctx)
+	if err != nil {
+		return Preview{}, err
+	}
+	return doc.Metadata, nil
+}
+
+// Scraper returns a Scraper for rawURL configured like the scrapers of the Previewer and
+// sharing its client, for callers that need the whole Document or FetchDocument.
+func (p *Previewer) Scraper(rawURL string) (*Scraper, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return p.scraper(u), nil
+}
+
+// CloseIdleConnections closes the connections kept alive by the client of the Previewer.
+func (p *Previewer) CloseIdleConnections() {
+	p.client.CloseIdleConnections()
+}
+
+// scraper returns a Scraper for u. The proxy, resolver and cookie jar of the options are
+// already set on the shared client.
+func (p *Previewer) scraper(u *url.URL) *Scraper {
+	scraper := p.opts.scraper(u)
+	scraper.HTTPClient = p.client
+	scraper.ProxyAddr, scraper.ProxyPool, scraper.Resolver, scraper.CookieJar = "", nil, nil, nil
+	scraper.siteRules = p.rules
+	return scraper
+}
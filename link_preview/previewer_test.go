@@ -0,0 +1,150 @@
+package link_preview
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newCountingServer returns a server answering every page with a title naming its path,
+// and the counter of the connections it accepted. /redirect/x redirects to /x.
+func newCountingServer(t *testing.T, name string) (*httptest.Server, *int32) {
+	var conns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if target, ok := strings.CutPrefix(r.URL.Path, "/redirect"); ok {
+			http.Redirect(w, r, target, http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><head><title>%s %s</title><meta name="description" content="%s"></head></html>`, name, r.URL.Path, r.Header.Get("X-Test"))
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server, &conns
+}
+
+func TestPreviewerConcurrent(t *testing.T) {
+	servers := make([]*httptest.Server, 3)
+	for i := range servers {
+		servers[i], _ = newCountingServer(t, fmt.Sprint("server", i))
+	}
+
+	previewer, err := NewPreviewer(PreviewerOptions{ScraperOptions: ScraperOptions{
+		MaxRedirect:  1,
+		IgnoreRobots: true,
+		Headers:      map[string]string{"X-Test": "shared"},
+	}})
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			server := servers[i%len(servers)]
+			for j := 0; j < 5; j++ {
+				path := fmt.Sprintf("/page/%d/%d", i, j)
+				link := server.URL + path
+				if j%2 == 0 {
+					link = server.URL + "/redirect" + path
+				}
+
+				p, err := previewer.Preview(context.Background(), link)
+
+				if !assert.NoError(t, err) {
+					return
+				}
+				assert.Equal(t, fmt.Sprintf("server%d %s", i%len(servers), path), p.Title)
+				assert.Equal(t, server.URL+path, p.URL)
+				assert.Equal(t, "shared", p.Description)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestPreviewerReusesConnections(t *testing.T) {
+	server, conns := newCountingServer(t, "server")
+	previewer, err := NewPreviewer(PreviewerOptions{ScraperOptions: ScraperOptions{IgnoreRobots: true}})
+	assert.NoError(t, err)
+	defer previewer.CloseIdleConnections()
+
+	for i := 0; i < 10; i++ {
+		_, err := previewer.Preview(context.Background(), fmt.Sprintf("%s/page/%d", server.URL, i))
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(conns))
+}
+
+func TestPreviewerCopiesOptions(t *testing.T) {
+	server, _ := newCountingServer(t, "server")
+	headers := map[string]string{"X-Test": "before"}
+	previewer, err := NewPreviewer(PreviewerOptions{ScraperOptions: ScraperOptions{IgnoreRobots: true, Headers: headers}})
+	assert.NoError(t, err)
+
+	headers["X-Test"] = "after"
+	p, err := previewer.Preview(context.Background(), server.URL+"/page")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "before", p.Description)
+}
+
+func TestPreviewerCache(t *testing.T) {
+	var requests int32
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Cached</title></head></html>`))
+	})
+	defer server.Close()
+
+	previewer, err := NewPreviewer(PreviewerOptions{
+		ScraperOptions: ScraperOptions{IgnoreRobots: true},
+		Cache:          NewMemoryCache(10),
+	})
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		p, err := previewer.Preview(context.Background(), server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, "Cached", p.Title)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestPreviewerOwnsTransport(t *testing.T) {
+	opts := ScraperOptions{ProxyAddr: "http://127.0.0.1:3128"}
+	first, err := NewPreviewer(PreviewerOptions{ScraperOptions: opts})
+	assert.NoError(t, err)
+	second := NewCachedPreviewer(NewMemoryCache(10), opts)
+	defer second.CloseIdleConnections()
+
+	transport, ok := first.client.Transport.(*http.Transport)
+	if assert.True(t, ok) {
+		assert.Equal(t, DefaultMaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+		proxyURL, err := transport.Proxy(httptest.NewRequest("GET", "http://example.com/", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, "127.0.0.1:3128", proxyURL.Host)
+	}
+	assert.NotSame(t, first.client.Transport, second.previewer.client.Transport)
+}
+
+func TestNewPreviewerInvalidProxy(t *testing.T) {
+	_, err := NewPreviewer(PreviewerOptions{ScraperOptions: ScraperOptions{ProxyAddr: "://bad"}})
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,158 @@
+package link_preview
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// DefaultMaxRefreshDelay is the default for Scraper.MaxRefreshDelay.
+const DefaultMaxRefreshDelay = 5 * time.Second
+
+// locationRegexp matches a script that does nothing but send the browser to a literal URL,
+// such as window.location.href = "/landing" or location.replace('/landing').
+var locationRegexp = regexp.MustCompile(`^(?:(?:window|document|self|top)\.)?location` +
+	`(?:\.href\s*=\s*|\s*=\s*|\.(?:replace|assign)\(\s*)(["'])((?:[^"'\\]|\\.)*)(["'])\s*\)?\s*;?$`)
+
+// clientRedirect returns the target of the meta refresh or script redirect of an interstitial
+// page, resolved against base, or nil if the page has none. Meta refreshes are followed if
+// their delay is at most maxDelay. Tags inside <noscript> are ignored, being meant for
+// browsers without the script redirect that is followed instead.
+func clientRedirect(body []byte, base *url.URL, maxDelay time.Duration) *url.URL {
+	t := html.NewTokenizer(bytes.NewReader(body))
+	noscript := 0
+	for {
+		tokenType := t.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			return nil
+		case html.EndTagToken:
+			if name, _ := t.TagName(); atom.Lookup(name) == atom.Noscript && noscript > 0 {
+				noscript--
+			}
+			continue
+		case html.StartTagToken, html.SelfClosingTagToken:
+		default:
+			continue
+		}
+
+		token := t.Token()
+		var target string
+		switch token.DataAtom {
+		case atom.Noscript:
+			if tokenType == html.StartTagToken {
+				noscript++
+			}
+		case atom.Meta:
+			if noscript > 0 || !strings.EqualFold(strings.TrimSpace(attrValue(token, "http-equiv")), "refresh") {
+				break
+			}
+			delay, ref, ok := parseRefresh(attrValue(token, "content"))
+			if ok && delay <= maxDelay {
+				target = ref
+			}
+		case atom.Script:
+			if tokenType != html.StartTagToken || attrValue(token, "src") != "" || !isJavaScript(attrValue(token, "type")) {
+				break
+			}
+			if t.Next() != html.TextToken {
+				break
+			}
+			if match := locationRegexp.FindStringSubmatch(strings.TrimSpace(string(t.Text()))); match != nil && match[1] == match[3] {
+				target = strings.ReplaceAll(match[2], `\/`, "/")
+			}
+		}
+
+		if target == "" {
+			continue
+		}
+		if u, err := base.Parse(target); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			u.Fragment = ""
+			return u
+		}
+	}
+}
+
+// parseRefresh parses the content of a meta refresh, such as "0; url=https://example.com/".
+// A refresh without a URL reloads the page and is reported as not ok.
+func parseRefresh(content string) (delay time.Duration, target string, ok bool) {
+	content = strings.TrimSpace(content)
+	i := 0
+	for i < len(content) && (content[i] >= '0' && content[i] <= '9' || content[i] == '.') {
+		i++
+	}
+	seconds, err := strconv.ParseFloat(content[:i], 64)
+	if err != nil {
+		return 0, "", false
+	}
+
+	rest := strings.TrimLeft(content[i:], " \t\n;,")
+	if len(rest) >= 3 && strings.EqualFold(rest[:3], "url") {
+		if after := strings.TrimLeft(rest[3:], " \t\n"); strings.HasPrefix(after, "=") {
+			rest = strings.TrimLeft(after[1:], " \t\n")
+		}
+	}
+	if len(rest) > 0 && (rest[0] == '\'' || rest[0] == '"') {
+		if end := strings.IndexByte(rest[1:], rest[0]); end >= 0 {
+			rest = rest[1 : end+1]
+		} else {
+			rest = rest[1:]
+		}
+	}
+	if rest = strings.TrimSpace(rest); rest == "" {
+		return 0, "", false
+	}
+	return time.Duration(seconds * float64(time.Second)), rest, true
+}
+
+// isJavaScript reports whether a script with the given type attribute is run by browsers.
+func isJavaScript(scriptType string) bool {
+	switch strings.ToLower(strings.TrimSpace(scriptType)) {
+	case "", "text/javascript", "application/javascript", "module":
+		return true
+	}
+	return false
+}
+
+// attrValue returns the value of the attribute key of token, or "".
+func attrValue(token html.Token, key string) string {
+	for _, attr := range token.Attr {
+		if strings.EqualFold(attr.Key, key) {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// followClientRedirect fetches the target of the meta refresh or script redirect of doc, if
+// it has one, counting it against MaxRedirect like an HTTP redirect. It returns doc itself
+// otherwise.
+func (scraper *Scraper) followClientRedirect(ctx context.Context, doc *Document) (*Document, error) {
+	maxDelay := scraper.MaxRefreshDelay
+	if maxDelay <= 0 {
+		maxDelay = DefaultMaxRefreshDelay
+	}
+	target := clientRedirect(doc.Body.Bytes(), scraper.Url, maxDelay)
+	if target == nil {
+		return doc, nil
+	}
+
+	rawURL := target.String()
+	scraper.logger().Debug("client redirect", "from", scraper.Url.String(), "to", rawURL)
+	if scraper.visited(rawURL) {
+		return nil, previewError(rawURL, ErrRedirectLoop)
+	}
+	if scraper.MaxRedirect <= 0 {
+		return nil, previewError(rawURL, ErrTooManyRedirects)
+	}
+	scraper.MaxRedirect--
+	scraper.Url, scraper.EscapedFragmentUrl = target, nil
+	return scraper.getDocument(ctx)
+}
@@ -0,0 +1,148 @@
+package link_preview
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRefresh(t *testing.T) {
+	tests := []struct {
+		content string
+		delay   time.Duration
+		target  string
+		ok      bool
+	}{
+		{"0;url=https://example.com/", 0, "https://example.com/", true},
+		{"0; URL='/landing?a=1'", 0, "/landing?a=1", true},
+		{` 3 , url = "/next" `, 3 * time.Second, "/next", true},
+		{"1.5; /plain", 1500 * time.Millisecond, "/plain", true},
+		{"0;url=/x", 0, "/x", true},
+		{"30", 0, "", false},
+		{"; url=/x", 0, "", false},
+		{"0; url=", 0, "", false},
+	}
+	for _, tt := range tests {
+		delay, target, ok := parseRefresh(tt.content)
+		assert.Equal(t, tt.ok, ok, tt.content)
+		if tt.ok {
+			assert.Equal(t, tt.delay, delay, tt.content)
+			assert.Equal(t, tt.target, target, tt.content)
+		}
+	}
+}
+
+func TestClientRedirect(t *testing.T) {
+	base, _ := url.Parse("https://short.example/abc")
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"meta refresh", `<head><meta http-equiv="Refresh" content="0;url=https://dest.example/page#top"></head>`, "https://dest.example/page"},
+		{"relative", `<meta http-equiv="refresh" content="2; url=../landing">`, "https://short.example/landing"},
+		{"too slow", `<meta http-equiv="refresh" content="30; url=/later">`, ""},
+		{"reload", `<meta http-equiv="refresh" content="0">`, ""},
+		{"noscript", `<noscript><meta http-equiv="refresh" content="0; url=/nojs"></noscript><p>Hi</p>`, ""},
+		{"location", `<script>window.location = "https:\/\/dest.example\/js";</script>`, "https://dest.example/js"},
+		{"location href", `<script type="text/javascript"> location.href='/js' </script>`, "https://short.example/js"},
+		{"location replace", `<script>document.location.replace("/replaced");</script>`, "https://short.example/replaced"},
+		{"conditional", `<script>if (mobile) { window.location = "/m"; }</script>`, ""},
+		{"two statements", `<script>track(); window.location = "/m";</script>`, ""},
+		{"external script", `<script src="/app.js">window.location = "/m"</script>`, ""},
+		{"json", `<script type="application/ld+json">location = "/m"</script>`, ""},
+		{"javascript url", `<meta http-equiv="refresh" content="0; url=javascript:alert(1)">`, ""},
+		{"no redirect", `<html><head><title>Page</title></head></html>`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := clientRedirect([]byte(tt.body), base, DefaultMaxRefreshDelay)
+			if tt.want == "" {
+				assert.Nil(t, target)
+			} else if assert.NotNil(t, target) {
+				assert.Equal(t, tt.want, target.String())
+			}
+		})
+	}
+}
+
+func TestScraperFollowsClientRedirects(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/short":
+			w.Write([]byte(`<html><head><title>Redirecting</title><meta http-equiv="refresh" content="0; url=/interstitial"></head></html>`))
+		case "/interstitial":
+			w.Write([]byte(`<html><head><title>Please wait</title></head><body><script>window.location.href = "/hop";</script></body></html>`))
+		case "/hop":
+			http.Redirect(w, r, "/article", http.StatusFound)
+		case "/article":
+			w.Write([]byte(`<html><head><title>The Article</title><meta property="og:description" content="Worth the wait"></head></html>`))
+		}
+	})
+	defer server.Close()
+
+	scraper, _ := NewScraper(server.URL+"/short", WithHTTPClient(server.Client()))
+	scraper.IgnoreRobots = true
+	doc, err := scraper.GetLinkPreviewItemsContext(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "The Article", doc.Metadata.Title)
+	assert.Equal(t, server.URL+"/article", doc.Metadata.URL)
+	assert.Equal(t, []string{server.URL + "/short", server.URL + "/interstitial", server.URL + "/hop", server.URL + "/article"}, doc.RedirectChain)
+	assert.Equal(t, DefaultMaxRedirect-3, scraper.MaxRedirect)
+}
+
+func TestScraperRefreshDelay(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/slow":
+			w.Write([]byte(`<html><head><title>Slow</title><meta http-equiv="refresh" content="10; url=/next"></head></html>`))
+		case "/next":
+			w.Write([]byte(`<html><head><title>Next</title></head></html>`))
+		}
+	})
+	defer server.Close()
+
+	doc, err := (&Scraper{Url: mustParse(t, server.URL+"/slow"), MaxRedirect: 5, IgnoreRobots: true}).GetLinkPreviewItemsContext(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "Slow", doc.Metadata.Title)
+
+	scraper := &Scraper{Url: mustParse(t, server.URL+"/slow"), MaxRedirect: 5, IgnoreRobots: true, MaxRefreshDelay: 10 * time.Second}
+	doc, err = scraper.GetLinkPreviewItemsContext(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "Next", doc.Metadata.Title)
+}
+
+func TestScraperClientRedirectLoop(t *testing.T) {
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/a":
+			w.Write([]byte(`<meta http-equiv="refresh" content="0; url=/b">`))
+		case "/b":
+			w.Write([]byte(`<script>location = "/a"</script>`))
+		}
+	})
+	defer server.Close()
+
+	_, err := (&Scraper{Url: mustParse(t, server.URL+"/a"), MaxRedirect: 10, IgnoreRobots: true}).GetLinkPreviewItemsContext(context.Background())
+	assert.ErrorIs(t, err, ErrRedirectLoop)
+
+	_, err = (&Scraper{Url: mustParse(t, server.URL+"/a"), MaxRedirect: 0, IgnoreRobots: true}).GetLinkPreviewItemsContext(context.Background())
+	assert.ErrorIs(t, err, ErrTooManyRedirects)
+}
+
+func mustParse(t *testing.T, rawURL string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
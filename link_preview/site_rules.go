@@ -0,0 +1,175 @@
+package link_preview
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// SiteRule fixes up the preview p of a page of a known site, overwriting the fields the
+// generic extraction got wrong. doc is the page.
+type SiteRule func(doc *goquery.Document, p *Preview) error
+
+type siteRule struct {
+	pattern string
+	fn      SiteRule
+}
+
+var (
+	siteRulesMu sync.RWMutex
+	siteRules   []siteRule
+)
+
+func init() {
+	RegisterSiteRule("youtube.com", youTubeRule)
+	RegisterSiteRule("*.youtube.com", youTubeRule)
+	RegisterSiteRule("github.com", gitHubRule)
+}
+
+// RegisterSiteRule adds a rule for the pages of the hosts matching hostPattern: either a
+// host such as "example.com", or "*." and a domain such as "*.example.com", which matches
+// its subdomains but not the domain itself. Rules run after the generic extraction, the
+// ones matching a page in registration order. A rule that fails is logged and skipped,
+// leaving the fields it had set.
+//
+// Rules for youtube.com, pulling the duration of videos from the player data, and for
+// github.com, pulling the stars and description of repositories, are registered by
+// default. RegisterSiteRule panics if hostPattern is invalid or fn is nil.
+func RegisterSiteRule(hostPattern string, fn SiteRule) {
+	pattern := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(hostPattern)), ".")
+	if pattern == "" || strings.Contains(strings.TrimPrefix(pattern, "*."), "*") || strings.Contains(pattern, "/") {
+		panic("link_preview: invalid site rule host pattern " + strconv.Quote(hostPattern))
+	}
+	if fn == nil {
+		panic("link_preview: nil site rule for " + hostPattern)
+	}
+
+	siteRulesMu.Lock()
+	defer siteRulesMu.Unlock()
+	siteRules = append(siteRules, siteRule{pattern: pattern, fn: fn})
+}
+
+// registeredSiteRules returns a copy of the rules registered so far.
+func registeredSiteRules() []siteRule {
+	siteRulesMu.RLock()
+	defer siteRulesMu.RUnlock()
+	return append([]siteRule{}, siteRules...)
+}
+
+// matchSiteRules returns the rules for host, in registration order. rules are the rules
+// to match, or nil for the registered ones.
+func matchSiteRules(rules []siteRule, host string) []SiteRule {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	if rules == nil {
+		rules = registeredSiteRules()
+	}
+	var matched []SiteRule
+	for _, rule := range rules {
+		if matchHostPattern(rule.pattern, host) {
+			matched = append(matched, rule.fn)
+		}
+	}
+	return matched
+}
+
+func matchHostPattern(pattern, host string) bool {
+	if domain, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+domain)
+	}
+	return host == pattern
+}
+
+// applySiteRules runs the rules for the host of the page of doc on its preview.
+func (scraper *Scraper) applySiteRules(doc *Document) {
+	rules := matchSiteRules(scraper.siteRules, scraper.Url.Hostname())
+	if len(rules) == 0 {
+		return
+	}
+	page, err := goquery.NewDocumentFromReader(bytes.NewReader(doc.Body.Bytes()))
+	if err != nil {
+		scraper.logger().Debug("site rule failed", "url", scraper.Url.String(), "err", err)
+		return
+	}
+	page.Url = scraper.Url
+	for _, rule := range rules {
+		if err := rule(page, &doc.Metadata); err != nil {
+			scraper.logger().Debug("site rule failed", "url", scraper.Url.String(), "err", err)
+		}
+	}
+}
+
+// youTubeRule sets the duration, title and channel of a watch page from the player data
+// of its scripts, og:title being cut short on long titles.
+func youTubeRule(doc *goquery.Document, p *Preview) error {
+	const marker = "ytInitialPlayerResponse = "
+
+	var script string
+	doc.Find("script").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		text := s.Text()
+		if i := strings.Index(text, marker); i >= 0 {
+			script = text[i+len(marker):]
+			return false
+		}
+		return true
+	})
+	if script == "" {
+		return nil
+	}
+
+	var player struct {
+		VideoDetails struct {
+			Title         string `json:"title"`
+			LengthSeconds string `json:"lengthSeconds"`
+			Author        string `json:"author"`
+		} `json:"videoDetails"`
+	}
+	// The object is followed by more script, which Decode leaves unread
+	if err := json.NewDecoder(strings.NewReader(script)).Decode(&player); err != nil {
+		return fmt.Errorf("youtube player data: %w", err)
+	}
+
+	details := player.VideoDetails
+	if seconds, err := strconv.Atoi(details.LengthSeconds); err == nil {
+		p.Duration = time.Duration(seconds) * time.Second
+	}
+	p.Title = firstNonEmpty(details.Title, p.Title)
+	p.AuthorName = firstNonEmpty(details.Author, p.AuthorName)
+	return nil
+}
+
+// gitHubRule sets the stars and the description of a repository page, whose og:description
+// repeats the description after the name of the repository.
+func gitHubRule(doc *goquery.Document, p *Preview) error {
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return err
+	}
+	if len(strings.Split(strings.Trim(u.Path, "/"), "/")) != 2 {
+		return nil
+	}
+
+	counter := doc.Find("#repo-stars-counter-star").First()
+	if counter.Length() == 0 {
+		return nil
+	}
+	// title holds the exact count, the text a rounded one such as "1.2k"
+	stars := strings.ReplaceAll(firstNonEmpty(counter.AttrOr("title", ""), strings.TrimSpace(counter.Text())), ",", "")
+	n, err := strconv.Atoi(stars)
+	if err != nil {
+		return fmt.Errorf("github stars %q: %w", stars, err)
+	}
+	p.Stars = n
+
+	if about := strings.Join(strings.Fields(doc.Find(".BorderGrid-cell p.f4").First().Text()), " "); about != "" {
+		p.Description = about
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package link_preview
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+// applyFixtureRules returns the preview of the fixture name served at rawURL, with the site
+// rules of its host applied.
+func applyFixtureRules(t *testing.T, name, rawURL string) Preview {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(rawURL)
+	p, _, err := parsePreview(strings.NewReader(string(body)), u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := &Document{Metadata: p}
+	doc.Body.Write(body)
+	(&Scraper{Url: u}).applySiteRules(doc)
+	return doc.Metadata
+}
+
+func TestYouTubeRule(t *testing.T) {
+	p := applyFixtureRules(t, "youtube_watch.html", "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+
+	assert.Equal(t, 21*time.Minute+3*time.Second, p.Duration)
+	assert.Equal(t, "Building a wooden boat in 30 days - part 1 of a very long series about boats", p.Title)
+	assert.Equal(t, "Harbour Workshop", p.AuthorName)
+	assert.Equal(t, "https://i.ytimg.com/vi/dQw4w9WgXcQ/maxresdefault.jpg", p.Image)
+}
+
+func TestGitHubRule(t *testing.T) {
+	p := applyFixtureRules(t, "github_repo.html", "https://github.com/octo-org/widgets")
+
+	assert.Equal(t, 12345, p.Stars)
+	assert.Equal(t, "Small, fast widgets for Go programs", p.Description)
+
+	// Only repository pages
+	p = applyFixtureRules(t, "github_repo.html", "https://github.com/octo-org/widgets/issues")
+	assert.Zero(t, p.Stars)
+}
+
+func TestMatchHostPattern(t *testing.T) {
+	assert.True(t, matchHostPattern("example.com", "example.com"))
+	assert.False(t, matchHostPattern("example.com", "www.example.com"))
+	assert.True(t, matchHostPattern("*.example.com", "www.example.com"))
+	assert.True(t, matchHostPattern("*.example.com", "a.b.example.com"))
+	assert.False(t, matchHostPattern("*.example.com", "example.com"))
+	assert.False(t, matchHostPattern("*.example.com", "notexample.com"))
+}
+
+func TestRegisterSiteRuleInvalid(t *testing.T) {
+	for _, pattern := range []string{"", "*", "www.*.com", "example.com/path"} {
+		assert.Panics(t, func() { RegisterSiteRule(pattern, youTubeRule) }, pattern)
+	}
+	assert.Panics(t, func() { RegisterSiteRule("example.com", nil) })
+}
+
+func TestSiteRules(t *testing.T) {
+	siteRulesMu.Lock()
+	registered := siteRules
+	siteRulesMu.Unlock()
+	defer func() {
+		siteRulesMu.Lock()
+		siteRules = registered
+		siteRulesMu.Unlock()
+	}()
+
+	var calls []string
+	RegisterSiteRule("127.0.0.1", func(doc *goquery.Document, p *Preview) error {
+		calls = append(calls, "first")
+		p.Title = doc.Find("h1").Text()
+		return errors.New("price not found")
+	})
+	RegisterSiteRule("*.0.0.1", func(doc *goquery.Document, p *Preview) error {
+		calls = append(calls, "second")
+		return nil
+	})
+	RegisterSiteRule("127.0.0.1", func(doc *goquery.Document, p *Preview) error {
+		calls = append(calls, "third")
+		p.Extra = map[string]string{"price": doc.Find(".price").Text()}
+		return nil
+	})
+	RegisterSiteRule("other.example", func(doc *goquery.Document, p *Preview) error {
+		calls = append(calls, "other")
+		return nil
+	})
+
+	server := createMockServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><meta property="og:title" content="Widget, blue, 3 pa"></head>
+<body><h1>Widget, blue, 3 pack</h1><span class="price">$12.99</span></body></html>`))
+	})
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	doc, err := (&Scraper{Url: u, MaxRedirect: 10, IgnoreRobots: true}).GetLinkPreviewItems()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first", "second", "third"}, calls)
+	assert.Equal(t, "Widget, blue, 3 pack", doc.Metadata.Title)
+	assert.Equal(t, map[string]string{"price": "$12.99"}, doc.Metadata.Extra)
+}
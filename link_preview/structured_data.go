@@ -0,0 +1,481 @@
+package link_preview
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Sources of StructuredData.
+const (
+	SourceJSONLD    = "json-ld"
+	SourceMicrodata = "microdata"
+)
+
+// StructuredData is a schema.org item of a page, from a JSON-LD script or from microdata.
+type StructuredData struct {
+	// Types are the schema.org types of the item without their context, e.g. ["Product"].
+	Types []string
+
+	// Source is SourceJSONLD or SourceMicrodata.
+	Source string
+
+	// Properties are the properties of the item as decoded from JSON: strings, float64s,
+	// bools, []any and map[string]any. With microdata, values are strings, nested items are
+	// maps with their "@type", and a property given several times is a []any.
+	Properties map[string]any
+}
+
+// Is reports whether the item has one of types.
+func (d StructuredData) Is(types ...string) bool {
+	for _, t := range d.Types {
+		for _, want := range types {
+			if t == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Article is the common data of a schema.org Article, or of one of its subtypes such as
+// NewsArticle and BlogPosting.
+type Article struct {
+	Headline string
+	// Author is the name of the author, or the names of the authors separated by ", ".
+	Author        string
+	DatePublished time.Time
+}
+
+// Product is the common data of a schema.org Product and of its first offer.
+type Product struct {
+	Name  string
+	Price float64
+	// Currency is the ISO 4217 code of Price, e.g. "EUR".
+	Currency string
+	// Availability is the schema.org availability of the offer, e.g. "InStock".
+	Availability string
+}
+
+// Recipe is the common data of a schema.org Recipe.
+type Recipe struct {
+	Name string
+	// Image is the absolute URL of the first image of the recipe.
+	Image string
+	// TotalTime is totalTime, or else the sum of prepTime and cookTime.
+	TotalTime time.Duration
+}
+
+var (
+	articleTypes = []string{"Article", "NewsArticle", "BlogPosting", "TechArticle", "ScholarlyArticle",
+		"Report", "SocialMediaPosting", "LiveBlogPosting", "AnalysisNewsArticle", "OpinionNewsArticle",
+		"ReportageNewsArticle", "ReviewNewsArticle"}
+	productTypes = []string{"Product", "ProductGroup", "ProductModel", "IndividualProduct"}
+	recipeTypes  = []string{"Recipe"}
+)
+
+// Article returns the first article of the structured data of the page.
+func (p *Preview) Article() (Article, bool) {
+	item, ok := p.structuredItem(articleTypes)
+	if !ok {
+		return Article{}, false
+	}
+	props := item.Properties
+	return Article{
+		Headline:      firstNonEmpty(textValue(props["headline"]), textValue(props["name"])),
+		Author:        authorNames(props["author"]),
+		DatePublished: parseSchemaDate(textValue(props["datePublished"])),
+	}, true
+}
+
+// Product returns the first product of the structured data of the page.
+func (p *Preview) Product() (Product, bool) {
+	item, ok := p.structuredItem(productTypes)
+	if !ok {
+		return Product{}, false
+	}
+	product := Product{Name: textValue(item.Properties["name"])}
+
+	// offers is an Offer, an AggregateOffer or a list of them
+	offer, _ := firstValue(item.Properties["offers"]).(map[string]any)
+	if offer != nil {
+		price := firstNonEmpty(textValue(offer["price"]), textValue(offer["lowPrice"]))
+		currency := textValue(offer["priceCurrency"])
+		if spec, ok := firstValue(offer["priceSpecification"]).(map[string]any); ok {
+			price = firstNonEmpty(price, textValue(spec["price"]))
+			currency = firstNonEmpty(currency, textValue(spec["priceCurrency"]))
+		}
+		product.Price = parseSchemaPrice(price)
+		product.Currency = strings.ToUpper(currency)
+		product.Availability = trimSchemaContext(textValue(offer["availability"]))
+	}
+	return product, true
+}
+
+// Recipe returns the first recipe of the structured data of the page.
+func (p *Preview) Recipe() (Recipe, bool) {
+	item, ok := p.structuredItem(recipeTypes)
+	if !ok {
+		return Recipe{}, false
+	}
+	props := item.Properties
+	recipe := Recipe{
+		Name:      textValue(props["name"]),
+		TotalTime: parseSchemaDuration(textValue(props["totalTime"])),
+	}
+	if recipe.TotalTime == 0 {
+		recipe.TotalTime = parseSchemaDuration(textValue(props["prepTime"])) + parseSchemaDuration(textValue(props["cookTime"]))
+	}
+	if image := imageValue(props["image"]); image != "" {
+		recipe.Image = image
+		if base, err := url.Parse(p.URL); err == nil {
+			if u, err := base.Parse(image); err == nil {
+				recipe.Image = u.String()
+			}
+		}
+	}
+	return recipe, true
+}
+
+// structuredItem returns the first item of the structured data of the page with one of types.
+func (p *Preview) structuredItem(types []string) (StructuredData, bool) {
+	for _, item := range p.StructuredData {
+		if item.Is(types...) {
+			return item, true
+		}
+	}
+	return StructuredData{}, false
+}
+
+// parseStructuredData returns the JSON-LD items of doc, then its microdata items, and a
+// warning for every JSON-LD script that could not be decoded. URLs of microdata are
+// resolved with resolve.
+func parseStructuredData(doc *goquery.Document, resolve func(string) string) ([]StructuredData, []string) {
+	var items []StructuredData
+	var warnings []string
+
+	doc.Find(`script[type="application/ld+json"]`).Each(func(i int, s *goquery.Selection) {
+		text := strings.TrimSpace(s.Text())
+		if text == "" {
+			return
+		}
+		var v any
+		if err := json.Unmarshal([]byte(text), &v); err != nil {
+			// Hand written JSON-LD often has trailing commas or line breaks in strings
+			if lenientErr := json.Unmarshal([]byte(lenientJSON(text)), &v); lenientErr != nil {
+				warnings = append(warnings, fmt.Sprintf("json-ld script %d: %v", i+1, err))
+				return
+			}
+		}
+		for _, props := range jsonLDItems(v) {
+			items = append(items, StructuredData{Types: schemaTypes(props["@type"]), Source: SourceJSONLD, Properties: props})
+		}
+	})
+
+	doc.Find("[itemscope]").Each(func(i int, s *goquery.Selection) {
+		// Items with an itemprop are properties of another item
+		if _, ok := s.Attr("itemprop"); ok {
+			return
+		}
+		props := microdataProperties(s, resolve)
+		if len(props) == 0 {
+			return
+		}
+		items = append(items, StructuredData{Types: microdataTypes(s), Source: SourceMicrodata, Properties: props})
+	})
+
+	return items, warnings
+}
+
+// mainStructuredItem returns the first article, product or recipe of items, which describes
+// the page itself rather than its site or breadcrumbs.
+func mainStructuredItem(items []StructuredData) (StructuredData, bool) {
+	for _, item := range items {
+		if item.Is(articleTypes...) || item.Is(productTypes...) || item.Is(recipeTypes...) {
+			return item, true
+		}
+	}
+	return StructuredData{}, false
+}
+
+// jsonLDItems returns the items of a decoded JSON-LD script, which holds an item, a list of
+// items or a @graph of items.
+func jsonLDItems(v any) []map[string]any {
+	switch v := v.(type) {
+	case []any:
+		var items []map[string]any
+		for _, e := range v {
+			items = append(items, jsonLDItems(e)...)
+		}
+		return items
+	case map[string]any:
+		graph, ok := v["@graph"]
+		if !ok {
+			return []map[string]any{v}
+		}
+		items := jsonLDItems(graph)
+		if _, typed := v["@type"]; typed {
+			item := make(map[string]any, len(v)-1)
+			for key, value := range v {
+				if key != "@graph" {
+					item[key] = value
+				}
+			}
+			items = append([]map[string]any{item}, items...)
+		}
+		return items
+	}
+	return nil
+}
+
+// lenientJSON removes the trailing commas of text and escapes the control characters in
+// its strings, and drops the HTML comment or CDATA section the script may be wrapped in.
+func lenientJSON(text string) string {
+	for _, wrapper := range [][2]string{{"<!--", "-->"}, {"//<![CDATA[", "//]]>"}, {"<![CDATA[", "]]>"}} {
+		if trimmed, ok := strings.CutPrefix(text, wrapper[0]); ok {
+			text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(trimmed), wrapper[1]))
+		}
+	}
+
+	var b strings.Builder
+	inString, escaped := false, false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			case c == '\n':
+				b.WriteString(`\n`)
+				continue
+			case c == '\r':
+				b.WriteString(`\r`)
+				continue
+			case c == '\t':
+				b.WriteString(`\t`)
+				continue
+			}
+		case c == '"':
+			inString = true
+		case c == ',':
+			next := strings.TrimLeft(text[i+1:], " \t\r\n")
+			if next != "" && (next[0] == '}' || next[0] == ']') {
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// microdataProperties returns the properties of the microdata item of scope: the values of
+// the elements with an itemprop that belong to it rather than to an item nested in it.
+func microdataProperties(scope *goquery.Selection, resolve func(string) string) map[string]any {
+	props := map[string]any{}
+	scope.Find("[itemprop]").Each(func(i int, s *goquery.Selection) {
+		owner := s.Parent().Closest("[itemscope]")
+		if owner.Length() == 0 || owner.Get(0) != scope.Get(0) {
+			return
+		}
+		value := microdataValue(s, resolve)
+		for _, name := range strings.Fields(s.AttrOr("itemprop", "")) {
+			switch existing := props[name].(type) {
+			case nil:
+				props[name] = value
+			case []any:
+				props[name] = append(existing, value)
+			default:
+				props[name] = []any{existing, value}
+			}
+		}
+	})
+	return props
+}
+
+// microdataValue returns the value of the property element s, as defined by the microdata
+// specification.
+func microdataValue(s *goquery.Selection, resolve func(string) string) any {
+	if _, ok := s.Attr("itemscope"); ok {
+		props := microdataProperties(s, resolve)
+		if types := microdataTypes(s); len(types) > 0 {
+			props["@type"] = types[0]
+		}
+		return props
+	}
+
+	switch goquery.NodeName(s) {
+	case "meta":
+		return strings.TrimSpace(s.AttrOr("content", ""))
+	case "a", "area", "link":
+		return resolve(s.AttrOr("href", ""))
+	case "img", "audio", "video", "source", "embed", "iframe", "track":
+		return resolve(s.AttrOr("src", ""))
+	case "object":
+		return resolve(s.AttrOr("data", ""))
+	case "time":
+		if datetime, ok := s.Attr("datetime"); ok {
+			return strings.TrimSpace(datetime)
+		}
+	case "data", "meter":
+		if value, ok := s.Attr("value"); ok {
+			return strings.TrimSpace(value)
+		}
+	}
+	// Pages often put the value in content on other elements too
+	if content, ok := s.Attr("content"); ok {
+		return strings.TrimSpace(content)
+	}
+	return strings.Join(strings.Fields(s.Text()), " ")
+}
+
+func microdataTypes(s *goquery.Selection) []string {
+	var types []string
+	for _, t := range strings.Fields(s.AttrOr("itemtype", "")) {
+		types = append(types, trimSchemaContext(t))
+	}
+	return types
+}
+
+// schemaTypes returns the types of a JSON-LD @type, a type or a list of types.
+func schemaTypes(v any) []string {
+	var types []string
+	switch v := v.(type) {
+	case string:
+		types = append(types, trimSchemaContext(v))
+	case []any:
+		for _, e := range v {
+			if t, ok := e.(string); ok {
+				types = append(types, trimSchemaContext(t))
+			}
+		}
+	}
+	return types
+}
+
+// trimSchemaContext returns a schema.org type or enumeration member without its context,
+// e.g. "InStock" for "https://schema.org/InStock".
+func trimSchemaContext(s string) string {
+	s = strings.TrimSpace(s)
+	for _, prefix := range []string{"https://schema.org/", "http://schema.org/", "schema:"} {
+		if trimmed, ok := strings.CutPrefix(s, prefix); ok {
+			return trimmed
+		}
+	}
+	return s
+}
+
+// firstValue returns the first element of a list, or v itself.
+func firstValue(v any) any {
+	if list, ok := v.([]any); ok {
+		if len(list) == 0 {
+			return nil
+		}
+		return list[0]
+	}
+	return v
+}
+
+// textValue returns a property as text: a string, a number, or the first of a list.
+func textValue(v any) string {
+	switch v := firstValue(v).(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// imageValue returns the URL of an image property: a URL, an ImageObject or a list of them.
+func imageValue(v any) string {
+	if image, ok := firstValue(v).(map[string]any); ok {
+		return firstNonEmpty(textValue(image["url"]), textValue(image["contentUrl"]))
+	}
+	return textValue(v)
+}
+
+// authorNames returns the names of an author property: a name, a Person or Organization,
+// or a list of them.
+func authorNames(v any) string {
+	list, ok := v.([]any)
+	if !ok {
+		list = []any{v}
+	}
+	var names []string
+	for _, author := range list {
+		var name string
+		if item, ok := author.(map[string]any); ok {
+			name = textValue(item["name"])
+		} else {
+			name = textValue(author)
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// schemaDateLayouts are the ISO 8601 layouts of dates found in structured data.
+var schemaDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseSchemaDate parses an ISO 8601 date, returning the zero time when it is not one.
+func parseSchemaDate(s string) time.Time {
+	for _, layout := range schemaDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// schemaDurationRegexp matches an ISO 8601 duration such as "PT1H30M" or "P1DT2H".
+var schemaDurationRegexp = regexp.MustCompile(`(?i)^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// parseSchemaDuration parses an ISO 8601 duration, returning 0 when it is not one.
+func parseSchemaDuration(s string) time.Duration {
+	m := schemaDurationRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if n, err := strconv.ParseFloat(m[i+1], 64); err == nil {
+			d += time.Duration(n * float64(unit))
+		}
+	}
+	return d
+}
+
+// parseSchemaPrice parses a price, which schema.org wants with a dot for decimals and no
+// thousands separator. A comma is taken for a thousands separator next to a dot, and for a
+// decimal comma otherwise.
+func parseSchemaPrice(s string) float64 {
+	s = strings.ReplaceAll(s, " ", "")
+	if strings.Contains(s, ".") {
+		s = strings.ReplaceAll(s, ",", "")
+	} else {
+		s = strings.ReplaceAll(s, ",", ".")
+	}
+	price, _ := strconv.ParseFloat(s, 64)
+	return price
+}
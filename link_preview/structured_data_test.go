@@ -0,0 +1,146 @@
+package link_preview
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePreviewJSONLDRecipe(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "recipe.html"))
+	assert.NoError(t, err)
+	base, _ := url.Parse("https://smallkitchen.example/recipes/shakshuka")
+
+	p, images, err := parsePreview(strings.NewReader(string(body)), base)
+
+	assert.NoError(t, err)
+	// The @graph with trailing commas is read, the unterminated script is skipped
+	if assert.Len(t, p.StructuredData, 2) {
+		assert.Equal(t, []string{"WebSite"}, p.StructuredData[0].Types)
+		assert.Equal(t, []string{"Recipe"}, p.StructuredData[1].Types)
+		assert.Equal(t, SourceJSONLD, p.StructuredData[1].Source)
+		assert.Equal(t, "4 servings", p.StructuredData[1].Properties["recipeYield"])
+	}
+	if assert.Len(t, p.Warnings, 1) {
+		assert.True(t, strings.HasPrefix(p.Warnings[0], "json-ld script 2: "), p.Warnings[0])
+	}
+
+	// Structured data takes precedence over OpenGraph
+	assert.Equal(t, "Weeknight Shakshuka", p.Title)
+	assert.Equal(t, "Eggs poached in a spiced tomato and pepper sauce, ready in half an hour.", p.Description)
+	assert.Equal(t, "https://smallkitchen.example/images/shakshuka-1200.jpg", p.Image)
+	assert.Equal(t, []string{"https://smallkitchen.example/images/shakshuka-1200.jpg", "https://smallkitchen.example/og/shakshuka.jpg"}, images.meta)
+
+	recipe, ok := p.Recipe()
+	assert.True(t, ok)
+	assert.Equal(t, Recipe{
+		Name:      "Weeknight Shakshuka",
+		Image:     "https://smallkitchen.example/images/shakshuka-1200.jpg",
+		TotalTime: 30 * time.Minute,
+	}, recipe)
+
+	_, ok = p.Article()
+	assert.False(t, ok)
+	_, ok = p.Product()
+	assert.False(t, ok)
+}
+
+func TestParsePreviewJSONLDArticle(t *testing.T) {
+	base, _ := url.Parse("https://news.example/2024/05/rivers")
+	page := `<html><head>
+<title>Rivers - News</title>
+<meta property="og:title" content="Rivers are running dry">
+<script type="application/ld+json">[
+	{"@context": "http://schema.org", "@type": ["NewsArticle", "Article"], "headline": "Rivers are running dry
+across the south", "datePublished": "2024-05-02T08:30:00+02:00",
+	 "author": [{"@type": "Person", "name": "Ana Ruiz"}, "Tom Berg"]}
+]</script>
+</head><body></body></html>`
+
+	p, _, err := parsePreview(strings.NewReader(page), base)
+
+	assert.NoError(t, err)
+	assert.Empty(t, p.Warnings)
+	assert.Equal(t, "Rivers are running dry\nacross the south", p.Title)
+	article, ok := p.Article()
+	assert.True(t, ok)
+	assert.Equal(t, "Rivers are running dry\nacross the south", article.Headline)
+	assert.Equal(t, "Ana Ruiz, Tom Berg", article.Author)
+	assert.True(t, time.Date(2024, 5, 2, 6, 30, 0, 0, time.UTC).Equal(article.DatePublished))
+}
+
+func TestParsePreviewMicrodataProduct(t *testing.T) {
+	base, _ := url.Parse("https://shop.example/p/kettle")
+	page := `<html><head><meta property="og:title" content="Kettle | Shop"></head><body>
+<div itemscope itemtype="https://schema.org/Product">
+	<h1 itemprop="name">Gooseneck Kettle</h1>
+	<img itemprop="image" src="/img/kettle.jpg">
+	<p itemprop="description">A pour over kettle with a thermometer.</p>
+	<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+		<span itemprop="priceCurrency" content="eur">€</span><span itemprop="price" content="49,90">49,90</span>
+		<link itemprop="availability" href="https://schema.org/InStock">
+		<span itemprop="name">Kettle offer</span>
+	</div>
+	<span itemprop="color">black</span> <span itemprop="color">white</span>
+</div>
+</body></html>`
+
+	p, _, err := parsePreview(strings.NewReader(page), base)
+
+	assert.NoError(t, err)
+	if assert.Len(t, p.StructuredData, 1) {
+		item := p.StructuredData[0]
+		assert.Equal(t, SourceMicrodata, item.Source)
+		assert.Equal(t, "Gooseneck Kettle", item.Properties["name"])
+		assert.Equal(t, []any{"black", "white"}, item.Properties["color"])
+	}
+	assert.Equal(t, "Gooseneck Kettle", p.Title)
+	assert.Equal(t, "A pour over kettle with a thermometer.", p.Description)
+	assert.Equal(t, "https://shop.example/img/kettle.jpg", p.Image)
+
+	product, ok := p.Product()
+	assert.True(t, ok)
+	assert.Equal(t, Product{Name: "Gooseneck Kettle", Price: 49.9, Currency: "EUR", Availability: "InStock"}, product)
+}
+
+func TestParsePreviewUnknownStructuredData(t *testing.T) {
+	base, _ := url.Parse("https://example.com/")
+	page := `<html><head><title>Home</title>
+<script type="application/ld+json">{"@context": "https://schema.org", "@type": "Organization", "name": "Example Inc", "sameAs": ["https://social.example/example"]}</script>
+<script type="application/ld+json">{"@type": "Event", "name": </script>
+</head><body></body></html>`
+
+	p, _, err := parsePreview(strings.NewReader(page), base)
+
+	assert.NoError(t, err)
+	// Only articles, products and recipes describe the page
+	assert.Equal(t, "Home", p.Title)
+	if assert.Len(t, p.StructuredData, 1) {
+		assert.True(t, p.StructuredData[0].Is("Organization"))
+		assert.Equal(t, []any{"https://social.example/example"}, p.StructuredData[0].Properties["sameAs"])
+	}
+	assert.Len(t, p.Warnings, 1)
+}
+
+func TestLenientJSON(t *testing.T) {
+	assert.Equal(t, `{"a": [1, 2], "b": "x, ]\n"}`, lenientJSON("<!--\n{\"a\": [1, 2,], \"b\": \"x, ]\n\",}\n-->"))
+}
+
+func TestParseSchemaDuration(t *testing.T) {
+	assert.Equal(t, 90*time.Minute, parseSchemaDuration("PT1H30M"))
+	assert.Equal(t, 26*time.Hour, parseSchemaDuration("P1DT2H"))
+	assert.Equal(t, 45*time.Second, parseSchemaDuration("pt45s"))
+	assert.Equal(t, time.Duration(0), parseSchemaDuration("30 minutes"))
+}
+
+func TestParseSchemaPrice(t *testing.T) {
+	assert.Equal(t, 1299.5, parseSchemaPrice("1,299.50"))
+	assert.Equal(t, 19.99, parseSchemaPrice("19,99"))
+	assert.Equal(t, 7.0, parseSchemaPrice("7"))
+	assert.Equal(t, 0.0, parseSchemaPrice(""))
+}
@@ -4,31 +4,22 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"log"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/mattn/go-runewidth"
+	"github.com/propro-productions/go-utils/internal/mdtable"
+	"github.com/propro-productions/go-utils/metrics"
 
 	"golang.org/x/net/html"
+	"gopkg.in/yaml.v3"
 )
 
-// A regex to escape certain characters
-// \ : since this is the excape character, become weird if printed literally
-// * : Used to start bullet lists, and as a delimiter
-// _ : Used as a delimiter
-// ( and ) : Used in links and images
-// [ and ] : Used in links and images
-// < : can be used to mean "raw HTML" which is allowed
-// > : Used in raw HTML, also used to define blockquotes
-// # : Used for headings
-// + : Can be used for unordered lists
-// - : Can be used for unordered lists
-// ! : Used for images
-// ` : Used for code blocks
-var escapeRegex = regexp.MustCompile(`(` + `\\|\*|_|\[|\]|\(|\)|<|>|#|\+|-|!|` + "`" + `)`)
-
 func isChildOf(node *html.Node, name string) bool {
 	node = node.Parent
 	return node != nil && node.Type == html.ElementNode && strings.ToLower(node.Data) == name
@@ -56,28 +47,74 @@ func attr(node *html.Node, key string) string {
 	return ""
 }
 
-// Gets the language of a code block based on the class
+// Gets the language of a code block based on the class of the <pre>, its <code> child
+// or a GitHub style "highlight highlight-source-go" wrapper
 // See: https://spec.commonmark.org/0.29/#example-112
 func langFromClass(node *html.Node) string {
-	if node.FirstChild == nil || strings.ToLower(node.FirstChild.Data) != "code" {
-		return ""
+	nodes := []*html.Node{node}
+	if node.FirstChild != nil && strings.ToLower(node.FirstChild.Data) == "code" {
+		nodes = append([]*html.Node{node.FirstChild}, nodes...)
+	}
+	for _, n := range nodes {
+		for _, class := range strings.Fields(attr(n, "class")) {
+			if strings.HasPrefix(class, "language-") {
+				return strings.TrimPrefix(class, "language-")
+			}
+		}
 	}
 
-	fChild := node.FirstChild
-	classes := strings.Fields(attr(fChild, "class"))
-	if len(classes) == 0 {
-		return ""
+	if parent := node.Parent; parent != nil && hasClass(parent, "highlight") {
+		for _, class := range strings.Fields(attr(parent, "class")) {
+			if strings.HasPrefix(class, "highlight-source-") {
+				return strings.TrimPrefix(class, "highlight-source-")
+			}
+		}
 	}
 
-	prefix := "language-"
-	for _, class := range classes {
-		if !strings.HasPrefix(class, prefix) {
+	return ""
+}
+
+// longestRun returns the length of the longest run of c in s.
+func longestRun(s string, c byte) int {
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != c {
+			run = 0
 			continue
 		}
-		return strings.TrimPrefix(class, prefix)
+		run++
+		if run > longest {
+			longest = run
+		}
 	}
+	return longest
+}
 
-	return ""
+// fence writes code as a fenced code block. The fence is made longer than any run of
+// backticks in the code.
+func fence(w io.Writer, code, lang string) {
+	delim := "```"
+	if n := longestRun(code, '`'); n >= len(delim) {
+		delim = strings.Repeat("`", n+1)
+	}
+
+	fmt.Fprint(w, delim+lang+"\n")
+	fmt.Fprint(w, code)
+	if !strings.HasSuffix(code, "\n") {
+		fmt.Fprint(w, "\n")
+	}
+	fmt.Fprint(w, delim+"\n\n")
+}
+
+// inlineCode writes code as a code span, using more backticks than the code contains.
+// See: https://spec.commonmark.org/0.29/#code-spans
+func inlineCode(w io.Writer, code string) {
+	code = strings.ReplaceAll(code, "\n", " ")
+	delim := strings.Repeat("`", longestRun(code, '`')+1)
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		code = " " + code + " "
+	}
+	fmt.Fprint(w, delim+code+delim)
 }
 
 func br(node *html.Node, w io.Writer, option *Option) {
@@ -136,62 +173,64 @@ func table(node *html.Node, w io.Writer, option *Option) {
 	fmt.Fprint(w, "\n")
 }
 
+// tableCell renders the content of a <td> or <th> on a single line.
+func tableCell(node *html.Node, option *Option) string {
+	var buf bytes.Buffer
+	walk(node, &buf, 0, option)
+	text := strings.Join(strings.Fields(buf.String()), " ")
+	return strings.ReplaceAll(text, "|", `\|`)
+}
+
+// span reads a colspan or rowspan attribute.
+func span(node *html.Node, key string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(attr(node, key)))
+	if err != nil || n < 1 {
+		return 1
+	}
+	// Browsers cap spans; a huge value would only blow up the table.
+	if n > 100 {
+		return 100
+	}
+	return n
+}
+
 func tableRows(node *html.Node, w io.Writer, option *Option) {
 	var rows [][]string
+	// pending holds the cells still covered by a rowspan, keyed by column.
+	pending := map[int]int{}
 	for tr := node.FirstChild; tr != nil; tr = tr.NextSibling {
 		if tr.Type != html.ElementNode || strings.ToLower(tr.Data) != "tr" {
 			continue
 		}
 		var cols []string
+		fill := func() {
+			for pending[len(cols)] > 0 {
+				pending[len(cols)]--
+				cols = append(cols, "")
+			}
+		}
 		for td := tr.FirstChild; td != nil; td = td.NextSibling {
 			nodeType := strings.ToLower(td.Data)
 			if td.Type != html.ElementNode || (nodeType != "td" && nodeType != "th") {
 				continue
 			}
-			var buf bytes.Buffer
-			walk(td, &buf, 0, option)
-			cols = append(cols, buf.String())
-		}
-		rows = append(rows, cols)
-	}
-
-	maxcol := 0
-	for _, cols := range rows {
-		if len(cols) > maxcol {
-			maxcol = len(cols)
-		}
-	}
-	widths := make([]int, maxcol)
-	for _, cols := range rows {
-		for i := 0; i < maxcol; i++ {
-			if i < len(cols) {
-				width := runewidth.StringWidth(cols[i])
-				if widths[i] < width {
-					widths[i] = width
+			fill()
+			text := tableCell(td, option)
+			rowspan := span(td, "rowspan")
+			for i := span(td, "colspan"); i > 0; i-- {
+				if rowspan > 1 {
+					pending[len(cols)] = rowspan - 1
 				}
+				cols = append(cols, text)
+				// The other columns of a colspan are left empty.
+				text = ""
 			}
 		}
+		fill()
+		rows = append(rows, cols)
 	}
-	for i, cols := range rows {
-		for j := 0; j < maxcol; j++ {
-			fmt.Fprint(w, "|")
-			if j < len(cols) {
-				width := runewidth.StringWidth(cols[j])
-				fmt.Fprint(w, cols[j])
-				fmt.Fprint(w, strings.Repeat(" ", widths[j]-width))
-			} else {
-				fmt.Fprint(w, strings.Repeat(" ", widths[j]))
-			}
-		}
-		fmt.Fprint(w, "|\n")
-		if i == 0 {
-			for j := 0; j < maxcol; j++ {
-				fmt.Fprint(w, "|")
-				fmt.Fprint(w, strings.Repeat("-", widths[j]))
-			}
-			fmt.Fprint(w, "|\n")
-		}
-	}
+	// Without a <thead> the first row is promoted to the header.
+	mdtable.Write(w, rows)
 }
 
 var emptyElements = []string{
@@ -230,6 +269,8 @@ func bq(node *html.Node, w io.Writer, option *Option) {
 func pre(node *html.Node, w io.Writer, option *Option) {
 	if node.Type == html.TextNode {
 		fmt.Fprint(w, node.Data)
+	} else if node.Type == html.ElementNode && strings.ToLower(node.Data) == "br" {
+		fmt.Fprint(w, "\n")
 	} else {
 		for c := node.FirstChild; c != nil; c = c.NextSibling {
 			pre(c, w, option)
@@ -237,287 +278,516 @@ func pre(node *html.Node, w io.Writer, option *Option) {
 	}
 }
 
-// In the spec, https://spec.commonmark.org/0.29/#delimiter-run
-// A  left-flanking delimiter run should not followed by Unicode whitespace
-// A  right-flanking delimiter run should not preceded by Unicode whitespace
-// This will wrap the delimiter (such as **) around the non-whitespace contents, but preserve the whitespace
-func aroundNonWhitespace(node *html.Node, w io.Writer, nest int, option *Option, before, after string) {
-	buf := &bytes.Buffer{}
+// list writes the items of a <ul> or <ol>. Nested lists are rendered by the items
+// themselves and indented along with the rest of their content.
+func list(node *html.Node, w io.Writer, option *Option) {
+	option = option.Clone()
+	option.TrimSpace = true
+
+	ordered := strings.ToLower(node.Data) == "ol"
+	n, err := strconv.Atoi(strings.TrimSpace(attr(node, "start")))
+	if err != nil {
+		n = 1
+	}
+	for li := node.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || strings.ToLower(li.Data) != "li" {
+			continue
+		}
+		marker := option.bulletMarker() + " "
+		if ordered {
+			marker = strconv.Itoa(n) + ". "
+			n++
+		}
+		listItem(li, w, marker, option)
+	}
+}
 
-	walk(node, buf, nest, option)
-	s := buf.String()
+// listItem writes the content of a <li> after marker. Continuation lines are indented
+// by ListIndent, or by the width of the marker when it is wider, so that paragraphs,
+// code blocks and nested lists stay inside the item.
+func listItem(node *html.Node, w io.Writer, marker string, option *Option) {
+	var buf bytes.Buffer
+	walk(node, &buf, 1, option)
 
-	// If the contents are simply whitespace, return without adding any delimiters
-	if strings.TrimSpace(s) == "" {
-		fmt.Fprint(w, s)
-		return
+	indent := option.ListIndent
+	if indent <= 0 {
+		indent = 2
+	}
+	if indent < len(marker) {
+		indent = len(marker)
 	}
 
-	start := 0
-	for ; start < len(s); start++ {
-		c := s[start]
-		if !unicode.IsSpace(rune(c)) {
-			break
+	for i, l := range blockLines(buf.String()) {
+		switch {
+		case i == 0:
+			fmt.Fprint(w, marker+l+"\n")
+		case l == "":
+			fmt.Fprint(w, "\n")
+		default:
+			fmt.Fprint(w, strings.Repeat(" ", indent)+l+"\n")
 		}
 	}
+}
 
-	stop := len(s)
-	for ; stop > start; stop-- {
-		c := s[stop-1]
-		if !unicode.IsSpace(rune(c)) {
-			break
+// blockLines splits markdown into lines for nesting it in a list item or a quote. The
+// blank lines around it are dropped, and those between its blocks are collapsed to
+// one, except inside code fences.
+func blockLines(markdown string) []string {
+	var lines []string
+	fenced, blank := false, false
+	for _, l := range strings.Split(strings.Trim(markdown, "\n"), "\n") {
+		l = trimLine(l)
+		if strings.HasPrefix(strings.TrimSpace(l), "```") {
+			fenced = !fenced
+		}
+		if l == "" && !fenced {
+			blank = len(lines) > 0
+			continue
 		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, l)
 	}
+	return lines
+}
 
-	s = s[:start] + before + s[start:stop] + after + s[stop:]
-
-	fmt.Fprint(w, s)
+func (o *Option) bulletMarker() string {
+	if o.BulletListMarker == "" {
+		return "*"
+	}
+	return o.BulletListMarker
 }
 
-// traverse through the node and its children, and write the result to w
-// change the html tag to markdown syntax
-func walk(node *html.Node, w io.Writer, nest int, option *Option) {
-	if node.Type == html.TextNode {
-		if option.TrimSpace && strings.TrimSpace(node.Data) == "" {
-			return
-		}
+// linkTextReplacer escapes the brackets in the alt text of images
+var linkTextReplacer = strings.NewReplacer("[", `\[`, "]", `\]`)
 
-		text := regexp.MustCompile(`[[:space:]][[:space:]]*`).ReplaceAllString(strings.Trim(node.Data, "\t\r\n"), " ")
+// destinationReplacer escapes the characters that would end a link destination early
+var destinationReplacer = strings.NewReplacer("(", "%28", ")", "%29", " ", "%20", "<", "%3C", ">", "%3E")
 
-		if !option.doNotEscape {
-			text = escapeRegex.ReplaceAllStringFunc(text, func(str string) string {
-				// return `\` + str
-				return "" + str
-			})
+// destination resolves ref against option.BaseURL and escapes it for use in a link
+func destination(ref string, option *Option) string {
+	ref = strings.TrimSpace(ref)
+	if option.BaseURL != nil {
+		if u, err := option.BaseURL.Parse(ref); err == nil {
+			ref = u.String()
 		}
-		fmt.Fprint(w, text)
 	}
+	return destinationReplacer.Replace(ref)
+}
 
-	n := 0
-	for c := node.FirstChild; c != nil; c = c.NextSibling {
-		switch c.Type {
-		case html.CommentNode:
-			fmt.Fprint(w, "<!--")
-			fmt.Fprint(w, c.Data)
-			fmt.Fprint(w, "-->\n")
-		case html.ElementNode:
-			customWalk, ok := option.customRulesMap[strings.ToLower(c.Data)]
-			if ok {
-				customWalk(c, w, nest, option)
-				break
-			}
+// link writes an <a> as [text](href "title"). Links without a usable href are written
+// as their text.
+func link(node *html.Node, w io.Writer, nest int, option *Option) {
+	href := strings.TrimSpace(attr(node, "href"))
+	if href == "" || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		walk(node, w, nest, option)
+		return
+	}
 
-			switch strings.ToLower(c.Data) {
-			case "a":
-				// Links are invalid in markdown if the link text extends beyond a single line
-				// So we render the contents and strip any spaces
-				href := attr(c, "href")
-				end := fmt.Sprintf("](%s)", href)
-				title := attr(c, "title")
-				if title != "" {
-					end = fmt.Sprintf("](%s %q)", href, title)
-				}
-				aroundNonWhitespace(c, w, nest, option, "[", end)
-			case "b", "strong":
-				aroundNonWhitespace(c, w, nest, option, "**", "**")
-			case "i", "em":
-				aroundNonWhitespace(c, w, nest, option, "_", "_")
-			case "del", "s":
-				aroundNonWhitespace(c, w, nest, option, "~~", "~~")
-			case "br":
-				br(c, w, option)
-				fmt.Fprint(w, "\n\n")
-			case "p":
-				br(c, w, option)
-				walk(c, w, nest, option)
-				br(c, w, option)
-				fmt.Fprint(w, "\n\n")
-			case "code":
-				if !isChildOf(c, "pre") {
-					fmt.Fprint(w, "`")
-					pre(c, w, option)
-					fmt.Fprint(w, "`")
-				}
-			case "pre":
-				br(c, w, option)
+	end := fmt.Sprintf("](%s)", destination(href, option))
+	if title := attr(node, "title"); title != "" {
+		end = fmt.Sprintf("](%s %q)", destination(href, option), title)
+	}
 
-				clone := option.Clone()
-				clone.doNotEscape = true
+	clone := option.Clone()
+	clone.inLink = true
+	var buf bytes.Buffer
+	walk(node, &buf, nest, clone)
 
-				var buf bytes.Buffer
-				pre(c, &buf, clone)
-				inner := buf.String()
+	// Links are invalid in markdown if the link text extends beyond a single line,
+	// so anchors wrapping block elements are collapsed to one line
+	text := buf.String()
+	if strings.Contains(text, "\n") {
+		text = strings.Join(strings.Fields(text), " ")
+	}
+	if strings.TrimSpace(text) == "" {
+		fmt.Fprint(w, text)
+		return
+	}
 
-				var lang string = langFromClass(c)
-				if option != nil && option.GuessLang != nil {
-					if guess, err := option.GuessLang(buf.String()); err == nil {
-						lang = guess
-					}
-				}
+	start := len(text) - len(strings.TrimLeftFunc(text, unicode.IsSpace))
+	stop := len(strings.TrimRightFunc(text, unicode.IsSpace))
+	fmt.Fprint(w, text[:start]+"["+text[start:stop]+end+text[stop:])
+}
 
-				fmt.Fprint(w, "```"+lang+"\n")
-				fmt.Fprint(w, inner)
-				if !strings.HasSuffix(inner, "\n") {
-					fmt.Fprint(w, "\n")
-				}
-				fmt.Fprint(w, "```\n\n")
-			case "div":
-				br(c, w, option)
-				walk(c, w, nest, option)
-				fmt.Fprint(w, "\n")
-			case "blockquote":
-				br(c, w, option)
-				var buf bytes.Buffer
-				if hasClass(c, "code") {
-					bq(c, &buf, option)
-					var lang string
-					if option != nil && option.GuessLang != nil {
-						if guess, err := option.GuessLang(buf.String()); err == nil {
-							lang = guess
-						}
-					}
-					fmt.Fprint(w, "```"+lang+"\n")
-					fmt.Fprint(w, strings.TrimLeft(buf.String(), "\n"))
-					if !strings.HasSuffix(buf.String(), "\n") {
-						fmt.Fprint(w, "\n")
-					}
-					fmt.Fprint(w, "```\n\n")
-				} else {
-					walk(c, &buf, nest+1, option)
+// image writes an image as ![alt](src "title")
+func image(w io.Writer, src, alt, title string, option *Option) {
+	if strings.TrimSpace(src) == "" {
+		return
+	}
 
-					if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) > 0 {
-						for _, l := range lines {
-							fmt.Fprint(w, "> "+strings.TrimSpace(l)+"\n")
-						}
-						fmt.Fprint(w, "\n")
-					}
-				}
-			case "ul", "ol":
-				br(c, w, option)
+	alt = linkTextReplacer.Replace(alt)
+	if title != "" {
+		fmt.Fprintf(w, "![%s](%s %q)", alt, destination(src, option), title)
+		return
+	}
+	fmt.Fprintf(w, "![%s](%s)", alt, destination(src, option))
+}
 
-				var newOption = option.Clone()
-				newOption.TrimSpace = true
+// heading writes an <h1> to <h6> with a blank line before and after it
+func heading(node *html.Node, w io.Writer, nest int, option *Option) {
+	level := int(node.Data[1] - '0')
 
-				var buf bytes.Buffer
-				walk(c, &buf, nest+1, newOption)
+	// Blocks end with a blank line already, inline content only with br()
+	br(node, w, option)
+	if prev := node.PrevSibling; prev != nil && prev.Type == html.TextNode && strings.TrimSpace(prev.Data) != "" {
+		fmt.Fprint(w, "\n")
+	}
 
-				// Remove any empty lines in the list
-				if lines := strings.Split(buf.String(), "\n"); len(lines) > 0 {
-					for i, l := range lines {
-						if strings.TrimSpace(l) == "" {
-							continue
-						}
+	if option.HeadingStyle == HeadingSetext && level <= 2 {
+		var buf bytes.Buffer
+		walk(node, &buf, nest, option)
+		text := strings.Join(strings.Fields(buf.String()), " ")
+		if text != "" {
+			underline := "="
+			if level == 2 {
+				underline = "-"
+			}
+			fmt.Fprint(w, text+"\n"+strings.Repeat(underline, runewidth.StringWidth(text))+"\n\n")
+			return
+		}
+	}
 
-						if i > 0 {
-							fmt.Fprint(w, "\n")
-						}
+	fmt.Fprint(w, strings.Repeat("#", level)+" ")
+	walk(node, w, nest, option)
+	fmt.Fprint(w, "\n\n")
+}
 
-						fmt.Fprint(w, l)
-					}
-					fmt.Fprint(w, "\n")
-					if nest == 0 {
-						fmt.Fprint(w, "\n")
-					}
+// wrapProtected matches the code spans and links that wrap() must not break
+var wrapProtected = regexp.MustCompile("`+[^`]*`+" + `|!?\[[^\]]*\]\([^)]*\)`)
+
+// wrap hard-wraps every line of text at width runes. Lines only break at spaces outside
+// of code spans and links; words longer than width are kept whole.
+func wrap(text string, width int) string {
+	// Protect the spaces of code spans and links from being used as break points
+	text = wrapProtected.ReplaceAllStringFunc(text, func(s string) string {
+		return strings.ReplaceAll(s, " ", "\x00")
+	})
+
+	var b strings.Builder
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		// The spaces of a hard line break are not a break point
+		suffix := ""
+		if trimmed := trimLine(line); strings.HasSuffix(trimmed, hardBreak) {
+			line, suffix = strings.TrimSuffix(trimmed, hardBreak), hardBreak
+		}
+		length := 0
+		for j, word := range strings.Split(line, " ") {
+			n := utf8.RuneCountInString(word)
+			if j > 0 {
+				// A word that would start a block at the beginning of a line stays on this one
+				if length > 0 && length+1+n > width && !lineStartRegex.MatchString(word) && word != "*" {
+					b.WriteString("\n")
+					length = 0
+				} else {
+					b.WriteString(" ")
+					length++
 				}
-			case "li":
-				br(c, w, option)
+			}
+			b.WriteString(word)
+			length += n
+		}
+		b.WriteString(suffix)
+	}
+	return strings.ReplaceAll(b.String(), "\x00", " ")
+}
 
-				var buf bytes.Buffer
-				walk(c, &buf, 0, option)
+// quote writes markdown as a block quote. Indentation is kept so that code blocks and
+// nested lists survive, and a nested quote gets a second ">".
+func quote(w io.Writer, markdown string) {
+	lines := blockLines(markdown)
+	if len(lines) == 0 {
+		return
+	}
+
+	for _, l := range lines {
+		if l == "" {
+			fmt.Fprint(w, ">\n")
+			continue
+		}
+		fmt.Fprint(w, "> "+l+"\n")
+	}
+	fmt.Fprint(w, "\n")
+}
 
-				markPrinted := false
+// definitionList writes the terms and definitions of a <dl>. With DefinitionLists they
+// use the "Term\n: Definition" extension syntax, otherwise the terms are made bold.
+func definitionList(node *html.Node, w io.Writer, nest int, option *Option) {
+	inDefinition := false
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
 
-				for _, l := range strings.Split(buf.String(), "\n") {
-					if strings.TrimSpace(l) == "" {
-						continue
-					}
-					// if markPrinted {
+		var buf bytes.Buffer
+		walk(c, &buf, nest, option)
+		switch strings.ToLower(c.Data) {
+		case "dt":
+			term := strings.Join(strings.Fields(buf.String()), " ")
+			if term == "" {
+				continue
+			}
+			if inDefinition && option.DefinitionLists {
+				fmt.Fprint(w, "\n")
+			}
+			inDefinition = false
+			if option.DefinitionLists {
+				fmt.Fprint(w, term+"\n")
+			} else {
+				fmt.Fprint(w, "**"+term+"**\n\n")
+			}
+		case "dd":
+			lines := blockLines(buf.String())
+			if len(lines) == 0 {
+				continue
+			}
+			inDefinition = true
+			if !option.DefinitionLists {
+				fmt.Fprint(w, strings.Join(lines, "\n")+"\n\n")
+				continue
+			}
+			for i, l := range lines {
+				switch {
+				case i == 0:
+					fmt.Fprint(w, ": "+l+"\n")
+				case l == "":
+					fmt.Fprint(w, "\n")
+				default:
+					fmt.Fprint(w, "  "+l+"\n")
+				}
+			}
+		}
+	}
+	if inDefinition && option.DefinitionLists {
+		fmt.Fprint(w, "\n")
+	}
+}
 
-					// }
-					if markPrinted {
-						fmt.Fprint(w, "\n    ")
-					}
+// paragraph writes node, a <p> or a block inferred to be one, as a paragraph.
+func paragraph(node *html.Node, w io.Writer, nest int, option *Option) {
+	br(node, w, option)
+	if option.WrapWidth > 0 {
+		var buf bytes.Buffer
+		walk(node, &buf, nest, option)
+		fmt.Fprint(w, wrap(buf.String(), option.WrapWidth))
+	} else {
+		walk(node, w, nest, option)
+	}
+	br(node, w, option)
+	fmt.Fprint(w, "\n\n")
+}
 
-					fmt.Fprint(w, strings.Repeat("    ", nest-1))
+// traverse through the node and its children, and write the result to w
+// change the html tag to markdown syntax
+func walk(node *html.Node, w io.Writer, nest int, option *Option) {
+	if applyRule(node, w, nest, option) {
+		return
+	}
 
-					if !markPrinted {
-						if isChildOf(c, "ul") {
-							fmt.Fprint(w, "* ")
-						} else if isChildOf(c, "ol") {
-							n++
-							fmt.Fprint(w, fmt.Sprintf("%d. ", n))
-						}
+	if node.Type == html.TextNode {
+		if option.TrimSpace && strings.TrimSpace(node.Data) == "" {
+			return
+		}
 
-						markPrinted = true
-					}
+		text := normalizeText(node, option)
+		if !option.doNotEscape {
+			text = escapeText(text, atLineEdge(node, false, option))
+		}
+		fmt.Fprint(w, text)
+	}
 
-					fmt.Fprint(w, l)
-				}
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		walkChild(c, w, nest, option)
+	}
+}
 
-				fmt.Fprint(w, "\n")
+// walkChild writes the result of c, a child of the node being walked, to w
+func walkChild(c *html.Node, w io.Writer, nest int, option *Option) {
+	switch c.Type {
+	case html.CommentNode:
+		if option.dropped(c) {
+			break
+		}
+		fmt.Fprint(w, "<!--")
+		fmt.Fprint(w, c.Data)
+		fmt.Fprint(w, "-->\n")
+	case html.ElementNode:
+		if option.dropped(c) {
+			break
+		}
+		if applyRule(c, w, nest, option) {
+			break
+		}
+		if option.inFootnote && isBacklink(c) {
+			break
+		}
+		if option.unwrapped(c) {
+			walk(c, w, nest, option)
+			break
+		}
+		if option.notes != nil && !option.inFootnote {
+			if id, ok := noteRef(c); ok {
+				footnoteRef(id, w, option)
+				break
+			}
+			if isNoteList(c) {
+				br(c, w, option)
+				footnoteList(c, w, option)
+				break
+			}
+		}
 
-			case "h1", "h2", "h3", "h4", "h5", "h6":
+		if option.InferBlocks {
+			switch inferBlock(c, option) {
+			case inferParagraph:
+				inferredParagraph(c, w, nest, option)
+				return
+			case inferDiv:
 				br(c, w, option)
-				fmt.Fprint(w, strings.Repeat("#", int(rune(c.Data[1])-rune('0')))+" ")
 				walk(c, w, nest, option)
-				fmt.Fprint(w, "\n\n")
-			// how do I handle this?
-			// I will need to add a new option to the parser
-			// adding a new parser is not a good idea
-			case "img":
-				src := attr(c, "src")
-				alt := attr(c, "alt")
-				title := attr(c, "title")
-
-				if src == "" {
-					break
-				}
+				fmt.Fprint(w, "\n")
+				return
+			}
+		}
 
-				full := fmt.Sprintf("![%s](%s)", alt, src)
-				if title != "" {
-					full = fmt.Sprintf("![%s](%s %q)", alt, src, title)
-				}
+		switch strings.ToLower(c.Data) {
+		case "head":
+			// Metadata, not content.
+		case "a":
+			link(c, w, nest, option)
+		case "b", "strong":
+			emphasis(c, w, nest, option, emphasisStrong)
+		case "i", "em":
+			emphasis(c, w, nest, option, emphasisEm)
+		case "del", "s":
+			emphasis(c, w, nest, option, emphasisDel)
+		case "sub", "sup":
+			subSup(c, w, nest, option)
+		case "br":
+			lineBreak(c, w, option)
+		case "p":
+			paragraph(c, w, nest, option)
+		case "code":
+			if !isChildOf(c, "pre") {
+				var buf bytes.Buffer
+				pre(c, &buf, option)
+				inlineCode(w, buf.String())
+			}
+		case "pre":
+			br(c, w, option)
 
-				fmt.Fprint(w, full)
-			case "source":
-				src := attr(c, "srcSet")
-				alt := attr(c, "alt")
-				title := attr(c, "title")
+			var buf bytes.Buffer
+			pre(c, &buf, option)
 
-				if src == "" {
-					break
+			lang := langFromClass(c)
+			if lang == "" && option != nil && option.GuessLang != nil {
+				if guess, err := option.GuessLang(buf.String()); err == nil {
+					lang = guess
 				}
+			}
 
-				full := fmt.Sprintf("![%s](%s)", alt, src)
-				if title != "" {
-					full = fmt.Sprintf("![%s](%s %q)", alt, src, title)
+			fence(w, buf.String(), lang)
+		case "div":
+			br(c, w, option)
+			walk(c, w, nest, option)
+			fmt.Fprint(w, "\n")
+		case "blockquote":
+			br(c, w, option)
+			var buf bytes.Buffer
+			if hasClass(c, "code") {
+				bq(c, &buf, option)
+				var lang string
+				if option != nil && option.GuessLang != nil {
+					if guess, err := option.GuessLang(buf.String()); err == nil {
+						lang = guess
+					}
 				}
-
-				fmt.Fprint(w, full)
-			case "hr":
+				fence(w, strings.TrimLeft(buf.String(), "\n"), lang)
+			} else {
+				walk(c, &buf, nest+1, option)
+				quote(w, buf.String())
+			}
+		case "dl":
+			br(c, w, option)
+			definitionList(c, w, nest, option)
+		case "ul", "ol":
+			br(c, w, option)
+			list(c, w, option)
+			if nest == 0 {
+				fmt.Fprint(w, "\n")
+			}
+		case "li":
+			// A list item outside of a list.
+			br(c, w, option)
+			listItem(c, w, option.bulletMarker()+" ", option)
+
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			if option.inLink {
+				// A heading can't be part of the text of a link
+				walk(c, w, nest, option)
+				fmt.Fprint(w, "\n")
+				break
+			}
+			heading(c, w, nest, option)
+		// how do I handle this?
+		// I will need to add a new option to the parser
+		// adding a new parser is not a good idea
+		case "img":
+			src := attr(c, "src")
+			if src == "" {
+				// Lazy loaded images keep their URL in data-src
+				src = attr(c, "data-src")
+			}
+			image(w, src, attr(c, "alt"), attr(c, "title"), option)
+		case "source":
+			// srcset is a list of "url descriptor" candidates, use the first one
+			src := strings.TrimSpace(strings.Split(attr(c, "srcset"), ",")[0])
+			if fields := strings.Fields(src); len(fields) > 0 {
+				src = fields[0]
+			}
+			image(w, src, attr(c, "alt"), attr(c, "title"), option)
+		case "iframe", "video", "audio":
+			embed(c, w, option)
+		case "hr":
+			br(c, w, option)
+			fmt.Fprint(w, "\n---\n\n")
+		case "table":
+			br(c, w, option)
+			if option.PlainTables {
+				walk(c, w, nest, option)
+				fmt.Fprint(w, "\n")
+				break
+			}
+			table(c, w, option)
+		case "tr":
+			// Only reached for plain tables, pipe tables render their rows themselves.
+			walk(c, w, nest, option)
+			fmt.Fprint(w, "\n")
+		case "td", "th":
+			walk(c, w, nest, option)
+			fmt.Fprint(w, " ")
+		case "style":
+			if option != nil && option.Style {
 				br(c, w, option)
-				fmt.Fprint(w, "\n---\n\n")
-			case "table":
+				raw(c, w, option)
+				fmt.Fprint(w, "\n\n")
+			}
+		case "script":
+			if option != nil && option.Script {
 				br(c, w, option)
-				table(c, w, option)
-			case "style":
-				if option != nil && option.Style {
-					br(c, w, option)
-					raw(c, w, option)
-					fmt.Fprint(w, "\n\n")
-				}
-			case "script":
-				if option != nil && option.Script {
-					br(c, w, option)
-					raw(c, w, option)
-					fmt.Fprint(w, "\n\n")
-				}
-			default:
-				walk(c, w, nest, option)
+				raw(c, w, option)
+				fmt.Fprint(w, "\n\n")
 			}
 		default:
 			walk(c, w, nest, option)
 		}
+	default:
+		walk(c, w, nest, option)
 	}
 }
 
@@ -536,15 +806,68 @@ type CustomRule interface {
 	Rule(next WalkFunc) (tagName string, customRule WalkFunc)
 }
 
+// HeadingStyle is how h1 and h2 headings are written. h3 to h6 are always ATX headings.
+type HeadingStyle int
+
+const (
+	// HeadingATX writes "# Heading".
+	HeadingATX HeadingStyle = iota
+	// HeadingSetext underlines the heading with "=" or "-".
+	HeadingSetext
+)
+
+// SubSupStyle is how <sub> and <sup> are written.
+type SubSupStyle int
+
+const (
+	// SubSupText writes only their content.
+	SubSupText SubSupStyle = iota
+	// SubSupHTML keeps them as inline HTML, "x<sup>2</sup>".
+	SubSupHTML
+	// SubSupNotation writes "H~2~O" and "x^2^", the extension of pandoc and markdown-it.
+	SubSupNotation
+)
+
 // Option is optional information for Convert.
 type Option struct {
-	GuessLang      func(string) (string, error)
-	Script         bool
-	Style          bool
-	TrimSpace      bool
-	CustomRules    []CustomRule
-	doNotEscape    bool // Used to know if to escape certain characters
-	customRulesMap map[string]WalkFunc
+	GuessLang        func(string) (string, error)
+	Script           bool
+	Style            bool
+	TrimSpace        bool
+	PlainTables      bool     // Render tables as plain text instead of GitHub-flavored pipe tables
+	ListIndent       int      // Spaces per nested list level, 2 if zero; never less than the marker width
+	BulletListMarker string   // Marker of unordered list items, "-", "+" or "*" (the default)
+	BaseURL          *url.URL // Used to resolve relative links and image sources
+	HeadingStyle     HeadingStyle
+	SubSupStyle      SubSupStyle
+	WrapWidth        int             // Wrap paragraphs at this many runes, 0 to not wrap
+	FrontMatter      map[string]any  // Written as a YAML front matter block before the content
+	AllowRawHTML     bool            // Keep raw HTML and script links in ToHTML instead of escaping them, and iframes, video and audio in Convert
+	DropEmbeds       bool            // Drop iframes, video and audio instead of writing a link to them
+	RichEmbeds       bool            // Write YouTube and Vimeo iframes, and videos with a poster, as a thumbnail linked to the video
+	EmbedText        string          // Text of the links written for iframes, video and audio, "▶ Watch on YouTube" and the like if empty
+	DefinitionLists  bool            // Write <dl> with the "Term\n: Definition" extension instead of bold terms
+	MainContent      bool            // Convert only the main article of the pages fetched by FetchAsMarkdown
+	RenderJS         bool            // Convert the pages fetched by FetchAsMarkdown as rendered by fetch.DefaultRenderer
+	Normalize        bool            // Clean up blank lines and trailing whitespace of the result, see Normalize
+	GenerateTOC      bool            // Write a table of contents of the headings in place of a "[TOC]" line, or before the content
+	HeadingIDs       bool            // Start every heading with an <a id> anchor of its slug, for renderers that make none, see Headings
+	DisableSanitize  bool            // Keep comments, hidden elements and the content of <noscript> and <template>, which are dropped by default
+	AllowedTags      []string        // Convert only these elements, writing just the content of the others; all elements if empty
+	Metrics          metrics.Metrics // Receives the duration of every conversion, see package metrics
+	DropFootnotes    bool            // Drop note references and the lists of notes instead of writing them as [^1] footnotes
+	BackslashBreaks  bool            // Write <br> as a backslash at the end of the line instead of two trailing spaces
+	InferBlocks      bool            // Write the <div> and block <span> elements holding only text of pages made without <p> as paragraphs
+	CustomRules      []CustomRule
+	doNotEscape      bool         // Used to know if to escape certain characters
+	inLink           bool         // Used to keep headings out of the text of a link
+	inFootnote       bool         // Used to drop the links back from a note to its references
+	emphasis         emphasisKind // The kinds of emphasis the node being converted is in
+	notes            *footnotes   // Numbers of the notes of the document being converted
+	inferred         *inferences  // Blocks inferred in the document being converted with InferBlocks
+	customRulesMap   map[string]WalkFunc
+	rules            []rule     // Added with AddRule
+	ruleNode         *html.Node // The node being converted by a custom rule
 }
 
 // Clone To make a copy of an option without changing the original
@@ -560,24 +883,110 @@ func (o *Option) Clone() *Option {
 
 // ConvertHTMLToMarkdown convert HTML to Markdown. Read HTML from r and write to w.
 func ConvertHTMLToMarkdown(w io.Writer, r io.Reader, option *Option) error {
-	log.Println("Convert to markdown: ", r)
-	doc, err := html.Parse(r)
-	log.Println("doc from convert", doc)
-	if err != nil {
-		log.Println("Error parsing html: ", err)
-		return err
+	return Convert(r, w, option)
+}
+
+// Convert reads HTML from r and writes its Markdown to w. r may hold a full document or
+// a fragment without <html> and <body>; the <head> is not converted. A nil option uses
+// the defaults.
+//
+// HTML in a *strings.Reader, *bytes.Reader or *bytes.Buffer is parsed as a whole, other
+// readers, such as files and response bodies, are converted as they are read with
+// ConvertStream.
+func Convert(r io.Reader, w io.Writer, option *Option) error {
+	switch r.(type) {
+	case *strings.Reader, *bytes.Reader, *bytes.Buffer:
+		return convert(r, w, option, false)
 	}
+	return convert(r, w, option, true)
+}
+
+// convert converts the HTML of r with streamConvert if stream is set, or walks the tree
+// html.Parse returns. Its duration is reported to option.Metrics.
+func convert(r io.Reader, w io.Writer, option *Option, stream bool) (err error) {
+	if option != nil && option.Metrics != nil {
+		start := time.Now()
+		m := option.Metrics
+		defer func() {
+			status := metrics.StatusOK
+			if err != nil {
+				status = metrics.StatusError
+			}
+			metrics.Timing(m, metrics.MarkdownDuration, time.Since(start), "status", status)
+		}()
+	}
+
+	// Blocks are inferred from the siblings and content of elements, which a stream lacks
+	if option != nil && option.InferBlocks {
+		stream = false
+	}
+	var doc *html.Node
+	if !stream {
+		if doc, err = html.Parse(r); err != nil {
+			return fmt.Errorf("markdown: parse html: %w", err)
+		}
+	}
+
+	option = option.Clone()
 	if option == nil {
 		option = &Option{}
 	}
-
+	option.notes = newFootnotes()
+	if option.InferBlocks {
+		option.inferred = newInferences()
+	}
 	option.customRulesMap = make(map[string]WalkFunc)
 	for _, cr := range option.CustomRules {
 		tag, customWalk := cr.Rule(walk)
 		option.customRulesMap[tag] = customWalk
 	}
 
-	walk(doc, w, 0, option)
+	// The result is post-processed as a whole, so streamed documents are buffered too
+	out := w
+	var buffered bytes.Buffer
+	postProcess := option.Normalize || option.GenerateTOC || option.HeadingIDs
+	if postProcess {
+		w = &buffered
+	}
+
+	if len(option.FrontMatter) > 0 {
+		var front bytes.Buffer
+		enc := yaml.NewEncoder(&front)
+		enc.SetIndent(2)
+		if err := enc.Encode(option.FrontMatter); err != nil {
+			return fmt.Errorf("markdown: front matter: %w", err)
+		}
+		fmt.Fprint(w, "---\n"+front.String()+"---\n\n")
+	}
+
+	if stream {
+		if err := streamConvert(r, w, option); err != nil {
+			return err
+		}
+	} else {
+		walk(doc, w, 0, option)
+	}
 	fmt.Fprint(w, "\n")
-	return nil
+	if !postProcess {
+		return nil
+	}
+	result := buffered.String()
+	if option.GenerateTOC || option.HeadingIDs {
+		result = addTableOfContents(result, option)
+	}
+	if option.Normalize {
+		result = Normalize(result)
+	}
+	_, err = io.WriteString(out, result)
+	return err
+}
+
+// ConvertString converts the HTML in s to Markdown. Leading and trailing whitespace is
+// trimmed from the result.
+func ConvertString(s string, option *Option) (string, error) {
+	var b strings.Builder
+	if err := Convert(strings.NewReader(s), &b, option); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
 }
@@ -1,9 +1,13 @@
 package markdown
 
 import (
+	"errors"
 	"io"
+	"net/url"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"golang.org/x/net/html"
 )
@@ -122,3 +126,463 @@ func TestLangFromClass(t *testing.T) {
 		t.Errorf("Expected an empty string, but got %s", lang)
 	}
 }
+
+func TestConvertString(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"fragment", `<p>Hello <b>world</b></p>`, "Hello **world**"},
+		{"document", `<html><head><title>Ignored</title></head><body><p>See <a href="/docs">the docs</a>.</p></body></html>`, "See [the docs](/docs)."},
+		{"list", `<ul><li>one</li><li>two</li></ul>`, "* one\n* two"},
+		{"code", "<pre><code class=\"language-go\">fmt.Println()\n</code></pre>", "```go\nfmt.Println()\n```"},
+		{"blockquote", `<blockquote>quoted</blockquote>`, "> quoted"},
+		{"malformed", `<p>unclosed <b>bold <i>italic</p><div>`, "unclosed **bold _italic_**"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertString(tt.html, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// Test that Convert writes to w and reports read errors
+func TestConvert(t *testing.T) {
+	var b strings.Builder
+	if err := Convert(strings.NewReader(`<h2>Title</h2>`), &b, &Option{TrimSpace: true}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := b.String(); got != "## Title\n\n\n" {
+		t.Errorf("Expected %q, got %q", "## Title\n\n\n", got)
+	}
+
+	readErr := errors.New("read failed")
+	if err := Convert(iotest.ErrReader(readErr), &b, nil); !errors.Is(err, readErr) {
+		t.Errorf("Expected %v, got %v", readErr, err)
+	}
+}
+
+func TestConvertTable(t *testing.T) {
+	tests := []struct {
+		name   string
+		html   string
+		option *Option
+		want   string
+	}{
+		{
+			name: "thead",
+			html: "<table><thead><tr><th>Name</th><th>Value</th></tr></thead>" +
+				"<tbody><tr><td>a|b</td><td>multi\nline</td></tr></tbody></table>",
+			want: "| Name | Value      |\n" +
+				"| ---- | ---------- |\n" +
+				"| a\\|b | multi line |",
+		},
+		{
+			name: "first row promoted",
+			html: `<table><tr><td>x</td><td>y</td></tr><tr><td>1</td><td>2</td></tr></table>`,
+			want: "| x   | y   |\n" +
+				"| --- | --- |\n" +
+				"| 1   | 2   |",
+		},
+		{
+			name: "spans",
+			html: `<table><tr><th>a</th><th>b</th><th>c</th></tr>` +
+				`<tr><td colspan="2">wide</td><td>1</td></tr>` +
+				`<tr><td rowspan="2">tall</td><td>2</td><td>3</td></tr>` +
+				`<tr><td>4</td><td>5</td></tr></table>`,
+			want: "| a    | b   | c   |\n" +
+				"| ---- | --- | --- |\n" +
+				"| wide |     | 1   |\n" +
+				"| tall | 2   | 3   |\n" +
+				"|      | 4   | 5   |",
+		},
+		{
+			name:   "plain",
+			html:   `<table><tr><td>x</td><td>y</td></tr><tr><td>1</td><td>2</td></tr></table>`,
+			option: &Option{PlainTables: true},
+			want:   "x y \n1 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertString(tt.html, tt.option)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConvertList(t *testing.T) {
+	nested := `<ol><li>one<ul><li>a<ol start="3"><li>x</li><li>y</li></ol></li><li>b</li></ul></li><li>two</li></ol>`
+	tests := []struct {
+		name   string
+		html   string
+		option *Option
+		want   string
+	}{
+		{
+			name: "nested",
+			html: nested,
+			want: "1. one\n" +
+				"   * a\n" +
+				"     3. x\n" +
+				"     4. y\n" +
+				"   * b\n" +
+				"2. two",
+		},
+		{
+			name:   "indent and marker",
+			html:   nested,
+			option: &Option{ListIndent: 4, BulletListMarker: "-"},
+			want: "1. one\n" +
+				"    - a\n" +
+				"        3. x\n" +
+				"        4. y\n" +
+				"    - b\n" +
+				"2. two",
+		},
+		{
+			name: "block content",
+			html: "<ul><li><p>para one</p><p>para two</p></li><li>code:<pre><code>line1\n\n  line2\n</code></pre></li></ul>",
+			want: "* para one\n" +
+				"\n" +
+				"  para two\n" +
+				"* code:\n" +
+				"  ```\n" +
+				"  line1\n" +
+				"\n" +
+				"    line2\n" +
+				"  ```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertString(tt.html, tt.option)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConvertCode(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"fenced", "<pre><code class=\"language-go\">func main() {\n\tfmt.Println(\"hi\")\n}\n</code></pre>", "```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```"},
+		{"pre class", "<pre class=\"language-python\"><code>  x = 1\n\n  y = 2</code></pre>", "```python\n  x = 1\n\n  y = 2\n```"},
+		{"no code", "<pre>plain\n  text</pre>", "```\nplain\n  text\n```"},
+		{"github highlight", "<div class=\"highlight highlight-source-go\"><pre>go run .</pre></div>", "```go\ngo run .\n```"},
+		{"backticks in block", "<pre><code>```\nnested\n```</code></pre>", "````\n```\nnested\n```\n````"},
+		{"inline", "<p>Run <code>go test</code> now</p>", "Run `go test` now"},
+		{"inline backtick", "<p><code>a`b</code> and <code>`c</code></p>", "``a`b`` and `` `c ``"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertString(tt.html, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConvertLinksAndImages(t *testing.T) {
+	base, _ := url.Parse("https://example.com/docs/page.html")
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"relative link", `<p>Read <a href="guide.html" title="The guide">the guide</a>.</p>`, `Read [the guide](https://example.com/docs/guide.html "The guide").`},
+		{"absolute link", `<a href="https://go.dev/">Go</a>`, `[Go](https://go.dev/)`},
+		{"escaping", `<a href="/wiki/Go_(language)">[Go] language</a>`, `[\[Go\] language](https://example.com/wiki/Go_%28language%29)`},
+		{"block content", `<a href="/post"><div><h3>Title</h3><p>Summary</p></div></a>`, `[Title Summary](https://example.com/post)`},
+		{"empty href", `<p><a href="">plain</a> and <a>anchor</a></p>`, `plain and anchor`},
+		{"javascript href", `<a href="javascript:void(0)">click</a>`, `click`},
+		{"image", `<img src="/img/cat.png" alt="a [cat]" title="Cat">`, `![a \[cat\]](https://example.com/img/cat.png "Cat")`},
+		{"lazy image", `<img data-src="lazy.png" alt="lazy">`, `![lazy](https://example.com/docs/lazy.png)`},
+		{"linked image", `<a href="/full.png"><img src="thumb.png" alt="thumb"></a>`, `[![thumb](https://example.com/docs/thumb.png)](https://example.com/full.png)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertString(tt.html, &Option{BaseURL: base})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConvertHeadingStyle(t *testing.T) {
+	html := `<p>text</p>inline<h1>Main title</h1><h2>Sub</h2><h3>Third</h3>`
+
+	got, err := ConvertString(html, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := "text\n\ninline\n\n# Main title\n\n\n## Sub\n\n\n### Third"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	got, err = ConvertString(html, &Option{HeadingStyle: HeadingSetext})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := "text\n\ninline\n\nMain title\n==========\n\n\nSub\n---\n\n\n### Third"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestConvertWrapWidth(t *testing.T) {
+	html := `<p>Go is an open source language that makes it simple to build ` +
+		`<a href="https://go.dev/doc/">secure, scalable systems</a> with <code>go build ./...</code> today.</p>` +
+		`<p>日本語のテキスト 日本語のテキスト 日本語のテキスト 日本語のテキスト 日本語のテキスト</p>`
+
+	got, err := ConvertString(html, &Option{WrapWidth: 40})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := "Go is an open source language that makes\n" +
+		"it simple to build\n" +
+		"[secure, scalable systems](https://go.dev/doc/)\n" +
+		"with `go build ./...` today.\n\n\n" +
+		"日本語のテキスト 日本語のテキスト 日本語のテキスト 日本語のテキスト\n" +
+		"日本語のテキスト"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestConvertFrontMatter(t *testing.T) {
+	got, err := ConvertString(`<p>Body</p>`, &Option{FrontMatter: map[string]any{
+		"title": "Hello: world",
+		"tags":  []string{"go", "html"},
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := "---\ntags:\n  - go\n  - html\ntitle: 'Hello: world'\n---\n\nBody"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestConvertEscaping(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"emphasis markers", `<p>2 * 3 = 6, *not em* or _this_</p>`, `2 * 3 = 6, \*not em\* or \_this\_`},
+		{"intraword underscore", `<p>snake_case_name</p>`, `snake_case_name`},
+		{"heading", `<p># not a heading</p>`, `\# not a heading`},
+		{"hashtag", `<p>#hashtag</p>`, `#hashtag`},
+		{"ordered list", `<p>1999. A year</p>`, `1999\. A year`},
+		{"bullet", `<p>- dash</p>`, `\- dash`},
+		{"plus", `<p>+ plus</p>`, `\+ plus`},
+		{"blockquote", `<p>&gt; quote</p>`, `\> quote`},
+		{"line start in span", "<p>\n  <span># tag</span> text\n</p>", `\# tag text`},
+		{"not at line start", `<p>see # 5 and 1. and - and &gt;</p>`, `see # 5 and 1. and - and >`},
+		{"after delimiter", `<p><b># 1</b> item</p>`, `**# 1** item`},
+		{"backticks and brackets", "<p>use `code` and [brackets] and a \\ backslash</p>", "use \\`code\\` and \\[brackets\\] and a \\\\ backslash"},
+		{"html", `<p>&lt;div&gt; and a &lt; b</p>`, `\<div> and a < b`},
+		{"inline code", `<p><code>*raw* [x] # ok</code></p>`, "`*raw* [x] # ok`"},
+		{"code block", `<pre>*x* _y_ # z</pre>`, "```\n*x* _y_ # z\n```"},
+		{"table cell", `<table><tr><td>a|b</td><td>*c*</td></tr></table>`, "| a\\|b | \\*c\\* |\n| ---- | ----- |"},
+		{"whitespace runs", "<p>a   lot\n\n of \t space</p>", "a lot of space"},
+		{"space between inline elements", "<p><b>bold</b>\n<i>italic</i></p>", "**bold** _italic_"},
+		{"space at block edges", "<p>\n   padded   \n</p>", "padded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertString(tt.html, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConvertBlocks(t *testing.T) {
+	definitions := `<dl><dt>Go</dt><dd>A language.</dd><dt>Rust</dt><dt>Zig</dt><dd><p>Systems.</p><p>More.</p></dd></dl>`
+	tests := []struct {
+		name   string
+		html   string
+		option *Option
+		want   string
+	}{
+		{
+			name: "blockquote with code",
+			html: "<blockquote><p>Look:</p><pre><code class=\"language-go\">x := 1\n\n    y()\n</code></pre><p>done</p></blockquote>",
+			want: "> Look:\n" +
+				">\n" +
+				"> ```go\n" +
+				"> x := 1\n" +
+				">\n" +
+				">     y()\n" +
+				"> ```\n" +
+				">\n" +
+				"> done",
+		},
+		{
+			name: "nested blockquote with list",
+			html: `<blockquote><p>outer</p><blockquote><p>inner</p><ul><li>a<ul><li>b</li></ul></li></ul></blockquote></blockquote>`,
+			want: "> outer\n" +
+				">\n" +
+				"> > inner\n" +
+				"> >\n" +
+				"> > * a\n" +
+				"> >   * b",
+		},
+		{
+			name: "hr and strikethrough",
+			html: `<p>a</p><hr><p><del>gone</del> and <s>struck</s></p>`,
+			want: "a\n\n\n\n---\n\n~~gone~~ and ~~struck~~",
+		},
+		{
+			name: "definition list",
+			html: definitions,
+			want: "**Go**\n\nA language.\n\n**Rust**\n\n**Zig**\n\nSystems.\n\nMore.",
+		},
+		{
+			name:   "definition list extension",
+			html:   definitions,
+			option: &Option{DefinitionLists: true},
+			want:   "Go\n: A language.\n\nRust\nZig\n: Systems.\n\n  More.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertString(tt.html, tt.option)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConvertEmbeds(t *testing.T) {
+	base, _ := url.Parse("https://example.com/news/story.html")
+	youTube := `<iframe width="560" src="https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ?rel=0" title="Talk"></iframe>`
+	tests := []struct {
+		name   string
+		html   string
+		option Option
+		want   string
+	}{
+		{"youtube", youTube, Option{}, `[▶ Watch on YouTube](https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ?rel=0)`},
+		{"youtube rich", youTube, Option{RichEmbeds: true}, `[![▶ Watch on YouTube](https://img.youtube.com/vi/dQw4w9WgXcQ/hqdefault.jpg)](https://www.youtube.com/watch?v=dQw4w9WgXcQ)`},
+		{"vimeo rich", `<iframe src="//player.vimeo.com/video/76979871?h=8272103f6e"></iframe>`, Option{RichEmbeds: true}, `[![▶ Watch on Vimeo](https://vumbnail.com/76979871.jpg)](https://vimeo.com/76979871)`},
+		{"placeholder", youTube, Option{EmbedText: "Video [HD]"}, `[Video \[HD\]](https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ?rel=0)`},
+		{"other iframe", `<iframe src="/maps/embed" title="Map of [the] city"></iframe>`, Option{}, `[Map of \[the\] city](https://example.com/maps/embed)`},
+		{"untitled iframe", `<iframe data-src="https://widgets.example.org/w"></iframe>`, Option{}, `[Embedded content](https://widgets.example.org/w)`},
+		{"video sources", `<video controls><source src="clip.mkv" type="video/x-matroska"><source src="clip.webm" type="video/webm; codecs=vp9"><source src="clip.mp4" type="video/mp4">Your browser does not support video.</video>`, Option{}, `[▶ Watch video](https://example.com/news/clip.webm)`},
+		{"unplayable sources", `<video><source src="clip.mkv" type="video/x-matroska"></video>`, Option{}, `[▶ Watch video](https://example.com/news/clip.mkv)`},
+		{"video poster", `<video src="/media/clip.mp4" poster="/media/poster.jpg"></video>`, Option{RichEmbeds: true}, `[![▶ Watch video](https://example.com/media/poster.jpg)](https://example.com/media/clip.mp4)`},
+		{"audio", `<p>Listen: <audio src="episode.mp3"></audio></p>`, Option{}, `Listen: [▶ Listen to audio](https://example.com/news/episode.mp3)`},
+		{"no source", `<video>Not supported</video>`, Option{}, ``},
+		{"raw html", youTube, Option{AllowRawHTML: true}, `<iframe width="560" src="https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ?rel=0" title="Talk"></iframe>`},
+		{"dropped", `<p>Before</p>` + youTube + `<p>After</p>`, Option{DropEmbeds: true, AllowRawHTML: true}, "Before\n\nAfter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			option := tt.option
+			option.BaseURL = base
+			got, err := ConvertString(tt.html, &option)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestVideoID(t *testing.T) {
+	tests := []struct {
+		url      string
+		provider string
+		id       string
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42", "YouTube", "dQw4w9WgXcQ"},
+		{"https://youtu.be/dQw4w9WgXcQ", "YouTube", "dQw4w9WgXcQ"},
+		{"https://m.youtube.com/shorts/dQw4w9WgXcQ", "YouTube", "dQw4w9WgXcQ"},
+		{"https://www.youtube.com/embed/videoseries?list=PL123", "", ""},
+		{"https://vimeo.com/76979871", "Vimeo", "76979871"},
+		{"https://vimeo.com/channels/staffpicks", "", ""},
+		{"https://example.com/embed/dQw4w9WgXcQ", "", ""},
+	}
+
+	for _, tt := range tests {
+		provider, id := videoID(tt.url)
+		if provider != tt.provider || id != tt.id {
+			t.Errorf("videoID(%q) = %q, %q, expected %q, %q", tt.url, provider, id, tt.provider, tt.id)
+		}
+	}
+}
+
+// timingMetrics records the names and labels of the timings it receives.
+type timingMetrics struct {
+	timed []string
+}
+
+func (m *timingMetrics) Count(name string, delta int64, labels ...string) {}
+
+func (m *timingMetrics) Timing(name string, d time.Duration, labels ...string) {
+	m.timed = append(m.timed, name+" "+strings.Join(labels, "="))
+}
+
+func TestConvertMetrics(t *testing.T) {
+	m := &timingMetrics{}
+	option := &Option{Metrics: m}
+
+	if _, err := ConvertString("<p>Text</p>", option); err != nil {
+		t.Fatal(err)
+	}
+	if err := ConvertStream(iotest.ErrReader(errors.New("read")), io.Discard, option); err == nil {
+		t.Error("Expected an error")
+	}
+
+	expected := []string{"markdown.duration status=ok", "markdown.duration status=error"}
+	if strings.Join(m.timed, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("Expected %q, got %q", expected, m.timed)
+	}
+}
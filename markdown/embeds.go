@@ -0,0 +1,159 @@
+package markdown
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	youTubeIDRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	vimeoIDRegex   = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// playableTypes are the media types of <source> elements browsers play without plugins
+var playableTypes = map[string]bool{
+	"video/mp4": true, "video/webm": true, "video/ogg": true,
+	"audio/mpeg": true, "audio/mp4": true, "audio/aac": true, "audio/ogg": true,
+	"audio/webm": true, "audio/wav": true, "audio/flac": true,
+}
+
+// embed writes an <iframe>, <video> or <audio>. It is dropped with option.DropEmbeds, kept
+// as HTML with option.AllowRawHTML and otherwise written as a link to its source, or with
+// option.RichEmbeds as a thumbnail linked to the video for YouTube and Vimeo.
+func embed(node *html.Node, w io.Writer, option *Option) {
+	if option.DropEmbeds {
+		return
+	}
+	if option.AllowRawHTML {
+		raw(node, w, option)
+		return
+	}
+
+	src := embedSource(node)
+	if src == "" {
+		return
+	}
+	if option.BaseURL != nil {
+		if u, err := option.BaseURL.Parse(src); err == nil {
+			src = u.String()
+		}
+	}
+
+	var thumbnail string
+	text := defaultEmbedText(node)
+	switch provider, id := videoID(src); provider {
+	case "YouTube":
+		text = "▶ Watch on YouTube"
+		if option.RichEmbeds {
+			thumbnail = "https://img.youtube.com/vi/" + id + "/hqdefault.jpg"
+			src = "https://www.youtube.com/watch?v=" + id
+		}
+	case "Vimeo":
+		text = "▶ Watch on Vimeo"
+		if option.RichEmbeds {
+			// Vimeo has no thumbnail URL derived from the ID, vumbnail.com serves them
+			thumbnail = "https://vumbnail.com/" + id + ".jpg"
+			src = "https://vimeo.com/" + id
+		}
+	default:
+		if option.RichEmbeds {
+			thumbnail = attr(node, "poster")
+		}
+	}
+	if option.EmbedText != "" {
+		text = option.EmbedText
+	}
+	if thumbnail == "" {
+		fmt.Fprintf(w, "[%s](%s)", linkTextReplacer.Replace(text), destination(src, option))
+		return
+	}
+	fmt.Fprint(w, "[")
+	image(w, thumbnail, text, "", option)
+	fmt.Fprintf(w, "](%s)", destination(src, option))
+}
+
+// embedSource returns the URL of an embed: its src, or else the first playable <source>.
+func embedSource(node *html.Node) string {
+	if src := strings.TrimSpace(attr(node, "src")); src != "" {
+		return src
+	}
+	if node.Data == "iframe" {
+		return strings.TrimSpace(attr(node, "data-src"))
+	}
+
+	var first string
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.Data != "source" {
+			continue
+		}
+		src := strings.TrimSpace(attr(c, "src"))
+		if src == "" {
+			continue
+		}
+		mediaType, _, _ := strings.Cut(strings.ToLower(attr(c, "type")), ";")
+		if mediaType == "" || playableTypes[strings.TrimSpace(mediaType)] {
+			return src
+		}
+		if first == "" {
+			first = src
+		}
+	}
+	return first
+}
+
+// defaultEmbedText is the link text of an embed that is not a known video
+func defaultEmbedText(node *html.Node) string {
+	switch node.Data {
+	case "video":
+		return "▶ Watch video"
+	case "audio":
+		return "▶ Listen to audio"
+	}
+	if title := strings.Join(strings.Fields(attr(node, "title")), " "); title != "" {
+		return title
+	}
+	return "Embedded content"
+}
+
+// videoID returns the provider and ID of a YouTube or Vimeo URL, such as the src of an
+// embedded player. The provider is empty for other URLs.
+func videoID(rawURL string) (provider, id string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	host = strings.TrimPrefix(host, "m.")
+	segments := strings.Split(strings.Trim(path.Clean("/"+u.Path), "/"), "/")
+
+	switch host {
+	case "youtube.com", "youtube-nocookie.com":
+		if u.Path == "/watch" {
+			id = u.Query().Get("v")
+		} else if len(segments) == 2 && (segments[0] == "embed" || segments[0] == "v" || segments[0] == "shorts") {
+			id = segments[1]
+		}
+		// Playlists are embedded as /embed/videoseries, which looks like an ID
+		if youTubeIDRegex.MatchString(id) && id != "videoseries" {
+			return "YouTube", id
+		}
+	case "youtu.be":
+		if len(segments) == 1 && youTubeIDRegex.MatchString(segments[0]) {
+			return "YouTube", segments[0]
+		}
+	case "vimeo.com", "player.vimeo.com":
+		if len(segments) > 0 {
+			id = segments[len(segments)-1]
+		}
+		if vimeoIDRegex.MatchString(id) {
+			return "Vimeo", id
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,130 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+var whitespaceRegex = regexp.MustCompile(`[[:space:]]+`)
+
+// lineStartRegex matches text that starts a block when it is at the beginning of a line:
+// headings, block quotes, list items, thematic breaks and setext underlines
+var lineStartRegex = regexp.MustCompile(`^(?:#{1,6}(?:\s|$)|>|[-+](?:\s|$)|-{3,}|={3,}|\d{1,9}[.)](?:\s|$))`)
+
+// blockElements are the elements that start on a new line of markdown
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "body": true, "br": true,
+	"dd": true, "div": true, "dl": true, "dt": true, "figcaption": true, "figure": true, "footer": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true,
+	"html": true, "li": true, "main": true, "nav": true, "ol": true, "p": true, "pre": true,
+	"section": true, "table": true, "td": true, "th": true, "tr": true, "ul": true,
+}
+
+// startsLine reports whether the element node starts on a new line of markdown, as one of
+// blockElements or, with InferBlocks, a <span> inferred to be a block.
+func startsLine(node *html.Node, option *Option) bool {
+	name := strings.ToLower(node.Data)
+	return blockElements[name] || option.InferBlocks && name == "span" && inferBlock(node, option) != inferNone
+}
+
+// delimitedElements are the inline elements written with markup around their content
+var delimitedElements = map[string]bool{
+	"a": true, "b": true, "strong": true, "i": true, "em": true, "del": true, "s": true, "code": true,
+}
+
+// atLineEdge reports whether node is the first (or with end, the last) content of a
+// line of markdown: nothing but whitespace and what option drops separates it from the
+// edge of a block.
+func atLineEdge(node *html.Node, end bool, option *Option) bool {
+	sibling := func(n *html.Node) *html.Node {
+		if end {
+			return n.NextSibling
+		}
+		return n.PrevSibling
+	}
+
+	for n := node; n != nil; n = n.Parent {
+		for s := sibling(n); s != nil; s = sibling(s) {
+			if s.Type == html.CommentNode || option.dropped(s) || (s.Type == html.TextNode && strings.TrimSpace(s.Data) == "") {
+				continue
+			}
+			return s.Type == html.ElementNode && startsLine(s, option)
+		}
+
+		parent := n.Parent
+		if parent == nil || parent.Type != html.ElementNode || startsLine(parent, option) {
+			return true
+		}
+		if delimitedElements[strings.ToLower(parent.Data)] {
+			return false
+		}
+	}
+	return true
+}
+
+// escapeText escapes the characters of text that markdown would read as markup. Code is
+// never passed through here.
+//
+// \ ` [ ] : always escaped
+// * _     : escaped when they could open or close emphasis, i.e. next to a non-space
+// <       : escaped when it could start an HTML tag
+// # > - + = and "1." : escaped at the start of a line, where they start blocks
+func escapeText(text string, lineStart bool) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch c {
+		case '\\', '`', '[', ']':
+			b.WriteByte('\\')
+		case '*', '_':
+			prev, _ := utf8.DecodeLastRuneInString(text[:i])
+			next, _ := utf8.DecodeRuneInString(text[i+1:])
+			// The edges of the text count as non-space, the text next to it is unknown
+			prevSpace := i > 0 && unicode.IsSpace(prev)
+			nextSpace := i+1 < len(text) && unicode.IsSpace(next)
+			intraword := c == '_' && isAlnum(prev) && isAlnum(next)
+			if (!prevSpace || !nextSpace) && !intraword {
+				b.WriteByte('\\')
+			}
+		case '<':
+			if i+1 < len(text) && (isAlnum(rune(text[i+1])) || strings.IndexByte("/!?", text[i+1]) >= 0) {
+				b.WriteByte('\\')
+			}
+		}
+		b.WriteByte(c)
+	}
+	text = b.String()
+
+	if lineStart {
+		if m := lineStartRegex.FindString(text); m != "" {
+			if c := m[0]; c >= '0' && c <= '9' {
+				// Escape the delimiter of an ordered list item
+				i := strings.IndexAny(m, ".)")
+				return text[:i] + `\` + text[i:]
+			}
+			return `\` + text
+		}
+	}
+	return text
+}
+
+func isAlnum(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// normalizeText collapses the whitespace of a text node, dropping it at the start and
+// end of a line of markdown where it is insignificant
+func normalizeText(node *html.Node, option *Option) string {
+	text := whitespaceRegex.ReplaceAllString(node.Data, " ")
+	if atLineEdge(node, false, option) || option.afterDropped(node) {
+		text = strings.TrimLeft(text, " ")
+	}
+	if atLineEdge(node, true, option) {
+		text = strings.TrimRight(text, " ")
+	}
+	return text
+}
@@ -0,0 +1,154 @@
+package markdown
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/propro-productions/go-utils/fetch"
+	"github.com/propro-productions/go-utils/store"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// DefaultFetchTimeout bounds FetchAsMarkdown when ctx has no earlier deadline.
+	DefaultFetchTimeout = 30 * time.Second
+
+	// DefaultMaxFetchSize is how much of a page FetchAsMarkdown reads.
+	DefaultMaxFetchSize = 5 << 20
+)
+
+// fetchUserAgent is sent with the requests of FetchAsMarkdown
+const fetchUserAgent = "GoScraper"
+
+// ContentTypeError is returned by FetchAsMarkdown for responses that are not HTML.
+type ContentTypeError struct {
+	URL         string
+	ContentType string
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("markdown: %s: unsupported content type %q", e.URL, e.ContentType)
+}
+
+// FetchAsMarkdown downloads the page at rawURL and returns its markdown. Relative links and
+// images are resolved against the URL of the page, after redirects, unless option sets
+// BaseURL. With option.MainContent only the main article of the page is converted, as
+// found by store.MainContent.
+//
+// With option.RenderJS the page is converted as rendered by fetch.DefaultRenderer, after its
+// scripts ran; without a browser this fails with fetch.ErrBrowserUnavailable.
+//
+// Responses that are not HTML fail with a *ContentTypeError. Only the first
+// DefaultMaxFetchSize bytes of a page are read.
+func FetchAsMarkdown(ctx context.Context, rawURL string, option *Option) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("markdown: unsupported URL scheme %q", u.Scheme)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", fetchUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("markdown: fetching %s: %s", u, resp.Status)
+	}
+
+	// A missing Content-Type is treated as HTML
+	header := resp.Header.Get("Content-Type")
+	if header != "" {
+		contentType, _, err := mime.ParseMediaType(header)
+		if err != nil || (contentType != "text/html" && contentType != "application/xhtml+xml") {
+			return "", &ContentTypeError{URL: resp.Request.URL.String(), ContentType: header}
+		}
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, DefaultMaxFetchSize), header)
+	if err != nil {
+		return "", err
+	}
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		return "", fmt.Errorf("markdown: parse html: %w", err)
+	}
+	doc.Url = resp.Request.URL
+
+	if option != nil && option.RenderJS {
+		page, err := fetch.DefaultRenderer.Fetch(ctx, doc.Url.String())
+		if err != nil {
+			return "", fmt.Errorf("markdown: rendering %s: %w", doc.Url, err)
+		}
+		rendered, err := goquery.NewDocumentFromReader(strings.NewReader(page.HTML))
+		if err != nil {
+			return "", fmt.Errorf("markdown: parse html: %w", err)
+		}
+		rendered.Url = doc.Url
+		doc = rendered
+	}
+
+	return ConvertDocument(doc, option)
+}
+
+// ConvertDocument returns the markdown of doc, a page fetched from doc.Url, the way
+// FetchAsMarkdown converts the pages it downloads: relative links and images are resolved
+// against doc.Url or its <base> unless option sets BaseURL, and with option.MainContent
+// only the main article is converted. option.RenderJS is ignored.
+func ConvertDocument(doc *goquery.Document, option *Option) (string, error) {
+	option = option.Clone()
+	if option == nil {
+		option = &Option{}
+	}
+	if option.BaseURL == nil && doc.Url != nil {
+		option.BaseURL = doc.Url
+		if base, ok := doc.Find("base[href]").Attr("href"); ok {
+			if baseURL, err := doc.Url.Parse(strings.TrimSpace(base)); err == nil {
+				option.BaseURL = baseURL
+			}
+		}
+	}
+
+	var main *goquery.Selection
+	if option.MainContent {
+		main = store.MainContent(doc)
+	}
+	var content string
+	var err error
+	if main != nil {
+		var b strings.Builder
+		for i := range main.Nodes {
+			h, err := goquery.OuterHtml(main.Eq(i))
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(h)
+		}
+		content = b.String()
+	} else {
+		content, err = doc.Html()
+		if err != nil {
+			return "", err
+		}
+	}
+	return ConvertString(content, option)
+}
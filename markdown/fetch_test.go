@@ -0,0 +1,148 @@
+package markdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/propro-productions/go-utils/fetch"
+)
+
+const fetchArticle = `<!DOCTYPE html>
+<html><head><title>Bike lanes</title></head>
+<body>
+<nav><a href="/">Home</a> <a href="/news">News</a></nav>
+<div class="article-body">
+<h1>Bike lanes approved</h1>
+<p>The council voted on Tuesday night to approve a network of protected bike lanes, ending a debate that has run for years, divided neighbourhoods, and filled meetings.</p>
+<p>Construction on the first phase, which covers the downtown core, will begin in the spring, according to the <a href="/transport">transport department</a>.</p>
+<p><img src="img/lanes.png" alt="Lanes"></p>
+</div>
+<div class="sidebar"><p>Subscribe for just $1 a week, cancel anytime, no questions asked, unlimited access.</p></div>
+</body></html>`
+
+func TestFetchAsMarkdown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/news/bike-lanes", http.StatusMovedPermanently)
+		case "/news/bike-lanes":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, fetchArticle)
+		case "/latin1":
+			w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+			fmt.Fprint(w, "<p>Caf\xe9</p>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		option  *Option
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "whole page",
+			path:    "/old",
+			want:    []string{"[Home](" + server.URL + "/)", "# Bike lanes approved", "Subscribe"},
+			notWant: []string{"Bike lanes\n"},
+		},
+		{
+			name:   "main content",
+			path:   "/old",
+			option: &Option{MainContent: true},
+			want: []string{
+				"# Bike lanes approved\n\nThe council voted",
+				"according to the [transport department](" + server.URL + "/transport).",
+				"![Lanes](" + server.URL + "/news/img/lanes.png)",
+			},
+			notWant: []string{"Home", "Subscribe"},
+		},
+		{
+			name: "charset",
+			path: "/latin1",
+			want: []string{"Café"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FetchAsMarkdown(context.Background(), server.URL+tt.path, tt.option)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("Expected %q in %q", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("Unexpected %q in %q", notWant, got)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchAsMarkdownErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/image.png" {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	_, err := FetchAsMarkdown(context.Background(), server.URL+"/image.png", nil)
+	var typeErr *ContentTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Expected a *ContentTypeError, got %v", err)
+	}
+	if typeErr.ContentType != "image/png" {
+		t.Errorf("Expected %q, got %q", "image/png", typeErr.ContentType)
+	}
+
+	if _, err := FetchAsMarkdown(context.Background(), server.URL+"/missing", nil); err == nil {
+		t.Errorf("Expected an error for a missing page")
+	}
+	if _, err := FetchAsMarkdown(context.Background(), "ftp://example.com/", nil); err == nil {
+		t.Errorf("Expected an error for an ftp URL")
+	}
+}
+
+type staticRenderer string
+
+func (r staticRenderer) Fetch(ctx context.Context, rawURL string) (*fetch.Page, error) {
+	return &fetch.Page{URL: rawURL, HTML: string(r)}, nil
+}
+
+func TestFetchAsMarkdownRenderJS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><div id="root"></div></body></html>`)
+	}))
+	defer server.Close()
+	defer func(renderer fetch.Fetcher) { fetch.DefaultRenderer = renderer }(fetch.DefaultRenderer)
+	fetch.DefaultRenderer = staticRenderer(`<html><body><h1>Rendered</h1><p><a href="/next">Next</a></p></body></html>`)
+
+	got, err := FetchAsMarkdown(context.Background(), server.URL+"/app", &Option{RenderJS: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# Rendered", "[Next](" + server.URL + "/next)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in\n%s", want, got)
+		}
+	}
+}
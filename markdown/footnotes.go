@@ -0,0 +1,199 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// footnotes numbers the notes of a document in the order they are first referenced. It
+// is shared by the clones of the Option of a conversion.
+type footnotes struct {
+	numbers map[string]int  // Number of every note id referenced or defined
+	defined map[string]bool // Ids of the notes already written
+	last    int
+}
+
+func newFootnotes() *footnotes {
+	return &footnotes{numbers: map[string]int{}, defined: map[string]bool{}}
+}
+
+// number returns the number of the note id, giving it the next one the first time.
+func (f *footnotes) number(id string) int {
+	n, ok := f.numbers[id]
+	if !ok || id == "" {
+		f.last++
+		n = f.last
+		if id != "" {
+			f.numbers[id] = n
+		}
+	}
+	return n
+}
+
+// hasAttr reports whether node has the attribute key, even if empty.
+func hasAttr(node *html.Node, key string) bool {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// noteRef returns the id of the note node refers to, for a Wikipedia style
+// <sup class="reference"><a href="#cite_note-1">[1]</a></sup>, or a link marked as a
+// note reference by pandoc and GitHub, <a href="#fn1" class="footnote-ref">.
+func noteRef(node *html.Node) (string, bool) {
+	if node.Type != html.ElementNode {
+		return "", false
+	}
+	switch strings.ToLower(node.Data) {
+	case "sup":
+		var a *html.Node
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			switch {
+			case c.Type == html.TextNode && strings.TrimSpace(c.Data) == "":
+			case c.Type == html.ElementNode && strings.ToLower(c.Data) == "a" && a == nil:
+				a = c
+			default:
+				return "", false
+			}
+		}
+		if a == nil {
+			return "", false
+		}
+		id, ok := fragment(a)
+		if !ok || !hasClass(node, "reference") && !strings.HasPrefix(id, "cite_note") && !isNoteLink(a) {
+			return "", false
+		}
+		return id, true
+	case "a":
+		if !isNoteLink(node) {
+			return "", false
+		}
+		return fragment(node)
+	}
+	return "", false
+}
+
+// isNoteLink reports whether the <a> is marked as a note reference.
+func isNoteLink(a *html.Node) bool {
+	return attr(a, "role") == "doc-noteref" || hasClass(a, "footnote-ref") || hasAttr(a, "data-footnote-ref")
+}
+
+// fragment returns the id an <a href="#id"> links to.
+func fragment(a *html.Node) (string, bool) {
+	href := strings.TrimSpace(attr(a, "href"))
+	if !strings.HasPrefix(href, "#") || len(href) == 1 {
+		return "", false
+	}
+	return href[1:], true
+}
+
+// isNoteList reports whether node is the <ol> of the notes of a document: an
+// <ol class="references"> or an <ol> in a section of class footnotes or references, or
+// with role="doc-endnotes" or data-footnotes.
+func isNoteList(node *html.Node) bool {
+	if node.Type != html.ElementNode || strings.ToLower(node.Data) != "ol" {
+		return false
+	}
+	if hasClass(node, "references") {
+		return true
+	}
+	for p := node.Parent; p != nil && p.Type == html.ElementNode; p = p.Parent {
+		if hasClass(p, "footnotes") || hasClass(p, "references") || attr(p, "role") == "doc-endnotes" || hasAttr(p, "data-footnotes") {
+			return true
+		}
+	}
+	return false
+}
+
+// isBacklink reports whether node links a note back to where it is referenced, such as
+// the "^" of Wikipedia or the "↩" of pandoc and GitHub.
+func isBacklink(node *html.Node) bool {
+	if node.Type != html.ElementNode {
+		return false
+	}
+	if hasClass(node, "mw-cite-backlink") {
+		return true
+	}
+	if strings.ToLower(node.Data) != "a" {
+		return false
+	}
+	if attr(node, "role") == "doc-backlink" || hasClass(node, "footnote-back") || hasAttr(node, "data-footnote-backref") {
+		return true
+	}
+	id, _ := fragment(node)
+	return strings.HasPrefix(id, "cite_ref")
+}
+
+// footnoteRef writes the reference to the note id as [^1], or nothing with
+// option.DropFootnotes.
+func footnoteRef(id string, w io.Writer, option *Option) {
+	if option.DropFootnotes {
+		return
+	}
+	fmt.Fprintf(w, "[^%d]", option.notes.number(id))
+}
+
+// footnoteList writes the items of a list of notes as footnote definitions, [^1]: text,
+// ordered by their numbers. Notes that are not referenced are numbered after the others,
+// and a note defined twice is written once. Nothing is written with option.DropFootnotes.
+func footnoteList(node *html.Node, w io.Writer, option *Option) {
+	if option.DropFootnotes {
+		return
+	}
+
+	type definition struct {
+		n     int
+		lines []string
+	}
+	var definitions []definition
+
+	clone := option.Clone()
+	clone.TrimSpace = true
+	clone.inFootnote = true
+	for li := node.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || strings.ToLower(li.Data) != "li" {
+			continue
+		}
+		id := attr(li, "id")
+		if id != "" && option.notes.defined[id] {
+			continue
+		}
+
+		var buf bytes.Buffer
+		walk(li, &buf, 1, clone)
+		lines := blockLines(buf.String())
+		if len(lines) == 0 {
+			continue
+		}
+		if id != "" {
+			option.notes.defined[id] = true
+		}
+		definitions = append(definitions, definition{option.notes.number(id), lines})
+	}
+
+	sort.SliceStable(definitions, func(i, j int) bool { return definitions[i].n < definitions[j].n })
+	for _, d := range definitions {
+		for i, l := range d.lines {
+			switch {
+			case i == 0:
+				fmt.Fprint(w, "[^"+strconv.Itoa(d.n)+"]: "+strings.TrimLeft(l, " \t")+"\n")
+			case l == "":
+				fmt.Fprint(w, "\n")
+			default:
+				fmt.Fprint(w, "    "+l+"\n")
+			}
+		}
+	}
+	if len(definitions) > 0 {
+		fmt.Fprint(w, "\n")
+	}
+}
@@ -0,0 +1,63 @@
+package markdown
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+const wikipediaReferences = `<div class="mw-parser-output"><p>Go was designed at Google.<sup id="cite_ref-pike_1-0" class="reference"><a href="#cite_note-pike-1">[1]</a></sup>
+It compiles fast.<sup class="reference"><a href="#cite_note-bench-2">[2]</a></sup><sup class="reference"><a href="#cite_note-pike-1">[1]</a></sup></p>
+<h2>References</h2>
+<div class="reflist"><ol class="references">
+<li id="cite_note-bench-2"><span class="mw-cite-backlink"><b><a href="#cite_ref-bench_2-0">^</a></b></span> <span class="reference-text">Benchmarks <i>game</i>.</span></li>
+<li id="cite_note-pike-1"><span class="mw-cite-backlink">^ <a href="#cite_ref-pike_1-0"><sup><i><b>a</b></i></sup></a> <a href="#cite_ref-pike_1-1"><sup><i><b>b</b></i></sup></a></span> <span class="reference-text">Pike, Rob (2012). <a href="https://go.dev/talks/2012/splash.article">Go at Google</a>.</span></li>
+</ol></div></div>`
+
+func TestConvertFootnotes(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		option   *Option
+		expected string
+	}{
+		{"wikipedia", wikipediaReferences, &Option{Normalize: true},
+			"Go was designed at Google.[^1] It compiles fast.[^2][^1]\n\n## References\n\n" +
+				"[^1]: Pike, Rob (2012). [Go at Google](https://go.dev/talks/2012/splash.article).\n" +
+				"[^2]: Benchmarks _game_."},
+		{"dropped", wikipediaReferences, &Option{Normalize: true, DropFootnotes: true},
+			"Go was designed at Google. It compiles fast.\n\n## References"},
+		{"pandoc", `<p>Text<a href="#fn1" class="footnote-ref" id="fnref1" role="doc-noteref"><sup>1</sup></a>.</p>
+<section class="footnotes" role="doc-endnotes"><ol><li id="fn1"><p>First.<a href="#fnref1" class="footnote-back" role="doc-backlink">↩︎</a></p><p>Second.</p></li></ol></section>`,
+			&Option{Normalize: true}, "Text[^1].\n\n[^1]: First.\n\n    Second."},
+		{"github", `<p>Claim<sup><a href="#user-content-fn-1" id="user-content-fnref-1" data-footnote-ref>1</a></sup></p>
+<section data-footnotes class="footnotes"><ol><li id="user-content-fn-1"><p>Source <a href="#user-content-fnref-1" data-footnote-backref class="data-footnote-backref">↩</a></p></li></ol></section>`,
+			&Option{Normalize: true}, "Claim[^1]\n\n[^1]: Source"},
+		{"orphans", `<p>A<sup class="reference"><a href="#cite_note-missing-1">[1]</a></sup> b<sup class="reference"><a href="#cite_note-b-2">[2]</a></sup></p>
+<ol class="references"><li id="cite_note-b-2">B.</li><li id="cite_note-unused-3">Unused.</li><li id="cite_note-b-2">Again.</li></ol><p>After.</p>`,
+			&Option{Normalize: true}, "A[^1] b[^2]\n\n[^2]: B.\n[^3]: Unused.\n\nAfter."},
+		{"not a reference", `<p>x<sup>2</sup> and <sup><a href="#section">see</a></sup> <sup class="noprint">[citation needed]</sup></p>`,
+			nil, "x2 and [see](#section) \\[citation needed\\]"},
+	}
+
+	for _, test := range tests {
+		result, err := ConvertString(test.html, test.option)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("%s: Expected %q, got %q", test.name, test.expected, result)
+		}
+
+		var b bytes.Buffer
+		if err := Convert(io.MultiReader(strings.NewReader(test.html)), &b, test.option); err != nil {
+			t.Errorf("%s: stream: %v", test.name, err)
+			continue
+		}
+		if result := strings.TrimSpace(b.String()); result != test.expected {
+			t.Errorf("%s: stream: Expected %q, got %q", test.name, test.expected, result)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package markdown
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// inferredParagraphLength is how many runes of text make an inline only <div> a paragraph
+// with InferBlocks, whatever its siblings.
+const inferredParagraphLength = 80
+
+// inferredBlock is how InferBlocks converts an element.
+type inferredBlock int
+
+const (
+	// inferNone converts the element as usual.
+	inferNone inferredBlock = iota
+	// inferDiv converts the element like a <div>, on lines of its own.
+	inferDiv
+	// inferParagraph converts the element like a <p>.
+	inferParagraph
+)
+
+// layoutElements hold the blocks of a page without being blocks of text themselves.
+// InferBlocks only looks at elements whose ancestors are all layout elements, so that
+// the elements of paragraphs, headings, list items, table cells and links are converted
+// as usual.
+var layoutElements = map[string]bool{
+	"html": true, "body": true, "div": true, "main": true, "article": true, "section": true,
+	"header": true, "footer": true, "aside": true, "nav": true, "figure": true, "form": true,
+	"center": true,
+}
+
+// blockClassRegexp matches the classes of utility CSS frameworks that display an element
+// as a block, such as "d-block", "block", "flex" and "paragraph".
+var blockClassRegexp = regexp.MustCompile(`^(?:[a-z]+[-_:])?(?:block|flex|grid|paragraph|para)$`)
+
+// blockStyleRegexp matches an inline style displaying an element as a block.
+var blockStyleRegexp = regexp.MustCompile(`(?i)display\s*:\s*(?:block|flex|grid)\b`)
+
+// inferences are the results InferBlocks has already worked out for the nodes of the
+// document being converted, so that each element is looked at once however many of its
+// siblings and descendants are inferred.
+type inferences struct {
+	blocks    map[*html.Node]inferredBlock
+	children  map[*html.Node]bool // Parents whose children are all blocks
+	ancestors map[*html.Node]bool // Elements in layout elements only, themselves included
+	inline    map[*html.Node]bool // Results of inlineOnly
+}
+
+func newInferences() *inferences {
+	return &inferences{
+		blocks:    map[*html.Node]inferredBlock{},
+		children:  map[*html.Node]bool{},
+		ancestors: map[*html.Node]bool{},
+		inline:    map[*html.Node]bool{},
+	}
+}
+
+// inferences returns the inferences of the document being converted with option.
+func (o *Option) inferences() *inferences {
+	if o.inferred == nil {
+		o.inferred = newInferences()
+	}
+	return o.inferred
+}
+
+// inferBlock returns how InferBlocks converts node: a <div>, or a <span> displayed as a
+// block by its class or style, with only inline content is a paragraph when it holds a
+// long text or a <br>, or when a sibling like it is next to it. A <span> with blocks in it
+// is a <div>. Elements within the text of a paragraph, a heading, a list item, a link or
+// another inline element are converted as usual.
+func inferBlock(node *html.Node, option *Option) inferredBlock {
+	inferred := option.inferences()
+	if block, ok := inferred.blocks[node]; ok {
+		return block
+	}
+	block := inferNodeBlock(node, option)
+	inferred.blocks[node] = block
+	return block
+}
+
+func inferNodeBlock(node *html.Node, option *Option) inferredBlock {
+	name := strings.ToLower(node.Data)
+	if name != "div" && !(name == "span" && displayedAsBlock(node)) {
+		return inferNone
+	}
+	if !inLayout(node, option) {
+		return inferNone
+	}
+	if !inlineOnly(node, option) {
+		if name == "span" {
+			return inferDiv
+		}
+		return inferNone
+	}
+
+	text := strings.Join(strings.Fields(textContent(node)), " ")
+	if utf8.RuneCountInString(text) >= inferredParagraphLength || hasDescendant(node, "br") && text != "" ||
+		inferredSibling(node, false, option) || inferredSibling(node, true, option) {
+		return inferParagraph
+	}
+	if name == "span" {
+		return inferDiv
+	}
+	return inferNone
+}
+
+// displayedAsBlock reports whether the class or style of node displays it as a block.
+func displayedAsBlock(node *html.Node) bool {
+	for _, class := range strings.Fields(strings.ToLower(attr(node, "class"))) {
+		if !strings.HasPrefix(class, "inline") && blockClassRegexp.MatchString(class) {
+			return true
+		}
+	}
+	return blockStyleRegexp.MatchString(attr(node, "style"))
+}
+
+// inLayout reports whether node is a block of a layout: its siblings are blocks too, or
+// scripts and the like, not text or inline elements, and its ancestors are layout
+// elements or blocks inferred from spans.
+func inLayout(node *html.Node, option *Option) bool {
+	inferred := option.inferences()
+	blocks, ok := inferred.children[node.Parent]
+	if !ok {
+		blocks = blockChildren(node.Parent)
+		inferred.children[node.Parent] = blocks
+	}
+	return blocks && inLayoutElements(node.Parent, inferred)
+}
+
+// inLayoutElements reports whether node and its ancestors are layout elements or spans
+// displayed as blocks.
+func inLayoutElements(node *html.Node, inferred *inferences) bool {
+	if node == nil || node.Type != html.ElementNode {
+		return true
+	}
+	if layout, ok := inferred.ancestors[node]; ok {
+		return layout
+	}
+	name := strings.ToLower(node.Data)
+	layout := (layoutElements[name] || name == "span" && displayedAsBlock(node)) && inLayoutElements(node.Parent, inferred)
+	inferred.ancestors[node] = layout
+	return layout
+}
+
+// blockChildren reports whether the children of node are blocks, or scripts and the like,
+// and whitespace.
+func blockChildren(node *html.Node) bool {
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.TextNode:
+			if strings.TrimSpace(c.Data) != "" {
+				return false
+			}
+		case html.ElementNode:
+			if !blockLike(c) && !headElements[strings.ToLower(c.Data)] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// blockLike reports whether node is written as a block of its own, or its class or style
+// display it as one.
+func blockLike(node *html.Node) bool {
+	name := strings.ToLower(node.Data)
+	return name != "br" && blockElements[name] || name == "span" && displayedAsBlock(node)
+}
+
+// inlineOnly reports whether node holds no blocks: only text, inline elements and spans
+// displayed as blocks within the text.
+func inlineOnly(node *html.Node, option *Option) bool {
+	inferred := option.inferences()
+	if inline, ok := inferred.inline[node]; ok {
+		return inline
+	}
+	inline := true
+	for c := node.FirstChild; c != nil && inline; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		name := strings.ToLower(c.Data)
+		if name != "br" && blockElements[name] || name == "span" && displayedAsBlock(c) && inLayout(c, option) || !inlineOnly(c, option) {
+			inline = false
+		}
+	}
+	inferred.inline[node] = inline
+	return inline
+}
+
+// inferredSibling reports whether the element before node, or after it with next, is a
+// <div> or a block <span> with inline content only, text or images.
+func inferredSibling(node *html.Node, next bool, option *Option) bool {
+	c := nextChild(node, !next)
+	for c != nil && c.Type != html.ElementNode {
+		c = nextChild(c, !next)
+	}
+	if c == nil {
+		return false
+	}
+	name := strings.ToLower(c.Data)
+	if name != "div" && !(name == "span" && displayedAsBlock(c)) || !inlineOnly(c, option) {
+		return false
+	}
+	return strings.TrimSpace(textContent(c)) != "" || hasDescendant(c, "img")
+}
+
+func textContent(node *html.Node) string {
+	var b strings.Builder
+	var text func(n *html.Node)
+	text = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			text(c)
+		}
+	}
+	text(node)
+	return b.String()
+}
+
+func hasDescendant(node *html.Node, name string) bool {
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && strings.ToLower(c.Data) == name || hasDescendant(c, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// inferredParagraph writes node as a paragraph, on a line of its own after a blank line
+// even when whitespace separates it from the block before it.
+func inferredParagraph(node *html.Node, w io.Writer, nest int, option *Option) {
+	prev := node.PrevSibling
+	if prev != nil && prev.Type == html.TextNode && strings.TrimSpace(prev.Data) == "" && !option.TrimSpace {
+		for prev != nil && prev.Type != html.ElementNode {
+			prev = prev.PrevSibling
+		}
+		if prev != nil {
+			fmt.Fprint(w, "\n")
+		}
+	}
+	paragraph(node, w, nest, option)
+}
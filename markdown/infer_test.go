@@ -0,0 +1,157 @@
+package markdown
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestInferBlocksFixture converts a page made of nested <div> and <span> elements, as the
+// front end frameworks of news sites write them, without and with InferBlocks.
+func TestInferBlocksFixture(t *testing.T) {
+	input, err := os.ReadFile(filepath.Join("testdata", "infer", "news.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expected string
+		option   *Option
+	}{
+		{"news.before.md", &Option{Normalize: true}},
+		{"news.md", &Option{Normalize: true, InferBlocks: true}},
+	}
+	for _, test := range tests {
+		expected, err := os.ReadFile(filepath.Join("testdata", "infer", test.expected))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var b strings.Builder
+		if err := Convert(strings.NewReader(string(input)), &b, test.option); err != nil {
+			t.Fatal(err)
+		}
+		if result := b.String(); result != string(expected) {
+			t.Errorf("%s: Expected %q, got %q", test.expected, expected, result)
+		}
+	}
+}
+
+// TestInferBlocksStream checks that streamed documents are converted like parsed ones.
+func TestInferBlocksStream(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "infer", "news.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	expected, err := os.ReadFile(filepath.Join("testdata", "infer", "news.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if err := ConvertStream(f, &b, &Option{Normalize: true, InferBlocks: true}); err != nil {
+		t.Fatal(err)
+	}
+	if result := b.String(); result != string(expected) {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestInferBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"sibling divs", "<div>\n  <div>Name</div>\n  <div>Price</div>\n</div>", "Name\n\nPrice"},
+		{"long text", "<div><h2>Title</h2>\n<div>" + strings.Repeat("word ", 20) + "</div></div>",
+			"## Title\n\n" + strings.TrimSpace(strings.Repeat("word ", 20))},
+		{"line breaks", "<div><h2>Title</h2>\n<div>One<br>Two</div></div>", "## Title\n\nOne  \nTwo"},
+		{"short div", "<div><div>Alone</div></div>", "Alone"},
+		{"block spans", `<div><span class="d-block">One</span><span style="display: block">Two</span></div>`, "One\n\nTwo"},
+		{"inline block span", `<div><span class="inline-block">One</span><span class="inline-block">Two</span></div>`, "OneTwo"},
+		{"span in a sentence", `<div>Ready in <span class="d-block">late 2027</span>, they said.</div>`, "Ready in late 2027, they said."},
+		{"paragraphs", "<div><div><p>One</p><p>Two</p></div>\n<div><p>Three</p></div></div>", "One\n\nTwo\n\nThree"},
+		{"escape", `<div><span class="d-block">- not a list</span><span class="d-block">2. not a list</span></div>`, "\\- not a list\n\n2\\. not a list"},
+	}
+
+	for _, test := range tests {
+		result, err := ConvertString(test.input, &Option{Normalize: true, InferBlocks: true})
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if result != test.expected {
+			t.Errorf("%s: Expected %q, got %q", test.name, test.expected, result)
+		}
+	}
+}
+
+// TestInferBlocksUnchanged checks that elements within text, list items, links and
+// headings are converted as without InferBlocks.
+func TestInferBlocksUnchanged(t *testing.T) {
+	tests := []string{
+		`<div><b>Note:</b><span class="block">read this</span></div>`,
+		`<div>Intro <div>One</div><div>Two</div> outro</div>`,
+		"<ul><li><div>One</div><div>Two</div></li></ul>",
+		`<div><a href="/a"><div>Card</div><div>Text</div></a></div>`,
+		`<h2><div>Part</div><div>one</div></h2>`,
+		`<table><tr><td><div>One</div><div>Two</div></td></tr></table>`,
+	}
+
+	for _, input := range tests {
+		expected, err := ConvertString(input, &Option{Normalize: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result, _ := ConvertString(input, &Option{Normalize: true, InferBlocks: true}); result != expected {
+			t.Errorf("%s: Expected %q, got %q", input, expected, result)
+		}
+	}
+}
+
+// siblingDivs returns a page of n sibling <div> elements holding a line of text each.
+func siblingDivs(n int) string {
+	var b strings.Builder
+	b.WriteString("<html><body><div>")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "<div>Line %d</div>", i)
+	}
+	b.WriteString("</div></body></html>")
+	return b.String()
+}
+
+// Test that every element is looked at once, however many siblings it has
+func TestInferBlocksManySiblings(t *testing.T) {
+	input := siblingDivs(20000)
+
+	start := time.Now()
+	result, err := ConvertString(input, &Option{Normalize: true, InferBlocks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected a linear conversion, took %v", elapsed)
+	}
+	if !strings.HasPrefix(result, "Line 0\n\nLine 1\n\n") || !strings.HasSuffix(result, "\n\nLine 19999") {
+		t.Errorf("Expected a paragraph per line, got %q...", result[:40])
+	}
+}
+
+func BenchmarkInferBlocks(b *testing.B) {
+	input := siblingDivs(8000)
+	for _, infer := range []bool{false, true} {
+		b.Run(fmt.Sprintf("infer=%v", infer), func(b *testing.B) {
+			b.SetBytes(int64(len(input)))
+			for i := 0; i < b.N; i++ {
+				if err := Convert(strings.NewReader(input), io.Discard, &Option{InferBlocks: infer}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
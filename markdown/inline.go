@@ -0,0 +1,317 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// emphasisKind is a kind of inline formatting written with delimiters around its content
+type emphasisKind uint8
+
+const (
+	emphasisEm emphasisKind = 1 << iota
+	emphasisStrong
+	emphasisDel
+)
+
+// hardBreak ends a line with a hard line break, unless Option.BackslashBreaks is set
+const hardBreak = "  "
+
+// emphasis writes node, a <b>, <strong>, <i>, <em>, <del> or <s>, with the delimiters of
+// kind around its content. The delimiters follow the rules of CommonMark for delimiter
+// runs, https://spec.commonmark.org/0.29/#delimiter-run:
+//
+//   - whitespace at the edges of the content is written outside of the delimiters, and
+//     content that is only whitespace gets none
+//   - emphasis nested in the same kind of emphasis gets none, "****" would close it
+//   - punctuation at the edge of the content is written outside of the delimiters when a
+//     letter is on the other side of them, since "a**:**b" would not be bold
+//   - emphasis inside a word uses "*", an intraword "_" is literal
+//   - emphasis that cannot be delimited, such as a code span followed by a letter, is
+//     written as inline HTML
+func emphasis(node *html.Node, w io.Writer, nest int, option *Option, kind emphasisKind) {
+	if option.emphasis&kind != 0 {
+		walk(node, w, nest, option)
+		return
+	}
+
+	clone := option.Clone()
+	clone.emphasis |= kind
+	var buf bytes.Buffer
+	walk(node, &buf, nest, clone)
+	s := buf.String()
+	if strings.TrimSpace(s) == "" {
+		fmt.Fprint(w, s)
+		return
+	}
+
+	start := len(s) - len(strings.TrimLeftFunc(s, unicode.IsSpace))
+	stop := len(strings.TrimRightFunc(s, unicode.IsSpace))
+	text := s[start:stop]
+
+	prev, next := ' ', ' '
+	if start == 0 {
+		prev = adjacentRune(node, false, option)
+	}
+	if stop == len(s) {
+		next = adjacentRune(node, true, option)
+	}
+	var before, after string
+	if isWordRune(prev) {
+		before, text = splitPunct(text, false)
+	}
+	if isWordRune(next) {
+		text, after = splitPunct(text, true)
+	}
+	if text == "" {
+		fmt.Fprint(w, s)
+		return
+	}
+
+	delim, tag := "**", "strong"
+	switch kind {
+	case emphasisEm:
+		delim, tag = "_", "em"
+		if before == "" && isWordRune(prev) || after == "" && isWordRune(next) {
+			delim = "*"
+		}
+	case emphasisDel:
+		delim, tag = "~~", "del"
+	}
+
+	// Markup at the edge of the content, such as a code span, cannot be moved out: the
+	// element is written as HTML when a letter is on the other side of the delimiters
+	inner := strings.Trim(text, delim[:1])
+	first, _ := utf8.DecodeRuneInString(inner)
+	last, _ := utf8.DecodeLastRuneInString(inner)
+	if before == "" && isWordRune(prev) && !isWordRune(first) || after == "" && isWordRune(next) && !isWordRune(last) {
+		fmt.Fprint(w, s[:start]+before+"<"+tag+">"+text+"</"+tag+">"+after+s[stop:])
+		return
+	}
+	fmt.Fprint(w, s[:start]+before+delim+text+delim+after+s[stop:])
+}
+
+// isWordRune reports whether r is neither whitespace nor punctuation, so that a delimiter
+// next to it must have content without punctuation on its other side.
+func isWordRune(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsPunct(r) && !unicode.IsSymbol(r)
+}
+
+// splitPunct splits text after the punctuation at its start, or with end before the
+// punctuation at its end. Escaped characters are punctuation, but not the characters of
+// markdown syntax, such as the delimiters of nested emphasis or the brackets of a link.
+func splitPunct(text string, end bool) (string, string) {
+	i := 0
+	if end {
+		i = len(text)
+	}
+	for {
+		var r rune
+		var size int
+		if end {
+			r, size = utf8.DecodeLastRuneInString(text[:i])
+		} else {
+			r, size = utf8.DecodeRuneInString(text[i:])
+		}
+		if size == 0 || !unicode.IsPunct(r) && !unicode.IsSymbol(r) {
+			break
+		}
+
+		escaped := false
+		if end {
+			escaped = i-size > 0 && text[i-size-1] == '\\'
+		} else {
+			escaped = r == '\\' && i+1 < len(text) && isASCIIPunct(text[i+1])
+		}
+		switch {
+		case escaped:
+			size++
+		case strings.ContainsRune("*_~`[]!<>()\\", r):
+			return text[:i], text[i:]
+		}
+
+		if end {
+			i -= size
+		} else {
+			i += size
+		}
+	}
+	return text[:i], text[i:]
+}
+
+// adjacentRune returns the rune of markdown written next to node, an inline element: the
+// last rune before it, or with after the first rune after it. Whitespace, and the edge of
+// a block, are returned as ' '. The delimiters of the emphasis node is in are looked
+// through, since their own delimiters are placed around them.
+func adjacentRune(node *html.Node, after bool, option *Option) rune {
+	sibling := func(n *html.Node) *html.Node {
+		if after {
+			return n.NextSibling
+		}
+		return n.PrevSibling
+	}
+
+	for n := node; n != nil; n = n.Parent {
+		for s := sibling(n); s != nil; s = sibling(s) {
+			if r, ok := edgeRune(s, !after, option); ok {
+				return r
+			}
+		}
+
+		parent := n.Parent
+		if parent == nil || parent.Type != html.ElementNode || blockElements[strings.ToLower(parent.Data)] {
+			return ' '
+		}
+		if strings.ToLower(parent.Data) == "a" && attr(parent, "href") != "" {
+			if after {
+				return ']'
+			}
+			return '['
+		}
+	}
+	return ' '
+}
+
+// edgeRune returns the first rune of the markdown of node, or with last its last rune.
+// It returns false for nodes that write nothing.
+func edgeRune(node *html.Node, last bool, option *Option) (rune, bool) {
+	switch node.Type {
+	case html.TextNode:
+		if node.Data == "" {
+			return 0, false
+		}
+		r, _ := utf8.DecodeRuneInString(node.Data)
+		if last {
+			r, _ = utf8.DecodeLastRuneInString(node.Data)
+		}
+		if unicode.IsSpace(r) {
+			return ' ', true
+		}
+		return r, true
+	case html.ElementNode:
+	default:
+		return 0, false
+	}
+	if option.dropped(node) {
+		return 0, false
+	}
+
+	name := strings.ToLower(node.Data)
+	switch {
+	case blockElements[name]:
+		return ' ', true
+	case name == "img":
+		return '!', true
+	case name == "code":
+		return '`', true
+	case name == "a" && attr(node, "href") != "":
+		if last {
+			return ')', true
+		}
+		return '[', true
+	case delimitedElements[name] && name != "a":
+		return '*', true
+	}
+
+	c := node.FirstChild
+	if last {
+		c = node.LastChild
+	}
+	for ; c != nil; c = nextChild(c, last) {
+		if r, ok := edgeRune(c, last, option); ok {
+			return r, true
+		}
+	}
+	return 0, false
+}
+
+func nextChild(c *html.Node, reverse bool) *html.Node {
+	if reverse {
+		return c.PrevSibling
+	}
+	return c.NextSibling
+}
+
+// lineBreak writes a <br> as a hard line break, two spaces or with BackslashBreaks a
+// backslash at the end of the line. A <br> that starts or ends the content of a block
+// only ends the line, and a second <br> in a row ends the paragraph. In headings and
+// table cells, which are a single line, it is written as a space.
+func lineBreak(node *html.Node, w io.Writer, option *Option) {
+	for p := node.Parent; p != nil && p.Type == html.ElementNode; p = p.Parent {
+		switch strings.ToLower(p.Data) {
+		case "h1", "h2", "h3", "h4", "h5", "h6", "td", "th":
+			fmt.Fprint(w, " ")
+			return
+		}
+	}
+
+	isBr := func(n *html.Node) bool {
+		return n != nil && n.Type == html.ElementNode && strings.ToLower(n.Data) == "br"
+	}
+	prev, next := node.PrevSibling, node.NextSibling
+	for prev != nil && (prev.Type == html.CommentNode || prev.Type == html.TextNode && strings.TrimSpace(prev.Data) == "") {
+		prev = prev.PrevSibling
+	}
+	for next != nil && (next.Type == html.CommentNode || next.Type == html.TextNode && strings.TrimSpace(next.Data) == "") {
+		next = next.NextSibling
+	}
+
+	switch {
+	case atLineEdge(node, false, option):
+		if isBr(prev) {
+			fmt.Fprint(w, "\n")
+		}
+	case atLineEdge(node, true, option) && !isBr(next):
+		fmt.Fprint(w, "\n")
+	case option.BackslashBreaks:
+		fmt.Fprint(w, "\\\n")
+	default:
+		fmt.Fprint(w, hardBreak+"\n")
+	}
+}
+
+// subSup writes a <sub> or <sup> in the SubSupStyle of option.
+func subSup(node *html.Node, w io.Writer, nest int, option *Option) {
+	name := strings.ToLower(node.Data)
+	switch option.SubSupStyle {
+	case SubSupHTML:
+		fmt.Fprint(w, "<"+name+">")
+		walk(node, w, nest, option)
+		fmt.Fprint(w, "</"+name+">")
+	case SubSupNotation:
+		var buf bytes.Buffer
+		walk(node, &buf, nest, option)
+		text := strings.TrimSpace(buf.String())
+		if text == "" {
+			fmt.Fprint(w, buf.String())
+			return
+		}
+		delim := "^"
+		if name == "sub" {
+			delim = "~"
+		}
+		// Spaces and the delimiters would end the text early
+		text = subSupReplacer.Replace(text)
+		fmt.Fprint(w, delim+text+delim)
+	default:
+		walk(node, w, nest, option)
+	}
+}
+
+var subSupReplacer = strings.NewReplacer(" ", `\ `, "\n", `\ `, "^", `\^`, "~", `\~`)
+
+// trimLine strips the trailing whitespace of a line of markdown, keeping the two spaces of
+// a hard line break.
+func trimLine(l string) string {
+	trimmed := strings.TrimRight(l, " \t")
+	if trimmed != "" && strings.HasSuffix(l, hardBreak) {
+		return trimmed + hardBreak
+	}
+	return trimmed
+}
@@ -0,0 +1,75 @@
+package markdown
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// The expected markdown of these cases was checked against the CommonMark spec for
+// delimiter runs, https://spec.commonmark.org/0.29/#emphasis-and-strong-emphasis.
+func TestConvertInline(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		option   *Option
+		expected string
+	}{
+		{"strong em", `<p><strong><em>x</em></strong></p>`, nil, "**_x_**"},
+		{"em strong", `<p><em><strong>x</strong></em></p>`, nil, "_**x**_"},
+		{"strong em in a word", `<p>a<strong><em>x</em></strong>b</p>`, nil, "a***x***b"},
+		{"strong in strong", `<p><b>a <strong>b</strong> c</b></p>`, nil, "**a b c**"},
+		{"em in em", `<p><em>a <i>b</i></em></p>`, nil, "_a b_"},
+		{"em in a word", `<p>un<em>believ</em>able</p>`, nil, "un*believ*able"},
+		{"em before a word", `<p><em>pre</em>fix</p>`, nil, "*pre*fix"},
+		{"strong in a word", `<p>foo<strong>bar</strong>baz</p>`, nil, "foo**bar**baz"},
+		{"punctuation before a word", `<p><strong>Note:</strong>text</p>`, nil, "**Note**:text"},
+		{"punctuation after a word", `<p>say<b>"hi"</b>now</p>`, nil, `say"**hi**"now`},
+		{"punctuation between spaces", `<p>a <b>"hi"</b> b</p>`, nil, `a **"hi"** b`},
+		{"only punctuation", `<p>a<b>:</b>b</p>`, nil, "a:b"},
+		{"escaped punctuation", `<p>x<b>[1]</b>y</p>`, nil, `x\[**1**\]y`},
+		{"empty", `<p>a<b></b><em> </em>b</p>`, nil, "a b"},
+		{"whitespace edges", `<p>a<b> bold </b>b</p>`, nil, "a **bold** b"},
+		{"link in strong", `<p><b><a href="/x">x</a></b></p>`, nil, "**[x](/x)**"},
+		{"strong in link", `<p><a href="/x">a<b>b</b></a></p>`, nil, "[a**b**](/x)"},
+		{"em next to link", `<p><a href="/x">x</a><em>y</em></p>`, nil, "[x](/x)_y_"},
+		{"del", `<p>a<s>b</s> <del><del>c</del></del></p>`, nil, "a~~b~~ ~~c~~"},
+		{"code in em", `<p><em><code>x</code></em>s</p>`, nil, "<em>`x`</em>s"},
+		{"code in em between spaces", `<p>a <em><code>x</code></em> s</p>`, nil, "a _`x`_ s"},
+		{"br", `<p>one<br>two<br/>three</p>`, nil, "one  \ntwo  \nthree"},
+		{"br backslash", `<p>one<br>two</p>`, &Option{BackslashBreaks: true}, "one\\\ntwo"},
+		{"br twice", `<p>one<br><br>two</p>`, nil, "one  \n\ntwo"},
+		{"br at edges", `<p><br>one<br></p><div>two<br></div>`, &Option{Normalize: true}, "one\n\ntwo"},
+		{"br in strong", `<p><b>one<br>two</b></p>`, nil, "**one  \ntwo**"},
+		{"br in heading", `<h2>one<br>two</h2>`, nil, "## one two"},
+		{"br in table", `<table><tr><th>a<br>b</th></tr></table>`, nil, "| a b |\n| --- |"},
+		{"br in list", `<ul><li>one<br>two</li></ul>`, nil, "* one  \n  two"},
+		{"br in quote", `<blockquote>one<br>two</blockquote>`, nil, "> one  \n> two"},
+		{"br normalized", `<p>one<br>two</p><p>three<br></p>`, &Option{Normalize: true}, "one  \ntwo\n\nthree"},
+		{"br wrapped", `<p>one two<br>three four</p>`, &Option{WrapWidth: 5}, "one\ntwo  \nthree\nfour"},
+		{"sub sup", `<p>H<sub>2</sub>O x<sup>2</sup></p>`, nil, "H2O x2"},
+		{"sub sup html", `<p>H<sub>2</sub>O x<sup>2</sup></p>`, &Option{SubSupStyle: SubSupHTML}, "H<sub>2</sub>O x<sup>2</sup>"},
+		{"sub sup notation", `<p>H<sub>2</sub>O x<sup>a b</sup><sup> </sup></p>`, &Option{SubSupStyle: SubSupNotation}, `H~2~O x^a\ b^`},
+	}
+
+	for _, test := range tests {
+		result, err := ConvertString(test.html, test.option)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("%s: Expected %q, got %q", test.name, test.expected, result)
+		}
+
+		var b bytes.Buffer
+		if err := Convert(io.MultiReader(strings.NewReader(test.html)), &b, test.option); err != nil {
+			t.Errorf("%s: stream: %v", test.name, err)
+			continue
+		}
+		if result := strings.TrimSpace(b.String()); result != test.expected {
+			t.Errorf("%s: stream: Expected %q, got %q", test.name, test.expected, result)
+		}
+	}
+}
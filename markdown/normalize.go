@@ -0,0 +1,123 @@
+package markdown
+
+import (
+	"strings"
+)
+
+// Normalize cleans up the whitespace of markdown, so that documents converted at different
+// times or from differently formatted HTML diff cleanly:
+//
+//   - line endings are "\n"
+//   - trailing spaces and tabs are stripped from every line, except the two spaces of a
+//     hard line break inside a paragraph
+//   - runs of blank lines are collapsed to one blank line
+//   - headings and code fences are set apart from the blocks around them by one blank line
+//   - the document has no blank lines at its start or end and ends with a newline
+//
+// Code fences are left as they are, and so is a YAML front matter block at the start.
+// Normalize is idempotent: normalizing its result changes nothing.
+func Normalize(markdown string) string {
+	markdown = strings.ReplaceAll(markdown, "\r\n", "\n")
+	markdown = strings.ReplaceAll(markdown, "\r", "\n")
+	lines := strings.Split(markdown, "\n")
+
+	var out []string
+	// blank is set when blank lines were dropped since the last line, separate when the last
+	// block must be followed by a blank line
+	blank, separate := false, false
+	// fence is the opening marker of the code fence being copied, fenceBlock set when it is
+	// not nested in a list item or quote
+	fence, fenceBlock := "", false
+
+	if end := frontMatterEnd(lines); end > 0 {
+		for _, l := range lines[:end+1] {
+			out = append(out, strings.TrimRight(l, " \t"))
+		}
+		lines = lines[end+1:]
+		separate = true
+	}
+
+	for _, l := range lines {
+		if fence != "" {
+			if isClosingFence(l, fence) {
+				l = strings.TrimRight(l, " \t")
+				fence, separate = "", fenceBlock
+			}
+			out = append(out, l)
+			continue
+		}
+
+		l = trimLine(l)
+		if strings.TrimSpace(l) == "" {
+			blank = len(out) > 0
+			continue
+		}
+		// Fences nested in list items and quotes belong to the block around them
+		marker := openingFence(l)
+		block := isATXHeading(l) || (marker != "" && l[0] == marker[0])
+		if len(out) > 0 && (blank || separate || block) {
+			// A hard line break does not end a paragraph
+			out[len(out)-1] = strings.TrimRight(out[len(out)-1], " \t")
+			out = append(out, "")
+		}
+		out = append(out, l)
+		blank, separate = false, block
+
+		if marker != "" {
+			fence, fenceBlock = marker, block
+			separate = false
+		}
+	}
+
+	// An unclosed fence runs to the end, blank lines included
+	for len(out) > 0 && strings.TrimSpace(out[len(out)-1]) == "" {
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return ""
+	}
+	if fence == "" {
+		out[len(out)-1] = strings.TrimRight(out[len(out)-1], " \t")
+	}
+	return strings.Join(out, "\n") + "\n"
+}
+
+// frontMatterEnd returns the index of the line closing the YAML front matter block the
+// lines start with, or -1.
+func frontMatterEnd(lines []string) int {
+	if len(lines) == 0 || strings.TrimRight(lines[0], " \t") != "---" {
+		return -1
+	}
+	for i := 1; i < len(lines); i++ {
+		if l := strings.TrimRight(lines[i], " \t"); l == "---" || l == "..." {
+			return i
+		}
+	}
+	return -1
+}
+
+// isATXHeading reports whether l is a heading such as "## Title".
+func isATXHeading(l string) bool {
+	level := len(l) - len(strings.TrimLeft(l, "#"))
+	return level >= 1 && level <= 6 && (len(l) == level || l[level] == ' ' || l[level] == '\t')
+}
+
+// openingFence returns the run of backticks or tildes that opens a code fence on l, or ""
+// if l opens none.
+func openingFence(l string) string {
+	l = strings.TrimLeft(l, " \t>")
+	if l == "" || (l[0] != '`' && l[0] != '~') {
+		return ""
+	}
+	marker := l[:len(l)-len(strings.TrimLeft(l, l[:1]))]
+	if len(marker) < 3 || (marker[0] == '`' && strings.Contains(l[len(marker):], "`")) {
+		return ""
+	}
+	return marker
+}
+
+// isClosingFence reports whether l closes the code fence opened by marker.
+func isClosingFence(l, marker string) bool {
+	l = strings.TrimSpace(strings.TrimLeft(l, " \t>"))
+	return len(l) >= len(marker) && strings.Trim(l, marker[:1]) == ""
+}
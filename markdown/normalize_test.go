@@ -0,0 +1,106 @@
+package markdown
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"empty", "", ""},
+		{"blank", " \n\n\t\n", ""},
+		{"newline", "text", "text\n"},
+		{"blank lines", "\n\na\n\n\n\nb\n\n\n", "a\n\nb\n"},
+		{"trailing whitespace", "a \nb\t\n", "a\nb\n"},
+		{"hard break", "a   \nb  \n\nc  \n# d  \ne  ", "a  \nb\n\nc\n\n# d\n\ne\n"},
+		{"line endings", "a\r\n\r\n\r\nb\rc\r\n", "a\n\nb\nc\n"},
+		{"headings", "# Title\ntext\n## Section\n- item", "# Title\n\ntext\n\n## Section\n\n- item\n"},
+		{"not headings", "#hashtag\n####### seven", "#hashtag\n####### seven\n"},
+		{"fence", "text\n```go\nx := 1   \n\n\n\ny := 2\n```  \nafter", "text\n\n```go\nx := 1   \n\n\n\ny := 2\n```\n\nafter\n"},
+		{"tilde fence", "~~~~\n```\n~~~\n~~~~\n", "~~~~\n```\n~~~\n~~~~\n"},
+		{"not a fence", "``` a ` b\n\n\ntext", "``` a ` b\n\ntext\n"},
+		{"nested fence", "- item\n  ```\n  code  \n  ```\n- next", "- item\n  ```\n  code  \n  ```\n- next\n"},
+		{"unclosed fence", "```\ncode  \n\n\n", "```\ncode  \n"},
+		{"front matter", "---\ntitle: x  \n---\n# Title", "---\ntitle: x\n---\n\n# Title\n"},
+		{"front matter only", "---\ntitle: x\n---\n\n\n", "---\ntitle: x\n---\n"},
+		{"rule", "a\n\n---\n\nb", "a\n\n---\n\nb\n"},
+	}
+
+	for _, test := range tests {
+		if result := Normalize(test.input); result != test.expected {
+			t.Errorf("%s: Expected %q, got %q", test.name, test.expected, result)
+		}
+		if result := Normalize(test.expected); result != test.expected {
+			t.Errorf("%s: Expected %q to be normalized, got %q", test.name, test.expected, result)
+		}
+	}
+}
+
+// TestNormalizeIdempotent converts the fixtures with Normalize and checks that normalizing
+// the result again changes nothing.
+func TestNormalizeIdempotent(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "normalize", "*.html"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("Expected fixtures, got %v", err)
+	}
+
+	options := map[string]*Option{
+		"default":      {Normalize: true},
+		"trim space":   {Normalize: true, TrimSpace: true},
+		"front matter": {Normalize: true, FrontMatter: map[string]any{"title": "Fixture"}},
+		"plain tables": {Normalize: true, PlainTables: true, HeadingStyle: HeadingSetext},
+	}
+	for _, file := range files {
+		input, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, option := range options {
+			var b strings.Builder
+			if err := Convert(strings.NewReader(string(input)), &b, option); err != nil {
+				t.Fatalf("%s, %s: %v", file, name, err)
+			}
+			result := b.String()
+			if again := Normalize(result); again != result {
+				t.Errorf("%s, %s: Expected normalizing twice to be a no-op, got %q then %q", file, name, result, again)
+			}
+			checkNormalized(t, file+", "+name, result)
+		}
+	}
+}
+
+// checkNormalized checks the whitespace guarantees of Normalize on markdown.
+func checkNormalized(t *testing.T, name, markdown string) {
+	t.Helper()
+	if markdown == "" {
+		return
+	}
+	if strings.Contains(markdown, "\r") {
+		t.Errorf("%s: Expected only \\n line endings, got %q", name, markdown)
+	}
+	if strings.HasPrefix(markdown, "\n") || !strings.HasSuffix(markdown, "\n") || strings.HasSuffix(markdown, "\n\n") {
+		t.Errorf("%s: Expected no blank lines at the start or end, got %q", name, markdown)
+	}
+	fence, blank := "", false
+	for i, line := range strings.Split(strings.TrimSuffix(markdown, "\n"), "\n") {
+		if fence != "" {
+			if isClosingFence(line, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if line == "" && blank {
+			t.Errorf("%s: Expected single blank lines, got another at line %d of %q", name, i+1, markdown)
+		}
+		if strings.TrimRight(line, " \t") != line {
+			t.Errorf("%s: Expected no trailing whitespace on line %d, got %q", name, i+1, line)
+		}
+		fence, blank = openingFence(line), line == ""
+	}
+}
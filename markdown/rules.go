@@ -0,0 +1,152 @@
+package markdown
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// selector is the parsed form of the selectors accepted by Option.AddRule.
+type selector struct {
+	tag     string
+	classes []string
+	attrs   []attrSelector
+}
+
+type attrSelector struct {
+	key      string
+	value    string
+	hasValue bool
+}
+
+// parseSelector parses a selector made of an optional tag name followed by any number
+// of ".class", "[attr]" and "[attr=value]" parts, e.g. "div.note" or "a[download]".
+func parseSelector(s string) (selector, error) {
+	var sel selector
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return sel, fmt.Errorf("markdown: empty selector")
+	}
+
+	i := strings.IndexAny(s, ".[")
+	if i < 0 {
+		i = len(s)
+	}
+	sel.tag = strings.ToLower(s[:i])
+	if sel.tag == "*" {
+		sel.tag = ""
+	}
+
+	for rest := s[i:]; rest != ""; {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[") + 1
+			if end == 0 {
+				end = len(rest)
+			}
+			if end == 1 {
+				return sel, fmt.Errorf("markdown: empty class in selector %q", s)
+			}
+			sel.classes = append(sel.classes, rest[1:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return sel, fmt.Errorf("markdown: unclosed [ in selector %q", s)
+			}
+			var a attrSelector
+			a.key, a.value, a.hasValue = strings.Cut(rest[1:end], "=")
+			a.key = strings.ToLower(strings.TrimSpace(a.key))
+			a.value = strings.Trim(strings.TrimSpace(a.value), `"'`)
+			if a.key == "" {
+				return sel, fmt.Errorf("markdown: empty attribute in selector %q", s)
+			}
+			sel.attrs = append(sel.attrs, a)
+			rest = rest[end+1:]
+		default:
+			return sel, fmt.Errorf("markdown: invalid selector %q", s)
+		}
+	}
+	if strings.ContainsAny(sel.tag, " >+~#:") {
+		return sel, fmt.Errorf("markdown: invalid selector %q", s)
+	}
+	return sel, nil
+}
+
+func (sel selector) match(node *html.Node) bool {
+	if node.Type != html.ElementNode {
+		return false
+	}
+	if sel.tag != "" && strings.ToLower(node.Data) != sel.tag {
+		return false
+	}
+	for _, class := range sel.classes {
+		if !hasClass(node, class) {
+			return false
+		}
+	}
+	for _, a := range sel.attrs {
+		found := false
+		for _, attr := range node.Attr {
+			if attr.Key == a.key && (!a.hasValue || attr.Val == a.value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+type rule struct {
+	selector selector
+	walk     WalkFunc
+}
+
+// AddRule registers fn to convert the elements matching selector instead of the built-in
+// handling. A selector is a tag name optionally followed by classes and attributes, such
+// as "aside", "div.warning", "span[data-tooltip]" or ".note[id=intro]". Rules are tried in
+// the order they were added and the first match wins; rules added with AddRule are tried
+// before CustomRules.
+//
+// fn can call walk(node, ...) through the WalkFunc it was given to convert the children
+// of the node. AddRule must not be called while a conversion using o is running.
+func (o *Option) AddRule(selector string, fn WalkFunc) error {
+	sel, err := parseSelector(selector)
+	if err != nil {
+		return err
+	}
+	o.rules = append(o.rules, rule{selector: sel, walk: fn})
+	return nil
+}
+
+// customRule returns the rule converting node, or nil if it has the built-in handling.
+// node itself is skipped while its rule is converting it, so that the rule can walk its
+// children.
+func (o *Option) customRule(node *html.Node) WalkFunc {
+	if node.Type != html.ElementNode || node == o.ruleNode {
+		return nil
+	}
+	for _, r := range o.rules {
+		if r.selector.match(node) {
+			return r.walk
+		}
+	}
+	return o.customRulesMap[strings.ToLower(node.Data)]
+}
+
+// applyRule converts node with its custom rule and reports whether it had one.
+func applyRule(node *html.Node, w io.Writer, nest int, option *Option) bool {
+	customWalk := option.customRule(node)
+	if customWalk == nil {
+		return false
+	}
+	clone := option.Clone()
+	clone.ruleNode = node
+	customWalk(node, w, nest, clone)
+	return true
+}
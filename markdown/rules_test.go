@@ -0,0 +1,84 @@
+package markdown
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestAddRule(t *testing.T) {
+	wrap := func(before, after string) WalkFunc {
+		return func(node *html.Node, w io.Writer, nest int, option *Option) {
+			fmt.Fprint(w, before)
+			walk(node, w, nest, option)
+			fmt.Fprint(w, after)
+		}
+	}
+	drop := func(node *html.Node, w io.Writer, nest int, option *Option) {}
+
+	tests := []struct {
+		name     string
+		selector string
+		fn       WalkFunc
+		html     string
+		want     string
+	}{
+		{"tag", "mark", wrap("==", "=="), `<p>a <mark>highlight</mark></p>`, "a ==highlight=="},
+		{"overrides built-in", "strong", wrap("__", "__"), `<p><strong>bold</strong></p>`, "__bold__"},
+		{"tag and class", "div.note", wrap("> **Note:** ", ""), `<div class="box note">Careful</div><div>Plain</div>`, "> **Note:** Careful\nPlain"},
+		{"class only", ".ad", drop, `<p>Text<span class="ad">Buy now</span></p>`, "Text"},
+		{"attribute", "span[data-tooltip]", wrap("", "*"), `<p><span data-tooltip="x">term</span> <span>other</span></p>`, "term* other"},
+		{"attribute value", "a[rel=nofollow]", wrap("", ""), `<p><a href="/x" rel="nofollow">plain</a> <a href="/y">link</a></p>`, "plain [link](/y)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			option := &Option{}
+			if err := option.AddRule(tt.selector, tt.fn); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			got, err := ConvertString(tt.html, option)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// Test that the first matching rule wins
+func TestAddRuleOrder(t *testing.T) {
+	option := &Option{}
+	for _, r := range []struct{ selector, text string }{
+		{"span.first", "first"},
+		{"span", "second"},
+	} {
+		text := r.text
+		err := option.AddRule(r.selector, func(node *html.Node, w io.Writer, nest int, option *Option) {
+			fmt.Fprint(w, text)
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	got, err := ConvertString(`<p><span class="first">a</span> <span>b</span></p>`, option)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := "first second"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestAddRuleInvalidSelector(t *testing.T) {
+	for _, selector := range []string{"", "div.", "a[href", "div > p", "[=x]"} {
+		if err := new(Option).AddRule(selector, walk); err == nil {
+			t.Errorf("Expected an error for %q", selector)
+		}
+	}
+}
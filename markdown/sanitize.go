@@ -0,0 +1,90 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// hiddenStyleRegex matches an inline style that hides an element.
+var hiddenStyleRegex = regexp.MustCompile(`(?i)(?:^|;)\s*(?:display\s*:\s*none|visibility\s*:\s*hidden)\s*(?:!important\s*)?(?:;|$)`)
+
+// structuralElements are converted whatever Option.AllowedTags holds.
+var structuralElements = map[string]bool{"html": true, "head": true, "body": true}
+
+// dropped reports whether the sanitizer leaves node out of the markdown: comments, <script>
+// and <style> unless Option.Script and Option.Style are set, <noscript>, <template>, and
+// elements hidden by the hidden attribute, aria-hidden="true" or an inline display:none or
+// visibility:hidden. Nothing is dropped with Option.DisableSanitize.
+func (o *Option) dropped(node *html.Node) bool {
+	if o != nil && o.DisableSanitize {
+		return false
+	}
+	if node.Type == html.CommentNode {
+		return true
+	}
+	if node.Type != html.ElementNode {
+		return false
+	}
+
+	switch strings.ToLower(node.Data) {
+	case "script":
+		return o == nil || !o.Script
+	case "style":
+		return o == nil || !o.Style
+	case "noscript", "template":
+		return true
+	}
+	for _, a := range node.Attr {
+		switch a.Key {
+		case "hidden":
+			return true
+		case "aria-hidden":
+			if strings.EqualFold(strings.TrimSpace(a.Val), "true") {
+				return true
+			}
+		case "style":
+			if hiddenStyleRegex.MatchString(a.Val) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unwrapped reports whether the element node is converted as its content alone, its tag
+// not being in Option.AllowedTags.
+func (o *Option) unwrapped(node *html.Node) bool {
+	if o == nil || len(o.AllowedTags) == 0 || node.Type != html.ElementNode {
+		return false
+	}
+	name := strings.ToLower(node.Data)
+	if structuralElements[name] {
+		return false
+	}
+	for _, tag := range o.AllowedTags {
+		if strings.EqualFold(tag, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// afterDropped reports whether the text node follows elements the sanitizer drops, right
+// after text ending in whitespace, which then stands for the whitespace of both.
+func (o *Option) afterDropped(node *html.Node) bool {
+	prev := node.PrevSibling
+	if prev == nil || !o.dropped(prev) {
+		return false
+	}
+	for prev != nil && o.dropped(prev) {
+		prev = prev.PrevSibling
+	}
+	if prev == nil || prev.Type != html.TextNode || prev.Data == "" {
+		return false
+	}
+	last := prev.Data[len(prev.Data)-1]
+	return last < 0x80 && unicode.IsSpace(rune(last))
+}
@@ -0,0 +1,79 @@
+package markdown
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		option   *Option
+		expected string
+	}{
+		{"style", `<html><head><style>body { color: red; }</style></head><body><style>.ad { display: block; }</style><p>Text</p></body></html>`, nil, "Text"},
+		{"script", `<p>Hello <script>track("x")</script></p><p>World</p>`, &Option{Normalize: true}, "Hello\n\nWorld"},
+		{"script option", `<p>Hello</p><script>run()</script>`, &Option{Script: true, Normalize: true}, "Hello\n\n<script>run()</script>"},
+		{"noscript", `<p>Photo</p><noscript><img src="pixel.gif"></noscript>`, nil, "Photo"},
+		{"template", `<p>Table</p><template><p>Row</p></template>`, nil, "Table"},
+		{"template before body", `<template><p>t</p></template><p>x`, nil, "x"},
+		{"template in head", `<head><template><div><p>t</div><body>b</template></head><body><p>x</p></body>`, nil, "x"},
+		{"comment", `<p>One<!-- secret --> two</p>`, nil, "One two"},
+		{"hidden", `<p>a <span hidden>b</span> c</p>`, nil, "a c"},
+		{"aria-hidden", `<h2><span aria-hidden="true">#</span>Title</h2>`, nil, "## Title"},
+		{"display none", `<div style="color: red; display:none !important">cheap pills</div><p>Article</p>`, nil, "Article"},
+		{"visibility hidden", `<p>Kept <em style="visibility: hidden">spam</em></p>`, nil, "Kept"},
+		{"display block", `<p style="display: block">Shown</p>`, nil, "Shown"},
+		{"iframe", `<iframe src="https://example.com/embed"></iframe>`, &Option{DropEmbeds: true}, ""},
+		{"disabled", `<p>a<!--c--><span hidden>b</span></p>`, &Option{DisableSanitize: true}, "a<!--c-->\nb"},
+		{"allowed tags", `<p>Text <b>bold</b> <x-widget>inner <a href="/x">link</a></x-widget></p><ul><li>item</li></ul>`,
+			&Option{AllowedTags: []string{"P", "a"}}, "Text bold inner [link](/x)\n\nitem"},
+	}
+
+	for _, test := range tests {
+		result, err := ConvertString(test.html, test.option)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("%s: Expected %q, got %q", test.name, test.expected, result)
+		}
+
+		var b bytes.Buffer
+		if err := Convert(io.MultiReader(strings.NewReader(test.html)), &b, test.option); err != nil {
+			t.Errorf("%s: stream: %v", test.name, err)
+			continue
+		}
+		if result := strings.TrimSpace(b.String()); result != test.expected {
+			t.Errorf("%s: stream: Expected %q, got %q", test.name, test.expected, result)
+		}
+	}
+}
+
+func TestSanitizeStyleBlob(t *testing.T) {
+	html := `<html><head><title>Shop</title>
+<style>
+.product-card{display:flex;margin:0 auto}.price::before{content:"$"}
+@media (max-width: 600px) { .product-card { flex-direction: column } }
+</style></head>
+<body><div class="product-card"><style>.inline-blob{color:#333;font-family:Arial}</style>
+<h1>Blue widget</h1><p>Only <span class="price">12.99</span> today.</p></div></body></html>`
+
+	result, err := ConvertString(html, &Option{Normalize: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, css := range []string{"{", "}", "display", "font-family", "@media"} {
+		if strings.Contains(result, css) {
+			t.Errorf("Expected no CSS in the markdown, got %q", result)
+			break
+		}
+	}
+	if expected := "# Blue widget\n\nOnly 12.99 today."; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
@@ -0,0 +1,663 @@
+package markdown
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// streamElements are the block elements that walk converts without markup of their own.
+// ConvertStream converts their children one by one as soon as they are complete, instead
+// of waiting for the whole element.
+var streamElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "body": true, "div": true, "figcaption": true,
+	"figure": true, "footer": true, "header": true, "html": true, "main": true, "nav": true,
+	"section": true,
+}
+
+// headElements are the elements html.Parse puts into <head> when they come before the body.
+var headElements = map[string]bool{
+	"base": true, "basefont": true, "bgsound": true, "link": true, "meta": true, "noframes": true,
+	"noscript": true, "script": true, "style": true, "template": true, "title": true,
+}
+
+// voidElements have no end tag and no children.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "basefont": true, "bgsound": true, "br": true, "col": true,
+	"embed": true, "hr": true, "img": true, "input": true, "keygen": true, "link": true,
+	"meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// closesParagraph are the start tags that end an open <p>.
+var closesParagraph = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "center": true,
+	"details": true, "dialog": true, "dir": true, "div": true, "dl": true, "fieldset": true,
+	"figcaption": true, "figure": true, "footer": true, "form": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hgroup": true, "hr": true,
+	"listing": true, "main": true, "menu": true, "nav": true, "ol": true, "p": true,
+	"plaintext": true, "pre": true, "section": true, "summary": true, "ul": true, "xmp": true,
+}
+
+// specialElements are the elements an end tag of an ordinary element does not close.
+var specialElements = map[string]bool{
+	"address": true, "applet": true, "area": true, "article": true, "aside": true, "base": true,
+	"basefont": true, "bgsound": true, "blockquote": true, "body": true, "br": true, "button": true,
+	"caption": true, "center": true, "col": true, "colgroup": true, "dd": true, "details": true,
+	"dir": true, "div": true, "dl": true, "dt": true, "embed": true, "fieldset": true,
+	"figcaption": true, "figure": true, "footer": true, "form": true, "frame": true,
+	"frameset": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"head": true, "header": true, "hgroup": true, "hr": true, "html": true, "iframe": true,
+	"img": true, "input": true, "keygen": true, "li": true, "link": true, "listing": true,
+	"main": true, "marquee": true, "menu": true, "meta": true, "nav": true, "noembed": true,
+	"noframes": true, "noscript": true, "object": true, "ol": true, "p": true, "param": true,
+	"plaintext": true, "pre": true, "script": true, "section": true, "select": true,
+	"source": true, "style": true, "summary": true, "table": true, "tbody": true, "td": true,
+	"template": true, "textarea": true, "tfoot": true, "th": true, "thead": true, "title": true,
+	"tr": true, "track": true, "ul": true, "wbr": true, "xmp": true,
+}
+
+// tableContent are the start tags that belong directly into a table, other content is
+// moved in front of it.
+var tableContent = map[string]bool{
+	"caption": true, "col": true, "colgroup": true, "form": true, "input": true, "script": true,
+	"style": true, "table": true, "tbody": true, "td": true, "template": true, "tfoot": true,
+	"th": true, "thead": true, "tr": true,
+}
+
+// The scopes an element is looked up in, by the elements that bound them
+var (
+	defaultScope = map[string]bool{
+		"applet": true, "caption": true, "html": true, "marquee": true, "object": true,
+		"table": true, "td": true, "template": true, "th": true,
+	}
+	buttonScope   = withScope(defaultScope, "button")
+	listItemScope = withScope(defaultScope, "ol", "ul")
+	tableScope    = map[string]bool{"html": true, "table": true, "template": true}
+)
+
+func withScope(scope map[string]bool, names ...string) map[string]bool {
+	s := make(map[string]bool, len(scope)+len(names))
+	for name := range scope {
+		s[name] = true
+	}
+	for _, name := range names {
+		s[name] = true
+	}
+	return s
+}
+
+// ConvertStream is like Convert, but reads r with the HTML tokenizer instead of parsing the
+// whole document first, and writes the Markdown as it goes. Content in <body>, <div>,
+// <section>, <article> and the like is converted as soon as it is complete and then
+// dropped, so memory grows with the largest table, list or other block rather than with
+// the document. Convert uses it for readers other than strings, bytes and buffers.
+//
+// The tree is built following the rules of html.Parse for well-formed and commonly
+// malformed HTML, such as unclosed paragraphs, list items and table cells, so the result
+// is the same as that of Convert. Misnested inline markup, such as <b><p>x</b></p>, is
+// closed where it ends instead of being reopened in the following blocks.
+//
+// Markdown is written to w while r is read, so on error w holds part of the result.
+// Normalize buffers the whole result, and an element matched by a custom rule is converted
+// as a whole. With InferBlocks, the document is parsed as a whole like with Convert.
+func ConvertStream(r io.Reader, w io.Writer, option *Option) error {
+	return convert(r, w, option, true)
+}
+
+// streamWriter keeps the first error of w, after which nothing more is written.
+type streamWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (sw *streamWriter) Write(p []byte) (int, error) {
+	if sw.err != nil {
+		return 0, sw.err
+	}
+	n, err := sw.w.Write(p)
+	sw.err = err
+	return n, err
+}
+
+// streamConverter builds the tree of a document from its tokens and converts the children
+// of stream elements as soon as they are complete.
+type streamConverter struct {
+	w      *streamWriter
+	option *Option
+	doc    *html.Node
+
+	// stack holds the open elements, the document first
+	stack []*html.Node
+	open  map[*html.Node]bool
+	// done maps every open stream element to its last converted child, or nil
+	done map[*html.Node]*html.Node
+
+	root, head, body *html.Node
+	quirks           bool // no <!DOCTYPE html>, tables do not close paragraphs
+	foreign          int  // open <svg> and <math>, whose self-closing tags are honored
+	dropNewline      bool // the newline starting the content of a <pre> is dropped
+}
+
+func newStreamConverter(w io.Writer, option *Option) *streamConverter {
+	s := &streamConverter{
+		w:      &streamWriter{w: w},
+		option: option,
+		doc:    &html.Node{Type: html.DocumentNode},
+		open:   map[*html.Node]bool{},
+		done:   map[*html.Node]*html.Node{},
+		quirks: true,
+	}
+	s.stack = []*html.Node{s.doc}
+	s.done[s.doc] = nil
+	return s
+}
+
+func streamConvert(r io.Reader, w io.Writer, option *Option) error {
+	s := newStreamConverter(w, option)
+	z := html.NewTokenizer(r)
+	for {
+		tokenType := z.Next()
+		if tokenType == html.ErrorToken {
+			if err := z.Err(); err != io.EOF {
+				return fmt.Errorf("markdown: parse html: %w", err)
+			}
+			break
+		}
+		s.token(tokenType, z.Token())
+		if s.w.err != nil {
+			return s.w.err
+		}
+	}
+
+	for len(s.stack) > 1 {
+		s.pop()
+	}
+	s.flush(s.doc, true)
+	return s.w.err
+}
+
+func (s *streamConverter) top() *html.Node {
+	return s.stack[len(s.stack)-1]
+}
+
+func (s *streamConverter) token(tokenType html.TokenType, token html.Token) {
+	dropNewline := s.dropNewline
+	s.dropNewline = false
+
+	switch tokenType {
+	case html.DoctypeToken:
+		if s.root == nil {
+			s.quirks = !strings.EqualFold(token.Data, "html")
+		}
+	case html.CommentToken:
+		s.top().AppendChild(&html.Node{Type: html.CommentNode, Data: token.Data})
+	case html.TextToken:
+		text := token.Data
+		if dropNewline {
+			text = strings.TrimPrefix(text, "\n")
+		}
+		s.text(text)
+	case html.StartTagToken, html.SelfClosingTagToken:
+		if token.DataAtom == atom.Image {
+			token.Data, token.DataAtom = "img", atom.Img
+		}
+		s.startTag(token, tokenType == html.SelfClosingTagToken)
+	case html.EndTagToken:
+		s.endTag(token.Data)
+	}
+
+	// Only the innermost stream element gets new children
+	for i := len(s.stack) - 1; i >= 0; i-- {
+		if _, ok := s.done[s.stack[i]]; ok {
+			s.flush(s.stack[i], false)
+			break
+		}
+	}
+}
+
+func (s *streamConverter) text(text string) {
+	if s.body == nil && (s.top() == s.doc || s.top() == s.root || s.top() == s.head) {
+		// Whitespace before the body is not content
+		if text = strings.TrimLeft(text, whitespace); text == "" {
+			return
+		}
+		s.startBody(nil)
+	}
+	if text == "" {
+		return
+	}
+
+	parent := s.top()
+	var before *html.Node
+	if inTable(parent) && strings.Trim(text, whitespace) != "" {
+		// Text in a table outside of a cell is moved in front of it
+		before = s.lookup("table", tableScope)
+		parent = before.Parent
+	}
+	prev := parent.LastChild
+	if before != nil {
+		prev = before.PrevSibling
+	}
+	if prev != nil && prev.Type == html.TextNode {
+		prev.Data += text
+		return
+	}
+	parent.InsertBefore(&html.Node{Type: html.TextNode, Data: text}, before)
+}
+
+const whitespace = " \t\n\f\r"
+
+func (s *streamConverter) startTag(token html.Token, selfClosing bool) {
+	name := token.Data
+	if s.headTemplate() > 0 {
+		// The content of a template stays in it, to be dropped with it
+		if name != "html" && name != "head" && name != "body" {
+			s.insert(s.top(), nil, token, selfClosing)
+		}
+		return
+	}
+	if s.body == nil {
+		switch {
+		case name == "html":
+			s.startRoot(token.Attr)
+			return
+		case name == "head":
+			s.startHead()
+			return
+		case headElements[name]:
+			s.startHead()
+			s.insert(s.head, nil, token, selfClosing)
+			return
+		case name == "body":
+			s.startBody(token.Attr)
+			return
+		}
+		s.startBody(nil)
+	}
+
+	switch name {
+	case "html", "body", "head", "frameset":
+		// Attributes of repeated tags are not content
+		return
+	case "table":
+		if !s.quirks {
+			s.closeParagraph()
+		}
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		s.closeParagraph()
+		if isHeading(s.top().Data) {
+			s.pop()
+		}
+	case "li":
+		s.closeListItem("li")
+	case "dd", "dt":
+		s.closeListItem("dd", "dt")
+	case "a":
+		if i := s.index(defaultScope, "a"); i > 0 {
+			s.popTo(i)
+		}
+	case "option":
+		if s.top().Data == "option" {
+			s.pop()
+		}
+	case "optgroup":
+		if s.top().Data == "option" {
+			s.pop()
+		}
+		if s.top().Data == "optgroup" {
+			s.pop()
+		}
+	case "caption", "colgroup", "tbody", "thead", "tfoot":
+		if !s.clearTo("table") {
+			return
+		}
+	case "tr":
+		if !s.clearTo("table", "tbody", "thead", "tfoot") {
+			return
+		}
+		if s.top().Data == "table" {
+			s.push(&html.Node{Type: html.ElementNode, Data: "tbody", DataAtom: atom.Tbody})
+		}
+	case "td", "th":
+		if s.lookup("table", tableScope) == nil {
+			return
+		}
+		if i := s.index(tableScope, "td", "th"); i > 0 {
+			s.popTo(i)
+		}
+		if !s.clearTo("table", "tbody", "thead", "tfoot", "tr") {
+			return
+		}
+		if s.top().Data == "table" {
+			s.push(&html.Node{Type: html.ElementNode, Data: "tbody", DataAtom: atom.Tbody})
+		}
+		if s.top().Data != "tr" {
+			s.push(&html.Node{Type: html.ElementNode, Data: "tr", DataAtom: atom.Tr})
+		}
+	default:
+		if closesParagraph[name] {
+			s.closeParagraph()
+		}
+	}
+
+	parent, before := s.top(), (*html.Node)(nil)
+	if inTable(parent) && !tableContent[name] {
+		before = s.lookup("table", tableScope)
+		parent = before.Parent
+	}
+	s.insert(parent, before, token, selfClosing)
+	switch name {
+	case "pre", "listing", "textarea":
+		s.dropNewline = true
+	}
+}
+
+func (s *streamConverter) endTag(name string) {
+	if t := s.headTemplate(); t > 0 {
+		for i := len(s.stack) - 1; i >= t; i-- {
+			if s.stack[i].Data == name {
+				s.popTo(i)
+				return
+			}
+		}
+		return
+	}
+	if s.body == nil {
+		if top := s.top(); top.Data == name && (top == s.head || top.Parent == s.head) {
+			s.pop()
+		}
+		return
+	}
+
+	switch name {
+	case "html", "body", "head":
+		// Content after </body> still belongs to the body
+	case "br":
+		s.startTag(html.Token{Type: html.StartTagToken, DataAtom: atom.Br, Data: "br"}, false)
+	case "p":
+		if s.index(buttonScope, "p") < 0 {
+			s.top().AppendChild(&html.Node{Type: html.ElementNode, Data: "p", DataAtom: atom.P})
+		}
+		s.closeParagraph()
+	case "li":
+		if i := s.index(listItemScope, "li"); i > 0 {
+			s.popTo(i)
+		}
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		if i := s.index(defaultScope, "h1", "h2", "h3", "h4", "h5", "h6"); i > 0 {
+			s.popTo(i)
+		}
+	case "table", "tbody", "thead", "tfoot", "tr", "td", "th", "caption", "colgroup":
+		if i := s.index(tableScope, name); i > 0 {
+			s.popTo(i)
+		}
+	default:
+		if specialElements[name] {
+			if i := s.index(defaultScope, name); i > 0 {
+				s.popTo(i)
+			}
+			return
+		}
+		for i := len(s.stack) - 1; i > 0; i-- {
+			if s.stack[i].Data == name {
+				s.popTo(i)
+				return
+			}
+			if specialElements[s.stack[i].Data] {
+				return
+			}
+		}
+	}
+}
+
+// insert adds the element of token to parent, before the given child or last, and opens
+// it unless it is void.
+func (s *streamConverter) insert(parent, before *html.Node, token html.Token, selfClosing bool) {
+	n := &html.Node{Type: html.ElementNode, Data: token.Data, DataAtom: token.DataAtom, Attr: token.Attr}
+	if voidElements[token.Data] || (selfClosing && s.foreign > 0) {
+		parent.InsertBefore(n, before)
+		return
+	}
+	if parent != s.top() || before != nil {
+		parent.InsertBefore(n, before)
+		s.stack = append(s.stack, n)
+		s.open[n] = true
+		s.opened(n)
+		return
+	}
+	s.push(n)
+}
+
+// push adds n as the last child of the current element and opens it. It becomes a stream
+// element if its parent is one and no custom rule converts it.
+func (s *streamConverter) push(n *html.Node) {
+	parent := s.top()
+	parent.AppendChild(n)
+	s.stack = append(s.stack, n)
+	s.open[n] = true
+	s.opened(n)
+
+	if _, ok := s.done[parent]; !ok || !streamElements[n.Data] || s.option.customRule(n) != nil ||
+		s.option.dropped(n) || s.option.unwrapped(n) {
+		return
+	}
+	// Everything before n is complete now
+	s.flush(parent, false)
+	s.done[n] = nil
+	if n.Data == "div" {
+		br(n, s.w, s.option)
+	}
+}
+
+func (s *streamConverter) opened(n *html.Node) {
+	if n.Data == "svg" || n.Data == "math" {
+		s.foreign++
+	}
+}
+
+// pop closes the current element. A stream element writes the rest of its children.
+func (s *streamConverter) pop() {
+	n := s.top()
+	s.stack = s.stack[:len(s.stack)-1]
+	delete(s.open, n)
+	if n.Data == "svg" || n.Data == "math" {
+		s.foreign--
+	}
+
+	if _, ok := s.done[n]; !ok {
+		return
+	}
+	s.flush(n, true)
+	if n.Data == "div" {
+		fmt.Fprint(s.w, "\n")
+	}
+	delete(s.done, n)
+	n.FirstChild, n.LastChild = nil, nil
+	if parent := n.Parent; parent != nil {
+		if _, ok := s.done[parent]; ok {
+			s.done[parent] = n
+			s.prune(parent)
+		}
+	}
+}
+
+// popTo closes the elements down to and including s.stack[i].
+func (s *streamConverter) popTo(i int) {
+	for len(s.stack) > i {
+		s.pop()
+	}
+}
+
+// flush converts the complete children of the stream element f that are followed by
+// content, or with closed all of them, and drops their subtrees. Converting a child needs
+// the first child after it, to know whether it ends a line.
+func (s *streamConverter) flush(f *html.Node, closed bool) {
+	done := s.done[f]
+	c := f.FirstChild
+	if done != nil {
+		c = done.NextSibling
+	}
+
+	var last *html.Node
+	if !closed {
+		for l := f.LastChild; l != nil && l != done; l = l.PrevSibling {
+			if isContent(l) {
+				last = l
+				break
+			}
+		}
+		if last == nil {
+			return
+		}
+	}
+
+	for ; c != nil && c != last && !s.open[c]; c = c.NextSibling {
+		walkChild(c, s.w, 0, s.option)
+		c.FirstChild, c.LastChild = nil, nil
+		done = c
+	}
+	s.done[f] = done
+	s.prune(f)
+}
+
+// prune drops the converted children of f, but for those from the last one with content
+// that is not dropped on, which the siblings after them look at.
+func (s *streamConverter) prune(f *html.Node) {
+	keep := s.done[f]
+	for keep != nil && (!isContent(keep) || s.option.dropped(keep)) && keep.PrevSibling != nil {
+		keep = keep.PrevSibling
+	}
+	if keep == nil {
+		return
+	}
+	for f.FirstChild != keep {
+		f.RemoveChild(f.FirstChild)
+	}
+}
+
+// isContent reports whether n is an element or text that is not whitespace.
+func isContent(n *html.Node) bool {
+	return n.Type == html.ElementNode || (n.Type == html.TextNode && strings.Trim(n.Data, whitespace) != "")
+}
+
+func (s *streamConverter) startRoot(attr []html.Attribute) {
+	if s.root != nil {
+		return
+	}
+	s.root = &html.Node{Type: html.ElementNode, Data: "html", DataAtom: atom.Html, Attr: attr}
+	s.push(s.root)
+}
+
+func (s *streamConverter) startHead() {
+	s.startRoot(nil)
+	if s.head != nil {
+		return
+	}
+	s.head = &html.Node{Type: html.ElementNode, Data: "head", DataAtom: atom.Head}
+	s.push(s.head)
+}
+
+func (s *streamConverter) startBody(attr []html.Attribute) {
+	s.startRoot(nil)
+	for s.top() != s.root {
+		s.pop()
+	}
+	s.body = &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body, Attr: attr}
+	s.push(s.body)
+}
+
+// headTemplate returns the position in the stack of the innermost open <template> of the
+// head, before the body, or -1.
+func (s *streamConverter) headTemplate() int {
+	if s.body != nil {
+		return -1
+	}
+	for i := len(s.stack) - 1; i > 0 && s.stack[i] != s.head; i-- {
+		if s.stack[i].Data == "template" {
+			return i
+		}
+	}
+	return -1
+}
+
+// closeParagraph closes an open <p>.
+func (s *streamConverter) closeParagraph() {
+	if i := s.index(buttonScope, "p"); i > 0 {
+		s.popTo(i)
+	}
+}
+
+// closeListItem closes the list item of the given names that a new one ends.
+func (s *streamConverter) closeListItem(names ...string) {
+	for i := len(s.stack) - 1; i > 0; i-- {
+		n := s.stack[i].Data
+		if hasName(n, names) {
+			s.popTo(i)
+			break
+		}
+		if specialElements[n] && n != "address" && n != "div" && n != "p" {
+			break
+		}
+	}
+	s.closeParagraph()
+}
+
+// clearTo closes the elements above the innermost of the table elements names and reports
+// whether there is a table to put the new element into.
+func (s *streamConverter) clearTo(names ...string) bool {
+	if s.lookup("table", tableScope) == nil {
+		return false
+	}
+	for !hasName(s.top().Data, names) {
+		s.pop()
+	}
+	return true
+}
+
+// index returns the position in the stack of the innermost open element of one of names
+// within scope, or -1.
+func (s *streamConverter) index(scope map[string]bool, names ...string) int {
+	for i := len(s.stack) - 1; i > 0; i-- {
+		n := s.stack[i].Data
+		if hasName(n, names) {
+			return i
+		}
+		if scope[n] {
+			break
+		}
+	}
+	return -1
+}
+
+// lookup returns the innermost open element name within scope, or nil.
+func (s *streamConverter) lookup(name string, scope map[string]bool) *html.Node {
+	if i := s.index(scope, name); i > 0 {
+		return s.stack[i]
+	}
+	return nil
+}
+
+func hasName(name string, names []string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isHeading(name string) bool {
+	return len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6'
+}
+
+// inTable reports whether n is a table element that holds no content of its own.
+func inTable(n *html.Node) bool {
+	switch n.Data {
+	case "table", "tbody", "thead", "tfoot", "tr":
+		return n.Type == html.ElementNode
+	}
+	return false
+}
@@ -0,0 +1,230 @@
+package markdown
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+
+	"golang.org/x/net/html"
+)
+
+// Test that ConvertStream writes the same Markdown as walking the parsed tree
+func TestConvertStream(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+	}{
+		{"unclosed paragraphs", "<p>one<p>two<div>three</div>four"},
+		{"unclosed list items", "<ul><li>a<li>b<ul><li>c</ul><li>d</ul>"},
+		{"definition list", "<dl><dt>t<dd>d<dt>t2<dd>d2</dl>"},
+		{"implied table rows", "<table><tr><td>a<td>b<tr><th>c</table>"},
+		{"cell without row", "<table><td>c</td></table>"},
+		{"text in table", "<table>stray<tr><td>x</td></tr></table>after"},
+		{"element in table", "<table><div>moved</div><tr><td>x</table>"},
+		{"pre newline", "<pre>\ncode\n  more</pre><textarea>\nx</textarea>"},
+		{"document", "<!DOCTYPE html><html><head><title>T</title><style>p{}</style></head>\n<body>\n<h1>H</h1>\n<!-- c --><p>x</p></body></html>trailing"},
+		{"head only", "<meta charset=utf-8><p>x"},
+		{"svg", "<svg><path/><text>t</text></svg><p>after</p>"},
+		{"nested divs", "<div>a<div>b</div>c<span>d</span> e <b>f</b></div>text"},
+		{"escaping", "<div><h2>Title</h2>#not a heading<p>1. item</p>\n<p>- dash</p></div>"},
+		{"stray end tags", "<p>a</span>b</p></p>c<h1>a<h2>b</h1>c"},
+		{"nested links", "<a href=x>one<a href=y>two</a>"},
+		{"breaks", "<section><blockquote><p>q</blockquote></section><br>x<hr><div><br>y</div><div></div>"},
+		{"list blocks", "<ol><li><p>a</p><li><pre>b</pre></ol>"},
+		{"leading text", "  leading <b>bold</b>"},
+		{"template before body", "<template><p>t</p></template><p>x"},
+		{"template in head", "<head><title>T</title><template><ul><li>t</ul></template></head><p>x"},
+	}
+
+	files, err := filepath.Glob("testdata/normalize/*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		input, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests = append(tests, struct {
+			name string
+			html string
+		}{filepath.Base(file), string(input)})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, option := range []*Option{nil, {TrimSpace: true}, {PlainTables: true, WrapWidth: 40}} {
+				var want, got bytes.Buffer
+				if err := convert(strings.NewReader(tt.html), &want, option, false); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if err := ConvertStream(strings.NewReader(tt.html), &got, option); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if got.String() != want.String() {
+					t.Errorf("Expected %q, got %q", want.String(), got.String())
+				}
+			}
+		})
+	}
+}
+
+// Test that Convert streams readers other than strings and bytes
+func TestConvertStreamReader(t *testing.T) {
+	input := "<div><p>First</p><ul><li>item</ul></div><p>Last</p>"
+	var b strings.Builder
+	if err := Convert(iotest.OneByteReader(strings.NewReader(input)), &b, &Option{TrimSpace: true}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want, err := ConvertString(input, &Option{TrimSpace: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := strings.TrimSpace(b.String()); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+// Test that completed blocks are written before the rest of the document is read
+func TestConvertStreamIncremental(t *testing.T) {
+	pr, pw := io.Pipe()
+	out := make(chan string)
+	var b syncBuffer
+	go func() {
+		ConvertStream(pr, &b, &Option{TrimSpace: true})
+		out <- b.String()
+	}()
+
+	io.WriteString(pw, "<body><h1>Title</h1><p>First</p><p>")
+	// Writing to the pipe returns once the converter reads the next chunk, by then it has
+	// converted everything before it
+	io.WriteString(pw, "Second")
+	if got := b.String(); !strings.Contains(got, "First") {
+		t.Errorf("Expected the first paragraph to be written, got %q", got)
+	}
+	pw.Close()
+	if got := <-out; !strings.Contains(got, "Second") {
+		t.Errorf("Expected the second paragraph to be written, got %q", got)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+// Test that ConvertStream reports read and write errors
+func TestConvertStreamErrors(t *testing.T) {
+	readErr := errors.New("read failed")
+	r := io.MultiReader(strings.NewReader("<p>x</p>"), iotest.ErrReader(readErr))
+	if err := ConvertStream(r, io.Discard, nil); !errors.Is(err, readErr) {
+		t.Errorf("Expected %v, got %v", readErr, err)
+	}
+
+	writeErr := errors.New("write failed")
+	if err := ConvertStream(strings.NewReader("<p>x</p><p>y</p>"), errWriter{writeErr}, nil); !errors.Is(err, writeErr) {
+		t.Errorf("Expected %v, got %v", writeErr, err)
+	}
+}
+
+type errWriter struct{ err error }
+
+func (w errWriter) Write([]byte) (int, error) { return 0, w.err }
+
+// largeDocument returns about size bytes of HTML made of repeated article sections.
+func largeDocument(size int) []byte {
+	section := `<section><h2>Release notes</h2>
+<p>The <b>parser</b> now handles <a href="/docs/parser">nested lists</a> and <code>pre</code> blocks.</p>
+<ul><li>First change</li><li>Second change with <em>emphasis</em></li></ul>
+<table><tr><th>Name</th><th>Value</th></tr><tr><td>size</td><td>42</td></tr></table>
+<pre><code class="language-go">func main() {}
+</code></pre>
+<div><img src="/img/diagram.png" alt="Diagram"><p>Caption</p></div>
+</section>
+`
+	var b bytes.Buffer
+	b.WriteString("<!DOCTYPE html><html><head><title>Large</title></head><body><main>")
+	for b.Len() < size {
+		b.WriteString(section)
+	}
+	b.WriteString("</main></body></html>")
+	return b.Bytes()
+}
+
+// readerOnly hides the type of the reader it wraps, so that Convert streams it.
+type readerOnly struct{ io.Reader }
+
+// Compare the allocations of the tree and streaming conversions of a 10 MB document
+func BenchmarkConvert(b *testing.B) {
+	doc := largeDocument(10 << 20)
+	b.Run("tree", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(doc)))
+		for i := 0; i < b.N; i++ {
+			if err := Convert(bytes.NewReader(doc), io.Discard, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(doc)))
+		for i := 0; i < b.N; i++ {
+			if err := Convert(readerOnly{bytes.NewReader(doc)}, io.Discard, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// Test that the tree the streaming conversion keeps stays small for large documents
+func TestConvertStreamMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("converts a large document")
+	}
+	var maxNodes int
+	count := func(n *html.Node) int {
+		nodes := 0
+		var visit func(*html.Node)
+		visit = func(n *html.Node) {
+			nodes++
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				visit(c)
+			}
+		}
+		visit(n)
+		return nodes
+	}
+
+	doc := largeDocument(256 << 10)
+	s := newStreamConverter(io.Discard, &Option{})
+	z := html.NewTokenizer(bytes.NewReader(doc))
+	for tokenType := z.Next(); tokenType != html.ErrorToken; tokenType = z.Next() {
+		s.token(tokenType, z.Token())
+		if n := count(s.doc); n > maxNodes {
+			maxNodes = n
+		}
+	}
+	if maxNodes > 100 {
+		t.Errorf("Expected at most 100 nodes kept, got %d", maxNodes)
+	}
+}
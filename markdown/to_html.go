@@ -0,0 +1,662 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ToHTML renders Markdown as HTML. It supports the subset that Convert writes: ATX and
+// Setext headings, paragraphs, emphasis, strikethrough, links, images, code spans, fenced
+// code blocks, block quotes, nested lists, pipe tables and horizontal rules.
+//
+// Raw HTML in md is escaped, and links with a javascript: or similar URL are written as
+// their text, unless option.AllowRawHTML is set. A nil option uses the defaults.
+func ToHTML(md []byte, option *Option) ([]byte, error) {
+	if option == nil {
+		option = &Option{}
+	}
+	if !utf8.Valid(md) {
+		return nil, fmt.Errorf("markdown: input is not valid UTF-8")
+	}
+
+	text := strings.ReplaceAll(string(md), "\r\n", "\n")
+
+	r := &renderer{option: option}
+	r.blocks(strings.Split(text, "\n"), false)
+	return r.buf.Bytes(), nil
+}
+
+type renderer struct {
+	buf    bytes.Buffer
+	option *Option
+}
+
+var (
+	atxHeadingRegex = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ ]+(.*?))?(?:[ ]+#+)?[ ]*$`)
+	setextRegex     = regexp.MustCompile(`^ {0,3}(=+|-+)[ ]*$`)
+	hrRegex         = regexp.MustCompile(`^ {0,3}(?:(?:\*[ ]*){3,}|(?:-[ ]*){3,}|(?:_[ ]*){3,})$`)
+	fenceRegex      = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ ]*([^`\\s]*)")
+	listItemRegex   = regexp.MustCompile(`^( *)([-*+]|\d{1,9}[.)])( +|$)`)
+	delimRowRegex   = regexp.MustCompile(`^ *\|? *:?-+:? *(?:\| *:?-+:? *)*\|? *$`)
+	htmlBlockRegex  = regexp.MustCompile(`(?i)^ {0,3}<(?:!--|/?(?:address|article|aside|blockquote|details|div|dl|fieldset|figure|footer|form|h[1-6]|header|hr|iframe|main|nav|ol|p|pre|script|section|style|table|ul|video)(?:[\s/>]|$))`)
+)
+
+// blocks renders lines as block elements. In a tight list item, paragraphs are written
+// without <p>.
+func (r *renderer) blocks(lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+		case fenceRegex.MatchString(line):
+			i = r.fencedCode(lines, i)
+		case atxHeadingRegex.MatchString(line):
+			m := atxHeadingRegex.FindStringSubmatch(line)
+			r.heading(len(m[1]), m[2])
+			i++
+		case hrRegex.MatchString(line):
+			r.buf.WriteString("<hr>\n")
+			i++
+		case isBlockquote(line):
+			i = r.blockquote(lines, i)
+		case listItemRegex.MatchString(line):
+			i = r.list(lines, i)
+		case isTableStart(lines, i):
+			i = r.table(lines, i)
+		case r.option.AllowRawHTML && htmlBlockRegex.MatchString(line):
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+				r.buf.WriteString(lines[i] + "\n")
+			}
+		default:
+			i = r.paragraph(lines, i, tight)
+		}
+	}
+}
+
+func (r *renderer) heading(level int, text string) {
+	fmt.Fprintf(&r.buf, "<h%d>%s</h%d>\n", level, r.inline(strings.TrimSpace(text)), level)
+}
+
+// interrupts reports whether line starts a block that ends a paragraph.
+func interrupts(line string) bool {
+	return fenceRegex.MatchString(line) || atxHeadingRegex.MatchString(line) || hrRegex.MatchString(line) ||
+		isBlockquote(line) || listItemRegex.MatchString(line)
+}
+
+func (r *renderer) paragraph(lines []string, i int, tight bool) int {
+	var para []string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" || (len(para) > 0 && interrupts(line) && !setextRegex.MatchString(line)) {
+			break
+		}
+		if len(para) > 0 && setextRegex.MatchString(line) {
+			level := 1
+			if strings.Contains(line, "-") {
+				level = 2
+			}
+			r.heading(level, strings.Join(para, "\n"))
+			return i + 1
+		}
+		para = append(para, line)
+	}
+
+	// A line ending with two spaces or a backslash is a hard line break
+	for j := 0; j < len(para)-1; j++ {
+		if strings.HasSuffix(para[j], "  ") || strings.HasSuffix(para[j], `\`) {
+			para[j] = strings.TrimRight(strings.TrimSuffix(para[j], `\`), " ") + "\x00"
+		} else {
+			para[j] = strings.TrimSpace(para[j])
+		}
+	}
+	para[len(para)-1] = strings.TrimSpace(para[len(para)-1])
+	text := strings.ReplaceAll(r.inline(strings.Join(para, "\n")), "\x00", "<br>")
+
+	if tight {
+		r.buf.WriteString(text + "\n")
+	} else {
+		r.buf.WriteString("<p>" + text + "</p>\n")
+	}
+	return i
+}
+
+func (r *renderer) fencedCode(lines []string, i int) int {
+	m := fenceRegex.FindStringSubmatch(lines[i])
+	indent, delim, lang := len(m[1]), m[2], m[3]
+
+	var code strings.Builder
+	for i++; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimLeft(line, " ")
+		if len(line)-len(trimmed) < 4 && strings.HasPrefix(trimmed, delim) && strings.Trim(trimmed, delim[:1]+" ") == "" {
+			i++
+			break
+		}
+		// Lines lose as much indentation as the opening fence had
+		for n := 0; n < indent && strings.HasPrefix(line, " "); n++ {
+			line = line[1:]
+		}
+		code.WriteString(line + "\n")
+	}
+
+	r.buf.WriteString("<pre><code")
+	if lang != "" {
+		r.buf.WriteString(` class="language-` + html.EscapeString(lang) + `"`)
+	}
+	r.buf.WriteString(">" + html.EscapeString(code.String()) + "</code></pre>\n")
+	return i
+}
+
+func isBlockquote(line string) bool {
+	return strings.HasPrefix(strings.TrimLeft(line, " "), ">") && len(line)-len(strings.TrimLeft(line, " ")) < 4
+}
+
+func (r *renderer) blockquote(lines []string, i int) int {
+	var inner []string
+	for ; i < len(lines) && isBlockquote(lines[i]); i++ {
+		line := strings.TrimPrefix(strings.TrimLeft(lines[i], " "), ">")
+		inner = append(inner, strings.TrimPrefix(line, " "))
+	}
+
+	r.buf.WriteString("<blockquote>\n")
+	r.blocks(inner, false)
+	r.buf.WriteString("</blockquote>\n")
+	return i
+}
+
+type listMarker struct {
+	indent  int // spaces before the marker
+	width   int // indentation of the content of the item
+	ordered bool
+	kind    byte   // bullet character or ordered list delimiter
+	number  int    // number of an ordered item
+	rest    string // content on the line of the marker
+}
+
+func parseListMarker(line string) (listMarker, bool) {
+	m := listItemRegex.FindStringSubmatch(line)
+	if m == nil {
+		return listMarker{}, false
+	}
+
+	marker := listMarker{indent: len(m[1]), rest: line[len(m[0]):]}
+	marker.kind = m[2][len(m[2])-1]
+	if n, err := strconv.Atoi(m[2][:len(m[2])-1]); err == nil {
+		marker.ordered, marker.number = true, n
+	}
+
+	// Content starting more than 4 spaces after the marker is indented code,
+	// so only one of the spaces counts
+	spaces := len(m[3])
+	if spaces > 4 || marker.rest == "" {
+		spaces = 1
+		marker.rest = strings.TrimLeft(marker.rest, " ")
+	}
+	marker.width = marker.indent + len(m[2]) + spaces
+	return marker, true
+}
+
+func (r *renderer) list(lines []string, i int) int {
+	first, _ := parseListMarker(lines[i])
+
+	var items [][]string
+	// Blank lines between items or between the blocks of an item make the list loose
+	loose, blankAfter := false, false
+	for i < len(lines) {
+		marker, ok := parseListMarker(lines[i])
+		if !ok || marker.ordered != first.ordered || marker.kind != first.kind || marker.indent >= first.width {
+			break
+		}
+		if blankAfter {
+			loose = true
+		}
+
+		item := []string{marker.rest}
+		i++
+		blank := false
+		for ; i < len(lines); i++ {
+			line := lines[i]
+			if strings.TrimSpace(line) == "" {
+				blank = true
+				item = append(item, "")
+				continue
+			}
+			indent := len(line) - len(strings.TrimLeft(line, " "))
+			if indent >= marker.width {
+				item = append(item, line[marker.width:])
+				blank = false
+				continue
+			}
+			// A lazy continuation of the paragraph on the previous line
+			if !blank && !interrupts(line) && !isFenced(item) {
+				item = append(item, strings.TrimLeft(line, " "))
+				continue
+			}
+			break
+		}
+
+		blankAfter = false
+		for len(item) > 0 && item[len(item)-1] == "" {
+			item = item[:len(item)-1]
+			blankAfter = true
+		}
+		if hasBlankLine(item) {
+			loose = true
+		}
+		items = append(items, item)
+	}
+
+	tag := "ul"
+	if first.ordered {
+		tag = "ol"
+	}
+	if first.ordered && first.number != 1 {
+		fmt.Fprintf(&r.buf, "<ol start=\"%d\">\n", first.number)
+	} else {
+		r.buf.WriteString("<" + tag + ">\n")
+	}
+	for _, item := range items {
+		r.buf.WriteString("<li>")
+		switch {
+		case len(item) == 0:
+			// An empty item, such as "- " at the end of a list
+		case !loose && !interrupts(item[0]):
+			// Keep the text of a tight item on the line of <li>
+			sub := renderer{option: r.option}
+			sub.blocks(item, true)
+			r.buf.WriteString(strings.TrimSuffix(sub.buf.String(), "\n"))
+		default:
+			r.buf.WriteString("\n")
+			r.blocks(item, !loose)
+		}
+		r.buf.WriteString("</li>\n")
+	}
+	r.buf.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// isFenced reports whether lines end inside an open code fence.
+func isFenced(lines []string) bool {
+	open := false
+	for _, line := range lines {
+		if fenceRegex.MatchString(line) {
+			open = !open
+		}
+	}
+	return open
+}
+
+// hasBlankLine reports whether lines have a blank line between two blocks, outside of
+// code fences.
+func hasBlankLine(lines []string) bool {
+	fenced := false
+	for _, line := range lines {
+		if fenceRegex.MatchString(line) {
+			fenced = !fenced
+		}
+		if !fenced && strings.TrimSpace(line) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// isTableStart reports whether lines[i] is the header row of a pipe table.
+func isTableStart(lines []string, i int) bool {
+	return i+1 < len(lines) && strings.Contains(lines[i], "|") && strings.Contains(lines[i+1], "|") &&
+		delimRowRegex.MatchString(lines[i+1])
+}
+
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+func (r *renderer) table(lines []string, i int) int {
+	header := splitTableRow(lines[i])
+	var aligns []string
+	for _, d := range splitTableRow(lines[i+1]) {
+		switch {
+		case strings.HasPrefix(d, ":") && strings.HasSuffix(d, ":"):
+			aligns = append(aligns, "center")
+		case strings.HasSuffix(d, ":"):
+			aligns = append(aligns, "right")
+		case strings.HasPrefix(d, ":"):
+			aligns = append(aligns, "left")
+		default:
+			aligns = append(aligns, "")
+		}
+	}
+
+	row := func(cells []string, tag string) {
+		r.buf.WriteString("<tr>")
+		for j := range header {
+			if j < len(aligns) && aligns[j] != "" {
+				fmt.Fprintf(&r.buf, `<%s align="%s">`, tag, aligns[j])
+			} else {
+				r.buf.WriteString("<" + tag + ">")
+			}
+			if j < len(cells) {
+				r.buf.WriteString(r.inline(cells[j]))
+			}
+			r.buf.WriteString("</" + tag + ">")
+		}
+		r.buf.WriteString("</tr>\n")
+	}
+
+	r.buf.WriteString("<table>\n<thead>\n")
+	row(header, "th")
+	r.buf.WriteString("</thead>\n")
+	i += 2
+	if i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.Contains(lines[i], "|") {
+		r.buf.WriteString("<tbody>\n")
+		for ; i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.Contains(lines[i], "|"); i++ {
+			row(splitTableRow(lines[i]), "td")
+		}
+		r.buf.WriteString("</tbody>\n")
+	}
+	r.buf.WriteString("</table>\n")
+	return i
+}
+
+var (
+	autolinkRegex = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^<>\s]*)>`)
+	rawTagRegex   = regexp.MustCompile(`^<(?:/?[a-zA-Z][a-zA-Z0-9-]*(?:\s[^<>]*)?/?|!--[\s\S]*?--)>`)
+)
+
+// inline renders the inline content of a block.
+func (r *renderer) inline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+		case c == '`':
+			n := runLength(s[i:], '`')
+			if end := closingBackticks(s, i+n, n); end >= 0 {
+				code := strings.ReplaceAll(s[i+n:end], "\n", " ")
+				if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+					code = code[1 : len(code)-1]
+				}
+				b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				i = end + n
+				continue
+			}
+			b.WriteString(s[i : i+n])
+			i += n
+		case c == '!' && strings.HasPrefix(s[i:], "!["):
+			if out, n := r.link(s[i+1:], true); n > 0 {
+				b.WriteString(out)
+				i += n + 1
+				continue
+			}
+			b.WriteString("!")
+			i++
+		case c == '[':
+			if out, n := r.link(s[i:], false); n > 0 {
+				b.WriteString(out)
+				i += n
+				continue
+			}
+			b.WriteString("[")
+			i++
+		case c == '<':
+			if m := autolinkRegex.FindStringSubmatch(s[i:]); m != nil && r.safeURL(m[1]) {
+				fmt.Fprintf(&b, `<a href="%s">%s</a>`, html.EscapeString(m[1]), html.EscapeString(m[1]))
+				i += len(m[0])
+				continue
+			}
+			if m := rawTagRegex.FindString(s[i:]); m != "" && r.option.AllowRawHTML {
+				b.WriteString(m)
+				i += len(m)
+				continue
+			}
+			b.WriteString("&lt;")
+			i++
+		case c == '*' || c == '_' || c == '~':
+			if out, n := r.emphasis(s, i); n > 0 {
+				b.WriteString(out)
+				i += n
+				continue
+			}
+			n := runLength(s[i:], c)
+			b.WriteString(s[i : i+n])
+			i += n
+		default:
+			_, size := utf8.DecodeRuneInString(s[i:])
+			b.WriteString(html.EscapeString(s[i : i+size]))
+			i += size
+		}
+	}
+	return b.String()
+}
+
+func isASCIIPunct(c byte) bool {
+	return c < utf8.RuneSelf && unicode.IsPunct(rune(c)) || strings.IndexByte("$+<=>^`|~", c) >= 0
+}
+
+func runLength(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	return n
+}
+
+// closingBackticks finds the run of exactly n backticks closing a code span that starts
+// at from, or returns -1.
+func closingBackticks(s string, from, n int) int {
+	for i := from; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		run := runLength(s[i:], '`')
+		if run == n {
+			return i
+		}
+		i += run
+	}
+	return -1
+}
+
+// emphasis renders the emphasis, strong emphasis or strikethrough opened at s[i]. It
+// returns the number of bytes used, 0 when s[i] does not open one.
+func (r *renderer) emphasis(s string, i int) (string, int) {
+	c := s[i]
+	run := runLength(s[i:], c)
+
+	delims := []int{2, 1}
+	if c == '~' {
+		delims = []int{2}
+	}
+	for _, n := range delims {
+		start := i + n
+		// The opener must be followed by a non-space, and an intraword _ is literal
+		if run < n || start >= len(s) || isSpaceAt(s, start) || (c == '_' && i > 0 && isWordAt(s, i-1)) {
+			continue
+		}
+		for j := start; j < len(s); {
+			switch s[j] {
+			case '\\':
+				j += 2
+				continue
+			case '`':
+				k := runLength(s[j:], '`')
+				if end := closingBackticks(s, j+k, k); end >= 0 {
+					j = end + k
+				} else {
+					j += k
+				}
+				continue
+			case c:
+			default:
+				j++
+				continue
+			}
+
+			// Close with the last n delimiters of the run, the others belong to the content
+			end := j + runLength(s[j:], c)
+			closeAt := end - n
+			if closeAt > start && !isSpaceAt(s, j-1) && !(c == '_' && end < len(s) && isWordAt(s, end)) {
+				tag := "em"
+				switch {
+				case c == '~':
+					tag = "del"
+				case n == 2:
+					tag = "strong"
+				}
+				return "<" + tag + ">" + r.inline(s[start:closeAt]) + "</" + tag + ">", end - i
+			}
+			j = end
+		}
+	}
+	return "", 0
+}
+
+func isSpaceAt(s string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return unicode.IsSpace(r)
+}
+
+func isWordAt(s string, i int) bool {
+	r, _ := utf8.DecodeLastRuneInString(s[:i+1])
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// link renders the link or image starting with the "[" at s[0]. It returns the number of
+// bytes used, 0 when s does not start a link.
+func (r *renderer) link(s string, image bool) (string, int) {
+	// Find the bracket closing the text, brackets may nest
+	depth, end := 0, -1
+	for i := 0; i < len(s) && end < 0; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '`':
+			if close := closingBackticks(s, i+runLength(s[i:], '`'), runLength(s[i:], '`')); close > 0 {
+				i = close + runLength(s[close:], '`') - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		}
+	}
+	if end < 0 || end+1 >= len(s) || s[end+1] != '(' {
+		return "", 0
+	}
+	// Parentheses in the destination may nest too
+	closing := -1
+	depth = 0
+	for i := end + 1; i < len(s) && closing < 0; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				closing = i
+			}
+		}
+	}
+	if closing < 0 {
+		return "", 0
+	}
+	text := s[1:end]
+	target := strings.TrimSpace(s[end+2 : closing])
+	n := closing + 1
+
+	dest, title := target, ""
+	if k := strings.IndexAny(target, " \n"); k >= 0 {
+		dest, title = target[:k], strings.TrimSpace(target[k:])
+		switch {
+		case len(title) < 2 || !strings.ContainsRune(`"'`, rune(title[0])) || title[len(title)-1] != title[0]:
+			return "", 0
+		case title[0] == '"':
+			if t, err := strconv.Unquote(title); err == nil {
+				title = t
+				break
+			}
+			fallthrough
+		default:
+			title = title[1 : len(title)-1]
+		}
+	}
+	dest = strings.TrimSuffix(strings.TrimPrefix(dest, "<"), ">")
+
+	titleAttr := ""
+	if title != "" {
+		titleAttr = ` title="` + html.EscapeString(title) + `"`
+	}
+	if image {
+		if !r.safeURL(dest) {
+			return html.EscapeString(text), n
+		}
+		return `<img src="` + html.EscapeString(dest) + `" alt="` + html.EscapeString(unescapeText(text)) + `"` + titleAttr + `>`, n
+	}
+	if !r.safeURL(dest) {
+		return r.inline(text), n
+	}
+	return `<a href="` + html.EscapeString(dest) + `"` + titleAttr + `>` + r.inline(text) + `</a>`, n
+}
+
+// unescapeText drops the backslashes escaping punctuation.
+func unescapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// safeURL reports whether a link to u may be written. Script URLs are only allowed with
+// AllowRawHTML.
+func (r *renderer) safeURL(u string) bool {
+	if r.option.AllowRawHTML {
+		return true
+	}
+	scheme := strings.ToLower(strings.TrimSpace(u))
+	for _, unsafe := range []string{"javascript:", "vbscript:", "data:text/html"} {
+		if strings.HasPrefix(scheme, unsafe) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestToHTML(t *testing.T) {
+	tests := []struct {
+		name   string
+		md     string
+		option *Option
+		want   string
+	}{
+		{"headings", "# One\n\nTwo\n---\n\n### Three ###", nil, "<h1>One</h1>\n<h2>Two</h2>\n<h3>Three</h3>\n"},
+		{"emphasis", "**b** _i_ ***bi*** ~~s~~ snake_case", nil, "<p><strong>b</strong> <em>i</em> <strong><em>bi</em></strong> <del>s</del> snake_case</p>\n"},
+		{"code span", "use ``a`b`` and `<br>`", nil, "<p>use <code>a`b</code> and <code>&lt;br&gt;</code></p>\n"},
+		{"link", `[the \[docs\]](https://go.dev/doc/ "Go docs")`, nil, `<p><a href="https://go.dev/doc/" title="Go docs">the [docs]</a></p>` + "\n"},
+		{"image", `![a cat](/cat.png)`, nil, `<p><img src="/cat.png" alt="a cat"></p>` + "\n"},
+		{"hard break", "one  \ntwo", nil, "<p>one<br>\ntwo</p>\n"},
+		{"fenced code", "```go\nif a < b {\n\n}\n```", nil, "<pre><code class=\"language-go\">if a &lt; b {\n\n}\n</code></pre>\n"},
+		{"blockquote", "> quoted\n> > nested", nil, "<blockquote>\n<p>quoted</p>\n<blockquote>\n<p>nested</p>\n</blockquote>\n</blockquote>\n"},
+		{"hr", "a\n\n---\n\nb", nil, "<p>a</p>\n<hr>\n<p>b</p>\n"},
+		{
+			name: "nested list",
+			md:   "1. one\n   * a\n2. two",
+			want: "<ol>\n<li>one\n<ul>\n<li>a</li>\n</ul></li>\n<li>two</li>\n</ol>\n",
+		},
+		{
+			name: "loose list",
+			md:   "3. one\n\n   more\n4. two",
+			want: "<ol start=\"3\">\n<li>\n<p>one</p>\n<p>more</p>\n</li>\n<li>\n<p>two</p>\n</li>\n</ol>\n",
+		},
+		{
+			name: "table",
+			md:   "| a | b\\|c |\n| :-- | --: |\n| 1 | 2 |",
+			want: "<table>\n<thead>\n<tr><th align=\"left\">a</th><th align=\"right\">b|c</th></tr>\n</thead>\n" +
+				"<tbody>\n<tr><td align=\"left\">1</td><td align=\"right\">2</td></tr>\n</tbody>\n</table>\n",
+		},
+		{"empty item", "- ", nil, "<ul>\n<li></li>\n</ul>\n"},
+		{"empty item without space", "*", nil, "<ul>\n<li></li>\n</ul>\n"},
+		{"empty ordered item", "1. ", nil, "<ol>\n<li></li>\n</ol>\n"},
+		{"empty item before blank line", "- \n", nil, "<ul>\n<li></li>\n</ul>\n"},
+		{"escaped html", `<script>alert(1)</script> [x](javascript:alert(1))`, nil, "<p>&lt;script&gt;alert(1)&lt;/script&gt; x</p>\n"},
+		{"raw html", "<div>\n<b>kept</b>\n</div>\n\n<i>inline</i>", &Option{AllowRawHTML: true}, "<div>\n<b>kept</b>\n</div>\n<p><i>inline</i></p>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToHTML([]byte(tt.md), tt.option)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, string(got))
+			}
+		})
+	}
+}
+
+// structure lists the structural elements of an HTML document in order
+func structure(t *testing.T, s string) []string {
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var tags []string
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "html", "head", "body", "thead", "tbody", "tr":
+			default:
+				tags = append(tags, n.Data)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(doc)
+	return tags
+}
+
+// Test that converting HTML to Markdown and back keeps its structure
+func TestToHTMLRoundTrip(t *testing.T) {
+	input := `<h1>Title</h1>
+<p>Some <strong>bold</strong>, <em>italic</em> and <code>code</code> with <a href="https://go.dev/">a link</a>.</p>
+<p><img src="https://go.dev/cat.png" alt="cat"></p>
+<h2>Lists</h2>
+<ol><li>one<ul><li>a</li><li>b</li></ul></li><li>two</li></ol>
+<blockquote><p>quoted</p></blockquote>
+<pre><code class="language-go">fmt.Println("hi")
+</code></pre>
+<table><tr><th>name</th><th>value</th></tr><tr><td>x</td><td>1</td></tr></table>`
+
+	md, err := ConvertString(input, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	output, err := ToHTML([]byte(md), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := strings.Join(structure(t, input), " ")
+	if got := strings.Join(structure(t, string(output)), " "); got != want {
+		t.Errorf("Expected %q, got %q\nmarkdown:\n%s", want, got, md)
+	}
+}
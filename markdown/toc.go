@@ -0,0 +1,318 @@
+package markdown
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Heading is a heading of a markdown document, as found by Headings.
+type Heading struct {
+	Level int    // 1 to 6
+	Text  string // The text of the heading without markup
+	Slug  string // The anchor GitHub gives the heading, unique in the document
+
+	line int // Index of the line holding the text
+}
+
+// Headings returns the ATX and setext headings of markdown outside of code fences, with
+// the anchors GitHub gives them: a heading repeating the slug of an earlier one gets "-1",
+// "-2" and so on appended.
+func Headings(markdown string) []Heading {
+	headings, _ := scanHeadings(strings.Split(markdown, "\n"))
+	return headings
+}
+
+// Slug returns the anchor GitHub gives a heading of text: it is lowercased, spaces become
+// hyphens, and everything but letters, digits, marks, hyphens and underscores is dropped.
+// Letters of every script are kept, so "Über uns" is "über-uns".
+func Slug(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case r == ' ':
+			b.WriteByte('-')
+		case r == '-' || unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r) || unicode.Is(unicode.Pc, r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// slugger makes slugs unique the way GitHub does, counting how often each was taken.
+type slugger map[string]int
+
+func (s slugger) slug(text string) string {
+	base := Slug(text)
+	slug := base
+	for {
+		if _, taken := s[slug]; !taken {
+			break
+		}
+		s[base]++
+		slug = base + "-" + strconv.Itoa(s[base])
+	}
+	s[slug] = 0
+	return slug
+}
+
+// scanHeadings returns the headings of lines and the indexes of the lines holding a [TOC]
+// placeholder, skipping the front matter and code fences.
+func scanHeadings(lines []string) (headings []Heading, placeholders []int) {
+	slugs := slugger{}
+	start := 0
+	if end := frontMatterEnd(lines); end > 0 {
+		start = end + 1
+	}
+
+	fence := ""
+	for i := start; i < len(lines); i++ {
+		l := strings.TrimRight(lines[i], " \t")
+		if fence != "" {
+			if isClosingFence(l, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if fence = openingFence(l); fence != "" {
+			continue
+		}
+
+		switch {
+		case isTOCPlaceholder(l):
+			placeholders = append(placeholders, i)
+		case isATXHeading(l):
+			level := len(l) - len(strings.TrimLeft(l, "#"))
+			text := plainText(atxText(l[level:]))
+			headings = append(headings, Heading{Level: level, Text: text, Slug: slugs.slug(text), line: i})
+		case i > start && isSetextUnderline(l) && isSetextText(lines[i-1]):
+			level := 1
+			if l[0] == '-' {
+				level = 2
+			}
+			text := plainText(strings.TrimSpace(lines[i-1]))
+			headings = append(headings, Heading{Level: level, Text: text, Slug: slugs.slug(text), line: i - 1})
+		}
+	}
+	return headings, placeholders
+}
+
+// isTOCPlaceholder reports whether l is "[TOC]", escaped or not, in any case.
+func isTOCPlaceholder(l string) bool {
+	l = strings.TrimSpace(l)
+	return strings.EqualFold(l, "[TOC]") || strings.EqualFold(l, `\[TOC\]`)
+}
+
+// atxText returns the text of an ATX heading after its opening sequence, without the
+// optional closing sequence of "#".
+func atxText(s string) string {
+	s = strings.TrimSpace(s)
+	if trimmed := strings.TrimRight(s, "#"); trimmed == "" || strings.HasSuffix(trimmed, " ") || strings.HasSuffix(trimmed, "\t") {
+		s = strings.TrimSpace(trimmed)
+	}
+	return s
+}
+
+// isSetextUnderline reports whether l underlines a setext heading: a run of "=" or "-".
+func isSetextUnderline(l string) bool {
+	return l != "" && (l[0] == '=' || l[0] == '-') && strings.Trim(l, l[:1]) == ""
+}
+
+// isSetextText reports whether l can be the text of a setext heading: a line of a
+// paragraph rather than a blank line or the start of another block.
+func isSetextText(l string) bool {
+	l = strings.TrimRight(l, " \t")
+	if l == "" || strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t") {
+		return false
+	}
+	return !lineStartRegex.MatchString(l) && !strings.HasPrefix(l, "* ") && !strings.HasPrefix(l, "|") &&
+		openingFence(l) == ""
+}
+
+// plainText returns the text markdown renders for the inline markup s: escapes are
+// resolved, code spans keep their content, links their text, and emphasis, images and
+// HTML tags are dropped.
+func plainText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && (unicode.IsPunct(rune(s[i+1])) || unicode.IsSymbol(rune(s[i+1]))):
+			b.WriteByte(s[i+1])
+			i += 2
+		case c == '`':
+			n := len(s[i:]) - len(strings.TrimLeft(s[i:], "`"))
+			run := s[i : i+n]
+			end := strings.Index(s[i+n:], run)
+			if end < 0 {
+				b.WriteString(run)
+				i += n
+				continue
+			}
+			code := s[i+n : i+n+end]
+			if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' {
+				code = code[1 : len(code)-1]
+			}
+			b.WriteString(code)
+			i += 2*n + end
+		case c == '*' || c == '~':
+			i++
+		case c == '_':
+			// Underscores inside words are not emphasis
+			if i > 0 && i+1 < len(s) && isWordByte(s[i-1]) && isWordByte(s[i+1]) {
+				b.WriteByte(c)
+			}
+			i++
+		case c == '[' || c == '!' && i+1 < len(s) && s[i+1] == '[':
+			image := c == '!'
+			if image {
+				i++
+			}
+			text, end, ok := inlineLink(s, i)
+			if !ok {
+				if image {
+					b.WriteByte('!')
+				}
+				b.WriteByte('[')
+				i++
+				continue
+			}
+			if !image {
+				b.WriteString(plainText(text))
+			}
+			i = end
+		case c == '<':
+			end := strings.IndexByte(s[i:], '>')
+			if end < 0 || i+1 >= len(s) || !(s[i+1] == '/' || unicode.IsLetter(rune(s[i+1]))) {
+				b.WriteByte(c)
+				i++
+				continue
+			}
+			i += end + 1
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func isWordByte(c byte) bool {
+	return c >= 0x80 || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+// inlineLink parses the link "[text](destination)" starting at s[start], returning its
+// text and the index after it.
+func inlineLink(s string, start int) (text string, end int, ok bool) {
+	close := matchingBracket(s, start, '[', ']')
+	if close < 0 || close+1 >= len(s) || s[close+1] != '(' {
+		return "", 0, false
+	}
+	paren := matchingBracket(s, close+1, '(', ')')
+	if paren < 0 {
+		return "", 0, false
+	}
+	return s[start+1 : close], paren + 1, true
+}
+
+// matchingBracket returns the index of the bracket closing the one at s[start], skipping
+// escaped brackets, or -1.
+func matchingBracket(s string, start int, open, close byte) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case open:
+			depth++
+		case close:
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// addTableOfContents adds to the markdown of a converted document the table of contents
+// of option.GenerateTOC and the anchors of option.HeadingIDs.
+func addTableOfContents(markdown string, option *Option) string {
+	lines := strings.Split(markdown, "\n")
+	headings, placeholders := scanHeadings(lines)
+
+	if option.HeadingIDs {
+		for _, h := range headings {
+			lines[h.line] = headingWithID(lines[h.line], h)
+		}
+	}
+	if !option.GenerateTOC {
+		return strings.Join(lines, "\n")
+	}
+
+	toc := tableOfContents(headings, option)
+	if len(placeholders) > 0 {
+		for _, i := range placeholders {
+			lines[i] = toc
+		}
+		return strings.Join(lines, "\n")
+	}
+	if toc == "" {
+		return markdown
+	}
+
+	// The table of contents goes after the front matter
+	start := frontMatterEnd(lines) + 1
+	if start > 0 {
+		toc = "\n" + toc
+	}
+	lines = append(lines[:start], append([]string{toc + "\n"}, lines[start:]...)...)
+	return strings.Join(lines, "\n")
+}
+
+// headingWithID returns the heading on line l with an <a id> anchor of its slug before its
+// text.
+func headingWithID(l string, h Heading) string {
+	anchor := fmt.Sprintf(`<a id="%s"></a>`, h.Slug)
+	if !isATXHeading(l) {
+		return anchor + l
+	}
+	level := len(l) - len(strings.TrimLeft(l, "#"))
+	return l[:level] + " " + anchor + strings.TrimLeft(l[level:], " \t")
+}
+
+// tableOfContents returns a nested bullet list linking to headings, indented from the
+// level of the highest one. Headings without text are left out.
+func tableOfContents(headings []Heading, option *Option) string {
+	minLevel := 7
+	for _, h := range headings {
+		if h.Text != "" && h.Level < minLevel {
+			minLevel = h.Level
+		}
+	}
+
+	marker := option.bulletMarker()
+	indent := option.ListIndent
+	if indent <= 0 {
+		indent = 2
+	}
+	if indent < len(marker)+1 {
+		indent = len(marker) + 1
+	}
+
+	var b strings.Builder
+	depth := -1
+	for _, h := range headings {
+		if h.Text == "" {
+			continue
+		}
+		// A level skipped, as from h1 to h3, nests the entry one level deeper only
+		next := h.Level - minLevel
+		if next > depth+1 {
+			next = depth + 1
+		}
+		depth = next
+		fmt.Fprintf(&b, "%s%s [%s](#%s)\n", strings.Repeat(" ", depth*indent), marker, escapeText(h.Text, false), h.Slug)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
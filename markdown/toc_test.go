@@ -0,0 +1,145 @@
+package markdown
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"Hello World", "hello-world"},
+		{"Hello, World!", "hello-world"},
+		{"C++ & Go", "c--go"},
+		{"What's new in v1.2?", "whats-new-in-v12"},
+		{"snake_case_name", "snake_case_name"},
+		{"--flag", "--flag"},
+		{"Emoji 🎉 party", "emoji--party"},
+		{"Über uns", "über-uns"},
+		{"Привет, мир", "привет-мир"},
+		{"日本語の見出し", "日本語の見出し"},
+		{"Café", "café"},
+		{"  Padded  ", "padded"},
+		{"!!!", ""},
+	}
+
+	for _, test := range tests {
+		if result := Slug(test.text); result != test.expected {
+			t.Errorf("%q: Expected %q, got %q", test.text, test.expected, result)
+		}
+	}
+}
+
+func TestHeadings(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		expected []Heading
+	}{
+		{"duplicates", "# Intro\n## Intro\n## Intro", []Heading{
+			{Level: 1, Text: "Intro", Slug: "intro"}, {Level: 2, Text: "Intro", Slug: "intro-1"}, {Level: 2, Text: "Intro", Slug: "intro-2"},
+		}},
+		{"duplicate of a suffix", "# Intro\n# Intro\n# Intro 1", []Heading{
+			{Level: 1, Text: "Intro", Slug: "intro"}, {Level: 1, Text: "Intro", Slug: "intro-1"}, {Level: 1, Text: "Intro 1", Slug: "intro-1-1"},
+		}},
+		{"code span", "## Using `fmt.Println()`", []Heading{
+			{Level: 2, Text: "Using fmt.Println()", Slug: "using-fmtprintln"},
+		}},
+		{"code span with backticks", "## The `` ` `` character", []Heading{
+			{Level: 2, Text: "The ` character", Slug: "the--character"},
+		}},
+		{"link and emphasis", "### [Install](https://example.com/install) *now* or **__later__**", []Heading{
+			{Level: 3, Text: "Install now or later", Slug: "install-now-or-later"},
+		}},
+		{"escapes", `## snake\_case and \[brackets\]`, []Heading{
+			{Level: 2, Text: "snake_case and [brackets]", Slug: "snake_case-and-brackets"},
+		}},
+		{"image and html", `## ![logo](logo.png)<span>Brand</span> name`, []Heading{
+			{Level: 2, Text: "Brand name", Slug: "brand-name"},
+		}},
+		{"closing sequence", "## Closing ##\n# C# #", []Heading{
+			{Level: 2, Text: "Closing", Slug: "closing"}, {Level: 1, Text: "C#", Slug: "c"},
+		}},
+		{"setext", "Title\n=====\n\nSection\n-------", []Heading{
+			{Level: 1, Text: "Title", Slug: "title"}, {Level: 2, Text: "Section", Slug: "section"},
+		}},
+		{"rule", "text\n\n---\n\n* item\n---", nil},
+		{"fences", "```\n# comment\n```\n~~~\nText\n===\n~~~\n# Real", []Heading{
+			{Level: 1, Text: "Real", Slug: "real"},
+		}},
+		{"front matter", "---\ntitle: x\n---\n# Title", []Heading{
+			{Level: 1, Text: "Title", Slug: "title"},
+		}},
+	}
+
+	for _, test := range tests {
+		result := Headings(test.markdown)
+		if len(result) != len(test.expected) {
+			t.Errorf("%s: Expected %d headings, got %+v", test.name, len(test.expected), result)
+			continue
+		}
+		for i, h := range result {
+			want := test.expected[i]
+			if h.Level != want.Level || h.Text != want.Text || h.Slug != want.Slug {
+				t.Errorf("%s: Expected %+v, got %+v", test.name, want, h)
+			}
+		}
+	}
+}
+
+const tocHTML = `<h1>Guide</h1><p>Intro</p><h2>Install</h2><h4>From source</h4><h2>Usage</h2><h2>Usage</h2>`
+
+func TestGenerateTOC(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		option   *Option
+		expected string
+	}{
+		{"prepended", tocHTML, &Option{GenerateTOC: true, Normalize: true},
+			"* [Guide](#guide)\n  * [Install](#install)\n    * [From source](#from-source)\n  * [Usage](#usage)\n  * [Usage](#usage-1)\n\n" +
+				"# Guide\n\nIntro\n\n## Install\n\n#### From source\n\n## Usage\n\n## Usage"},
+		{"placeholder", `<p>Intro</p><p>[TOC]</p><h2>A</h2><h2>B</h2>`, &Option{GenerateTOC: true, BulletListMarker: "-", Normalize: true},
+			"Intro\n\n- [A](#a)\n- [B](#b)\n\n## A\n\n## B"},
+		{"no headings", `<p>Text</p>`, &Option{GenerateTOC: true}, "Text"},
+		{"escaped text", `<h2>*Stars* [and] brackets</h2>`, &Option{GenerateTOC: true, Normalize: true},
+			"* [\\*Stars\\* \\[and\\] brackets](#stars-and-brackets)\n\n## \\*Stars\\* \\[and\\] brackets"},
+		{"heading ids", `<h2>Same</h2><h2>Same</h2>`, &Option{HeadingIDs: true, Normalize: true},
+			"## <a id=\"same\"></a>Same\n\n## <a id=\"same-1\"></a>Same"},
+		{"setext ids", `<h1>Title</h1><h3>Sub</h3>`, &Option{HeadingIDs: true, HeadingStyle: HeadingSetext, Normalize: true},
+			"<a id=\"title\"></a>Title\n=====\n\n### <a id=\"sub\"></a>Sub"},
+		{"front matter", `<h1>Title</h1>`, &Option{GenerateTOC: true, Normalize: true, FrontMatter: map[string]any{"title": "x"}},
+			"---\ntitle: x\n---\n\n* [Title](#title)\n\n# Title"},
+	}
+
+	for _, test := range tests {
+		result, err := ConvertString(test.html, test.option)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("%s: Expected %q, got %q", test.name, test.expected, result)
+		}
+	}
+}
+
+func TestGenerateTOCStream(t *testing.T) {
+	option := &Option{GenerateTOC: true, HeadingIDs: true}
+	expected, err := ConvertString(tocHTML, option)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := Convert(io.MultiReader(strings.NewReader(tocHTML)), &b, option); err != nil {
+		t.Fatal(err)
+	}
+	if result := strings.TrimSpace(b.String()); result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
@@ -0,0 +1,62 @@
+package metrics_test
+
+import (
+	"expvar"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/propro-productions/go-utils/metrics"
+	"github.com/propro-productions/go-utils/search"
+)
+
+// expvarMetrics publishes counters and total durations in milliseconds to an expvar.Map,
+// keyed by name and labels, such as "search.finished{engine=google,status=ok}". A
+// Prometheus adapter would instead add to a CounterVec and observe d.Seconds() with a
+// HistogramVec, using the values of labels as label values.
+type expvarMetrics struct {
+	vars *expvar.Map
+}
+
+func (m expvarMetrics) Count(name string, delta int64, labels ...string) {
+	m.vars.Add(key(name, labels), delta)
+}
+
+func (m expvarMetrics) Timing(name string, d time.Duration, labels ...string) {
+	m.vars.AddFloat(key(name, labels)+".ms", float64(d)/float64(time.Millisecond))
+}
+
+func key(name string, labels []string) string {
+	if len(labels) == 0 {
+		return name
+	}
+	var pairs []string
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+"="+labels[i+1])
+	}
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+var publishOnce sync.Once
+
+func Example_expvar() {
+	m := expvarMetrics{vars: new(expvar.Map).Init()}
+	// Served at /debug/vars by the expvar handler
+	publishOnce.Do(func() { expvar.Publish("goutils", m.vars) })
+
+	// Searches made with these options report to m, and so do the scrapers of
+	// link_preview.WithMetrics and the conversions of markdown.Option.Metrics
+	opt := search.NewOptions(search.WithMetrics(m))
+	fmt.Println(opt.Metrics != nil)
+
+	// Reported the way the packages report a search and a cache hit
+	metrics.Count(m, metrics.SearchFinished, 1, "engine", "google", "status", metrics.StatusOK)
+	metrics.Count(m, metrics.CacheHits, 1, "cache", "search")
+	fmt.Println(m.vars.Get("search.finished{engine=google,status=ok}"))
+	fmt.Println(m.vars.Get("cache.hits{cache=search}"))
+	// Output:
+	// true
+	// 1
+	// 1
+}
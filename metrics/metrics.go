@@ -0,0 +1,80 @@
+// Package metrics defines the hooks the search, link_preview and markdown packages report
+// their work to, so that blocked searches, latencies and cache use can be monitored with
+// any metrics library. See the package example for an adapter.
+package metrics
+
+import "time"
+
+// Metrics receives measurements. labels alternate between names and values, such as
+// "engine", "google". Implementations must be safe for concurrent use and return quickly,
+// being called on the path of every request.
+type Metrics interface {
+	// Count adds delta to the counter name.
+	Count(name string, delta int64, labels ...string)
+
+	// Timing records a duration of name.
+	Timing(name string, d time.Duration, labels ...string)
+}
+
+// Names of the measurements reported.
+const (
+	// SearchStarted counts the search requests made, labeled by engine.
+	SearchStarted = "search.started"
+	// SearchFinished counts the search requests done, labeled by engine and status.
+	SearchFinished = "search.finished"
+	// SearchDuration times the search requests, labeled by engine and status.
+	SearchDuration = "search.duration"
+	// SearchBlocked counts the search requests that failed with search.ErrBlocked,
+	// labeled by engine.
+	SearchBlocked = "search.blocked"
+	// SearchRetries counts the retries of search requests, labeled by engine.
+	SearchRetries = "search.retries"
+
+	// PreviewDuration times the previews fetched, labeled by status.
+	PreviewDuration = "preview.duration"
+	// PreviewBytes counts the bytes of the pages previewed.
+	PreviewBytes = "preview.bytes"
+
+	// MarkdownDuration times the conversions to Markdown, labeled by status.
+	MarkdownDuration = "markdown.duration"
+
+	// CacheHits and CacheMisses count the lookups of the search and preview caches, labeled
+	// by cache, "search" or "preview".
+	CacheHits   = "cache.hits"
+	CacheMisses = "cache.misses"
+)
+
+// Values of the status label.
+const (
+	StatusOK      = "ok"
+	StatusBlocked = "blocked"
+	StatusError   = "error"
+)
+
+// Nop discards every measurement. It is used when no Metrics is set.
+var Nop Metrics = nop{}
+
+type nop struct{}
+
+func (nop) Count(name string, delta int64, labels ...string) {}
+
+func (nop) Timing(name string, d time.Duration, labels ...string) {}
+
+// Count adds delta to the counter name of m. A nil m is ignored and a panic of m is
+// recovered, so that metrics never break the code reporting them.
+func Count(m Metrics, name string, delta int64, labels ...string) {
+	if m == nil {
+		return
+	}
+	defer func() { recover() }()
+	m.Count(name, delta, labels...)
+}
+
+// Timing records the duration d of name with m, like Count.
+func Timing(m Metrics, name string, d time.Duration, labels ...string) {
+	if m == nil {
+		return
+	}
+	defer func() { recover() }()
+	m.Timing(name, d, labels...)
+}
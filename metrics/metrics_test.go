@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type panicMetrics struct{}
+
+func (panicMetrics) Count(name string, delta int64, labels ...string) { panic("count") }
+
+func (panicMetrics) Timing(name string, d time.Duration, labels ...string) { panic("timing") }
+
+func TestPanicRecovered(t *testing.T) {
+	assert.NotPanics(t, func() {
+		Count(panicMetrics{}, SearchStarted, 1, "engine", "google")
+		Timing(panicMetrics{}, SearchDuration, time.Second, "engine", "google")
+	})
+}
+
+func TestNil(t *testing.T) {
+	assert.NotPanics(t, func() {
+		Count(nil, CacheHits, 1)
+		Timing(nil, PreviewDuration, time.Second)
+		Count(Nop, CacheHits, 1)
+	})
+}
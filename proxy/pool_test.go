@@ -0,0 +1,229 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func nextHosts(t *testing.T, p *Pool, n int) []string {
+	t.Helper()
+	var hosts []string
+	for i := 0; i < n; i++ {
+		u, err := p.Next()
+		if err != nil {
+			hosts = append(hosts, err.Error())
+			continue
+		}
+		hosts = append(hosts, u.Host)
+	}
+	return hosts
+}
+
+func TestNewPool(t *testing.T) {
+	p, err := NewPool("http://a:1", "socks5://b:2", "http://a:1")
+	assert.NoError(t, err)
+	assert.Len(t, p.Status(), 2)
+
+	_, err = NewPool()
+	assert.Error(t, err)
+	_, err = NewPool("a:1")
+	assert.Error(t, err)
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	p, _ := NewPool("http://a:1", "http://b:1", "http://c:1")
+
+	assert.Equal(t, []string{"a:1", "b:1", "c:1", "a:1"}, nextHosts(t, p, 4))
+}
+
+func TestPoolLeastRecentlyUsed(t *testing.T) {
+	p, _ := NewPool("http://a:1", "http://b:1", "http://c:1")
+	p.Strategy = LeastRecentlyUsed
+
+	assert.Equal(t, []string{"a:1", "b:1", "c:1"}, nextHosts(t, p, 3))
+	p.Block(&url.URL{Scheme: "http", Host: "a:1"})
+	time.Sleep(time.Millisecond)
+	assert.Equal(t, []string{"b:1", "c:1", "b:1"}, nextHosts(t, p, 3))
+}
+
+func TestPoolFailures(t *testing.T) {
+	p, _ := NewPool("http://a:1", "http://b:1")
+	p.MaxFailures = 2
+	a, _ := url.Parse("http://a:1")
+
+	p.Fail(a)
+	p.Succeed(a)
+	p.Fail(a)
+	assert.True(t, p.Status()[0].Healthy, "failures are only counted in a row")
+
+	p.Fail(a)
+	assert.False(t, p.Status()[0].Healthy)
+	assert.Equal(t, []string{"b:1", "b:1"}, nextHosts(t, p, 2))
+
+	p.Block(&url.URL{Scheme: "http", Host: "b:1"})
+	_, err := p.Next()
+	assert.ErrorIs(t, err, ErrNoHealthyProxy)
+
+	// Proxies that are not in the pool are ignored
+	p.Fail(&url.URL{Scheme: "http", Host: "z:1"})
+	p.Succeed(nil)
+}
+
+func TestPoolCheck(t *testing.T) {
+	p, _ := NewPool("http://a:1", "http://b:1")
+	p.Probe = func(ctx context.Context, proxyURL *url.URL) error {
+		if proxyURL.Host == "a:1" {
+			return nil
+		}
+		return errors.New("connection refused")
+	}
+	p.Block(&url.URL{Scheme: "http", Host: "a:1"})
+	p.Block(&url.URL{Scheme: "http", Host: "b:1"})
+
+	p.Check(context.Background())
+
+	status := p.Status()
+	assert.True(t, status[0].Healthy)
+	assert.Equal(t, 0, status[0].Failures)
+	assert.False(t, status[1].Healthy)
+}
+
+func TestPoolProbesInBackground(t *testing.T) {
+	p, _ := NewPool("http://a:1")
+	p.CheckInterval = 10 * time.Millisecond
+	var probes int32
+	p.Probe = func(ctx context.Context, proxyURL *url.URL) error {
+		atomic.AddInt32(&probes, 1)
+		return nil
+	}
+	p.Block(&url.URL{Scheme: "http", Host: "a:1"})
+
+	_, err := p.Next()
+	assert.ErrorIs(t, err, ErrNoHealthyProxy)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&probes), "the proxy rests for CheckInterval first")
+
+	time.Sleep(20 * time.Millisecond)
+	p.Next()
+	assert.Eventually(t, func() bool {
+		_, err := p.Next()
+		return err == nil
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&probes))
+}
+
+func TestPoolDefaultProbe(t *testing.T) {
+	var requested []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.String())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	p, _ := NewPool(proxy.URL, dead.URL)
+	p.ProbeURL = "http://probe.invalid/generate_204"
+	for _, s := range p.Status() {
+		p.Block(s.URL)
+	}
+
+	p.Check(context.Background())
+
+	status := p.Status()
+	assert.True(t, status[0].Healthy)
+	assert.False(t, status[1].Healthy)
+	assert.Equal(t, []string{"http://probe.invalid/generate_204"}, requested)
+}
+
+func TestPoolTransport(t *testing.T) {
+	var hits int32
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		fmt.Fprint(w, "via proxy ", r.URL.String())
+	}))
+	defer good.Close()
+	blocking := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer blocking.Close()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	p, _ := NewPool(dead.URL, blocking.URL, good.URL)
+	p.MaxFailures = 1
+	client := &http.Client{Transport: p.Transport(nil)}
+
+	_, err := client.Get("http://example.invalid/page")
+	assert.Error(t, err)
+	resp, err := client.Get("http://example.invalid/page")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	resp.Body.Close()
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get("http://example.invalid/page")
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "via proxy http://example.invalid/page", string(body))
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&hits))
+
+	status := p.Status()
+	assert.False(t, status[0].Healthy)
+	assert.False(t, status[1].Healthy)
+	assert.True(t, status[2].Healthy)
+}
+
+func TestPoolConcurrentUse(t *testing.T) {
+	p, _ := NewPool("http://a:1", "http://b:1", "http://c:1", "http://d:1")
+	p.MaxFailures = 5
+	p.CheckInterval = time.Millisecond
+	p.Probe = func(ctx context.Context, proxyURL *url.URL) error { return nil }
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				u, err := p.Next()
+				if err != nil {
+					continue
+				}
+				switch (i + j) % 3 {
+				case 0:
+					p.Fail(u)
+				case 1:
+					p.Block(u)
+				default:
+					p.Succeed(u)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	time.Sleep(2 * time.Millisecond)
+	p.Check(context.Background())
+
+	// Probes started by Next may still be running
+	assert.Eventually(t, func() bool {
+		for _, s := range p.Status() {
+			if !s.Healthy {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,64 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+type proxyKey struct{}
+
+// Transport returns a RoundTripper that sends every request through the next proxy of p and
+// reports how it went: an error or a 407 status is a failure of the proxy, a 429 status
+// blocks it and any other response is a success. base is cloned and left unchanged; nil
+// means http.DefaultTransport.
+//
+// Requests fail with ErrNoHealthyProxy while no proxy is healthy. They are not retried
+// through another proxy.
+func (p *Pool) Transport(base *http.Transport) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		u, _ := req.Context().Value(proxyKey{}).(*url.URL)
+		return u, nil
+	}
+	return &poolTransport{pool: p, transport: transport}
+}
+
+type poolTransport struct {
+	pool      *Pool
+	transport *http.Transport
+}
+
+func (t *poolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	proxyURL, err := t.pool.Next()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	resp, err := t.transport.RoundTrip(req.WithContext(context.WithValue(req.Context(), proxyKey{}, proxyURL)))
+	switch {
+	case err != nil:
+		// A cancelled request says nothing about the proxy
+		if req.Context().Err() == nil {
+			t.pool.Fail(proxyURL)
+		}
+	case resp.StatusCode == http.StatusProxyAuthRequired:
+		t.pool.Fail(proxyURL)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		t.pool.Block(proxyURL)
+	default:
+		t.pool.Succeed(proxyURL)
+	}
+	return resp, err
+}
+
+// CloseIdleConnections closes the idle connections to every proxy.
+func (t *poolTransport) CloseIdleConnections() {
+	t.transport.CloseIdleConnections()
+}
@@ -0,0 +1,158 @@
+// Package robots checks URLs against the robots.txt of their host.
+package robots
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+)
+
+// ErrDisallowed is returned by fetchers that refuse a URL because robots.txt disallows it.
+var ErrDisallowed = errors.New("disallowed by robots.txt")
+
+// DefaultTTL is how long a fetched robots.txt is reused before it is fetched again.
+const DefaultTTL = time.Hour
+
+// maxRobotsSize bounds how much of a robots.txt file is read. Google ignores anything past 500 KiB.
+const maxRobotsSize = 500 << 10
+
+// DefaultChecker is the Checker used by Allowed.
+var DefaultChecker = NewChecker(DefaultTTL)
+
+// Allowed reports whether userAgent may fetch rawURL according to DefaultChecker.
+func Allowed(userAgent, rawURL string) (bool, error) {
+	return DefaultChecker.Allowed(userAgent, rawURL)
+}
+
+// Checker fetches and caches robots.txt per host. It is safe for concurrent use.
+type Checker struct {
+
+	// TTL is how long a robots.txt is cached. Zero means DefaultTTL.
+	TTL time.Duration
+
+	// HTTPClient is used to fetch robots.txt. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+type entry struct {
+	data    *robotstxt.RobotsData
+	expires time.Time
+}
+
+// NewChecker returns a Checker that caches robots.txt for ttl.
+func NewChecker(ttl time.Duration) *Checker {
+	return &Checker{TTL: ttl}
+}
+
+// Allowed reports whether userAgent may fetch rawURL.
+//
+// A robots.txt that is missing or answers with a 4xx status allows everything, while a 5xx
+// status disallows everything until the next attempt. Server errors are not cached.
+func (c *Checker) Allowed(userAgent, rawURL string) (bool, error) {
+	return c.AllowedContext(context.Background(), userAgent, rawURL)
+}
+
+// AllowedContext is like Allowed but fetches robots.txt with ctx.
+func (c *Checker) AllowedContext(ctx context.Context, userAgent, rawURL string) (bool, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false, errors.New("robots: unsupported scheme " + u.Scheme)
+	}
+
+	data, err := c.robots(ctx, u, userAgent)
+	if err != nil {
+		return false, err
+	}
+	return data.TestAgent(u.RequestURI(), userAgent), nil
+}
+
+// Sitemaps returns the sitemap URLs listed in the robots.txt of the host of rawURL.
+func (c *Checker) Sitemaps(ctx context.Context, userAgent, rawURL string) ([]string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.New("robots: unsupported scheme " + u.Scheme)
+	}
+
+	data, err := c.robots(ctx, u, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	return data.Sitemaps, nil
+}
+
+func (c *Checker) robots(ctx context.Context, u *url.URL, userAgent string) (*robotstxt.RobotsData, error) {
+	key := u.Scheme + "://" + u.Host
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(e.expires) {
+		return e.data, nil
+	}
+
+	data, status, err := c.fetch(ctx, key+"/robots.txt", userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	if status < 500 {
+		ttl := c.TTL
+		if ttl <= 0 {
+			ttl = DefaultTTL
+		}
+		c.mu.Lock()
+		if c.entries == nil {
+			c.entries = map[string]entry{}
+		}
+		c.entries[key] = entry{data: data, expires: time.Now().Add(ttl)}
+		c.mu.Unlock()
+	}
+	return data, nil
+}
+
+func (c *Checker) fetch(ctx context.Context, robotsURL, userAgent string) (*robotstxt.RobotsData, int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsSize))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	data, err := robotstxt.FromStatusAndBytes(resp.StatusCode, body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, resp.StatusCode, nil
+}
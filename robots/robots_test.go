@@ -0,0 +1,129 @@
+package robots
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckerAllowed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /private\n\nUser-agent: BadBot\nDisallow: /\n"))
+	}))
+	defer server.Close()
+
+	c := NewChecker(time.Minute)
+
+	tests := []struct {
+		agent string
+		path  string
+		want  bool
+	}{
+		{"GoScraper", "/", true},
+		{"GoScraper", "/public/page?x=1", true},
+		{"GoScraper", "/private", false},
+		{"GoScraper", "/private/page", false},
+		{"BadBot", "/", false},
+	}
+
+	for _, tt := range tests {
+		allowed, err := c.Allowed(tt.agent, server.URL+tt.path)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, allowed, tt.agent+" "+tt.path)
+	}
+}
+
+func TestCheckerMissingRobots(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	allowed, err := NewChecker(time.Minute).Allowed("GoScraper", server.URL+"/anything")
+
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+func TestCheckerServerError(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := NewChecker(time.Minute)
+	for i := 0; i < 2; i++ {
+		allowed, err := c.Allowed("GoScraper", server.URL+"/")
+		assert.NoError(t, err)
+		assert.False(t, allowed)
+	}
+	// Server errors are temporary and must not be cached.
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestCheckerCachesPerHost(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	}))
+	defer server.Close()
+
+	c := NewChecker(time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Allowed("GoScraper", server.URL+"/page")
+		}()
+	}
+	wg.Wait()
+
+	before := atomic.LoadInt32(&requests)
+	allowed, err := c.Allowed("GoScraper", server.URL+"/private")
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, before, atomic.LoadInt32(&requests))
+}
+
+func TestCheckerExpires(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte("User-agent: *\nAllow: /\n"))
+	}))
+	defer server.Close()
+
+	c := NewChecker(time.Nanosecond)
+	c.Allowed("GoScraper", server.URL+"/")
+	time.Sleep(time.Millisecond)
+	c.Allowed("GoScraper", server.URL+"/")
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestCheckerInvalidURL(t *testing.T) {
+	_, err := NewChecker(time.Minute).Allowed("GoScraper", "mailto:someone@example.com")
+
+	assert.Error(t, err)
+}
+
+func TestCheckerSitemaps(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /private\n\nSitemap: https://example.com/sitemap.xml\nSitemap: https://example.com/news.xml\n"))
+	}))
+	defer server.Close()
+
+	sitemaps, err := NewChecker(time.Minute).Sitemaps(context.Background(), "GoScraper", server.URL+"/page")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/sitemap.xml", "https://example.com/news.xml"}, sitemaps)
+}
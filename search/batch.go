@@ -0,0 +1,69 @@
+package search
+
+import (
+	"context"
+	"sync"
+)
+
+// SearchGoogleBatch runs SearchGoogle for every term using at most concurrency parallel workers.
+// All workers share the limiter of opts, so concurrency only bounds the number of requests in flight.
+//
+// The first map holds the results of the terms that succeeded and the second the error of every
+// term that failed, so that callers can retry just the failures. Once ctx is cancelled no new
+// searches are started and the remaining terms are reported with ctx.Err().
+func SearchGoogleBatch(ctx context.Context, terms []string, concurrency int, opts ...SearchOptions) (map[string][]Result, map[string]error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make(map[string][]Result)
+	errs := make(map[string]error)
+	var mu sync.Mutex
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for term := range jobs {
+				r, err := SearchGoogle(ctx, term, opts...)
+
+				mu.Lock()
+				if err != nil {
+					errs[term] = err
+				} else {
+					results[term] = r
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	seen := make(map[string]bool)
+	for _, term := range terms {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		if ctx.Err() == nil {
+			select {
+			case jobs <- term:
+				continue
+			case <-ctx.Done():
+			}
+		}
+
+		mu.Lock()
+		errs[term] = ctx.Err()
+		mu.Unlock()
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, errs
+}
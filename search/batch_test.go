@@ -0,0 +1,56 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchGoogleBatch(t *testing.T) {
+	fixture := serveFixture(t, "google_results.html")
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+
+		if r.URL.Query().Get("q") == "blocked" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fixture(w, r)
+	})
+
+	terms := []string{"go", "rust", "blocked", "zig", "go", "odin"}
+	results, errs := SearchGoogleBatch(context.Background(), terms, 2, SearchOptions{HTTPClient: client})
+
+	assert.Len(t, results, 4)
+	assert.Len(t, results["go"], 3)
+	assert.NotContains(t, results, "blocked")
+	assert.Len(t, errs, 1)
+	assert.ErrorIs(t, errs["blocked"], ErrBlocked)
+	assert.LessOrEqual(t, maxInFlight, 2)
+}
+
+func TestSearchGoogleBatchCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, errs := SearchGoogleBatch(ctx, []string{"go", "rust"}, 4)
+
+	assert.Empty(t, results)
+	assert.Len(t, errs, 2)
+	assert.ErrorIs(t, errs["go"], context.Canceled)
+}
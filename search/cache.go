@@ -0,0 +1,148 @@
+package search
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL is how long results are cached when SearchOptions.CacheTTL is not set.
+const DefaultCacheTTL = time.Hour
+
+// Cache stores parsed results pages. Implementations must be safe for concurrent use.
+//
+// Keys are fingerprints of the query (see SearchOptions.Cache). Callers copy responses
+// before storing and after loading them, so implementations may keep the values as is.
+type Cache interface {
+
+	// Get returns the response stored for key, if it exists and has not expired.
+	Get(key string) (*SearchResponse, bool)
+
+	// Set stores resp for key for the duration of ttl.
+	Set(key string, resp *SearchResponse, ttl time.Duration)
+}
+
+// cacheKey fingerprints a results page request. The term is case and whitespace
+// normalized; everything else that changes the page, such as country, language,
+// start and num, is taken from the canonical search URL.
+func cacheKey(searchTerm string, opt SearchOptions) string {
+	term := strings.ToLower(strings.Join(strings.Fields(searchTerm), " "))
+	sum := sha256.Sum256([]byte(string(EngineGoogle) + "\x00" + getSearchURL(term, opt)))
+	return hex.EncodeToString(sum[:])
+}
+
+// clone returns a deep copy of resp so that cached values are never shared with callers.
+func (resp *SearchResponse) clone() *SearchResponse {
+	c := *resp
+	if resp.Results != nil {
+		c.Results = make([]Result, len(resp.Results))
+		for i, r := range resp.Results {
+			if r.Sitelinks != nil {
+				r.Sitelinks = append([]Sitelink(nil), r.Sitelinks...)
+			}
+			if r.Rating != nil {
+				rating := *r.Rating
+				r.Rating = &rating
+			}
+			c.Results[i] = r
+		}
+	}
+	if resp.RelatedQueries != nil {
+		c.RelatedQueries = append([]string(nil), resp.RelatedQueries...)
+	}
+	if resp.FeaturedSnippet != nil {
+		snippet := *resp.FeaturedSnippet
+		if snippet.List != nil {
+			snippet.List = append([]string(nil), snippet.List...)
+		}
+		if snippet.Table != nil {
+			snippet.Table = make([][]string, len(resp.FeaturedSnippet.Table))
+			for i, row := range resp.FeaturedSnippet.Table {
+				snippet.Table[i] = append([]string(nil), row...)
+			}
+		}
+		c.FeaturedSnippet = &snippet
+	}
+	return &c
+}
+
+// MemoryCache is an in-memory Cache that evicts the least recently used entry once
+// it holds MaxEntries. It is safe for concurrent use.
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	entries    map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key     string
+	resp    *SearchResponse
+	expires time.Time
+}
+
+// NewMemoryCache returns a MemoryCache holding at most maxEntries pages.
+// A maxEntries of 0 means no limit.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+// Get returns the response stored for key, if it exists and has not expired.
+func (c *MemoryCache) Get(key string) (*SearchResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*memoryEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return entry.resp, true
+}
+
+// Set stores resp for key. A ttl of 0 or less means the entry never expires.
+func (c *MemoryCache) Set(key string, resp *SearchResponse, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+
+	if el, ok := c.entries[key]; ok {
+		c.ll.MoveToFront(el)
+		entry := el.Value.(*memoryEntry)
+		entry.resp, entry.expires = resp, expires
+		return
+	}
+
+	c.entries[key] = c.ll.PushFront(&memoryEntry{key: key, resp: resp, expires: expires})
+	if c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		c.remove(c.ll.Back())
+	}
+}
+
+// Len returns the number of cached entries, including expired ones not yet evicted.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *MemoryCache) remove(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.entries, el.Value.(*memoryEntry).key)
+}
@@ -0,0 +1,95 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewMemoryCache(2)
+	c.Set("a", &SearchResponse{TotalResults: 1}, time.Minute)
+	c.Set("b", &SearchResponse{TotalResults: 2}, time.Minute)
+
+	// Touch a so that b is the least recently used.
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+
+	c.Set("c", &SearchResponse{TotalResults: 3}, time.Minute)
+
+	assert.Equal(t, 2, c.Len())
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+	resp, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), resp.TotalResults)
+}
+
+func TestMemoryCacheExpires(t *testing.T) {
+	c := NewMemoryCache(0)
+	c.Set("a", &SearchResponse{}, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestCacheKey(t *testing.T) {
+	base := SearchOptions{CountryCode: "de", LanguageCode: "de", Limit: 10}
+
+	assert.Equal(t, cacheKey("golang  tutorial", base), cacheKey(" Golang tutorial", base))
+	assert.NotEqual(t, cacheKey("golang", base), cacheKey("rust", base))
+
+	for _, opt := range []SearchOptions{
+		{CountryCode: "us", LanguageCode: "de", Limit: 10},
+		{CountryCode: "de", LanguageCode: "en", Limit: 10},
+		{CountryCode: "de", LanguageCode: "de", Limit: 20},
+		{CountryCode: "de", LanguageCode: "de", Limit: 10, Start: 10},
+	} {
+		assert.NotEqual(t, cacheKey("golang", base), cacheKey("golang", opt))
+	}
+}
+
+func TestSearchGoogleCache(t *testing.T) {
+	requests := 0
+	fixture := serveFixture(t, "google_results.html")
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fixture(w, r)
+	})
+	opt := SearchOptions{HTTPClient: client, Cache: NewMemoryCache(10)}
+
+	first, err := SearchGoogle(context.Background(), "golang", opt)
+	assert.NoError(t, err)
+
+	// Modifying the returned results must not affect the cached copy.
+	first[0].Title = "changed"
+	first[0].Sitelinks[0].Title = "changed"
+
+	second, err := SearchGoogle(context.Background(), "golang", opt)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, "The Go Programming Language", second[0].Title)
+	assert.Equal(t, "Get Started", second[0].Sitelinks[0].Title)
+}
+
+func TestSearchGoogleCacheSkipsErrors(t *testing.T) {
+	requests := 0
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	cache := NewMemoryCache(10)
+	opt := SearchOptions{HTTPClient: client, Cache: cache}
+
+	for i := 0; i < 2; i++ {
+		_, err := SearchGoogle(context.Background(), "golang", opt)
+		assert.ErrorIs(t, err, ErrBlocked)
+	}
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, 0, cache.Len())
+}
@@ -0,0 +1,233 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	googleCacheURL      = "https://webcache.googleusercontent.com/search?q=cache:"
+	waybackAvailableURL = "https://archive.org/wayback/available"
+)
+
+// CachedPage is a copy of a page served by a cache instead of the site itself.
+type CachedPage struct {
+
+	// URL is the page that was asked for.
+	URL string
+
+	// CacheURL is where the copy was fetched from.
+	CacheURL string
+
+	// Source is EngineGoogleCache or EngineWayback.
+	Source Engine
+
+	// Timestamp is when the copy was taken, or zero if the source does not tell.
+	Timestamp time.Time
+
+	// HTML is the body of the copy, at most SearchOptions.MaxBodySize bytes of it.
+	HTML string
+}
+
+// CacheFetchOption modifies how FetchCached works.
+type CacheFetchOption func(*cacheFetchOptions)
+
+type cacheFetchOptions struct {
+	search   SearchOptions
+	sources  []Engine
+	snapshot time.Time
+}
+
+// WithCacheSearchOptions makes the requests of FetchCached as configured by opt: its
+// UserAgent, proxies, Timeout, MaxBodySize, retries, Limiter, HTTPClient and Logger are used.
+func WithCacheSearchOptions(opt SearchOptions) CacheFetchOption {
+	return func(o *cacheFetchOptions) {
+		o.search = opt
+	}
+}
+
+// WithCacheSources sets the sources FetchCached tries, in order.
+// Default: EngineGoogleCache, then EngineWayback.
+func WithCacheSources(sources ...Engine) CacheFetchOption {
+	return func(o *cacheFetchOptions) {
+		o.sources = sources
+	}
+}
+
+// WithSnapshotTime makes FetchCached ask the Wayback Machine for the snapshot closest to t
+// instead of the most recent one.
+func WithSnapshotTime(t time.Time) CacheFetchOption {
+	return func(o *cacheFetchOptions) {
+		o.snapshot = t
+	}
+}
+
+// FetchCached returns a cached copy of resultURL, for results whose page is gone or
+// paywalled. Google's cache is tried first and, if it has no copy or is blocked, the
+// closest snapshot of the Wayback Machine.
+//
+// When no source serves a copy the error wraps ErrNoCacheAvailable, joined with the errors
+// of the sources that failed for another reason, such as ErrBlocked.
+func FetchCached(ctx context.Context, resultURL string, opts ...CacheFetchOption) (*CachedPage, error) {
+	o := cacheFetchOptions{sources: []Engine{EngineGoogleCache, EngineWayback}}
+	for _, fn := range opts {
+		fn(&o)
+	}
+	opt := searchOptions([]SearchOptions{o.search})
+
+	errs := []error{fmt.Errorf("%s: %w", resultURL, ErrNoCacheAvailable)}
+	for _, source := range o.sources {
+		var page *CachedPage
+		var err error
+		switch source {
+		case EngineGoogleCache:
+			page, err = fetchGoogleCache(ctx, resultURL, opt)
+		case EngineWayback:
+			page, err = fetchWayback(ctx, resultURL, o.snapshot, opt)
+		default:
+			err = fmt.Errorf("search: unknown cache source %q", source)
+		}
+		if err == nil {
+			return page, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		logger(opt).Debug("cache miss", "source", source, "url", resultURL, "err", err)
+		if !errors.Is(err, ErrNoCacheAvailable) {
+			errs = append(errs, err)
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// googleCacheDateRegex matches the date in the banner of a Google cache page.
+var googleCacheDateRegex = regexp.MustCompile(`appeared on (\d{1,2} \w{3} \d{4} \d{2}:\d{2}:\d{2}) GMT`)
+
+func fetchGoogleCache(ctx context.Context, resultURL string, opt SearchOptions) (*CachedPage, error) {
+	cacheURL := googleCacheURL + url.QueryEscape(resultURL)
+	body, err := fetchCache(ctx, EngineGoogleCache, cacheURL, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &CachedPage{URL: resultURL, CacheURL: cacheURL, Source: EngineGoogleCache, HTML: string(body)}
+	if match := googleCacheDateRegex.FindSubmatch(body); match != nil {
+		page.Timestamp, _ = time.Parse("2 Jan 2006 15:04:05", string(match[1]))
+	}
+	return page, nil
+}
+
+// waybackTimestamp is the layout of the timestamps of the Wayback Machine.
+const waybackTimestamp = "20060102150405"
+
+// waybackSnapshotRegex matches the prefix of a snapshot URL, before which "id_" asks for
+// the page as archived, without the toolbar and rewritten links.
+var waybackSnapshotRegex = regexp.MustCompile(`^https?://web\.archive\.org/web/(\d+)/`)
+
+func fetchWayback(ctx context.Context, resultURL string, snapshot time.Time, opt SearchOptions) (*CachedPage, error) {
+	query := url.Values{"url": {resultURL}}
+	if !snapshot.IsZero() {
+		query.Set("timestamp", snapshot.UTC().Format(waybackTimestamp))
+	}
+	availableURL := waybackAvailableURL + "?" + query.Encode()
+	body, err := fetchCache(ctx, EngineWayback, availableURL, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	var available struct {
+		ArchivedSnapshots struct {
+			Closest *struct {
+				Available bool   `json:"available"`
+				URL       string `json:"url"`
+				Timestamp string `json:"timestamp"`
+				Status    string `json:"status"`
+			} `json:"closest"`
+		} `json:"archived_snapshots"`
+	}
+	if err := json.Unmarshal(body, &available); err != nil {
+		return nil, cacheError(opt, EngineWayback, availableURL, 0, fmt.Errorf("decode availability: %w", err))
+	}
+	closest := available.ArchivedSnapshots.Closest
+	if closest == nil || !closest.Available || closest.URL == "" || (closest.Status != "" && closest.Status != "200") {
+		return nil, cacheError(opt, EngineWayback, availableURL, 0, ErrNoCacheAvailable)
+	}
+
+	snapshotURL := waybackSnapshotRegex.ReplaceAllString(closest.URL, "https://web.archive.org/web/${1}id_/")
+	if body, err = fetchCache(ctx, EngineWayback, snapshotURL, opt); err != nil {
+		return nil, err
+	}
+	page := &CachedPage{URL: resultURL, CacheURL: snapshotURL, Source: EngineWayback, HTML: string(body)}
+	page.Timestamp, _ = time.Parse(waybackTimestamp, closest.Timestamp)
+	return page, nil
+}
+
+// fetchCache requests rawURL from source, retrying as configured by opt, and returns the
+// body of a successful response. A 404 is reported as ErrNoCacheAvailable.
+func fetchCache(ctx context.Context, source Engine, rawURL string, opt SearchOptions) ([]byte, error) {
+	var body []byte
+	err := withRetry(ctx, opt, source, func(attempt SearchOptions) error {
+		var err error
+		body, err = fetchCacheOnce(ctx, source, rawURL, attempt)
+		return err
+	})
+	return body, err
+}
+
+func fetchCacheOnce(ctx context.Context, source Engine, rawURL string, opt SearchOptions) ([]byte, error) {
+	if err := waitLimit(ctx, opt, rawURL); err != nil {
+		return nil, err
+	}
+	client, err := newHTTPClient(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	logger(opt).Debug("cache request", "source", source, "url", rawURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	setHeaders(req, opt)
+
+	resp, err := doRequest(client, req, opt)
+	if err != nil {
+		return nil, cacheError(opt, source, rawURL, 0, err)
+	}
+	defer resp.Body.Close()
+
+	finalURL := resp.Request.URL.String()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, cacheError(opt, source, finalURL, resp.StatusCode, ErrNoCacheAvailable)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		return nil, cacheError(opt, source, finalURL, resp.StatusCode, ErrBlocked)
+	case resp.StatusCode != http.StatusOK:
+		return nil, cacheError(opt, source, finalURL, resp.StatusCode, ErrUnexpectedStatus)
+	}
+
+	body, err := readBody(resp.Body, opt)
+	if err != nil {
+		return nil, cacheError(opt, source, finalURL, resp.StatusCode, err)
+	}
+	if source == EngineGoogleCache && isBlockedPage(resp.Request.URL, body) {
+		return nil, cacheError(opt, source, finalURL, resp.StatusCode, ErrCaptcha)
+	}
+	if strings.TrimSpace(string(body)) == "" {
+		return nil, cacheError(opt, source, finalURL, resp.StatusCode, ErrNoCacheAvailable)
+	}
+	return body, nil
+}
+
+func cacheError(opt SearchOptions, source Engine, rawURL string, statusCode int, err error) *SearchError {
+	return &SearchError{Engine: source, URL: rawURL, StatusCode: statusCode, Profile: opt.profileName(), Err: err}
+}
@@ -0,0 +1,106 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const waybackAvailable = `{"url": "example.com/article", "archived_snapshots": {"closest": {"status": "200", "available": true,
+	"url": "http://web.archive.org/web/20230105120000/https://example.com/article", "timestamp": "20230105120000"}}}`
+
+func TestFetchCachedGoogle(t *testing.T) {
+	client, rt := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/search", r.URL.Path)
+		w.Write([]byte(`<div>This is Google's cache of https://example.com/article. It is a snapshot of the page as it appeared on 3 Jan 2023 10:04:05 GMT.</div><p>Article</p>`))
+	})
+
+	page, err := FetchCached(context.Background(), "https://example.com/article",
+		WithCacheSearchOptions(SearchOptions{HTTPClient: client, UserAgent: "test-agent"}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, EngineGoogleCache, page.Source)
+	assert.Equal(t, "https://example.com/article", page.URL)
+	assert.Contains(t, page.HTML, "<p>Article</p>")
+	assert.Equal(t, time.Date(2023, 1, 3, 10, 4, 5, 0, time.UTC), page.Timestamp)
+	assert.Len(t, rt.requests, 1)
+	assert.Equal(t, "webcache.googleusercontent.com", rt.requests[0].URL.Host)
+	assert.Equal(t, "cache:https://example.com/article", rt.requests[0].URL.Query().Get("q"))
+	assert.Equal(t, "test-agent", rt.requests[0].Header.Get("User-Agent"))
+}
+
+func TestFetchCachedWaybackFallback(t *testing.T) {
+	client, rt := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/search":
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/wayback/available":
+			assert.Equal(t, "https://example.com/article", r.URL.Query().Get("url"))
+			assert.Equal(t, "20230101000000", r.URL.Query().Get("timestamp"))
+			w.Write([]byte(waybackAvailable))
+		case strings.HasPrefix(r.URL.Path, "/web/"):
+			w.Write([]byte(`<p>Archived</p>`))
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+		}
+	})
+
+	page, err := FetchCached(context.Background(), "https://example.com/article",
+		WithCacheSearchOptions(SearchOptions{HTTPClient: client}),
+		WithSnapshotTime(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	assert.NoError(t, err)
+	assert.Equal(t, EngineWayback, page.Source)
+	assert.Equal(t, "<p>Archived</p>", page.HTML)
+	assert.Equal(t, "https://web.archive.org/web/20230105120000id_/https://example.com/article", page.CacheURL)
+	assert.Equal(t, time.Date(2023, 1, 5, 12, 0, 0, 0, time.UTC), page.Timestamp)
+	assert.Len(t, rt.requests, 3)
+}
+
+func TestFetchCachedNoCopy(t *testing.T) {
+	tests := []struct {
+		name        string
+		googleCode  int
+		wantBlocked bool
+	}{
+		{"not cached", http.StatusNotFound, false},
+		{"google blocked", http.StatusTooManyRequests, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/search" {
+					w.WriteHeader(tt.googleCode)
+					return
+				}
+				w.Write([]byte(`{"url": "example.com/gone", "archived_snapshots": {}}`))
+			})
+
+			page, err := FetchCached(context.Background(), "https://example.com/gone",
+				WithCacheSearchOptions(SearchOptions{HTTPClient: client}))
+
+			assert.Nil(t, page)
+			assert.True(t, errors.Is(err, ErrNoCacheAvailable))
+			assert.Equal(t, tt.wantBlocked, errors.Is(err, ErrBlocked))
+		})
+	}
+}
+
+func TestFetchCachedSources(t *testing.T) {
+	client, rt := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	_, err := FetchCached(context.Background(), "https://example.com/article",
+		WithCacheSearchOptions(SearchOptions{HTTPClient: client}), WithCacheSources(EngineWayback))
+
+	assert.True(t, errors.Is(err, ErrNoCacheAvailable))
+	assert.Len(t, rt.requests, 1)
+	assert.Equal(t, "archive.org", rt.requests[0].URL.Host)
+}
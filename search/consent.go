@@ -0,0 +1,155 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ConsentMode is how a search gets past the cookie consent page Google serves to visitors
+// from the EU instead of results.
+type ConsentMode string
+
+const (
+	// ConsentAuto tries ConsentCookies, then ConsentForm.
+	ConsentAuto ConsentMode = "auto"
+
+	// ConsentCookies sets the cookies of a visitor who rejected all optional cookies and
+	// requests the results page again.
+	ConsentCookies ConsentMode = "cookies"
+
+	// ConsentForm submits the form of the consent page rejecting all optional cookies,
+	// which redirects to the results page.
+	ConsentForm ConsentMode = "form"
+
+	// ConsentNever does not try to get past the consent page.
+	ConsentNever ConsentMode = "never"
+)
+
+// consentCookies returns the cookies Google keeps the answer to its consent page in, for
+// all of the Google domain of u. Their values reject all optional cookies. They are set for
+// the domain, like Google sets them, so that an answer of Google replaces them.
+func consentCookies(u *url.URL) []*http.Cookie {
+	var domain string
+	if host := u.Hostname(); strings.HasPrefix(host, "www.") {
+		domain = strings.TrimPrefix(host, "www.")
+	}
+	return []*http.Cookie{
+		{Name: "SOCS", Value: "CAI", Domain: domain, Path: "/"},
+		{Name: "CONSENT", Value: "PENDING+987", Domain: domain, Path: "/"},
+	}
+}
+
+// consentFormRegexp matches the form of the consent page, which posts to the consent host
+// of any Google domain.
+var consentFormRegexp = regexp.MustCompile(`<form[^>]+action="https://consent\.google\.[a-z.]+/save"`)
+
+// resultsPageMarkers are found on results pages, which may hold a consent form in a dialog
+// on top of the results.
+var resultsPageMarkers = [][]byte{
+	[]byte(`id="rso"`),
+	[]byte(`id="search"`),
+}
+
+// isConsentPage reports whether the final request URL or the body belong to Google's
+// consent page rather than to a results page.
+func isConsentPage(u *url.URL, body []byte) bool {
+	if u != nil && strings.HasPrefix(u.Hostname(), "consent.") {
+		return true
+	}
+	if !consentFormRegexp.Match(body) {
+		return false
+	}
+	for _, marker := range resultsPageMarkers {
+		if bytes.Contains(body, marker) {
+			return false
+		}
+	}
+	return true
+}
+
+// passConsent gets past the consent page served from pageURL for req, page being its body,
+// as set by opt.Consent, and returns the body of the results page. It returns
+// ErrConsentRequired when the consent page is still served.
+func passConsent(ctx context.Context, client *http.Client, req *http.Request, pageURL *url.URL, page []byte, opt SearchOptions) ([]byte, error) {
+	mode := opt.Consent
+	if mode == "" {
+		mode = ConsentAuto
+	}
+	logger(opt).Debug("consent page", "engine", EngineGoogle, "url", pageURL.String(), "consent", mode)
+
+	if client.Jar == nil {
+		// The answer must be kept for the requests that follow
+		c := *client
+		c.Jar, _ = cookiejar.New(nil)
+		client = &c
+	}
+
+	if mode == ConsentAuto || mode == ConsentCookies {
+		client.Jar.SetCookies(req.URL, consentCookies(req.URL))
+		body, finalURL, err := doGoogle(client, req.Clone(ctx), opt)
+		if err != nil {
+			return nil, err
+		}
+		if !isConsentPage(finalURL, body) {
+			return body, nil
+		}
+		pageURL, page = finalURL, body
+	}
+
+	if mode == ConsentAuto || mode == ConsentForm {
+		if formReq := consentFormRequest(ctx, pageURL, page, opt); formReq != nil {
+			body, finalURL, err := doGoogle(client, formReq, opt)
+			if err != nil {
+				return nil, err
+			}
+			if !isConsentPage(finalURL, body) {
+				return body, nil
+			}
+			pageURL = finalURL
+		}
+	}
+
+	return nil, googleError(opt, pageURL.String(), http.StatusOK, ErrConsentRequired)
+}
+
+// consentFormRequest returns the request submitting the form of the consent page that
+// rejects all optional cookies, or the first form when none is recognized as such. It
+// returns nil when the page has no consent form.
+func consentFormRequest(ctx context.Context, pageURL *url.URL, page []byte, opt SearchOptions) *http.Request {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+	if err != nil {
+		return nil
+	}
+	forms := doc.Find(`form[action*="consent."]`)
+	if forms.Length() == 0 {
+		return nil
+	}
+	form := forms.First()
+	if reject := forms.Has(`input[name="set_eom"][value="true"]`); reject.Length() > 0 {
+		form = reject.First()
+	}
+
+	action, err := pageURL.Parse(form.AttrOr("action", ""))
+	if err != nil {
+		return nil
+	}
+	values := url.Values{}
+	form.Find("input[name]").Each(func(i int, input *goquery.Selection) {
+		values.Add(input.AttrOr("name", ""), input.AttrOr("value", ""))
+	})
+
+	req, err := http.NewRequestWithContext(ctx, "POST", action.String(), strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil
+	}
+	setHeaders(req, opt)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
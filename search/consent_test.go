@@ -0,0 +1,146 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsConsentPage(t *testing.T) {
+	searchURL, _ := url.Parse("https://www.google.com/search?q=golang")
+	consentURL, _ := url.Parse("https://consent.google.com/ml?continue=https://www.google.com/search?q%3Dgolang")
+
+	for _, name := range []string{"google_consent.html", "google_consent_de.html"} {
+		body, err := os.ReadFile(filepath.Join("testdata", name))
+		assert.NoError(t, err)
+
+		assert.True(t, isConsentPage(searchURL, body), name)
+		assert.True(t, isConsentPage(consentURL, body), name)
+	}
+
+	for _, name := range []string{"google_results.html", "google_results_de.html", "google_news.html", "google_images.html", "google_scholar_captcha.html"} {
+		body, err := os.ReadFile(filepath.Join("testdata", name))
+		assert.NoError(t, err)
+
+		assert.False(t, isConsentPage(searchURL, body), name)
+	}
+
+	// A consent dialog on top of the results does not hide them
+	assert.False(t, isConsentPage(searchURL, []byte(`<div id="search"></div><form action="https://consent.google.com/save" method="POST"></form>`)))
+	assert.True(t, isConsentPage(consentURL, nil))
+}
+
+// consentServer serves results to requests that send the cookie named cookie with value,
+// and redirects the others to the consent page. Submitting the consent form sets
+// SOCS=answered for all of google.com.
+func consentServer(t *testing.T, cookie, value string) http.HandlerFunc {
+	results := serveFixture(t, "google_results.html")
+	consent := serveFixture(t, "google_consent.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Host == "consent.google.com" && r.URL.Path == "/save":
+			if r.Method != "POST" || r.PostFormValue("set_eom") != "true" {
+				http.Error(w, "bad form", http.StatusBadRequest)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "SOCS", Value: "answered", Domain: "google.com", Path: "/"})
+			http.Redirect(w, r, r.PostFormValue("continue"), http.StatusSeeOther)
+		case r.Host == "consent.google.com":
+			consent(w, r)
+		default:
+			if c, err := r.Cookie(cookie); err == nil && c.Value == value {
+				results(w, r)
+				return
+			}
+			http.Redirect(w, r, "https://consent.google.com/ml?continue="+url.QueryEscape(r.URL.String()), http.StatusFound)
+		}
+	}
+}
+
+func TestSearchGoogleConsentCookies(t *testing.T) {
+	client, rt := newTestClient(t, consentServer(t, "SOCS", "CAI"))
+
+	results, err := SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client})
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	if assert.Len(t, rt.requests, 3) {
+		assert.Equal(t, "consent.google.com", rt.requests[1].URL.Host)
+		assert.Equal(t, "/search", rt.requests[2].URL.Path)
+	}
+}
+
+func TestSearchGoogleConsentForm(t *testing.T) {
+	client, rt := newTestClient(t, consentServer(t, "SOCS", "answered"))
+	jar, _ := cookiejar.New(nil)
+
+	results, err := SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client, Consent: ConsentForm, CookieJar: jar})
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	var methods []string
+	for _, req := range rt.requests {
+		methods = append(methods, req.Method+" "+req.URL.Host+req.URL.Path)
+	}
+	assert.Equal(t, []string{
+		"GET www.google.com/search",
+		"GET consent.google.com/ml",
+		"POST consent.google.com/save",
+		"GET www.google.com/search",
+	}, methods)
+
+	// The answer is kept in the jar for the next searches
+	_, err = SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client, Consent: ConsentNever, CookieJar: jar})
+
+	assert.NoError(t, err)
+	assert.Len(t, rt.requests, 5)
+}
+
+func TestSearchGoogleConsentAuto(t *testing.T) {
+	// The cookies are not enough, the form is submitted
+	client, rt := newTestClient(t, consentServer(t, "SOCS", "answered"))
+
+	results, err := SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client})
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	var methods []string
+	for _, req := range rt.requests {
+		methods = append(methods, req.Method+" "+req.URL.Host+req.URL.Path)
+	}
+	assert.Equal(t, []string{
+		"GET www.google.com/search",
+		"GET consent.google.com/ml",
+		"GET www.google.com/search",
+		"GET consent.google.com/ml",
+		"POST consent.google.com/save",
+		"GET www.google.com/search",
+	}, methods)
+}
+
+func TestSearchGoogleConsentRequired(t *testing.T) {
+	client, rt := newTestClient(t, consentServer(t, "SOCS", "never"))
+
+	_, err := SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client, Consent: ConsentCookies, MaxRetries: 2})
+
+	assert.ErrorIs(t, err, ErrConsentRequired)
+	assert.NotErrorIs(t, err, ErrBlocked)
+	assert.NotErrorIs(t, err, ErrNoResults)
+	// Not retried
+	assert.Len(t, rt.requests, 4)
+}
+
+func TestSearchGoogleConsentNever(t *testing.T) {
+	client, rt := newTestClient(t, serveFixture(t, "google_consent.html"))
+
+	_, err := SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client, Consent: ConsentNever})
+
+	assert.ErrorIs(t, err, ErrConsentRequired)
+	assert.Len(t, rt.requests, 1)
+}
@@ -0,0 +1,322 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const customSearchBase = "https://www.googleapis.com/customsearch/v1?"
+
+const (
+	// customSearchPageSize is the largest num the Custom Search JSON API accepts.
+	customSearchPageSize = 10
+
+	// customSearchMaxResults is how deep the Custom Search JSON API pages: start+num may not
+	// exceed it.
+	customSearchMaxResults = 100
+)
+
+// CustomSearchProvider searches with Google's official Custom Search JSON API, which needs an
+// API key and a Programmable Search Engine ID and is billed per request beyond a free daily
+// quota. It is a Searcher, so it can also be added to Engines.
+//
+// See: https://developers.google.com/custom-search/v1/overview
+type CustomSearchProvider struct {
+
+	// APIKey is the Google Cloud API key, sent as key.
+	APIKey string
+
+	// CX is the ID of the Programmable Search Engine to query.
+	CX string
+}
+
+// Search returns a list of search results from the Custom Search JSON API.
+func (p *CustomSearchProvider) Search(ctx context.Context, searchTerm string, opts ...SearchOptions) ([]Result, error) {
+	resp, err := p.SearchFull(ctx, searchTerm, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// SearchFull returns the results of the Custom Search JSON API with the result count and
+// search time it reports.
+//
+// Limit is sent as num and Start as start. The API returns at most 10 results per request
+// and 100 per query, so a larger Limit costs one request for every 10 results. LanguageCode
+// is sent as lr, unless Lr is set, and Gl, DateRestrict, SafeSearch, NoFilter and
+// ExtraParams are honored. CountryCode and the browser options are ignored. A used up quota
+// is reported as ErrQuotaExceeded.
+func (p *CustomSearchProvider) SearchFull(ctx context.Context, searchTerm string, opts ...SearchOptions) (*SearchResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	opt := searchOptions(opts)
+	if err := validateOptions(opt); err != nil {
+		return nil, err
+	}
+	if p.APIKey == "" || p.CX == "" {
+		return nil, errors.New("custom search: APIKey and CX are required")
+	}
+
+	var first *SearchResponse
+	// exhausted is set once a page shows there are no more results, to not pay for an empty one
+	exhausted := false
+	fetch := func(page SearchOptions) ([]Result, error) {
+		if exhausted || page.Start >= customSearchMaxResults {
+			exhausted = true
+			return nil, nil
+		}
+		resp, err := p.searchPage(ctx, searchTerm, page)
+		if err != nil {
+			return nil, err
+		}
+		if first == nil {
+			first = resp
+		}
+		exhausted = len(resp.Results) < customSearchNum(page) ||
+			int64(page.Start+len(resp.Results)) >= resp.TotalResults
+		return resp.Results, nil
+	}
+
+	var results []Result
+	var err error
+	if opt.Limit <= 0 {
+		results, err = fetch(opt)
+	} else {
+		results, err = collectPages(opt, opt.OverLimit || opt.Dedupe, fetch)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if first == nil {
+		first = &SearchResponse{Engine: EngineCustomSearch}
+	}
+	first.EndOfResults = exhausted
+	first.Results, first.LanguageFiltered = filterLanguage(filterFreshness(cleanResults(results, opt), opt.MinFreshness, time.Now()), opt)
+	return first, nil
+}
+
+// searchPage makes a single request to the API, retrying as configured by opt. Results are
+// ranked from opt.Start+1.
+func (p *CustomSearchProvider) searchPage(ctx context.Context, searchTerm string, opt SearchOptions) (*SearchResponse, error) {
+	searchURL := getCustomSearchURL(searchTerm, p.CX, opt)
+
+	var resp *SearchResponse
+	err := withRetry(ctx, opt, EngineCustomSearch, func(attempt SearchOptions) error {
+		var err error
+		resp, err = p.searchPageOnce(ctx, searchURL, attempt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range resp.Results {
+		resp.Results[i].Rank += opt.Start
+	}
+	return resp, nil
+}
+
+func (p *CustomSearchProvider) searchPageOnce(ctx context.Context, searchURL string, opt SearchOptions) (*SearchResponse, error) {
+	if err := waitLimit(ctx, opt, searchURL); err != nil {
+		return nil, err
+	}
+
+	client, err := newHTTPClient(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	// The key is left out of searchURL so that it is neither logged nor part of errors
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL+"&key="+url.QueryEscape(p.APIKey), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	logger(opt).Debug("search request", "engine", EngineCustomSearch, "url", searchURL)
+	httpResp, err := doRequest(client, req, opt)
+	if err != nil {
+		// The transport error quotes the URL requested, key included
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, customSearchError(opt, searchURL, 0, err)
+	}
+	defer httpResp.Body.Close()
+	logger(opt).Debug("search response", "engine", EngineCustomSearch, "url", searchURL, "status", httpResp.StatusCode)
+
+	body, err := readBody(httpResp.Body, opt)
+	if err != nil {
+		return nil, customSearchError(opt, searchURL, httpResp.StatusCode, err)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, customSearchError(opt, searchURL, httpResp.StatusCode, parseCustomSearchError(httpResp.StatusCode, body))
+	}
+
+	resp, err := parseCustomSearch(body)
+	if err != nil {
+		return nil, customSearchError(opt, searchURL, httpResp.StatusCode, err)
+	}
+	logger(opt).Debug("parsed results", "engine", EngineCustomSearch, "url", searchURL, "results", len(resp.Results))
+	return resp, nil
+}
+
+func customSearchError(opt SearchOptions, searchURL string, statusCode int, err error) *SearchError {
+	return &SearchError{Engine: EngineCustomSearch, URL: searchURL, StatusCode: statusCode, Err: err}
+}
+
+// getCustomSearchURL returns the API URL for searchTerm, without the API key.
+func getCustomSearchURL(searchTerm, cx string, opts SearchOptions) string {
+	params := url.Values{}
+	params.Set("cx", cx)
+	params.Set("q", searchTerm)
+	// start is 1-based
+	params.Set("start", strconv.Itoa(opts.Start+1))
+	params.Set("num", strconv.Itoa(customSearchNum(opts)))
+	if opts.Lr != "" {
+		params.Set("lr", languageRestrict(opts.Lr))
+	} else if opts.LanguageCode != "" {
+		params.Set("lr", languageRestrict(opts.LanguageCode))
+	}
+	if opts.Gl != "" {
+		params.Set("gl", strings.ToLower(opts.Gl))
+	}
+	if opts.DateRestrict != "" {
+		// The API wants a count after the unit
+		restrict := opts.DateRestrict
+		if len(restrict) == 1 {
+			restrict += "1"
+		}
+		params.Set("dateRestrict", restrict)
+	}
+	switch opts.SafeSearch {
+	case SafeSearchOff:
+		params.Set("safe", "off")
+	case SafeSearchModerate, SafeSearchStrict:
+		// The API knows no moderate level
+		params.Set("safe", "active")
+	}
+	if opts.NoFilter {
+		params.Set("filter", "0")
+	}
+	for key, value := range opts.ExtraParams {
+		params.Set(key, value)
+	}
+	return customSearchBase + params.Encode()
+}
+
+// customSearchNum returns how many results to request for the page of opts.
+func customSearchNum(opts SearchOptions) int {
+	num := opts.Limit
+	if num <= 0 || num > customSearchPageSize {
+		num = customSearchPageSize
+	}
+	if rest := customSearchMaxResults - opts.Start; num > rest {
+		num = rest
+	}
+	return num
+}
+
+type customSearchResponse struct {
+	SearchInformation struct {
+		SearchTime   float64 `json:"searchTime"`
+		TotalResults string  `json:"totalResults"`
+	} `json:"searchInformation"`
+	Items []struct {
+		Title        string `json:"title"`
+		Link         string `json:"link"`
+		Snippet      string `json:"snippet"`
+		FormattedURL string `json:"formattedUrl"`
+	} `json:"items"`
+}
+
+// parseCustomSearch converts an API response to the results of a Google results page.
+// A response without items is a search without results.
+func parseCustomSearch(body []byte) (*SearchResponse, error) {
+	var data customSearchResponse
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("custom search response: %w", err)
+	}
+
+	resp := &SearchResponse{Engine: EngineCustomSearch, SearchTime: data.SearchInformation.SearchTime}
+	resp.TotalResults, _ = strconv.ParseInt(data.SearchInformation.TotalResults, 10, 64)
+	now := time.Now()
+	for _, item := range data.Items {
+		if item.Link == "" {
+			continue
+		}
+		// Snippets are broken into lines
+		publishedAt, description := parseSnippetDate(strings.Join(strings.Fields(item.Snippet), " "), now)
+		resp.Results = append(resp.Results, Result{
+			Rank:        len(resp.Results) + 1,
+			URL:         item.Link,
+			Title:       item.Title,
+			Description: description,
+			Breadcrumb:  item.FormattedURL,
+			PublishedAt: publishedAt,
+		})
+	}
+	return resp, nil
+}
+
+// parseCustomSearchError returns the error of an API response with the given status:
+// ErrQuotaExceeded for a used up quota and ErrUnexpectedStatus otherwise, with the message
+// of the API if there is one.
+func parseCustomSearchError(statusCode int, body []byte) error {
+	var data struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+			Errors  []struct {
+				Reason string `json:"reason"`
+			} `json:"errors"`
+		} `json:"error"`
+	}
+	json.Unmarshal(body, &data)
+
+	err := ErrUnexpectedStatus
+	if statusCode == http.StatusTooManyRequests || data.Error.Status == "RESOURCE_EXHAUSTED" {
+		err = ErrQuotaExceeded
+	}
+	for _, e := range data.Error.Errors {
+		switch e.Reason {
+		case "dailyLimitExceeded", "rateLimitExceeded", "userRateLimitExceeded", "quotaExceeded":
+			err = ErrQuotaExceeded
+		}
+	}
+	if data.Error.Message != "" {
+		return fmt.Errorf("%w: %s", err, data.Error.Message)
+	}
+	return err
+}
+
+// SearchWithFallback searches Google like SearchGoogleFull and, only if Google blocks the
+// scraper with ErrBlocked or ErrCaptcha, repeats the search with opt.CustomSearch, so that
+// the paid API is used no more than needed. SearchResponse.Engine tells which of them
+// produced the results.
+//
+// Without CustomSearch the error of Google is returned as is. If the API fails too, its
+// error is returned, which is ErrQuotaExceeded once the quota is used up.
+func SearchWithFallback(ctx context.Context, searchTerm string, opts ...SearchOptions) (*SearchResponse, error) {
+	resp, err := SearchGoogleFull(ctx, searchTerm, opts...)
+	if err == nil || !errors.Is(err, ErrBlocked) {
+		return resp, err
+	}
+
+	opt := searchOptions(opts)
+	if opt.CustomSearch == nil {
+		return nil, err
+	}
+	logger(opt).Debug("falling back", "engine", EngineCustomSearch, "err", err)
+	return opt.CustomSearch.SearchFull(ctx, searchTerm, opt)
+}
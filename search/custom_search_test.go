@@ -0,0 +1,182 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// serveCustomSearch answers API requests with total results, served num at a time from start.
+func serveCustomSearch(total int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		num, _ := strconv.Atoi(r.URL.Query().Get("num"))
+		if num == 0 {
+			num = 10
+		}
+		var items []string
+		for i := start; i < start+num && i <= total; i++ {
+			items = append(items, fmt.Sprintf(`{"title": "Result %d", "link": "https://example.com/%d", "snippet": "Line one\nline two", "formattedUrl": "https://example.com/%d"}`, i, i, i))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"searchInformation": {"searchTime": 0.25, "totalResults": "%d"}, "items": [%s]}`, total, strings.Join(items, ","))
+	}
+}
+
+func TestCustomSearchRequest(t *testing.T) {
+	client, rt := newTestClient(t, serveCustomSearch(3))
+	provider := &CustomSearchProvider{APIKey: "secret", CX: "engine"}
+
+	resp, err := provider.SearchFull(context.Background(), "golang", SearchOptions{
+		HTTPClient:   client,
+		Limit:        5,
+		LanguageCode: "de",
+		Gl:           "DE",
+		DateRestrict: "w",
+		SafeSearch:   SafeSearchModerate,
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, rt.requests, 1)
+	query := rt.requests[0].URL.Query()
+	assert.Equal(t, "www.googleapis.com", rt.requests[0].URL.Host)
+	assert.Equal(t, "/customsearch/v1", rt.requests[0].URL.Path)
+	assert.Equal(t, "secret", query.Get("key"))
+	assert.Equal(t, "engine", query.Get("cx"))
+	assert.Equal(t, "golang", query.Get("q"))
+	assert.Equal(t, "5", query.Get("num"))
+	assert.Equal(t, "1", query.Get("start"))
+	assert.Equal(t, "lang_de", query.Get("lr"))
+	assert.Equal(t, "de", query.Get("gl"))
+	assert.Equal(t, "w1", query.Get("dateRestrict"))
+	assert.Equal(t, "active", query.Get("safe"))
+
+	assert.Equal(t, EngineCustomSearch, resp.Engine)
+	assert.Equal(t, int64(3), resp.TotalResults)
+	assert.Equal(t, 0.25, resp.SearchTime)
+	assert.Equal(t, []Result{
+		{Rank: 1, URL: "https://example.com/1", Title: "Result 1", Description: "Line one line two", Breadcrumb: "https://example.com/1"},
+		{Rank: 2, URL: "https://example.com/2", Title: "Result 2", Description: "Line one line two", Breadcrumb: "https://example.com/2"},
+		{Rank: 3, URL: "https://example.com/3", Title: "Result 3", Description: "Line one line two", Breadcrumb: "https://example.com/3"},
+	}, resp.Results)
+}
+
+func TestCustomSearchPaginates(t *testing.T) {
+	client, rt := newTestClient(t, serveCustomSearch(200))
+	provider := &CustomSearchProvider{APIKey: "secret", CX: "engine"}
+
+	results, err := provider.Search(context.Background(), "golang", SearchOptions{HTTPClient: client, Limit: 25, Start: 80})
+
+	assert.NoError(t, err)
+	// The API stops at the 100th result
+	assert.Len(t, results, 20)
+	var pages []string
+	for _, req := range rt.requests {
+		pages = append(pages, req.URL.Query().Get("start")+"+"+req.URL.Query().Get("num"))
+	}
+	assert.Equal(t, []string{"81+10", "91+10"}, pages)
+	assert.Equal(t, 81, results[0].Rank)
+	assert.Equal(t, 100, results[19].Rank)
+}
+
+func TestCustomSearchQuotaExceeded(t *testing.T) {
+	client, rt := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error": {"code": 429, "message": "Quota exceeded for quota metric 'Queries'.", "errors": [{"reason": "rateLimitExceeded"}], "status": "RESOURCE_EXHAUSTED"}}`))
+	})
+	provider := &CustomSearchProvider{APIKey: "secret", CX: "engine"}
+
+	_, err := provider.Search(context.Background(), "golang", SearchOptions{HTTPClient: client, MaxRetries: 2})
+
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.False(t, errors.Is(err, ErrBlocked))
+	assert.Len(t, rt.requests, 1, "a used up quota is not retried")
+	assert.Contains(t, err.Error(), "Quota exceeded for quota metric")
+	assert.NotContains(t, err.Error(), "secret")
+}
+
+func TestCustomSearchForbidden(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error": {"code": 403, "message": "Daily Limit Exceeded", "errors": [{"reason": "dailyLimitExceeded"}]}}`))
+	})
+	provider := &CustomSearchProvider{APIKey: "secret", CX: "engine"}
+
+	_, err := provider.Search(context.Background(), "golang", SearchOptions{HTTPClient: client})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	client, _ = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": {"code": 400, "message": "Request contains an invalid argument.", "status": "INVALID_ARGUMENT"}}`))
+	})
+	_, err = provider.Search(context.Background(), "golang", SearchOptions{HTTPClient: client})
+	assert.ErrorIs(t, err, ErrUnexpectedStatus)
+	assert.False(t, errors.Is(err, ErrQuotaExceeded))
+}
+
+func TestCustomSearchRequiresCredentials(t *testing.T) {
+	_, err := (&CustomSearchProvider{APIKey: "secret"}).Search(context.Background(), "golang")
+	assert.Error(t, err)
+}
+
+func TestSearchWithFallback(t *testing.T) {
+	client, rt := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/customsearch/v1" {
+			serveCustomSearch(2)(w, r)
+			return
+		}
+		w.Write([]byte(`<form id="captcha-form"></form>`))
+	})
+	opt := SearchOptions{HTTPClient: client, CustomSearch: &CustomSearchProvider{APIKey: "secret", CX: "engine"}}
+
+	resp, err := SearchWithFallback(context.Background(), "golang", opt)
+
+	assert.NoError(t, err)
+	assert.Equal(t, EngineCustomSearch, resp.Engine)
+	assert.Len(t, resp.Results, 2)
+	assert.Len(t, rt.requests, 2)
+	assert.Equal(t, "www.google.com", rt.requests[0].URL.Host)
+	assert.Equal(t, "www.googleapis.com", rt.requests[1].URL.Host)
+}
+
+func TestSearchWithFallbackPrefersScraping(t *testing.T) {
+	results := serveFixture(t, "google_results.html")
+	client, rt := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/customsearch/v1" {
+			t.Error("The API must not be used when scraping works")
+		}
+		results(w, r)
+	})
+	opt := SearchOptions{HTTPClient: client, CustomSearch: &CustomSearchProvider{APIKey: "secret", CX: "engine"}}
+
+	resp, err := SearchWithFallback(context.Background(), "golang", opt)
+
+	assert.NoError(t, err)
+	assert.Equal(t, EngineGoogle, resp.Engine)
+	assert.Len(t, resp.Results, 3)
+	assert.Len(t, rt.requests, 1)
+}
+
+func TestSearchWithFallbackOnlyWhenBlocked(t *testing.T) {
+	client, rt := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	opt := SearchOptions{HTTPClient: client, CustomSearch: &CustomSearchProvider{APIKey: "secret", CX: "engine"}}
+
+	_, err := SearchWithFallback(context.Background(), "golang", opt)
+
+	assert.ErrorIs(t, err, ErrUnexpectedStatus)
+	assert.Len(t, rt.requests, 1)
+
+	client, _ = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	_, err = SearchWithFallback(context.Background(), "golang", SearchOptions{HTTPClient: client})
+	assert.ErrorIs(t, err, ErrBlocked)
+}
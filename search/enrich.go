@@ -0,0 +1,100 @@
+package search
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/propro-productions/go-utils/link_preview"
+)
+
+// EnrichTimeout bounds the preview of a single result in EnrichResults.
+var EnrichTimeout = 10 * time.Second
+
+// enrichMaxRedirect is how many redirects EnrichResults follows for a result.
+const enrichMaxRedirect = 5
+
+// EnrichedResult is a search result with data from a preview of its page.
+type EnrichedResult struct {
+	Result
+
+	// ImageURL is the og:image of the page, or its best fallback.
+	ImageURL     string `json:"image_url,omitempty"`
+	SiteName     string `json:"site_name,omitempty"`
+	CanonicalURL string `json:"canonical_url,omitempty"`
+
+	// Err is the error the preview failed with. The other fields are then those of the
+	// plain Result.
+	Err error `json:"-"`
+}
+
+// EnrichResults previews the page of every result, at most concurrency at a time, and
+// returns the results in the same order with the image, site name and canonical URL of
+// their page. A result without a description gets the one of its page.
+//
+// A failed preview sets the Err of its result and doesn't affect the others. Once ctx is
+// cancelled no new previews are started and the remaining results get ctx.Err().
+func EnrichResults(ctx context.Context, results []Result, concurrency int) []EnrichedResult {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	enriched := make([]EnrichedResult, len(results))
+	for i, r := range results {
+		enriched[i].Result = r
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				enrich(ctx, &enriched[i])
+			}
+		}()
+	}
+
+	for i := range enriched {
+		if ctx.Err() == nil {
+			select {
+			case jobs <- i:
+				continue
+			case <-ctx.Done():
+			}
+		}
+		enriched[i].Err = ctx.Err()
+	}
+	close(jobs)
+	wg.Wait()
+
+	return enriched
+}
+
+func enrich(ctx context.Context, r *EnrichedResult) {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		r.Err = err
+		return
+	}
+
+	scraper := &link_preview.Scraper{Url: u, MaxRedirect: enrichMaxRedirect, Timeout: EnrichTimeout}
+	doc, err := scraper.GetLinkPreviewItemsContext(ctx)
+	if err != nil {
+		r.Err = err
+		return
+	}
+
+	preview := doc.Metadata
+	r.ImageURL = preview.Image
+	r.SiteName = preview.SiteName
+	r.CanonicalURL = preview.CanonicalURL
+	if r.Description == "" {
+		r.Description = preview.Description
+	}
+}
@@ -0,0 +1,71 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/propro-productions/go-utils/link_preview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnrichResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/article":
+			fmt.Fprint(w, `<html><head>
+<meta property="og:image" content="/cover.png">
+<meta property="og:site_name" content="Example">
+<meta property="og:description" content="From the page">
+<link rel="canonical" href="/article?canonical=1">
+</head><body></body></html>`)
+		case "/file":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			fmt.Fprint(w, "binary")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	results := []Result{
+		{Rank: 1, URL: server.URL + "/article", Title: "Article", Description: "From the SERP"},
+		{Rank: 2, URL: server.URL + "/file", Title: "File"},
+		{Rank: 3, URL: server.URL + "/article", Title: "Article again"},
+	}
+
+	enriched := EnrichResults(context.Background(), results, 2)
+
+	assert.Len(t, enriched, 3)
+
+	assert.NoError(t, enriched[0].Err)
+	assert.Equal(t, results[0], enriched[0].Result)
+	assert.Equal(t, server.URL+"/cover.png", enriched[0].ImageURL)
+	assert.Equal(t, "Example", enriched[0].SiteName)
+	assert.Equal(t, server.URL+"/article?canonical=1", enriched[0].CanonicalURL)
+
+	assert.ErrorIs(t, enriched[1].Err, link_preview.ErrNotHTML)
+	assert.Equal(t, results[1], enriched[1].Result)
+	assert.Empty(t, enriched[1].ImageURL)
+
+	// Results without a description get the one of the page
+	assert.NoError(t, enriched[2].Err)
+	assert.Equal(t, 3, enriched[2].Rank)
+	assert.Equal(t, "From the page", enriched[2].Description)
+}
+
+func TestEnrichResultsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := []Result{{Rank: 1, URL: "https://example.com/"}, {Rank: 2, URL: "https://example.org/"}}
+	enriched := EnrichResults(ctx, results, 4)
+
+	assert.Len(t, enriched, 2)
+	for i, r := range enriched {
+		assert.ErrorIs(t, r.Err, context.Canceled)
+		assert.Equal(t, results[i], r.Result)
+	}
+}
@@ -0,0 +1,94 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrCaptcha indicates that a captcha page was served instead of results.
+	// It wraps ErrBlocked, so errors.Is(err, ErrBlocked) also holds for it.
+	ErrCaptcha = fmt.Errorf("captcha page: %w", ErrBlocked)
+
+	// ErrConsentRequired indicates that Google served its cookie consent page instead of
+	// results, and SearchOptions.Consent could not get past it. Unlike ErrBlocked, it is not
+	// retried: the same request would get the same page.
+	ErrConsentRequired = errors.New("consent required")
+
+	// ErrNoResults indicates that the page was parsed but contained no result blocks
+	// although it did not state that nothing matched. This usually means the layout changed,
+	// which SearchGoogle reports as ErrLayoutChanged.
+	ErrNoResults = errors.New("no results found on page")
+
+	// ErrLayoutChanged indicates that no layout yields results on a page that should have
+	// some, as returned in a LayoutError. It wraps ErrNoResults.
+	ErrLayoutChanged = fmt.Errorf("layout changed: %w", ErrNoResults)
+
+	// ErrUnexpectedStatus indicates a response status other than 200 that is not a block.
+	ErrUnexpectedStatus = errors.New("unexpected response status")
+
+	// ErrUnsupportedCountry indicates a CountryCode that is not in GoogleDomains.
+	ErrUnsupportedCountry = errors.New("unsupported country code")
+
+	// ErrUnsupportedLanguage indicates a LanguageCode that is not an ISO 639-1 code.
+	ErrUnsupportedLanguage = errors.New("unsupported language code")
+
+	// ErrQuotaExceeded indicates that the Custom Search JSON API refused a request because the
+	// daily or per-minute quota of the API key is used up.
+	ErrQuotaExceeded = errors.New("custom search quota exceeded")
+
+	// ErrNoCacheAvailable indicates that no source of FetchCached has a copy of the page.
+	ErrNoCacheAvailable = errors.New("no cached copy available")
+
+	// ErrInvalidQuery indicates an empty or malformed QueryBuilder argument, or a domain
+	// FindDomainRank cannot look for.
+	ErrInvalidQuery = errors.New("invalid query")
+
+	// ErrNotRanked indicates that FindDomainRank did not find the domain within the depth it
+	// searched.
+	ErrNotRanked = errors.New("domain not ranked")
+)
+
+// SearchError describes a failed search request. Err is one of the sentinel errors of this
+// package or the underlying transport or parse error, and is matched by errors.Is and errors.As.
+type SearchError struct {
+
+	// Engine that was queried.
+	Engine Engine
+
+	// CountryCode of the search, if any.
+	CountryCode string
+
+	// URL is the last URL requested.
+	URL string
+
+	// StatusCode of the response, or 0 if none was received.
+	StatusCode int
+
+	// Profile is the Name of the HeaderProfile sent with SearchOptions.RotateUserAgent, if any.
+	Profile string
+
+	Err error
+}
+
+func (e *SearchError) Error() string {
+	msg := string(e.Engine)
+	if e.CountryCode != "" {
+		msg += " (" + e.CountryCode + ")"
+	}
+	msg += " search"
+	if e.URL != "" {
+		msg += " " + e.URL
+	}
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(" returned status %d", e.StatusCode)
+	}
+	if e.Profile != "" {
+		msg += " as " + e.Profile
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *SearchError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,70 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchGoogleErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantErr    error
+		wantStatus int
+	}{
+		{"rate limited", http.StatusTooManyRequests, "", ErrBlocked, http.StatusTooManyRequests},
+		{"captcha", http.StatusOK, `<form id="captcha-form"></form>`, ErrCaptcha, http.StatusOK},
+		{"not found", http.StatusNotFound, "", ErrUnexpectedStatus, http.StatusNotFound},
+		{"layout change", http.StatusOK, `<html><body><div id="rso"></div></body></html>`, ErrNoResults, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			_, err := SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client, CountryCode: "de"})
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			var searchErr *SearchError
+			if assert.True(t, errors.As(err, &searchErr)) {
+				assert.Equal(t, EngineGoogle, searchErr.Engine)
+				assert.Equal(t, "de", searchErr.CountryCode)
+				assert.Equal(t, tt.wantStatus, searchErr.StatusCode)
+				assert.Contains(t, searchErr.URL, "/search?q=golang")
+			}
+		})
+	}
+}
+
+func TestErrCaptchaIsBlocked(t *testing.T) {
+	assert.ErrorIs(t, ErrCaptcha, ErrBlocked)
+	assert.NotErrorIs(t, ErrBlocked, ErrCaptcha)
+}
+
+func TestSearchGoogleNoMatch(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<div id="topstuff"><p>Your search - <b>xyzzy</b> - did not match any documents.</p></div>`))
+	})
+
+	results, err := SearchGoogle(context.Background(), "xyzzy", SearchOptions{HTTPClient: client})
+
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestSearchGoogleNoMatchLocalized(t *testing.T) {
+	client, _ := newTestClient(t, serveFixture(t, "google_noresults_de.html"))
+
+	results, err := SearchGoogle(context.Background(), "xyzzyqwv", SearchOptions{HTTPClient: client, LanguageCode: "de"})
+
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
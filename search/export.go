@@ -0,0 +1,214 @@
+package search
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/propro-productions/go-utils/internal/mdtable"
+)
+
+// ExportJSON writes results as a JSON array, indented when pretty is set.
+func ExportJSON[T any](w io.Writer, results []T, pretty bool) error {
+	if results == nil {
+		results = []T{}
+	}
+	encoder := json.NewEncoder(w)
+	if pretty {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder.Encode(results)
+}
+
+// ExportCSV writes results as CSV with a header row. results can be a slice of Result or of
+// any struct type, such as ScholarResult or EnrichedResult. There is a column for every
+// exported field, named like its JSON key; the fields of embedded structs get columns of
+// their own. Fields with the JSON key "-" are skipped.
+//
+// Strings, numbers and booleans are written as is, times in RFC 3339 and slices of strings
+// joined by "; ". Any other value, such as Sitelinks, is written as JSON.
+func ExportCSV[T any](w io.Writer, results []T) error {
+	columns, err := exportColumns(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.name
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, r := range results {
+		row, err := exportRow(reflect.ValueOf(r), columns)
+		if err != nil {
+			return err
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ExportMarkdownTable writes results as a markdown table with the columns of ExportCSV,
+// leaving out the columns that are empty for every result. Line breaks in values are
+// replaced by spaces.
+func ExportMarkdownTable[T any](w io.Writer, results []T) error {
+	columns, err := exportColumns(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(results))
+	used := make([]bool, len(columns))
+	for _, r := range results {
+		row, err := exportRow(reflect.ValueOf(r), columns)
+		if err != nil {
+			return err
+		}
+		for i, value := range row {
+			value = strings.Join(strings.Fields(value), " ")
+			row[i] = strings.ReplaceAll(value, "|", `\|`)
+			used[i] = used[i] || value != ""
+		}
+		rows = append(rows, row)
+	}
+
+	// Without results there is nothing to tell empty columns by
+	var keep []int
+	for i := range columns {
+		if used[i] || len(rows) == 0 {
+			keep = append(keep, i)
+		}
+	}
+	header := make([]string, len(keep))
+	for j, i := range keep {
+		header[j] = columns[i].name
+	}
+	table := [][]string{header}
+	for _, row := range rows {
+		cols := make([]string, len(keep))
+		for j, i := range keep {
+			cols[j] = row[i]
+		}
+		table = append(table, cols)
+	}
+
+	var b strings.Builder
+	mdtable.Write(&b, table)
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// exportColumn is a field exported as a column. index is its path through embedded structs,
+// as used by reflect.Value.FieldByIndex.
+type exportColumn struct {
+	name  string
+	index []int
+}
+
+// exportColumns lists the columns of t, which must be a struct or a pointer to one.
+func exportColumns(t reflect.Type) ([]exportColumn, error) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot export results of type %s", t)
+	}
+
+	var columns []exportColumn
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			fieldIndex := append(append([]int(nil), index...), i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if field.Anonymous && field.Type.Kind() == reflect.Struct && name == "" {
+				walk(field.Type, fieldIndex)
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			columns = append(columns, exportColumn{name: name, index: fieldIndex})
+		}
+	}
+	walk(t, nil)
+	return columns, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// exportRow formats the columns of the result v.
+func exportRow(v reflect.Value, columns []exportColumn) ([]string, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return make([]string, len(columns)), nil
+		}
+		v = v.Elem()
+	}
+
+	row := make([]string, len(columns))
+	for i, c := range columns {
+		value, err := exportValue(v.FieldByIndex(c.index))
+		if err != nil {
+			return nil, fmt.Errorf("exporting %s: %w", c.name, err)
+		}
+		row[i] = value
+	}
+	return row, nil
+}
+
+func exportValue(v reflect.Value) (string, error) {
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return "", nil
+		}
+		return t.Format(time.RFC3339), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v.Interface()), nil
+	case reflect.Pointer, reflect.Interface, reflect.Map:
+		if v.IsNil() {
+			return "", nil
+		}
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return "", nil
+		}
+		if v.Type().Elem().Kind() == reflect.String {
+			values := make([]string, v.Len())
+			for i := range values {
+				values[i] = v.Index(i).String()
+			}
+			return strings.Join(values, "; "), nil
+		}
+	}
+
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,93 @@
+package search
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var exportResults = []Result{
+	{Rank: 1, URL: "https://go.dev/", Title: "The Go Programming Language", Description: "Go is fast, simple\nand \"productive\"."},
+	{Rank: 2, URL: "https://go.dev/doc", Title: "Documentation | Go", Sitelinks: []Sitelink{{Title: "Tour", URL: "https://go.dev/tour"}}, Rating: &Rating{Value: 4.5}},
+}
+
+func TestExportCSV(t *testing.T) {
+	var b bytes.Buffer
+
+	err := ExportCSV(&b, exportResults)
+
+	assert.NoError(t, err)
+	records, err := csv.NewReader(&b).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rank", "url", "title", "description", "sitelinks", "breadcrumb", "rating", "published_at"}, records[0])
+	assert.Len(t, records, 3)
+	assert.Equal(t, []string{"1", "https://go.dev/", "The Go Programming Language", "Go is fast, simple\nand \"productive\".", "", "", "", ""}, records[1])
+	assert.Equal(t, `[{"url":"https://go.dev/tour","title":"Tour"}]`, records[2][4])
+
+	var rating Rating
+	assert.NoError(t, json.Unmarshal([]byte(records[2][6]), &rating))
+	assert.Equal(t, 4.5, rating.Value)
+}
+
+func TestExportCSVExtendedResults(t *testing.T) {
+	results := []ScholarResult{{
+		Result:       Result{Rank: 1, Title: "Attention is all you need"},
+		Authors:      []string{"A Vaswani", "N Shazeer"},
+		CitedByCount: 1000,
+	}}
+	enriched := []EnrichedResult{{Result: Result{Rank: 1}, SiteName: "Go", Err: errors.New("timeout")}}
+	var b bytes.Buffer
+
+	assert.NoError(t, ExportCSV(&b, results))
+	records, err := csv.NewReader(&b).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rank", "url", "title", "description", "sitelinks", "breadcrumb", "rating", "published_at", "authors", "publication_year", "venue", "cited_by_count", "pdf_link"}, records[0])
+	assert.Equal(t, "A Vaswani; N Shazeer", records[1][8])
+	assert.Equal(t, "1000", records[1][11])
+
+	b.Reset()
+	assert.NoError(t, ExportCSV(&b, enriched))
+	records, err = csv.NewReader(&b).ReadAll()
+	assert.NoError(t, err)
+	// Err is not exported
+	assert.Equal(t, []string{"rank", "url", "title", "description", "sitelinks", "breadcrumb", "rating", "published_at", "image_url", "site_name", "canonical_url"}, records[0])
+}
+
+func TestExportCSVNotStruct(t *testing.T) {
+	err := ExportCSV(&bytes.Buffer{}, []string{"a"})
+
+	assert.Error(t, err)
+}
+
+func TestExportJSON(t *testing.T) {
+	var b bytes.Buffer
+
+	assert.NoError(t, ExportJSON(&b, exportResults, false))
+	var got []Result
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &got))
+	assert.Equal(t, exportResults, got)
+
+	b.Reset()
+	assert.NoError(t, ExportJSON[Result](&b, nil, true))
+	assert.Equal(t, "[]\n", b.String())
+}
+
+func TestExportMarkdownTable(t *testing.T) {
+	var b bytes.Buffer
+
+	err := ExportMarkdownTable(&b, exportResults)
+
+	assert.NoError(t, err)
+	lines := strings.Split(b.String(), "\n")
+	assert.Len(t, lines, 5)
+	// Breadcrumb and PublishedAt are empty for every result
+	assert.Equal(t, []string{"rank", "url", "title", "description", "sitelinks", "rating"}, strings.Fields(strings.ReplaceAll(lines[0], "|", "")))
+	assert.True(t, strings.HasPrefix(lines[1], "| ---- | ---"))
+	assert.Contains(t, lines[2], "| Go is fast, simple and \"productive\". |")
+	assert.Contains(t, lines[3], "| Documentation \\| Go ")
+}
@@ -0,0 +1,99 @@
+package search
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// SnippetType is the layout of a featured snippet.
+type SnippetType string
+
+const (
+	SnippetParagraph SnippetType = "paragraph"
+	SnippetList      SnippetType = "list"
+	SnippetTable     SnippetType = "table"
+)
+
+// Snippet is the featured snippet (answer box) Google shows above the results
+// for question-style queries.
+type Snippet struct {
+	Type SnippetType `json:"type"`
+
+	// Text is the answer as plain text. List items are separated by newlines
+	// and table rows by newlines with their cells separated by tabs.
+	Text string `json:"text"`
+
+	// List holds the items of a list snippet.
+	List []string `json:"list,omitempty"`
+
+	// Table holds the rows of a table snippet, including the header row.
+	Table [][]string `json:"table,omitempty"`
+
+	// SourceURL and SourceTitle describe the page the answer was taken from.
+	SourceURL   string `json:"source_url"`
+	SourceTitle string `json:"source_title"`
+}
+
+// featuredSnippetSelector matches the answer box container.
+const featuredSnippetSelector = "div.xpdopen, div.ifM9O"
+
+func parseFeaturedSnippet(doc *goquery.Document) *Snippet {
+	box := doc.Find(featuredSnippetSelector).First()
+	if box.Length() == 0 {
+		return nil
+	}
+
+	snippet := &Snippet{}
+
+	if rows := snippetTable(box); len(rows) > 0 {
+		snippet.Type = SnippetTable
+		snippet.Table = rows
+		lines := make([]string, len(rows))
+		for i, row := range rows {
+			lines[i] = strings.Join(row, "\t")
+		}
+		snippet.Text = strings.Join(lines, "\n")
+	} else if items := snippetList(box); len(items) > 0 {
+		snippet.Type = SnippetList
+		snippet.List = items
+		snippet.Text = strings.Join(items, "\n")
+	} else {
+		snippet.Type = SnippetParagraph
+		snippet.Text = strings.TrimSpace(box.Find("span.hgKElc, div[data-attrid='wa:/description'] span").First().Text())
+	}
+
+	if snippet.Text == "" {
+		return nil
+	}
+
+	titleEl := box.Find("h3").First()
+	snippet.SourceTitle = strings.TrimSpace(titleEl.Text())
+	snippet.SourceURL = resultLink(box.Find("div.yuRUbf a").First(), titleEl)
+
+	return snippet
+}
+
+func snippetTable(box *goquery.Selection) [][]string {
+	var rows [][]string
+	box.Find("table").First().Find("tr").Each(func(i int, tr *goquery.Selection) {
+		var row []string
+		tr.Find("th, td").Each(func(i int, cell *goquery.Selection) {
+			row = append(row, strings.TrimSpace(cell.Text()))
+		})
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+	})
+	return rows
+}
+
+func snippetList(box *goquery.Selection) []string {
+	var items []string
+	box.Find("ul, ol").First().Find("li").Each(func(i int, li *goquery.Selection) {
+		if item := strings.TrimSpace(li.Text()); item != "" {
+			items = append(items, item)
+		}
+	})
+	return items
+}
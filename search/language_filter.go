@@ -0,0 +1,66 @@
+package search
+
+import (
+	"strings"
+
+	"github.com/propro-productions/go-utils/internal/langdetect"
+)
+
+// DefaultLanguageConfidence is the default for SearchOptions.LanguageConfidence. Snippets
+// are short, so detections of closely related languages often fall below it.
+const DefaultLanguageConfidence = 0.05
+
+// FilteredResult is a result left out by SearchOptions.FilterLanguage.
+type FilteredResult struct {
+	Result
+
+	// Language is the ISO 639-1 code of the language detected in the title and description.
+	Language string `json:"language"`
+	// Confidence is how sure the detection was, from 0 to 1.
+	Confidence float64 `json:"confidence"`
+}
+
+// filterLanguage drops, with opt.FilterLanguage, the results whose title and description
+// are confidently detected to be in another language. Results too short to tell, or in a
+// language the detector doesn't know, are kept. The rest is renumbered from the rank of the
+// first result.
+func filterLanguage(results []Result, opt SearchOptions) ([]Result, []FilteredResult) {
+	want := filterLanguageCode(opt.FilterLanguage)
+	if want == "" {
+		return results, nil
+	}
+	threshold := opt.LanguageConfidence
+	if threshold <= 0 {
+		threshold = DefaultLanguageConfidence
+	}
+
+	kept := make([]Result, 0, len(results))
+	var dropped []FilteredResult
+	for _, r := range results {
+		lang, confidence := langdetect.Detect(r.Title + ". " + r.Description)
+		if lang != "" && lang != want && confidence >= threshold {
+			dropped = append(dropped, FilteredResult{Result: r, Language: lang, Confidence: confidence})
+			continue
+		}
+		kept = append(kept, r)
+	}
+
+	if len(kept) > 0 && len(dropped) > 0 {
+		first := results[0].Rank
+		for i := range kept {
+			kept[i].Rank = first + i
+		}
+	}
+	return kept, dropped
+}
+
+// filterLanguageCode returns the ISO 639-1 code of lang, which may also be written like
+// Lr, "lang_de", or as a language tag such as "pt-BR".
+func filterLanguageCode(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	lang = strings.TrimPrefix(lang, "lang_")
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
@@ -0,0 +1,77 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mixedLanguageResults are the title and description of results in several languages.
+var mixedLanguageResults = [][2]string{
+	{"Jaguar – Wikipedia", "Der Jaguar ist eine Art der Gattung der Eigentlichen Großkatzen und die einzige heute lebende Art dieser Gattung in Amerika."},
+	{"Jaguar | Definition, Habitat, & Facts | Britannica", "Jaguar, largest cat native to the Western Hemisphere, found in forests and grasslands from Mexico to Argentina."},
+	{"Jaguar XE", "2.0d R-Sport"},
+	{"Jaguar - Wikipedia, la enciclopedia libre", "El jaguar es un carnívoro félido de la subfamilia de los panterinos y género Panthera."},
+	{"Jaguar kaufen bei mobile.de", "Gebrauchtwagen und Neuwagen aus ganz Deutschland, günstig und sicher finden."},
+}
+
+func mixedLanguageServer(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+	b.WriteString(`<html><body><div id="search">`)
+	for i, result := range mixedLanguageResults {
+		fmt.Fprintf(&b, `<div class="g"><div class="yuRUbf"><a href="https://site%d.example/"><h3>%s</h3></a></div><div class="VwiC3b">%s</div></div>`,
+			i+1, result[0], result[1])
+	}
+	b.WriteString(`</div></body></html>`)
+	w.Write([]byte(b.String()))
+}
+
+func TestFilterLanguage(t *testing.T) {
+	client, _ := newTestClient(t, mixedLanguageServer)
+
+	resp, err := SearchGoogleFull(context.Background(), "jaguar", SearchOptions{HTTPClient: client, FilterLanguage: "de-DE"})
+
+	assert.NoError(t, err)
+	var urls []string
+	for _, r := range resp.Results {
+		urls = append(urls, fmt.Sprintf("%d %s", r.Rank, r.URL))
+	}
+	// The result too short to tell the language of is kept
+	assert.Equal(t, []string{"1 https://site1.example/", "2 https://site3.example/", "3 https://site5.example/"}, urls)
+
+	if assert.Len(t, resp.LanguageFiltered, 2) {
+		assert.Equal(t, 2, resp.LanguageFiltered[0].Rank)
+		assert.Equal(t, "en", resp.LanguageFiltered[0].Language)
+		assert.Equal(t, 4, resp.LanguageFiltered[1].Rank)
+		assert.Equal(t, "es", resp.LanguageFiltered[1].Language)
+		assert.Greater(t, resp.LanguageFiltered[1].Confidence, DefaultLanguageConfidence)
+	}
+}
+
+func TestFilterLanguageConfidence(t *testing.T) {
+	client, _ := newTestClient(t, mixedLanguageServer)
+
+	resp, err := SearchGoogleFull(context.Background(), "jaguar", SearchOptions{HTTPClient: client, FilterLanguage: "lang_de", LanguageConfidence: 0.99})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Results, len(mixedLanguageResults))
+	assert.Empty(t, resp.LanguageFiltered)
+}
+
+func TestFilterLanguageInvalid(t *testing.T) {
+	_, err := SearchGoogle(context.Background(), "jaguar", SearchOptions{FilterLanguage: "german"})
+
+	assert.True(t, errors.Is(err, ErrUnsupportedLanguage), "%v", err)
+}
+
+func TestFilterLanguageCode(t *testing.T) {
+	assert.Equal(t, "de", filterLanguageCode("de"))
+	assert.Equal(t, "pt", filterLanguageCode(" pt-BR"))
+	assert.Equal(t, "zh", filterLanguageCode("zh_TW"))
+	assert.Equal(t, "fr", filterLanguageCode("lang_FR"))
+}
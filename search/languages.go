@@ -0,0 +1,92 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SupportedCountries returns the country codes of GoogleDomains in alphabetical order.
+func SupportedCountries() []string {
+	countries := make([]string, 0, len(GoogleDomains))
+	for cc := range GoogleDomains {
+		countries = append(countries, cc)
+	}
+	sort.Strings(countries)
+	return countries
+}
+
+// IsValidLanguage reports whether code is an ISO 639-1 language code, optionally followed by
+// a region as Google accepts it, e.g. "en", "pt-BR" or "zh_TW". Codes are case-insensitive.
+func IsValidLanguage(code string) bool {
+	lang, region, hasRegion := strings.Cut(strings.Replace(code, "_", "-", 1), "-")
+	if hasRegion && (len(region) < 2 || len(region) > 4) {
+		return false
+	}
+	return iso6391[strings.ToLower(lang)]
+}
+
+// validateOptions checks the countries and languages of opt. Empty codes select the defaults.
+func validateOptions(opt SearchOptions) error {
+	if opt.CountryCode != "" {
+		if _, ok := GoogleDomains[opt.CountryCode]; !ok {
+			return fmt.Errorf("%w: %q", ErrUnsupportedCountry, opt.CountryCode)
+		}
+	}
+	if opt.LanguageCode != "" && !IsValidLanguage(opt.LanguageCode) {
+		return fmt.Errorf("%w: %q", ErrUnsupportedLanguage, opt.LanguageCode)
+	}
+	if opt.Gl != "" && !isCountryCode(opt.Gl) {
+		return fmt.Errorf("%w: %q", ErrUnsupportedCountry, opt.Gl)
+	}
+	if opt.Lr != "" {
+		for _, lang := range strings.Split(opt.Lr, "|") {
+			if !IsValidLanguage(strings.TrimPrefix(strings.TrimSpace(lang), "lang_")) {
+				return fmt.Errorf("%w: %q", ErrUnsupportedLanguage, opt.Lr)
+			}
+		}
+	}
+	if opt.FilterLanguage != "" && !IsValidLanguage(strings.TrimPrefix(strings.TrimSpace(opt.FilterLanguage), "lang_")) {
+		return fmt.Errorf("%w: %q", ErrUnsupportedLanguage, opt.FilterLanguage)
+	}
+	return nil
+}
+
+// isCountryCode reports whether code looks like an ISO 3166-1 alpha-2 code. Unlike
+// CountryCode, Gl accepts countries without a Google domain of their own.
+func isCountryCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, r := range strings.ToLower(code) {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// iso6391 holds the ISO 639-1 language codes.
+//
+// See: https://en.wikipedia.org/wiki/List_of_ISO_639-1_codes
+var iso6391 = map[string]bool{
+	"aa": true, "ab": true, "ae": true, "af": true, "ak": true, "am": true, "an": true, "ar": true, "as": true, "av": true,
+	"ay": true, "az": true, "ba": true, "be": true, "bg": true, "bh": true, "bi": true, "bm": true, "bn": true, "bo": true,
+	"br": true, "bs": true, "ca": true, "ce": true, "ch": true, "co": true, "cr": true, "cs": true, "cu": true, "cv": true,
+	"cy": true, "da": true, "de": true, "dv": true, "dz": true, "ee": true, "el": true, "en": true, "eo": true, "es": true,
+	"et": true, "eu": true, "fa": true, "ff": true, "fi": true, "fj": true, "fo": true, "fr": true, "fy": true, "ga": true,
+	"gd": true, "gl": true, "gn": true, "gu": true, "gv": true, "ha": true, "he": true, "hi": true, "ho": true, "hr": true,
+	"ht": true, "hu": true, "hy": true, "hz": true, "ia": true, "id": true, "ie": true, "ig": true, "ii": true, "ik": true,
+	"io": true, "is": true, "it": true, "iu": true, "ja": true, "jv": true, "ka": true, "kg": true, "ki": true, "kj": true,
+	"kk": true, "kl": true, "km": true, "kn": true, "ko": true, "kr": true, "ks": true, "ku": true, "kv": true, "kw": true,
+	"ky": true, "la": true, "lb": true, "lg": true, "li": true, "ln": true, "lo": true, "lt": true, "lu": true, "lv": true,
+	"mg": true, "mh": true, "mi": true, "mk": true, "ml": true, "mn": true, "mr": true, "ms": true, "mt": true, "my": true,
+	"na": true, "nb": true, "nd": true, "ne": true, "ng": true, "nl": true, "nn": true, "no": true, "nr": true, "nv": true,
+	"ny": true, "oc": true, "oj": true, "om": true, "or": true, "os": true, "pa": true, "pi": true, "pl": true, "ps": true,
+	"pt": true, "qu": true, "rm": true, "rn": true, "ro": true, "ru": true, "rw": true, "sa": true, "sc": true, "sd": true,
+	"se": true, "sg": true, "si": true, "sk": true, "sl": true, "sm": true, "sn": true, "so": true, "sq": true, "sr": true,
+	"ss": true, "st": true, "su": true, "sv": true, "sw": true, "ta": true, "te": true, "tg": true, "th": true, "ti": true,
+	"tk": true, "tl": true, "tn": true, "to": true, "tr": true, "ts": true, "tt": true, "tw": true, "ty": true, "ug": true,
+	"uk": true, "ur": true, "uz": true, "ve": true, "vi": true, "vo": true, "wa": true, "wo": true, "xh": true, "yi": true,
+	"yo": true, "za": true, "zh": true, "zu": true,
+}
@@ -0,0 +1,66 @@
+package search
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupportedCountries(t *testing.T) {
+	countries := SupportedCountries()
+
+	assert.Len(t, countries, len(GoogleDomains))
+	assert.True(t, sort.StringsAreSorted(countries))
+	assert.Contains(t, countries, "us")
+	assert.Contains(t, countries, "de")
+}
+
+func TestIsValidLanguage(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"en", true},
+		{"DE", true},
+		{"pt-BR", true},
+		{"zh_TW", true},
+		{"enn", false},
+		{"xx", false},
+		{"en-", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, IsValidLanguage(tt.code), tt.code)
+	}
+}
+
+func TestSearchGoogleValidatesOptions(t *testing.T) {
+	client, rt := newTestClient(t, serveFixture(t, "google_results.html"))
+
+	_, err := SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client, CountryCode: "xx"})
+	assert.ErrorIs(t, err, ErrUnsupportedCountry)
+	assert.Contains(t, err.Error(), `"xx"`)
+
+	_, err = SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client, LanguageCode: "enn"})
+	assert.ErrorIs(t, err, ErrUnsupportedLanguage)
+	assert.Contains(t, err.Error(), `"enn"`)
+
+	_, err = SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client, Gl: "usa"})
+	assert.ErrorIs(t, err, ErrUnsupportedCountry)
+
+	_, err = SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client, Lr: "lang_de|lang_xx"})
+	assert.ErrorIs(t, err, ErrUnsupportedLanguage)
+
+	assert.Empty(t, rt.requests)
+
+	// Gl accepts countries without a Google domain
+	_, err = SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client, Gl: "kp", Lr: "ko"})
+	assert.NoError(t, err)
+
+	// Empty codes keep selecting the defaults.
+	_, err = SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client})
+	assert.NoError(t, err)
+}
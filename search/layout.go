@@ -0,0 +1,158 @@
+package search
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Layout holds the CSS selectors the results of one layout of the Google results page are
+// parsed with. Google rotates its class names regularly; when it does, a Layout passed in
+// SearchOptions.Layouts fixes parsing until a release ships the new one.
+type Layout struct {
+
+	// Name identifies the layout in SearchResponse.Layout and in errors.
+	Name string `json:"name"`
+
+	// Container selects the element of every result.
+	Container string `json:"container"`
+
+	// Title selects the title within a container.
+	Title string `json:"title"`
+
+	// Link selects the anchor of the result within a container. When it is empty or matches
+	// nothing, the anchor around the title is used.
+	Link string `json:"link,omitempty"`
+
+	// Descriptions are tried in order until one yields a non-empty snippet.
+	Descriptions []string `json:"descriptions,omitempty"`
+}
+
+// DefaultLayouts are the layouts of the results page known to this package, newest first.
+// Google serves different markup depending on the locale and the browser, so older layouts
+// are kept as fallbacks.
+var DefaultLayouts = []Layout{
+	{
+		Name:      "desktop",
+		Container: ".g",
+		Title:     "h3",
+		Link:      "div.yuRUbf a",
+		Descriptions: []string{
+			"div.VwiC3b",
+			"div[data-sncf]",
+			"div.IsZvec",
+			"span.aCOpRe",
+			".aCOpRe span",
+			// Localized pages, e.g. German ones, often wrap the snippet differently
+			"div.Hdw6tb",
+			"div[data-content-feature='1']",
+		},
+	},
+	{
+		// The markup served to browsers without JavaScript, common for Asian locales
+		Name:         "basic",
+		Container:    "div.Gx5Zad",
+		Title:        "h3, div.vvjwJb",
+		Link:         "div.egMi0 a, div.kCrYT > a",
+		Descriptions: []string{"div.BNeawe.s3v9rd", "div.s3v9rd"},
+	},
+}
+
+// LayoutError is returned when a results page that is neither a block page nor states that
+// nothing matched yields no results with any layout. It matches ErrLayoutChanged, and
+// ErrNoResults through it.
+type LayoutError struct {
+
+	// Tried are the layouts the page was parsed with, in order.
+	Tried []Layout
+}
+
+func (e *LayoutError) Error() string {
+	tried := make([]string, len(e.Tried))
+	for i, layout := range e.Tried {
+		tried[i] = fmt.Sprintf("%s (container %q, title %q, link %q)", layout.Name, layout.Container, layout.Title, layout.Link)
+	}
+	return ErrLayoutChanged.Error() + ", tried " + strings.Join(tried, ", ")
+}
+
+func (e *LayoutError) Unwrap() error {
+	return ErrLayoutChanged
+}
+
+// layouts returns the layouts to parse results pages with: opt.Layouts, then DefaultLayouts.
+func layouts(opt SearchOptions) []Layout {
+	if len(opt.Layouts) == 0 {
+		return DefaultLayouts
+	}
+	return append(append([]Layout(nil), opt.Layouts...), DefaultLayouts...)
+}
+
+// parseResults parses a results page with DefaultLayouts. start is the offset of the page,
+// its first result being ranked start+1.
+func parseResults(r io.Reader, start int) ([]Result, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+	results, _ := parseResultsDocument(doc, DefaultLayouts, start)
+	return results, nil
+}
+
+// parseResultsDocument tries layouts in order and returns the results of the first one
+// yielding any, with its name. Results are ranked from start+1.
+func parseResultsDocument(doc *goquery.Document, layouts []Layout, start int) ([]Result, string) {
+	for _, layout := range layouts {
+		if results := layout.parse(doc, start); len(results) > 0 {
+			return results, layout.Name
+		}
+	}
+	return nil, ""
+}
+
+func (layout Layout) parse(doc *goquery.Document, start int) []Result {
+	if layout.Container == "" {
+		return nil
+	}
+
+	var results []Result
+	s := doc.Find(layout.Container)
+	rank := start + 1
+	now := time.Now()
+
+	s.Each(func(i int, el *goquery.Selection) {
+		// Nested blocks (e.g. grouped results) are handled by their outer block.
+		if el.ParentsFiltered(layout.Container).Length() > 0 {
+			return
+		}
+		// The source of the answer box is reported as SearchResponse.FeaturedSnippet instead.
+		if el.ParentsFiltered(featuredSnippetSelector).Length() > 0 {
+			return
+		}
+
+		titleEl := el.Find(layout.Title).First()
+		// An empty or invalid selector matches nothing
+		link := resultLink(el.Find(layout.Link).First(), titleEl)
+		if link == "" {
+			return
+		}
+
+		result := Result{}
+
+		result.Rank = rank
+		rank++
+
+		result.Title = strings.TrimSpace(titleEl.Text())
+		result.URL = link
+		result.PublishedAt, result.Description = parseSnippetDate(resultDescription(el, layout.Descriptions), now)
+		result.Sitelinks = parseSitelinks(el)
+		result.Breadcrumb = strings.TrimSpace(el.Find("cite").First().Text())
+		result.Rating = parseRating(el)
+
+		results = append(results, result)
+	})
+
+	return results
+}
@@ -0,0 +1,74 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const reshuffledPage = `<html><body><div id="rso">
+<div class="Xq1"><span class="t9"><a href="https://go.dev/">The Go Programming Language</a></span><p class="s0">Go is an open source programming language.</p></div>
+<div class="Xq1"><span class="t9"><a href="/url?q=https://pkg.go.dev/&amp;sa=U">Go Packages</a></span></div>
+</div></body></html>`
+
+func TestParseResponseRecordsLayout(t *testing.T) {
+	resp, err := parseResponse(strings.NewReader(`<html><body><div class="g"><a href="https://go.dev/"><h3>Go</h3></a></div></body></html>`), DefaultLayouts, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Results, 1)
+	assert.Equal(t, "desktop", resp.Layout)
+
+	resp, err = parseResponse(strings.NewReader(`<html><body><div class="Gx5Zad"><div class="egMi0"><a href="/url?q=https://go.dev/"><h3>Go</h3></a></div></div></body></html>`), DefaultLayouts, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Results, 1)
+	assert.Equal(t, "basic", resp.Layout)
+}
+
+func TestSearchGoogleLayoutChanged(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(reshuffledPage))
+	})
+
+	_, err := SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client})
+
+	assert.ErrorIs(t, err, ErrLayoutChanged)
+	assert.ErrorIs(t, err, ErrNoResults)
+	var layoutErr *LayoutError
+	if assert.True(t, errors.As(err, &layoutErr)) {
+		assert.Equal(t, DefaultLayouts, layoutErr.Tried)
+	}
+	assert.Contains(t, err.Error(), `desktop (container ".g", title "h3", link "div.yuRUbf a")`)
+	assert.Contains(t, err.Error(), `basic (container "div.Gx5Zad"`)
+}
+
+func TestSearchGoogleCustomLayout(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(reshuffledPage))
+	})
+	hotfix := Layout{Name: "hotfix", Container: "div.Xq1", Title: "span.t9", Link: "span.t9 a", Descriptions: []string{"p.s0"}}
+
+	resp, err := SearchGoogleFull(context.Background(), "golang", SearchOptions{HTTPClient: client, Layouts: []Layout{hotfix}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "hotfix", resp.Layout)
+	assert.Equal(t, []Result{
+		{Rank: 1, URL: "https://go.dev/", Title: "The Go Programming Language", Description: "Go is an open source programming language."},
+		{Rank: 2, URL: "https://pkg.go.dev/", Title: "Go Packages"},
+	}, resp.Results)
+}
+
+func TestSearchGoogleCustomLayoutFallsBack(t *testing.T) {
+	client, _ := newTestClient(t, serveFixture(t, "google_results.html"))
+	broken := Layout{Name: "broken", Container: "div.missing", Title: "h3"}
+
+	resp, err := SearchGoogleFull(context.Background(), "golang", SearchOptions{HTTPClient: client, Layouts: []Layout{broken, {Name: "empty"}}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "desktop", resp.Layout)
+	assert.Len(t, resp.Results, 3)
+}
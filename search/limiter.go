@@ -0,0 +1,115 @@
+package search
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// Limiter decides when a request to a host may be made.
+type Limiter interface {
+
+	// Wait blocks until a request to host is allowed or ctx is done.
+	Wait(ctx context.Context, host string) error
+}
+
+// DefaultLimiter is used when SearchOptions.Limiter is nil. It applies RateLimit to every host.
+var DefaultLimiter Limiter = globalLimiter{}
+
+// globalLimiter applies the package-level RateLimit regardless of host. RateLimit is read on
+// every call so that replacing it keeps working.
+type globalLimiter struct{}
+
+func (globalLimiter) Wait(ctx context.Context, host string) error {
+	return RateLimit.Wait(ctx)
+}
+
+// HostLimiter rate limits requests separately for each host. It is safe for concurrent use.
+//
+// Hosts are compared case-insensitively and without port, so "www.google.de" and
+// "www.google.com" are limited independently.
+type HostLimiter struct {
+	mu        sync.Mutex
+	rate      rate.Limit
+	burst     int
+	overrides map[string]hostRate
+	limiters  map[string]*rate.Limiter
+}
+
+type hostRate struct {
+	rate  rate.Limit
+	burst int
+}
+
+// NewHostLimiter returns a HostLimiter that allows defaultRate requests per second with the
+// given burst to every host without an override.
+func NewHostLimiter(defaultRate rate.Limit, burst int) *HostLimiter {
+	return &HostLimiter{
+		rate:      defaultRate,
+		burst:     burst,
+		overrides: map[string]hostRate{},
+		limiters:  map[string]*rate.Limiter{},
+	}
+}
+
+// SetHostLimit overrides the rate and burst for host. It also applies to requests already waiting.
+func (l *HostLimiter) SetHostLimit(host string, r rate.Limit, burst int) {
+	host = limiterKey(host)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.overrides[host] = hostRate{rate: r, burst: burst}
+	if limiter, ok := l.limiters[host]; ok {
+		limiter.SetLimit(r)
+		limiter.SetBurst(burst)
+	}
+}
+
+// Wait blocks until a request to host is allowed or ctx is done.
+func (l *HostLimiter) Wait(ctx context.Context, host string) error {
+	return l.limiter(host).Wait(ctx)
+}
+
+func (l *HostLimiter) limiter(host string) *rate.Limiter {
+	host = limiterKey(host)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limiter, ok := l.limiters[host]
+	if !ok {
+		r, burst := l.rate, l.burst
+		if o, ok := l.overrides[host]; ok {
+			r, burst = o.rate, o.burst
+		}
+		limiter = rate.NewLimiter(r, burst)
+		l.limiters[host] = limiter
+	}
+	return limiter
+}
+
+func limiterKey(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// waitLimit waits for the limiter of opt (or DefaultLimiter) before a request to rawURL.
+func waitLimit(ctx context.Context, opt SearchOptions, rawURL string) error {
+	limiter := opt.Limiter
+	if limiter == nil {
+		limiter = DefaultLimiter
+	}
+
+	var host string
+	if u, err := url.Parse(rawURL); err == nil {
+		host = u.Hostname()
+	}
+	return limiter.Wait(ctx, host)
+}
@@ -0,0 +1,78 @@
+package search
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestHostLimiterPerHost(t *testing.T) {
+	l := NewHostLimiter(rate.Every(time.Hour), 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// Each host has its own burst.
+	assert.NoError(t, l.Wait(ctx, "www.google.com"))
+	assert.NoError(t, l.Wait(ctx, "www.google.de"))
+	assert.NoError(t, l.Wait(ctx, "WWW.Example.com:8080"))
+
+	// The second request to a host has to wait an hour.
+	assert.Error(t, l.Wait(ctx, "www.google.com"))
+	assert.Error(t, l.Wait(ctx, "www.example.com"))
+}
+
+func TestHostLimiterOverride(t *testing.T) {
+	l := NewHostLimiter(rate.Every(time.Hour), 1)
+	l.SetHostLimit("www.google.de", rate.Inf, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, l.Wait(ctx, "www.google.de"))
+	}
+
+	assert.NoError(t, l.Wait(ctx, "www.google.com"))
+	l.SetHostLimit("www.google.com", rate.Inf, 0)
+	assert.NoError(t, l.Wait(ctx, "www.google.com"))
+}
+
+type recordingLimiter struct {
+	mu    sync.Mutex
+	hosts []string
+}
+
+func (l *recordingLimiter) Wait(ctx context.Context, host string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hosts = append(l.hosts, host)
+	return nil
+}
+
+func TestSearchGoogleUsesLimiter(t *testing.T) {
+	client, _ := newTestClient(t, serveFixture(t, "google_results.html"))
+	limiter := &recordingLimiter{}
+
+	_, err := SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client, CountryCode: "de", Limiter: limiter})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"www.google.de"}, limiter.hosts)
+}
+
+func TestSearchGoogleDefaultsToRateLimit(t *testing.T) {
+	defer func(l *rate.Limiter) { RateLimit = l }(RateLimit)
+	RateLimit = rate.NewLimiter(rate.Every(time.Hour), 1)
+
+	client, _ := newTestClient(t, serveFixture(t, "google_results.html"))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := SearchGoogle(ctx, "golang", SearchOptions{HTTPClient: client})
+	assert.NoError(t, err)
+
+	_, err = SearchGoogle(ctx, "golang", SearchOptions{HTTPClient: client})
+	assert.Error(t, err)
+}
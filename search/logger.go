@@ -0,0 +1,56 @@
+package search
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Logger receives debug messages about what the package is doing: the URLs requested, how
+// many results were parsed from a page and the retries made. args alternate between keys
+// and values, so that a *slog.Logger can be used as a Logger.
+type Logger interface {
+	Debug(msg string, args ...any)
+}
+
+// DefaultLogger is used when SearchOptions.Logger is nil. It discards every message.
+var DefaultLogger Logger = nopLogger{}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...any) {}
+
+// StdLogger returns a Logger writing every message to l as a line like
+// `search request engine=google url="https://..."`.
+func StdLogger(l *log.Logger) Logger {
+	return stdLogger{l}
+}
+
+type stdLogger struct {
+	l *log.Logger
+}
+
+func (s stdLogger) Debug(msg string, args ...any) {
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			fmt.Fprintf(&b, " %v", args[i])
+			break
+		}
+		value := fmt.Sprint(args[i+1])
+		if strings.ContainsAny(value, " =\"") || value == "" {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(&b, " %v=%s", args[i], value)
+	}
+	s.l.Print(b.String())
+}
+
+// logger returns the Logger of opt.
+func logger(opt SearchOptions) Logger {
+	if opt.Logger != nil {
+		return opt.Logger
+	}
+	return DefaultLogger
+}
@@ -0,0 +1,71 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type logEntry struct {
+	msg  string
+	args []any
+}
+
+type recordingLogger struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+func (l *recordingLogger) Debug(msg string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, logEntry{msg, args})
+}
+
+func (l *recordingLogger) messages() []string {
+	var messages []string
+	for _, e := range l.entries {
+		messages = append(messages, e.msg)
+	}
+	return messages
+}
+
+func TestSearchGoogleLogger(t *testing.T) {
+	fixture := serveFixture(t, "google_results.html")
+	calls := 0
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls++; calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fixture(w, r)
+	})
+	logger := &recordingLogger{}
+
+	_, err := SearchGoogle(context.Background(), "golang", SearchOptions{
+		HTTPClient:   client,
+		MaxRetries:   1,
+		RetryBackoff: time.Millisecond,
+		Logger:       logger,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"search request", "search response", "retrying", "search request", "search response", "parsed results"}, logger.messages())
+	assert.Contains(t, logger.entries[0].args, "https://www.google.com/search?q=golang&start=0")
+	assert.Equal(t, []any{"engine", EngineGoogle, "url", "https://www.google.com/search?q=golang&start=0", "results", 3}, logger.entries[5].args)
+}
+
+func TestStdLogger(t *testing.T) {
+	var b bytes.Buffer
+	logger := StdLogger(log.New(&b, "", 0))
+
+	logger.Debug("search request", "engine", EngineGoogle, "url", "https://www.google.com/search?q=a b", "empty", "", "odd")
+
+	assert.Equal(t, "search request engine=google url=\"https://www.google.com/search?q=a b\" empty=\"\" odd\n", b.String())
+}
@@ -0,0 +1,41 @@
+package search
+
+import (
+	"errors"
+	"time"
+
+	"github.com/propro-productions/go-utils/metrics"
+)
+
+// observeRequest makes a request to engine with request and reports it to opt.Metrics: its
+// start, its end with its status and duration, and ErrBlocked.
+func observeRequest(opt SearchOptions, engine Engine, request func() error) error {
+	m := opt.Metrics
+	if m == nil {
+		return request()
+	}
+
+	metrics.Count(m, metrics.SearchStarted, 1, "engine", string(engine))
+	start := time.Now()
+	err := request()
+	elapsed := time.Since(start)
+
+	status := metrics.StatusOK
+	switch {
+	case errors.Is(err, ErrBlocked):
+		status = metrics.StatusBlocked
+		metrics.Count(m, metrics.SearchBlocked, 1, "engine", string(engine))
+	case err != nil:
+		status = metrics.StatusError
+	}
+	metrics.Count(m, metrics.SearchFinished, 1, "engine", string(engine), "status", status)
+	metrics.Timing(m, metrics.SearchDuration, elapsed, "engine", string(engine), "status", status)
+	return err
+}
+
+// countCache counts a lookup of opt.Cache as name, metrics.CacheHits or metrics.CacheMisses.
+func countCache(opt SearchOptions, name string) {
+	if opt.Metrics != nil {
+		metrics.Count(opt.Metrics, name, 1, "cache", "search")
+	}
+}
@@ -0,0 +1,84 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingMetrics records the counts it receives by name and labels, and the names timed.
+type recordingMetrics struct {
+	mu     sync.Mutex
+	counts map[string]int64
+	timed  map[string]int
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{counts: map[string]int64{}, timed: map[string]int{}}
+}
+
+func (m *recordingMetrics) Count(name string, delta int64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[fmt.Sprint(name, labels)] += delta
+}
+
+func (m *recordingMetrics) Timing(name string, d time.Duration, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timed[fmt.Sprint(name, labels)]++
+}
+
+func TestMetricsRetry(t *testing.T) {
+	fixture := serveFixture(t, "google_results.html")
+	calls := 0
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls++; calls == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fixture(w, r)
+	})
+	m := newRecordingMetrics()
+	cache := NewMemoryCache(10)
+	opt := SearchOptions{HTTPClient: client, MaxRetries: 1, RetryBackoff: time.Millisecond, Cache: cache}.With(WithMetrics(m))
+
+	_, err := SearchGoogle(context.Background(), "golang", opt)
+	assert.NoError(t, err)
+	_, err = SearchGoogle(context.Background(), "golang", opt)
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]int64{
+		"cache.hits[cache search]":                      1,
+		"cache.misses[cache search]":                    1,
+		"search.blocked[engine google]":                 1,
+		"search.finished[engine google status blocked]": 1,
+		"search.finished[engine google status ok]":      1,
+		"search.retries[engine google]":                 1,
+		"search.started[engine google]":                 2,
+	}, m.counts)
+	assert.Equal(t, map[string]int{
+		"search.duration[engine google status blocked]": 1,
+		"search.duration[engine google status ok]":      1,
+	}, m.timed)
+}
+
+func TestMetricsBing(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	m := newRecordingMetrics()
+
+	_, err := SearchBing(context.Background(), "golang", SearchOptions{HTTPClient: client, Metrics: m})
+
+	assert.True(t, errors.Is(err, ErrBlocked), "%v", err)
+	assert.Equal(t, int64(1), m.counts["search.started[engine bing]"])
+	assert.Equal(t, int64(1), m.counts["search.blocked[engine bing]"])
+	assert.Equal(t, 1, m.timed["search.duration[engine bing status blocked]"])
+}
@@ -0,0 +1,342 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MonitorQuery is a search watched by a Monitor.
+type MonitorQuery struct {
+
+	// Key identifies the query in the SnapshotStore and in diffs.
+	// Default: Term
+	Key string
+
+	Term string
+
+	// Engine runs the query.
+	// Default: EngineGoogle
+	Engine Engine
+
+	Options SearchOptions
+}
+
+// Snapshot is the list of results of a query at a point in time.
+type Snapshot struct {
+	Key     string    `json:"key"`
+	Time    time.Time `json:"time"`
+	Results []Result  `json:"results"`
+}
+
+// SnapshotStore keeps the latest snapshot of every query of a Monitor. Implementations
+// must be safe for concurrent use.
+type SnapshotStore interface {
+
+	// Load returns the snapshot stored for key, or nil when there is none.
+	Load(key string) (*Snapshot, error)
+
+	// Save replaces the snapshot stored for snapshot.Key.
+	Save(snapshot *Snapshot) error
+}
+
+// RankChange is a URL found by both snapshots at a different rank.
+type RankChange struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	From  int    `json:"from"`
+	To    int    `json:"to"`
+}
+
+// Diff describes how the results of a query changed between two runs. Results are matched
+// by their URL in the form returned by NormalizeURL, so changes in tracking parameters or
+// fragments are not reported.
+type Diff struct {
+	Key string `json:"key"`
+
+	// Previous is the time of the previous snapshot, zero on the first run of a query.
+	Previous time.Time `json:"previous"`
+	Current  time.Time `json:"current"`
+
+	// New are the results missing from the previous snapshot, Dropped the results of the
+	// previous snapshot missing now.
+	New     []Result `json:"new,omitempty"`
+	Dropped []Result `json:"dropped,omitempty"`
+
+	// Moved are ordered by their new rank.
+	Moved []RankChange `json:"moved,omitempty"`
+}
+
+// Empty reports whether d holds no change.
+func (d Diff) Empty() bool {
+	return len(d.New) == 0 && len(d.Dropped) == 0 && len(d.Moved) == 0
+}
+
+// Monitor runs a set of queries repeatedly and reports how their results change from one run
+// to the next. It is safe for concurrent use.
+type Monitor struct {
+
+	// OnDiff is called with every non-empty Diff, on the goroutine running Run.
+	OnDiff func(Diff)
+
+	// OnError is called by Watch with the errors of Run.
+	OnError func(error)
+
+	store SnapshotStore
+
+	mu      sync.Mutex
+	queries []MonitorQuery
+}
+
+// NewMonitor returns a Monitor keeping its snapshots in store. A nil store keeps them in
+// memory.
+func NewMonitor(store SnapshotStore) *Monitor {
+	if store == nil {
+		store = NewMemorySnapshotStore()
+	}
+	return &Monitor{store: store}
+}
+
+// Add registers q. A query with the key of an already registered query replaces it.
+func (m *Monitor) Add(q MonitorQuery) error {
+	if q.Key == "" {
+		q.Key = q.Term
+	}
+	if q.Engine == "" {
+		q.Engine = EngineGoogle
+	}
+	if _, ok := Engines[q.Engine]; !ok {
+		return fmt.Errorf("unknown search engine: %q", q.Engine)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.queries {
+		if m.queries[i].Key == q.Key {
+			m.queries[i] = q
+			return nil
+		}
+	}
+	m.queries = append(m.queries, q)
+	return nil
+}
+
+// Run searches every registered query once, in the order they were added, stores the new
+// snapshots and returns the non-empty diffs against the previous ones. On the first run of
+// a query every result is new.
+//
+// A failing query leaves its snapshot untouched and doesn't stop the others; the errors are
+// returned joined.
+func (m *Monitor) Run(ctx context.Context) ([]Diff, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	m.mu.Lock()
+	queries := append([]MonitorQuery(nil), m.queries...)
+	m.mu.Unlock()
+
+	var diffs []Diff
+	var errs []error
+	for _, q := range queries {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		diff, err := m.run(ctx, q)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("monitor %q: %w", q.Key, err))
+			continue
+		}
+		if diff.Empty() {
+			continue
+		}
+		diffs = append(diffs, diff)
+		if m.OnDiff != nil {
+			m.OnDiff(diff)
+		}
+	}
+	return diffs, errors.Join(errs...)
+}
+
+func (m *Monitor) run(ctx context.Context, q MonitorQuery) (Diff, error) {
+	previous, err := m.store.Load(q.Key)
+	if err != nil {
+		return Diff{}, err
+	}
+
+	results, err := Engines[q.Engine].Search(ctx, q.Term, q.Options)
+	if err != nil {
+		return Diff{}, err
+	}
+	current := &Snapshot{Key: q.Key, Time: time.Now(), Results: results}
+	if err := m.store.Save(current); err != nil {
+		return Diff{}, err
+	}
+	return DiffSnapshots(previous, current), nil
+}
+
+// Watch calls Run right away and then every interval until ctx is done, and returns
+// ctx.Err(). The errors of Run are passed to OnError.
+func (m *Monitor) Watch(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.Run(ctx); err != nil && ctx.Err() == nil && m.OnError != nil {
+			m.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// DiffSnapshots compares two snapshots of the same query. previous may be nil.
+func DiffSnapshots(previous, current *Snapshot) Diff {
+	diff := Diff{Key: current.Key, Current: current.Time}
+	var before map[string]Result
+	if previous != nil {
+		diff.Previous = previous.Time
+		before = rankedByURL(previous.Results)
+	}
+	after := rankedByURL(current.Results)
+
+	for _, r := range current.Results {
+		key := dedupeKey(r.URL)
+		if after[key].Rank != r.Rank {
+			// A lower ranked duplicate
+			continue
+		}
+		old, ok := before[key]
+		switch {
+		case !ok:
+			diff.New = append(diff.New, r)
+		case old.Rank != r.Rank:
+			diff.Moved = append(diff.Moved, RankChange{URL: r.URL, Title: r.Title, From: old.Rank, To: r.Rank})
+		}
+	}
+	if previous != nil {
+		for _, r := range previous.Results {
+			key := dedupeKey(r.URL)
+			if _, ok := after[key]; !ok && before[key].Rank == r.Rank {
+				diff.Dropped = append(diff.Dropped, r)
+			}
+		}
+	}
+	return diff
+}
+
+// rankedByURL maps the normalized URL of every result to its best ranked result.
+func rankedByURL(results []Result) map[string]Result {
+	m := make(map[string]Result, len(results))
+	for _, r := range results {
+		key := dedupeKey(r.URL)
+		if best, ok := m[key]; !ok || r.Rank < best.Rank {
+			m[key] = r
+		}
+	}
+	return m
+}
+
+// MemorySnapshotStore is a SnapshotStore keeping snapshots in memory.
+type MemorySnapshotStore struct {
+	mu        sync.Mutex
+	snapshots map[string]*Snapshot
+}
+
+// NewMemorySnapshotStore returns an empty MemorySnapshotStore.
+func NewMemorySnapshotStore() *MemorySnapshotStore {
+	return &MemorySnapshotStore{snapshots: map[string]*Snapshot{}}
+}
+
+// Load returns the snapshot stored for key, or nil when there is none.
+func (s *MemorySnapshotStore) Load(key string) (*Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshots[key], nil
+}
+
+// Save replaces the snapshot stored for snapshot.Key.
+func (s *MemorySnapshotStore) Save(snapshot *Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots[snapshot.Key] = snapshot
+	return nil
+}
+
+// JSONSnapshotStore is a SnapshotStore keeping the snapshots of all queries in a single JSON
+// file, an object keyed by query. The file is rewritten on every Save.
+type JSONSnapshotStore struct {
+	path string
+
+	mu        sync.Mutex
+	snapshots map[string]*Snapshot
+}
+
+// NewJSONSnapshotStore returns a JSONSnapshotStore backed by the file at path, loading the
+// snapshots it already holds. The file is created by the first Save.
+func NewJSONSnapshotStore(path string) (*JSONSnapshotStore, error) {
+	s := &JSONSnapshotStore{path: path, snapshots: map[string]*Snapshot{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.snapshots); err != nil {
+		return nil, fmt.Errorf("reading snapshots %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Load returns the snapshot stored for key, or nil when there is none.
+func (s *JSONSnapshotStore) Load(key string) (*Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshots[key], nil
+}
+
+// Save replaces the snapshot stored for snapshot.Key and rewrites the file. The file is
+// replaced atomically, so a crash never leaves it half written.
+func (s *JSONSnapshotStore) Save(snapshot *Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshots := make(map[string]*Snapshot, len(s.snapshots)+1)
+	for key, value := range s.snapshots {
+		snapshots[key] = value
+	}
+	snapshots[snapshot.Key] = snapshot
+
+	data, err := json.MarshalIndent(snapshots, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	s.snapshots = snapshots
+	return nil
+}
@@ -0,0 +1,116 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	previous := &Snapshot{Key: "golang", Time: time.Unix(100, 0), Results: []Result{
+		{Rank: 1, URL: "https://go.dev/"},
+		{Rank: 2, URL: "https://example.com/a?utm_source=news"},
+		{Rank: 3, URL: "https://example.com/dropped"},
+		{Rank: 4, URL: "https://example.com/b"},
+	}}
+	current := &Snapshot{Key: "golang", Time: time.Unix(200, 0), Results: []Result{
+		{Rank: 1, URL: "https://go.dev/"},
+		{Rank: 2, URL: "https://example.com/b#top", Title: "B"},
+		{Rank: 3, URL: "https://example.com/new"},
+		{Rank: 4, URL: "https://example.com/a?utm_source=feed"},
+		{Rank: 5, URL: "https://example.com/b"},
+	}}
+
+	diff := DiffSnapshots(previous, current)
+
+	assert.Equal(t, "golang", diff.Key)
+	assert.Equal(t, time.Unix(100, 0), diff.Previous)
+	assert.Equal(t, []Result{{Rank: 3, URL: "https://example.com/new"}}, diff.New)
+	assert.Equal(t, []Result{{Rank: 3, URL: "https://example.com/dropped"}}, diff.Dropped)
+	assert.Equal(t, []RankChange{
+		{URL: "https://example.com/b#top", Title: "B", From: 4, To: 2},
+		{URL: "https://example.com/a?utm_source=feed", From: 2, To: 4},
+	}, diff.Moved)
+}
+
+func TestDiffSnapshotsFirstRun(t *testing.T) {
+	current := &Snapshot{Key: "golang", Results: []Result{{Rank: 1, URL: "https://go.dev/"}}}
+
+	diff := DiffSnapshots(nil, current)
+
+	assert.True(t, diff.Previous.IsZero())
+	assert.Equal(t, current.Results, diff.New)
+	assert.Empty(t, diff.Dropped)
+	assert.Empty(t, diff.Moved)
+}
+
+// fakeEngine registers an engine returning the next page of pages on every search.
+func fakeEngine(t *testing.T, pages ...[]Result) Engine {
+	t.Helper()
+	engine := Engine("fake-" + t.Name())
+	Engines[engine] = SearcherFunc(func(ctx context.Context, searchTerm string, opts ...SearchOptions) ([]Result, error) {
+		if len(pages) == 0 {
+			return nil, errors.New("no more pages")
+		}
+		page := pages[0]
+		pages = pages[1:]
+		return page, nil
+	})
+	t.Cleanup(func() { delete(Engines, engine) })
+	return engine
+}
+
+func TestMonitorRun(t *testing.T) {
+	engine := fakeEngine(t,
+		[]Result{{Rank: 1, URL: "https://go.dev/"}, {Rank: 2, URL: "https://example.com/"}},
+		[]Result{{Rank: 1, URL: "https://go.dev/"}, {Rank: 2, URL: "https://example.com/?gclid=1"}},
+		[]Result{{Rank: 1, URL: "https://example.com/"}, {Rank: 2, URL: "https://go.dev/"}},
+	)
+	store, err := NewJSONSnapshotStore(filepath.Join(t.TempDir(), "snapshots.json"))
+	assert.NoError(t, err)
+	monitor := NewMonitor(store)
+	var received []Diff
+	monitor.OnDiff = func(d Diff) { received = append(received, d) }
+	assert.NoError(t, monitor.Add(MonitorQuery{Term: "golang", Engine: engine}))
+
+	diffs, err := monitor.Run(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, diffs, 1)
+	assert.Len(t, diffs[0].New, 2)
+
+	// Only a tracking parameter changed
+	diffs, err = monitor.Run(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	diffs, err = monitor.Run(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, diffs, 1)
+	assert.Equal(t, []RankChange{
+		{URL: "https://example.com/", From: 2, To: 1},
+		{URL: "https://go.dev/", From: 1, To: 2},
+	}, diffs[0].Moved)
+	assert.Len(t, received, 2)
+
+	// A failed search keeps the last snapshot
+	_, err = monitor.Run(context.Background())
+	assert.ErrorContains(t, err, `monitor "golang"`)
+
+	reopened, err := NewJSONSnapshotStore(store.path)
+	assert.NoError(t, err)
+	snapshot, err := reopened.Load("golang")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/", snapshot.Results[0].URL)
+}
+
+func TestMonitorAddUnknownEngine(t *testing.T) {
+	monitor := NewMonitor(nil)
+
+	err := monitor.Add(MonitorQuery{Term: "golang", Engine: "altavista"})
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,117 @@
+package search
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// trackingParams are query parameters dropped by NormalizeURL. Entries ending in "_" are prefixes.
+var trackingParams = []string{"utm_", "gclid", "fbclid", "dclid", "msclkid"}
+
+// NormalizeURL returns a canonical form of rawURL so that URLs differing only in tracking junk
+// compare equal: the scheme and host are lowercased, default ports, fragments and tracking
+// parameters (utm_*, gclid, fbclid, ...) are removed, and a trailing slash is dropped from
+// non-root paths.
+func NormalizeURL(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", err
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return "", errors.New("not an absolute URL: " + rawURL)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	if len(u.Path) > 1 {
+		u.Path = strings.TrimRight(u.Path, "/")
+		u.RawPath = ""
+		if u.Path == "" {
+			u.Path = "/"
+		}
+	}
+
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			if isTrackingParam(key) {
+				query.Del(key)
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String(), nil
+}
+
+func isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	for _, param := range trackingParams {
+		if strings.HasSuffix(param, "_") {
+			if strings.HasPrefix(key, param) {
+				return true
+			}
+		} else if key == param {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupeKey is the normalized form of rawURL, or rawURL itself when it cannot be normalized.
+func dedupeKey(rawURL string) string {
+	if normalized, err := NormalizeURL(rawURL); err == nil {
+		return normalized
+	}
+	return rawURL
+}
+
+// cleanResults applies the NormalizeURLs and Dedupe options to results.
+// Ranks are renumbered from the first result's rank when results are dropped.
+func cleanResults(results []Result, opt SearchOptions) []Result {
+	if !opt.NormalizeURLs && !opt.Dedupe {
+		return results
+	}
+
+	cleaned := make([]Result, 0, len(results))
+	seen := map[string]bool{}
+	for _, r := range results {
+		key := dedupeKey(r.URL)
+		if opt.Dedupe {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		if opt.NormalizeURLs {
+			r.URL = key
+		}
+		cleaned = append(cleaned, r)
+	}
+
+	if len(cleaned) > 0 && len(cleaned) < len(results) {
+		first := results[0].Rank
+		for i := range cleaned {
+			cleaned[i].Rank = first + i
+		}
+	}
+	return cleaned
+}
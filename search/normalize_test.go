@@ -0,0 +1,74 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://Example.COM/Path", "https://example.com/Path"},
+		{"HTTPS://example.com:443/", "https://example.com/"},
+		{"http://example.com:80/a/", "http://example.com/a"},
+		{"http://example.com:8080/a", "http://example.com:8080/a"},
+		{"https://example.com/a#section", "https://example.com/a"},
+		{"https://example.com/a?utm_source=x&utm_medium=y&id=3&gclid=abc&fbclid=def", "https://example.com/a?id=3"},
+		{"https://example.com/a?b=2&a=1", "https://example.com/a?a=1&b=2"},
+		{"https://example.com", "https://example.com"},
+		{"https://[::1]:443/a", "https://[::1]/a"},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeURL(tt.in)
+		assert.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+}
+
+func TestNormalizeURLInvalid(t *testing.T) {
+	_, err := NormalizeURL("/relative/path")
+	assert.Error(t, err)
+
+	_, err = NormalizeURL("http://exa mple.com/%zz")
+	assert.Error(t, err)
+}
+
+func TestCleanResults(t *testing.T) {
+	results := []Result{
+		{Rank: 1, URL: "https://example.com/a?utm_source=google"},
+		{Rank: 2, URL: "https://EXAMPLE.com/a/"},
+		{Rank: 3, URL: "https://example.com/b#top"},
+	}
+
+	deduped := cleanResults(append([]Result(nil), results...), SearchOptions{Dedupe: true})
+	assert.Equal(t, []Result{
+		{Rank: 1, URL: "https://example.com/a?utm_source=google"},
+		{Rank: 2, URL: "https://example.com/b#top"},
+	}, deduped)
+
+	normalized := cleanResults(append([]Result(nil), results...), SearchOptions{NormalizeURLs: true, Dedupe: true})
+	assert.Equal(t, []Result{
+		{Rank: 1, URL: "https://example.com/a"},
+		{Rank: 2, URL: "https://example.com/b"},
+	}, normalized)
+
+	assert.Equal(t, results, cleanResults(results, SearchOptions{}))
+}
+
+func TestCollectPagesDedupeNormalizes(t *testing.T) {
+	fetch := func(page SearchOptions) ([]Result, error) {
+		return []Result{
+			{URL: "https://example.com/?utm_campaign=x"},
+			{URL: "https://example.com/" + string(rune('a'+page.Start))},
+		}, nil
+	}
+
+	results, err := collectPages(SearchOptions{Limit: 3, Dedupe: true}, true, fetch)
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+}
@@ -0,0 +1,95 @@
+package search
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/propro-productions/go-utils/doh"
+	"github.com/propro-productions/go-utils/internal/httpopts"
+	"github.com/propro-productions/go-utils/metrics"
+	"github.com/propro-productions/go-utils/proxy"
+)
+
+// Option sets how the requests of a search are made. It is the same type as
+// link_preview.Option, so a set of options can be built once and used for searches and
+// previews alike:
+//
+//	opts := []search.Option{search.WithUserAgent("MyBot/1.0"), search.WithProxy("http://10.0.0.1:8080")}
+//	results, err := search.SearchGoogle(ctx, "golang", search.NewOptions(opts...))
+//	scraper, err := link_preview.NewScraper(results[0].URL, opts...)
+type Option = httpopts.Option
+
+// WithUserAgent sets SearchOptions.UserAgent.
+func WithUserAgent(userAgent string) Option {
+	return httpopts.WithUserAgent(userAgent)
+}
+
+// WithTimeout sets SearchOptions.Timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return httpopts.WithTimeout(timeout)
+}
+
+// WithProxy sets SearchOptions.ProxyAddr.
+func WithProxy(proxyAddr string) Option {
+	return httpopts.WithProxy(proxyAddr)
+}
+
+// WithProxyPool sets SearchOptions.ProxyPool.
+func WithProxyPool(pool *proxy.Pool) Option {
+	return httpopts.WithProxyPool(pool)
+}
+
+// WithResolver sets SearchOptions.Resolver.
+func WithResolver(resolver *doh.Resolver) Option {
+	return httpopts.WithResolver(resolver)
+}
+
+// WithHTTPClient sets SearchOptions.HTTPClient.
+func WithHTTPClient(client *http.Client) Option {
+	return httpopts.WithHTTPClient(client)
+}
+
+// WithLogger sets SearchOptions.Logger.
+func WithLogger(logger Logger) Option {
+	return httpopts.WithLogger(logger)
+}
+
+// WithMetrics sets SearchOptions.Metrics.
+func WithMetrics(m metrics.Metrics) Option {
+	return httpopts.WithMetrics(m)
+}
+
+// NewOptions returns SearchOptions set by opts. The other fields can be set on the result.
+func NewOptions(opts ...Option) SearchOptions {
+	return SearchOptions{}.With(opts...)
+}
+
+// With returns a copy of o with opts applied. Settings that opts leave alone keep their value.
+func (o SearchOptions) With(opts ...Option) SearchOptions {
+	h := httpopts.Apply(opts)
+	if h.UserAgent != "" {
+		o.UserAgent = h.UserAgent
+	}
+	if h.Timeout > 0 {
+		o.Timeout = h.Timeout
+	}
+	if h.ProxyAddr != "" {
+		o.ProxyAddr = h.ProxyAddr
+	}
+	if h.ProxyPool != nil {
+		o.ProxyPool = h.ProxyPool
+	}
+	if h.Resolver != nil {
+		o.Resolver = h.Resolver
+	}
+	if h.HTTPClient != nil {
+		o.HTTPClient = h.HTTPClient
+	}
+	if h.Logger != nil {
+		o.Logger = h.Logger
+	}
+	if h.Metrics != nil {
+		o.Metrics = h.Metrics
+	}
+	return o
+}
@@ -0,0 +1,90 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/propro-productions/go-utils/doh"
+	"github.com/propro-productions/go-utils/link_preview"
+	"github.com/propro-productions/go-utils/proxy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchOptionsWith(t *testing.T) {
+	pool, _ := proxy.NewPool("http://a:1")
+	logger := &recordingLogger{}
+
+	opt := SearchOptions{CountryCode: "de", UserAgent: "old", ProxyAddr: "http://kept:1"}.With(
+		WithUserAgent("new"),
+		WithTimeout(time.Second),
+		WithProxyPool(pool),
+		WithLogger(logger),
+		nil,
+	)
+
+	assert.Equal(t, "de", opt.CountryCode)
+	assert.Equal(t, "new", opt.UserAgent)
+	assert.Equal(t, time.Second, opt.Timeout)
+	assert.Equal(t, "http://kept:1", opt.ProxyAddr)
+	assert.Same(t, pool, opt.ProxyPool)
+	assert.Same(t, logger, opt.Logger)
+
+	assert.Equal(t, SearchOptions{ProxyAddr: "http://p:1"}, NewOptions(WithProxy("http://p:1")))
+}
+
+func TestNewHTTPClientTimeout(t *testing.T) {
+	client, err := newHTTPClient(NewOptions(WithTimeout(time.Second)))
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, client.Timeout)
+}
+
+func TestNewHTTPClientResolver(t *testing.T) {
+	resolver, _ := doh.NewResolver(doh.CloudflareURL)
+	opt := NewOptions(WithResolver(resolver), WithProxy("http://10.0.0.1:8080"))
+	assert.Same(t, resolver, opt.Resolver)
+
+	client, err := newHTTPClient(opt)
+
+	assert.NoError(t, err)
+	transport := client.Transport.(*http.Transport)
+	assert.NotNil(t, transport.DialContext)
+	proxyURL, _ := transport.Proxy(&http.Request{})
+	assert.Equal(t, "10.0.0.1:8080", proxyURL.Host)
+}
+
+func TestOptionsSharedWithLinkPreview(t *testing.T) {
+	results, err := os.ReadFile(filepath.Join("testdata", "google_results.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, rt := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+		if r.URL.Path == "/search" {
+			w.Write(results)
+			return
+		}
+		w.Write([]byte(`<html><head><title>Preview</title></head></html>`))
+	})
+	opts := []Option{WithHTTPClient(client), WithUserAgent("shared-agent/1.0"), WithTimeout(5 * time.Second)}
+
+	_, err = SearchGoogle(context.Background(), "golang", NewOptions(opts...))
+	assert.NoError(t, err)
+
+	scraper, err := link_preview.NewScraper("http://preview.invalid/page", opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, scraper.RequestTimeout)
+	scraper.IgnoreRobots = true
+	doc, err := scraper.GetLinkPreviewItems()
+	assert.NoError(t, err)
+	assert.Equal(t, "Preview", doc.Metadata.Title)
+
+	assert.Len(t, rt.requests, 2)
+	for _, req := range rt.requests {
+		assert.Equal(t, "shared-agent/1.0", req.Header.Get("User-Agent"), req.URL.String())
+	}
+}
@@ -0,0 +1,160 @@
+package search
+
+import (
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// HeaderProfile is the set of headers one browser sends with a navigation request. Sending
+// a User-Agent along with the Accept headers and client hints of the same browser looks
+// less like a bot than a User-Agent alone.
+type HeaderProfile struct {
+
+	// Name identifies the profile in a SearchError, e.g. "chrome-windows".
+	Name string
+
+	// UserAgent is sent as User-Agent.
+	UserAgent string
+
+	// Header holds the other headers, such as Accept and the sec-ch-ua client hints.
+	// Accept-Encoding is best left out, so that Go still decompresses gzip responses by
+	// itself. Without an Accept-Language, one is built from the LanguageCode of the search,
+	// so that the browser seems to speak the language of the results it asks for.
+	Header http.Header
+}
+
+// ProfileRotation is how a HeaderProfile is picked with SearchOptions.RotateUserAgent.
+type ProfileRotation int
+
+const (
+	// RotateRandom picks a profile at random.
+	RotateRandom ProfileRotation = iota
+
+	// RotateRoundRobin picks the profiles in turn. The turn is shared by all searches.
+	RotateRoundRobin
+)
+
+const (
+	chromeAccept  = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"
+	firefoxAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+)
+
+// DefaultProfiles are the profiles picked from with RotateUserAgent when
+// SearchOptions.Profiles is not set: current desktop versions of Chrome, Edge, Firefox and
+// Safari. They have no Accept-Language, which follows the LanguageCode of the search.
+var DefaultProfiles = []HeaderProfile{
+	{
+		Name:      "chrome-windows",
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/140.0.0.0 Safari/537.36",
+		Header: http.Header{
+			"Accept":             {chromeAccept},
+			"Sec-Ch-Ua":          {`"Chromium";v="140", "Not=A?Brand";v="24", "Google Chrome";v="140"`},
+			"Sec-Ch-Ua-Mobile":   {"?0"},
+			"Sec-Ch-Ua-Platform": {`"Windows"`},
+		},
+	},
+	{
+		Name:      "chrome-macos",
+		UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/140.0.0.0 Safari/537.36",
+		Header: http.Header{
+			"Accept":             {chromeAccept},
+			"Sec-Ch-Ua":          {`"Chromium";v="140", "Not=A?Brand";v="24", "Google Chrome";v="140"`},
+			"Sec-Ch-Ua-Mobile":   {"?0"},
+			"Sec-Ch-Ua-Platform": {`"macOS"`},
+		},
+	},
+	{
+		Name:      "edge-windows",
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/140.0.0.0 Safari/537.36 Edg/140.0.0.0",
+		Header: http.Header{
+			"Accept":             {chromeAccept},
+			"Sec-Ch-Ua":          {`"Chromium";v="140", "Not=A?Brand";v="24", "Microsoft Edge";v="140"`},
+			"Sec-Ch-Ua-Mobile":   {"?0"},
+			"Sec-Ch-Ua-Platform": {`"Windows"`},
+		},
+	},
+	{
+		// Firefox and Safari send no client hints
+		Name:      "firefox-windows",
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:143.0) Gecko/20100101 Firefox/143.0",
+		Header: http.Header{
+			"Accept": {firefoxAccept},
+		},
+	},
+	{
+		Name:      "safari-macos",
+		UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.6 Safari/605.1.15",
+		Header: http.Header{
+			"Accept": {firefoxAccept},
+		},
+	},
+}
+
+// profileTurn is the index of the next profile with RotateRoundRobin.
+var profileTurn uint32
+
+// pickProfile returns the profile of a search with opt.RotateUserAgent, or nil without it.
+func pickProfile(opt SearchOptions) *HeaderProfile {
+	if !opt.RotateUserAgent {
+		return nil
+	}
+	profiles := opt.Profiles
+	if len(profiles) == 0 {
+		profiles = DefaultProfiles
+	}
+	var i int
+	if opt.ProfileRotation == RotateRoundRobin {
+		i = int((atomic.AddUint32(&profileTurn, 1) - 1) % uint32(len(profiles)))
+	} else {
+		i = rand.Intn(len(profiles))
+	}
+	profile := profiles[i]
+	return &profile
+}
+
+// setHeaders sets the User-Agent of req and, with RotateUserAgent, the other headers of the
+// profile of the search.
+func setHeaders(req *http.Request, opt SearchOptions) {
+	if opt.profile != nil {
+		for key, values := range opt.profile.Header {
+			req.Header[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+		}
+		if req.Header.Get("Accept-Language") == "" {
+			req.Header.Set("Accept-Language", acceptLanguage(opt.LanguageCode))
+		}
+	}
+	req.Header.Set("User-Agent", opt.UserAgent)
+}
+
+// acceptLanguage returns the Accept-Language of a browser set to languageCode, such as
+// "de" or "pt-BR", falling back to English as browsers do: "de,en-US;q=0.9,en;q=0.8".
+// Without languageCode it is the header of an American English browser.
+func acceptLanguage(languageCode string) string {
+	base, region := strings.ToLower(languageCode), ""
+	if i := strings.IndexAny(base, "-_"); i >= 0 {
+		base, region = base[:i], strings.ToUpper(base[i+1:])
+	}
+	if base == "" {
+		base = "en"
+	}
+	if base == "en" {
+		if region == "" {
+			region = "US"
+		}
+		return "en-" + region + ",en;q=0.9"
+	}
+	if region == "" {
+		return base + ",en-US;q=0.9,en;q=0.8"
+	}
+	return base + "-" + region + "," + base + ";q=0.9,en-US;q=0.8,en;q=0.7"
+}
+
+// profileName is the name of the profile of the search, if any.
+func (o SearchOptions) profileName() string {
+	if o.profile == nil {
+		return ""
+	}
+	return o.profile.Name
+}
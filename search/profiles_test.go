@@ -0,0 +1,142 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testProfiles = []HeaderProfile{
+	{Name: "first", UserAgent: "first-agent", Header: http.Header{"Accept-Language": {"de-DE"}, "sec-ch-ua-platform": {`"Linux"`}}},
+	{Name: "second", UserAgent: "second-agent", Header: http.Header{"Accept-Language": {"fr-FR"}}},
+}
+
+func TestRotateUserAgentKeepsProfileAcrossRetries(t *testing.T) {
+	fixture := serveFixture(t, "google_results.html")
+	requests := 0
+	client, rt := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fixture(w, r)
+	})
+
+	_, err := SearchGoogleAll(context.Background(), "golang", 6, SearchOptions{
+		HTTPClient:      client,
+		RotateUserAgent: true,
+		Profiles:        testProfiles,
+		MaxRetries:      1,
+		RetryBackoff:    time.Millisecond,
+	})
+
+	assert.NoError(t, err)
+	// A failed and a retried request for the first page, one for the second
+	if assert.Len(t, rt.requests, 3) {
+		agent := rt.requests[0].Header.Get("User-Agent")
+		assert.Contains(t, []string{"first-agent", "second-agent"}, agent)
+		for _, req := range rt.requests {
+			assert.Equal(t, agent, req.Header.Get("User-Agent"))
+			assert.Equal(t, rt.requests[0].Header.Get("Accept-Language"), req.Header.Get("Accept-Language"))
+		}
+	}
+}
+
+func TestRotateUserAgentRoundRobin(t *testing.T) {
+	client, rt := newTestClient(t, serveFixture(t, "google_results.html"))
+	opt := SearchOptions{HTTPClient: client, RotateUserAgent: true, Profiles: testProfiles, ProfileRotation: RotateRoundRobin}
+
+	for i := 0; i < 2; i++ {
+		_, err := SearchGoogle(context.Background(), "golang", opt)
+		assert.NoError(t, err)
+	}
+
+	if assert.Len(t, rt.requests, 2) {
+		assert.NotEqual(t, rt.requests[0].Header.Get("User-Agent"), rt.requests[1].Header.Get("User-Agent"))
+		for _, req := range rt.requests {
+			if req.Header.Get("User-Agent") == "first-agent" {
+				assert.Equal(t, "de-DE", req.Header.Get("Accept-Language"))
+				assert.Equal(t, `"Linux"`, req.Header.Get("Sec-Ch-Ua-Platform"))
+			} else {
+				assert.Equal(t, "fr-FR", req.Header.Get("Accept-Language"))
+				assert.Empty(t, req.Header.Get("Sec-Ch-Ua-Platform"))
+			}
+		}
+	}
+}
+
+func TestRotateUserAgentDefaultProfiles(t *testing.T) {
+	client, rt := newTestClient(t, serveFixture(t, "google_results.html"))
+
+	_, err := SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client, RotateUserAgent: true, UserAgent: "ignored"})
+
+	assert.NoError(t, err)
+	var agents []string
+	for _, profile := range DefaultProfiles {
+		assert.NotEmpty(t, profile.Name)
+		assert.NotEmpty(t, profile.Header.Get("Accept"))
+		agents = append(agents, profile.UserAgent)
+	}
+	if assert.Len(t, rt.requests, 1) {
+		assert.Contains(t, agents, rt.requests[0].Header.Get("User-Agent"))
+		assert.NotEmpty(t, rt.requests[0].Header.Get("Accept"))
+	}
+}
+
+func TestRotateUserAgentAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		languageCode string
+		want         string
+	}{
+		{"", "en-US,en;q=0.9"},
+		{"en", "en-US,en;q=0.9"},
+		{"en-GB", "en-GB,en;q=0.9"},
+		{"de", "de,en-US;q=0.9,en;q=0.8"},
+		{"pt-BR", "pt-BR,pt;q=0.9,en-US;q=0.8,en;q=0.7"},
+		{"zh_tw", "zh-TW,zh;q=0.9,en-US;q=0.8,en;q=0.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.languageCode, func(t *testing.T) {
+			client, rt := newTestClient(t, serveFixture(t, "google_results.html"))
+
+			_, err := SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client, RotateUserAgent: true, LanguageCode: tt.languageCode})
+
+			assert.NoError(t, err)
+			if assert.Len(t, rt.requests, 1) {
+				assert.Equal(t, tt.want, rt.requests[0].Header.Get("Accept-Language"))
+			}
+		})
+	}
+}
+
+func TestSearchErrorReportsProfile(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	_, err := SearchBing(context.Background(), "golang", SearchOptions{HTTPClient: client, RotateUserAgent: true, Profiles: testProfiles[:1]})
+
+	var searchErr *SearchError
+	if assert.True(t, errors.As(err, &searchErr)) {
+		assert.Equal(t, "first", searchErr.Profile)
+		assert.Contains(t, searchErr.Error(), "returned status 429 as first")
+	}
+}
+
+func TestSearchWithoutRotationSendsUserAgentOnly(t *testing.T) {
+	client, rt := newTestClient(t, serveFixture(t, "google_results.html"))
+
+	_, err := SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client})
+
+	assert.NoError(t, err)
+	if assert.Len(t, rt.requests, 1) {
+		assert.Equal(t, defaultUserAgent, rt.requests[0].Header.Get("User-Agent"))
+		assert.Empty(t, rt.requests[0].Header.Get("Accept-Language"))
+	}
+}
@@ -0,0 +1,153 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// QueryBuilder assembles a search term from Google search operators, quoting values as needed.
+// The zero value is an empty query.
+//
+//	q := new(QueryBuilder).Site("example.com").InTitle("pricing").Exclude("inurl:blog")
+//	term, err := q.Build() // site:example.com intitle:pricing -inurl:blog
+//
+// Methods record the first invalid argument, which Build and Err report; String leaves out
+// invalid parts.
+type QueryBuilder struct {
+	parts []string
+	err   error
+}
+
+// Term adds words that are searched as is.
+func (q *QueryBuilder) Term(s string) *QueryBuilder {
+	return q.add("Term", s, func(v string) string { return v })
+}
+
+// Site restricts results to domain and its subdomains. A scheme or path is dropped.
+func (q *QueryBuilder) Site(domain string) *QueryBuilder {
+	domain = strings.TrimSpace(domain)
+	if i := strings.Index(domain, "://"); i >= 0 {
+		domain = domain[i+3:]
+	}
+	domain = strings.TrimRight(domain, "/")
+	if strings.IndexFunc(domain, unicode.IsSpace) >= 0 || strings.Contains(domain, `"`) {
+		return q.fail("Site", domain)
+	}
+	return q.add("Site", domain, func(v string) string { return "site:" + v })
+}
+
+// Exclude drops results matching term, which may itself be an operator such as "inurl:blog".
+func (q *QueryBuilder) Exclude(term string) *QueryBuilder {
+	return q.add("Exclude", term, func(v string) string { return "-" + quoteIfNeeded(v) })
+}
+
+// ExactPhrase requires s to appear verbatim.
+func (q *QueryBuilder) ExactPhrase(s string) *QueryBuilder {
+	return q.add("ExactPhrase", s, quote)
+}
+
+// InTitle requires s to appear in the title of results.
+func (q *QueryBuilder) InTitle(s string) *QueryBuilder {
+	return q.add("InTitle", s, func(v string) string { return "intitle:" + quoteIfNeeded(v) })
+}
+
+// InURL requires s to appear in the URL of results.
+func (q *QueryBuilder) InURL(s string) *QueryBuilder {
+	return q.add("InURL", s, func(v string) string { return "inurl:" + quoteIfNeeded(v) })
+}
+
+// FileType restricts results to documents with the extension ext, e.g. "pdf" or ".pdf".
+func (q *QueryBuilder) FileType(ext string) *QueryBuilder {
+	ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+	for _, r := range ext {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return q.fail("FileType", ext)
+		}
+	}
+	return q.add("FileType", ext, func(v string) string { return "filetype:" + v })
+}
+
+// OrGroup matches results containing any of terms. Empty terms are invalid.
+func (q *QueryBuilder) OrGroup(terms ...string) *QueryBuilder {
+	if len(terms) == 0 {
+		return q.fail("OrGroup", "")
+	}
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		term = normalizeQueryValue(term)
+		if term == "" {
+			return q.fail("OrGroup", term)
+		}
+		quoted[i] = quoteIfNeeded(term)
+	}
+	if len(quoted) == 1 {
+		q.parts = append(q.parts, quoted[0])
+	} else {
+		q.parts = append(q.parts, "("+strings.Join(quoted, " OR ")+")")
+	}
+	return q
+}
+
+// String returns the query, leaving out invalid parts.
+func (q *QueryBuilder) String() string {
+	return strings.Join(q.parts, " ")
+}
+
+// Err returns the first invalid argument, or nil.
+func (q *QueryBuilder) Err() error {
+	return q.err
+}
+
+// Build returns the query, or an error wrapping ErrInvalidQuery if any argument was invalid
+// or the query is empty.
+func (q *QueryBuilder) Build() (string, error) {
+	if q.err != nil {
+		return "", q.err
+	}
+	if len(q.parts) == 0 {
+		return "", fmt.Errorf("%w: empty query", ErrInvalidQuery)
+	}
+	return q.String(), nil
+}
+
+func (q *QueryBuilder) add(op, value string, format func(string) string) *QueryBuilder {
+	value = normalizeQueryValue(value)
+	if value == "" {
+		return q.fail(op, value)
+	}
+	q.parts = append(q.parts, format(value))
+	return q
+}
+
+func (q *QueryBuilder) fail(op, value string) *QueryBuilder {
+	if q.err == nil {
+		q.err = fmt.Errorf("%w: %s(%q)", ErrInvalidQuery, op, value)
+	}
+	return q
+}
+
+// normalizeQueryValue collapses whitespace and removes double quotes. Google has no way of
+// escaping a quote inside a phrase, so nested quotes are dropped rather than ending the phrase early.
+func normalizeQueryValue(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case '"', '“', '”', '„':
+			return ' '
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func quote(s string) string {
+	return `"` + s + `"`
+}
+
+// quoteIfNeeded quotes s if it consists of more than one word.
+func quoteIfNeeded(s string) string {
+	if strings.Contains(s, " ") {
+		return quote(s)
+	}
+	return s
+}
@@ -0,0 +1,76 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryBuilder(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *QueryBuilder
+		want  string
+	}{
+		{"site", new(QueryBuilder).Site("example.com"), "site:example.com"},
+		{"site with scheme", new(QueryBuilder).Site("https://example.com/"), "site:example.com"},
+		{"exact phrase", new(QueryBuilder).ExactPhrase("go  modules"), `"go modules"`},
+		{"nested quotes", new(QueryBuilder).ExactPhrase(`the "best" language`), `"the best language"`},
+		{"smart quotes", new(QueryBuilder).InTitle("“pricing” plans"), `intitle:"pricing plans"`},
+		{"exclude word", new(QueryBuilder).Term("golang").Exclude("java"), "golang -java"},
+		{"exclude phrase", new(QueryBuilder).Exclude("hello world"), `-"hello world"`},
+		{"exclude operator", new(QueryBuilder).Exclude("inurl:blog"), "-inurl:blog"},
+		{"in url", new(QueryBuilder).InURL("docs"), "inurl:docs"},
+		{"file type", new(QueryBuilder).FileType(".PDF"), "filetype:pdf"},
+		{"or group", new(QueryBuilder).OrGroup("go", "golang", "go lang"), `(go OR golang OR "go lang")`},
+		{"single or", new(QueryBuilder).OrGroup("go"), "go"},
+		{
+			"combined",
+			new(QueryBuilder).Site("example.com").InTitle("pricing").Exclude("inurl:blog"),
+			"site:example.com intitle:pricing -inurl:blog",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.query.String())
+			got, err := tt.query.Build()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestQueryBuilderInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *QueryBuilder
+	}{
+		{"empty query", new(QueryBuilder)},
+		{"empty site", new(QueryBuilder).Site("")},
+		{"site with spaces", new(QueryBuilder).Site("example .com")},
+		{"blank phrase", new(QueryBuilder).ExactPhrase(`  "" `)},
+		{"empty exclude", new(QueryBuilder).Exclude(" ")},
+		{"empty title", new(QueryBuilder).InTitle("")},
+		{"empty url", new(QueryBuilder).InURL("")},
+		{"bad file type", new(QueryBuilder).FileType("p df")},
+		{"empty file type", new(QueryBuilder).FileType(".")},
+		{"empty or group", new(QueryBuilder).OrGroup()},
+		{"empty or term", new(QueryBuilder).OrGroup("go", "")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.query.Build()
+			assert.ErrorIs(t, err, ErrInvalidQuery)
+		})
+	}
+}
+
+func TestQueryBuilderKeepsValidParts(t *testing.T) {
+	q := new(QueryBuilder).Term("golang").InTitle("").Site("go.dev")
+
+	assert.Equal(t, "golang site:go.dev", q.String())
+	assert.ErrorIs(t, q.Err(), ErrInvalidQuery)
+	assert.Contains(t, q.Err().Error(), "InTitle")
+}
@@ -0,0 +1,143 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	// DefaultRankDepth is how many results FindDomainRank looks at when maxDepth is zero.
+	DefaultRankDepth = 100
+
+	// defaultRankPageSize is how many results FindDomainRank requests at a time when Limit
+	// is not set, the size of a page of Google results.
+	defaultRankPageSize = 10
+)
+
+// RankResult is where a domain ranks for a search, as found by FindDomainRank.
+type RankResult struct {
+	Domain     string
+	SearchTerm string
+
+	// Rank is the position of the first result of the domain, from 1.
+	Rank int
+
+	// URL is the first result of the domain.
+	URL string
+
+	// Matches are all the results of the domain on the pages fetched, the first one
+	// included, with their overall rank.
+	Matches []Result
+
+	// Searched is how many results were looked at.
+	Searched int
+}
+
+// FindDomainRank searches Google for searchTerm and returns where domain first ranks among
+// the top maxDepth results, DefaultRankDepth if zero. Results on domain or on one of its
+// subdomains match, so blog.example.co.uk matches example.co.uk but example.co.uk does not
+// match co.uk, which is rejected with ErrInvalidQuery for being a public suffix.
+//
+// Results are requested opts.Limit at a time, 10 by default, and no page is requested
+// after the one holding the first match. When the domain is not found, the error wraps
+// ErrNotRanked.
+func FindDomainRank(ctx context.Context, domain string, searchTerm string, maxDepth int, opts ...SearchOptions) (*RankResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	domain, err := rankDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+	if maxDepth <= 0 {
+		maxDepth = DefaultRankDepth
+	}
+
+	opt := searchOptions(opts)
+	if err := validateOptions(opt); err != nil {
+		return nil, err
+	}
+	pageSize := opt.Limit
+	if pageSize <= 0 {
+		pageSize = defaultRankPageSize
+	}
+	if pageSize > maxResultsPerPage {
+		pageSize = maxResultsPerPage
+	}
+
+	rank := &RankResult{Domain: domain, SearchTerm: searchTerm}
+	page := opt
+	page.OverLimit = false
+	for rank.Searched < maxDepth && len(rank.Matches) == 0 {
+		page.Limit = pageSize
+		if remaining := maxDepth - rank.Searched; remaining < page.Limit {
+			page.Limit = remaining
+		}
+
+		results, err := searchGooglePage(ctx, searchTerm, page)
+		// Past the last page Google serves an empty page rather than a "no match" notice.
+		if errors.Is(err, ErrNoResults) && rank.Searched > 0 {
+			break
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		if len(results) == 0 {
+			break
+		}
+
+		for _, r := range results {
+			if rank.Searched == maxDepth {
+				break
+			}
+			rank.Searched++
+			r.Rank = opt.Start + rank.Searched
+			if matchesDomain(r.URL, domain) {
+				rank.Matches = append(rank.Matches, r)
+			}
+		}
+		page.Start += len(results)
+	}
+
+	if len(rank.Matches) == 0 {
+		return nil, fmt.Errorf("search: %s for %q in the top %d results: %w", domain, searchTerm, rank.Searched, ErrNotRanked)
+	}
+	rank.Rank, rank.URL = rank.Matches[0].Rank, rank.Matches[0].URL
+	return rank, nil
+}
+
+// rankDomain returns the host of domain, which may be given as a URL, lowercased. Public
+// suffixes are rejected.
+func rankDomain(domain string) (string, error) {
+	host := strings.TrimSpace(domain)
+	if u, err := url.Parse(host); err == nil && u.Host != "" {
+		host = u.Hostname()
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" {
+		return "", fmt.Errorf("search: empty domain: %w", ErrInvalidQuery)
+	}
+	if _, err := publicsuffix.EffectiveTLDPlusOne(host); err != nil {
+		return "", fmt.Errorf("search: domain %q: %v: %w", domain, err, ErrInvalidQuery)
+	}
+	return host, nil
+}
+
+// matchesDomain reports whether the host of rawURL is domain or one of its subdomains.
+func matchesDomain(rawURL, domain string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
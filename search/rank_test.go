@@ -0,0 +1,112 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rankingServer serves Google pages of total results, the result at position p linking to
+// urls[p] if set and to a filler site otherwise.
+func rankingServer(total int, urls map[int]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		num, _ := strconv.Atoi(r.URL.Query().Get("num"))
+
+		var b strings.Builder
+		b.WriteString(`<html><body><div id="search">`)
+		for p := start + 1; p <= start+num && p <= total; p++ {
+			link, ok := urls[p]
+			if !ok {
+				link = fmt.Sprintf("https://filler%d.example.org/", p)
+			}
+			fmt.Fprintf(&b, `<div class="g"><div class="yuRUbf"><a href="%s"><h3>Result %d</h3></a></div></div>`, link, p)
+		}
+		b.WriteString(`</div></body></html>`)
+		w.Write([]byte(b.String()))
+	}
+}
+
+func TestFindDomainRank(t *testing.T) {
+	client, rt := newTestClient(t, rankingServer(100, map[int]string{
+		14: "https://blog.example.co.uk/post",
+		17: "https://example.co.uk/",
+		35: "https://www.example.co.uk/later",
+	}))
+
+	rank, err := FindDomainRank(context.Background(), "example.co.uk", "widgets", 50, SearchOptions{HTTPClient: client})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 14, rank.Rank)
+	assert.Equal(t, "https://blog.example.co.uk/post", rank.URL)
+	if assert.Len(t, rank.Matches, 2) {
+		assert.Equal(t, 17, rank.Matches[1].Rank)
+	}
+	assert.Equal(t, 20, rank.Searched)
+	// The search stops on the page of the first match
+	assert.Len(t, rt.requests, 2)
+	assert.Equal(t, "10", rt.requests[1].URL.Query().Get("start"))
+}
+
+func TestFindDomainRankNotRanked(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    int
+		urls     map[int]string
+		requests int
+		searched int
+		lastNum  string
+	}{
+		{"beyond depth", 100, map[int]string{26: "https://example.com/"}, 3, 25, "5"},
+		{"similar domains", 100, map[int]string{3: "https://notexample.com/", 5: "https://example.com.evil.org/"}, 3, 25, "5"},
+		{"past the last page", 12, nil, 3, 12, "10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, rt := newTestClient(t, rankingServer(tt.total, tt.urls))
+
+			rank, err := FindDomainRank(context.Background(), "example.com", "widgets", 25, SearchOptions{HTTPClient: client})
+
+			assert.Nil(t, rank)
+			assert.True(t, errors.Is(err, ErrNotRanked), "%v", err)
+			assert.Contains(t, err.Error(), fmt.Sprintf("top %d results", tt.searched))
+			assert.Len(t, rt.requests, tt.requests)
+			assert.Equal(t, tt.lastNum, rt.requests[len(rt.requests)-1].URL.Query().Get("num"))
+		})
+	}
+}
+
+func TestFindDomainRankPageSize(t *testing.T) {
+	client, rt := newTestClient(t, rankingServer(100, map[int]string{60: "https://shop.example.com/"}))
+
+	rank, err := FindDomainRank(context.Background(), "https://example.com/", "widgets", 0, SearchOptions{HTTPClient: client, Limit: 100})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 60, rank.Rank)
+	assert.Equal(t, "example.com", rank.Domain)
+	assert.Len(t, rt.requests, 1)
+	assert.Equal(t, "100", rt.requests[0].URL.Query().Get("num"))
+}
+
+func TestFindDomainRankInvalidDomain(t *testing.T) {
+	for _, domain := range []string{"", "co.uk", "com"} {
+		_, err := FindDomainRank(context.Background(), domain, "widgets", 10)
+
+		assert.True(t, errors.Is(err, ErrInvalidQuery), "%q: %v", domain, err)
+	}
+}
+
+func TestMatchesDomain(t *testing.T) {
+	assert.True(t, matchesDomain("https://example.co.uk/", "example.co.uk"))
+	assert.True(t, matchesDomain("https://Blog.Example.co.uk./x", "example.co.uk"))
+	assert.False(t, matchesDomain("https://myexample.co.uk/", "example.co.uk"))
+	assert.False(t, matchesDomain("https://example.co.uk.evil.com/", "example.co.uk"))
+	assert.False(t, matchesDomain("::", "example.co.uk"))
+}
@@ -0,0 +1,269 @@
+package search
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrNotRecorded is returned by a Recorder in replay mode for a request it has no
+// recording of.
+var ErrNotRecorded = errors.New("no recording for request")
+
+// RecorderMode is whether a Recorder makes requests or replays them.
+type RecorderMode int
+
+const (
+	// ModeReplay serves recorded responses and fails every other request with ErrNotRecorded.
+	ModeReplay RecorderMode = iota
+
+	// ModeRecord makes the requests and saves their responses, replacing earlier recordings.
+	ModeRecord
+
+	// ModeReplayOrRecord serves recorded responses and records the requests it has none of.
+	ModeReplayOrRecord
+)
+
+// sensitiveHeaders are left out of recordings.
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "Set-Cookie", "X-Api-Key"}
+
+// sensitiveParams are query parameters whose values are replaced by "REDACTED" in
+// recordings. Requests are matched after the replacement, so a recording is found whatever
+// the key used.
+var sensitiveParams = []string{"key", "api_key", "apikey", "access_token", "token"}
+
+// Recorder is an http.RoundTripper that records responses to files and replays them, for
+// tests that must not depend on live requests. Use it through the HTTPClient of
+// SearchOptions or of link_preview.Scraper:
+//
+//	rec := search.NewRecorder("testdata/cassettes", search.ModeReplay)
+//	results, err := search.SearchGoogle(ctx, "golang", search.SearchOptions{HTTPClient: rec.Client()})
+//
+// Every request is saved as a JSON file of its own, named after its host and a hash of its
+// method, body and canonical URL: the scheme and host in lower case, without the default port or
+// fragment and with the query parameters sorted. Bodies are stored as text, so that
+// recordings can be edited by hand, or base64 encoded if they are not UTF-8. Cookies and
+// credentials, whether in headers or in query parameters such as key, are left out.
+//
+// A Recorder is safe for concurrent use.
+type Recorder struct {
+
+	// Dir is the directory holding the recordings.
+	Dir string
+
+	// Mode is whether requests are made or replayed. Default: ModeReplay.
+	Mode RecorderMode
+
+	// Transport makes the requests that are recorded. Default: http.DefaultTransport.
+	Transport http.RoundTripper
+
+	mu sync.Mutex
+}
+
+// Recording is the content of a recording file.
+type Recording struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the request of a Recording.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+}
+
+// RecordedResponse is the response of a Recording. Body holds a UTF-8 body and BodyBase64
+// any other.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 string      `json:"body_base64,omitempty"`
+}
+
+// NewRecorder returns a Recorder keeping its recordings in dir.
+func NewRecorder(dir string, mode RecorderMode) *Recorder {
+	return &Recorder{Dir: dir, Mode: mode}
+}
+
+// Client returns a client making its requests through r.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip replays or records req, depending on Mode.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	canonical := canonicalRequestURL(req.URL)
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	path := filepath.Join(r.Dir, recordingName(req.Method, canonical, body))
+
+	if r.Mode != ModeRecord {
+		resp, err := r.replay(req, path)
+		if err == nil || !errors.Is(err, os.ErrNotExist) {
+			return resp, err
+		}
+		if r.Mode == ModeReplay {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, fmt.Errorf("recorder: %w: %s %s (%s)", ErrNotRecorded, req.Method, canonical, path)
+		}
+	}
+	return r.record(req, canonical, path)
+}
+
+func (r *Recorder) replay(req *http.Request, path string) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var recording Recording
+	if err := json.Unmarshal(data, &recording); err != nil {
+		return nil, fmt.Errorf("recorder: %s: %w", path, err)
+	}
+
+	body := []byte(recording.Response.Body)
+	if recording.Response.BodyBase64 != "" {
+		if body, err = base64.StdEncoding.DecodeString(recording.Response.BodyBase64); err != nil {
+			return nil, fmt.Errorf("recorder: %s: %w", path, err)
+		}
+	}
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	header := recording.Response.Header
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recording.Response.StatusCode, http.StatusText(recording.Response.StatusCode)),
+		StatusCode:    recording.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func (r *Recorder) record(req *http.Request, canonical, path string) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	recording := Recording{
+		Request:  RecordedRequest{Method: req.Method, URL: canonical, Header: redactHeader(req.Header)},
+		Response: RecordedResponse{StatusCode: resp.StatusCode, Header: redactHeader(resp.Header)},
+	}
+	if utf8.Valid(body) {
+		recording.Response.Body = string(body)
+	} else {
+		recording.Response.BodyBase64 = base64.StdEncoding.EncodeToString(body)
+	}
+	data, err := json.MarshalIndent(recording, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := os.MkdirAll(r.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("recorder: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return nil, fmt.Errorf("recorder: %w", err)
+	}
+	return resp, nil
+}
+
+// canonicalRequestURL returns u with a lower case scheme and host, without the default port,
+// user info or fragment, with its query parameters sorted and sensitive ones redacted.
+func canonicalRequestURL(u *url.URL) string {
+	c := *u
+	c.Scheme = strings.ToLower(c.Scheme)
+	c.Host = strings.ToLower(c.Host)
+	if port := c.Port(); (c.Scheme == "http" && port == "80") || (c.Scheme == "https" && port == "443") {
+		c.Host = c.Hostname()
+	}
+	c.User = nil
+	c.Fragment = ""
+	c.RawFragment = ""
+
+	query := c.Query()
+	for key := range query {
+		for _, param := range sensitiveParams {
+			if strings.EqualFold(key, param) {
+				query.Set(key, "REDACTED")
+			}
+		}
+	}
+	c.RawQuery = query.Encode()
+	return c.String()
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// recordingName is the file name of the recording of a request. The body is part of the
+// hash when there is one, so that forms posted to the same URL are recorded apart.
+func recordingName(method, canonical string, body []byte) string {
+	key := method + " " + canonical
+	if len(body) > 0 {
+		bodySum := sha1.Sum(body)
+		key += " " + hex.EncodeToString(bodySum[:])
+	}
+	sum := sha1.Sum([]byte(key))
+	host := "request"
+	if u, err := url.Parse(canonical); err == nil && u.Hostname() != "" {
+		host = unsafeFileChars.ReplaceAllString(u.Hostname(), "_")
+	}
+	return host + "-" + hex.EncodeToString(sum[:6]) + ".json"
+}
+
+// redactHeader returns a copy of header without sensitiveHeaders.
+func redactHeader(header http.Header) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+	redacted := header.Clone()
+	for _, key := range sensitiveHeaders {
+		redacted.Del(key)
+	}
+	return redacted
+}
@@ -0,0 +1,200 @@
+package search
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// SearchResponse holds the results of a search together with the metadata of the results page.
+type SearchResponse struct {
+	Results []Result `json:"results"`
+
+	// Engine is the backend that produced the results: EngineGoogle, or EngineCustomSearch
+	// for the Custom Search JSON API.
+	Engine Engine `json:"engine,omitempty"`
+
+	// TotalResults is Google's estimate of the number of matching documents ("About 1,230,000 results").
+	// It is 0 when the page does not show it.
+	TotalResults int64 `json:"total_results"`
+
+	// SearchTime is the search duration in seconds reported by Google, or 0.
+	SearchTime float64 `json:"search_time"`
+
+	// RelatedQueries lists the "related searches" shown at the bottom of the page.
+	RelatedQueries []string `json:"related_queries,omitempty"`
+
+	// FeaturedSnippet is the answer box shown above the results, or nil if there is none.
+	FeaturedSnippet *Snippet `json:"featured_snippet,omitempty"`
+
+	// Layout is the Name of the Layout the results were parsed with, if any.
+	Layout string `json:"layout,omitempty"`
+
+	// LanguageFiltered are the results dropped by SearchOptions.FilterLanguage, with their
+	// original ranks.
+	LanguageFiltered []FilteredResult `json:"language_filtered,omitempty"`
+
+	// EndOfResults reports that there are no more results, so that a loop paginating with
+	// Start can stop: with Limit, Google returned fewer results than requested; without it,
+	// the page has no link to a next page, or, on a page without page links, fewer than
+	// DefaultPageSize results. It is set before Dedupe, FilterLanguage and the like drop any
+	// result.
+	EndOfResults bool `json:"end_of_results,omitempty"`
+
+	// RawHTML is the body of the results page, set only with SearchOptions.ReturnRawHTML.
+	// When several pages are requested it is the body of the first one.
+	RawHTML string `json:"raw_html,omitempty"`
+
+	// noMatch reports that the page is Google's notice that the query matched no documents.
+	noMatch bool
+}
+
+// SearchGoogleFull behaves like SearchGoogle but also returns the metadata of the first results page.
+func SearchGoogleFull(ctx context.Context, searchTerm string, opts ...SearchOptions) (*SearchResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// Checking options
+	opt := searchOptions(opts)
+	if err := validateOptions(opt); err != nil {
+		return nil, err
+	}
+
+	var first *SearchResponse
+	fetch := func(page SearchOptions) ([]Result, error) {
+		resp, err := searchGoogleResponse(ctx, searchTerm, page)
+		if err != nil {
+			return nil, err
+		}
+		if first == nil {
+			first = resp
+		}
+		return resp.Results, nil
+	}
+
+	if opt.Limit <= 0 {
+		if _, err := fetch(opt); err != nil {
+			return nil, err
+		}
+		first.Results, first.LanguageFiltered = filterLanguage(filterFreshness(cleanResults(first.Results, opt), opt.MinFreshness, time.Now()), opt)
+		first.Engine = EngineGoogle
+		return first, nil
+	}
+
+	results, err := collectPages(opt, opt.OverLimit || opt.Dedupe, fetch)
+	if err != nil {
+		return nil, err
+	}
+	first.EndOfResults = len(results) < opt.Limit
+	first.Results, first.LanguageFiltered = filterLanguage(filterFreshness(cleanResults(results, opt), opt.MinFreshness, time.Now()), opt)
+	first.Engine = EngineGoogle
+	return first, nil
+}
+
+// parseResponse parses a results page with layouts and its metadata. start is the offset of
+// the page, its first result being ranked start+1.
+func parseResponse(r io.Reader, layouts []Layout, start int) (*SearchResponse, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &SearchResponse{}
+	resp.Results, resp.Layout = parseResultsDocument(doc, layouts, start)
+	resp.TotalResults, resp.SearchTime = parseResultStats(doc.Find("#result-stats").Text())
+	resp.RelatedQueries = parseRelatedQueries(doc)
+	resp.FeaturedSnippet = parseFeaturedSnippet(doc)
+	resp.noMatch = len(resp.Results) == 0 && isNoMatchPage(doc)
+	resp.EndOfResults = isLastPage(doc, len(resp.Results))
+	return resp, nil
+}
+
+// isLastPage reports whether doc, a page of results results, is the last one. Google fills
+// some of the slots of a page with other kinds of results, so a page with fewer than
+// DefaultPageSize results is only the last one when it has no page links to tell.
+func isLastPage(doc *goquery.Document, results int) bool {
+	if doc.Find("#pnnext").Length() > 0 {
+		return false
+	}
+	if doc.Find("#pnprev, table.AaVjTc").Length() > 0 {
+		return true
+	}
+	return results < DefaultPageSize
+}
+
+// isNoMatchPage reports whether doc is Google's notice that the query matched no documents,
+// in any language: the notice is a card above an empty #rso, which may be missing.
+func isNoMatchPage(doc *goquery.Document) bool {
+	if doc.Find("#rso").Children().Length() > 0 {
+		return false
+	}
+	if strings.TrimSpace(doc.Find("#topstuff .card-section").Text()) != "" {
+		return true
+	}
+	return strings.Contains(doc.Text(), noMatchMarker)
+}
+
+var (
+	// resultCountRegexp matches a number with any of the thousands separators Google uses.
+	resultCountRegexp = regexp.MustCompile(`\d[\d.,'\s\x{00a0}\x{202f}]*`)
+	searchTimeRegexp  = regexp.MustCompile(`\d+(?:[.,]\d+)?`)
+)
+
+// parseResultStats extracts the result count and search time from the result stats line,
+// e.g. "About 1,230,000 results (0.42 seconds)" or "Ungefähr 1.230.000 Ergebnisse (0,42 Sekunden)".
+func parseResultStats(text string) (int64, float64) {
+	text = strings.Replace(text, "（", "(", -1)
+	countText, timeText := text, ""
+	if i := strings.Index(text, "("); i >= 0 {
+		countText, timeText = text[:i], text[i:]
+	}
+
+	var total int64
+	// The count is the last number before the time, which skips prefixes like "Page 2 of about".
+	if matches := resultCountRegexp.FindAllString(countText, -1); len(matches) > 0 {
+		match := matches[len(matches)-1]
+		digits := strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, match)
+		total, _ = strconv.ParseInt(digits, 10, 64)
+	}
+
+	var seconds float64
+	if match := searchTimeRegexp.FindString(timeText); match != "" {
+		seconds, _ = strconv.ParseFloat(strings.Replace(match, ",", ".", 1), 64)
+	}
+
+	return total, seconds
+}
+
+// relatedQuerySelectors are tried in order until one yields related searches.
+var relatedQuerySelectors = []string{
+	"#bres a.k8XOCe",
+	"#bres div.s75CSd",
+	"#bres a",
+	"div.AJLUJb a",
+}
+
+func parseRelatedQueries(doc *goquery.Document) []string {
+	for _, selector := range relatedQuerySelectors {
+		var queries []string
+		doc.Find(selector).Each(func(i int, el *goquery.Selection) {
+			if q := strings.TrimSpace(el.Text()); q != "" {
+				queries = append(queries, q)
+			}
+		})
+		if len(queries) > 0 {
+			return queries
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseResponse(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "google_results.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	resp, err := parseResponse(f, DefaultLayouts, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Results, 3)
+	assert.Equal(t, int64(1230000000), resp.TotalResults)
+	assert.Equal(t, 0.42, resp.SearchTime)
+	assert.Equal(t, []string{"golang tutorial", "golang vs rust", "golang download"}, resp.RelatedQueries)
+}
+
+func TestParseResponseWithoutMetadata(t *testing.T) {
+	resp, err := parseResponse(strings.NewReader(`<html><body><div class="g"><a href="https://go.dev/"><h3>Go</h3></a></div></body></html>`), DefaultLayouts, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Results, 1)
+	assert.Zero(t, resp.TotalResults)
+	assert.Zero(t, resp.SearchTime)
+	assert.Nil(t, resp.RelatedQueries)
+}
+
+func TestParseResultStats(t *testing.T) {
+	tests := []struct {
+		text    string
+		total   int64
+		seconds float64
+	}{
+		{"About 1,230,000 results (0.42 seconds)", 1230000, 0.42},
+		{"Page 2 of about 1,230,000 results (0.42 seconds)", 1230000, 0.42},
+		{"1 result (0.20 seconds)", 1, 0.2},
+		{"Ungefähr 1.230.000 Ergebnisse (0,42 Sekunden)", 1230000, 0.42},
+		{"Environ 1 230 000 résultats (0,35 secondes)", 1230000, 0.35},
+		{"約 1,230,000 件 （0.31 秒）", 1230000, 0.31},
+		{"About 98 results", 98, 0},
+		{"", 0, 0},
+	}
+
+	for _, tt := range tests {
+		total, seconds := parseResultStats(tt.text)
+		assert.Equal(t, tt.total, total, tt.text)
+		assert.Equal(t, tt.seconds, seconds, tt.text)
+	}
+}
+
+func TestSearchGoogleFull(t *testing.T) {
+	client, _ := newTestClient(t, serveFixture(t, "google_results.html"))
+
+	resp, err := SearchGoogleFull(context.Background(), "golang", SearchOptions{HTTPClient: client})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Results, 3)
+	assert.Equal(t, int64(1230000000), resp.TotalResults)
+	assert.Len(t, resp.RelatedQueries, 3)
+}
+
+func TestParseFeaturedSnippet(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    *Snippet
+	}{
+		{
+			"google_featured_paragraph.html",
+			&Snippet{
+				Type:        SnippetParagraph,
+				Text:        "Go is a statically typed, compiled high-level programming language designed at Google by Robert Griesemer, Rob Pike, and Ken Thompson.",
+				SourceURL:   "https://en.wikipedia.org/wiki/Go_(programming_language)",
+				SourceTitle: "Go (programming language) - Wikipedia",
+			},
+		},
+		{
+			"google_featured_list.html",
+			&Snippet{
+				Type: SnippetList,
+				Text: "Download the installer for your platform.\nOpen the package file and follow the prompts.\nVerify the installation with go version.",
+				List: []string{
+					"Download the installer for your platform.",
+					"Open the package file and follow the prompts.",
+					"Verify the installation with go version.",
+				},
+				SourceURL:   "https://go.dev/doc/install",
+				SourceTitle: "Download and install - The Go Programming Language",
+			},
+		},
+		{
+			"google_featured_table.html",
+			&Snippet{
+				Type: SnippetTable,
+				Text: "Version\tReleased\nGo 1.20\tFebruary 2023\nGo 1.21\tAugust 2023",
+				Table: [][]string{
+					{"Version", "Released"},
+					{"Go 1.20", "February 2023"},
+					{"Go 1.21", "August 2023"},
+				},
+				SourceURL:   "https://go.dev/doc/devel/release",
+				SourceTitle: "Release History - The Go Programming Language",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			resp, err := parseResponse(f, DefaultLayouts, 0)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, resp.FeaturedSnippet)
+			// The answer box source is not counted as an ordinary result.
+			if assert.Len(t, resp.Results, 1) {
+				assert.Equal(t, 1, resp.Results[0].Rank)
+				assert.Equal(t, "https://go.dev/", resp.Results[0].URL)
+			}
+		})
+	}
+}
+
+func TestParseResponseWithoutFeaturedSnippet(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "google_results.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	resp, err := parseResponse(f, DefaultLayouts, 0)
+
+	assert.NoError(t, err)
+	assert.Nil(t, resp.FeaturedSnippet)
+}
+
+func TestSearchGoogleFullRawHTML(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("testdata", "google_results.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, _ := newTestClient(t, serveFixture(t, "google_results.html"))
+
+	resp, err := SearchGoogleFull(context.Background(), "golang", SearchOptions{HTTPClient: client, ReturnRawHTML: true})
+	assert.NoError(t, err)
+	assert.Equal(t, string(fixture), resp.RawHTML)
+	assert.Len(t, resp.Results, 3)
+
+	resp, err = SearchGoogleFull(context.Background(), "golang", SearchOptions{HTTPClient: client})
+	assert.NoError(t, err)
+	assert.Empty(t, resp.RawHTML)
+}
+
+func TestSearchGoogleFullMaxBodySize(t *testing.T) {
+	client, _ := newTestClient(t, serveFixture(t, "google_results.html"))
+
+	resp, err := SearchGoogleFull(context.Background(), "golang", SearchOptions{HTTPClient: client, ReturnRawHTML: true, MaxBodySize: 2048})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.RawHTML, 2048)
+	// The page is cut off in the second result.
+	if assert.NotEmpty(t, resp.Results) {
+		assert.Equal(t, "https://go.dev/", resp.Results[0].URL)
+	}
+}
+
+func TestSearchGoogleFullRawHTMLCache(t *testing.T) {
+	requests := 0
+	fixture := serveFixture(t, "google_results.html")
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fixture(w, r)
+	})
+	cache := NewMemoryCache(10)
+
+	// A page cached without its body is fetched again when the body is wanted.
+	_, err := SearchGoogleFull(context.Background(), "golang", SearchOptions{HTTPClient: client, Cache: cache})
+	assert.NoError(t, err)
+	resp, err := SearchGoogleFull(context.Background(), "golang", SearchOptions{HTTPClient: client, Cache: cache, ReturnRawHTML: true})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, resp.RawHTML)
+	assert.Equal(t, 2, requests)
+
+	resp, err = SearchGoogleFull(context.Background(), "golang", SearchOptions{HTTPClient: client, Cache: cache})
+	assert.NoError(t, err)
+	assert.Empty(t, resp.RawHTML)
+	assert.Equal(t, 2, requests)
+}
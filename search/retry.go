@@ -0,0 +1,120 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/propro-productions/go-utils/metrics"
+	"github.com/propro-productions/go-utils/proxy"
+)
+
+// DefaultRetryBackoff is the wait before the first retry when SearchOptions.RetryBackoff is not set.
+const DefaultRetryBackoff = time.Second
+
+// maxRetryBackoff caps the exponential growth of the wait between retries.
+const maxRetryBackoff = time.Minute
+
+// isRetryable reports whether err is worth another attempt: a block or a 5xx response.
+func isRetryable(err error) bool {
+	if errors.Is(err, ErrBlocked) {
+		return true
+	}
+	var searchErr *SearchError
+	return errors.As(err, &searchErr) && searchErr.StatusCode >= http.StatusInternalServerError
+}
+
+// withRetry calls attempt until it succeeds, fails with a non-retryable error or opt.MaxRetries
+// retries have been made. Every attempt receives opt with ProxyAddr set to the next proxy of
+// opt.ProxyPool, which is told how the attempt went, or else to the next entry of
+// opt.Proxies. When more than one attempt was made, the last error is wrapped with the count.
+// The attempts and retries are reported to opt.Metrics as requests to engine.
+func withRetry(ctx context.Context, opt SearchOptions, engine Engine, attempt func(opt SearchOptions) error) error {
+	backoff := opt.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+
+	attempts := 0
+	for {
+		attemptOpt := opt
+		var proxyURL *url.URL
+		if opt.ProxyPool != nil {
+			var err error
+			if proxyURL, err = opt.ProxyPool.Next(); err != nil {
+				return retryError(err, attempts)
+			}
+			attemptOpt.ProxyAddr = proxyURL.String()
+			attemptOpt.ProxyPool = nil
+		} else if len(opt.Proxies) > 0 {
+			attemptOpt.ProxyAddr = opt.Proxies[attempts%len(opt.Proxies)]
+		}
+
+		err := observeRequest(opt, engine, func() error { return attempt(attemptOpt) })
+		attempts++
+		// A cancelled search says nothing about the proxy
+		if proxyURL != nil && ctx.Err() == nil {
+			reportProxy(opt.ProxyPool, proxyURL, err)
+		}
+		if err == nil {
+			return nil
+		}
+		if attempts > opt.MaxRetries || !isRetryable(err) {
+			return retryError(err, attempts)
+		}
+
+		wait := jitter(backoff)
+		if opt.Metrics != nil {
+			metrics.Count(opt.Metrics, metrics.SearchRetries, 1, "engine", string(engine))
+		}
+		logger(opt).Debug("retrying", "attempt", attempts+1, "wait", wait, "proxy", attemptOpt.ProxyAddr, "err", err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return retryError(ctx.Err(), attempts)
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// reportProxy tells pool how the attempt through proxyURL went. Blocks and transport errors
+// count against the proxy; any response, even an error status, shows that it works.
+func reportProxy(pool *proxy.Pool, proxyURL *url.URL, err error) {
+	var searchErr *SearchError
+	switch {
+	case err == nil:
+		pool.Succeed(proxyURL)
+	case errors.Is(err, ErrBlocked):
+		pool.Block(proxyURL)
+	case errors.As(err, &searchErr) && searchErr.StatusCode != 0 && searchErr.StatusCode != http.StatusProxyAuthRequired:
+		pool.Succeed(proxyURL)
+	default:
+		pool.Fail(proxyURL)
+	}
+}
+
+func retryError(err error, attempts int) error {
+	if attempts <= 1 {
+		return err
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+}
+
+// jitter returns a random duration in [d/2, d).
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
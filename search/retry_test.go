@@ -0,0 +1,154 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/propro-productions/go-utils/proxy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRetryRotatesProxies(t *testing.T) {
+	var proxies []string
+	opt := SearchOptions{MaxRetries: 3, RetryBackoff: time.Millisecond, Proxies: []string{"http://a:1", "http://b:1"}}
+
+	err := withRetry(context.Background(), opt, EngineGoogle, func(attempt SearchOptions) error {
+		proxies = append(proxies, attempt.ProxyAddr)
+		return ErrBlocked
+	})
+
+	assert.ErrorIs(t, err, ErrBlocked)
+	assert.Contains(t, err.Error(), "4 attempts")
+	assert.Equal(t, []string{"http://a:1", "http://b:1", "http://a:1", "http://b:1"}, proxies)
+}
+
+func TestWithRetryStopsOnSuccess(t *testing.T) {
+	calls := 0
+	opt := SearchOptions{MaxRetries: 5, RetryBackoff: time.Millisecond}
+
+	err := withRetry(context.Background(), opt, EngineGoogle, func(attempt SearchOptions) error {
+		calls++
+		if calls < 3 {
+			return &SearchError{Engine: EngineGoogle, StatusCode: http.StatusBadGateway, Err: ErrUnexpectedStatus}
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestWithRetryDoesNotRetryPermanentErrors(t *testing.T) {
+	calls := 0
+	permanent := errors.New("parse failure")
+	opt := SearchOptions{MaxRetries: 5, RetryBackoff: time.Millisecond}
+
+	err := withRetry(context.Background(), opt, EngineGoogle, func(attempt SearchOptions) error {
+		calls++
+		return permanent
+	})
+
+	assert.Same(t, permanent, err)
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	notFound := &SearchError{Engine: EngineGoogle, StatusCode: http.StatusNotFound, Err: ErrUnexpectedStatus}
+	err = withRetry(context.Background(), opt, EngineGoogle, func(attempt SearchOptions) error {
+		calls++
+		return notFound
+	})
+
+	assert.Same(t, notFound, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestWithRetryAbortsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	opt := SearchOptions{MaxRetries: 5, RetryBackoff: time.Hour}
+
+	err := withRetry(ctx, opt, EngineGoogle, func(attempt SearchOptions) error {
+		calls++
+		cancel()
+		return ErrBlocked
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls)
+}
+
+func TestSearchGoogleRetriesServerErrors(t *testing.T) {
+	fixture := serveFixture(t, "google_results.html")
+	requests := 0
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fixture(w, r)
+	})
+
+	results, err := SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client, MaxRetries: 2, RetryBackoff: time.Millisecond})
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, 2, requests)
+}
+
+func TestWithRetryProxyPool(t *testing.T) {
+	pool, _ := proxy.NewPool("http://a:1", "http://b:1", "http://c:1")
+	pool.MaxFailures = 2
+	var proxies []string
+	opt := SearchOptions{MaxRetries: 5, RetryBackoff: time.Millisecond, ProxyPool: pool, ProxyAddr: "http://ignored:1"}
+
+	err := withRetry(context.Background(), opt, EngineGoogle, func(attempt SearchOptions) error {
+		proxies = append(proxies, attempt.ProxyAddr)
+		switch attempt.ProxyAddr {
+		case "http://a:1":
+			return &SearchError{Engine: EngineGoogle, Err: ErrCaptcha, StatusCode: http.StatusOK}
+		case "http://b:1":
+			return &SearchError{Engine: EngineGoogle, StatusCode: http.StatusBadGateway, Err: ErrUnexpectedStatus}
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"http://a:1", "http://b:1", "http://c:1"}, proxies)
+	status := pool.Status()
+	assert.False(t, status[0].Healthy, "blocked")
+	assert.True(t, status[1].Healthy, "a response shows the proxy works")
+	assert.True(t, status[2].Healthy)
+}
+
+func TestWithRetryProxyPoolExhausted(t *testing.T) {
+	pool, _ := proxy.NewPool("http://a:1", "http://b:1")
+	opt := SearchOptions{MaxRetries: 5, RetryBackoff: time.Millisecond, ProxyPool: pool}
+	calls := 0
+
+	err := withRetry(context.Background(), opt, EngineGoogle, func(attempt SearchOptions) error {
+		calls++
+		return ErrBlocked
+	})
+
+	assert.ErrorIs(t, err, proxy.ErrNoHealthyProxy)
+	assert.Contains(t, err.Error(), "2 attempts")
+	assert.Equal(t, 2, calls)
+}
+
+func TestWithRetryProxyPoolTransportErrors(t *testing.T) {
+	pool, _ := proxy.NewPool("http://a:1")
+	pool.MaxFailures = 2
+	opt := SearchOptions{MaxRetries: 1, RetryBackoff: time.Millisecond, ProxyPool: pool}
+
+	for i := 0; i < 2; i++ {
+		withRetry(context.Background(), opt, EngineGoogle, func(attempt SearchOptions) error {
+			return &SearchError{Engine: EngineGoogle, Err: errors.New("proxyconnect tcp: connection refused")}
+		})
+	}
+
+	assert.False(t, pool.Status()[0].Healthy)
+}
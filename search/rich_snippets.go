@@ -0,0 +1,90 @@
+package search
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Sitelink is a sub-link shown under a search result.
+type Sitelink struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+}
+
+// Rating is the star rating shown for a search result.
+type Rating struct {
+
+	// Value is the rating, usually out of 5.
+	Value float64 `json:"value"`
+
+	// Count is the number of reviews or votes, or 0 if not shown.
+	Count int `json:"count"`
+}
+
+// sitelinkSelectors match the anchors of expanded (block) and inline sitelinks.
+var sitelinkSelectors = []string{
+	"div.usJj9c h3 a",
+	"table.jmjoTe td a",
+	"div.HiHjCd a",
+}
+
+func parseSitelinks(el *goquery.Selection) []Sitelink {
+	var sitelinks []Sitelink
+	for _, selector := range sitelinkSelectors {
+		el.Find(selector).Each(func(i int, a *goquery.Selection) {
+			href, _ := a.Attr("href")
+			link := cleanResultURL(href)
+			title := strings.TrimSpace(a.Text())
+			if link == "" || title == "" {
+				return
+			}
+			sitelinks = append(sitelinks, Sitelink{URL: link, Title: title})
+		})
+		if len(sitelinks) > 0 {
+			return sitelinks
+		}
+	}
+	return nil
+}
+
+var (
+	ratingValueRegexp = regexp.MustCompile(`(\d+(?:[.,]\d+)?)`)
+	ratingCountRegexp = regexp.MustCompile(`(?i)([\d.,\x{00a0}]+)\s*(?:reviews?|votes?|ratings?)`)
+)
+
+// parseRating reads the rating line that accompanies the review stars,
+// e.g. "Rating: 4.5 · 1,234 reviews". It returns nil when the result has none.
+func parseRating(el *goquery.Selection) *Rating {
+	text := el.Find("div.fG8Fp").First().Text()
+	if text == "" {
+		text = el.Find("g-review-stars").First().Parent().Text()
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	match := ratingValueRegexp.FindString(text)
+	if match == "" {
+		return nil
+	}
+	value, err := strconv.ParseFloat(strings.Replace(match, ",", ".", 1), 64)
+	if err != nil {
+		return nil
+	}
+
+	rating := &Rating{Value: value}
+	if m := ratingCountRegexp.FindStringSubmatch(text); m != nil {
+		digits := strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, m[1])
+		rating.Count, _ = strconv.Atoi(digits)
+	}
+	return rating
+}
@@ -0,0 +1,40 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRating(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want *Rating
+	}{
+		{"value and reviews", `<div class="fG8Fp">Rating: 4.5 · 1,234 reviews</div>`, &Rating{Value: 4.5, Count: 1234}},
+		{"decimal comma", `<div class="fG8Fp">Bewertung: 4,2 · 87 Rezensionen</div>`, &Rating{Value: 4.2}},
+		{"votes", `<span><g-review-stars></g-review-stars> Rating: 5 - 12 votes</span>`, &Rating{Value: 5, Count: 12}},
+		{"no rating", `<div class="VwiC3b">Plain snippet</div>`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<div class="g">` + tt.html + `</div>`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.want, parseRating(doc.Find(".g")))
+		})
+	}
+}
+
+func TestParseSitelinksMissing(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<div class="g"><a href="https://go.dev/"><h3>Go</h3></a></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, parseSitelinks(doc.Find(".g")))
+}
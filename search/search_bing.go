@@ -0,0 +1,135 @@
+package search
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const stdBingBase = "https://www.bing.com/search?q="
+
+// SearchBing returns a list of search results from Bing.
+//
+// It honors the same SearchOptions as SearchGoogle: Limit is sent as `count`, Start as `first`
+// and LanguageCode as `setlang`. CountryCode is ignored.
+func SearchBing(ctx context.Context, searchTerm string, opts ...SearchOptions) ([]Result, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	opt := searchOptions(opts)
+	if err := validateOptions(opt); err != nil {
+		return nil, err
+	}
+	searchURL := getBingURL(searchTerm, opt)
+
+	var results []Result
+	err := observeRequest(opt, EngineBing, func() error {
+		var err error
+		results, err = searchBingPage(ctx, searchURL, opt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if opt.Limit > 0 && len(results) > opt.Limit {
+		results = results[:opt.Limit]
+	}
+
+	return results, nil
+}
+
+// searchBingPage requests a single page of results.
+func searchBingPage(ctx context.Context, searchURL string, opt SearchOptions) ([]Result, error) {
+	if err := waitLimit(ctx, opt, searchURL); err != nil {
+		return nil, err
+	}
+
+	client, err := newHTTPClient(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	setHeaders(req, opt)
+
+	logger(opt).Debug("search request", "engine", EngineBing, "url", searchURL)
+	resp, err := doRequest(client, req, opt)
+	if err != nil {
+		return nil, bingError(opt, req.URL.String(), 0, err)
+	}
+	defer resp.Body.Close()
+	logger(opt).Debug("search response", "engine", EngineBing, "url", searchURL, "status", resp.StatusCode)
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, bingError(opt, req.URL.String(), resp.StatusCode, ErrBlocked)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, bingError(opt, req.URL.String(), resp.StatusCode, ErrUnexpectedStatus)
+	}
+
+	results, err := parseBingResults(limitBody(resp.Body, opt))
+	if err != nil {
+		return nil, bingError(opt, req.URL.String(), resp.StatusCode, err)
+	}
+	logger(opt).Debug("parsed results", "engine", EngineBing, "url", searchURL, "results", len(results))
+	return results, nil
+}
+
+func bingError(opt SearchOptions, searchURL string, statusCode int, err error) *SearchError {
+	return &SearchError{Engine: EngineBing, URL: searchURL, StatusCode: statusCode, Profile: opt.profileName(), Err: err}
+}
+
+func parseBingResults(r io.Reader) ([]Result, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	rank := 1
+
+	doc.Find("li.b_algo").Each(func(i int, el *goquery.Selection) {
+		titleEl := el.Find("h2 a").First()
+		link, ok := titleEl.Attr("href")
+		if !ok || link == "" {
+			return
+		}
+
+		result := Result{}
+		result.Rank = rank
+		rank++
+
+		result.URL = link
+		result.Title = titleEl.Text()
+		result.Description = el.Find(".b_caption p").First().Text()
+
+		results = append(results, result)
+	})
+
+	return results, nil
+}
+
+func getBingURL(searchTerm string, opts SearchOptions) string {
+	u := stdBingBase + url.QueryEscape(searchTerm)
+	if opts.LanguageCode != "" {
+		u += "&setlang=" + url.QueryEscape(opts.LanguageCode)
+	}
+	if opts.Limit > 0 {
+		u += "&count=" + strconv.Itoa(opts.Limit)
+	}
+	if opts.Start > 0 {
+		// Bing's offset is 1-based.
+		u += "&first=" + strconv.Itoa(opts.Start+1)
+	}
+	return u
+}
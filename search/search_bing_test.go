@@ -0,0 +1,56 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const bingPage = `<html><body><ol id="b_results">
+<li class="b_algo"><h2><a href="https://go.dev/">The Go Programming Language</a></h2>
+<div class="b_caption"><p>Go is an open source programming language.</p></div></li>
+<li class="b_ad"><h2><a href="https://ads.example.com/">Ad</a></h2></li>
+<li class="b_algo"><h2><a href="https://go.dev/doc/?tab=1">Documentation</a></h2>
+<div class="b_caption"><p>Getting started with Go.</p></div></li>
+</ol></body></html>`
+
+func TestParseBingResults(t *testing.T) {
+	results, err := parseBingResults(strings.NewReader(bingPage))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Result{
+		{Rank: 1, URL: "https://go.dev/", Title: "The Go Programming Language", Description: "Go is an open source programming language."},
+		{Rank: 2, URL: "https://go.dev/doc/?tab=1", Title: "Documentation", Description: "Getting started with Go."},
+	}, results)
+}
+
+func TestGetBingURL(t *testing.T) {
+	u := getBingURL("go lang", SearchOptions{Limit: 20, Start: 10})
+
+	assert.Equal(t, "https://www.bing.com/search?q=go+lang&count=20&first=11", u)
+}
+
+func TestSearchFallsBackWhenBlocked(t *testing.T) {
+	saved := Engines
+	defer func() { Engines = saved }()
+
+	var called []Engine
+	Engines = map[Engine]Searcher{
+		EngineGoogle: SearcherFunc(func(ctx context.Context, searchTerm string, opts ...SearchOptions) ([]Result, error) {
+			called = append(called, EngineGoogle)
+			return nil, ErrBlocked
+		}),
+		EngineBing: SearcherFunc(func(ctx context.Context, searchTerm string, opts ...SearchOptions) ([]Result, error) {
+			called = append(called, EngineBing)
+			return []Result{{Rank: 1, URL: "https://go.dev/"}}, nil
+		}),
+	}
+
+	results, err := Search(context.Background(), "go", EngineGoogle)
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, []Engine{EngineGoogle, EngineBing}, called)
+}
@@ -0,0 +1,172 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const stdDuckDuckGoBase = "https://html.duckduckgo.com/html/"
+
+// SearchDuckDuckGo returns a list of search results from DuckDuckGo's HTML endpoint.
+//
+// Start is sent as the `s` offset of the results form and Limit is satisfied by posting
+// the form again for further pages. When both CountryCode and LanguageCode are set they
+// select the region (`kl`), e.g. "de" and "de" become "de-de".
+func SearchDuckDuckGo(ctx context.Context, searchTerm string, opts ...SearchOptions) ([]Result, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	opt := searchOptions(opts)
+	if err := validateOptions(opt); err != nil {
+		return nil, err
+	}
+
+	client, err := newHTTPClient(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	fetch := func(page SearchOptions) (results []Result, err error) {
+		err = observeRequest(opt, EngineDuckDuckGo, func() error {
+			results, err = searchDuckDuckGoPage(ctx, client, searchTerm, page)
+			return err
+		})
+		return results, err
+	}
+
+	if opt.Limit <= 0 {
+		return fetch(opt)
+	}
+
+	results, err := collectPages(opt, opt.OverLimit, fetch)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// searchDuckDuckGoPage posts the results form for a single page.
+func searchDuckDuckGoPage(ctx context.Context, client *http.Client, searchTerm string, opt SearchOptions) ([]Result, error) {
+	if err := waitLimit(ctx, opt, stdDuckDuckGoBase); err != nil {
+		return nil, err
+	}
+
+	form := getDuckDuckGoForm(searchTerm, opt)
+	req, err := http.NewRequestWithContext(ctx, "POST", stdDuckDuckGoBase, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	setHeaders(req, opt)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	logger(opt).Debug("search request", "engine", EngineDuckDuckGo, "url", stdDuckDuckGoBase, "form", form.Encode())
+	resp, err := doRequest(client, req, opt)
+	if err != nil {
+		return nil, duckDuckGoError(opt, 0, err)
+	}
+	defer resp.Body.Close()
+	logger(opt).Debug("search response", "engine", EngineDuckDuckGo, "url", stdDuckDuckGoBase, "status", resp.StatusCode)
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusForbidden {
+		return nil, duckDuckGoError(opt, resp.StatusCode, ErrBlocked)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, duckDuckGoError(opt, resp.StatusCode, ErrUnexpectedStatus)
+	}
+
+	body, err := readBody(resp.Body, opt)
+	if err != nil {
+		return nil, duckDuckGoError(opt, resp.StatusCode, err)
+	}
+
+	// DuckDuckGo answers suspected bots with a 200 "anomaly" challenge page.
+	if bytes.Contains(body, []byte("anomaly-modal")) {
+		return nil, duckDuckGoError(opt, resp.StatusCode, ErrCaptcha)
+	}
+
+	results, err := parseDuckDuckGoResults(bytes.NewReader(body))
+	if err != nil {
+		return nil, duckDuckGoError(opt, resp.StatusCode, err)
+	}
+	logger(opt).Debug("parsed results", "engine", EngineDuckDuckGo, "url", stdDuckDuckGoBase, "results", len(results))
+	return results, nil
+}
+
+func duckDuckGoError(opt SearchOptions, statusCode int, err error) *SearchError {
+	return &SearchError{Engine: EngineDuckDuckGo, URL: stdDuckDuckGoBase, StatusCode: statusCode, Profile: opt.profileName(), Err: err}
+}
+
+func getDuckDuckGoForm(searchTerm string, opts SearchOptions) url.Values {
+	form := url.Values{}
+	form.Set("q", searchTerm)
+	if opts.Start > 0 {
+		form.Set("s", strconv.Itoa(opts.Start))
+		form.Set("dc", strconv.Itoa(opts.Start+1))
+	}
+	if opts.CountryCode != "" && opts.LanguageCode != "" {
+		form.Set("kl", strings.ToLower(opts.CountryCode+"-"+opts.LanguageCode))
+	}
+	return form
+}
+
+func parseDuckDuckGoResults(r io.Reader) ([]Result, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	rank := 1
+
+	doc.Find(".result").Each(func(i int, el *goquery.Selection) {
+		// Sponsored results link through an ad redirect rather than uddg.
+		if el.HasClass("result--ad") {
+			return
+		}
+
+		titleEl := el.Find("a.result__a").First()
+		href, _ := titleEl.Attr("href")
+		link := duckDuckGoTarget(href)
+		if link == "" {
+			return
+		}
+
+		result := Result{}
+		result.Rank = rank
+		rank++
+
+		result.URL = link
+		result.Title = strings.TrimSpace(titleEl.Text())
+		result.Description = strings.TrimSpace(el.Find(".result__snippet").First().Text())
+
+		results = append(results, result)
+	})
+
+	return results, nil
+}
+
+// duckDuckGoTarget decodes the real destination from DuckDuckGo's //duckduckgo.com/l/?uddg= redirect.
+func duckDuckGoTarget(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if target := u.Query().Get("uddg"); target != "" {
+		if u, err = url.Parse(target); err != nil {
+			return ""
+		}
+	}
+	if !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
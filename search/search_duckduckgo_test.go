@@ -0,0 +1,77 @@
+package search
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDuckDuckGoResults(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "duckduckgo_results.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	results, err := parseDuckDuckGoResults(f)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Result{
+		{
+			Rank:        1,
+			URL:         "https://go.dev/",
+			Title:       "The Go Programming Language",
+			Description: "Go is an open source programming language supported by Google.",
+		},
+		{
+			Rank:        2,
+			URL:         "https://en.wikipedia.org/wiki/Go_(programming_language)?uselang=en",
+			Title:       "Go (programming language) - Wikipedia",
+			Description: "Go is a statically typed, compiled high-level programming language designed at Google.",
+		},
+	}, results)
+}
+
+func TestSearchDuckDuckGoPostsForm(t *testing.T) {
+	var forms []url.Values
+	fixture := serveFixture(t, "duckduckgo_results.html")
+	client, rt := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		form, _ := url.ParseQuery(string(body))
+		forms = append(forms, form)
+		fixture(w, r)
+	})
+
+	results, err := SearchDuckDuckGo(context.Background(), "golang", SearchOptions{
+		HTTPClient:   client,
+		Start:        10,
+		CountryCode:  "de",
+		LanguageCode: "de",
+		UserAgent:    "test-agent",
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "POST", rt.requests[0].Method)
+	assert.Equal(t, "html.duckduckgo.com", rt.requests[0].URL.Host)
+	assert.Equal(t, "test-agent", rt.requests[0].Header.Get("User-Agent"))
+	assert.Equal(t, "golang", forms[0].Get("q"))
+	assert.Equal(t, "10", forms[0].Get("s"))
+	assert.Equal(t, "de-de", forms[0].Get("kl"))
+}
+
+func TestSearchDuckDuckGoAnomalyPage(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><div class="anomaly-modal__title">Unfortunately, bots use DuckDuckGo too.</div></body></html>`))
+	})
+
+	_, err := SearchDuckDuckGo(context.Background(), "golang", SearchOptions{HTTPClient: client})
+
+	assert.ErrorIs(t, err, ErrBlocked)
+}
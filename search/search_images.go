@@ -0,0 +1,159 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// ImageResult represents a single result from Google Image search.
+type ImageResult struct {
+
+	// Rank is the order number of the search result.
+	Rank int `json:"rank"`
+
+	// ImageURL is the full size image.
+	ImageURL string `json:"image_url"`
+
+	// ThumbnailURL is Google's cached thumbnail of the image.
+	ThumbnailURL string `json:"thumbnail_url"`
+
+	// SourcePageURL is the page the image was found on.
+	SourcePageURL string `json:"source_page_url"`
+
+	// Title of the source page.
+	Title string `json:"title"`
+
+	// Width and Height of the full size image in pixels.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// SearchGoogleImages returns a list of image results from Google (tbm=isch).
+//
+// Results are extracted from the JSON payloads Google embeds in the page rather than
+// from its markup. Limit truncates the results; only a single page is requested.
+func SearchGoogleImages(ctx context.Context, searchTerm string, opts ...SearchOptions) ([]ImageResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	opt := searchOptions(opts)
+	if err := validateOptions(opt); err != nil {
+		return nil, err
+	}
+	opt.ExtraParams = withParam(opt.ExtraParams, "tbm", "isch")
+
+	searchURL := getSearchURL(searchTerm, opt)
+	body, err := fetchGoogle(ctx, searchURL, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	results := parseImageResults(body)
+	logger(opt).Debug("parsed image results", "engine", EngineGoogle, "url", searchURL, "results", len(results))
+	if opt.Limit > 0 && len(results) > opt.Limit {
+		results = results[:opt.Limit]
+	}
+	return results, nil
+}
+
+// initDataRegexp captures the data argument of AF_initDataCallback({key: 'ds:1', hash: '2', data:[...], sideChannel: {}});
+var initDataRegexp = regexp.MustCompile(`(?s)AF_initDataCallback\(\{[^{]*?data:(.*?), sideChannel: \{\}\}\);`)
+
+// parseImageResults extracts image results from every AF_initDataCallback payload in body.
+// Payloads that are not valid JSON are skipped.
+func parseImageResults(body []byte) []ImageResult {
+	var results []ImageResult
+	seen := map[string]bool{}
+
+	for _, match := range initDataRegexp.FindAllSubmatch(body, -1) {
+		var data interface{}
+		if err := json.Unmarshal(match[1], &data); err != nil {
+			continue
+		}
+
+		walkImageData(data, func(result ImageResult) {
+			if seen[result.ImageURL] {
+				return
+			}
+			seen[result.ImageURL] = true
+			result.Rank = len(results) + 1
+			results = append(results, result)
+		})
+	}
+
+	return results
+}
+
+// walkImageData searches the decoded payload for image entries. An entry is an array holding
+// the thumbnail as [url, height, width] immediately followed by the full image in the same
+// shape, next to a {"2003": [..., pageURL, title, ...]} metadata object.
+func walkImageData(node interface{}, emit func(ImageResult)) {
+	list, ok := node.([]interface{})
+	if !ok {
+		if m, ok := node.(map[string]interface{}); ok {
+			for _, child := range m {
+				walkImageData(child, emit)
+			}
+		}
+		return
+	}
+
+	for i := 0; i+1 < len(list); i++ {
+		thumbURL, _, _, ok := imageTriple(list[i])
+		if !ok || !strings.Contains(thumbURL, "gstatic.com") {
+			continue
+		}
+		imageURL, height, width, ok := imageTriple(list[i+1])
+		if !ok {
+			continue
+		}
+
+		result := ImageResult{ImageURL: imageURL, ThumbnailURL: thumbURL, Width: width, Height: height}
+		for _, sibling := range list[i+2:] {
+			if meta, ok := sibling.(map[string]interface{}); ok {
+				result.SourcePageURL, result.Title = imageSource(meta)
+				break
+			}
+		}
+		emit(result)
+		return
+	}
+
+	for _, child := range list {
+		walkImageData(child, emit)
+	}
+}
+
+// imageTriple decodes a [url, height, width] array.
+func imageTriple(node interface{}) (string, int, int, bool) {
+	triple, ok := node.([]interface{})
+	if !ok || len(triple) != 3 {
+		return "", 0, 0, false
+	}
+	u, ok := triple[0].(string)
+	if !ok || !strings.HasPrefix(u, "http") {
+		return "", 0, 0, false
+	}
+	height, ok := triple[1].(float64)
+	if !ok {
+		return "", 0, 0, false
+	}
+	width, ok := triple[2].(float64)
+	if !ok {
+		return "", 0, 0, false
+	}
+	return u, int(height), int(width), true
+}
+
+func imageSource(meta map[string]interface{}) (string, string) {
+	info, ok := meta["2003"].([]interface{})
+	if !ok || len(info) < 4 {
+		return "", ""
+	}
+	pageURL, _ := info[2].(string)
+	title, _ := info[3].(string)
+	return pageURL, title
+}
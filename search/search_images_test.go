@@ -0,0 +1,65 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImageResults(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "google_images.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := parseImageResults(body)
+
+	assert.Equal(t, []ImageResult{
+		{
+			Rank:          1,
+			ImageURL:      "https://go.dev/images/gophers/ladder.svg",
+			ThumbnailURL:  "https://encrypted-tbn0.gstatic.com/images?q=tbn:ANd9GcQ1",
+			SourcePageURL: "https://go.dev/blog/gopher",
+			Title:         "The Go Gopher - The Go Programming Language",
+			Width:         1200,
+			Height:        1200,
+		},
+		{
+			Rank:          2,
+			ImageURL:      "https://upload.wikimedia.org/wikipedia/commons/0/05/Go_Logo_Blue.svg",
+			ThumbnailURL:  "https://encrypted-tbn0.gstatic.com/images?q=tbn:ANd9GcQ2",
+			SourcePageURL: "https://en.wikipedia.org/wiki/Go_(programming_language)",
+			Title:         "Go (programming language) - Wikipedia",
+			Width:         768,
+			Height:        512,
+		},
+	}, results)
+}
+
+func TestParseImageResultsWithoutPayload(t *testing.T) {
+	assert.Empty(t, parseImageResults([]byte(`<html><body>no images</body></html>`)))
+}
+
+func TestSearchGoogleImages(t *testing.T) {
+	client, rt := newTestClient(t, serveFixture(t, "google_images.html"))
+
+	results, err := SearchGoogleImages(context.Background(), "gopher", SearchOptions{HTTPClient: client, Limit: 1})
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "isch", rt.requests[0].URL.Query().Get("tbm"))
+}
+
+func TestSearchGoogleImagesBlocked(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><form id="captcha-form" action="index"></form></body></html>`))
+	})
+
+	_, err := SearchGoogleImages(context.Background(), "gopher", SearchOptions{HTTPClient: client})
+
+	assert.ErrorIs(t, err, ErrBlocked)
+}
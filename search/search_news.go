@@ -0,0 +1,127 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// NewsResult represents a single result from Google News search. Its PublishedAt is derived
+// from the relative timestamp Google shows ("3 hours ago"), and is the zero time when the
+// timestamp could not be parsed.
+type NewsResult struct {
+	Result
+
+	// Source is the name of the publisher.
+	Source string `json:"source"`
+}
+
+// SearchGoogleNews returns a list of news results from Google (tbm=nws).
+//
+// All SearchOptions behave as for SearchGoogle, except that only a single page is requested.
+func SearchGoogleNews(ctx context.Context, searchTerm string, opts ...SearchOptions) ([]NewsResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	opt := searchOptions(opts)
+	if err := validateOptions(opt); err != nil {
+		return nil, err
+	}
+	opt.ExtraParams = withParam(opt.ExtraParams, "tbm", "nws")
+
+	searchURL := getSearchURL(searchTerm, opt)
+	body, err := fetchGoogle(ctx, searchURL, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := parseNewsResults(bytes.NewReader(body), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	logger(opt).Debug("parsed news results", "engine", EngineGoogle, "url", searchURL, "results", len(results))
+
+	if opt.Limit > 0 && len(results) > opt.Limit {
+		results = results[:opt.Limit]
+	}
+	return results, nil
+}
+
+// withParam returns a copy of params with key set to value, unless the caller already set key.
+func withParam(params map[string]string, key, value string) map[string]string {
+	merged := map[string]string{key: value}
+	for k, v := range params {
+		merged[k] = v
+	}
+	return merged
+}
+
+// parseNewsResults parses news cards, resolving relative timestamps against now.
+func parseNewsResults(r io.Reader, now time.Time) ([]NewsResult, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []NewsResult
+	rank := 1
+
+	doc.Find("div.SoaBEf").Each(func(i int, el *goquery.Selection) {
+		href, _ := el.Find("a").First().Attr("href")
+		link := cleanResultURL(href)
+		if link == "" {
+			return
+		}
+
+		result := NewsResult{}
+		result.Rank = rank
+		rank++
+
+		result.URL = link
+		result.Title = strings.TrimSpace(el.Find("div[role=heading]").First().Text())
+		result.Description = strings.TrimSpace(el.Find("div.GI74Re").First().Text())
+		result.Source = strings.TrimSpace(el.Find("div.MgUUmf").First().Text())
+		result.PublishedAt = parseRelativeTime(el.Find("div.OSrXXb span").Last().Text(), now)
+
+		results = append(results, result)
+	})
+
+	return results, nil
+}
+
+var relativeTimeRegexp = regexp.MustCompile(`(?i)(\d+)\s*(min|mins|minute|minutes|hour|hours|day|days|week|weeks)\s+ago`)
+
+// parseRelativeTime turns a timestamp like "3 hours ago" into an absolute time relative to now.
+// It understands minutes, hours, days and weeks and returns the zero time otherwise.
+func parseRelativeTime(text string, now time.Time) time.Time {
+	matches := relativeTimeRegexp.FindStringSubmatch(text)
+	if matches == nil {
+		return time.Time{}
+	}
+
+	n, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return time.Time{}
+	}
+
+	var unit time.Duration
+	switch strings.ToLower(matches[2]) {
+	case "min", "mins", "minute", "minutes":
+		unit = time.Minute
+	case "hour", "hours":
+		unit = time.Hour
+	case "day", "days":
+		unit = 24 * time.Hour
+	case "week", "weeks":
+		unit = 7 * 24 * time.Hour
+	}
+
+	return now.Add(-time.Duration(n) * unit)
+}
@@ -0,0 +1,59 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNewsResults(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "google_news.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	now := time.Date(2024, 5, 2, 18, 0, 0, 0, time.UTC)
+
+	results, err := parseNewsResults(f, now)
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+
+	assert.Equal(t, 1, results[0].Rank)
+	assert.Equal(t, "https://www.reuters.com/business/acme-earnings-2024-05-02/", results[0].URL)
+	assert.Equal(t, "Acme beats quarterly earnings estimates", results[0].Title)
+	assert.Equal(t, "Acme Corp reported better than expected results on Thursday, driven by demand.", results[0].Description)
+	assert.Equal(t, "Reuters", results[0].Source)
+	assert.Equal(t, now.Add(-3*time.Hour), results[0].PublishedAt)
+
+	assert.Equal(t, "https://www.theverge.com/acme-rocket-skates", results[1].URL)
+	assert.Equal(t, "The Verge", results[1].Source)
+	assert.Equal(t, now.Add(-48*time.Hour), results[1].PublishedAt)
+
+	assert.True(t, results[2].PublishedAt.IsZero())
+}
+
+func TestParseRelativeTime(t *testing.T) {
+	now := time.Date(2024, 5, 2, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		text string
+		want time.Time
+	}{
+		{"5 mins ago", now.Add(-5 * time.Minute)},
+		{"1 minute ago", now.Add(-time.Minute)},
+		{"1 hour ago", now.Add(-time.Hour)},
+		{"12 hours ago", now.Add(-12 * time.Hour)},
+		{"1 day ago", now.Add(-24 * time.Hour)},
+		{"3 weeks ago", now.Add(-21 * 24 * time.Hour)},
+		{"Mar 3, 2019", time.Time{}},
+		{"", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, parseRelativeTime(tt.text, now), tt.text)
+	}
+}
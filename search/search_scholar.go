@@ -0,0 +1,203 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const stdScholarBase = "https://scholar.google.com/scholar?"
+
+// maxScholarResultsPerPage is the largest value Google Scholar accepts for the num parameter.
+const maxScholarResultsPerPage = 20
+
+// ScholarResult represents a single result from Google Scholar.
+type ScholarResult struct {
+	Result
+
+	// Authors are the authors as listed by Scholar, usually initials and last name.
+	// Long author lists are cut short by Scholar.
+	Authors []string `json:"authors,omitempty"`
+
+	// PublicationYear is 0 when Scholar doesn't show one.
+	PublicationYear int `json:"publication_year,omitempty"`
+
+	// Venue is the journal, conference or book the work was published in, or the site
+	// hosting it when Scholar shows no venue.
+	Venue string `json:"venue,omitempty"`
+
+	CitedByCount int `json:"cited_by_count"`
+
+	// PDFLink is the link to a full text PDF shown next to the result, if any.
+	PDFLink string `json:"pdf_link,omitempty"`
+}
+
+// SearchGoogleScholar returns a list of results from Google Scholar.
+//
+// Start and Limit paginate like for SearchGoogle. LanguageCode, UserAgent, the retry and
+// proxy options, HTTPClient, Limiter and ExtraParams are honored as well; CountryCode and
+// the other Google Search filters are ignored. Scholar's captcha page is reported as
+// ErrCaptcha, which wraps ErrBlocked.
+func SearchGoogleScholar(ctx context.Context, searchTerm string, opts ...SearchOptions) ([]ScholarResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	opt := searchOptions(opts)
+	if err := validateOptions(opt); err != nil {
+		return nil, err
+	}
+
+	var results []ScholarResult
+	page := opt
+	for {
+		if opt.Limit > 0 {
+			page.Limit = opt.Limit - len(results)
+			if page.Limit > maxScholarResultsPerPage {
+				page.Limit = maxScholarResultsPerPage
+			}
+		}
+
+		searchURL := getScholarURL(searchTerm, page)
+		body, err := fetchGoogle(ctx, searchURL, page)
+		if err != nil {
+			return nil, err
+		}
+		pageResults, err := parseScholarResults(bytes.NewReader(body))
+		if err != nil {
+			return nil, googleError(opt, searchURL, 0, err)
+		}
+		logger(opt).Debug("parsed scholar results", "url", searchURL, "results", len(pageResults))
+
+		for _, r := range pageResults {
+			r.Rank = opt.Start + len(results) + 1
+			results = append(results, r)
+		}
+
+		if opt.Limit <= 0 || len(pageResults) == 0 || len(results) >= opt.Limit {
+			break
+		}
+		page.Start += len(pageResults)
+	}
+
+	if opt.Limit > 0 && len(results) > opt.Limit {
+		results = results[:opt.Limit]
+	}
+	return results, nil
+}
+
+func getScholarURL(searchTerm string, opt SearchOptions) string {
+	params := url.Values{}
+	params.Set("q", searchTerm)
+	if opt.LanguageCode != "" {
+		params.Set("hl", opt.LanguageCode)
+	}
+	if opt.Start > 0 {
+		params.Set("start", strconv.Itoa(opt.Start))
+	}
+	if opt.Limit > 0 {
+		params.Set("num", strconv.Itoa(opt.Limit))
+	}
+	for key, value := range opt.ExtraParams {
+		params.Set(key, value)
+	}
+	return stdScholarBase + params.Encode()
+}
+
+// parseScholarResults parses the result blocks of a Scholar results page. Citations without
+// a link are returned with an empty URL.
+func parseScholarResults(r io.Reader) ([]ScholarResult, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []ScholarResult
+	doc.Find("div.gs_r.gs_or").Each(func(i int, el *goquery.Selection) {
+		title := el.Find("h3.gs_rt").First()
+		// Drop the [PDF], [BOOK] or [CITATION] tags in front of the title
+		title.Find("span.gs_ctc, span.gs_ctu").Remove()
+
+		result := ScholarResult{}
+		result.Rank = len(results) + 1
+		result.Title = cleanScholarText(title.Text())
+		if result.Title == "" {
+			return
+		}
+		result.URL, _ = title.Find("a").First().Attr("href")
+		result.Description = cleanScholarText(el.Find("div.gs_rs").First().Text())
+		result.Authors, result.Venue, result.PublicationYear = parseScholarByline(el.Find("div.gs_a").First().Text())
+
+		// The "Cited by" text is localized, the link to the citing works is not
+		el.Find("div.gs_fl a[href*='cites=']").EachWithBreak(func(i int, a *goquery.Selection) bool {
+			result.CitedByCount = parseCitedBy(a.Text())
+			return false
+		})
+
+		if href, ok := el.Find("div.gs_ggs a, div.gs_or_ggsm a").First().Attr("href"); ok {
+			result.PDFLink = href
+		}
+
+		results = append(results, result)
+	})
+
+	return results, nil
+}
+
+var (
+	yearRegexp    = regexp.MustCompile(`\b(1[5-9]|20)\d{2}\b`)
+	citedByRegexp = regexp.MustCompile(`\d[\d.,\s\x{00a0}\x{202f}]*`)
+)
+
+// parseScholarByline splits a byline like "A Vaswani, N Shazeer… - Advances in neural …,
+// 2017 - proceedings.neurips.cc" into its authors, venue and year.
+func parseScholarByline(text string) (authors []string, venue string, year int) {
+	parts := strings.Split(cleanScholarText(text), " - ")
+	for _, author := range strings.Split(parts[0], ",") {
+		author = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(author), "…"))
+		if author != "" {
+			authors = append(authors, author)
+		}
+	}
+	if len(parts) < 2 {
+		return authors, "", 0
+	}
+
+	publication := parts[1]
+	if matches := yearRegexp.FindAllStringIndex(publication, -1); len(matches) > 0 {
+		last := matches[len(matches)-1]
+		year, _ = strconv.Atoi(publication[last[0]:last[1]])
+		publication = publication[:last[0]] + publication[last[1]:]
+	}
+	venue = strings.Trim(publication, " ,…")
+	if venue == "" && len(parts) > 2 {
+		venue = parts[len(parts)-1]
+	}
+	return authors, venue, year
+}
+
+// parseCitedBy returns the number in a "Cited by 1,234" link in any language, e.g.
+// "Cité 1 234 fois" or "被引用次数：1234". It returns 0 when there is no number.
+func parseCitedBy(text string) int {
+	match := citedByRegexp.FindString(text)
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, match)
+	n, _ := strconv.Atoi(digits)
+	return n
+}
+
+// cleanScholarText collapses whitespace, including the non-breaking spaces Scholar puts
+// around separators.
+func cleanScholarText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
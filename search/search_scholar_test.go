@@ -0,0 +1,104 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScholarResults(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "google_scholar.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	results, err := parseScholarResults(f)
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+
+	assert.Equal(t, 1, results[0].Rank)
+	assert.Equal(t, "Attention is all you need", results[0].Title)
+	assert.Equal(t, "https://proceedings.neurips.cc/paper/2017/hash/3f5ee243547dee91fbd053c1c4a845aa-Abstract.html", results[0].URL)
+	assert.Equal(t, []string{"A Vaswani", "N Shazeer", "N Parmar"}, results[0].Authors)
+	assert.Equal(t, 2017, results[0].PublicationYear)
+	assert.Equal(t, "Advances in neural", results[0].Venue)
+	assert.Equal(t, 123456, results[0].CitedByCount)
+	assert.Equal(t, "https://arxiv.org/pdf/1706.03762", results[0].PDFLink)
+	assert.Contains(t, results[0].Description, "The dominant sequence transduction models")
+
+	// The [BOOK] tag is dropped and the host stands in for the missing venue
+	assert.Equal(t, "The Go programming language", results[1].Title)
+	assert.Equal(t, []string{"AAA Donovan", "BW Kernighan"}, results[1].Authors)
+	assert.Equal(t, 2015, results[1].PublicationYear)
+	assert.Equal(t, "books.google.com", results[1].Venue)
+	assert.Equal(t, 512, results[1].CitedByCount)
+	assert.Empty(t, results[1].PDFLink)
+
+	// Citations have no link
+	assert.Equal(t, "Communicating sequential processes", results[2].Title)
+	assert.Empty(t, results[2].URL)
+	assert.Equal(t, "Communications of the ACM", results[2].Venue)
+	assert.Equal(t, 1978, results[2].PublicationYear)
+	assert.Equal(t, 0, results[2].CitedByCount)
+}
+
+func TestParseCitedBy(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"Cited by 123", 123},
+		{"Cited by 12,345", 12345},
+		{"Cité 1 234 fois", 1234},
+		{"Zitiert von: 1.234", 1234},
+		{"Citado por 56", 56},
+		{"被引用次数：789", 789},
+		{"Related articles", 0},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, parseCitedBy(tt.text), tt.text)
+	}
+}
+
+func TestSearchGoogleScholarPagination(t *testing.T) {
+	client, rt := newTestClient(t, serveFixture(t, "google_scholar.html"))
+
+	results, err := SearchGoogleScholar(context.Background(), "attention", SearchOptions{HTTPClient: client, Start: 10, Limit: 5})
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 5)
+	assert.Equal(t, 11, results[0].Rank)
+	assert.Equal(t, 15, results[4].Rank)
+	assert.Len(t, rt.requests, 2)
+	assert.Equal(t, "scholar.google.com", rt.requests[0].URL.Host)
+	assert.Equal(t, "10", rt.requests[0].URL.Query().Get("start"))
+	assert.Equal(t, "5", rt.requests[0].URL.Query().Get("num"))
+	assert.Equal(t, "13", rt.requests[1].URL.Query().Get("start"))
+	assert.Equal(t, "2", rt.requests[1].URL.Query().Get("num"))
+}
+
+func TestSearchGoogleScholarCaptcha(t *testing.T) {
+	client, _ := newTestClient(t, serveFixture(t, "google_scholar_captcha.html"))
+
+	_, err := SearchGoogleScholar(context.Background(), "attention", SearchOptions{HTTPClient: client})
+
+	assert.ErrorIs(t, err, ErrBlocked)
+	assert.ErrorIs(t, err, ErrCaptcha)
+}
+
+func TestSearchGoogleScholarUnexpectedStatus(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	_, err := SearchGoogleScholar(context.Background(), "attention", SearchOptions{HTTPClient: client})
+
+	assert.ErrorIs(t, err, ErrUnexpectedStatus)
+}
@@ -0,0 +1,301 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ShoppingResult represents a single product from Google Shopping.
+type ShoppingResult struct {
+
+	// Rank is the order number of the product.
+	Rank int `json:"rank"`
+
+	Title string `json:"title"`
+
+	// URL is the offer of the merchant, or the product page of Google Shopping when the
+	// card links there.
+	URL string `json:"url"`
+
+	// Merchant is the name of the store selling the product, or a summary like
+	// "& more" when several stores do.
+	Merchant string `json:"merchant"`
+
+	Price Price `json:"price"`
+
+	// Rating is the average rating out of 5, or 0 if not shown.
+	Rating float64 `json:"rating,omitempty"`
+
+	// ReviewCount is the number of reviews, or 0 if not shown.
+	ReviewCount int `json:"review_count,omitempty"`
+
+	// FreeShipping is set when the card says shipping or delivery is free.
+	FreeShipping bool `json:"free_shipping"`
+}
+
+// Price is the price of a product, as read by parsePrice.
+type Price struct {
+
+	// Text is the price as shown, such as "1.299,00 €".
+	Text string `json:"text"`
+
+	// Amount is the price as a decimal number with a dot, such as "1299.00". It is empty
+	// when no number was found in Text.
+	Amount string `json:"amount,omitempty"`
+
+	// Currency is the ISO 4217 code of the currency, such as "EUR". A symbol used by
+	// several currencies, like "$" or "kr", is read as the currency of the country.
+	Currency string `json:"currency,omitempty"`
+
+	// Value is Amount as a number.
+	Value float64 `json:"value,omitempty"`
+}
+
+// SearchGoogleShopping returns a list of products from Google Shopping (tbm=shop).
+//
+// All SearchOptions behave as for SearchGoogle, except that only a single page is requested.
+// CountryCode also sets how prices are read: whether a comma or a dot separates the
+// decimals, and which currency a "$" stands for.
+func SearchGoogleShopping(ctx context.Context, searchTerm string, opts ...SearchOptions) ([]ShoppingResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	opt := searchOptions(opts)
+	if err := validateOptions(opt); err != nil {
+		return nil, err
+	}
+	opt.ExtraParams = withParam(opt.ExtraParams, "tbm", "shop")
+
+	searchURL := getSearchURL(searchTerm, opt)
+	body, err := fetchGoogle(ctx, searchURL, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := parseShoppingResults(bytes.NewReader(body), searchURL, opt.CountryCode)
+	if err != nil {
+		return nil, err
+	}
+	logger(opt).Debug("parsed shopping results", "engine", EngineGoogle, "url", searchURL, "results", len(results))
+
+	if opt.Limit > 0 && len(results) > opt.Limit {
+		results = results[:opt.Limit]
+	}
+	return results, nil
+}
+
+// Selectors of the parts of a product card, in the grid and list layouts.
+const (
+	shoppingCardSelector     = "div.sh-dgr__content, div.sh-dlr__list-result"
+	shoppingTitleSelector    = "h3"
+	shoppingLinkSelector     = "a.shntl[href]"
+	shoppingMerchantSelector = "div.aULzUe, div.b07ME"
+	shoppingPriceSelector    = "span.a8Pemb, span.HRLxBb"
+	shoppingRatingSelector   = "span.Rsc7Yb"
+	shoppingReviewsSelector  = "span.QIrs8"
+)
+
+// freeShippingRegexp matches the free shipping notes of the Google Shopping locales.
+var freeShippingRegexp = regexp.MustCompile(`(?i)free (?:shipping|delivery)|kostenlose[rn]? (?:versand|lieferung)|versandkostenfrei|livraison gratuite|env[ií]o gratis|spedizione gratuita|gratis verzending|frete gr[aá]tis|darmowa dostawa|fri frakt|送料無料`)
+
+// parseShoppingResults parses the product cards of a Google Shopping page. Links to Google
+// are resolved against searchURL, and prices are read for countryCode.
+func parseShoppingResults(r io.Reader, searchURL, countryCode string) ([]ShoppingResult, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+	base, err := url.Parse(searchURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []ShoppingResult
+	doc.Find(shoppingCardSelector).Each(func(i int, el *goquery.Selection) {
+		title := strings.TrimSpace(el.Find(shoppingTitleSelector).First().Text())
+		if title == "" {
+			return
+		}
+
+		href, _ := el.Find(shoppingLinkSelector).First().Attr("href")
+		link := cleanResultURL(href)
+		if link == "" && strings.HasPrefix(href, "/") {
+			if u, err := base.Parse(href); err == nil {
+				link = u.String()
+			}
+		}
+
+		result := ShoppingResult{
+			Rank:         len(results) + 1,
+			Title:        title,
+			URL:          link,
+			Merchant:     strings.TrimSpace(el.Find(shoppingMerchantSelector).First().Text()),
+			Price:        parsePrice(el.Find(shoppingPriceSelector).First().Text(), countryCode),
+			FreeShipping: freeShippingRegexp.MatchString(el.Text()),
+		}
+		if match := ratingValueRegexp.FindString(el.Find(shoppingRatingSelector).First().Text()); match != "" {
+			result.Rating, _ = strconv.ParseFloat(strings.Replace(match, ",", ".", 1), 64)
+		}
+		result.ReviewCount = parseReviewCount(el.Find(shoppingReviewsSelector).First().Text())
+
+		results = append(results, result)
+	})
+
+	return results, nil
+}
+
+// reviewCountRegexp matches a count of reviews, such as "(1,234)" or "2.5K".
+var reviewCountRegexp = regexp.MustCompile(`(\d[\d.,\x{00a0}\x{202f} ]*)\s*([kK])?`)
+
+// parseReviewCount reads a count of reviews, in which "K" stands for thousands.
+func parseReviewCount(text string) int {
+	match := reviewCountRegexp.FindStringSubmatch(text)
+	if match == nil {
+		return 0
+	}
+	number := strings.TrimRight(match[1], ".,   ")
+	if match[2] != "" {
+		value, err := strconv.ParseFloat(strings.Replace(number, ",", ".", 1), 64)
+		if err != nil {
+			return 0
+		}
+		return int(value * 1000)
+	}
+	count, _ := strconv.Atoi(digitsOnly(number))
+	return count
+}
+
+// digitsOnly returns the ASCII digits of s.
+func digitsOnly(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// priceNumberRegexp matches a number with the separators of prices.
+var priceNumberRegexp = regexp.MustCompile(`\d[\d.,'\x{00a0}\x{202f} ]*`)
+
+// currencySymbols map the symbols of prices to currencies, longest first. "$", "¥" and
+// "kr" depend on the country.
+var currencySymbols = []struct{ symbol, code string }{
+	{"US$", "USD"}, {"CA$", "CAD"}, {"AU$", "AUD"}, {"NZ$", "NZD"}, {"HK$", "HKD"},
+	{"MX$", "MXN"}, {"R$", "BRL"}, {"C$", "CAD"}, {"A$", "AUD"}, {"S$", "SGD"},
+	{"CHF", "CHF"}, {"zł", "PLN"}, {"Kč", "CZK"}, {"€", "EUR"}, {"£", "GBP"}, {"₹", "INR"},
+	{"₩", "KRW"}, {"₽", "RUB"}, {"₺", "TRY"}, {"₫", "VND"}, {"₱", "PHP"},
+}
+
+// countryCurrencies are the currencies of the countries, by CountryCode.
+var countryCurrencies = map[string]string{
+	"us": "USD", "ca": "CAD", "au": "AUD", "nz": "NZD", "mx": "MXN", "sg": "SGD", "hk": "HKD",
+	"gb": "GBP", "uk": "GBP", "jp": "JPY", "cn": "CNY", "in": "INR", "br": "BRL", "ch": "CHF",
+	"se": "SEK", "no": "NOK", "dk": "DKK", "is": "ISK", "pl": "PLN", "cz": "CZK", "ru": "RUB",
+	"tr": "TRY", "kr": "KRW", "za": "ZAR", "ar": "ARS", "cl": "CLP", "co": "COP",
+	"at": "EUR", "be": "EUR", "de": "EUR", "ee": "EUR", "es": "EUR", "fi": "EUR", "fr": "EUR",
+	"gr": "EUR", "ie": "EUR", "it": "EUR", "lt": "EUR", "lu": "EUR", "lv": "EUR", "nl": "EUR",
+	"pt": "EUR", "si": "EUR", "sk": "EUR",
+}
+
+// commaDecimalCountries write prices with a decimal comma, such as "1.299,00".
+var commaDecimalCountries = map[string]bool{
+	"ar": true, "at": true, "be": true, "br": true, "cl": true, "co": true, "cz": true,
+	"de": true, "dk": true, "ee": true, "es": true, "fi": true, "fr": true, "gr": true,
+	"id": true, "is": true, "it": true, "lt": true, "lu": true, "lv": true, "nl": true,
+	"no": true, "pl": true, "pt": true, "ru": true, "se": true, "si": true, "sk": true,
+	"tr": true, "vn": true, "za": true,
+}
+
+// isoCurrencyRegexp matches a currency code next to a price, such as "EUR 12,99".
+var isoCurrencyRegexp = regexp.MustCompile(`(?:^|[^A-Za-z])([A-Z]{3})(?:[^A-Za-z]|$)`)
+
+// parsePrice reads the first price of text, with the symbol or code of its currency
+// before or after the number. The separators of the number are told apart by their
+// position: the last one is the decimal separator if both a dot and a comma are used, or
+// if it is not followed by exactly three digits. Otherwise, as in "1.299", the convention
+// of countryCode decides.
+func parsePrice(text, countryCode string) Price {
+	text = strings.TrimSpace(strings.Join(strings.Fields(text), " "))
+	price := Price{Text: text}
+	country := strings.ToLower(countryCode)
+	if country == "" {
+		country = "us"
+	}
+
+	loc := priceNumberRegexp.FindStringIndex(text)
+	if loc == nil {
+		return price
+	}
+	number := strings.TrimRight(text[loc[0]:loc[1]], ".,'   ")
+	price.Amount = priceAmount(number, commaDecimalCountries[country])
+	price.Value, _ = strconv.ParseFloat(price.Amount, 64)
+
+	price.Currency = priceCurrency(text[:loc[0]], country)
+	if price.Currency == "" {
+		price.Currency = priceCurrency(text[loc[1]:], country)
+	}
+	if price.Currency == "" {
+		price.Currency = countryCurrencies[country]
+	}
+	return price
+}
+
+// priceAmount returns number, with its separators, as a decimal number with a dot.
+func priceAmount(number string, commaDecimal bool) string {
+	last := strings.LastIndexAny(number, ".,")
+	if last < 0 {
+		return digitsOnly(number)
+	}
+	sep, fraction := number[last], number[last+1:]
+
+	decimal := strings.Contains(number, ".") && strings.Contains(number, ",") ||
+		len(fraction) != 3 && strings.Count(number, string(sep)) == 1
+	if !decimal && len(fraction) == 3 && strings.Count(number, string(sep)) == 1 {
+		decimal = (sep == ',') == commaDecimal
+	}
+	if !decimal {
+		return digitsOnly(number)
+	}
+	return digitsOnly(number[:last]) + "." + digitsOnly(fraction)
+}
+
+// priceCurrency returns the currency of the symbol or code in s, the text before or after
+// the number of a price, or "" if there is none.
+func priceCurrency(s, country string) string {
+	for _, c := range currencySymbols {
+		if strings.Contains(s, c.symbol) {
+			return c.code
+		}
+	}
+	switch {
+	case strings.Contains(s, "$"):
+		if code := countryCurrencies[country]; strings.HasSuffix(code, "D") || code == "MXN" || code == "ARS" || code == "CLP" || code == "COP" {
+			return code
+		}
+		return "USD"
+	case strings.Contains(s, "¥") || strings.Contains(s, "￥") || strings.Contains(s, "円"):
+		if country == "cn" {
+			return "CNY"
+		}
+		return "JPY"
+	case strings.Contains(strings.ToLower(s), "kr"):
+		switch code := countryCurrencies[country]; code {
+		case "NOK", "DKK", "ISK":
+			return code
+		}
+		return "SEK"
+	}
+	if match := isoCurrencyRegexp.FindStringSubmatch(s); match != nil {
+		return match[1]
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseShoppingResults(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "google_shopping.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	results, err := parseShoppingResults(f, "https://www.google.com/search?q=espresso+machine&tbm=shop", "us")
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+
+	assert.Equal(t, ShoppingResult{
+		Rank:         1,
+		Title:        "Breville Barista Pro Espresso Machine",
+		URL:          "https://www.example-coffee.com/barista-pro",
+		Merchant:     "Example Coffee",
+		Price:        Price{Text: "$1,299.99", Amount: "1299.99", Currency: "USD", Value: 1299.99},
+		Rating:       4.6,
+		ReviewCount:  1234,
+		FreeShipping: true,
+	}, results[0])
+
+	assert.Equal(t, "https://www.google.com/shopping/product/1234567890?q=espresso+machine&prds=pid:1", results[1].URL)
+	assert.Equal(t, "Kitchen Outlet & more", results[1].Merchant)
+	assert.Equal(t, "249.95", results[1].Price.Amount)
+	assert.Equal(t, 2500, results[1].ReviewCount)
+	assert.False(t, results[1].FreeShipping)
+
+	assert.Equal(t, 3, results[2].Rank)
+	assert.Equal(t, "35", results[2].Price.Amount)
+	assert.Zero(t, results[2].Rating)
+	assert.True(t, results[2].FreeShipping)
+}
+
+func TestParseShoppingResultsGerman(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "google_shopping_de.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	results, err := parseShoppingResults(f, "https://www.google.de/search?q=Espressomaschine&tbm=shop", "de")
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	assert.Equal(t, ShoppingResult{
+		Rank:         1,
+		Title:        "Breville Barista Pro Espressomaschine",
+		URL:          "https://www.beispiel-kaffee.de/barista-pro",
+		Merchant:     "Beispiel Kaffee",
+		Price:        Price{Text: "1.299,00 €", Amount: "1299.00", Currency: "EUR", Value: 1299},
+		Rating:       4.6,
+		ReviewCount:  1234,
+		FreeShipping: true,
+	}, results[0])
+
+	assert.Equal(t, Price{Text: "34,90 €", Amount: "34.90", Currency: "EUR", Value: 34.9}, results[1].Price)
+	assert.False(t, results[1].FreeShipping)
+}
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		text     string
+		country  string
+		amount   string
+		currency string
+	}{
+		{"$1,299.99", "us", "1299.99", "USD"},
+		{"$1,299", "us", "1299", "USD"},
+		{"$12.5", "us", "12.5", "USD"},
+		{"$24.99", "ca", "24.99", "CAD"},
+		{"US$24.99", "ca", "24.99", "USD"},
+		{"1.299,00 €", "de", "1299.00", "EUR"},
+		{"1.299 €", "de", "1299", "EUR"},
+		{"€1,299", "ie", "1299", "EUR"},
+		{"12,99 €", "fr", "12.99", "EUR"},
+		{"1 299,00 €", "fr", "1299.00", "EUR"},
+		{"£19.99", "gb", "19.99", "GBP"},
+		{"CHF 1'299.00", "ch", "1299.00", "CHF"},
+		{"R$ 1.299,90", "br", "1299.90", "BRL"},
+		{"¥12,800", "jp", "12800", "JPY"},
+		{"¥99.00", "cn", "99.00", "CNY"},
+		{"1 499 kr", "se", "1499", "SEK"},
+		{"499,00 kr", "no", "499.00", "NOK"},
+		{"49,99 zł", "pl", "49.99", "PLN"},
+		{"EUR 12,99", "de", "12.99", "EUR"},
+		{"1.234.567", "de", "1234567", "EUR"},
+		{"$10.99 - $15.99", "us", "10.99", "USD"},
+		{"12.99", "", "12.99", "USD"},
+	}
+
+	for _, tt := range tests {
+		price := parsePrice(tt.text, tt.country)
+		assert.Equal(t, tt.amount, price.Amount, tt.text)
+		assert.Equal(t, tt.currency, price.Currency, tt.text)
+	}
+
+	assert.Equal(t, Price{Text: "Out of stock"}, parsePrice(" Out of  stock ", "us"))
+}
+
+func TestSearchGoogleShopping(t *testing.T) {
+	client, rt := newTestClient(t, serveFixture(t, "google_shopping_de.html"))
+
+	results, err := SearchGoogleShopping(context.Background(), "Espressomaschine", SearchOptions{HTTPClient: client, CountryCode: "de", Limit: 1})
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, 1299.0, results[0].Price.Value)
+	assert.Equal(t, "shop", rt.requests[0].URL.Query().Get("tbm"))
+}
+
+func TestSearchGoogleShoppingBlocked(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><form id="captcha-form" action="index"></form></body></html>`))
+	})
+
+	_, err := SearchGoogleShopping(context.Background(), "espresso machine", SearchOptions{HTTPClient: client})
+
+	assert.ErrorIs(t, err, ErrBlocked)
+}
@@ -1,20 +1,27 @@
 package search
 
 import (
+	"bytes"
 	"context"
-	"fmt"
 	"github.com/PuerkitoBio/goquery"
-	"log"
+	"io"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"errors"
+	"github.com/propro-productions/go-utils/doh"
+	"github.com/propro-productions/go-utils/internal/httpopts"
+	"github.com/propro-productions/go-utils/metrics"
+	"github.com/propro-productions/go-utils/proxy"
 	"golang.org/x/time/rate"
 )
 
 // ErrBlocked indicates that Google has detected that you were scraping and temporarily blocked you.
+// It is returned for 429 and 503 responses and for the captcha interstitial page.
 // The duration of the block is unspecified.
 //
 // See: https://github.com/rocketlaunchr/google-search#warning-warning
@@ -24,6 +31,9 @@ var ErrBlocked = errors.New("google block")
 // The default is unlimited (but obviously Google Search will block you temporarily if you do too many
 // calls too quickly).
 //
+// RateLimit is applied through DefaultLimiter and is ignored for searches that set
+// SearchOptions.Limiter; use NewHostLimiter to limit hosts independently.
+//
 // See: https://godoc.org/golang.org/x/time/rate#NewLimiter
 var RateLimit = rate.NewLimiter(rate.Inf, 0)
 
@@ -41,10 +51,38 @@ type Result struct {
 
 	// Description of the result.
 	Description string `json:"description"`
+
+	// Sitelinks are the sub-links shown under the main result, if any.
+	Sitelinks []Sitelink `json:"sitelinks,omitempty"`
+
+	// Breadcrumb is the display path of the result, e.g. "https://go.dev › doc".
+	Breadcrumb string `json:"breadcrumb,omitempty"`
+
+	// Rating is the star rating of the result, if any.
+	Rating *Rating `json:"rating,omitempty"`
+
+	// PublishedAt is the date shown in front of the description of dated pages, which is
+	// stripped from Description. It is the zero time for results without one.
+	PublishedAt time.Time `json:"published_at"`
 }
 
 const stdGoogleBase = "https://www.google."
 
+// DefaultTimeout bounds every request made with the default client, so a hung response
+// cannot block forever even without a context deadline.
+const DefaultTimeout = 30 * time.Second
+
+// DefaultMaxBodySize is the default for SearchOptions.MaxBodySize.
+const DefaultMaxBodySize = 5 << 20
+
+// maxResultsPerPage is the largest value Google accepts for the num parameter.
+const maxResultsPerPage = 100
+
+// DefaultPageSize is how many results Google returns for a page when Limit is not set.
+const DefaultPageSize = 10
+
+const defaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/61.0.3163.100 Safari/537.36"
+
 // GoogleDomains represents localized Google homepages. The 2 letter country code is based on ISO 3166-1 alpha-2.
 //
 // See: https://en.wikipedia.org/wiki/ISO_3166-1_alpha-2
@@ -249,6 +287,15 @@ var GoogleDomains = map[string]string{
 	"zw":  "co.zw/search?q=",
 }
 
+// SafeSearch is a SafeSearch filtering level, as sent in the safe parameter.
+type SafeSearch string
+
+const (
+	SafeSearchOff      SafeSearch = "off"
+	SafeSearchModerate SafeSearch = "medium"
+	SafeSearchStrict   SafeSearch = "active"
+)
+
 // SearchOptions modifies how the Search function behaves.
 type SearchOptions struct {
 
@@ -260,77 +307,494 @@ type SearchOptions struct {
 	// Default: en
 	LanguageCode string
 
-	// Limit sets how many results to fetch (at maximum).
+	// Gl biases results towards a country, given as an ISO 3166-1 alpha-2 code, independently
+	// of the Google domain chosen by CountryCode. Sent as gl.
+	Gl string
+
+	// Lr restricts results to pages written in a language, e.g. "de" or "lang_de". Several
+	// languages are separated by "|". Sent as lr.
+	Lr string
+
+	// Limit sets how many results to fetch (at maximum). Results past it are dropped, even
+	// when Google returns more.
 	Limit int
 
-	// Start sets from what rank the new result set should return.
+	// Start sets from what rank the new result set should return. Ranks are absolute, so
+	// that with Start 20 the first result has Rank 21 and windows can be stitched together.
 	Start int
 
-	// UserAgent sets the UserAgent of the http request.
+	// UserAgent sets the UserAgent of the http request. It is ignored with RotateUserAgent.
 	// Default: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/61.0.3163.100 Safari/537.36"
 	UserAgent string
 
+	// RotateUserAgent sends the User-Agent, Accept headers and client hints of a HeaderProfile
+	// picked from Profiles for every search. All pages and retries of a search use the same
+	// profile, which is reported in SearchError.
+	RotateUserAgent bool
+
+	// Profiles are picked from with RotateUserAgent.
+	// Default: DefaultProfiles.
+	Profiles []HeaderProfile
+
+	// ProfileRotation is how a profile is picked with RotateUserAgent.
+	// Default: RotateRandom.
+	ProfileRotation ProfileRotation
+
 	// OverLimit searches for more results than that specified by Limit.
 	// It then reduces the returned results to match Limit.
 	OverLimit bool
 
 	// ProxyAddr sets a proxy address to avoid IP blocking.
+	// When HTTPClient is also set, the proxy is applied to a copy of its transport.
 	ProxyAddr string
+
+	// DateRestrict limits results to a recent period: "h", "d", "w", "m" or "y",
+	// optionally followed by a count (e.g. "m6" for the past 6 months). Sent as tbs=qdr:<value>.
+	DateRestrict string
+
+	// SafeSearch sets the SafeSearch filtering level.
+	// Default: Google's own setting for the request.
+	SafeSearch SafeSearch
+
+	// NoFilter disables Google's omission of similar and duplicate results (filter=0).
+	NoFilter bool
+
+	// ExtraParams are added to the query string as is. They override any parameter
+	// set by the other options, so they can also be used to work around them.
+	ExtraParams map[string]string
+
+	// MaxRetries sets how many times a request is retried after ErrBlocked or a 5xx response.
+	// Other errors are never retried. Default: 0 (no retries).
+	MaxRetries int
+
+	// RetryBackoff sets the wait before the first retry. It doubles with every further
+	// retry and is jittered. Default: DefaultRetryBackoff.
+	RetryBackoff time.Duration
+
+	// Proxies are rotated through on every attempt: attempt n uses Proxies[n % len(Proxies)].
+	// When set, they take precedence over ProxyAddr.
+	Proxies []string
+
+	// ProxyPool hands out the proxy of every attempt and is told how it went, so that proxies
+	// that are blocked or keep failing are left out. When set, it takes precedence over
+	// Proxies and ProxyAddr.
+	ProxyPool *proxy.Pool
+
+	// Resolver, if set, looks up the hosts requested, and the proxies, with DNS over HTTPS,
+	// for networks whose resolver blocks the search engines.
+	Resolver *doh.Resolver
+
+	// NormalizeURLs rewrites result URLs with NormalizeURL.
+	NormalizeURLs bool
+
+	// Dedupe drops results whose normalized URL was already returned and re-ranks the rest.
+	// When paginating, further pages are requested to make up for dropped results.
+	Dedupe bool
+
+	// FilterLanguage drops the results whose title and description are detected to be in
+	// another language, given as an ISO 639-1 code such as "de", and re-ranks the rest.
+	// Detection runs locally. Results it is not sure about are kept, and the dropped ones
+	// are reported in SearchResponse.LanguageFiltered. It applies to SearchGoogle,
+	// SearchGoogleFull and the Custom Search API, and is applied after pagination, so fewer
+	// than Limit results may be returned.
+	FilterLanguage string
+
+	// LanguageConfidence is how confident the detection of another language must be, from 0
+	// to 1, for FilterLanguage to drop a result.
+	// Default: DefaultLanguageConfidence.
+	LanguageConfidence float64
+
+	// MinFreshness drops the results whose PublishedAt is older than it, and re-ranks the
+	// rest. Results without a date are kept. Dates without a time of day are taken as
+	// midnight UTC. Like FilterLanguage, it is applied after pagination, so fewer than Limit
+	// results may be returned.
+	MinFreshness time.Duration
+
+	// HTTPClient sets the client used for requests. It is never modified.
+	// Default: a client with Timeout.
+	HTTPClient *http.Client
+
+	// Timeout bounds every request made with the default client, including reading its body.
+	// Default: DefaultTimeout.
+	Timeout time.Duration
+
+	// Limiter is waited on before every request, keyed by the host being requested.
+	// Default: DefaultLimiter, which applies RateLimit.
+	Limiter Limiter
+
+	// Cache, if set, stores every successfully parsed results page. Pages found in the
+	// cache are returned without a request. Errors, including ErrBlocked, are never cached.
+	Cache Cache
+
+	// CacheTTL is how long pages are kept in Cache.
+	// Default: DefaultCacheTTL.
+	CacheTTL time.Duration
+
+	// ReturnRawHTML sets SearchResponse.RawHTML to the body of the results page.
+	ReturnRawHTML bool
+
+	// MaxBodySize caps how many bytes of a response body are read. Longer bodies are
+	// truncated, which at worst drops the results at the end of the page.
+	// Default: DefaultMaxBodySize.
+	MaxBodySize int64
+
+	// Layouts are tried before DefaultLayouts to parse results pages, so that a new layout of
+	// Google can be supported without waiting for a release.
+	Layouts []Layout
+
+	// CustomSearch is the Custom Search JSON API that SearchWithFallback turns to when
+	// Google blocks the scraper.
+	CustomSearch *CustomSearchProvider
+
+	// Logger receives debug messages about the requests made for the search.
+	// Default: DefaultLogger, which discards them.
+	Logger Logger
+
+	// Consent is how the cookie consent page Google serves to visitors from the EU is got
+	// past. Default: ConsentAuto.
+	Consent ConsentMode
+
+	// CookieJar stores the cookies Google sets, such as those answering the consent page.
+	// Share it between searches to answer the consent page only once.
+	// Default: the jar of HTTPClient, or else a new jar for every search, kept across its
+	// pages and retries.
+	CookieJar http.CookieJar
+
+	// OnRequest, if set, is called with every request of the search right before it is
+	// sent, with its URL, headers and client built, including the requests of retries,
+	// further pages and the consent page. It may change the headers of the request, which
+	// is sent whatever it does, but nothing else; a form posted in the body is read with
+	// GetBody. Redirects followed by the client are not passed to it. The request of
+	// SearchCustom holds the API key.
+	OnRequest func(req *http.Request)
+
+	// Metrics, if set, receives counts and timings of the requests made, blocks, retries
+	// and lookups of Cache, see package metrics.
+	Metrics metrics.Metrics
+
+	// profile is the HeaderProfile picked for the search with RotateUserAgent.
+	profile *HeaderProfile
 }
 
 // SearchGoogle returns a list of search results from Google.
+//
+// When Limit is set, additional pages are requested until Limit results have been
+// collected or Google stops returning results. With OverLimit, one extra page is
+// fetched so that duplicate URLs can be dropped while still returning Limit results.
 func SearchGoogle(ctx context.Context, searchTerm string, opts ...SearchOptions) ([]Result, error) {
+	resp, err := SearchGoogleFull(ctx, searchTerm, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// SearchGoogleAll paginates through Google results until maxResults unique URLs have been
+// collected or a page yields no new URLs. Limit and OverLimit in opts are ignored.
+//
+// Results are deduplicated by URL and ranked globally. If pagination fails part way through,
+// the results collected so far are returned alongside the error; a cancelled context is
+// reported as ctx.Err().
+func SearchGoogleAll(ctx context.Context, searchTerm string, maxResults int, opts ...SearchOptions) ([]Result, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	if err := RateLimit.Wait(ctx); err != nil {
+	opt := searchOptions(opts)
+	if err := validateOptions(opt); err != nil {
 		return nil, err
 	}
+	opt.Limit = maxResults
+	opt.OverLimit = false
 
-	// Checking options
-	opt := SearchOptions{}
-	if len(opts) > 0 {
-		opt = opts[0]
+	results, err := collectPages(opt, true, func(page SearchOptions) ([]Result, error) {
+		return searchGooglePage(ctx, searchTerm, page)
+	})
+	if err != nil && ctx.Err() != nil {
+		return results, ctx.Err()
+	}
+	return results, err
+}
+
+// searchGooglePage requests a single results page from Google.
+func searchGooglePage(ctx context.Context, searchTerm string, opt SearchOptions) ([]Result, error) {
+	resp, err := searchGoogleResponse(ctx, searchTerm, opt)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// searchGoogleResponse requests a single results page from Google and parses it with its metadata.
+func searchGoogleResponse(ctx context.Context, searchTerm string, opt SearchOptions) (*SearchResponse, error) {
+	var key string
+	if opt.Cache != nil {
+		key = cacheKey(searchTerm, opt)
+		// Pages cached without their body cannot satisfy ReturnRawHTML.
+		if resp, ok := opt.Cache.Get(key); ok && (!opt.ReturnRawHTML || resp.RawHTML != "") {
+			logger(opt).Debug("cache hit", "engine", EngineGoogle, "key", key)
+			countCache(opt, metrics.CacheHits)
+			resp = resp.clone()
+			if !opt.ReturnRawHTML {
+				resp.RawHTML = ""
+			}
+			return resp, nil
+		}
 	}
 
-	if opt.UserAgent == "" {
-		opt.UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/61.0.3163.100 Safari/537.36"
+	if opt.Cache != nil {
+		countCache(opt, metrics.CacheMisses)
 	}
 
-	client := &http.Client{}
-	log.Println(getSearchURL(searchTerm, opt))
-	req, err := http.NewRequest("GET", getSearchURL(searchTerm, opt), nil)
+	searchURL := getSearchURL(searchTerm, opt)
+	body, err := fetchGoogle(ctx, searchURL, opt)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("User-Agent", opt.UserAgent)
-	if opt.ProxyAddr != "" {
-		proxyUrl, _ := url.Parse(opt.ProxyAddr)
-		client.Transport = &http.Transport{
-			Proxy: http.ProxyURL(proxyUrl),
+	resp, err := parseResponse(bytes.NewReader(body), layouts(opt), opt.Start)
+	if err != nil {
+		logger(opt).Debug("parsing results failed", "engine", EngineGoogle, "url", searchURL, "err", err)
+		return nil, googleError(opt, searchURL, 0, err)
+	}
+	logger(opt).Debug("parsed results", "engine", EngineGoogle, "url", searchURL, "results", len(resp.Results))
+
+	if len(resp.Results) == 0 && !resp.noMatch {
+		return nil, googleError(opt, searchURL, 0, &LayoutError{Tried: layouts(opt)})
+	}
+	// Google may return more results than num asks for
+	if opt.Limit > 0 && len(resp.Results) > opt.Limit {
+		resp.Results = resp.Results[:opt.Limit]
+	}
+
+	if opt.ReturnRawHTML {
+		resp.RawHTML = string(body)
+	}
+
+	if opt.Cache != nil {
+		ttl := opt.CacheTTL
+		if ttl <= 0 {
+			ttl = DefaultCacheTTL
 		}
+		opt.Cache.Set(key, resp.clone(), ttl)
 	}
 
-	resp, err := client.Do(req)
+	return resp, nil
+}
+
+// fetchGoogle requests searchURL, retrying as configured by opt, and returns the body of
+// a successful response. Rate limiting and captcha pages are reported as ErrBlocked, and
+// consent pages that could not be got past as ErrConsentRequired.
+func fetchGoogle(ctx context.Context, searchURL string, opt SearchOptions) ([]byte, error) {
+	var body []byte
+	err := withRetry(ctx, opt, EngineGoogle, func(attempt SearchOptions) error {
+		var err error
+		body, err = fetchGoogleOnce(ctx, searchURL, attempt)
+		return err
+	})
+	return body, err
+}
+
+// fetchGoogleOnce waits for the limiter and makes a single request for searchURL.
+func fetchGoogleOnce(ctx context.Context, searchURL string, opt SearchOptions) ([]byte, error) {
+	if err := waitLimit(ctx, opt, searchURL); err != nil {
+		return nil, err
+	}
+
+	client, err := newHTTPClient(opt)
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Println("Received non-200 response code")
-		return nil, fmt.Errorf("Received non-200 response code: %d", resp.StatusCode)
+	logger(opt).Debug("search request", "engine", EngineGoogle, "url", searchURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
+	if err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	results, err := parseResults(resp)
+	setHeaders(req, opt)
+
+	body, finalURL, err := doGoogle(client, req, opt)
 	if err != nil {
-		log.Println("Error parsing results")
 		return nil, err
 	}
+	if isConsentPage(finalURL, body) {
+		return passConsent(ctx, client, req, finalURL, body, opt)
+	}
+	return body, nil
+}
+
+// doGoogle makes req and returns the body of the response with the URL it was served from.
+// Rate limiting and captcha pages are reported as ErrBlocked.
+func doGoogle(client *http.Client, req *http.Request, opt SearchOptions) ([]byte, *url.URL, error) {
+	resp, err := doRequest(client, req, opt)
+	if err != nil {
+		return nil, nil, googleError(opt, req.URL.String(), 0, err)
+	}
+	defer resp.Body.Close()
+
+	finalURL := resp.Request.URL.String()
+	logger(opt).Debug("search response", "engine", EngineGoogle, "url", finalURL, "status", resp.StatusCode)
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		return nil, nil, googleError(opt, finalURL, resp.StatusCode, ErrBlocked)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, googleError(opt, finalURL, resp.StatusCode, ErrUnexpectedStatus)
+	}
+
+	body, err := readBody(resp.Body, opt)
+	if err != nil {
+		return nil, nil, googleError(opt, finalURL, resp.StatusCode, err)
+	}
+
+	if isBlockedPage(resp.Request.URL, body) {
+		return nil, nil, googleError(opt, finalURL, resp.StatusCode, ErrCaptcha)
+	}
+
+	return body, resp.Request.URL, nil
+}
 
-	return results, nil
+func googleError(opt SearchOptions, searchURL string, statusCode int, err error) *SearchError {
+	return &SearchError{Engine: EngineGoogle, CountryCode: opt.CountryCode, URL: searchURL, StatusCode: statusCode, Profile: opt.profileName(), Err: err}
+}
+
+// collectPages calls fetch with an advancing Start until opt.Limit results are
+// collected or a page yields nothing new. With dedupe, results whose URL was already
+// collected are dropped. Ranks are renumbered from opt.Start+1 to stay continuous across
+// pages.
+//
+// On error, the results collected so far are returned alongside it.
+func collectPages(opt SearchOptions, dedupe bool, fetch func(page SearchOptions) ([]Result, error)) ([]Result, error) {
+	page := opt
+	if page.Limit > maxResultsPerPage {
+		page.Limit = maxResultsPerPage
+	}
+
+	var results []Result
+	seen := map[string]bool{}
+	extra := opt.OverLimit
+
+	for {
+		if len(results) >= opt.Limit {
+			if !extra {
+				break
+			}
+			extra = false
+		}
+
+		pageResults, err := fetch(page)
+		if errors.Is(err, ErrNoResults) && len(results) > 0 {
+			// Past the last page Google serves an empty page rather than a "no match" notice.
+			break
+		}
+		if err != nil {
+			return rankResults(results, opt.Start, opt.Limit), err
+		}
+
+		added := 0
+		for _, r := range pageResults {
+			if dedupe {
+				key := r.URL
+				if opt.Dedupe {
+					key = dedupeKey(r.URL)
+				}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			results = append(results, r)
+			added++
+		}
+
+		if added == 0 {
+			break
+		}
+		page.Start += len(pageResults)
+	}
+
+	return rankResults(results, opt.Start, opt.Limit), nil
+}
+
+// rankResults truncates results to limit and renumbers them from start+1, their position
+// on the results pages.
+func rankResults(results []Result, start, limit int) []Result {
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	for i := range results {
+		results[i].Rank = start + i + 1
+	}
+	return results
+}
+
+// searchOptions returns the first of opts (or the zero value) with defaults applied and,
+// with RotateUserAgent, the profile of the search picked.
+func searchOptions(opts []SearchOptions) SearchOptions {
+	opt := SearchOptions{}
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	if opt.UserAgent == "" {
+		opt.UserAgent = defaultUserAgent
+	}
+	if opt.CookieJar == nil && (opt.HTTPClient == nil || opt.HTTPClient.Jar == nil) {
+		// cookiejar.New never fails without options
+		opt.CookieJar, _ = cookiejar.New(nil)
+	}
+	if opt.profile == nil {
+		if opt.profile = pickProfile(opt); opt.profile != nil && opt.profile.UserAgent != "" {
+			opt.UserAgent = opt.profile.UserAgent
+		}
+	}
+	return opt
+}
+
+// readBody reads at most opt.MaxBodySize bytes of r.
+func readBody(r io.Reader, opt SearchOptions) ([]byte, error) {
+	return io.ReadAll(limitBody(r, opt))
+}
+
+func limitBody(r io.Reader, opt SearchOptions) io.Reader {
+	limit := opt.MaxBodySize
+	if limit <= 0 {
+		limit = DefaultMaxBodySize
+	}
+	return io.LimitReader(r, limit)
+}
+
+// newHTTPClient returns the client used for a search: opt.HTTPClient or a default one,
+// routed through opt.ProxyPool or opt.ProxyAddr and resolving with opt.Resolver when set.
+func newHTTPClient(opt SearchOptions) (*http.Client, error) {
+	timeout := opt.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	client, err := httpopts.NewClient(httpopts.Options{
+		Timeout:    timeout,
+		ProxyAddr:  opt.ProxyAddr,
+		ProxyPool:  opt.ProxyPool,
+		Resolver:   opt.Resolver,
+		HTTPClient: opt.HTTPClient,
+	})
+	if err != nil || opt.CookieJar == nil {
+		return client, err
+	}
+	// The client may be opt.HTTPClient, which is never modified
+	c := *client
+	c.Jar = opt.CookieJar
+	return &c, nil
+}
+
+// doRequest sends req with client, after passing it to opt.OnRequest.
+func doRequest(client *http.Client, req *http.Request, opt SearchOptions) (*http.Response, error) {
+	if opt.OnRequest != nil {
+		opt.OnRequest(req)
+	}
+	return client.Do(req)
 }
 
 func containsAny(text string, values ...string) bool {
@@ -342,50 +806,129 @@ func containsAny(text string, values ...string) bool {
 	return false
 }
 
-func parseResults(resp *http.Response) ([]Result, error) {
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	var results []Result
-	s := doc.Find(".g")
-	rank := 1
+// noMatchMarker is shown by Google when a query genuinely has no results, on English
+// pages without the card of isNoMatchPage.
+const noMatchMarker = "did not match any documents"
+
+// blockedPageMarkers are fragments of Google's "unusual traffic" captcha interstitial,
+// which is sometimes served with a 200 status, and of the captcha page of Google Scholar.
+var blockedPageMarkers = [][]byte{
+	[]byte("/sorry/index"),
+	[]byte(`id="captcha-form"`),
+	[]byte("Our systems have detected unusual traffic"),
+	[]byte(`id="gs_captcha_ccl"`),
+}
 
-	log.Println("extract s", s)
+// isBlockedPage reports whether the final request URL or the body belong to Google's block page.
+func isBlockedPage(u *url.URL, body []byte) bool {
+	if u != nil && strings.HasPrefix(u.Path, "/sorry/") {
+		return true
+	}
+	for _, marker := range blockedPageMarkers {
+		if bytes.Contains(body, marker) {
+			return true
+		}
+	}
+	return false
+}
 
-	s.Each(func(i int, el *goquery.Selection) {
-		result := Result{}
+// resultLink returns the absolute URL of the anchor a, or of the anchor around the title
+// when a is empty.
+func resultLink(a *goquery.Selection, titleEl *goquery.Selection) string {
+	if a.Length() == 0 {
+		a = titleEl.Closest("a")
+	}
 
-		result.Rank = rank
-		rank++
+	href, ok := a.Attr("href")
+	if !ok {
+		return ""
+	}
+	return cleanResultURL(href)
+}
 
-		titleEl := el.Find(".LC20lb.DKV0Md")
-		result.Title = titleEl.Text()
+// cleanResultURL unwraps Google's /url?q= redirects and drops links that are not absolute.
+func cleanResultURL(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
 
-		link, _ := titleEl.Attr("href")
-		parsedLink, _ := url.Parse(link)
-		result.URL = "http://" + parsedLink.Host + parsedLink.Path
+	if u.Path == "/url" {
+		target := u.Query().Get("q")
+		if target == "" {
+			target = u.Query().Get("url")
+		}
+		if u, err = url.Parse(target); err != nil {
+			return ""
+		}
+	}
 
-		desc := el.Find(".aCOpRe span")
-		result.Description = desc.Text()
+	if !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
 
-		results = append(results, result)
-	})
+func resultDescription(el *goquery.Selection, selectors []string) string {
+	for _, selector := range selectors {
+		if desc := strings.TrimSpace(el.Find(selector).First().Text()); desc != "" {
+			return desc
+		}
+	}
+	return ""
+}
 
-	return results, nil
+// languageRestrict formats lr for the lr parameter, adding the "lang_" prefix Google
+// expects to every language.
+func languageRestrict(lr string) string {
+	languages := strings.Split(lr, "|")
+	for i, lang := range languages {
+		lang = strings.TrimSpace(lang)
+		if !strings.HasPrefix(lang, "lang_") {
+			lang = "lang_" + lang
+		}
+		languages[i] = lang
+	}
+	return strings.Join(languages, "|")
 }
 
 func getSearchURL(searchTerm string, opts SearchOptions) string {
-	base := stdGoogleBase
-	if val, ok := GoogleDomains[opts.CountryCode]; ok {
-		base += val
-	} else {
-		base += GoogleDomains["us"]
+	domain, ok := GoogleDomains[opts.CountryCode]
+	if !ok {
+		domain = GoogleDomains["us"]
+	}
+	// GoogleDomains values carry the path and an empty q parameter; only the domain is needed here.
+	domain = strings.TrimSuffix(domain, "/search?q=")
+
+	params := url.Values{}
+	params.Set("q", searchTerm)
+	if opts.LanguageCode != "" {
+		params.Set("hl", opts.LanguageCode)
+	}
+	if opts.Gl != "" {
+		params.Set("gl", strings.ToLower(opts.Gl))
+	}
+	if opts.Lr != "" {
+		params.Set("lr", languageRestrict(opts.Lr))
+	}
+	params.Set("start", strconv.Itoa(opts.Start))
+	if opts.Limit > 0 {
+		params.Set("num", strconv.Itoa(opts.Limit))
+	}
+	if opts.DateRestrict != "" {
+		params.Set("tbs", "qdr:"+opts.DateRestrict)
+	}
+	if opts.SafeSearch != "" {
+		params.Set("safe", string(opts.SafeSearch))
+	}
+	if opts.NoFilter {
+		params.Set("filter", "0")
+	}
+	for key, value := range opts.ExtraParams {
+		params.Set(key, value)
 	}
 
-	query := url.QueryEscape(searchTerm)
-	return base + query + "&hl=" + opts.LanguageCode + "&start=" + strconv.Itoa(opts.Start)
+	return stdGoogleBase + domain + "/search?" + params.Encode()
 }
 
 func base(url string) string {
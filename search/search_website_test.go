@@ -0,0 +1,642 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/propro-productions/go-utils/proxy"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePages serves pageSize results per call from a pool of total distinct URLs.
+func fakePages(total, pageSize int) (func(page SearchOptions) ([]Result, error), *[]int) {
+	var starts []int
+	return func(page SearchOptions) ([]Result, error) {
+		starts = append(starts, page.Start)
+		var results []Result
+		for i := page.Start; i < page.Start+pageSize && i < total; i++ {
+			results = append(results, Result{Rank: i - page.Start + 1, URL: fmt.Sprintf("https://example.com/%d", i)})
+		}
+		return results, nil
+	}, &starts
+}
+
+func TestCollectPagesTruncatesToLimit(t *testing.T) {
+	fetch, starts := fakePages(100, 10)
+
+	results, err := collectPages(SearchOptions{Limit: 5}, false, fetch)
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 5)
+	assert.Equal(t, []int{0}, *starts)
+}
+
+func TestCollectPagesPaginates(t *testing.T) {
+	fetch, starts := fakePages(100, 10)
+
+	results, err := collectPages(SearchOptions{Limit: 25}, false, fetch)
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 25)
+	assert.Equal(t, []int{0, 10, 20}, *starts)
+	for i, r := range results {
+		assert.Equal(t, i+1, r.Rank)
+		assert.Equal(t, fmt.Sprintf("https://example.com/%d", i), r.URL)
+	}
+}
+
+func TestCollectPagesStopsWhenExhausted(t *testing.T) {
+	fetch, _ := fakePages(12, 10)
+
+	results, err := collectPages(SearchOptions{Limit: 50}, false, fetch)
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 12)
+}
+
+func TestCollectPagesOverLimitDeduplicates(t *testing.T) {
+	calls := 0
+	fetch := func(page SearchOptions) ([]Result, error) {
+		calls++
+		// Every page repeats the same sponsored URL.
+		return []Result{
+			{URL: "https://example.com/dup"},
+			{URL: fmt.Sprintf("https://example.com/%d", page.Start)},
+		}, nil
+	}
+
+	results, err := collectPages(SearchOptions{Limit: 3, OverLimit: true}, true, fetch)
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, 3, calls)
+	seen := map[string]bool{}
+	for _, r := range results {
+		assert.False(t, seen[r.URL], "duplicate %s", r.URL)
+		seen[r.URL] = true
+	}
+}
+
+func TestCollectPagesReturnsPartialResultsOnError(t *testing.T) {
+	fetch, starts := fakePages(100, 10)
+	failing := func(page SearchOptions) ([]Result, error) {
+		if page.Start >= 20 {
+			return nil, context.Canceled
+		}
+		return fetch(page)
+	}
+
+	results, err := collectPages(SearchOptions{Limit: 50}, true, failing)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, results, 20)
+	assert.Equal(t, []int{0, 10}, *starts)
+	assert.Equal(t, 20, results[19].Rank)
+}
+
+// serveWindows serves results pages of a search with total results, honoring start and num.
+func serveWindows(total int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		num, _ := strconv.Atoi(r.URL.Query().Get("num"))
+		if num == 0 {
+			num = DefaultPageSize
+		}
+		var b strings.Builder
+		b.WriteString(`<html><body><div id="search">`)
+		for i := start; i < start+num && i < total; i++ {
+			fmt.Fprintf(&b, `<div class="g"><div class="yuRUbf"><a href="https://example.com/%d"><h3>Result %d</h3></a></div></div>`, i+1, i+1)
+		}
+		b.WriteString(`</div></body></html>`)
+		w.Write([]byte(b.String()))
+	}
+}
+
+func TestSearchGoogleWindows(t *testing.T) {
+	client, _ := newTestClient(t, serveWindows(25))
+
+	var ranks []int
+	var urls []string
+	var ends []bool
+	for start := 0; ; start += 10 {
+		resp, err := SearchGoogleFull(context.Background(), "golang", SearchOptions{HTTPClient: client, Start: start, Limit: 10})
+		if !assert.NoError(t, err) {
+			return
+		}
+		for _, r := range resp.Results {
+			ranks = append(ranks, r.Rank)
+			urls = append(urls, r.URL)
+		}
+		ends = append(ends, resp.EndOfResults)
+		if resp.EndOfResults {
+			break
+		}
+	}
+
+	assert.Equal(t, []bool{false, false, true}, ends)
+	assert.Len(t, ranks, 25)
+	for i := range ranks {
+		assert.Equal(t, i+1, ranks[i])
+		assert.Equal(t, fmt.Sprintf("https://example.com/%d", i+1), urls[i])
+	}
+}
+
+func TestSearchGoogleWindowLimit(t *testing.T) {
+	// Google ignoring num
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		r.URL.RawQuery = "start=" + r.URL.Query().Get("start")
+		serveWindows(100)(w, r)
+	})
+
+	resp, err := SearchGoogleFull(context.Background(), "golang", SearchOptions{HTTPClient: client, Start: 20, Limit: 3})
+
+	assert.NoError(t, err)
+	if assert.Len(t, resp.Results, 3) {
+		assert.Equal(t, 21, resp.Results[0].Rank)
+		assert.Equal(t, "https://example.com/21", resp.Results[0].URL)
+		assert.Equal(t, 23, resp.Results[2].Rank)
+	}
+	assert.False(t, resp.EndOfResults)
+
+	// A single page without Limit
+	resp, err = SearchGoogleFull(context.Background(), "golang", SearchOptions{HTTPClient: client, Start: 95})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Results, 5)
+	assert.Equal(t, 96, resp.Results[0].Rank)
+	assert.True(t, resp.EndOfResults)
+}
+
+func TestSearchGoogleEndOfResultsPageLinks(t *testing.T) {
+	// Fewer than DefaultPageSize results, the other slots holding other kinds of results
+	client, _ := newTestClient(t, serveFixture(t, "google_results_partial.html"))
+
+	resp, err := SearchGoogleFull(context.Background(), "golang", SearchOptions{HTTPClient: client})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Results, 7)
+	assert.False(t, resp.EndOfResults)
+
+	// The last page links to the previous pages only
+	client, _ = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><div id="rso"><div class="g"><div class="yuRUbf"><a href="https://go.dev/"><h3>Go</h3></a></div></div></div>` +
+			`<table class="AaVjTc"><tr><td><a id="pnprev" href="/search?q=golang&amp;start=10">Previous</a></td><td>3</td></tr></table></body></html>`))
+	})
+
+	resp, err = SearchGoogleFull(context.Background(), "golang", SearchOptions{HTTPClient: client, Start: 20})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Results, 1)
+	assert.True(t, resp.EndOfResults)
+}
+
+func TestGetSearchURL(t *testing.T) {
+	tests := []struct {
+		name string
+		term string
+		opts SearchOptions
+		want string
+	}{
+		{
+			name: "defaults",
+			term: "golang",
+			want: "https://www.google.com/search?q=golang&start=0",
+		},
+		{
+			name: "country, language and paging",
+			term: "golang",
+			opts: SearchOptions{CountryCode: "de", LanguageCode: "de", Start: 10, Limit: 20},
+			want: "https://www.google.de/search?hl=de&num=20&q=golang&start=10",
+		},
+		{
+			name: "geolocation and language restrict",
+			term: "golang",
+			opts: SearchOptions{Gl: "AT", Lr: "de|lang_en"},
+			want: "https://www.google.com/search?gl=at&lr=lang_de%7Clang_en&q=golang&start=0",
+		},
+		{
+			name: "special characters are encoded",
+			term: `c++ & "go" #1`,
+			want: "https://www.google.com/search?q=c%2B%2B+%26+%22go%22+%231&start=0",
+		},
+		{
+			name: "date, safe search and filter",
+			term: "golang",
+			opts: SearchOptions{DateRestrict: "w", SafeSearch: SafeSearchStrict, NoFilter: true},
+			want: "https://www.google.com/search?filter=0&q=golang&safe=active&start=0&tbs=qdr%3Aw",
+		},
+		{
+			name: "extra params override built-in ones",
+			term: "golang",
+			opts: SearchOptions{SafeSearch: SafeSearchStrict, ExtraParams: map[string]string{"safe": "off", "tbm": "nws"}},
+			want: "https://www.google.com/search?q=golang&safe=off&start=0&tbm=nws",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, getSearchURL(tt.term, tt.opts))
+		})
+	}
+}
+
+func TestIsBlockedPage(t *testing.T) {
+	sorry, _ := url.Parse("https://www.google.com/sorry/index?continue=https://www.google.com/search")
+	normal, _ := url.Parse("https://www.google.com/search?q=golang")
+
+	assert.True(t, isBlockedPage(sorry, nil))
+	assert.True(t, isBlockedPage(normal, []byte(`<form id="captcha-form" action="index" method="post">`)))
+	assert.True(t, isBlockedPage(normal, []byte("Our systems have detected unusual traffic from your computer network.")))
+	assert.False(t, isBlockedPage(normal, []byte(`<div class="g"><h3>golang</h3></div>`)))
+}
+
+func parseFixture(t *testing.T, name string) []Result {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	results, err := parseResults(f, 0)
+	assert.NoError(t, err)
+	return results
+}
+
+func TestParseResults(t *testing.T) {
+	results := parseFixture(t, "google_results.html")
+
+	assert.Equal(t, []Result{
+		{
+			Rank:        1,
+			URL:         "https://go.dev/",
+			Title:       "The Go Programming Language",
+			Description: "Go is an open source programming language that makes it simple to build secure, scalable systems.",
+			Breadcrumb:  "https://go.dev",
+			Sitelinks: []Sitelink{
+				{URL: "https://go.dev/learn/", Title: "Get Started"},
+				{URL: "https://go.dev/doc/", Title: "Documentation"},
+				{URL: "https://go.dev/dl/", Title: "Downloads"},
+			},
+		},
+		{
+			Rank:        2,
+			URL:         "https://pkg.go.dev/search?q=golang&m=package",
+			Title:       "golang packages - Go Packages",
+			Description: "Search results for golang on Go Packages.",
+			Breadcrumb:  "https://pkg.go.dev › search",
+			Rating:      &Rating{Value: 4.6, Count: 1024},
+			Sitelinks: []Sitelink{
+				{URL: "https://pkg.go.dev/std", Title: "Standard library"},
+				{URL: "https://pkg.go.dev/about", Title: "About"},
+			},
+		},
+		{
+			Rank:        3,
+			URL:         "http://golang.example.org/tour",
+			Title:       "A Tour of Go",
+			Description: "Welcome to a tour of the Go programming language.",
+		},
+	}, results)
+}
+
+func TestParseResultsOffset(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "google_results.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	results, err := parseResults(f, 20)
+
+	assert.NoError(t, err)
+	var ranks []int
+	for _, r := range results {
+		ranks = append(ranks, r.Rank)
+	}
+	assert.Equal(t, []int{21, 22, 23}, ranks)
+}
+
+func TestParseResultsLegacyLayout(t *testing.T) {
+	results := parseFixture(t, "google_results_legacy.html")
+
+	assert.Equal(t, []Result{
+		{
+			Rank:        1,
+			URL:         "https://en.wikipedia.org/wiki/Go_(programming_language)",
+			Title:       "Go (programming language) - Wikipedia",
+			Description: "Go is a statically typed, compiled programming language designed at Google.",
+		},
+		{
+			Rank:        2,
+			URL:         "https://github.com/golang/go?tab=readme",
+			Title:       "golang/go - GitHub",
+			Description: "The Go programming language. Contribute to golang/go development.",
+		},
+	}, results)
+}
+
+func TestParseResultsLocalizedLayouts(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    []Result
+	}{
+		{
+			fixture: "google_results_de.html",
+			want: []Result{
+				{
+					Rank:        1,
+					URL:         "https://go.dev/",
+					Title:       "The Go Programming Language",
+					Description: "Go ist eine quelloffene Programmiersprache, mit der sich einfache, zuverlässige Software erstellen lässt.",
+					Breadcrumb:  "https://go.dev",
+				},
+				{
+					Rank:        2,
+					URL:         "https://de.wikipedia.org/wiki/Go_(Programmiersprache)",
+					Title:       "Go (Programmiersprache) – Wikipedia",
+					Description: "Go ist eine kompilierbare Programmiersprache, die Nebenläufigkeit unterstützt und über eine automatische Speicherbereinigung verfügt.",
+					Breadcrumb:  "https://de.wikipedia.org › wiki › Go_(Programmie...",
+				},
+			},
+		},
+		{
+			fixture: "google_results_ja.html",
+			want: []Result{
+				{
+					Rank:        1,
+					URL:         "https://go.dev/",
+					Title:       "The Go Programming Language",
+					Description: "Go はシンプルで信頼性が高く、効率的なソフトウェアを簡単に構築できるオープンソースのプログラミング言語です。",
+				},
+				{
+					Rank:        2,
+					URL:         "https://ja.wikipedia.org/wiki/Go_(%E3%83%97%E3%83%AD%E3%82%B0%E3%83%A9%E3%83%9F%E3%83%B3%E3%82%B0%E8%A8%80%E8%AA%9E)",
+					Title:       "Go (プログラミング言語) - Wikipedia",
+					Description: "Go はプログラミング言語の1つである。2009年、Google で開発された。",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseFixture(t, tt.fixture))
+		})
+	}
+}
+
+// rewriteTransport sends every request to the test server, keeping the original path and query.
+type rewriteTransport struct {
+	target   *url.URL
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func (rt *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.mu.Lock()
+	rt.requests = append(rt.requests, req)
+	rt.mu.Unlock()
+
+	r := req.Clone(req.Context())
+	// The server sees the host that was requested, as redirected requests leave Host empty
+	if r.Host == "" {
+		r.Host = req.URL.Host
+	}
+	r.URL.Scheme = rt.target.Scheme
+	r.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// newTestClient starts a server for handler and returns a client that routes every request to it.
+func newTestClient(t *testing.T, handler http.HandlerFunc) (*http.Client, *rewriteTransport) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	target, _ := url.Parse(server.URL)
+	rt := &rewriteTransport{target: target}
+	return &http.Client{Transport: rt}, rt
+}
+
+func serveFixture(t *testing.T, name string) http.HandlerFunc {
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+		w.Write(body)
+	}
+}
+
+func TestSearchGoogleUsesHTTPClient(t *testing.T) {
+	client, rt := newTestClient(t, serveFixture(t, "google_results.html"))
+
+	results, err := SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client, UserAgent: "test-agent"})
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.Len(t, rt.requests, 1)
+	assert.Equal(t, "www.google.com", rt.requests[0].URL.Host)
+	assert.Equal(t, "test-agent", rt.requests[0].Header.Get("User-Agent"))
+}
+
+func TestSearchGoogleBlockedStatus(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	_, err := SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client})
+
+	assert.ErrorIs(t, err, ErrBlocked)
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		client, err := newHTTPClient(SearchOptions{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultTimeout, client.Timeout)
+	})
+
+	t.Run("provided client is used as is", func(t *testing.T) {
+		provided := &http.Client{Timeout: time.Second}
+		client, err := newHTTPClient(SearchOptions{HTTPClient: provided})
+
+		assert.NoError(t, err)
+		assert.Same(t, provided, client)
+	})
+
+	t.Run("proxy wraps provided transport", func(t *testing.T) {
+		transport := &http.Transport{MaxIdleConns: 7}
+		provided := &http.Client{Timeout: time.Second, Transport: transport}
+		client, err := newHTTPClient(SearchOptions{HTTPClient: provided, ProxyAddr: "http://proxy.local:8080"})
+
+		assert.NoError(t, err)
+		assert.NotSame(t, provided, client)
+		assert.Equal(t, time.Second, client.Timeout)
+		assert.Nil(t, transport.Proxy)
+
+		proxied := client.Transport.(*http.Transport)
+		assert.Equal(t, 7, proxied.MaxIdleConns)
+		proxyUrl, _ := proxied.Proxy(httptest.NewRequest("GET", "https://www.google.com/", nil))
+		assert.Equal(t, "proxy.local:8080", proxyUrl.Host)
+	})
+
+	t.Run("proxy pool takes precedence", func(t *testing.T) {
+		pool, _ := proxy.NewPool("http://pool.local:8080")
+		provided := &http.Client{Timeout: time.Second}
+		client, err := newHTTPClient(SearchOptions{HTTPClient: provided, ProxyPool: pool, ProxyAddr: "http://proxy.local:8080"})
+
+		assert.NoError(t, err)
+		assert.NotSame(t, provided, client)
+		assert.Nil(t, provided.Transport)
+		_, isTransport := client.Transport.(*http.Transport)
+		assert.False(t, isTransport)
+
+		_, err = newHTTPClient(SearchOptions{HTTPClient: &http.Client{Transport: &rewriteTransport{}}, ProxyPool: pool})
+		assert.ErrorContains(t, err, "ProxyPool")
+	})
+
+	t.Run("proxy cannot wrap custom round tripper", func(t *testing.T) {
+		provided := &http.Client{Transport: &rewriteTransport{}}
+		_, err := newHTTPClient(SearchOptions{HTTPClient: provided, ProxyAddr: "http://proxy.local:8080"})
+
+		assert.Error(t, err)
+	})
+}
+
+// receivedRequests records the requests a test server receives.
+type receivedRequests struct {
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func (rr *receivedRequests) record(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rr.mu.Lock()
+		rr.requests = append(rr.requests, r.Clone(context.Background()))
+		rr.mu.Unlock()
+		handler(w, r)
+	}
+}
+
+func TestSearchGoogleOnRequest(t *testing.T) {
+	var received receivedRequests
+	client, _ := newTestClient(t, received.record(serveFixture(t, "google_results.html")))
+
+	var hooked []*http.Request
+	opt := SearchOptions{
+		HTTPClient:   client,
+		CountryCode:  "de",
+		Gl:           "AT",
+		LanguageCode: "de",
+		SafeSearch:   SafeSearchStrict,
+		UserAgent:    "compliance-agent",
+		OnRequest: func(req *http.Request) {
+			req.Header.Set("X-Audit-Id", "42")
+			hooked = append(hooked, req)
+		},
+	}
+	_, err := SearchGoogle(context.Background(), "golang", opt)
+
+	assert.NoError(t, err)
+	if !assert.Len(t, hooked, 1) || !assert.Len(t, received.requests, 1) {
+		return
+	}
+	req, got := hooked[0], received.requests[0]
+	assert.Equal(t, "www.google.de", req.URL.Host)
+	assert.Equal(t, "active", req.URL.Query().Get("safe"))
+	assert.Equal(t, "at", req.URL.Query().Get("gl"))
+
+	// The server receives the request as the hook saw it, with its changes
+	assert.Equal(t, req.URL.Host, got.Host)
+	assert.Equal(t, req.URL.RawQuery, got.URL.RawQuery)
+	for key := range req.Header {
+		assert.Equal(t, req.Header.Values(key), got.Header.Values(key), key)
+	}
+	assert.Equal(t, "compliance-agent", got.Header.Get("User-Agent"))
+	assert.Equal(t, "42", got.Header.Get("X-Audit-Id"))
+}
+
+func TestSearchGoogleOnRequestRetries(t *testing.T) {
+	var received receivedRequests
+	fixture := serveFixture(t, "google_results.html")
+	client, _ := newTestClient(t, received.record(func(w http.ResponseWriter, r *http.Request) {
+		if len(received.requests) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fixture(w, r)
+	}))
+
+	var hooked []string
+	opt := SearchOptions{HTTPClient: client, MaxRetries: 2, RetryBackoff: time.Millisecond, SafeSearch: SafeSearchStrict,
+		OnRequest: func(req *http.Request) { hooked = append(hooked, req.URL.String()) }}
+	_, err := SearchGoogle(context.Background(), "golang", opt)
+
+	assert.NoError(t, err)
+	assert.Len(t, received.requests, 2)
+	if assert.Len(t, hooked, 2) {
+		assert.Equal(t, hooked[0], hooked[1])
+		assert.Contains(t, hooked[1], "safe=active")
+	}
+}
+
+func TestSearchGoogleOnRequestPages(t *testing.T) {
+	var received receivedRequests
+	client, _ := newTestClient(t, received.record(rankingServer(100, map[int]string{14: "https://example.com/"})))
+
+	var starts []string
+	opt := SearchOptions{HTTPClient: client, Gl: "fr", OnRequest: func(req *http.Request) {
+		starts = append(starts, req.URL.Query().Get("start"))
+		assert.Equal(t, "fr", req.URL.Query().Get("gl"))
+	}}
+	_, err := FindDomainRank(context.Background(), "example.com", "widgets", 50, opt)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0", "10"}, starts)
+	if assert.Len(t, received.requests, 2) {
+		assert.Equal(t, "10", received.requests[1].URL.Query().Get("start"))
+	}
+}
+
+func TestSearchDuckDuckGoOnRequest(t *testing.T) {
+	var received receivedRequests
+	var forms []string
+	fixture := serveFixture(t, "duckduckgo_results.html")
+	client, _ := newTestClient(t, received.record(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		forms = append(forms, string(body))
+		fixture(w, r)
+	}))
+
+	var hooked []string
+	opt := SearchOptions{HTTPClient: client, CountryCode: "at", LanguageCode: "de", OnRequest: func(req *http.Request) {
+		req.Header.Set("Accept-Language", "de-AT")
+		// The form is read from a copy of the body, which is still sent
+		body, _ := req.GetBody()
+		form, _ := io.ReadAll(body)
+		hooked = append(hooked, string(form))
+	}}
+	_, err := SearchDuckDuckGo(context.Background(), "golang", opt)
+
+	assert.NoError(t, err)
+	if assert.Len(t, hooked, 1) && assert.Len(t, received.requests, 1) {
+		assert.Equal(t, hooked, forms)
+		assert.Contains(t, forms[0], "kl=at-de")
+		assert.Equal(t, "de-AT", received.requests[0].Header.Get("Accept-Language"))
+	}
+}
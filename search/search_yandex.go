@@ -0,0 +1,206 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const stdYandexBase = "https://yandex.ru/search/"
+
+// yandexPageSize is how many organic results Yandex serves per page. The page size cannot
+// be set, so Start is rounded down to a multiple of it.
+const yandexPageSize = 10
+
+// YandexRegions maps ISO 3166-1 alpha-2 country codes to the Yandex region IDs sent as lr
+// by SearchYandex.
+var YandexRegions = map[string]int{
+	"ru": 225,
+	"ua": 187,
+	"by": 149,
+	"kz": 159,
+	"uz": 171,
+	"tr": 983,
+	"de": 96,
+	"us": 84,
+	"gb": 102,
+}
+
+// SearchYandex returns a list of search results from Yandex.
+//
+// CountryCode selects the region (`lr`) through YandexRegions, and Start the page (`p`) it
+// falls on. Limit is satisfied by requesting further pages. LanguageCode is ignored.
+// Yandex's captcha pages are reported as ErrCaptcha, which wraps ErrBlocked.
+func SearchYandex(ctx context.Context, searchTerm string, opts ...SearchOptions) ([]Result, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	opt := searchOptions(opts)
+	if err := validateOptions(opt); err != nil {
+		return nil, err
+	}
+
+	client, err := newHTTPClient(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	page := opt.Start / yandexPageSize
+	fetch := func(SearchOptions) (results []Result, err error) {
+		err = observeRequest(opt, EngineYandex, func() error {
+			results, err = searchYandexPage(ctx, client, getYandexURL(searchTerm, page, opt), opt)
+			return err
+		})
+		page++
+		return results, err
+	}
+
+	if opt.Limit <= 0 {
+		return fetch(opt)
+	}
+
+	results, err := collectPages(opt, opt.OverLimit, fetch)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// searchYandexPage requests a single page of results.
+func searchYandexPage(ctx context.Context, client *http.Client, searchURL string, opt SearchOptions) ([]Result, error) {
+	if err := waitLimit(ctx, opt, searchURL); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	setHeaders(req, opt)
+
+	logger(opt).Debug("search request", "engine", EngineYandex, "url", searchURL)
+	resp, err := doRequest(client, req, opt)
+	if err != nil {
+		return nil, yandexError(opt, searchURL, 0, err)
+	}
+	defer resp.Body.Close()
+	logger(opt).Debug("search response", "engine", EngineYandex, "url", searchURL, "status", resp.StatusCode)
+
+	finalURL := resp.Request.URL.String()
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusForbidden {
+		return nil, yandexError(opt, finalURL, resp.StatusCode, ErrBlocked)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, yandexError(opt, finalURL, resp.StatusCode, ErrUnexpectedStatus)
+	}
+
+	body, err := readBody(resp.Body, opt)
+	if err != nil {
+		return nil, yandexError(opt, finalURL, resp.StatusCode, err)
+	}
+	if isYandexCaptcha(resp.Request.URL, body) {
+		return nil, yandexError(opt, finalURL, resp.StatusCode, ErrCaptcha)
+	}
+
+	results, err := parseYandexResults(bytes.NewReader(body))
+	if err != nil {
+		return nil, yandexError(opt, finalURL, resp.StatusCode, err)
+	}
+	logger(opt).Debug("parsed results", "engine", EngineYandex, "url", searchURL, "results", len(results))
+	return results, nil
+}
+
+func yandexError(opt SearchOptions, searchURL string, statusCode int, err error) *SearchError {
+	return &SearchError{Engine: EngineYandex, CountryCode: opt.CountryCode, URL: searchURL, StatusCode: statusCode, Profile: opt.profileName(), Err: err}
+}
+
+// yandexCaptchaMarkers appear on the checkbox and image captcha pages Yandex serves to
+// suspected bots, with a 200 status.
+var yandexCaptchaMarkers = [][]byte{
+	[]byte("/checkcaptcha"),
+	[]byte("CheckboxCaptcha"),
+	[]byte("AdvancedCaptcha"),
+	[]byte("smart-captcha"),
+}
+
+// isYandexCaptcha reports whether a search was redirected to, or answered with, a captcha.
+func isYandexCaptcha(u *url.URL, body []byte) bool {
+	if u != nil && (strings.HasPrefix(u.Path, "/showcaptcha") || strings.HasPrefix(u.Path, "/checkcaptcha")) {
+		return true
+	}
+	for _, marker := range yandexCaptchaMarkers {
+		if bytes.Contains(body, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func getYandexURL(searchTerm string, page int, opts SearchOptions) string {
+	u := stdYandexBase + "?text=" + url.QueryEscape(searchTerm)
+	if region, ok := YandexRegions[strings.ToLower(opts.CountryCode)]; ok {
+		u += "&lr=" + strconv.Itoa(region)
+	}
+	if page > 0 {
+		u += "&p=" + strconv.Itoa(page)
+	}
+	return u
+}
+
+func parseYandexResults(r io.Reader) ([]Result, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	rank := 1
+
+	doc.Find("#search-result .serp-item").Each(func(i int, el *goquery.Selection) {
+		// Ads are served by Yandex Direct and link through yabs.yandex.ru.
+		if el.AttrOr("data-fast-name", "") == "direct" {
+			return
+		}
+
+		titleEl := el.Find(".OrganicTitle-Link, .organic__url").First()
+		link := yandexTarget(titleEl.AttrOr("href", ""))
+		if link == "" {
+			return
+		}
+
+		result := Result{}
+		result.Rank = rank
+		rank++
+
+		result.URL = link
+		result.Title = strings.TrimSpace(titleEl.Find(".OrganicTitleContentSpan, .organic__title").First().Text())
+		if result.Title == "" {
+			result.Title = strings.TrimSpace(titleEl.Text())
+		}
+		result.Description = strings.TrimSpace(el.Find(".OrganicTextContentSpan, .organic__text").First().Text())
+
+		results = append(results, result)
+	})
+
+	return results, nil
+}
+
+// yandexTarget returns href when it is an absolute link off Yandex's ad network.
+func yandexTarget(href string) string {
+	u, err := url.Parse(href)
+	if err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	if u.Hostname() == "yabs.yandex.ru" {
+		return ""
+	}
+	return u.String()
+}
@@ -0,0 +1,103 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseYandexResults(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "yandex_results.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	results, err := parseYandexResults(f)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Result{
+		{Rank: 1, URL: "https://go.dev/", Title: "The Go Programming Language", Description: "Go is an open source programming language that makes it simple to build secure, scalable systems."},
+		{Rank: 2, URL: "https://ru.wikipedia.org/wiki/Go", Title: "Go — Википедия", Description: "Go (часто также golang) — компилируемый многопоточный язык программирования, разработанный внутри компании Google."},
+		{Rank: 3, URL: "https://habr.com/ru/hub/go/", Title: "Go – Хабр", Description: "Статьи и новости о языке Go."},
+	}, results)
+}
+
+func TestGetYandexURL(t *testing.T) {
+	assert.Equal(t, "https://yandex.ru/search/?text=go+lang", getYandexURL("go lang", 0, SearchOptions{}))
+	assert.Equal(t, "https://yandex.ru/search/?text=go+lang&lr=225&p=2", getYandexURL("go lang", 2, SearchOptions{CountryCode: "ru"}))
+	assert.Equal(t, "https://yandex.ru/search/?text=go", getYandexURL("go", 0, SearchOptions{CountryCode: "fr"}))
+}
+
+func TestSearchYandex(t *testing.T) {
+	client, rt := newTestClient(t, serveFixture(t, "yandex_results.html"))
+
+	results, err := SearchYandex(context.Background(), "golang", SearchOptions{HTTPClient: client, UserAgent: "test-agent", CountryCode: "ru"})
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.Len(t, rt.requests, 1)
+	assert.Equal(t, "yandex.ru", rt.requests[0].URL.Host)
+	assert.Equal(t, "golang", rt.requests[0].URL.Query().Get("text"))
+	assert.Equal(t, "225", rt.requests[0].URL.Query().Get("lr"))
+	assert.Equal(t, "test-agent", rt.requests[0].Header.Get("User-Agent"))
+}
+
+func TestSearchYandexPages(t *testing.T) {
+	client, rt := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("p"))
+		w.Write([]byte(`<ul id="search-result">
+			<li class="serp-item"><a class="OrganicTitle-Link" href="https://example.com/` + strconv.Itoa(page) + `/a">A</a></li>
+			<li class="serp-item"><a class="OrganicTitle-Link" href="https://example.com/` + strconv.Itoa(page) + `/b">B</a></li>
+		</ul>`))
+	})
+
+	results, err := SearchYandex(context.Background(), "golang", SearchOptions{HTTPClient: client, Limit: 5, Start: 10})
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 5)
+	assert.Equal(t, "https://example.com/1/a", results[0].URL)
+	assert.Equal(t, "https://example.com/3/a", results[4].URL)
+	assert.Equal(t, 15, results[4].Rank)
+	assert.Len(t, rt.requests, 3)
+}
+
+func TestSearchYandexCaptcha(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"captcha page", serveFixture(t, "yandex_captcha.html")},
+		{"captcha redirect", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/search/" {
+				http.Redirect(w, r, "/showcaptcha?cc=1&retpath=https%3A%2F%2Fyandex.ru%2Fsearch%2F", http.StatusFound)
+				return
+			}
+			w.Write([]byte(`<html><body>robot check</body></html>`))
+		}},
+		{"too many requests", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestClient(t, tt.handler)
+
+			results, err := SearchYandex(context.Background(), "golang", SearchOptions{HTTPClient: client})
+
+			assert.Nil(t, results)
+			assert.True(t, errors.Is(err, ErrBlocked))
+			var searchErr *SearchError
+			if assert.True(t, errors.As(err, &searchErr)) {
+				assert.Equal(t, EngineYandex, searchErr.Engine)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Searcher is implemented by every search engine backend in this package.
+type Searcher interface {
+	Search(ctx context.Context, searchTerm string, opts ...SearchOptions) ([]Result, error)
+}
+
+// SearcherFunc adapts an ordinary search function such as SearchGoogle to the Searcher interface.
+type SearcherFunc func(ctx context.Context, searchTerm string, opts ...SearchOptions) ([]Result, error)
+
+// Search calls f(ctx, searchTerm, opts...).
+func (f SearcherFunc) Search(ctx context.Context, searchTerm string, opts ...SearchOptions) ([]Result, error) {
+	return f(ctx, searchTerm, opts...)
+}
+
+// Engine identifies a search engine backend.
+type Engine string
+
+const (
+	EngineGoogle     Engine = "google"
+	EngineBing       Engine = "bing"
+	EngineDuckDuckGo Engine = "duckduckgo"
+	EngineYandex     Engine = "yandex"
+
+	// EngineCustomSearch is Google's Custom Search JSON API, queried by CustomSearchProvider.
+	EngineCustomSearch Engine = "customsearch"
+
+	// EngineGoogleCache and EngineWayback are the sources of the copies FetchCached returns.
+	EngineGoogleCache Engine = "googlecache"
+	EngineWayback     Engine = "wayback"
+)
+
+// Engines maps every known Engine to its Searcher. The iteration order used for
+// fallbacks is defined by EngineOrder, not by this map.
+var Engines = map[Engine]Searcher{
+	EngineGoogle:     SearcherFunc(SearchGoogle),
+	EngineBing:       SearcherFunc(SearchBing),
+	EngineDuckDuckGo: SearcherFunc(SearchDuckDuckGo),
+	EngineYandex:     SearcherFunc(SearchYandex),
+}
+
+// EngineOrder is the order in which engines are tried after the preferred one is blocked.
+var EngineOrder = []Engine{EngineGoogle, EngineBing, EngineDuckDuckGo}
+
+// Search runs searchTerm against the preferred engine. When that engine returns ErrBlocked,
+// the remaining engines in EngineOrder are tried in turn. Any other error is returned immediately.
+func Search(ctx context.Context, searchTerm string, preferred Engine, opts ...SearchOptions) ([]Result, error) {
+	if _, ok := Engines[preferred]; !ok {
+		return nil, fmt.Errorf("unknown search engine: %q", preferred)
+	}
+
+	engines := []Engine{preferred}
+	for _, e := range EngineOrder {
+		if e != preferred {
+			engines = append(engines, e)
+		}
+	}
+
+	var err error
+	for _, e := range engines {
+		searcher, ok := Engines[e]
+		if !ok {
+			continue
+		}
+
+		var results []Result
+		results, err = searcher.Search(ctx, searchTerm, opts...)
+		if err == nil {
+			return results, nil
+		}
+		if !errors.Is(err, ErrBlocked) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
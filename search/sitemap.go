@@ -0,0 +1,422 @@
+package search
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/propro-productions/go-utils/robots"
+)
+
+const (
+	// DefaultSitemapMaxDepth is how deep sitemap indexes are followed when
+	// SitemapOptions.MaxDepth is not set.
+	DefaultSitemapMaxDepth = 3
+
+	// DefaultSitemapMaxEntries caps the entries returned when SitemapOptions.MaxEntries is
+	// not set.
+	DefaultSitemapMaxEntries = 100000
+
+	// DefaultSitemapMaxSitemaps caps the sitemap files fetched when
+	// SitemapOptions.MaxSitemaps is not set.
+	DefaultSitemapMaxSitemaps = 1000
+)
+
+// maxSitemapSize is the largest uncompressed sitemap allowed by the protocol.
+const maxSitemapSize = 50 << 20
+
+// ErrNotSitemap is returned for documents that are neither a urlset nor a sitemapindex.
+var ErrNotSitemap = errors.New("not a sitemap")
+
+// ChangeFreq is how often a page is expected to change, as declared by its sitemap.
+type ChangeFreq string
+
+const (
+	ChangeFreqAlways  ChangeFreq = "always"
+	ChangeFreqHourly  ChangeFreq = "hourly"
+	ChangeFreqDaily   ChangeFreq = "daily"
+	ChangeFreqWeekly  ChangeFreq = "weekly"
+	ChangeFreqMonthly ChangeFreq = "monthly"
+	ChangeFreqYearly  ChangeFreq = "yearly"
+	ChangeFreqNever   ChangeFreq = "never"
+)
+
+// SitemapEntry is a page listed by a sitemap.
+type SitemapEntry struct {
+
+	// URL of the page.
+	URL string `json:"url"`
+
+	// LastMod is when the page last changed, zero when the sitemap doesn't say or the
+	// date is malformed.
+	LastMod time.Time `json:"lastmod"`
+
+	// ChangeFreq is empty when the sitemap doesn't say.
+	ChangeFreq ChangeFreq `json:"changefreq,omitempty"`
+
+	// Priority is between 0 and 1. Pages without one get the default of the protocol, 0.5.
+	Priority float64 `json:"priority"`
+
+	// Sitemap is the URL of the sitemap file listing the page.
+	Sitemap string `json:"sitemap"`
+}
+
+// SitemapOptions modifies how FetchSitemap behaves.
+type SitemapOptions struct {
+
+	// MaxDepth is how many levels of sitemap indexes are followed below the first sitemap.
+	// Default: DefaultSitemapMaxDepth
+	MaxDepth int
+
+	// MaxEntries caps the number of entries returned. Fetching stops once it is reached.
+	// Default: DefaultSitemapMaxEntries
+	MaxEntries int
+
+	// MaxSitemaps caps the number of sitemap files fetched, indexes included.
+	// Default: DefaultSitemapMaxSitemaps
+	MaxSitemaps int
+
+	// UserAgent sets the UserAgent of the http requests.
+	// Default: the default UserAgent of SearchOptions
+	UserAgent string
+
+	// HTTPClient sets the client used for requests, robots.txt included.
+	// Default: a client with DefaultTimeout.
+	HTTPClient *http.Client
+
+	// Limiter is waited on before every request, keyed by the host being requested.
+	// Default: DefaultLimiter, which applies RateLimit.
+	Limiter Limiter
+
+	// Logger receives debug messages about the sitemaps fetched.
+	// Default: DefaultLogger, which discards them.
+	Logger Logger
+}
+
+// FetchSitemap returns the pages listed by the sitemap at sitemapURL. Sitemap indexes are
+// followed recursively up to MaxDepth, and gzipped sitemaps are decompressed.
+//
+// When sitemapURL has no path, like "example.com" or "https://example.com/", the sitemaps
+// are looked up in the robots.txt of the site, falling back to /sitemap.xml. A URL without
+// scheme is fetched over https.
+//
+// ctx bounds the whole fetch: once it ends, the entries collected so far are returned with
+// ctx.Err(). Sitemaps that fail are skipped, and their errors are returned joined along
+// with the entries of the others.
+func FetchSitemap(ctx context.Context, sitemapURL string, opts ...SitemapOptions) ([]SitemapEntry, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	opt := sitemapOptions(opts)
+
+	if !strings.Contains(sitemapURL, "://") {
+		sitemapURL = "https://" + sitemapURL
+	}
+	u, err := url.Parse(sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("sitemap: unsupported URL scheme %q", u.Scheme)
+	}
+
+	client := opt.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+	f := &sitemapFetcher{opt: opt, client: client, seen: map[string]bool{}}
+
+	if u.Path == "" || u.Path == "/" {
+		roots, err := f.discover(ctx, u)
+		if err != nil {
+			return nil, err
+		}
+		for _, root := range roots {
+			f.fetch(ctx, root, 0)
+		}
+	} else {
+		f.fetch(ctx, u.String(), 0)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return f.entries, err
+	}
+	return f.entries, errors.Join(f.errs...)
+}
+
+// sitemapOptions returns the first of opts (or the zero value) with defaults applied.
+func sitemapOptions(opts []SitemapOptions) SitemapOptions {
+	opt := SitemapOptions{}
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	if opt.MaxDepth <= 0 {
+		opt.MaxDepth = DefaultSitemapMaxDepth
+	}
+	if opt.MaxEntries <= 0 {
+		opt.MaxEntries = DefaultSitemapMaxEntries
+	}
+	if opt.MaxSitemaps <= 0 {
+		opt.MaxSitemaps = DefaultSitemapMaxSitemaps
+	}
+	if opt.UserAgent == "" {
+		opt.UserAgent = defaultUserAgent
+	}
+	if opt.Logger == nil {
+		opt.Logger = DefaultLogger
+	}
+	return opt
+}
+
+type sitemapFetcher struct {
+	opt    SitemapOptions
+	client *http.Client
+
+	seen    map[string]bool
+	fetched int
+	entries []SitemapEntry
+	errs    []error
+}
+
+// discover returns the sitemaps listed in the robots.txt of the site of u, or its
+// /sitemap.xml when there are none.
+func (f *sitemapFetcher) discover(ctx context.Context, u *url.URL) ([]string, error) {
+	checker := robots.DefaultChecker
+	if f.opt.HTTPClient != nil {
+		checker = &robots.Checker{HTTPClient: f.opt.HTTPClient}
+	}
+	sitemaps, err := checker.Sitemaps(ctx, f.opt.UserAgent, u.String())
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		f.opt.Logger.Debug("robots.txt failed", "url", u.String(), "err", err)
+	}
+	if len(sitemaps) == 0 {
+		sitemaps = []string{u.Scheme + "://" + u.Host + "/sitemap.xml"}
+	}
+	f.opt.Logger.Debug("discovered sitemaps", "url", u.String(), "sitemaps", len(sitemaps))
+	return sitemaps, nil
+}
+
+// done reports whether fetching must stop.
+func (f *sitemapFetcher) done(ctx context.Context) bool {
+	return ctx.Err() != nil || len(f.entries) >= f.opt.MaxEntries
+}
+
+// fetch adds the entries of the sitemap at sitemapURL, following it if it is an index.
+func (f *sitemapFetcher) fetch(ctx context.Context, sitemapURL string, depth int) {
+	if f.done(ctx) || f.seen[sitemapURL] {
+		return
+	}
+	if f.fetched >= f.opt.MaxSitemaps {
+		f.opt.Logger.Debug("sitemap limit reached", "url", sitemapURL)
+		return
+	}
+	f.seen[sitemapURL] = true
+	f.fetched++
+
+	children, err := f.fetchOne(ctx, sitemapURL)
+	if err != nil {
+		if ctx.Err() == nil {
+			f.errs = append(f.errs, fmt.Errorf("sitemap %s: %w", sitemapURL, err))
+		}
+		return
+	}
+
+	if len(children) > 0 && depth >= f.opt.MaxDepth {
+		f.opt.Logger.Debug("sitemap depth reached", "url", sitemapURL, "sitemaps", len(children))
+		return
+	}
+	for _, child := range children {
+		f.fetch(ctx, child, depth+1)
+	}
+}
+
+// fetchOne requests the sitemap at sitemapURL, adds its entries and returns the sitemaps it
+// lists if it is an index.
+func (f *sitemapFetcher) fetchOne(ctx context.Context, sitemapURL string) ([]string, error) {
+	if err := waitLimit(ctx, SearchOptions{Limiter: f.opt.Limiter}, sitemapURL); err != nil {
+		return nil, err
+	}
+
+	f.opt.Logger.Debug("sitemap request", "url", sitemapURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", sitemapURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", f.opt.UserAgent)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	f.opt.Logger.Debug("sitemap response", "url", resp.Request.URL.String(), "status", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+
+	body, err := decompressSitemap(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	before := len(f.entries)
+	children, err := parseSitemap(io.LimitReader(body, maxSitemapSize), resp.Request.URL.String(), func(e SitemapEntry) bool {
+		f.entries = append(f.entries, e)
+		return len(f.entries) < f.opt.MaxEntries
+	})
+	f.opt.Logger.Debug("parsed sitemap", "url", sitemapURL, "entries", len(f.entries)-before, "sitemaps", len(children))
+	return children, err
+}
+
+// decompressSitemap returns a reader decompressing r when it is gzipped. The magic bytes
+// are checked rather than the URL or headers, which servers get wrong both ways.
+func decompressSitemap(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+type sitemapURLElement struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod"`
+	ChangeFreq string `xml:"changefreq"`
+	Priority   string `xml:"priority"`
+}
+
+// parseSitemap reads a urlset, calling emit with every entry until it returns false, or a
+// sitemapindex, returning the sitemaps it lists. Relative locations are resolved against
+// sitemapURL.
+func parseSitemap(r io.Reader, sitemapURL string, emit func(SitemapEntry) bool) ([]string, error) {
+	base, err := url.Parse(sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := xml.NewDecoder(r)
+	// Sitemaps declared as another encoding are nearly always ASCII or UTF-8 anyway
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var root string
+	var sitemaps []string
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return sitemaps, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if root == "" {
+			root = start.Name.Local
+			if root != "urlset" && root != "sitemapindex" {
+				return nil, ErrNotSitemap
+			}
+			continue
+		}
+
+		switch {
+		case root == "urlset" && start.Name.Local == "url":
+			var el sitemapURLElement
+			if err := decoder.DecodeElement(&el, &start); err != nil {
+				return nil, err
+			}
+			loc := resolveSitemapLoc(base, el.Loc)
+			if loc == "" {
+				continue
+			}
+			entry := SitemapEntry{
+				URL:        loc,
+				LastMod:    parseLastMod(el.LastMod),
+				ChangeFreq: ChangeFreq(strings.ToLower(strings.TrimSpace(el.ChangeFreq))),
+				Priority:   parsePriority(el.Priority),
+				Sitemap:    sitemapURL,
+			}
+			if !emit(entry) {
+				return nil, nil
+			}
+		case root == "sitemapindex" && start.Name.Local == "sitemap":
+			var el sitemapURLElement
+			if err := decoder.DecodeElement(&el, &start); err != nil {
+				return nil, err
+			}
+			if loc := resolveSitemapLoc(base, el.Loc); loc != "" {
+				sitemaps = append(sitemaps, loc)
+			}
+		default:
+			if err := decoder.Skip(); err != nil {
+				return sitemaps, err
+			}
+		}
+	}
+
+	if root == "" {
+		return nil, ErrNotSitemap
+	}
+	return sitemaps, nil
+}
+
+func resolveSitemapLoc(base *url.URL, loc string) string {
+	loc = strings.TrimSpace(loc)
+	if loc == "" {
+		return ""
+	}
+	u, err := base.Parse(loc)
+	if err != nil {
+		return ""
+	}
+	return u.String()
+}
+
+// lastModLayouts are the W3C Datetime formats allowed for lastmod, from most to least precise.
+var lastModLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+func parseLastMod(text string) time.Time {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return time.Time{}
+	}
+	for _, layout := range lastModLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func parsePriority(text string) float64 {
+	p, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || p < 0 || p > 1 {
+		return 0.5
+	}
+	return p
+}
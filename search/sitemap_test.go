@@ -0,0 +1,141 @@
+package search
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSitemapIndex = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>/sitemap-pages.xml</loc></sitemap>
+  <sitemap><loc>/sitemap-posts.xml.gz</loc><lastmod>2024-01-01</lastmod></sitemap>
+  <sitemap><loc>/sitemap-missing.xml</loc></sitemap>
+  <sitemap><loc>/sitemap.xml</loc></sitemap>
+</sitemapindex>`
+
+const testSitemapPages = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://example.com/</loc>
+    <lastmod>2024-03-05T10:30:00+01:00</lastmod>
+    <changefreq>Daily</changefreq>
+    <priority>1.0</priority>
+  </url>
+  <url>
+    <loc> https://example.com/about </loc>
+    <lastmod>2023-11</lastmod>
+  </url>
+</urlset>`
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func newSitemapServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	posts := gzipped(t, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/posts/1</loc><lastmod>2024-02-01T08:00Z</lastmod><priority>0.3</priority></url>
+  <url><loc>https://example.com/posts/2</loc><priority>7</priority></url>
+</urlset>`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nDisallow:\n\nSitemap: http://%s/sitemap.xml\n", r.Host)
+		case "/sitemap.xml":
+			fmt.Fprint(w, testSitemapIndex)
+		case "/sitemap-pages.xml":
+			fmt.Fprint(w, testSitemapPages)
+		case "/sitemap-posts.xml.gz":
+			w.Header().Set("Content-Type", "application/x-gzip")
+			w.Write(posts)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetchSitemap(t *testing.T) {
+	server := newSitemapServer(t)
+
+	entries, err := FetchSitemap(context.Background(), server.URL+"/sitemap.xml")
+
+	assert.ErrorIs(t, err, ErrUnexpectedStatus)
+	assert.ErrorContains(t, err, "sitemap-missing.xml")
+	assert.Len(t, entries, 4)
+
+	assert.Equal(t, "https://example.com/", entries[0].URL)
+	assert.True(t, time.Date(2024, 3, 5, 9, 30, 0, 0, time.UTC).Equal(entries[0].LastMod))
+	assert.Equal(t, ChangeFreqDaily, entries[0].ChangeFreq)
+	assert.Equal(t, 1.0, entries[0].Priority)
+	assert.Equal(t, server.URL+"/sitemap-pages.xml", entries[0].Sitemap)
+
+	assert.Equal(t, "https://example.com/about", entries[1].URL)
+	assert.Equal(t, time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC), entries[1].LastMod)
+	assert.Empty(t, entries[1].ChangeFreq)
+	assert.Equal(t, 0.5, entries[1].Priority)
+
+	// Gzipped
+	assert.Equal(t, "https://example.com/posts/1", entries[2].URL)
+	assert.Equal(t, time.Date(2024, 2, 1, 8, 0, 0, 0, time.UTC), entries[2].LastMod)
+	assert.Equal(t, 0.3, entries[2].Priority)
+	// Out of range
+	assert.Equal(t, 0.5, entries[3].Priority)
+}
+
+func TestFetchSitemapDiscoversFromRobots(t *testing.T) {
+	server := newSitemapServer(t)
+
+	entries, _ := FetchSitemap(context.Background(), server.URL, SitemapOptions{HTTPClient: server.Client()})
+
+	assert.Len(t, entries, 4)
+}
+
+func TestFetchSitemapLimits(t *testing.T) {
+	server := newSitemapServer(t)
+
+	entries, err := FetchSitemap(context.Background(), server.URL+"/sitemap.xml", SitemapOptions{MaxEntries: 3})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	// The index and the first sitemap it lists
+	entries, err = FetchSitemap(context.Background(), server.URL+"/sitemap.xml", SitemapOptions{MaxSitemaps: 2})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestFetchSitemapContext(t *testing.T) {
+	server := newSitemapServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := FetchSitemap(ctx, server.URL+"/sitemap.xml")
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestParseSitemapNotSitemap(t *testing.T) {
+	_, err := parseSitemap(strings.NewReader("<html><body>Not found</body></html>"), "https://example.com/sitemap.xml", func(SitemapEntry) bool { return true })
+
+	assert.ErrorIs(t, err, ErrNotSitemap)
+}
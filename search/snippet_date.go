@@ -0,0 +1,157 @@
+package search
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// snippetDateSeparator is what Google puts between the date of a snippet and its text.
+// A date is only taken from a snippet when it is followed by one, so that a snippet that
+// merely starts with a date, e.g. "March 3, 2024 was a Sunday", is left alone.
+const snippetDateSeparator = `\s*(?:—|–|-|·|\.\.\.|…)\s*`
+
+var (
+	// "Mar 3, 2024"
+	monthFirstDateRegexp = regexp.MustCompile(`^(\p{L}+)\.?\s+(\d{1,2}),?\s+(\d{4})` + snippetDateSeparator)
+	// "3. März 2024", "3 mars 2024", "3 de marzo de 2024"
+	dayFirstDateRegexp = regexp.MustCompile(`^(\d{1,2})\.?\s+(?:de\s+)?(\p{L}+)\.?\s+(?:de\s+)?(\d{4})` + snippetDateSeparator)
+	// "2024-03-03"
+	isoDateRegexp = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})` + snippetDateSeparator)
+	// "03.03.2024"
+	dottedDateRegexp = regexp.MustCompile(`^(\d{1,2})\.(\d{1,2})\.(\d{4})` + snippetDateSeparator)
+	// "3/4/2024", either day or month first
+	slashDateRegexp = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})/(\d{4})` + snippetDateSeparator)
+	// "2 days ago", "vor 2 Tagen", "il y a 2 jours", "hace 2 días"
+	relativeDateRegexp = regexp.MustCompile(`(?i)^(?:(\d+)\s+(\p{L}+)\s+ago|vor\s+(\d+)\s+(\p{L}+)|il\s+y\s+a\s+(\d+)\s+(\p{L}+)|hace\s+(\d+)\s+(\p{L}+))` + snippetDateSeparator)
+)
+
+// snippetMonths maps the lower-case month names and abbreviations of English, German,
+// French and Spanish to their month. The abbreviations the languages share, such as "mar",
+// mean the same month in all of them.
+var snippetMonths = map[string]time.Month{
+	"jan": time.January, "january": time.January, "januar": time.January, "janv": time.January, "janvier": time.January, "ene": time.January, "enero": time.January,
+	"feb": time.February, "february": time.February, "februar": time.February, "févr": time.February, "février": time.February, "febrero": time.February,
+	"mar": time.March, "march": time.March, "mär": time.March, "märz": time.March, "mars": time.March, "marzo": time.March,
+	"apr": time.April, "april": time.April, "avr": time.April, "avril": time.April, "abr": time.April, "abril": time.April,
+	"may": time.May, "mai": time.May, "mayo": time.May,
+	"jun": time.June, "june": time.June, "juni": time.June, "juin": time.June, "junio": time.June,
+	"jul": time.July, "july": time.July, "juli": time.July, "juil": time.July, "juillet": time.July, "julio": time.July,
+	"aug": time.August, "august": time.August, "août": time.August, "ago": time.August, "agosto": time.August,
+	"sep": time.September, "sept": time.September, "september": time.September, "septembre": time.September, "septiembre": time.September,
+	"oct": time.October, "october": time.October, "okt": time.October, "oktober": time.October, "octobre": time.October, "octubre": time.October,
+	"nov": time.November, "november": time.November, "novembre": time.November, "noviembre": time.November,
+	"dec": time.December, "december": time.December, "dez": time.December, "dezember": time.December, "déc": time.December, "décembre": time.December, "dic": time.December, "diciembre": time.December,
+}
+
+// snippetUnits maps the lower-case units of relative dates to their duration.
+var snippetUnits = map[string]time.Duration{
+	"min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute, "minuten": time.Minute, "minuto": time.Minute, "minutos": time.Minute,
+	"hour": time.Hour, "hours": time.Hour, "stunde": time.Hour, "stunden": time.Hour, "heure": time.Hour, "heures": time.Hour, "hora": time.Hour, "horas": time.Hour,
+	"day": 24 * time.Hour, "days": 24 * time.Hour, "tag": 24 * time.Hour, "tagen": 24 * time.Hour, "jour": 24 * time.Hour, "jours": 24 * time.Hour, "día": 24 * time.Hour, "días": 24 * time.Hour, "dia": 24 * time.Hour, "dias": 24 * time.Hour,
+	"week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour, "woche": 7 * 24 * time.Hour, "wochen": 7 * 24 * time.Hour, "semaine": 7 * 24 * time.Hour, "semaines": 7 * 24 * time.Hour, "semana": 7 * 24 * time.Hour, "semanas": 7 * 24 * time.Hour,
+}
+
+// parseSnippetDate parses the date Google puts in front of the snippets of dated pages, e.g.
+// "Mar 3, 2024 — ", in English, German, French or Spanish, and returns it with the snippet
+// stripped of it. Relative dates such as "2 days ago — " are resolved against now. Dates
+// are taken as midnight UTC.
+//
+// Snippets without a date are returned unchanged with the zero time, and so are those
+// starting with a date whose day and month cannot be told apart, such as "3/4/2024".
+func parseSnippetDate(snippet string, now time.Time) (time.Time, string) {
+	if m := relativeDateRegexp.FindStringSubmatch(snippet); m != nil {
+		// Only the groups of the language that matched are set
+		for i := 1; i < len(m); i += 2 {
+			if m[i] == "" {
+				continue
+			}
+			n, err := strconv.Atoi(m[i])
+			unit, ok := snippetUnits[strings.ToLower(m[i+1])]
+			if err != nil || !ok {
+				return time.Time{}, snippet
+			}
+			return now.Add(-time.Duration(n) * unit), snippet[len(m[0]):]
+		}
+	}
+
+	var year, day int
+	var month time.Month
+	var m []string
+	switch {
+	case matchDate(monthFirstDateRegexp, snippet, &m):
+		month = snippetMonths[strings.ToLower(m[1])]
+		day, _ = strconv.Atoi(m[2])
+		year, _ = strconv.Atoi(m[3])
+	case matchDate(dayFirstDateRegexp, snippet, &m):
+		day, _ = strconv.Atoi(m[1])
+		month = snippetMonths[strings.ToLower(m[2])]
+		year, _ = strconv.Atoi(m[3])
+	case matchDate(isoDateRegexp, snippet, &m):
+		year, _ = strconv.Atoi(m[1])
+		n, _ := strconv.Atoi(m[2])
+		month = time.Month(n)
+		day, _ = strconv.Atoi(m[3])
+	case matchDate(dottedDateRegexp, snippet, &m):
+		day, _ = strconv.Atoi(m[1])
+		n, _ := strconv.Atoi(m[2])
+		month = time.Month(n)
+		year, _ = strconv.Atoi(m[3])
+	case matchDate(slashDateRegexp, snippet, &m):
+		a, _ := strconv.Atoi(m[1])
+		b, _ := strconv.Atoi(m[2])
+		year, _ = strconv.Atoi(m[3])
+		switch {
+		case a > 12 && b <= 12:
+			day, month = a, time.Month(b)
+		case b > 12 && a <= 12:
+			day, month = b, time.Month(a)
+		case a == b:
+			day, month = a, time.Month(a)
+		default:
+			return time.Time{}, snippet
+		}
+	default:
+		return time.Time{}, snippet
+	}
+
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	// time.Date normalizes days past the end of the month, e.g. Feb 30, which are no dates
+	if month < time.January || month > time.December || date.Day() != day || date.Month() != month {
+		return time.Time{}, snippet
+	}
+	return date, snippet[len(m[0]):]
+}
+
+// matchDate sets m to the submatches of re in s and reports whether it matched.
+func matchDate(re *regexp.Regexp, s string, m *[]string) bool {
+	*m = re.FindStringSubmatch(s)
+	return *m != nil
+}
+
+// filterFreshness drops, with a positive minFreshness, the results published more than
+// minFreshness before now. Results without a date are kept. The rest is renumbered from
+// the rank of the first result.
+func filterFreshness(results []Result, minFreshness time.Duration, now time.Time) []Result {
+	if minFreshness <= 0 {
+		return results
+	}
+
+	oldest := now.Add(-minFreshness)
+	kept := make([]Result, 0, len(results))
+	for _, r := range results {
+		if !r.PublishedAt.IsZero() && r.PublishedAt.Before(oldest) {
+			continue
+		}
+		kept = append(kept, r)
+	}
+
+	if len(kept) > 0 && len(kept) < len(results) {
+		first := results[0].Rank
+		for i := range kept {
+			kept[i].Rank = first + i
+		}
+	}
+	return kept
+}
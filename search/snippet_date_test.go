@@ -0,0 +1,105 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSnippetDate(t *testing.T) {
+	now := time.Date(2024, 5, 2, 18, 0, 0, 0, time.UTC)
+	march3 := time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		snippet     string
+		want        time.Time
+		description string
+	}{
+		// English
+		{"Mar 3, 2024 — Go 1.22 is released.", march3, "Go 1.22 is released."},
+		{"March 3, 2024 - Go 1.22 is released.", march3, "Go 1.22 is released."},
+		{"Sept 30, 2023 · Go 1.21.2", time.Date(2023, 9, 30, 0, 0, 0, 0, time.UTC), "Go 1.21.2"},
+		{"2 days ago — Go 1.22.3 is released.", now.Add(-48 * time.Hour), "Go 1.22.3 is released."},
+		{"1 hour ago ... Breaking", now.Add(-time.Hour), "Breaking"},
+		// German
+		{"03.03.2024 — Go 1.22 ist erschienen.", march3, "Go 1.22 ist erschienen."},
+		{"3. März 2024 — Go 1.22 ist erschienen.", march3, "Go 1.22 ist erschienen."},
+		{"3. Mär. 2024 — Go 1.22", march3, "Go 1.22"},
+		{"vor 3 Stunden — Go 1.22", now.Add(-3 * time.Hour), "Go 1.22"},
+		{"vor 1 Tag – Go 1.22", now.Add(-24 * time.Hour), "Go 1.22"},
+		// French
+		{"3 mars 2024 — Go 1.22 est sorti.", march3, "Go 1.22 est sorti."},
+		{"12 déc. 2023 — Go", time.Date(2023, 12, 12, 0, 0, 0, 0, time.UTC), "Go"},
+		{"il y a 2 semaines — Go", now.Add(-14 * 24 * time.Hour), "Go"},
+		// Spanish
+		{"3 mar 2024 — Go 1.22 ya está disponible.", march3, "Go 1.22 ya está disponible."},
+		{"3 de marzo de 2024 — Go", march3, "Go"},
+		{"15 ago 2023 — Go", time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC), "Go"},
+		{"hace 5 días — Go", now.Add(-5 * 24 * time.Hour), "Go"},
+		// Numeric
+		{"2024-03-03 — Go", march3, "Go"},
+		{"13/3/2024 — Go", time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC), "Go"},
+		{"3/13/2024 — Go", time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC), "Go"},
+		{"3/3/2024 — Go", march3, "Go"},
+		// Ambiguous or no dates are left alone
+		{"3/4/2024 — Go", time.Time{}, "3/4/2024 — Go"},
+		{"Mar 3, 2024 was a Sunday.", time.Time{}, "Mar 3, 2024 was a Sunday."},
+		{"Feb 30, 2024 — Go", time.Time{}, "Feb 30, 2024 — Go"},
+		{"31.13.2024 — Go", time.Time{}, "31.13.2024 — Go"},
+		{"Go 1 — 2 days ago", time.Time{}, "Go 1 — 2 days ago"},
+		{"Foo 3, 2024 — Go", time.Time{}, "Foo 3, 2024 — Go"},
+		{"2 moons ago — Go", time.Time{}, "2 moons ago — Go"},
+		{"", time.Time{}, ""},
+	}
+
+	for _, tt := range tests {
+		publishedAt, description := parseSnippetDate(tt.snippet, now)
+		assert.Equal(t, tt.want, publishedAt, tt.snippet)
+		assert.Equal(t, tt.description, description, tt.snippet)
+	}
+}
+
+func TestFilterFreshness(t *testing.T) {
+	now := time.Date(2024, 5, 2, 18, 0, 0, 0, time.UTC)
+	results := []Result{
+		{Rank: 11, URL: "https://old.example/", PublishedAt: now.Add(-30 * 24 * time.Hour)},
+		{Rank: 12, URL: "https://undated.example/"},
+		{Rank: 13, URL: "https://new.example/", PublishedAt: now.Add(-time.Hour)},
+	}
+
+	assert.Equal(t, results, filterFreshness(results, 0, now))
+
+	fresh := filterFreshness(results, 7*24*time.Hour, now)
+
+	if assert.Len(t, fresh, 2) {
+		assert.Equal(t, Result{Rank: 11, URL: "https://undated.example/"}, fresh[0])
+		assert.Equal(t, 12, fresh[1].Rank)
+		assert.Equal(t, "https://new.example/", fresh[1].URL)
+	}
+}
+
+func TestSearchGoogleMinFreshness(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body>
+<div class="g"><div class="yuRUbf"><a href="https://old.example/"><h3>Old</h3></a></div><div class="VwiC3b"><span>Mar 3, 2019 — </span>An old page.</div></div>
+<div class="g"><div class="yuRUbf"><a href="https://undated.example/"><h3>Undated</h3></a></div><div class="VwiC3b">A page without a date.</div></div>
+<div class="g"><div class="yuRUbf"><a href="https://new.example/"><h3>New</h3></a></div><div class="VwiC3b"><span>2 hours ago — </span>A new page.</div></div>
+</body></html>`)
+	})
+
+	results, err := SearchGoogle(context.Background(), "golang", SearchOptions{HTTPClient: client, MinFreshness: 24 * time.Hour})
+
+	assert.NoError(t, err)
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, 1, results[0].Rank)
+		assert.Equal(t, "https://undated.example/", results[0].URL)
+		assert.True(t, results[0].PublishedAt.IsZero())
+		assert.Equal(t, 2, results[1].Rank)
+		assert.Equal(t, "A new page.", results[1].Description)
+		assert.WithinDuration(t, time.Now().Add(-2*time.Hour), results[1].PublishedAt, time.Minute)
+	}
+}
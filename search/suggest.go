@@ -0,0 +1,212 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const suggestBase = "https://suggestqueries.google.com/complete/search?"
+
+// Suggest returns the suggestions Google's autocomplete shows for prefix, best first.
+//
+// LanguageCode is sent as hl and CountryCode, unless Gl is set, as gl. Limit truncates the
+// suggestions. The rate limiter, retry and proxy options, UserAgent and HTTPClient are
+// honored like for SearchGoogle. The firefox client is requested; ExtraParams can select
+// another, such as chrome, whose navigational suggestions are left out.
+func Suggest(ctx context.Context, prefix string, opts ...SearchOptions) ([]string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	opt := searchOptions(opts)
+	if err := validateOptions(opt); err != nil {
+		return nil, err
+	}
+	return suggest(ctx, prefix, opt)
+}
+
+func suggest(ctx context.Context, prefix string, opt SearchOptions) ([]string, error) {
+	suggestURL := getSuggestURL(prefix, opt)
+	body, err := fetchGoogle(ctx, suggestURL, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions, err := parseSuggestions(body)
+	if err != nil {
+		logger(opt).Debug("parsing suggestions failed", "engine", EngineGoogle, "url", suggestURL, "err", err)
+		return nil, googleError(opt, suggestURL, 0, err)
+	}
+	logger(opt).Debug("parsed suggestions", "engine", EngineGoogle, "url", suggestURL, "suggestions", len(suggestions))
+
+	if opt.Limit > 0 && len(suggestions) > opt.Limit {
+		suggestions = suggestions[:opt.Limit]
+	}
+	return suggestions, nil
+}
+
+func getSuggestURL(prefix string, opt SearchOptions) string {
+	params := url.Values{}
+	params.Set("client", "firefox")
+	params.Set("q", prefix)
+	// Without them, some languages are served in a legacy charset
+	params.Set("ie", "utf-8")
+	params.Set("oe", "utf-8")
+	if opt.LanguageCode != "" {
+		params.Set("hl", opt.LanguageCode)
+	}
+	gl := opt.Gl
+	if gl == "" {
+		gl = opt.CountryCode
+	}
+	if gl != "" {
+		params.Set("gl", strings.ToLower(gl))
+	}
+	for key, value := range opt.ExtraParams {
+		params.Set(key, value)
+	}
+	return suggestBase + params.Encode()
+}
+
+var tagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// parseSuggestions parses the suggestions of an autocomplete response: a JSON array of the
+// query and its suggestions, possibly wrapped in a JSONP callback. Suggestions are strings
+// for the firefox and chrome clients and arrays starting with the string, which may hold
+// <b> tags, for the others. The chrome client adds the type of every suggestion, and only
+// queries are kept.
+func parseSuggestions(body []byte) ([]string, error) {
+	body = bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(body), []byte(")]}'")))
+	if !bytes.HasPrefix(body, []byte("[")) {
+		start, end := bytes.IndexByte(body, '('), bytes.LastIndexByte(body, ')')
+		if start < 0 || end < start {
+			return nil, errors.New("autocomplete response is neither JSON nor JSONP")
+		}
+		body = body[start+1 : end]
+	}
+
+	var response []json.RawMessage
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("autocomplete response: %w", err)
+	}
+	if len(response) < 2 {
+		return nil, errors.New("autocomplete response has no suggestions")
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(response[1], &items); err != nil {
+		return nil, fmt.Errorf("autocomplete suggestions: %w", err)
+	}
+
+	var types []string
+	if len(response) >= 5 {
+		var extra struct {
+			Types []string `json:"google:suggesttype"`
+		}
+		// Other clients put other values there
+		if json.Unmarshal(response[4], &extra) == nil {
+			types = extra.Types
+		}
+	}
+
+	suggestions := []string{}
+	for i, item := range items {
+		if i < len(types) && types[i] != "QUERY" {
+			continue
+		}
+		var suggestion string
+		if json.Unmarshal(item, &suggestion) != nil {
+			var fields []json.RawMessage
+			if json.Unmarshal(item, &fields) != nil || len(fields) == 0 || json.Unmarshal(fields[0], &suggestion) != nil {
+				continue
+			}
+		}
+		suggestion = strings.TrimSpace(html.UnescapeString(tagRegex.ReplaceAllString(suggestion, "")))
+		if suggestion != "" {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	return suggestions, nil
+}
+
+// SuggestNode is a query of a SuggestTree and the suggestions found by expanding it.
+type SuggestNode struct {
+	Query    string         `json:"query"`
+	Children []*SuggestNode `json:"children,omitempty"`
+}
+
+// Queries returns the queries below n, breadth first.
+func (n *SuggestNode) Queries() []string {
+	var queries []string
+	level := n.Children
+	for len(level) > 0 {
+		var next []*SuggestNode
+		for _, child := range level {
+			queries = append(queries, child.Query)
+			next = append(next, child.Children...)
+		}
+		level = next
+	}
+	return queries
+}
+
+// SuggestTree builds a keyword list from the autocomplete suggestions for seed. A query is
+// expanded by asking for the suggestions of the query itself and of the query followed by
+// every letter from a to z, which makes 27 requests. The suggestions found become the
+// children of the query and are expanded in turn, down to depth levels below seed.
+//
+// Suggestions are deduplicated across the tree, ignoring case and spacing, so that every
+// query appears, and is expanded, once. The options are those of Suggest; Limit applies to
+// every request. If a request fails, the tree built so far is returned with the error.
+func SuggestTree(ctx context.Context, seed string, depth int, opts ...SearchOptions) (*SuggestNode, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	opt := searchOptions(opts)
+	if err := validateOptions(opt); err != nil {
+		return nil, err
+	}
+
+	root := &SuggestNode{Query: seed}
+	seen := map[string]bool{suggestKey(seed): true}
+	level := []*SuggestNode{root}
+	for d := 0; d < depth && len(level) > 0; d++ {
+		var next []*SuggestNode
+		for _, node := range level {
+			prefixes := []string{node.Query}
+			for letter := 'a'; letter <= 'z'; letter++ {
+				prefixes = append(prefixes, node.Query+" "+string(letter))
+			}
+			for _, prefix := range prefixes {
+				suggestions, err := suggest(ctx, prefix, opt)
+				if err != nil {
+					return root, err
+				}
+				for _, suggestion := range suggestions {
+					key := suggestKey(suggestion)
+					if seen[key] {
+						continue
+					}
+					seen[key] = true
+					child := &SuggestNode{Query: suggestion}
+					node.Children = append(node.Children, child)
+					next = append(next, child)
+				}
+			}
+		}
+		level = next
+	}
+	return root, nil
+}
+
+// suggestKey is the form of a suggestion suggestions are deduplicated by.
+func suggestKey(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
@@ -0,0 +1,120 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSuggestions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"firefox", `["golang",["golang","golang tutorial","golang generics"]]`, []string{"golang", "golang tutorial", "golang generics"}},
+		{"chrome", `["golang",["golang tutorial","https://go.dev/","golang jobs"],["","",""],[],{"google:suggestrelevance":[1250,900,600],"google:suggesttype":["QUERY","NAVIGATION","QUERY"]}]`,
+			[]string{"golang tutorial", "golang jobs"}},
+		{"jsonp", `window.google.ac.h(["golang",[["golang <b>tutorial</b>",0,[512]],["golang <b>q&amp;a</b>",0]],{"q":"abc"}])`,
+			[]string{"golang tutorial", "golang q&a"}},
+		{"xssi prefix", ")]}'\n[\"go\",[\"go maps\"]]", []string{"go maps"}},
+		{"empty", `["zzqx",[]]`, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestions, err := parseSuggestions([]byte(tt.body))
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, suggestions)
+		})
+	}
+}
+
+func TestParseSuggestionsInvalid(t *testing.T) {
+	for _, body := range []string{"", "<html></html>", `["golang"]`, `callback({"q":"golang"})`} {
+		_, err := parseSuggestions([]byte(body))
+		assert.Error(t, err, body)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	client, rt := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/javascript; charset=UTF-8")
+		w.Write([]byte(`["golang",["golang","golang tutorial","golang generics"]]`))
+	})
+
+	suggestions, err := Suggest(context.Background(), "golang", SearchOptions{HTTPClient: client, LanguageCode: "de", CountryCode: "at", Limit: 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"golang", "golang tutorial"}, suggestions)
+	if assert.Len(t, rt.requests, 1) {
+		u := rt.requests[0].URL
+		assert.Equal(t, "suggestqueries.google.com", u.Host)
+		assert.Equal(t, "/complete/search", u.Path)
+		assert.Equal(t, "firefox", u.Query().Get("client"))
+		assert.Equal(t, "golang", u.Query().Get("q"))
+		assert.Equal(t, "de", u.Query().Get("hl"))
+		assert.Equal(t, "at", u.Query().Get("gl"))
+	}
+}
+
+func TestSuggestError(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	_, err := Suggest(context.Background(), "golang", SearchOptions{HTTPClient: client})
+
+	assert.ErrorIs(t, err, ErrUnexpectedStatus)
+	var searchErr *SearchError
+	if assert.True(t, errors.As(err, &searchErr)) {
+		assert.Equal(t, http.StatusForbidden, searchErr.StatusCode)
+	}
+}
+
+func TestSuggestTree(t *testing.T) {
+	client, rt := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch q := r.URL.Query().Get("q"); q {
+		case "go":
+			w.Write([]byte(`["go",["go","go maps","golang"]]`))
+		case "go m":
+			w.Write([]byte(`["go m",["Go  Maps","go modules"]]`))
+		case "go maps":
+			w.Write([]byte(`["go maps",["go maps sorted","golang"]]`))
+		default:
+			w.Write([]byte(`["` + q + `",[]]`))
+		}
+	})
+
+	tree, err := SuggestTree(context.Background(), "go", 2, SearchOptions{HTTPClient: client})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &SuggestNode{Query: "go", Children: []*SuggestNode{
+		{Query: "go maps", Children: []*SuggestNode{{Query: "go maps sorted"}}},
+		{Query: "golang"},
+		{Query: "go modules"},
+	}}, tree)
+	assert.Equal(t, []string{"go maps", "golang", "go modules", "go maps sorted"}, tree.Queries())
+	// The seed and the three suggestions found are expanded
+	assert.Len(t, rt.requests, 4*27)
+}
+
+func TestSuggestTreePartial(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if q := r.URL.Query().Get("q"); strings.HasSuffix(q, " b") {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`["go",["go maps"]]`))
+	})
+
+	tree, err := SuggestTree(context.Background(), "go", 1, SearchOptions{HTTPClient: client})
+
+	assert.ErrorIs(t, err, ErrBlocked)
+	assert.Equal(t, []string{"go maps"}, tree.Queries())
+}
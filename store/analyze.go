@@ -0,0 +1,234 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// DefaultWordsPerMinute is the reading speed of Analyze for words separated by spaces.
+	DefaultWordsPerMinute = 230
+
+	// DefaultCharsPerMinute is the reading speed of Analyze for Chinese and Japanese, whose
+	// characters are counted as words.
+	DefaultCharsPerMinute = 500
+
+	// DefaultKeywords is how many keywords Analyze returns.
+	DefaultKeywords = 10
+)
+
+// Stats are figures derived from the text of a page by Analyze.
+type Stats struct {
+
+	// WordCount is the number of words. Every Chinese and Japanese character is a word.
+	WordCount int `json:"word_count"`
+
+	// ReadingTime is the time needed to read the text, to the second.
+	ReadingTime time.Duration `json:"reading_time"`
+
+	// Language is the ISO 639-1 code of the language of the text, as by DetectLanguage.
+	Language string `json:"language,omitempty"`
+
+	// Keywords are the most frequent words other than stop words, most frequent first.
+	Keywords []Keyword `json:"keywords,omitempty"`
+}
+
+// Keyword is a frequent word of a text. In Chinese and Japanese it is a pair of characters.
+type Keyword struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+
+	// Score is Count divided by the number of words of the text.
+	Score float64 `json:"score"`
+}
+
+// AnalyzeOption changes how Analyze works.
+type AnalyzeOption func(*analyzeOptions)
+
+type analyzeOptions struct {
+	wordsPerMinute int
+	charsPerMinute int
+	keywords       int
+	language       string
+}
+
+// WithWordsPerMinute sets the reading speed for words separated by spaces.
+// Default: DefaultWordsPerMinute.
+func WithWordsPerMinute(wpm int) AnalyzeOption {
+	return func(o *analyzeOptions) { o.wordsPerMinute = wpm }
+}
+
+// WithCharsPerMinute sets the reading speed for Chinese and Japanese characters.
+// Default: DefaultCharsPerMinute.
+func WithCharsPerMinute(cpm int) AnalyzeOption {
+	return func(o *analyzeOptions) { o.charsPerMinute = cpm }
+}
+
+// WithKeywords sets how many keywords are returned. Zero or less returns none.
+// Default: DefaultKeywords.
+func WithKeywords(n int) AnalyzeOption {
+	return func(o *analyzeOptions) { o.keywords = n }
+}
+
+// WithLanguage sets the language of the text, an ISO 639-1 code, instead of detecting it.
+func WithLanguage(code string) AnalyzeOption {
+	return func(o *analyzeOptions) { o.language = code }
+}
+
+// Analyze returns the word count, reading time, language and keywords of the text of
+// content: its headings, paragraphs, list items, block quotes and tables. Code is left out.
+// The result only depends on content and opts.
+func Analyze(content *ExtractedContent, opts ...AnalyzeOption) Stats {
+	if content == nil {
+		return AnalyzeText("", opts...)
+	}
+	var text strings.Builder
+	for _, block := range content.Blocks {
+		switch block.Kind {
+		case BlockHeading, BlockParagraph, BlockListItem, BlockBlockquote:
+			text.WriteString(block.Text)
+			text.WriteByte('\n')
+		case BlockTable:
+			for _, row := range block.Rows {
+				text.WriteString(strings.Join(row, " "))
+				text.WriteByte('\n')
+			}
+		}
+	}
+	return AnalyzeText(text.String(), opts...)
+}
+
+// AnalyzeText is like Analyze for plain text.
+func AnalyzeText(text string, opts ...AnalyzeOption) Stats {
+	o := analyzeOptions{wordsPerMinute: DefaultWordsPerMinute, charsPerMinute: DefaultCharsPerMinute, keywords: DefaultKeywords}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	words, runs := splitWords(text)
+	chars := 0
+	for _, run := range runs {
+		chars += len([]rune(run))
+	}
+	stats := Stats{WordCount: len(words) + chars, Language: o.language}
+	if stats.Language == "" {
+		stats.Language = DetectLanguage(text)
+	}
+
+	minutes := 0.0
+	if o.wordsPerMinute > 0 {
+		minutes += float64(len(words)) / float64(o.wordsPerMinute)
+	}
+	if o.charsPerMinute > 0 {
+		minutes += float64(chars) / float64(o.charsPerMinute)
+	}
+	stats.ReadingTime = time.Duration(minutes * float64(time.Minute)).Round(time.Second)
+
+	if o.keywords > 0 && stats.WordCount > 0 {
+		stats.Keywords = keywords(words, runs, stats.WordCount, stats.Language, o.keywords)
+	}
+	return stats
+}
+
+// isIdeograph reports whether r is a Chinese or Japanese character, which is a word of its own.
+// The long vowel mark and the iteration mark are not in any script but are written like kana.
+func isIdeograph(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) || r == 'ー' || r == '々'
+}
+
+func isWordRune(r rune) bool {
+	return (unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)) && !isIdeograph(r)
+}
+
+// splitWords returns the words of text separated by spaces or punctuation, and the runs of
+// Chinese and Japanese characters. Apostrophes and hyphens between letters are part of a word.
+func splitWords(text string) (words, runs []string) {
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		j := i
+		switch {
+		case isIdeograph(runes[i]):
+			for j < len(runes) && isIdeograph(runes[j]) {
+				j++
+			}
+			runs = append(runs, string(runes[i:j]))
+		case isWordRune(runes[i]):
+			for j < len(runes) && (isWordRune(runes[j]) ||
+				(strings.ContainsRune("'’-", runes[j]) && j+1 < len(runes) && isWordRune(runes[j+1]))) {
+				j++
+			}
+			words = append(words, string(runes[i:j]))
+		default:
+			j++
+		}
+		i = j
+	}
+	return words, runs
+}
+
+// keywords returns the n most frequent terms of words and runs that are not stop words of
+// language: the words of at least 3 letters, or 2 in Korean, and the pairs of characters of
+// the runs. Terms as frequent as each other are sorted in byte order.
+func keywords(words, runs []string, total int, language string, n int) []Keyword {
+	stop := map[string]bool{}
+	for _, word := range stopWords[language] {
+		stop[word] = true
+	}
+
+	counts := map[string]int{}
+	for _, word := range words {
+		word = keywordForm(strings.ToLower(word))
+		minLength := 3
+		if language == "ko" {
+			minLength = 2
+		}
+		if len([]rune(word)) < minLength || stop[word] || isNumber(word) {
+			continue
+		}
+		counts[word]++
+	}
+	for _, run := range runs {
+		runes := []rune(run)
+		for i := 0; i+2 <= len(runes); i++ {
+			if pair := string(runes[i : i+2]); !stop[pair] {
+				counts[pair]++
+			}
+		}
+	}
+
+	result := make([]Keyword, 0, len(counts))
+	for word, count := range counts {
+		result = append(result, Keyword{Word: word, Count: count, Score: float64(count) / float64(total)})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Word < result[j].Word
+	})
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// keywordForm strips the elided article of a word such as "l'information" and the
+// possessive of a word such as "gopher's".
+func keywordForm(word string) string {
+	word = strings.ReplaceAll(word, "’", "'")
+	if before, after, ok := strings.Cut(word, "'"); ok && len([]rune(before)) <= 2 {
+		word = after
+	}
+	return strings.TrimSuffix(word, "'s")
+}
+
+func isNumber(word string) bool {
+	for _, r := range word {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
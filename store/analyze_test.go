@@ -0,0 +1,71 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const gopherText = "Gophers are small burrowing rodents. The gopher digs tunnels, and every gopher tunnel has several exits. Gophers eat roots, so gardeners don't like gophers in their gardens."
+
+func TestAnalyzeText(t *testing.T) {
+	stats := AnalyzeText(gopherText, WithKeywords(3))
+
+	assert.Equal(t, Stats{
+		WordCount:   27,
+		ReadingTime: 7 * time.Second,
+		Language:    "en",
+		Keywords: []Keyword{
+			{Word: "gophers", Count: 3, Score: 3.0 / 27},
+			{Word: "gopher", Count: 2, Score: 2.0 / 27},
+			{Word: "burrowing", Count: 1, Score: 1.0 / 27},
+		},
+	}, stats)
+}
+
+func TestAnalyzeTextOptions(t *testing.T) {
+	stats := AnalyzeText(gopherText, WithWordsPerMinute(27), WithKeywords(0), WithLanguage("xx"))
+
+	assert.Equal(t, time.Minute, stats.ReadingTime)
+	assert.Equal(t, "xx", stats.Language)
+	assert.Empty(t, stats.Keywords)
+}
+
+func TestAnalyzeTextCJK(t *testing.T) {
+	stats := AnalyzeText("昨天我们沿着河边散步，直到太阳落山，然后在老桥附近的一家小餐馆吃了晚饭。我们很喜欢河边。", WithKeywords(2))
+
+	assert.Equal(t, 40, stats.WordCount)
+	assert.Equal(t, 5*time.Second, stats.ReadingTime)
+	assert.Equal(t, "zh", stats.Language)
+	// 我们 is a stop word
+	assert.Equal(t, []Keyword{{Word: "河边", Count: 2, Score: 2.0 / 40}, {Word: "一家", Count: 1, Score: 1.0 / 40}}, stats.Keywords)
+
+	// One word and ten characters
+	stats = AnalyzeText("Go 言語で書かれたツール", WithWordsPerMinute(6), WithCharsPerMinute(60))
+	assert.Equal(t, 11, stats.WordCount)
+	assert.Equal(t, 20*time.Second, stats.ReadingTime)
+}
+
+func TestAnalyze(t *testing.T) {
+	content := &ExtractedContent{Blocks: []Block{
+		{Kind: BlockHeading, Level: 1, Text: "Gophers"},
+		{Kind: BlockParagraph, Text: "Gophers dig tunnels."},
+		{Kind: BlockLink, Text: "tunnels", Href: "/tunnels"},
+		{Kind: BlockCode, Text: "go run gopher.go"},
+		{Kind: BlockTable, Rows: [][]string{{"Name", "Tunnels"}, {"Pocket gopher", "12"}}},
+	}}
+
+	stats := Analyze(content, WithKeywords(2))
+
+	assert.Equal(t, 9, stats.WordCount)
+	assert.Equal(t, []Keyword{{Word: "gophers", Count: 2, Score: 2.0 / 9}, {Word: "tunnels", Count: 2, Score: 2.0 / 9}}, stats.Keywords)
+	assert.Equal(t, Stats{}, Analyze(nil))
+}
+
+func TestSplitWords(t *testing.T) {
+	words, runs := splitWords("L'été, c'est well-known — don't panic! 東京へ行く 42")
+
+	assert.Equal(t, []string{"L'été", "c'est", "well-known", "don't", "panic", "42"}, words)
+	assert.Equal(t, []string{"東京へ行く"}, runs)
+}
@@ -0,0 +1,555 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/propro-productions/go-utils/internal/httpopts"
+	"github.com/propro-productions/go-utils/proxy"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// DefaultMaxInlinedSize is how many bytes of assets ArchivePage inlines in total.
+	DefaultMaxInlinedSize = 50 << 20
+
+	// DefaultMaxArchiveSize is the largest archive ArchivePage writes.
+	DefaultMaxArchiveSize = 100 << 20
+)
+
+// ErrArchiveTooLarge indicates a page whose archive would be larger than the maximum
+// archive size.
+var ErrArchiveTooLarge = errors.New("archive too large")
+
+// ArchiveOption changes how ArchivePage works.
+type ArchiveOption func(*archiveOptions)
+
+type archiveOptions struct {
+	timeout        time.Duration
+	userAgent      string
+	proxyAddr      string
+	proxyPool      *proxy.Pool
+	httpClient     *http.Client
+	concurrency    int
+	maxAssetSize   int64
+	maxInlinedSize int64
+	maxArchiveSize int64
+	keepScripts    bool
+}
+
+// WithArchiveTimeout bounds every request made with the default client.
+// Default: DefaultAssetTimeout.
+func WithArchiveTimeout(timeout time.Duration) ArchiveOption {
+	return func(o *archiveOptions) { o.timeout = timeout }
+}
+
+// WithArchiveUserAgent sets the User-Agent sent with every request.
+func WithArchiveUserAgent(userAgent string) ArchiveOption {
+	return func(o *archiveOptions) { o.userAgent = userAgent }
+}
+
+// WithArchiveProxy sends every request through the proxy at proxyAddr.
+func WithArchiveProxy(proxyAddr string) ArchiveOption {
+	return func(o *archiveOptions) { o.proxyAddr = proxyAddr }
+}
+
+// WithArchiveProxyPool sends every request through the next proxy of pool. It takes
+// precedence over WithArchiveProxy.
+func WithArchiveProxyPool(pool *proxy.Pool) ArchiveOption {
+	return func(o *archiveOptions) { o.proxyPool = pool }
+}
+
+// WithArchiveHTTPClient makes the requests with client, which is never modified.
+func WithArchiveHTTPClient(client *http.Client) ArchiveOption {
+	return func(o *archiveOptions) { o.httpClient = client }
+}
+
+// WithArchiveConcurrency sets how many assets are fetched at once.
+// Default: DefaultAssetConcurrency.
+func WithArchiveConcurrency(n int) ArchiveOption {
+	return func(o *archiveOptions) { o.concurrency = n }
+}
+
+// WithMaxAssetSize sets the largest asset in bytes that is inlined. Larger ones are linked.
+// Default: DefaultMaxAssetSize.
+func WithMaxAssetSize(n int64) ArchiveOption {
+	return func(o *archiveOptions) { o.maxAssetSize = n }
+}
+
+// WithMaxInlinedSize sets how many bytes of assets are inlined in total. The assets
+// fetched once it is reached are linked.
+// Default: DefaultMaxInlinedSize.
+func WithMaxInlinedSize(n int64) ArchiveOption {
+	return func(o *archiveOptions) { o.maxInlinedSize = n }
+}
+
+// WithMaxArchiveSize sets the largest archive in bytes, above which ArchivePage fails with
+// ErrArchiveTooLarge.
+// Default: DefaultMaxArchiveSize.
+func WithMaxArchiveSize(n int64) ArchiveOption {
+	return func(o *archiveOptions) { o.maxArchiveSize = n }
+}
+
+// WithKeepScripts keeps the scripts of the page, which are removed by default.
+func WithKeepScripts() ArchiveOption {
+	return func(o *archiveOptions) { o.keepScripts = true }
+}
+
+// ArchivePage fetches the page at rawURL and writes it to w as a single HTML file that
+// needs nothing else to be displayed. Linked stylesheets are inlined in <style> elements,
+// and images, including those the stylesheets refer to, become data: URLs. Other links
+// are rewritten to absolute URLs. Scripts and event handler attributes are removed, unless
+// WithKeepScripts is given. The page is written as UTF-8.
+//
+// Assets are fetched concurrently. One that cannot be fetched, is larger than the maximum
+// asset size or comes after the maximum inlined size is reached keeps its absolute URL.
+// Nothing is written if ctx ends before the assets are fetched. An archive larger than the
+// maximum archive size fails with ErrArchiveTooLarge, in which case nothing is written.
+func ArchivePage(ctx context.Context, rawURL string, w io.Writer, opts ...ArchiveOption) error {
+	o := archiveOptions{
+		timeout:        DefaultAssetTimeout,
+		concurrency:    DefaultAssetConcurrency,
+		maxAssetSize:   DefaultMaxAssetSize,
+		maxInlinedSize: DefaultMaxInlinedSize,
+		maxArchiveSize: DefaultMaxArchiveSize,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.concurrency <= 0 {
+		o.concurrency = DefaultAssetConcurrency
+	}
+
+	clientOpts := httpopts.Options{
+		Timeout:    o.timeout,
+		ProxyAddr:  o.proxyAddr,
+		ProxyPool:  o.proxyPool,
+		HTTPClient: o.httpClient,
+	}
+	client, err := httpopts.NewClient(clientOpts)
+	if err != nil {
+		return fmt.Errorf("store: archive client: %w", err)
+	}
+	if httpopts.Owned(clientOpts) {
+		defer client.CloseIdleConnections()
+	}
+	a := &archiver{ctx: ctx, client: client, opts: o, assets: map[string]*archiveAsset{}, styleBases: map[*html.Node]*url.URL{}}
+
+	doc, base, err := a.fetchPage(rawURL)
+	if err != nil {
+		return err
+	}
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if u, err := base.Parse(strings.TrimSpace(href)); err == nil {
+			base = u
+		}
+	}
+	doc.Find("base").Remove()
+
+	if !o.keepScripts {
+		doc.Find("script").Remove()
+		doc.Find("*").Each(func(_ int, s *goquery.Selection) {
+			removeEventHandlers(s.Get(0))
+		})
+	}
+
+	// The stylesheets are fetched with the images of the page, then the images they refer to
+	stylesheets := doc.Find("link[href]").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return hasToken(s.AttrOr("rel", ""), "stylesheet")
+	})
+	images := doc.Find("img")
+	var refs []string
+	stylesheets.Each(func(_ int, s *goquery.Selection) {
+		refs = append(refs, resolve(base, s.AttrOr("href", "")))
+	})
+	images.Each(func(_ int, s *goquery.Selection) {
+		refs = append(refs, resolve(base, imageSource(s)))
+	})
+	a.fetchAll(refs)
+
+	stylesheets.Each(func(_ int, s *goquery.Selection) {
+		ref := resolve(base, s.AttrOr("href", ""))
+		asset := a.assets[ref]
+		if asset == nil || asset.data == nil {
+			s.SetAttr("href", ref)
+			return
+		}
+		style := &html.Node{Type: html.ElementNode, Data: "style", DataAtom: atom.Style}
+		if media, ok := s.Attr("media"); ok {
+			style.Attr = []html.Attribute{{Key: "media", Val: media}}
+		}
+		style.AppendChild(&html.Node{Type: html.TextNode, Data: string(asset.data)})
+		s.ReplaceWithNodes(style)
+		if sheetURL, err := url.Parse(ref); err == nil {
+			a.styleBases[style] = sheetURL
+		}
+	})
+
+	// The url() of every stylesheet and style attribute is resolved against its own URL
+	var styles []*html.Node
+	var cssRefs []string
+	doc.Find("style").Each(func(_ int, s *goquery.Selection) {
+		n := s.Get(0)
+		if n.FirstChild == nil {
+			return
+		}
+		styles = append(styles, n)
+		cssRefs = append(cssRefs, cssURLs(n.FirstChild.Data, a.styleBase(n, base))...)
+	})
+	doc.Find("[style]").Each(func(_ int, s *goquery.Selection) {
+		cssRefs = append(cssRefs, cssURLs(s.AttrOr("style", ""), base)...)
+	})
+	a.fetchAll(cssRefs)
+
+	for _, n := range styles {
+		n.FirstChild.Data = a.rewriteCSS(n.FirstChild.Data, a.styleBase(n, base))
+	}
+	doc.Find("[style]").Each(func(_ int, s *goquery.Selection) {
+		s.SetAttr("style", a.rewriteCSS(s.AttrOr("style", ""), base))
+	})
+
+	images.Each(func(_ int, s *goquery.Selection) {
+		ref := resolve(base, imageSource(s))
+		if data := a.dataURL(ref); data != "" {
+			s.SetAttr("src", data)
+			s.RemoveAttr("srcset")
+			s.RemoveAttr("sizes")
+			s.RemoveAttr("loading")
+			// The sources of a <picture> would take precedence over the inlined image
+			s.Parent().Filter("picture").ChildrenFiltered("source").Remove()
+		}
+	})
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	rewriteLinks(doc, base)
+	setCharset(doc)
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, doc.Get(0)); err != nil {
+		return fmt.Errorf("store: archive: %w", err)
+	}
+	if int64(buf.Len()) > o.maxArchiveSize {
+		return fmt.Errorf("store: archive %s: %w", rawURL, ErrArchiveTooLarge)
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// archiver holds the state of an ArchivePage call.
+type archiver struct {
+	ctx    context.Context
+	client *http.Client
+	opts   archiveOptions
+
+	// assets are written before fetchAll returns and read after
+	assets map[string]*archiveAsset
+	// styleBases are the URLs of the stylesheets inlined as <style> elements
+	styleBases map[*html.Node]*url.URL
+	// inlined is how many bytes of assets were kept for inlining
+	mu      sync.Mutex
+	inlined int64
+}
+
+// archiveAsset is a stylesheet or image fetched by the archiver. data is nil if it could
+// not be fetched or inlined.
+type archiveAsset struct {
+	data        []byte
+	contentType string
+}
+
+// fetchPage fetches and parses the page at rawURL. It returns the URL the page was
+// served from, after redirects.
+func (a *archiver) fetchPage(rawURL string) (*goquery.Document, *url.URL, error) {
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("store: archive %s: %w", rawURL, err)
+	}
+	a.setHeaders(req, "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("store: archive %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("store: archive %s: unexpected status %d", rawURL, resp.StatusCode)
+	}
+	if resp.ContentLength > a.opts.maxArchiveSize {
+		return nil, nil, fmt.Errorf("store: archive %s: %w", rawURL, ErrArchiveTooLarge)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, a.opts.maxArchiveSize+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("store: archive %s: %w", rawURL, err)
+	}
+	if int64(len(body)) > a.opts.maxArchiveSize {
+		return nil, nil, fmt.Errorf("store: archive %s: %w", rawURL, ErrArchiveTooLarge)
+	}
+	r, err := charset.NewReader(bytes.NewReader(body), resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("store: archive %s: %w", rawURL, err)
+	}
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("store: archive %s: %w", rawURL, err)
+	}
+	return doc, resp.Request.URL, nil
+}
+
+func (a *archiver) setHeaders(req *http.Request, accept string) {
+	if a.opts.userAgent != "" {
+		req.Header.Set("User-Agent", a.opts.userAgent)
+	}
+	req.Header.Set("Accept", accept)
+}
+
+// fetchAll fetches the http and https URLs of refs that were not fetched yet, at most
+// opts.concurrency at once.
+func (a *archiver) fetchAll(refs []string) {
+	var pending []*archiveAsset
+	var urls []string
+	for _, ref := range refs {
+		if _, ok := a.assets[ref]; ok || !strings.HasPrefix(ref, "http://") && !strings.HasPrefix(ref, "https://") {
+			continue
+		}
+		asset := &archiveAsset{}
+		a.assets[ref] = asset
+		pending = append(pending, asset)
+		urls = append(urls, ref)
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, a.opts.concurrency)
+	for i, asset := range pending {
+		asset, rawURL := asset, urls[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-a.ctx.Done():
+				return
+			}
+			a.fetch(asset, rawURL)
+		}()
+	}
+	wg.Wait()
+}
+
+// fetch fetches the asset at rawURL, leaving its data nil if it fails or the inlined
+// assets would exceed opts.maxInlinedSize.
+func (a *archiver) fetch(asset *archiveAsset, rawURL string) {
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return
+	}
+	a.setHeaders(req, "*/*")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ContentLength > a.opts.maxAssetSize {
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, a.opts.maxAssetSize+1))
+	if err != nil || int64(len(body)) > a.opts.maxAssetSize {
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(body))
+	}
+	// An error page served as the asset is not inlined
+	if mediaType == "text/html" {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.inlined+int64(len(body)) > a.opts.maxInlinedSize {
+		return
+	}
+	a.inlined += int64(len(body))
+	asset.data, asset.contentType = body, mediaType
+}
+
+// dataURL returns the data: URL of the asset fetched from ref, or "" if there is none.
+func (a *archiver) dataURL(ref string) string {
+	asset := a.assets[ref]
+	if asset == nil || asset.data == nil {
+		return ""
+	}
+	return "data:" + asset.contentType + ";base64," + base64.StdEncoding.EncodeToString(asset.data)
+}
+
+// styleBase returns the URL the url() of the <style> n are relative to: that of the
+// stylesheet it was inlined from, or base.
+func (a *archiver) styleBase(n *html.Node, base *url.URL) *url.URL {
+	if u, ok := a.styleBases[n]; ok {
+		return u
+	}
+	return base
+}
+
+// cssURLRegex matches the url() references of CSS, with the URL in one of its groups.
+var cssURLRegex = regexp.MustCompile(`(?i)url\(\s*(?:"([^"]*)"|'([^']*)'|([^)"'\s]*))\s*\)`)
+
+// cssURL returns the URL matched by cssURLRegex in groups.
+func cssURL(css string, groups []int) string {
+	for i := 2; i < len(groups); i += 2 {
+		if groups[i] >= 0 {
+			return css[groups[i]:groups[i+1]]
+		}
+	}
+	return ""
+}
+
+// cssURLs returns the absolute URLs of the url() references of css.
+func cssURLs(css string, base *url.URL) []string {
+	var refs []string
+	for _, groups := range cssURLRegex.FindAllStringSubmatchIndex(css, -1) {
+		refs = append(refs, resolve(base, cssURL(css, groups)))
+	}
+	return refs
+}
+
+// rewriteCSS replaces the url() references of css with the data: URLs of their assets,
+// or their absolute URLs.
+func (a *archiver) rewriteCSS(css string, base *url.URL) string {
+	return cssURLRegex.ReplaceAllStringFunc(css, func(match string) string {
+		ref := cssURL(match, cssURLRegex.FindStringSubmatchIndex(match))
+		if ref == "" || strings.HasPrefix(ref, "#") || strings.HasPrefix(strings.ToLower(ref), "data:") {
+			return match
+		}
+		abs := resolve(base, ref)
+		if data := a.dataURL(abs); data != "" {
+			abs = data
+		}
+		return `url("` + strings.ReplaceAll(abs, `"`, "%22") + `")`
+	})
+}
+
+// linkAttributes are the attributes holding URLs that rewriteLinks makes absolute, by
+// element.
+var linkAttributes = map[string][]string{
+	"a": {"href"}, "area": {"href"}, "link": {"href"}, "form": {"action"}, "iframe": {"src"},
+	"frame": {"src"}, "embed": {"src"}, "object": {"data"}, "script": {"src"},
+	"img": {"src"}, "video": {"src", "poster"}, "audio": {"src"}, "source": {"src"},
+	"track": {"src"}, "input": {"src"}, "blockquote": {"cite"}, "q": {"cite"},
+	"ins": {"cite"}, "del": {"cite"},
+}
+
+// rewriteLinks makes the URLs of doc absolute, except for fragments, which point into
+// the archive itself, and data: URLs. The candidates of srcset attributes are resolved too.
+func rewriteLinks(doc *goquery.Document, base *url.URL) {
+	doc.Find("*").Each(func(_ int, s *goquery.Selection) {
+		n := s.Get(0)
+		for _, key := range linkAttributes[n.Data] {
+			ref, ok := s.Attr(key)
+			if !ok {
+				continue
+			}
+			ref = strings.TrimSpace(ref)
+			if ref == "" || strings.HasPrefix(ref, "#") || strings.HasPrefix(strings.ToLower(ref), "data:") {
+				continue
+			}
+			s.SetAttr(key, resolve(base, ref))
+		}
+		if srcset, ok := s.Attr("srcset"); ok {
+			candidates := strings.Split(srcset, ",")
+			for i, candidate := range candidates {
+				fields := strings.Fields(candidate)
+				if len(fields) > 0 {
+					fields[0] = resolve(base, fields[0])
+					candidates[i] = strings.Join(fields, " ")
+				}
+			}
+			s.SetAttr("srcset", strings.Join(candidates, ", "))
+		}
+	})
+}
+
+// setCharset declares the archive as UTF-8, which html.Render writes, in place of the
+// charset of the page.
+func setCharset(doc *goquery.Document) {
+	doc.Find("meta[charset]").Remove()
+	doc.Find("meta[http-equiv]").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return strings.EqualFold(s.AttrOr("http-equiv", ""), "content-type")
+	}).Remove()
+	head := doc.Find("head").First()
+	if head.Length() == 0 {
+		return
+	}
+	meta := &html.Node{Type: html.ElementNode, Data: "meta", DataAtom: atom.Meta, Attr: []html.Attribute{{Key: "charset", Val: "utf-8"}}}
+	h := head.Get(0)
+	h.InsertBefore(meta, h.FirstChild)
+}
+
+// removeEventHandlers removes the on* attributes of n and its javascript: links.
+func removeEventHandlers(n *html.Node) {
+	attrs := n.Attr[:0]
+	for _, attr := range n.Attr {
+		key := strings.ToLower(attr.Key)
+		if strings.HasPrefix(key, "on") {
+			continue
+		}
+		if (key == "href" || key == "src" || key == "action") && strings.HasPrefix(strings.ToLower(strings.TrimSpace(attr.Val)), "javascript:") {
+			continue
+		}
+		attrs = append(attrs, attr)
+	}
+	n.Attr = attrs
+}
+
+// imageSource returns the URL of the image of an <img>, that of data-src for the lazy
+// loaded ones without a src.
+func imageSource(s *goquery.Selection) string {
+	src := strings.TrimSpace(s.AttrOr("src", ""))
+	if src == "" || strings.HasPrefix(src, "data:") {
+		if lazy := strings.TrimSpace(s.AttrOr("data-src", "")); lazy != "" {
+			return lazy
+		}
+	}
+	return src
+}
+
+// resolve returns ref resolved against base, or ref itself if it is empty or not a valid
+// URL.
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+// hasToken reports whether the space separated list s holds token, ignoring case.
+func hasToken(s, token string) bool {
+	for _, field := range strings.Fields(s) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const archivedPage = `<!DOCTYPE html><html><head><meta charset="iso-8859-1"><title>Caf` + "\xe9" + `</title>
+<link rel="stylesheet" href="/css/site.css" media="screen"><link rel="stylesheet" href="/css/missing.css">
+<link rel="icon" href="favicon.ico"><script src="/app.js"></script><script>track()</script>
+<style>.logo { background: url('img/logo.png') }</style></head>
+<body onload="init()"><h1 style="background-image:url(/a.png)">Title</h1>
+<p><a href="/about">About</a> <a href="#top">Top</a> <a href="javascript:void(0)" onclick="go()">Go</a></p>
+<img src="/a.png" srcset="/a.png 1x, /large.png 2x" alt="A"><img src="/missing.png"><img data-src="/a.png" src="data:image/gif;base64,R0lGOD">
+<picture><source srcset="/a.webp"><img src="/a.png"></picture><img src="/large.png"></body></html>`
+
+// archiveServer serves archivedPage at /page, its stylesheets and images, and a page of two
+// images at /two. It counts the most requests in flight at once.
+func archiveServer(t *testing.T) (*httptest.Server, *int32) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+			w.Write([]byte(archivedPage))
+		case "/two":
+			w.Write([]byte(`<html><body><img src="/a.png"><img src="/b.png"></body></html>`))
+		case "/redirect":
+			http.Redirect(w, r, "/page", http.StatusFound)
+		case "/css/site.css":
+			w.Header().Set("Content-Type", "text/css")
+			w.Write([]byte(`body { background: url("../a.png") } @font-face { src: url(/font.woff2) }`))
+		case "/a.png", "/b.png", "/img/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(pngData)
+		case "/large.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(append(pngData, bytes.Repeat([]byte{0}, 1000)...))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &maxInFlight
+}
+
+func archive(t *testing.T, rawURL string, opts ...ArchiveOption) string {
+	t.Helper()
+	var b strings.Builder
+	err := ArchivePage(context.Background(), rawURL, &b, opts...)
+	assert.NoError(t, err)
+	return b.String()
+}
+
+func TestArchivePage(t *testing.T) {
+	server, maxInFlight := archiveServer(t)
+	png := "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngData)
+
+	result := archive(t, server.URL+"/redirect", WithMaxAssetSize(500), WithArchiveConcurrency(2))
+
+	assert.Contains(t, result, `<meta charset="utf-8"/><title>Café</title>`)
+	assert.NotContains(t, result, "iso-8859-1")
+	// Stylesheets are inlined with their url() resolved against them
+	assert.Contains(t, result, `<style media="screen">body { background: url("`+png+`") } @font-face { src: url("`+server.URL+`/font.woff2") }</style>`)
+	assert.Contains(t, result, `<link rel="stylesheet" href="`+server.URL+`/css/missing.css"/>`)
+	assert.Contains(t, result, `<link rel="icon" href="`+server.URL+`/favicon.ico"/>`)
+	assert.Contains(t, result, `.logo { background: url("`+png+`") }`)
+	assert.Contains(t, result, `<h1 style="background-image:url(&#34;`+png+`&#34;)">`)
+	// Scripts and event handlers are removed
+	assert.NotContains(t, result, "script")
+	assert.NotContains(t, result, "init()")
+	assert.NotContains(t, result, "go()")
+	assert.Contains(t, result, `<a href="`+server.URL+`/about">About</a> <a href="#top">Top</a> <a>Go</a>`)
+	// Images are inlined, those that fail keep their absolute URL
+	assert.Contains(t, result, `<img src="`+png+`" alt="A"/>`)
+	assert.Contains(t, result, `<img src="`+server.URL+`/missing.png"/>`)
+	assert.Contains(t, result, `<img data-src="/a.png" src="`+png+`"/>`)
+	assert.Contains(t, result, `<picture><img src="`+png+`"/></picture>`)
+	assert.Contains(t, result, `<img src="`+server.URL+`/large.png"/>`)
+	assert.LessOrEqual(t, atomic.LoadInt32(maxInFlight), int32(2))
+}
+
+func TestArchivePageKeepScripts(t *testing.T) {
+	server, _ := archiveServer(t)
+
+	result := archive(t, server.URL+"/page", WithKeepScripts())
+
+	assert.Contains(t, result, `<script src="`+server.URL+`/app.js"></script><script>track()</script>`)
+	assert.Contains(t, result, `<body onload="init()">`)
+}
+
+func TestArchivePageMaxInlinedSize(t *testing.T) {
+	server, _ := archiveServer(t)
+
+	result := archive(t, server.URL+"/two", WithMaxInlinedSize(int64(len(pngData))))
+
+	// Whichever image is fetched first is inlined
+	assert.Equal(t, 1, strings.Count(result, "data:image/png"))
+	assert.Equal(t, 1, strings.Count(result, `src="`+server.URL))
+}
+
+func TestArchivePageTooLarge(t *testing.T) {
+	server, _ := archiveServer(t)
+
+	var b bytes.Buffer
+	err := ArchivePage(context.Background(), server.URL+"/page", &b, WithMaxArchiveSize(2000))
+
+	assert.ErrorIs(t, err, ErrArchiveTooLarge)
+	assert.Zero(t, b.Len())
+
+	err = ArchivePage(context.Background(), server.URL+"/page", &b, WithMaxArchiveSize(100))
+	assert.ErrorIs(t, err, ErrArchiveTooLarge)
+}
+
+func TestArchivePageErrors(t *testing.T) {
+	server, _ := archiveServer(t)
+
+	var b bytes.Buffer
+	assert.Error(t, ArchivePage(context.Background(), server.URL+"/missing", &b))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, ArchivePage(ctx, server.URL+"/page", &b), context.Canceled)
+	assert.Zero(t, b.Len())
+}
+
+func TestArchivePageProxy(t *testing.T) {
+	var proxied int32
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&proxied, 1)
+		assert.Equal(t, "pages.example", r.URL.Host)
+		if r.URL.Path == "/a.png" {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(pngData)
+			return
+		}
+		w.Write([]byte(`<html><body><img src="/a.png"></body></html>`))
+	}))
+	defer proxyServer.Close()
+
+	result := archive(t, "http://pages.example/page", WithArchiveProxy(proxyServer.URL), WithArchiveTimeout(time.Second))
+
+	assert.Contains(t, result, `<img src="data:image/png;base64,`)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&proxied))
+}
@@ -0,0 +1,383 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/propro-productions/go-utils/internal/httpopts"
+	"github.com/propro-productions/go-utils/proxy"
+)
+
+const (
+	// DefaultAssetConcurrency is the default for AssetOptions.Concurrency.
+	DefaultAssetConcurrency = 4
+
+	// DefaultMaxAssetSize is the default for AssetOptions.MaxSize.
+	DefaultMaxAssetSize = 10 << 20
+
+	// DefaultAssetTimeout is the default for AssetOptions.Timeout.
+	DefaultAssetTimeout = 30 * time.Second
+)
+
+var (
+	// ErrAssetTooLarge indicates an image larger than AssetOptions.MaxSize.
+	ErrAssetTooLarge = errors.New("asset too large")
+
+	// ErrNotImage indicates a response that is not an image.
+	ErrNotImage = errors.New("asset is not an image")
+)
+
+// AssetOptions modifies how DownloadAssets and DownloadMarkdownAssets work.
+type AssetOptions struct {
+
+	// BaseURL resolves relative image references.
+	// Default: the URL of the content for DownloadAssets.
+	BaseURL string
+
+	// PathPrefix is put before the file name of an image in the rewritten references, so that
+	// they are relative to where the content is saved.
+	// Default: the last element of the destination directory, for content saved next to it.
+	PathPrefix string
+
+	// Concurrency is how many images are downloaded at once.
+	// Default: DefaultAssetConcurrency.
+	Concurrency int
+
+	// MaxSize is the largest image in bytes that is downloaded.
+	// Default: DefaultMaxAssetSize.
+	MaxSize int64
+
+	// Timeout bounds every download made with the default client.
+	// Default: DefaultAssetTimeout.
+	Timeout time.Duration
+
+	// UserAgent is sent with every request, if set.
+	UserAgent string
+
+	// ProxyAddr sends every request through a proxy.
+	ProxyAddr string
+
+	// ProxyPool sends every request through the next proxy of the pool. It takes precedence
+	// over ProxyAddr.
+	ProxyPool *proxy.Pool
+
+	// HTTPClient makes the requests. It is never modified.
+	// Default: a client with Timeout.
+	HTTPClient *http.Client
+}
+
+// Asset is an image referenced by the content, and where it was saved.
+type Asset struct {
+
+	// URL is the absolute URL the image was downloaded from.
+	URL string `json:"url"`
+
+	// Path is the reference written in place of the original one, PathPrefix and the file
+	// name. It is empty if the download failed.
+	Path string `json:"path,omitempty"`
+
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+
+	// Err is why the download failed, in which case the original reference is kept.
+	Err error `json:"-"`
+}
+
+// AssetManifest maps every image reference of the content, as it was written there, to its
+// Asset.
+type AssetManifest map[string]Asset
+
+// Failed returns the references whose image could not be downloaded.
+func (m AssetManifest) Failed() []string {
+	var failed []string
+	for ref, asset := range m {
+		if asset.Err != nil {
+			failed = append(failed, ref)
+		}
+	}
+	return failed
+}
+
+// DownloadAssets downloads the images of content into dir and rewrites the Src of their
+// blocks to the local copies. Files are named by the hash of their content, so images
+// referenced twice or downloaded again are stored once.
+//
+// An image that cannot be downloaded, is not an image or is larger than MaxSize keeps its
+// original reference and is reported in the manifest. The error is only set if dir cannot
+// be created or ctx ends, in which case the images not downloaded yet are left as they are.
+func DownloadAssets(ctx context.Context, content *ExtractedContent, dir string, opts AssetOptions) (AssetManifest, error) {
+	if opts.BaseURL == "" {
+		opts.BaseURL = content.URL
+	}
+	var refs []string
+	for _, block := range content.Blocks {
+		if block.Kind == BlockImage {
+			refs = append(refs, block.Src)
+		}
+	}
+
+	manifest, err := downloadAssets(ctx, refs, dir, opts)
+	for i, block := range content.Blocks {
+		if asset, ok := manifest[block.Src]; ok && block.Kind == BlockImage && asset.Err == nil {
+			content.Blocks[i].Src = asset.Path
+		}
+	}
+	return manifest, err
+}
+
+// markdownImageRegex matches the start of a markdown image up to its destination.
+var markdownImageRegex = regexp.MustCompile(`!\[(?:[^\]\\]|\\.)*\]\(\s*<?([^)\s>]+)`)
+
+// DownloadMarkdownAssets is like DownloadAssets for markdown, such as that written by the
+// markdown package. The images of ![alt](src) references are downloaded, those in code
+// fences are left alone.
+func DownloadMarkdownAssets(ctx context.Context, markdown, dir string, opts AssetOptions) (string, AssetManifest, error) {
+	lines := strings.Split(markdown, "\n")
+	// inCode marks the lines that belong to a code fence
+	inCode := make([]bool, len(lines))
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t>")
+		switch {
+		case fence != "":
+			inCode[i] = true
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(strings.TrimSpace(trimmed), fence[:1]) == "" {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			inCode[i] = true
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+		}
+	}
+
+	var refs []string
+	for i, line := range lines {
+		if inCode[i] {
+			continue
+		}
+		for _, match := range markdownImageRegex.FindAllStringSubmatch(line, -1) {
+			refs = append(refs, match[1])
+		}
+	}
+
+	manifest, err := downloadAssets(ctx, refs, dir, opts)
+	for i, line := range lines {
+		if inCode[i] {
+			continue
+		}
+		lines[i] = markdownImageRegex.ReplaceAllStringFunc(line, func(image string) string {
+			match := markdownImageRegex.FindStringSubmatchIndex(image)
+			ref := image[match[2]:match[3]]
+			if asset, ok := manifest[ref]; ok && asset.Err == nil {
+				return image[:match[2]] + asset.Path
+			}
+			return image
+		})
+	}
+	return strings.Join(lines, "\n"), manifest, err
+}
+
+// downloadAssets downloads the images refs reference into dir, every one once.
+// References that are not http or https URLs, such as data: URLs, are skipped.
+func downloadAssets(ctx context.Context, refs []string, dir string, opts AssetOptions) (AssetManifest, error) {
+	manifest := AssetManifest{}
+	base, err := url.Parse(opts.BaseURL)
+	if err != nil {
+		return manifest, fmt.Errorf("store: asset base URL: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return manifest, fmt.Errorf("store: asset directory: %w", err)
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultAssetTimeout
+	}
+	client, err := httpopts.NewClient(httpopts.Options{
+		Timeout:    timeout,
+		ProxyAddr:  opts.ProxyAddr,
+		ProxyPool:  opts.ProxyPool,
+		HTTPClient: opts.HTTPClient,
+	})
+	if err != nil {
+		return manifest, fmt.Errorf("store: asset client: %w", err)
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultAssetConcurrency
+	}
+	prefix := opts.PathPrefix
+	if prefix == "" {
+		prefix = filepath.Base(filepath.Clean(dir))
+	}
+
+	// Every goroutine only writes the asset of its own reference
+	var unique []string
+	assets := map[string]*Asset{}
+	for _, ref := range refs {
+		if _, ok := assets[ref]; ok {
+			continue
+		}
+		u, err := base.Parse(strings.TrimSpace(ref))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		unique = append(unique, ref)
+		assets[ref] = &Asset{URL: u.String()}
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for _, ref := range unique {
+		asset := assets[ref]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				asset.Err = ctx.Err()
+				return
+			}
+			if asset.Err = ctx.Err(); asset.Err != nil {
+				return
+			}
+			name, err := downloadAsset(ctx, client, asset, dir, opts)
+			if err == nil {
+				asset.Path = path.Join(filepath.ToSlash(prefix), name)
+			}
+			asset.Err = err
+		}()
+	}
+	wg.Wait()
+
+	for ref, asset := range assets {
+		manifest[ref] = *asset
+	}
+	return manifest, ctx.Err()
+}
+
+// downloadAsset downloads asset.URL into dir and returns the file name it is saved as.
+func downloadAsset(ctx context.Context, client *http.Client, asset *Asset, dir string, opts AssetOptions) (string, error) {
+	maxSize := opts.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxAssetSize
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", asset.URL, nil)
+	if err != nil {
+		return "", err
+	}
+	if opts.UserAgent != "" {
+		req.Header.Set("User-Agent", opts.UserAgent)
+	}
+	req.Header.Set("Accept", "image/avif,image/webp,image/*,*/*;q=0.8")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxSize {
+		return "", ErrAssetTooLarge
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(body)) > maxSize {
+		return "", ErrAssetTooLarge
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mediaType, "image/") {
+		// Servers label images as application/octet-stream and the like
+		if mediaType = http.DetectContentType(body); !strings.HasPrefix(mediaType, "image/") {
+			return "", ErrNotImage
+		}
+	}
+	asset.ContentType = mediaType
+	asset.Size = int64(len(body))
+
+	sum := sha256.Sum256(body)
+	name := hex.EncodeToString(sum[:16]) + imageExtension(mediaType, asset.URL)
+	file := filepath.Join(dir, name)
+	if _, err := os.Stat(file); err == nil {
+		return name, nil
+	}
+	return name, writeFileAtomic(file, body)
+}
+
+// imageExtensions are the extensions of the common image types, which mime.ExtensionsByType
+// returns in an order that depends on the system.
+var imageExtensions = map[string]string{
+	"image/avif":    ".avif",
+	"image/bmp":     ".bmp",
+	"image/gif":     ".gif",
+	"image/jpeg":    ".jpg",
+	"image/png":     ".png",
+	"image/svg+xml": ".svg",
+	"image/tiff":    ".tiff",
+	"image/webp":    ".webp",
+	"image/x-icon":  ".ico",
+}
+
+// imageExtension returns the file extension for an image of the given type, falling back to
+// that of its URL.
+func imageExtension(mediaType, rawURL string) string {
+	if ext, ok := imageExtensions[mediaType]; ok {
+		return ext
+	}
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		return exts[0]
+	}
+	if u, err := url.Parse(rawURL); err == nil {
+		if ext := path.Ext(u.Path); len(ext) <= 5 {
+			return strings.ToLower(ext)
+		}
+	}
+	return ""
+}
+
+// writeFileAtomic writes data to a temporary file renamed to name, so that name is never
+// left half written.
+func writeFileAtomic(name string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(name), ".asset-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), name); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Chmod(name, 0o644)
+}
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var pngData = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDRfake image data")
+
+// assetServer serves a PNG at /a.png and /copy.png, a PNG labelled as octet-stream at
+// /unlabelled, a page at /page, a large PNG at /large.png, and 404 for anything else.
+func assetServer(t *testing.T) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch r.URL.Path {
+		case "/a.png", "/copy.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(pngData)
+		case "/unlabelled":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(pngData)
+		case "/page":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><body>Not an image</body></html>"))
+		case "/large.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(append(pngData, bytes.Repeat([]byte{0}, 1000)...))
+		case "/slow.png":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestDownloadAssets(t *testing.T) {
+	server, requests := assetServer(t)
+	dir := filepath.Join(t.TempDir(), "assets")
+	content := &ExtractedContent{URL: server.URL + "/article/", Blocks: []Block{
+		{Kind: BlockParagraph, Text: "Text"},
+		{Kind: BlockImage, Src: "/a.png", Alt: "A"},
+		{Kind: BlockImage, Src: server.URL + "/copy.png"},
+		{Kind: BlockImage, Src: "../unlabelled"},
+		{Kind: BlockImage, Src: "/page"},
+		{Kind: BlockImage, Src: "/large.png"},
+		{Kind: BlockImage, Src: "/missing.png"},
+		{Kind: BlockImage, Src: "data:image/png;base64,AAAA"},
+		{Kind: BlockImage, Src: "/a.png"},
+	}}
+
+	manifest, err := DownloadAssets(context.Background(), content, dir, AssetOptions{MaxSize: 500, Concurrency: 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(6), atomic.LoadInt32(requests), "every image is downloaded once")
+	assert.Len(t, manifest, 6)
+
+	local := manifest["/a.png"]
+	assert.NoError(t, local.Err)
+	assert.Equal(t, server.URL+"/a.png", local.URL)
+	assert.Equal(t, "image/png", local.ContentType)
+	assert.Equal(t, int64(len(pngData)), local.Size)
+	assert.Regexp(t, `^assets/[0-9a-f]{32}\.png$`, local.Path)
+	assert.Equal(t, local.Path, manifest[server.URL+"/copy.png"].Path, "identical images share a file")
+	assert.Equal(t, local.Path, manifest["../unlabelled"].Path)
+
+	saved, err := os.ReadFile(filepath.Join(filepath.Dir(dir), filepath.FromSlash(local.Path)))
+	assert.NoError(t, err)
+	assert.Equal(t, pngData, saved)
+	files, _ := os.ReadDir(dir)
+	assert.Len(t, files, 1)
+
+	assert.ErrorIs(t, manifest["/page"].Err, ErrNotImage)
+	assert.ErrorIs(t, manifest["/large.png"].Err, ErrAssetTooLarge)
+	assert.Error(t, manifest["/missing.png"].Err)
+	failed := manifest.Failed()
+	sort.Strings(failed)
+	assert.Equal(t, []string{"/large.png", "/missing.png", "/page"}, failed)
+
+	var srcs []string
+	for _, block := range content.Blocks {
+		if block.Kind == BlockImage {
+			srcs = append(srcs, block.Src)
+		}
+	}
+	assert.Equal(t, []string{local.Path, local.Path, local.Path, "/page", "/large.png", "/missing.png", "data:image/png;base64,AAAA", local.Path}, srcs)
+}
+
+func TestDownloadMarkdownAssets(t *testing.T) {
+	server, _ := assetServer(t)
+	markdown := "# Title\n\n![A \\] picture](" + server.URL + "/a.png \"Title\") and ![missing](/missing.png)\n\n" +
+		"```\n![in code](" + server.URL + "/copy.png)\n```\n\n[![thumb](/a.png)](https://example.com/video)\n"
+
+	result, manifest, err := DownloadMarkdownAssets(context.Background(), markdown, filepath.Join(t.TempDir(), "img"), AssetOptions{BaseURL: server.URL, PathPrefix: "./files"})
+
+	assert.NoError(t, err)
+	path := manifest["/a.png"].Path
+	assert.Regexp(t, `^files/[0-9a-f]{32}\.png$`, path)
+	assert.Equal(t, "# Title\n\n![A \\] picture]("+path+" \"Title\") and ![missing](/missing.png)\n\n"+
+		"```\n![in code]("+server.URL+"/copy.png)\n```\n\n[![thumb]("+path+")](https://example.com/video)\n", result)
+	assert.Len(t, manifest, 3)
+	assert.Error(t, manifest["/missing.png"].Err)
+}
+
+func TestDownloadAssetsDeadline(t *testing.T) {
+	server, _ := assetServer(t)
+	content := &ExtractedContent{URL: server.URL, Blocks: []Block{
+		{Kind: BlockImage, Src: "/slow.png"},
+		{Kind: BlockImage, Src: "/a.png"},
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	manifest, err := DownloadAssets(ctx, content, t.TempDir(), AssetOptions{Concurrency: 1})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Error(t, manifest["/slow.png"].Err)
+	assert.Equal(t, "/slow.png", content.Blocks[0].Src)
+}
+
+func TestDownloadAssetsProxy(t *testing.T) {
+	var proxied int32
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&proxied, 1)
+		assert.Equal(t, "images.example", r.URL.Host)
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(pngData)
+	}))
+	defer proxyServer.Close()
+	content := &ExtractedContent{Blocks: []Block{{Kind: BlockImage, Src: "http://images.example/a.png"}}}
+
+	manifest, err := DownloadAssets(context.Background(), content, t.TempDir(), AssetOptions{ProxyAddr: proxyServer.URL})
+
+	assert.NoError(t, err)
+	assert.NoError(t, manifest["http://images.example/a.png"].Err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&proxied))
+}
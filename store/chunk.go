@@ -0,0 +1,343 @@
+package store
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultChunkTokens is the default for ChunkOptions.MaxTokens.
+	DefaultChunkTokens = 512
+
+	// DefaultCharsPerToken is the default for ChunkOptions.CharsPerToken, about the number of
+	// characters of English text in a token of the common embedding models.
+	DefaultCharsPerToken = 4
+)
+
+// ChunkOptions changes how Chunk splits content.
+type ChunkOptions struct {
+
+	// MaxTokens is the most tokens a chunk holds, Overlap included. With RespectStructure,
+	// a code block or table longer than it is a chunk of its own, longer than MaxTokens.
+	// Default: DefaultChunkTokens.
+	MaxTokens int
+
+	// Overlap is how many tokens of the end of a chunk are repeated at the start of the next
+	// one, so that text around the split is not lost to either. It is at most half of
+	// MaxTokens. With RespectStructure, the overlap is made of whole sentences where
+	// possible, and is left out for chunks starting with a heading.
+	Overlap int
+
+	// CharsPerToken approximates the tokens of a text as its number of characters divided
+	// by it. Default: DefaultCharsPerToken.
+	CharsPerToken float64
+
+	// Tokenizer, if set, counts the tokens of a text instead of CharsPerToken, e.g. with the
+	// tokenizer of the embedding model.
+	Tokenizer func(text string) int
+
+	// RespectStructure splits content at the boundaries of its structure: preferably before
+	// a heading, then between blocks, then between sentences, and between words only for a
+	// sentence longer than a chunk. Code blocks and tables are never split. Without it,
+	// content is split between any two words.
+	RespectStructure bool
+}
+
+// ContentChunk is a piece of ExtractedContent small enough to be embedded, as returned by
+// Chunk.
+type ContentChunk struct {
+	Text string `json:"text"`
+
+	// Headings is the path of the headings the start of the chunk falls under, outermost
+	// first, such as ["Guide", "Install", "Linux"]. A chunk starting with a heading is under
+	// it.
+	Headings []string `json:"headings,omitempty"`
+
+	// StartBlock and EndBlock are the indices of the blocks the text comes from:
+	// ExtractedContent.Blocks[StartBlock:EndBlock]. Blocks of chunks overlap with Overlap.
+	StartBlock int `json:"start_block"`
+	EndBlock   int `json:"end_block"`
+
+	// Tokens is the number of tokens of Text, as counted with the options.
+	Tokens int `json:"tokens"`
+}
+
+// Boundaries between segments, weakest first. Content is split at the strongest boundary
+// that makes its pieces fit in a chunk.
+const (
+	boundaryWord = iota
+	boundarySentence
+	boundaryBlock
+	boundaryHeading
+)
+
+// chunkSegment is a piece of text Chunk puts together into chunks: a sentence, a word, or
+// with RespectStructure a whole code block or table.
+type chunkSegment struct {
+	text string
+	// sep is written before text unless the segment starts a chunk.
+	sep      string
+	boundary int
+	// first and last are the indices of the blocks the segment comes from.
+	first, last int
+	// atomic segments are never split.
+	atomic bool
+}
+
+// sentenceEndRegexp matches the end of a sentence and the whitespace after it.
+var sentenceEndRegexp = regexp.MustCompile(`[.!?…。！？]+["'”’)\]]*\s+`)
+
+// Chunk splits the text of content into chunks of at most opts.MaxTokens tokens, for
+// embedding. Links and images are left out, as their text is part of the blocks holding
+// them. The chunks only depend on content and opts.
+func Chunk(content *ExtractedContent, opts ChunkOptions) []ContentChunk {
+	if content == nil {
+		return nil
+	}
+	c := newChunker(opts)
+	segments := c.segments(content.Blocks)
+	if len(segments) == 0 {
+		return nil
+	}
+
+	level := boundaryWord
+	if opts.RespectStructure {
+		level = boundaryHeading
+	}
+	groups := c.split(segments, level)
+	paths := headingPaths(content.Blocks)
+
+	chunks := make([]ContentChunk, 0, len(groups))
+	for i, group := range groups {
+		if i > 0 {
+			group = append(c.overlap(groups[i-1], group[0]), group...)
+		}
+		text := joinSegments(group)
+		chunks = append(chunks, ContentChunk{
+			Text:       text,
+			Headings:   append([]string(nil), paths[group[0].first]...),
+			StartBlock: group[0].first,
+			EndBlock:   group[len(group)-1].last + 1,
+			Tokens:     c.tokens(text),
+		})
+	}
+	return chunks
+}
+
+type chunker struct {
+	opts ChunkOptions
+	// budget is the most tokens of a chunk before the overlap is added.
+	budget int
+}
+
+func newChunker(opts ChunkOptions) *chunker {
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = DefaultChunkTokens
+	}
+	if opts.CharsPerToken <= 0 {
+		opts.CharsPerToken = DefaultCharsPerToken
+	}
+	if opts.Overlap < 0 {
+		opts.Overlap = 0
+	}
+	if opts.Overlap > opts.MaxTokens/2 {
+		opts.Overlap = opts.MaxTokens / 2
+	}
+	return &chunker{opts: opts, budget: opts.MaxTokens - opts.Overlap}
+}
+
+func (c *chunker) tokens(text string) int {
+	if c.opts.Tokenizer != nil {
+		return c.opts.Tokenizer(text)
+	}
+	return int(math.Ceil(float64(utf8.RuneCountInString(text)) / c.opts.CharsPerToken))
+}
+
+func (c *chunker) fits(segments []chunkSegment) bool {
+	return c.tokens(joinSegments(segments)) <= c.budget
+}
+
+// segments returns the text of blocks as sentences, with RespectStructure, or as a segment
+// per block to be split into words.
+func (c *chunker) segments(blocks []Block) []chunkSegment {
+	var segments []chunkSegment
+	for i := 0; i < len(blocks); i++ {
+		block := blocks[i]
+		boundary := boundaryBlock
+		if block.Kind == BlockHeading {
+			boundary = boundaryHeading
+		}
+
+		var text string
+		first := i
+		atomic := c.opts.RespectStructure
+		switch block.Kind {
+		case BlockHeading:
+			text = block.Text
+		case BlockParagraph, BlockListItem, BlockBlockquote:
+			text, atomic = block.Text, false
+		case BlockCode:
+			text = block.Text
+		case BlockTable:
+			text = tableText(block.Rows)
+		case BlockTableRow:
+			// The rows of a table from StreamExtract are a table together
+			rows := block.Rows
+			for i+1 < len(blocks) && blocks[i+1].Kind == BlockTableRow {
+				i++
+				rows = append(rows[:len(rows):len(rows)], blocks[i].Rows...)
+			}
+			text = tableText(rows)
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		segment := chunkSegment{text: text, sep: "\n\n", boundary: boundary, first: first, last: i, atomic: atomic}
+		if !c.opts.RespectStructure || atomic {
+			segments = append(segments, segment)
+			continue
+		}
+		for j, sentence := range splitSentences(text) {
+			if j > 0 {
+				segment.sep, segment.boundary = " ", boundarySentence
+			}
+			segment.text = sentence
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// split groups segments into chunks, splitting them at boundaries of level and merging the
+// pieces while they fit. Pieces that do not fit are split at the next weaker boundary.
+func (c *chunker) split(segments []chunkSegment, level int) [][]chunkSegment {
+	if level == boundaryWord {
+		segments = splitSegmentWords(segments)
+	}
+
+	var groups [][]chunkSegment
+	var current []chunkSegment
+	for _, part := range partition(segments, level) {
+		candidate := append(append([]chunkSegment(nil), current...), part...)
+		if c.fits(candidate) {
+			current = candidate
+			continue
+		}
+		if len(current) > 0 {
+			groups = append(groups, current)
+			current = nil
+		}
+		// Words and atomic segments cannot be split further
+		if c.fits(part) || level == boundaryWord {
+			current = part
+			continue
+		}
+		groups = append(groups, c.split(part, level-1)...)
+	}
+	if len(current) > 0 {
+		groups = append(groups, current)
+	}
+	return groups
+}
+
+// overlap returns the segments at the end of previous to repeat before next, the first
+// segment of the following chunk.
+func (c *chunker) overlap(previous []chunkSegment, next chunkSegment) []chunkSegment {
+	if c.opts.Overlap <= 0 || c.opts.RespectStructure && next.boundary == boundaryHeading {
+		return nil
+	}
+	n := 0
+	for n < len(previous) && c.tokens(joinSegments(previous[len(previous)-n-1:])) <= c.opts.Overlap {
+		n++
+	}
+	return append([]chunkSegment(nil), previous[len(previous)-n:]...)
+}
+
+// partition splits segments before every boundary of level or stronger.
+func partition(segments []chunkSegment, level int) [][]chunkSegment {
+	var parts [][]chunkSegment
+	start := 0
+	for i := 1; i < len(segments); i++ {
+		if segments[i].boundary >= level {
+			parts = append(parts, segments[start:i])
+			start = i
+		}
+	}
+	return append(parts, segments[start:])
+}
+
+// splitSegmentWords splits the segments that are not atomic into words.
+func splitSegmentWords(segments []chunkSegment) []chunkSegment {
+	var words []chunkSegment
+	for _, segment := range segments {
+		if segment.atomic {
+			words = append(words, segment)
+			continue
+		}
+		for j, word := range strings.Fields(segment.text) {
+			w := segment
+			if j > 0 {
+				w.sep, w.boundary = " ", boundaryWord
+			}
+			w.text = word
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for _, loc := range sentenceEndRegexp.FindAllStringIndex(text, -1) {
+		if sentence := strings.TrimSpace(text[start:loc[1]]); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		start = loc[1]
+	}
+	if sentence := strings.TrimSpace(text[start:]); sentence != "" {
+		sentences = append(sentences, sentence)
+	}
+	return sentences
+}
+
+func joinSegments(segments []chunkSegment) string {
+	var b strings.Builder
+	for i, segment := range segments {
+		if i > 0 {
+			b.WriteString(segment.sep)
+		}
+		b.WriteString(segment.text)
+	}
+	return b.String()
+}
+
+// tableText writes the rows of a table as lines of cells separated by " | ".
+func tableText(rows [][]string) string {
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		lines[i] = strings.Join(row, " | ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// headingPaths returns, for every block, the path of the headings it falls under.
+func headingPaths(blocks []Block) [][]string {
+	paths := make([][]string, len(blocks))
+	var levels []int
+	var path []string
+	for i, block := range blocks {
+		if block.Kind == BlockHeading {
+			for len(levels) > 0 && levels[len(levels)-1] >= block.Level {
+				levels, path = levels[:len(levels)-1], path[:len(path)-1]
+			}
+			levels = append(levels, block.Level)
+			path = append(path[:len(path):len(path)], block.Text)
+		}
+		paths[i] = path
+	}
+	return paths
+}
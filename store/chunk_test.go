@@ -0,0 +1,144 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func loadGuide(t *testing.T) *ExtractedContent {
+	t.Helper()
+	return Extract(loadFixture(t, "guide.html").Find("article"))
+}
+
+func TestChunkStructure(t *testing.T) {
+	content := loadGuide(t)
+	opts := ChunkOptions{MaxTokens: 200, Overlap: 30, RespectStructure: true}
+
+	chunks := Chunk(content, opts)
+
+	assert.Equal(t, chunks, Chunk(content, opts))
+	if !assert.Len(t, chunks, 12) {
+		return
+	}
+
+	for _, c := range chunks {
+		assert.LessOrEqual(t, c.Tokens, 200)
+		assert.Less(t, c.StartBlock, c.EndBlock)
+		// Chunks starting with a heading are under it
+		if content.Blocks[c.StartBlock].Kind == BlockHeading {
+			assert.True(t, strings.HasPrefix(c.Text, c.Headings[len(c.Headings)-1]+"\n\n"), c.Text)
+		}
+	}
+
+	assert.Equal(t, ContentChunk{
+		Text:       "Beans\n\nFresh beans are the single biggest improvement most people can make. Coffee is at its best between one and four weeks after roasting, when most of the carbon dioxide has escaped but the aromatics have not. Beans older than two months make flat, woody shots no matter how well they are ground.",
+		Headings:   []string{"A Field Guide to Home Espresso", "Beans"},
+		StartBlock: 3,
+		EndBlock:   5,
+		Tokens:     75,
+	}, chunks[1])
+	assert.Equal(t, []string{"A Field Guide to Home Espresso", "Beans", "Storage"}, chunks[3].Headings)
+	assert.True(t, strings.HasSuffix(chunks[3].Text, "Let a fresh bag rest for a few days before pulling shots."))
+
+	// The section too long for a chunk is split between blocks, the next chunk repeating
+	// the last sentence
+	assert.Equal(t, []string{"A Field Guide to Home Espresso", "Grinding"}, chunks[5].Headings)
+	assert.Equal(t, 15, chunks[5].StartBlock)
+	assert.True(t, strings.HasPrefix(chunks[5].Text, "Change it in small steps"))
+	assert.True(t, strings.HasSuffix(chunks[4].Text, "since the old grounds sit in the burrs."))
+	// No overlap across headings
+	assert.True(t, strings.HasPrefix(chunks[6].Text, "Distribution and tamping\n\n"))
+
+	// Tables and code blocks are kept whole
+	table := "Style | Dose | Yield | Time\nRistretto | 18 g | 27 g | 25 s\nNormale | 18 g | 36 g | 28 s\nLungo | 18 g | 54 g | 32 s"
+	assert.Contains(t, chunks[7].Text, table)
+	assert.Contains(t, chunks[8].Text, content.Blocks[24].Text)
+	assert.Equal(t, 25, chunks[8].EndBlock)
+
+	assert.Equal(t, []string{"A Field Guide to Home Espresso", "Cleaning"}, chunks[11].Headings)
+	assert.Equal(t, len(content.Blocks), chunks[11].EndBlock)
+}
+
+func TestChunkWords(t *testing.T) {
+	content := loadGuide(t)
+	words := func(text string) int { return len(strings.Fields(text)) }
+
+	chunks := Chunk(content, ChunkOptions{MaxTokens: 60, Overlap: 10, Tokenizer: words})
+
+	assert.Greater(t, len(chunks), 10)
+	var all []string
+	for i, c := range chunks {
+		assert.LessOrEqual(t, c.Tokens, 60)
+		assert.Equal(t, words(c.Text), c.Tokens)
+		fields := strings.Fields(c.Text)
+		if i > 0 {
+			previous := strings.Fields(chunks[i-1].Text)
+			assert.Equal(t, previous[len(previous)-10:], fields[:10])
+			fields = fields[10:]
+		}
+		all = append(all, fields...)
+	}
+
+	// Without the overlap, the chunks are the text of the content in order
+	var text []string
+	for _, block := range content.Blocks {
+		switch block.Kind {
+		case BlockTable:
+			text = append(text, strings.Fields(tableText(block.Rows))...)
+		case BlockLink, BlockImage:
+		default:
+			text = append(text, strings.Fields(block.Text)...)
+		}
+	}
+	assert.Equal(t, text, all)
+}
+
+func TestChunkOversized(t *testing.T) {
+	code := strings.Repeat("fmt.Println(\"espresso\")\n", 10)
+	long := strings.Repeat("very ", 30) + "long sentence."
+	content := &ExtractedContent{Blocks: []Block{
+		{Kind: BlockHeading, Level: 1, Text: "Code"},
+		{Kind: BlockCode, Text: code},
+		{Kind: BlockParagraph, Text: long + " Short one."},
+	}}
+
+	chunks := Chunk(content, ChunkOptions{MaxTokens: 20, RespectStructure: true})
+
+	if assert.GreaterOrEqual(t, len(chunks), 4) {
+		assert.Equal(t, ContentChunk{Text: "Code", Headings: []string{"Code"}, StartBlock: 0, EndBlock: 1, Tokens: 1}, chunks[0])
+		// The code block is longer than MaxTokens but not split
+		assert.Equal(t, code, chunks[1].Text)
+		assert.Greater(t, chunks[1].Tokens, 20)
+		// The sentence is split between words, the next one is not
+		assert.True(t, strings.HasPrefix(chunks[2].Text, "very very"))
+		assert.Equal(t, "Short one.", chunks[len(chunks)-1].Text)
+	}
+	for _, c := range chunks[2:] {
+		assert.LessOrEqual(t, c.Tokens, 20)
+		assert.Equal(t, []string{"Code"}, c.Headings)
+		assert.Equal(t, 2, c.StartBlock)
+	}
+}
+
+func TestChunkTableRows(t *testing.T) {
+	content := &ExtractedContent{Blocks: []Block{
+		{Kind: BlockParagraph, Text: "Recipes."},
+		{Kind: BlockTableRow, Rows: [][]string{{"Style", "Dose"}}},
+		{Kind: BlockTableRow, Rows: [][]string{{"Ristretto", "18 g"}}},
+		{Kind: BlockTableRow, Rows: [][]string{{"Lungo", "18 g"}}},
+	}}
+
+	chunks := Chunk(content, ChunkOptions{MaxTokens: 6, RespectStructure: true})
+
+	assert.Equal(t, []ContentChunk{
+		{Text: "Recipes.", StartBlock: 0, EndBlock: 1, Tokens: 2},
+		{Text: "Style | Dose\nRistretto | 18 g\nLungo | 18 g", StartBlock: 1, EndBlock: 4, Tokens: 11},
+	}, chunks)
+}
+
+func TestChunkEmpty(t *testing.T) {
+	assert.Nil(t, Chunk(nil, ChunkOptions{}))
+	assert.Nil(t, Chunk(&ExtractedContent{Blocks: []Block{{Kind: BlockImage, Src: "/a.png"}}}, ChunkOptions{}))
+}
@@ -0,0 +1,169 @@
+package store
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// BlockKind is the kind of a content Block.
+type BlockKind string
+
+const (
+	BlockHeading    BlockKind = "heading"
+	BlockParagraph  BlockKind = "paragraph"
+	BlockListItem   BlockKind = "list_item"
+	BlockLink       BlockKind = "link"
+	BlockImage      BlockKind = "image"
+	BlockTable      BlockKind = "table"
+	BlockTableRow   BlockKind = "table_row" // A row of a table, emitted by StreamExtract
+	BlockBlockquote BlockKind = "blockquote"
+	BlockCode       BlockKind = "code"
+)
+
+// Block is a piece of page content. Which fields are set depends on Kind.
+type Block struct {
+	Kind BlockKind `json:"kind"`
+
+	// Level is the level of a heading, 1 to 6.
+	Level int `json:"level,omitempty"`
+	// Text is the text of headings, paragraphs, list items, block quotes and code, and
+	// the anchor text of links. Whitespace is collapsed except in code.
+	Text string `json:"text,omitempty"`
+
+	Href string `json:"href,omitempty"`
+	Src  string `json:"src,omitempty"`
+	Alt  string `json:"alt,omitempty"`
+
+	// Rows are the cells of a table, header rows included. A table row holds one.
+	Rows [][]string `json:"rows,omitempty"`
+}
+
+// ExtractedContent is the content of a page as blocks in document order.
+type ExtractedContent struct {
+	// URL is the address of the page. Extract leaves it empty, Pipeline sets it.
+	URL    string  `json:"url,omitempty"`
+	Author string  `json:"author,omitempty"`
+	Blocks []Block `json:"blocks"`
+}
+
+// skippedElements hold navigation and other boilerplate rather than content.
+var skippedElements = map[string]bool{
+	"header": true, "footer": true, "nav": true, "aside": true, "comments": true,
+	"script": true, "style": true, "noscript": true, "template": true,
+}
+
+// Extract returns the content of s and its descendants. Links and images inside a
+// heading, paragraph or list item follow the block holding them.
+func Extract(s *goquery.Selection) *ExtractedContent {
+	content := &ExtractedContent{Blocks: []Block{}}
+	s.Each(func(i int, s *goquery.Selection) {
+		extract(s, content)
+	})
+	return content
+}
+
+func extract(s *goquery.Selection, content *ExtractedContent) {
+	nodeName := goquery.NodeName(s)
+	if skippedElements[nodeName] {
+		return
+	}
+
+	switch nodeName {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		content.addText(Block{Kind: BlockHeading, Level: int(nodeName[1] - '0')}, s)
+		content.addInline(s)
+		return
+	case "p", "span":
+		content.addText(Block{Kind: BlockParagraph}, s)
+		content.addInline(s)
+		return
+	case "li":
+		// Nested lists are extracted as items of their own
+		own := s.Clone()
+		own.Find("ul, ol").Remove()
+		content.addText(Block{Kind: BlockListItem}, own)
+		s.Contents().Each(func(i int, child *goquery.Selection) {
+			switch goquery.NodeName(child) {
+			case "ul", "ol":
+				extract(child, content)
+			default:
+				content.addInline(child)
+			}
+		})
+		return
+	case "blockquote":
+		content.addText(Block{Kind: BlockBlockquote}, s)
+		return
+	case "pre":
+		if code := strings.TrimRight(s.Text(), "\n"); strings.TrimSpace(code) != "" {
+			content.Blocks = append(content.Blocks, Block{Kind: BlockCode, Text: code})
+		}
+		return
+	case "table":
+		content.addTable(s)
+		return
+	case "a", "img":
+		content.addInline(s)
+		return
+	case "meta":
+		if name, _ := s.Attr("name"); strings.EqualFold(name, "author") && content.Author == "" {
+			content.Author = strings.TrimSpace(s.AttrOr("content", ""))
+		}
+		return
+	}
+
+	s.Children().Each(func(i int, child *goquery.Selection) {
+		extract(child, content)
+	})
+}
+
+func (content *ExtractedContent) addText(block Block, s *goquery.Selection) {
+	block.Text = cleanText(s.Text())
+	if block.Text != "" {
+		content.Blocks = append(content.Blocks, block)
+	}
+}
+
+// addInline adds the links and images in s, s included.
+func (content *ExtractedContent) addInline(s *goquery.Selection) {
+	s.Find("a, img").AddBack().Filter("a, img").Each(func(i int, s *goquery.Selection) {
+		switch goquery.NodeName(s) {
+		case "a":
+			href := strings.TrimSpace(s.AttrOr("href", ""))
+			if href != "" {
+				content.Blocks = append(content.Blocks, Block{Kind: BlockLink, Href: href, Text: cleanText(s.Text())})
+			}
+		case "img":
+			src, exists := s.Attr("src")
+			if !exists {
+				// I want to handle lazy loading images too
+				src, _ = s.Attr("data-src")
+			}
+			if src = strings.TrimSpace(src); src != "" {
+				content.Blocks = append(content.Blocks, Block{Kind: BlockImage, Src: src, Alt: strings.TrimSpace(s.AttrOr("alt", ""))})
+			}
+		}
+	})
+}
+
+func (content *ExtractedContent) addTable(s *goquery.Selection) {
+	var rows [][]string
+	// Rows of nested tables belong to the cells holding them
+	s.Find("tr").FilterFunction(func(i int, tr *goquery.Selection) bool {
+		return tr.Closest("table").IsSelection(s)
+	}).Each(func(i int, tr *goquery.Selection) {
+		var cells []string
+		tr.ChildrenFiltered("th, td").Each(func(j int, cell *goquery.Selection) {
+			cells = append(cells, cleanText(cell.Text()))
+		})
+		rows = append(rows, cells)
+	})
+	if len(rows) > 0 {
+		content.Blocks = append(content.Blocks, Block{Kind: BlockTable, Rows: rows})
+	}
+}
+
+func cleanText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
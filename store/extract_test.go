@@ -0,0 +1,59 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func loadFixture(t *testing.T, name string) *goquery.Document {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestExtract(t *testing.T) {
+	doc := loadFixture(t, "article.html")
+
+	content := Extract(doc.Selection)
+
+	assert.Equal(t, "Jane Gopher", content.Author)
+	assert.Equal(t, []Block{
+		{Kind: BlockHeading, Level: 1, Text: "Understanding Go Channels"},
+		{Kind: BlockParagraph, Text: "Channels are the pipes that connect concurrent goroutines."},
+		{Kind: BlockLink, Href: "https://go.dev/doc/effective_go#goroutines", Text: "concurrent goroutines"},
+		{Kind: BlockImage, Src: "/img/pipes.png", Alt: "Pipes"},
+		{Kind: BlockHeading, Level: 2, Text: "Buffered channels"},
+		{Kind: BlockListItem, Text: "Unbuffered channels block"},
+		{Kind: BlockListItem, Text: "until a receiver is ready"},
+		{Kind: BlockListItem, Text: "Buffered channels block when full"},
+		{Kind: BlockBlockquote, Text: "Don't communicate by sharing memory; share memory by communicating."},
+		{Kind: BlockCode, Text: "ch := make(chan int, 3)\nch <- 1"},
+		{Kind: BlockTable, Rows: [][]string{{"Kind", "Blocks"}, {"unbuffered", "always"}, {"buffered", "when full"}}},
+		{Kind: BlockImage, Src: "/img/lazy.png", Alt: "Lazy"},
+	}, content.Blocks)
+}
+
+func TestExtractEmpty(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<nav><p>Menu</p></nav>`))
+	assert.NoError(t, err)
+
+	content := Extract(doc.Selection)
+
+	assert.Empty(t, content.Author)
+	assert.NotNil(t, content.Blocks)
+	assert.Empty(t, content.Blocks)
+}
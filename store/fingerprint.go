@@ -0,0 +1,164 @@
+package store
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"sync"
+)
+
+const (
+	// shingleSize is how many consecutive words make up a shingle of Fingerprint.
+	shingleSize = 3
+
+	// minFingerprintWords is how many words a block other than a heading needs to count
+	// for Fingerprint.
+	minFingerprintWords = 8
+)
+
+// Fingerprint returns the SimHash of the text of content: its headings, paragraphs, list
+// items, block quotes and tables. Links, images and code are left out, and so are blocks
+// of fewer than 8 words other than headings, being the bylines, share buttons and calls
+// to subscribe that differ between the copies of an article on different sites. Copies of
+// the same text get fingerprints that differ in a few bits, as measured by Similarity.
+func Fingerprint(content *ExtractedContent) uint64 {
+	if content == nil {
+		return 0
+	}
+	var text strings.Builder
+	for _, block := range content.Blocks {
+		switch block.Kind {
+		case BlockParagraph, BlockListItem, BlockBlockquote:
+			if words, runs := splitWords(block.Text); len(words)+len(runs) < minFingerprintWords {
+				continue
+			}
+			fallthrough
+		case BlockHeading:
+			text.WriteString(block.Text)
+			text.WriteByte('\n')
+		case BlockTable:
+			for _, row := range block.Rows {
+				text.WriteString(strings.Join(row, " "))
+				text.WriteByte('\n')
+			}
+		}
+	}
+	return FingerprintText(text.String())
+}
+
+// FingerprintText is like Fingerprint for plain text. The text is lowercased and split
+// into words without punctuation, every Chinese and Japanese character being a word, and
+// every run of shingleSize words is hashed.
+func FingerprintText(text string) uint64 {
+	words, runs := splitWords(strings.ToLower(text))
+	for _, run := range runs {
+		for _, r := range run {
+			words = append(words, string(r))
+		}
+	}
+	if len(words) == 0 {
+		return 0
+	}
+
+	// A text shorter than a shingle is a shingle of its own
+	shingles := len(words) - shingleSize + 1
+	if shingles < 1 {
+		shingles = 1
+	}
+	var weights [64]int
+	h := fnv.New64a()
+	for i := 0; i < shingles; i++ {
+		end := i + shingleSize
+		if end > len(words) {
+			end = len(words)
+		}
+		h.Reset()
+		h.Write([]byte(strings.Join(words[i:end], " ")))
+		sum := h.Sum64()
+		for bit := range weights {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var fp uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fp |= 1 << bit
+		}
+	}
+	return fp
+}
+
+// Similarity returns the share of bits two fingerprints have in common, from 0 to 1.
+// Copies of an article with different headers and footers are usually above 0.85,
+// unrelated texts around 0.5.
+func Similarity(a, b uint64) float64 {
+	return 1 - float64(bits.OnesCount64(a^b))/64
+}
+
+// indexBands is how many bands of 5 or 6 bits a DuplicateIndex looks fingerprints up by.
+// Two fingerprints differing in fewer bits than there are bands have a band in common.
+const indexBands = 12
+
+// DuplicateIndex finds the fingerprints added before that are similar to a new one. They
+// are bucketed by each of their 12 bands of bits, so that only those sharing a band are
+// compared. Fingerprints differing in at most 11 bits, a Similarity of 0.83 and above, are
+// always found; less similar ones may be missed.
+//
+// The zero value is an empty index ready to use. A DuplicateIndex is safe for concurrent
+// use.
+type DuplicateIndex struct {
+	mu      sync.Mutex
+	ids     []string
+	fps     []uint64
+	buckets [indexBands]map[uint64][]int
+}
+
+// AddIfNew adds fp as the fingerprint of id unless a fingerprint with a Similarity of at
+// least threshold was added before, in which case the id of the most similar one is
+// returned and fp is not added.
+func (idx *DuplicateIndex) AddIfNew(fp uint64, id string, threshold float64) (dupOfID string, isNew bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	best, bestSimilarity := -1, -1.0
+	for band := range idx.buckets {
+		for _, i := range idx.buckets[band][bandOf(fp, band)] {
+			if s := Similarity(fp, idx.fps[i]); s >= threshold && (s > bestSimilarity || (s == bestSimilarity && i < best)) {
+				best, bestSimilarity = i, s
+			}
+		}
+	}
+	if best >= 0 {
+		return idx.ids[best], false
+	}
+
+	i := len(idx.fps)
+	idx.ids = append(idx.ids, id)
+	idx.fps = append(idx.fps, fp)
+	for band := range idx.buckets {
+		if idx.buckets[band] == nil {
+			idx.buckets[band] = map[uint64][]int{}
+		}
+		key := bandOf(fp, band)
+		idx.buckets[band][key] = append(idx.buckets[band][key], i)
+	}
+	return "", true
+}
+
+// Len returns the number of fingerprints in the index.
+func (idx *DuplicateIndex) Len() int {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return len(idx.fps)
+}
+
+// bandOf returns the bits of fp in the given band.
+func bandOf(fp uint64, band int) uint64 {
+	start, end := band*64/indexBands, (band+1)*64/indexBands
+	return fp >> start & (1<<(end-start) - 1)
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var wireStory = []string{
+	"City Council Approves New Budget for Public Transit",
+	"The city council voted seven to two on Tuesday night to approve a budget that increases funding for public transit by twelve percent over the next fiscal year.",
+	"The plan adds three new bus routes connecting the eastern neighborhoods to the downtown business district, and extends service hours on the light rail line until two in the morning on weekends.",
+	"Supporters said the investment was overdue. \"Residents have waited years for reliable service, and this budget finally delivers it,\" said council member Ana Ruiz, who sponsored the proposal.",
+	"Opponents argued that the increase would require cuts to road maintenance and parks. Council member David Park warned that the city could face a shortfall if sales tax revenue falls short of projections.",
+	"The transit agency expects to hire about forty new drivers and mechanics before the new routes open in the spring. Fares will remain unchanged for at least another year.",
+	"The mayor is expected to sign the budget later this week.",
+}
+
+var otherStory = []string{
+	"Local Bakery Wins National Bread Competition",
+	"A family-owned bakery on Elm Street took first place in the national sourdough championship on Saturday, beating more than two hundred entries from across the country.",
+	"The owners, who opened the shop a decade ago, credit a starter culture that has been kept alive since their grandmother brought it from Italy in the nineteen fifties.",
+	"Judges praised the loaf for its open crumb and deep, caramelized crust. The bakery plans to use the prize money to buy a second oven and hire two more bakers.",
+	"Lines outside the shop stretched around the block on Sunday morning as customers came to taste the winning bread.",
+}
+
+// article returns the content of a page with paragraphs between a site's header and footer.
+func article(header string, paragraphs []string, footer string) *ExtractedContent {
+	content := &ExtractedContent{Blocks: []Block{
+		{Kind: BlockParagraph, Text: header},
+		{Kind: BlockLink, Text: "Share on Facebook", Href: "https://facebook.com/share"},
+		{Kind: BlockHeading, Level: 1, Text: paragraphs[0]},
+	}}
+	for _, p := range paragraphs[1:] {
+		content.Blocks = append(content.Blocks, Block{Kind: BlockParagraph, Text: p})
+	}
+	content.Blocks = append(content.Blocks,
+		Block{Kind: BlockImage, Src: "https://example.com/ad.png", Alt: "Advertisement"},
+		Block{Kind: BlockParagraph, Text: footer})
+	return content
+}
+
+func TestFingerprintDuplicates(t *testing.T) {
+	copyA := article("From the Morning Herald, by staff reporters.", wireStory,
+		"Copyright Morning Herald. Subscribe for unlimited access.")
+	copyB := article("THE DAILY GAZETTE — Associated wire report", wireStory,
+		"Read more local news at the Gazette. Follow us on social media!")
+	different := article("From the Morning Herald, by staff reporters.", otherStory,
+		"Copyright Morning Herald. Subscribe for unlimited access.")
+
+	a, b, c := Fingerprint(copyA), Fingerprint(copyB), Fingerprint(different)
+
+	assert.GreaterOrEqual(t, Similarity(a, b), 0.85)
+	assert.Less(t, Similarity(a, c), 0.7)
+	assert.Less(t, Similarity(b, c), 0.7)
+}
+
+func TestFingerprintText(t *testing.T) {
+	text := strings.Join(wireStory, " ")
+
+	assert.Equal(t, FingerprintText(text), FingerprintText(strings.ToUpper(text)))
+	assert.Equal(t, FingerprintText(text), FingerprintText(strings.NewReplacer(",", "", ".", " ", "\"", "").Replace(text)))
+	assert.Equal(t, uint64(0), FingerprintText(" ... "))
+	assert.NotEqual(t, uint64(0), FingerprintText("short"))
+	assert.Equal(t, uint64(0), Fingerprint(nil))
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, Similarity(42, 42))
+	assert.Equal(t, 0.0, Similarity(0, ^uint64(0)))
+	assert.Equal(t, 1-4.0/64, Similarity(0, 0xF000))
+}
+
+func TestDuplicateIndex(t *testing.T) {
+	var idx DuplicateIndex
+	story := Fingerprint(article("Herald", wireStory, "Subscribe"))
+
+	dup, isNew := idx.AddIfNew(story, "herald", 0.9)
+	assert.True(t, isNew)
+	assert.Empty(t, dup)
+
+	dup, isNew = idx.AddIfNew(Fingerprint(article("Gazette", wireStory, "Follow us")), "gazette", 0.85)
+	assert.False(t, isNew)
+	assert.Equal(t, "herald", dup)
+
+	_, isNew = idx.AddIfNew(Fingerprint(article("Herald", otherStory, "Subscribe")), "bakery", 0.85)
+	assert.True(t, isNew)
+
+	// Flipping 10 bits in different bands still finds the original
+	dup, isNew = idx.AddIfNew(story^0x0041041041041041, "flipped", 0.8)
+	assert.False(t, isNew)
+	assert.Equal(t, "herald", dup)
+
+	assert.Equal(t, 2, idx.Len())
+}
+
+func TestDuplicateIndexConcurrent(t *testing.T) {
+	var idx DuplicateIndex
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			idx.AddIfNew(FingerprintText(fmt.Sprintf("document number %d about topic %d", i, i*7)), fmt.Sprint(i), 1)
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, idx.Len(), 50)
+}
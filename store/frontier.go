@@ -0,0 +1,212 @@
+package store
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/propro-productions/go-utils/search"
+)
+
+// Frontier is the set of URLs a crawler has yet to fetch. Every URL is handed out by Next
+// at most once, however many times it is added. URLs are compared in the form returned by
+// search.NormalizeURL: lowercase host, without fragment or tracking parameters.
+//
+// URLs come out of Next by decreasing priority, and in the order they were added among URLs
+// of the same priority. A Frontier is safe for concurrent use.
+type Frontier struct {
+	mu      sync.Mutex
+	seen    map[string]bool
+	queue   frontierQueue
+	seq     int
+	pending map[string]int
+
+	log *os.File
+}
+
+type frontierItem struct {
+	url      string
+	host     string
+	priority int
+	seq      int
+}
+
+// frontierQueue is a heap of items by decreasing priority, then increasing seq.
+type frontierQueue []frontierItem
+
+func (q frontierQueue) Len() int { return len(q) }
+func (q frontierQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+func (q frontierQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *frontierQueue) Push(x any)   { *q = append(*q, x.(frontierItem)) }
+func (q *frontierQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// NewFrontier returns an empty in-memory Frontier.
+func NewFrontier() *Frontier {
+	return &Frontier{seen: map[string]bool{}, pending: map[string]int{}}
+}
+
+// OpenFrontier returns a Frontier persisted to the file at path, created if needed. The
+// file is an append-only log of the URLs added and handed out, so a Frontier reopened after
+// a restart neither hands out URLs again nor forgets the pending ones.
+func OpenFrontier(path string) (*Frontier, error) {
+	f := NewFrontier()
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("store: opening frontier: %w", err)
+	}
+	if err := f.replay(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("store: reading frontier %s: %w", path, err)
+	}
+	f.log = file
+	return f, nil
+}
+
+// replay reads the log, which has a line per event: "+ <priority> <url>" when a URL is
+// added and "- <url>" when it is handed out by Next.
+func (f *Frontier) replay(file *os.File) error {
+	var added []frontierItem
+	done := map[string]bool{}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		op, rest, _ := strings.Cut(scanner.Text(), " ")
+		switch op {
+		case "+":
+			p, u, ok := strings.Cut(rest, " ")
+			priority, err := strconv.Atoi(p)
+			if !ok || err != nil {
+				return fmt.Errorf("line %d: invalid entry %q", line, scanner.Text())
+			}
+			added = append(added, frontierItem{url: u, priority: priority})
+		case "-":
+			done[rest] = true
+		case "":
+			// A line cut short by a crash
+		default:
+			return fmt.Errorf("line %d: invalid entry %q", line, scanner.Text())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for _, item := range added {
+		if f.seen[item.url] {
+			continue
+		}
+		f.seen[item.url] = true
+		if !done[item.url] {
+			f.push(item.url, item.priority)
+		}
+	}
+	return nil
+}
+
+// Add adds rawURL with priority 0. It returns false if the URL was added before or is not
+// an absolute URL.
+func (f *Frontier) Add(rawURL string) bool {
+	return f.AddPriority(rawURL, 0)
+}
+
+// AddPriority adds rawURL to be handed out before the URLs of lower priority. It returns
+// false if the URL was added before or is not an absolute URL.
+func (f *Frontier) AddPriority(rawURL string, priority int) bool {
+	normalized, err := search.NormalizeURL(rawURL)
+	if err != nil {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.seen[normalized] {
+		return false
+	}
+	if err := f.writeLog("+ %d %s\n", priority, normalized); err != nil {
+		return false
+	}
+	f.seen[normalized] = true
+	f.push(normalized, priority)
+	return true
+}
+
+// Next removes and returns the next URL to fetch. It returns false when the Frontier is
+// empty.
+func (f *Frontier) Next() (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.queue.Len() == 0 {
+		return "", false
+	}
+	item := heap.Pop(&f.queue).(frontierItem)
+	if f.pending[item.host]--; f.pending[item.host] == 0 {
+		delete(f.pending, item.host)
+	}
+	// The URL is handed out even if it can't be logged: it would only be fetched again
+	// after a restart
+	_ = f.writeLog("- %s\n", item.url)
+	return item.url, true
+}
+
+// Len returns the number of URLs waiting to be handed out.
+func (f *Frontier) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.queue.Len()
+}
+
+// Pending returns the number of URLs of host waiting to be handed out, so that callers can
+// limit the requests they make to a host.
+func (f *Frontier) Pending(host string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pending[strings.ToLower(host)]
+}
+
+// Close closes the log of a persisted Frontier.
+func (f *Frontier) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.log == nil {
+		return nil
+	}
+	err := f.log.Close()
+	f.log = nil
+	return err
+}
+
+// push must be called with f.mu held.
+func (f *Frontier) push(normalized string, priority int) {
+	var host string
+	if u, err := url.Parse(normalized); err == nil {
+		host = u.Hostname()
+	}
+	f.seq++
+	heap.Push(&f.queue, frontierItem{url: normalized, host: host, priority: priority, seq: f.seq})
+	f.pending[host]++
+}
+
+// writeLog must be called with f.mu held.
+func (f *Frontier) writeLog(format string, args ...any) error {
+	if f.log == nil {
+		return nil
+	}
+	_, err := fmt.Fprintf(f.log, format, args...)
+	return err
+}
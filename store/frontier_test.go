@@ -0,0 +1,137 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func drain(f *Frontier) []string {
+	var urls []string
+	for u, ok := f.Next(); ok; u, ok = f.Next() {
+		urls = append(urls, u)
+	}
+	return urls
+}
+
+func TestFrontierAdd(t *testing.T) {
+	f := NewFrontier()
+
+	assert.True(t, f.Add("https://Example.com/a"))
+	assert.False(t, f.Add("https://example.com/a#section"))
+	assert.False(t, f.Add("https://EXAMPLE.com:443/a?utm_source=feed"))
+	assert.True(t, f.Add("https://example.com/b?id=1"))
+	assert.False(t, f.Add("/relative"))
+	assert.False(t, f.Add("://bad"))
+	assert.Equal(t, 2, f.Len())
+
+	// Handed out URLs are still seen
+	assert.Equal(t, []string{"https://example.com/a", "https://example.com/b?id=1"}, drain(f))
+	assert.False(t, f.Add("https://example.com/a"))
+	assert.Equal(t, 0, f.Len())
+}
+
+func TestFrontierOrder(t *testing.T) {
+	f := NewFrontier()
+	f.Add("https://example.com/1")
+	f.AddPriority("https://example.com/urgent", 10)
+	f.Add("https://example.com/2")
+	f.AddPriority("https://example.com/later", -1)
+	f.AddPriority("https://example.com/urgent-too", 10)
+	f.Add("https://example.com/3")
+
+	assert.Equal(t, []string{
+		"https://example.com/urgent",
+		"https://example.com/urgent-too",
+		"https://example.com/1",
+		"https://example.com/2",
+		"https://example.com/3",
+		"https://example.com/later",
+	}, drain(f))
+}
+
+func TestFrontierPending(t *testing.T) {
+	f := NewFrontier()
+	f.Add("https://a.example.com/1")
+	f.Add("https://a.example.com/2")
+	f.Add("https://b.example.com:8080/1")
+
+	assert.Equal(t, 2, f.Pending("a.example.com"))
+	assert.Equal(t, 2, f.Pending("A.example.com"))
+	assert.Equal(t, 1, f.Pending("b.example.com"))
+	assert.Equal(t, 0, f.Pending("c.example.com"))
+
+	f.Next()
+	assert.Equal(t, 1, f.Pending("a.example.com"))
+}
+
+func TestFrontierConcurrent(t *testing.T) {
+	f := NewFrontier()
+
+	var wg sync.WaitGroup
+	for p := 0; p < 4; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Every producer adds the same URLs
+			for i := 0; i < 100; i++ {
+				f.Add(fmt.Sprintf("https://example.com/%d", i))
+			}
+		}()
+	}
+	wg.Wait()
+
+	var mu sync.Mutex
+	got := map[string]int{}
+	for c := 0; c < 4; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u, ok := f.Next(); ok; u, ok = f.Next() {
+				mu.Lock()
+				got[u]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, got, 100)
+	for u, n := range got {
+		assert.Equal(t, 1, n, u)
+	}
+}
+
+func TestFrontierPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frontier.log")
+
+	f, err := OpenFrontier(path)
+	assert.NoError(t, err)
+	f.Add("https://example.com/1")
+	f.AddPriority("https://example.com/2", 5)
+	f.Add("https://example.com/3")
+	u, _ := f.Next()
+	assert.Equal(t, "https://example.com/2", u)
+	assert.NoError(t, f.Close())
+
+	f, err = OpenFrontier(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	assert.Equal(t, 2, f.Len())
+	assert.False(t, f.Add("https://example.com/2"))
+	assert.False(t, f.Add("https://example.com/3"))
+	assert.True(t, f.Add("https://example.com/4"))
+	assert.Equal(t, []string{"https://example.com/1", "https://example.com/3", "https://example.com/4"}, drain(f))
+}
+
+func TestOpenFrontierInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frontier.log")
+	assert.NoError(t, os.WriteFile(path, []byte("+ 0 https://example.com/\n? nonsense\n"), 0o644))
+
+	_, err := OpenFrontier(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,16 @@
+package store
+
+import "github.com/propro-productions/go-utils/internal/langdetect"
+
+// DetectLanguage returns the ISO 639-1 code of the language text is written in, or "" if it
+// is too short to tell or in none of the languages known: Arabic, Chinese, Dutch, English,
+// French, German, Hindi, Indonesian, Italian, Japanese, Korean, Persian, Polish,
+// Portuguese, Russian, Spanish, Swedish, Turkish, Ukrainian and Vietnamese.
+//
+// The script of the text settles the language where only one of them is written in it.
+// Otherwise the frequencies of the letter trigrams of the text are compared with those of
+// a sample of every language written in that script, and the closest one wins.
+func DetectLanguage(text string) string {
+	lang, _ := langdetect.Detect(text)
+	return lang
+}
@@ -0,0 +1,26 @@
+package store
+
+// stopWords are the words of every language that say nothing about the topic of a text and
+// are left out of its keywords. Chinese and Japanese keywords are pairs of characters.
+var stopWords = map[string][]string{
+	"ar": {"في", "من", "على", "إلى", "عن", "مع", "هذا", "هذه", "ذلك", "التي", "الذي", "الذين", "أن", "إن", "كان", "كانت", "لم", "لا", "ما", "هو", "هي", "هم", "نحن", "كل", "قد", "لقد", "ثم", "أو", "بين", "بعد", "قبل", "عند", "عندما", "كيف", "أيضا", "يجب", "هناك", "ولكن", "لكن"},
+	"de": {"der", "die", "das", "den", "dem", "des", "ein", "eine", "einen", "einem", "einer", "eines", "und", "oder", "aber", "als", "auch", "auf", "aus", "bei", "bis", "durch", "für", "mit", "nach", "von", "vor", "zum", "zur", "über", "unter", "ist", "sind", "war", "waren", "wird", "werden", "hat", "haben", "hatte", "nicht", "noch", "nur", "sich", "sie", "wir", "ihr", "ich", "man", "wie", "was", "wer", "wenn", "weil", "dass", "daß", "diese", "dieser", "dieses", "sehr", "schon", "kann", "können", "muss", "müssen", "mehr", "viele", "immer"},
+	"en": {"a", "about", "after", "all", "also", "an", "and", "any", "are", "as", "at", "be", "because", "been", "before", "being", "but", "by", "can", "could", "did", "do", "does", "for", "from", "had", "has", "have", "he", "her", "here", "him", "his", "how", "if", "in", "into", "is", "it", "its", "just", "like", "many", "more", "most", "much", "my", "no", "not", "now", "of", "on", "one", "only", "or", "other", "our", "out", "over", "she", "should", "so", "some", "than", "that", "the", "their", "them", "then", "there", "these", "they", "this", "those", "through", "to", "too", "up", "us", "very", "was", "we", "were", "what", "when", "where", "which", "while", "who", "why", "will", "with", "would", "you", "your"},
+	"es": {"el", "la", "los", "las", "un", "una", "unos", "unas", "y", "o", "pero", "de", "del", "al", "a", "en", "con", "por", "para", "sin", "sobre", "entre", "que", "quien", "como", "cuando", "donde", "es", "son", "era", "fue", "ser", "está", "están", "ha", "han", "hay", "no", "sí", "se", "su", "sus", "lo", "le", "les", "este", "esta", "estos", "estas", "ese", "esa", "muy", "más", "también", "ya", "todo", "todos", "porque", "nosotros"},
+	"fa": {"و", "در", "به", "از", "که", "این", "آن", "را", "با", "برای", "است", "بود", "شد", "می", "های", "هم", "یک", "تا", "بر", "اما", "یا", "هر", "ما", "آنها", "خود", "نیز", "باید", "چه", "کند", "کنند", "شود", "دارد"},
+	"fr": {"le", "la", "les", "un", "une", "des", "du", "de", "et", "ou", "mais", "donc", "car", "à", "au", "aux", "en", "dans", "par", "pour", "sur", "avec", "sans", "sous", "que", "qui", "quoi", "dont", "où", "est", "sont", "était", "être", "avoir", "ont", "a", "ne", "pas", "plus", "se", "ce", "cette", "ces", "son", "sa", "ses", "leur", "leurs", "il", "elle", "ils", "elles", "nous", "vous", "on", "aussi", "très", "tout", "tous", "comme", "parce"},
+	"hi": {"का", "के", "की", "है", "हैं", "में", "से", "को", "और", "पर", "यह", "वह", "भी", "था", "थे", "थी", "कि", "जो", "एक", "लिए", "तो", "ही", "या", "इस", "उस", "कर", "करने", "किया", "होता", "होती", "हो", "नहीं", "अपने", "साथ"},
+	"id": {"yang", "dan", "di", "ke", "dari", "ini", "itu", "dengan", "untuk", "pada", "adalah", "dalam", "tidak", "akan", "juga", "atau", "oleh", "karena", "ada", "sudah", "telah", "kita", "kami", "mereka", "dia", "saya", "anda", "bisa", "dapat", "lebih", "secara", "tetapi", "sangat", "seperti", "bahwa", "banyak"},
+	"it": {"il", "lo", "la", "i", "gli", "le", "un", "uno", "una", "e", "o", "ma", "di", "del", "della", "dei", "delle", "a", "al", "alla", "da", "dal", "in", "nel", "nella", "con", "su", "per", "tra", "fra", "che", "chi", "come", "quando", "dove", "è", "sono", "era", "essere", "ha", "hanno", "non", "si", "ci", "questo", "questa", "questi", "quello", "quella", "loro", "anche", "molto", "più", "tutto", "perché"},
+	"ja": {"して", "です", "ます", "した", "こと", "ない", "いる", "ある", "この", "その", "ので", "から", "まで", "など", "れる", "られ", "よう", "もの", "ため", "でき"},
+	"ko": {"그리고", "하지만", "그러나", "그", "이", "저", "것", "수", "등", "및", "더", "또", "있다", "없다", "하는", "있는", "위해", "대한", "우리", "그들"},
+	"nl": {"de", "het", "een", "en", "of", "maar", "van", "in", "op", "aan", "met", "voor", "door", "bij", "uit", "naar", "over", "om", "te", "tot", "dat", "die", "dit", "deze", "wat", "wie", "waar", "als", "is", "zijn", "was", "waren", "wordt", "worden", "heeft", "hebben", "niet", "ook", "nog", "al", "er", "we", "wij", "ze", "zij", "hij", "ik", "je", "u", "ons", "hun", "heel", "veel", "meer", "omdat"},
+	"pl": {"i", "w", "z", "na", "do", "od", "po", "o", "za", "przez", "dla", "przy", "pod", "nad", "że", "to", "jest", "są", "był", "była", "było", "się", "nie", "tak", "jak", "ale", "lub", "oraz", "który", "która", "które", "których", "ten", "ta", "te", "tym", "też", "także", "już", "bardzo", "jego", "jej", "ich", "co", "kto", "czy", "aby"},
+	"pt": {"o", "a", "os", "as", "um", "uma", "uns", "umas", "e", "ou", "mas", "de", "do", "da", "dos", "das", "em", "no", "na", "nos", "nas", "por", "para", "com", "sem", "sobre", "entre", "que", "quem", "como", "quando", "onde", "é", "são", "era", "foi", "ser", "está", "estão", "tem", "têm", "não", "se", "seu", "sua", "seus", "suas", "este", "esta", "isso", "isto", "muito", "mais", "também", "já", "todo", "todos", "porque"},
+	"ru": {"и", "в", "во", "не", "что", "он", "на", "я", "с", "со", "как", "а", "то", "все", "она", "так", "его", "но", "да", "ты", "к", "у", "же", "вы", "за", "бы", "по", "только", "её", "ее", "мы", "из", "от", "для", "о", "об", "это", "этот", "эти", "который", "которые", "также", "очень", "когда", "есть", "были", "был", "была", "их", "они", "потому"},
+	"sv": {"och", "i", "att", "det", "som", "en", "ett", "på", "är", "av", "för", "med", "till", "den", "de", "har", "inte", "om", "men", "var", "vi", "så", "sig", "från", "kan", "när", "eller", "hade", "nu", "man", "också", "mycket", "alla", "där", "här", "vad", "vem", "dem", "deras", "sin", "sina", "eftersom"},
+	"tr": {"ve", "bir", "bu", "da", "de", "için", "ile", "ama", "fakat", "gibi", "daha", "çok", "olan", "olarak", "her", "ne", "o", "şu", "mi", "mı", "mu", "mü", "kadar", "sonra", "önce", "değil", "var", "yok", "ise", "ya", "veya", "en", "biz", "siz", "onlar", "ben", "sen", "çünkü"},
+	"uk": {"і", "й", "в", "у", "на", "з", "із", "до", "від", "за", "по", "про", "для", "не", "що", "як", "це", "той", "ця", "ці", "але", "або", "так", "також", "який", "яка", "які", "його", "її", "їх", "ми", "ви", "вони", "він", "вона", "дуже", "коли", "тому", "бо", "є", "був", "була", "були"},
+	"vi": {"và", "của", "là", "có", "được", "cho", "trong", "với", "các", "những", "một", "này", "đó", "không", "người", "đã", "sẽ", "đang", "thì", "mà", "từ", "khi", "để", "cũng", "rất", "như", "về", "ra", "vào", "chúng", "ta", "tôi"},
+	"zh": {"我们", "他们", "你们", "一个", "这个", "那个", "没有", "就是", "不是", "可以", "因为", "所以", "但是", "如果", "已经", "自己", "什么", "这些", "那些", "还是"},
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := map[string]string{
+		"en": "Yesterday we walked along the river until the sun went down, and then we had dinner at a small restaurant near the old bridge.",
+		"de": "Gestern sind wir am Fluss entlang gelaufen, bis die Sonne unterging, und dann haben wir in einem kleinen Restaurant an der alten Brücke gegessen.",
+		"fr": "Hier, nous avons marché le long de la rivière jusqu'au coucher du soleil, puis nous avons dîné dans un petit restaurant près du vieux pont.",
+		"es": "Ayer caminamos a lo largo del río hasta que se puso el sol, y luego cenamos en un pequeño restaurante cerca del puente viejo.",
+		"pt": "Ontem caminhámos ao longo do rio até o sol se pôr, e depois jantámos num pequeno restaurante perto da ponte velha.",
+		"it": "Ieri abbiamo camminato lungo il fiume fino al tramonto, e poi abbiamo cenato in un piccolo ristorante vicino al vecchio ponte.",
+		"nl": "Gisteren liepen we langs de rivier tot de zon onderging, en daarna hebben we gegeten in een klein restaurant bij de oude brug.",
+		"sv": "Igår promenerade vi längs floden tills solen gick ner, och sedan åt vi middag på en liten restaurang nära den gamla bron.",
+		"pl": "Wczoraj spacerowaliśmy wzdłuż rzeki, aż zaszło słońce, a potem zjedliśmy kolację w małej restauracji niedaleko starego mostu.",
+		"tr": "Dün güneş batana kadar nehir boyunca yürüdük, sonra eski köprünün yakınındaki küçük bir restoranda akşam yemeği yedik.",
+		"id": "Kemarin kami berjalan di sepanjang sungai sampai matahari terbenam, lalu kami makan malam di sebuah restoran kecil dekat jembatan tua.",
+		"vi": "Hôm qua chúng tôi đi dạo dọc bờ sông cho đến khi mặt trời lặn, rồi chúng tôi ăn tối ở một nhà hàng nhỏ gần cây cầu cũ.",
+		"ru": "Вчера мы гуляли вдоль реки, пока не зашло солнце, а потом поужинали в маленьком ресторане у старого моста.",
+		"uk": "Учора ми гуляли вздовж річки, доки не зайшло сонце, а потім повечеряли в маленькому ресторані біля старого мосту.",
+		"ar": "بالأمس مشينا على طول النهر حتى غروب الشمس، ثم تناولنا العشاء في مطعم صغير بالقرب من الجسر القديم.",
+		"fa": "دیروز در کنار رودخانه قدم زدیم تا خورشید غروب کرد، و بعد در یک رستوران کوچک نزدیک پل قدیمی شام خوردیم.",
+		"zh": "昨天我们沿着河边散步，直到太阳落山，然后在老桥附近的一家小餐馆吃了晚饭。",
+		"ja": "昨日は日が沈むまで川沿いを歩いて、それから古い橋の近くの小さなレストランで夕食を食べました。",
+		"ko": "어제 우리는 해가 질 때까지 강을 따라 걸었고, 그 다음에 오래된 다리 근처의 작은 식당에서 저녁을 먹었습니다.",
+		"hi": "कल हम सूरज ढलने तक नदी के किनारे टहलते रहे, और फिर पुराने पुल के पास एक छोटे से रेस्टोरेंट में खाना खाया।",
+	}
+
+	for want, text := range tests {
+		assert.Equal(t, want, DetectLanguage(text), text)
+	}
+}
+
+func TestDetectLanguageUnknown(t *testing.T) {
+	assert.Empty(t, DetectLanguage("Hello there"))
+	assert.Empty(t, DetectLanguage("12345 67890 !!! ---"))
+	assert.Empty(t, DetectLanguage("Καλημέρα σας, τι κάνετε σήμερα το πρωί;"))
+}
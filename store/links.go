@@ -0,0 +1,310 @@
+package store
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/publicsuffix"
+)
+
+// LinkCategory is where a Link leads.
+type LinkCategory string
+
+const (
+	LinkInternal   LinkCategory = "internal"   // A page of the registrable domain of the base URL
+	LinkExternal   LinkCategory = "external"   // A page of another domain
+	LinkFragment   LinkCategory = "fragment"   // A "#fragment" of the same page
+	LinkJavaScript LinkCategory = "javascript" // A "javascript:" URL
+	LinkMailto     LinkCategory = "mailto"     // A "mailto:" address
+	LinkOther      LinkCategory = "other"      // Other schemes such as "tel:", and hrefs that don't parse
+)
+
+// LinkRegion is the part of a page a Link is in.
+type LinkRegion string
+
+const (
+	RegionMain       LinkRegion = "main"       // The main content, as found by MainContent
+	RegionNavigation LinkRegion = "navigation" // Navigation bars, menus and breadcrumbs
+	RegionFooter     LinkRegion = "footer"     // Page footers
+	RegionSidebar    LinkRegion = "sidebar"    // Sidebars, related articles and widgets
+	RegionOther      LinkRegion = "other"      // Anywhere else, such as comments and banners
+)
+
+// Link is a link of a page, as found by ExtractLinks.
+type Link struct {
+	// URL is the href resolved against the base URL. javascript: and mailto: links and
+	// hrefs that don't parse keep their href as written.
+	URL string `json:"url"`
+	// Text is the anchor text, or the alt text of the image the link holds.
+	Text string `json:"text,omitempty"`
+
+	Rel       []string `json:"rel,omitempty"`
+	NoFollow  bool     `json:"nofollow,omitempty"`
+	UGC       bool     `json:"ugc,omitempty"`
+	Sponsored bool     `json:"sponsored,omitempty"`
+
+	Category LinkCategory `json:"category"`
+	Region   LinkRegion   `json:"region"`
+}
+
+// LinkSummary counts the links of a page, as returned by SummarizeLinks.
+type LinkSummary struct {
+	Total      int                  `json:"total"`
+	ByCategory map[LinkCategory]int `json:"by_category"`
+	ByRegion   map[LinkRegion]int   `json:"by_region"`
+	NoFollow   int                  `json:"nofollow"`
+	UGC        int                  `json:"ugc"`
+	Sponsored  int                  `json:"sponsored"`
+}
+
+// ExtractLinks returns the links of doc in document order. Hrefs are resolved against the
+// <base> of doc, itself resolved against baseURL, or doc.Url when baseURL is nil. Links are
+// internal when their host has the same registrable domain as baseURL, so blog.example.com
+// is internal to www.example.com, whatever the <base>. Without a base URL, relative links
+// are internal.
+//
+// The region of a link is found the way ExtractMainContent finds boilerplate: links inside
+// the main content are in RegionMain, and on pages without one, so are the links outside
+// navigation, footers and sidebars. doc is not modified.
+func ExtractLinks(doc *goquery.Document, baseURL *url.URL) []Link {
+	if doc == nil {
+		return nil
+	}
+	if baseURL == nil {
+		baseURL = doc.Url
+	}
+	site := baseURL
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if u, err := url.Parse(strings.TrimSpace(href)); err == nil {
+			if baseURL != nil {
+				u = baseURL.ResolveReference(u)
+			}
+			if u.IsAbs() {
+				baseURL = u
+			}
+		}
+	}
+
+	main := mainContentNodes(doc)
+	links := []Link{}
+	doc.Find("a[href], area[href]").Each(func(i int, s *goquery.Selection) {
+		href := strings.TrimSpace(s.AttrOr("href", ""))
+		link := Link{
+			Text:   linkText(s),
+			Region: linkRegion(s.Get(0), main),
+		}
+		link.URL, link.Category = resolveLink(href, baseURL, site)
+
+		for _, rel := range strings.Fields(strings.ToLower(s.AttrOr("rel", ""))) {
+			link.Rel = append(link.Rel, rel)
+			switch rel {
+			case "nofollow":
+				link.NoFollow = true
+			case "ugc":
+				link.UGC = true
+			case "sponsored":
+				link.Sponsored = true
+			}
+		}
+		links = append(links, link)
+	})
+	return links
+}
+
+// SummarizeLinks counts links by category and region, and those marked nofollow, ugc or
+// sponsored.
+func SummarizeLinks(links []Link) LinkSummary {
+	summary := LinkSummary{
+		Total:      len(links),
+		ByCategory: map[LinkCategory]int{},
+		ByRegion:   map[LinkRegion]int{},
+	}
+	for _, link := range links {
+		summary.ByCategory[link.Category]++
+		summary.ByRegion[link.Region]++
+		if link.NoFollow {
+			summary.NoFollow++
+		}
+		if link.UGC {
+			summary.UGC++
+		}
+		if link.Sponsored {
+			summary.Sponsored++
+		}
+	}
+	return summary
+}
+
+// linkText returns the text of the link s, the alt text of its images when it has none.
+func linkText(s *goquery.Selection) string {
+	if text := cleanText(s.Text()); text != "" {
+		return text
+	}
+	var alts []string
+	s.Find("img[alt]").Each(func(i int, img *goquery.Selection) {
+		if alt := cleanText(img.AttrOr("alt", "")); alt != "" {
+			alts = append(alts, alt)
+		}
+	})
+	if len(alts) == 0 {
+		return cleanText(s.AttrOr("aria-label", s.AttrOr("title", "")))
+	}
+	return strings.Join(alts, " ")
+}
+
+// resolveLink returns the URL href leads to from base and its category, internal links
+// being those of the site of the page.
+func resolveLink(href string, base, site *url.URL) (string, LinkCategory) {
+	if strings.HasPrefix(href, "#") {
+		if base == nil {
+			return href, LinkFragment
+		}
+		u := *base
+		u.Fragment = href[1:]
+		u.RawFragment = ""
+		return u.String(), LinkFragment
+	}
+
+	u, err := url.Parse(href)
+	if err != nil {
+		return href, LinkOther
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "javascript":
+		return href, LinkJavaScript
+	case "mailto":
+		return href, LinkMailto
+	case "", "http", "https":
+	default:
+		return u.String(), LinkOther
+	}
+
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if !u.IsAbs() {
+		return u.String(), LinkInternal
+	}
+	if site == nil || !sameSite(u.Hostname(), site.Hostname()) {
+		return u.String(), LinkExternal
+	}
+	return u.String(), LinkInternal
+}
+
+// sameSite reports whether hosts a and b have the same registrable domain. Hosts without
+// one, such as IP addresses and localhost, must be equal.
+func sameSite(a, b string) bool {
+	a = strings.TrimSuffix(strings.ToLower(a), ".")
+	b = strings.TrimSuffix(strings.ToLower(b), ".")
+	if a == b {
+		return true
+	}
+	domainA, errA := publicsuffix.EffectiveTLDPlusOne(a)
+	domainB, errB := publicsuffix.EffectiveTLDPlusOne(b)
+	return errA == nil && errB == nil && domainA == domainB
+}
+
+// mainContentNodes returns the nodes of doc holding its main content, as selected by
+// MainContent, or nil when it has none.
+func mainContentNodes(doc *goquery.Document) map[*html.Node]bool {
+	// MainContent works on a copy, so the copy is mapped back to the nodes of doc
+	origins := map[*html.Node]*html.Node{}
+	clone := goquery.NewDocumentFromNode(cloneNode(doc.Get(0), origins))
+	removeUnlikelyCandidates(clone)
+
+	top, scores := scoreCandidates(clone)
+	if top == nil || scores[top] < minMainContentScore {
+		return nil
+	}
+	nodes := map[*html.Node]bool{}
+	for _, n := range mainContent(clone, top, scores) {
+		nodes[origins[n]] = true
+	}
+	return nodes
+}
+
+// cloneNode returns a deep copy of n, recording the original of every copied node.
+func cloneNode(n *html.Node, origins map[*html.Node]*html.Node) *html.Node {
+	c := &html.Node{
+		Type:      n.Type,
+		DataAtom:  n.DataAtom,
+		Data:      n.Data,
+		Namespace: n.Namespace,
+		Attr:      append([]html.Attribute(nil), n.Attr...),
+	}
+	origins[c] = n
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.AppendChild(cloneNode(child, origins))
+	}
+	return c
+}
+
+// linkRegion returns the region of the link n: the main content if one of its ancestors
+// is in main, otherwise that of the closest boilerplate ancestor.
+func linkRegion(n *html.Node, main map[*html.Node]bool) LinkRegion {
+	for p := n; p != nil; p = p.Parent {
+		if main[p] {
+			return RegionMain
+		}
+		if p.Type != html.ElementNode || p == n {
+			continue
+		}
+		if region, ok := boilerplateRegion(p); ok {
+			return region
+		}
+	}
+	if main == nil {
+		return RegionMain
+	}
+	return RegionOther
+}
+
+// boilerplateRegion returns the region of the element n when removeUnlikelyCandidates
+// would remove it as boilerplate.
+func boilerplateRegion(n *html.Node) (LinkRegion, bool) {
+	role := ""
+	var hints []string
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "role":
+			role = attr.Val
+		case "class":
+			hints = append(hints, strings.Fields(attr.Val)...)
+		case "id":
+			hints = append(hints, attr.Val)
+		}
+	}
+
+	switch {
+	case n.Data == "nav" || role == "navigation":
+		return RegionNavigation, true
+	case n.Data == "footer":
+		return RegionFooter, true
+	case n.Data == "aside" || role == "complementary":
+		return RegionSidebar, true
+	case n.Data == "article" || n.Data == "main":
+		return "", false
+	}
+
+	for _, hint := range hints {
+		if !unlikelyCandidateRegex.MatchString(hint) || maybeCandidateRegex.MatchString(hint) {
+			continue
+		}
+		hint = strings.ToLower(hint)
+		switch {
+		case strings.Contains(hint, "footer"):
+			return RegionFooter, true
+		case strings.Contains(hint, "menu") || strings.Contains(hint, "breadcrumb") || strings.Contains(hint, "pager") ||
+			strings.Contains(hint, "pagination") || strings.Contains(hint, "header"):
+			return RegionNavigation, true
+		case strings.Contains(hint, "sidebar") || strings.Contains(hint, "related") || strings.Contains(hint, "widget") ||
+			strings.Contains(hint, "promo") || strings.Contains(hint, "sponsor"):
+			return RegionSidebar, true
+		default:
+			return RegionOther, true
+		}
+	}
+	return "", false
+}
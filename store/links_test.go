@@ -0,0 +1,103 @@
+package store
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+const linksHTML = `<html><body>
+<header class="site-header"><a href="/"><img src="/logo.png" alt="Example News"></a></header>
+<nav><a href="/news">News</a> <a href="https://shop.example.com/">Shop</a></nav>
+<article>
+<h1>Council approves bike lanes</h1>
+<p>The council voted on Tuesday night to approve a network of protected bike lanes, ending a debate that has run for years, according to <a href="https://other.org/report" rel="nofollow noopener">the report</a>.</p>
+<p>Construction on the first phase, which covers the downtown core, will begin in the spring and is expected to take eighteen months, the <a href="/transport">transport department</a> said.</p>
+<p>Supporters, including cycling groups, parents, and business owners, said the lanes would make streets safer. Read the <a href="#route">route map</a>, or <a href="mailto:desk@example.com">write to us</a>.</p>
+</article>
+<aside><a href="https://ads.example.net/" rel="sponsored">Sponsored</a></aside>
+<div class="comments"><a href="https://spam.example.org/" rel="ugc nofollow">cheap deals</a></div>
+<footer><a href="javascript:void(0)">Back to top</a> <a href="tel:+15550100">Call</a></footer>
+</body></html>`
+
+func TestExtractLinks(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(linksHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://www.example.com/news/bike-lanes")
+
+	links := ExtractLinks(doc, base)
+
+	want := []Link{
+		{URL: "https://www.example.com/", Text: "Example News", Category: LinkInternal, Region: RegionNavigation},
+		{URL: "https://www.example.com/news", Text: "News", Category: LinkInternal, Region: RegionNavigation},
+		{URL: "https://shop.example.com/", Text: "Shop", Category: LinkInternal, Region: RegionNavigation},
+		{URL: "https://other.org/report", Text: "the report", Rel: []string{"nofollow", "noopener"}, NoFollow: true, Category: LinkExternal, Region: RegionMain},
+		{URL: "https://www.example.com/transport", Text: "transport department", Category: LinkInternal, Region: RegionMain},
+		{URL: "https://www.example.com/news/bike-lanes#route", Text: "route map", Category: LinkFragment, Region: RegionMain},
+		{URL: "mailto:desk@example.com", Text: "write to us", Category: LinkMailto, Region: RegionMain},
+		{URL: "https://ads.example.net/", Text: "Sponsored", Rel: []string{"sponsored"}, Sponsored: true, Category: LinkExternal, Region: RegionSidebar},
+		{URL: "https://spam.example.org/", Text: "cheap deals", Rel: []string{"ugc", "nofollow"}, UGC: true, NoFollow: true, Category: LinkExternal, Region: RegionOther},
+		{URL: "javascript:void(0)", Text: "Back to top", Category: LinkJavaScript, Region: RegionFooter},
+		{URL: "tel:+15550100", Text: "Call", Category: LinkOther, Region: RegionFooter},
+	}
+	assert.Equal(t, want, links)
+	// doc is not modified
+	assert.Equal(t, 1, doc.Find("nav").Length())
+
+	summary := SummarizeLinks(links)
+	assert.Equal(t, 11, summary.Total)
+	assert.Equal(t, 4, summary.ByCategory[LinkInternal])
+	assert.Equal(t, 3, summary.ByCategory[LinkExternal])
+	assert.Equal(t, 4, summary.ByRegion[RegionMain])
+	assert.Equal(t, 2, summary.NoFollow)
+	assert.Equal(t, 1, summary.UGC)
+	assert.Equal(t, 1, summary.Sponsored)
+}
+
+func TestExtractLinksBase(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(
+		`<html><head><base href="https://cdn.example.org/docs/"></head><body><p><a href="guide">Guide</a> <a href="//example.org/">Home</a> <a href="https://www.example.com/">Site</a></p></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://example.com/")
+
+	links := ExtractLinks(doc, base)
+
+	if assert.Len(t, links, 3) {
+		assert.Equal(t, "https://cdn.example.org/docs/guide", links[0].URL)
+		assert.Equal(t, LinkExternal, links[0].Category)
+		assert.Equal(t, "https://example.org/", links[1].URL)
+		assert.Equal(t, LinkExternal, links[1].Category)
+		assert.Equal(t, LinkInternal, links[2].Category)
+		// Without main content, links outside boilerplate are in the main region
+		assert.Equal(t, RegionMain, links[0].Region)
+	}
+}
+
+func TestExtractLinksWithoutBase(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<a href="/about">About</a><a href="http://127.0.0.1/">Local</a><a href="#top">Top</a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links := ExtractLinks(doc, nil)
+
+	if assert.Len(t, links, 3) {
+		assert.Equal(t, Link{URL: "/about", Text: "About", Category: LinkInternal, Region: RegionMain}, links[0])
+		assert.Equal(t, LinkExternal, links[1].Category)
+		assert.Equal(t, Link{URL: "#top", Text: "Top", Category: LinkFragment, Region: RegionMain}, links[2])
+	}
+}
+
+func TestSameSite(t *testing.T) {
+	assert.True(t, sameSite("blog.example.co.uk", "www.example.co.uk"))
+	assert.True(t, sameSite("localhost", "LOCALHOST."))
+	assert.False(t, sameSite("example.co.uk", "other.co.uk"))
+	assert.False(t, sameSite("127.0.0.1", "127.0.0.2"))
+}
@@ -0,0 +1,277 @@
+package store
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Metadata is the information a page gives about itself.
+type Metadata struct {
+	Author string `json:"author,omitempty"`
+
+	// Published and Modified are zero when the page has no such date or when it is
+	// written in a layout ExtractMetadata doesn't know. PublishedRaw and ModifiedRaw
+	// hold the dates as written on the page.
+	Published    time.Time `json:"published"`
+	PublishedRaw string    `json:"published_raw,omitempty"`
+	Modified     time.Time `json:"modified"`
+	ModifiedRaw  string    `json:"modified_raw,omitempty"`
+
+	Canonical string   `json:"canonical,omitempty"`
+	Lang      string   `json:"lang,omitempty"`
+	Keywords  []string `json:"keywords,omitempty"`
+
+	// JSONLD are the objects of the JSON-LD scripts of the page, with @graph lists
+	// flattened.
+	JSONLD []map[string]any `json:"json_ld,omitempty"`
+	// Article is the first Article, or subtype of it, found in JSONLD.
+	Article *Article `json:"article,omitempty"`
+}
+
+// Article holds the fields of a schema.org Article, such as a NewsArticle or a
+// BlogPosting.
+type Article struct {
+	Type          string `json:"type"`
+	Headline      string `json:"headline,omitempty"`
+	Description   string `json:"description,omitempty"`
+	Author        string `json:"author,omitempty"`
+	Publisher     string `json:"publisher,omitempty"`
+	Image         string `json:"image,omitempty"`
+	URL           string `json:"url,omitempty"`
+	DatePublished string `json:"date_published,omitempty"`
+	DateModified  string `json:"date_modified,omitempty"`
+}
+
+// articleTypes are the schema.org types surfaced as an Article
+var articleTypes = map[string]bool{
+	"Article": true, "NewsArticle": true, "BlogPosting": true, "ReportageNewsArticle": true,
+	"AnalysisNewsArticle": true, "OpinionNewsArticle": true, "TechArticle": true, "ScholarlyArticle": true,
+}
+
+// dateLayouts are tried in order to parse the dates of a page
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02",
+	"20060102",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.RFC822Z,
+	time.RFC822,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+}
+
+var (
+	publishedSelectors = []string{
+		`meta[property="article:published_time"]`,
+		`meta[name="article:published_time"]`,
+		`meta[itemprop="datePublished"]`,
+		`meta[name="pubdate"]`,
+		`meta[name="publishdate"]`,
+		`meta[name="publish-date"]`,
+		`meta[name="parsely-pub-date"]`,
+		`meta[name="sailthru.date"]`,
+		`meta[name="dc.date.issued"]`,
+		`meta[name="DC.date.issued"]`,
+		`meta[name="dcterms.created"]`,
+		`meta[name="date"]`,
+		`time[itemprop="datePublished"]`,
+		`time[pubdate]`,
+		`time[datetime]`,
+	}
+	modifiedSelectors = []string{
+		`meta[property="article:modified_time"]`,
+		`meta[name="article:modified_time"]`,
+		`meta[property="og:updated_time"]`,
+		`meta[itemprop="dateModified"]`,
+		`meta[name="dcterms.modified"]`,
+		`meta[name="last-modified"]`,
+		`time[itemprop="dateModified"]`,
+	}
+)
+
+// ExtractMetadata returns the metadata of doc from its meta tags, links and JSON-LD
+// scripts. Malformed JSON-LD scripts are skipped.
+func ExtractMetadata(doc *goquery.Document) Metadata {
+	var meta Metadata
+
+	meta.JSONLD = jsonLD(doc)
+	for _, object := range meta.JSONLD {
+		if articleTypes[jsonLDType(object)] {
+			meta.Article = newArticle(object)
+			break
+		}
+	}
+
+	meta.Author = firstValue(doc, `meta[name="author"]`, `meta[property="article:author"]`, `meta[name="article:author"]`)
+	if meta.Author == "" {
+		meta.Author = cleanText(doc.Find(`a[rel~="author"], [itemprop="author"] [itemprop="name"]`).First().Text())
+	}
+
+	meta.PublishedRaw = firstValue(doc, publishedSelectors...)
+	meta.ModifiedRaw = firstValue(doc, modifiedSelectors...)
+	if article := meta.Article; article != nil {
+		if meta.Author == "" {
+			meta.Author = article.Author
+		}
+		if meta.PublishedRaw == "" {
+			meta.PublishedRaw = article.DatePublished
+		}
+		if meta.ModifiedRaw == "" {
+			meta.ModifiedRaw = article.DateModified
+		}
+	}
+	meta.Published = parseDate(meta.PublishedRaw)
+	meta.Modified = parseDate(meta.ModifiedRaw)
+
+	meta.Canonical = strings.TrimSpace(doc.Find(`link[rel="canonical"]`).AttrOr("href", ""))
+	if meta.Canonical == "" {
+		meta.Canonical = firstValue(doc, `meta[property="og:url"]`)
+	}
+
+	meta.Lang = strings.TrimSpace(doc.Find("html").AttrOr("lang", ""))
+	if meta.Lang == "" {
+		meta.Lang = firstValue(doc, `meta[http-equiv="content-language"]`, `meta[http-equiv="Content-Language"]`)
+	}
+
+	seen := map[string]bool{}
+	doc.Find(`meta[name="keywords"], meta[name="news_keywords"], meta[property="article:tag"]`).Each(func(i int, s *goquery.Selection) {
+		for _, keyword := range strings.Split(s.AttrOr("content", ""), ",") {
+			keyword = strings.TrimSpace(keyword)
+			if keyword != "" && !seen[strings.ToLower(keyword)] {
+				seen[strings.ToLower(keyword)] = true
+				meta.Keywords = append(meta.Keywords, keyword)
+			}
+		}
+	})
+
+	return meta
+}
+
+// firstValue returns the first non empty value of the elements matching selectors, tried
+// in order. The value of a meta tag is its content and the value of a time its datetime.
+func firstValue(doc *goquery.Document, selectors ...string) string {
+	for _, selector := range selectors {
+		var value string
+		doc.Find(selector).EachWithBreak(func(i int, s *goquery.Selection) bool {
+			switch goquery.NodeName(s) {
+			case "meta":
+				value = s.AttrOr("content", "")
+			case "time":
+				value = s.AttrOr("datetime", "")
+				if value == "" {
+					value = s.Text()
+				}
+			}
+			value = strings.TrimSpace(value)
+			return value == ""
+		})
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func parseDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func jsonLD(doc *goquery.Document) []map[string]any {
+	var objects []map[string]any
+	doc.Find(`script[type="application/ld+json"]`).Each(func(i int, s *goquery.Selection) {
+		var data any
+		if err := json.Unmarshal([]byte(strings.TrimSpace(s.Text())), &data); err != nil {
+			return
+		}
+		objects = appendJSONLD(objects, data)
+	})
+	return objects
+}
+
+// appendJSONLD appends the objects of data, which can be an object, a list of objects or
+// an object with a @graph list.
+func appendJSONLD(objects []map[string]any, data any) []map[string]any {
+	switch data := data.(type) {
+	case []any:
+		for _, item := range data {
+			objects = appendJSONLD(objects, item)
+		}
+	case map[string]any:
+		if graph, ok := data["@graph"]; ok {
+			return appendJSONLD(objects, graph)
+		}
+		objects = append(objects, data)
+	}
+	return objects
+}
+
+// jsonLDType returns the first schema.org type of object.
+func jsonLDType(object map[string]any) string {
+	switch t := object["@type"].(type) {
+	case string:
+		return t
+	case []any:
+		for _, t := range t {
+			if t, ok := t.(string); ok {
+				return t
+			}
+		}
+	}
+	return ""
+}
+
+func newArticle(object map[string]any) *Article {
+	return &Article{
+		Type:          jsonLDType(object),
+		Headline:      jsonLDString(object["headline"], "name"),
+		Description:   jsonLDString(object["description"], "name"),
+		Author:        jsonLDString(object["author"], "name"),
+		Publisher:     jsonLDString(object["publisher"], "name"),
+		Image:         jsonLDString(object["image"], "url"),
+		URL:           jsonLDString(object["url"], "@id"),
+		DatePublished: jsonLDString(object["datePublished"], "@value"),
+		DateModified:  jsonLDString(object["dateModified"], "@value"),
+	}
+}
+
+// jsonLDString returns value when it is a string, its key field when it is an object and
+// the first of these when it is a list.
+func jsonLDString(value any, key string) string {
+	switch value := value.(type) {
+	case string:
+		return strings.TrimSpace(value)
+	case map[string]any:
+		return jsonLDString(value[key], key)
+	case []any:
+		for _, item := range value {
+			if s := jsonLDString(item, key); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
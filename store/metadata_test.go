@@ -0,0 +1,82 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractMetadata(t *testing.T) {
+	doc := loadFixture(t, "metadata.html")
+
+	meta := ExtractMetadata(doc)
+
+	assert.Equal(t, "Priya Shah", meta.Author)
+	assert.Equal(t, time.Date(2024, 3, 8, 9, 30, 0, 0, time.UTC), meta.Published.UTC())
+	assert.Equal(t, "2024-03-08T09:30:00+00:00", meta.PublishedRaw)
+	assert.Equal(t, time.Date(2024, 3, 8, 14, 5, 0, 0, time.UTC), meta.Modified)
+	assert.Equal(t, "https://news.example.com/weather/rain-weekend", meta.Canonical)
+	assert.Equal(t, "en-GB", meta.Lang)
+	assert.Equal(t, []string{"weather", "rain", "London", "Forecast"}, meta.Keywords)
+
+	// The malformed script is skipped and the @graph is flattened
+	assert.Len(t, meta.JSONLD, 2)
+	assert.Equal(t, &Article{
+		Type:          "NewsArticle",
+		Headline:      "Rain expected all weekend",
+		Description:   "Forecasters warn of heavy showers across the south.",
+		Author:        "Priya Shah",
+		Publisher:     "Example News",
+		Image:         "https://news.example.com/img/rain.jpg",
+		DatePublished: "2024-03-08T09:30:00Z",
+		DateModified:  "2024-03-08T14:05:00Z",
+	}, meta.Article)
+}
+
+func TestExtractMetadataFallbacks(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><head>
+<meta property="og:url" content="https://example.com/post">
+<meta http-equiv="content-language" content="fr">
+<script type="application/ld+json">[{"@type": "BlogPosting", "author": "Anne Martin"}]</script>
+</head><body><time datetime="Friday afternoon">Friday</time></body></html>`))
+	assert.NoError(t, err)
+
+	meta := ExtractMetadata(doc)
+
+	assert.Equal(t, "Anne Martin", meta.Author)
+	assert.True(t, meta.Published.IsZero())
+	assert.Equal(t, "Friday afternoon", meta.PublishedRaw)
+	assert.True(t, meta.Modified.IsZero())
+	assert.Empty(t, meta.ModifiedRaw)
+	assert.Equal(t, "https://example.com/post", meta.Canonical)
+	assert.Equal(t, "fr", meta.Lang)
+	assert.Equal(t, "BlogPosting", meta.Article.Type)
+}
+
+func TestParseDate(t *testing.T) {
+	want := time.Date(2023, 11, 5, 0, 0, 0, 0, time.UTC)
+	tests := []string{
+		"2023-11-05",
+		"2023-11-05T00:00:00Z",
+		"2023-11-05T00:00:00",
+		"2023-11-05T00:00:00+0000",
+		"2023-11-05 00:00:00",
+		"2023/11/05",
+		"20231105",
+		"Sun, 05 Nov 2023 00:00:00 GMT",
+		"November 5, 2023",
+		"Nov 5, 2023",
+		"5 November 2023",
+	}
+
+	for _, value := range tests {
+		t.Run(value, func(t *testing.T) {
+			assert.True(t, want.Equal(parseDate(value)), "got %v", parseDate(value))
+		})
+	}
+
+	assert.True(t, parseDate("yesterday").IsZero())
+}
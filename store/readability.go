@@ -0,0 +1,208 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// ErrNoContent is returned by ExtractMainContent for pages without any content.
+var ErrNoContent = errors.New("store: no content found")
+
+// minMainContentScore is the score a container needs to be taken as the main content of
+// a page. Below it the whole body is extracted.
+const minMainContentScore = 20
+
+var (
+	// unlikelyCandidateRegex matches the class or id of boilerplate such as cookie
+	// banners, sidebars, related articles and comments
+	unlikelyCandidateRegex = regexp.MustCompile(`(?i)ad-break|agegate|banner|breadcrumb|combx|comment|community|consent|cookie|disqus|extra|footer|gdpr|header|legends|menu|modal|newsletter|pager|pagination|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|supplemental|widget`)
+	// maybeCandidateRegex keeps class names such as "content-header" that start like
+	// content even though they look unlikely
+	maybeCandidateRegex = regexp.MustCompile(`(?i)^(?:article|body|column|content|main|shadow)`)
+
+	positiveHintRegex = regexp.MustCompile(`(?i)article|blog|body|content|entry|hentry|h-entry|main|page|post|story|text`)
+	negativeHintRegex = regexp.MustCompile(`(?i)banner|combx|comment|com-|contact|cookie|foot|masthead|media|meta|nav|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+// ExtractMainContent returns the content of the main article of doc, leaving out
+// navigation, sidebars, cookie banners, related articles and comment sections.
+//
+// Containers are scored by the paragraphs they hold: their number, length and commas,
+// the class and id of the container and how much of its text is links. The best
+// container and the siblings that score close to it are extracted. When no container
+// scores high enough, such as on pages that are not articles, the whole body is extracted
+// instead. doc is not modified.
+func ExtractMainContent(doc *goquery.Document) (*ExtractedContent, error) {
+	if doc == nil {
+		return nil, ErrNoContent
+	}
+
+	var content *ExtractedContent
+	if main := MainContent(doc); main != nil {
+		content = Extract(main)
+	}
+	if content == nil || len(content.Blocks) == 0 {
+		content = Extract(doc.Find("body"))
+	}
+	content.Author = Extract(doc.Find("meta[name]")).Author
+
+	if len(content.Blocks) == 0 {
+		return content, ErrNoContent
+	}
+	return content, nil
+}
+
+// MainContent returns the container of the main article of doc with the siblings that
+// belong to it, scored like in ExtractMainContent. It returns nil when no container scores
+// high enough. The selection is taken from a copy of doc without boilerplate, doc is not
+// modified.
+func MainContent(doc *goquery.Document) *goquery.Selection {
+	clone := goquery.CloneDocument(doc)
+	removeUnlikelyCandidates(clone)
+
+	top, scores := scoreCandidates(clone)
+	if top == nil || scores[top] < minMainContentScore {
+		return nil
+	}
+	return clone.FindNodes(mainContent(clone, top, scores)...)
+}
+
+func removeUnlikelyCandidates(doc *goquery.Document) {
+	doc.Find("script, style, noscript, template, nav, aside, footer").Remove()
+	doc.Find(`[role="navigation"], [role="complementary"], [role="dialog"], [role="alertdialog"]`).Remove()
+
+	doc.Find("body *").Each(func(i int, s *goquery.Selection) {
+		switch goquery.NodeName(s) {
+		case "article", "main", "a":
+			return
+		}
+		hints := append(strings.Fields(s.AttrOr("class", "")), s.AttrOr("id", ""))
+		for _, hint := range hints {
+			if unlikelyCandidateRegex.MatchString(hint) && !maybeCandidateRegex.MatchString(hint) {
+				s.Remove()
+				return
+			}
+		}
+	})
+}
+
+// scoreCandidates scores the parents and grandparents of the paragraphs of doc and returns
+// the best of them. Candidates with equal scores are won by the first in the document.
+func scoreCandidates(doc *goquery.Document) (*html.Node, map[*html.Node]float64) {
+	scores := map[*html.Node]float64{}
+	var candidates []*html.Node
+	addScore := func(node *html.Node, score float64) {
+		if node == nil || node.Type != html.ElementNode || node.Data == "html" {
+			return
+		}
+		if _, ok := scores[node]; !ok {
+			scores[node] = initialScore(node)
+			candidates = append(candidates, node)
+		}
+		scores[node] += score
+	}
+
+	doc.Find("p, pre, td").Each(func(i int, s *goquery.Selection) {
+		text := cleanText(s.Text())
+		if len(text) < 25 {
+			return
+		}
+		// One point for the paragraph, one per comma and one per 100 characters up to 3
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text)/100), 3)
+		parent := s.Get(0).Parent
+		addScore(parent, score)
+		if parent != nil {
+			addScore(parent.Parent, score/2)
+		}
+	})
+
+	var top *html.Node
+	for _, node := range candidates {
+		scores[node] *= 1 - linkDensity(doc.FindNodes(node))
+		if top == nil || scores[node] > scores[top] {
+			top = node
+		}
+	}
+	return top, scores
+}
+
+func initialScore(node *html.Node) float64 {
+	var score float64
+	switch node.Data {
+	case "article", "div", "main", "section":
+		score += 5
+	case "blockquote", "pre", "td":
+		score += 3
+	case "address", "dd", "dl", "dt", "form", "li", "ol", "ul":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+
+	var hints []string
+	for _, attr := range node.Attr {
+		if attr.Key == "class" || attr.Key == "id" {
+			hints = append(hints, attr.Val)
+		}
+	}
+	switch hint := strings.Join(hints, " "); {
+	case negativeHintRegex.MatchString(hint):
+		score -= 25
+	case positiveHintRegex.MatchString(hint):
+		score += 25
+	}
+	return score
+}
+
+// linkDensity returns the share of the text of s that is inside links.
+func linkDensity(s *goquery.Selection) float64 {
+	textLength := len(cleanText(s.Text()))
+	if textLength == 0 {
+		return 0
+	}
+	var linkLength int
+	s.Find("a").Each(func(i int, a *goquery.Selection) {
+		linkLength += len(cleanText(a.Text()))
+	})
+	return float64(linkLength) / float64(textLength)
+}
+
+// mainContent returns top along with the siblings that belong to the same article: other
+// well scoring candidates, long paragraphs that are mostly text and headings such as the
+// title of the article.
+func mainContent(doc *goquery.Document, top *html.Node, scores map[*html.Node]float64) []*html.Node {
+	if top.Parent == nil {
+		return []*html.Node{top}
+	}
+
+	threshold := math.Max(10, scores[top]*0.2)
+	var nodes []*html.Node
+	for n := top.Parent.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		if n == top {
+			nodes = append(nodes, n)
+			continue
+		}
+		if score, ok := scores[n]; ok && score >= threshold {
+			nodes = append(nodes, n)
+			continue
+		}
+		switch n.Data {
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			nodes = append(nodes, n)
+		case "p":
+			s := doc.FindNodes(n)
+			if len(cleanText(s.Text())) > 80 && linkDensity(s) < 0.25 {
+				nodes = append(nodes, n)
+			}
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractMainContent(t *testing.T) {
+	tests := []struct {
+		fixture string
+		author  string
+		want    []Block
+	}{
+		{
+			fixture: "news.html",
+			author:  "Sam Rivera",
+			want: []Block{
+				{Kind: BlockHeading, Level: 1, Text: "City council approves new bike lanes"},
+				{Kind: BlockParagraph, Text: "By Sam Rivera"},
+				{Kind: BlockLink, Href: "/authors/sam-rivera", Text: "Sam Rivera"},
+				{Kind: BlockParagraph, Text: "The city council voted seven to two on Tuesday night to approve a network of protected bike lanes, ending a debate that has run for more than three years, divided neighbourhoods, and filled public meetings."},
+				{Kind: BlockParagraph, Text: "Construction on the first phase, which covers the downtown core, will begin in the spring and is expected to take eighteen months, according to the transport department."},
+				{Kind: BlockImage, Src: "/images/bike-lanes.jpg", Alt: "A cyclist on Main Street"},
+				{Kind: BlockParagraph, Text: "Supporters, including local cycling groups, parents, and several business owners, said the lanes would make streets safer. Opponents argued that removing parking would hurt shops along the route."},
+				{Kind: BlockHeading, Level: 2, Text: "What happens next"},
+				{Kind: BlockParagraph, Text: "The council will publish a detailed route map next month, and residents will have six weeks to comment on it before the design is finalised."},
+			},
+		},
+		{
+			fixture: "blog.html",
+			want: []Block{
+				{Kind: BlockHeading, Level: 1, Text: "Sourdough starter in five days"},
+				{Kind: BlockParagraph, Text: "A sourdough starter is nothing more than flour and water, left to ferment until wild yeast and bacteria take over, but getting one going can feel like a mystery."},
+				{Kind: BlockParagraph, Text: "All you need is a clean jar, a kitchen scale, whole wheat flour, and about five minutes a day. Keep the jar somewhere warm, ideally around 24 degrees."},
+				{Kind: BlockListItem, Text: "Mix 50 g of flour with 50 g of water."},
+				{Kind: BlockListItem, Text: "Discard half and feed it again every day."},
+				{Kind: BlockParagraph, Text: "By the fifth day, the starter should double within a few hours of feeding, smell pleasantly sour, and be full of bubbles. That is when it is ready to bake with."},
+			},
+		},
+		{
+			// Not an article: the whole body is extracted
+			fixture: "landing.html",
+			want: []Block{
+				{Kind: BlockHeading, Level: 1, Text: "Deploy Go in seconds"},
+				{Kind: BlockParagraph, Text: "No servers to manage."},
+				{Kind: BlockLink, Href: "/signup", Text: "Start for free"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			doc := loadFixture(t, tt.fixture)
+
+			content, err := ExtractMainContent(doc)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.author, content.Author)
+			assert.Equal(t, tt.want, content.Blocks)
+		})
+	}
+}
+
+func TestExtractMainContentKeepsDocument(t *testing.T) {
+	doc := loadFixture(t, "news.html")
+	before, err := doc.Html()
+	assert.NoError(t, err)
+
+	_, err = ExtractMainContent(doc)
+	assert.NoError(t, err)
+
+	after, err := doc.Html()
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+}
+
+func TestExtractMainContentEmpty(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<nav><p>Menu</p></nav>`))
+	assert.NoError(t, err)
+
+	content, err := ExtractMainContent(doc)
+
+	assert.ErrorIs(t, err, ErrNoContent)
+	assert.Empty(t, content.Blocks)
+
+	_, err = ExtractMainContent(nil)
+	assert.ErrorIs(t, err, ErrNoContent)
+}
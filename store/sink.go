@@ -0,0 +1,118 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Sink stores extracted content. Sinks are safe for concurrent use.
+type Sink interface {
+	Write(ctx context.Context, content *ExtractedContent) error
+	Close() error
+}
+
+// JSONSink writes each document as an indented JSON value to a file. The file can be
+// read back with a json.Decoder.
+type JSONSink struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+// NewJSONSink creates, or truncates, the file at path.
+func NewJSONSink(path string) (*JSONSink, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("store: creating JSON sink: %w", err)
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return &JSONSink{file: file, encoder: encoder}, nil
+}
+
+func (s *JSONSink) Write(ctx context.Context, content *ExtractedContent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.encoder.Encode(content)
+}
+
+func (s *JSONSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// JSONLSink appends each document as a line of JSON to a file.
+type JSONLSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewJSONLSink opens the file at path for appending, creating it if needed.
+func NewJSONLSink(path string) (*JSONLSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("store: opening JSONL sink: %w", err)
+	}
+	return &JSONLSink{file: file}, nil
+}
+
+func (s *JSONLSink) Write(ctx context.Context, content *ExtractedContent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// Marshal before locking, the line is written with a single call so that lines of
+	// concurrent writers don't interleave
+	line, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(line)
+	return err
+}
+
+func (s *JSONLSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// Pipeline extracts the content of documents into a Sink.
+type Pipeline struct {
+	Sink Sink
+	// Extract extracts the content of a document. ExtractMainContent is used when nil.
+	Extract func(doc *goquery.Document) (*ExtractedContent, error)
+}
+
+// NewPipeline returns a Pipeline writing the main content of documents to sink.
+func NewPipeline(sink Sink) *Pipeline {
+	return &Pipeline{Sink: sink}
+}
+
+// Process extracts the content of doc and writes it to the sink, with the URL of doc.
+func (p *Pipeline) Process(ctx context.Context, doc *goquery.Document) error {
+	extract := p.Extract
+	if extract == nil {
+		extract = ExtractMainContent
+	}
+	content, err := extract(doc)
+	if err != nil {
+		return err
+	}
+	if doc.Url != nil {
+		content.URL = doc.Url.String()
+	}
+	return p.Sink.Write(ctx, content)
+}
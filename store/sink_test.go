@@ -0,0 +1,147 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var sinkContent = []*ExtractedContent{
+	{
+		URL:    "https://example.com/a",
+		Author: "Jane Gopher",
+		Blocks: []Block{
+			{Kind: BlockHeading, Level: 1, Text: "Title"},
+			{Kind: BlockParagraph, Text: "Some text."},
+			{Kind: BlockLink, Href: "/b", Text: "next"},
+			{Kind: BlockImage, Src: "/img.png", Alt: "An image"},
+			{Kind: BlockTable, Rows: [][]string{{"a", "b"}, {"1", "2"}}},
+		},
+	},
+	{
+		URL:    "https://example.com/b",
+		Blocks: []Block{{Kind: BlockCode, Text: "x := 1\n  y := 2"}},
+	},
+}
+
+func TestJSONSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "content.json")
+	sink, err := NewJSONSink(path)
+	assert.NoError(t, err)
+	for _, content := range sinkContent {
+		assert.NoError(t, sink.Write(context.Background(), content))
+	}
+	assert.NoError(t, sink.Close())
+
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	var got []*ExtractedContent
+	for decoder := json.NewDecoder(f); decoder.More(); {
+		var content ExtractedContent
+		assert.NoError(t, decoder.Decode(&content))
+		got = append(got, &content)
+	}
+	assert.Equal(t, sinkContent, got)
+}
+
+func TestJSONLSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "content.jsonl")
+	sink, err := NewJSONLSink(path)
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			content := &ExtractedContent{URL: fmt.Sprintf("https://example.com/%d", i), Blocks: sinkContent[0].Blocks}
+			assert.NoError(t, sink.Write(context.Background(), content))
+		}(i)
+	}
+	wg.Wait()
+	assert.NoError(t, sink.Close())
+
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	urls := map[string]bool{}
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		var content ExtractedContent
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &content))
+		assert.Equal(t, sinkContent[0].Blocks, content.Blocks)
+		urls[content.URL] = true
+	}
+	assert.Len(t, urls, 50)
+}
+
+func TestSinkCanceled(t *testing.T) {
+	sink, err := NewJSONLSink(filepath.Join(t.TempDir(), "content.jsonl"))
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, sink.Write(ctx, sinkContent[0]), context.Canceled)
+}
+
+func openSQLiteSink(t *testing.T) *SQLiteSink {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "content.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := NewSQLiteSink(db)
+	t.Cleanup(func() { sink.Close() })
+	return sink
+}
+
+func TestSQLiteSink(t *testing.T) {
+	ctx := context.Background()
+	sink := openSQLiteSink(t)
+
+	for _, content := range sinkContent {
+		assert.NoError(t, sink.Write(ctx, content))
+	}
+	for _, want := range sinkContent {
+		got, err := sink.Read(ctx, want.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	// Writing a URL again replaces its document
+	replacement := &ExtractedContent{URL: "https://example.com/a", Blocks: []Block{{Kind: BlockParagraph, Text: "Updated."}}}
+	assert.NoError(t, sink.Write(ctx, replacement))
+	got, err := sink.Read(ctx, replacement.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, replacement, got)
+
+	_, err = sink.Read(ctx, "https://example.com/missing")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestPipeline(t *testing.T) {
+	ctx := context.Background()
+	sink := openSQLiteSink(t)
+	doc := loadFixture(t, "news.html")
+	doc.Url, _ = url.Parse("https://news.example.com/bike-lanes")
+
+	assert.NoError(t, NewPipeline(sink).Process(ctx, doc))
+
+	want, err := ExtractMainContent(doc)
+	assert.NoError(t, err)
+	want.URL = "https://news.example.com/bike-lanes"
+	got, err := sink.Read(ctx, want.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS documents (
+	id     INTEGER PRIMARY KEY AUTOINCREMENT,
+	url    TEXT UNIQUE,
+	author TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS blocks (
+	document_id INTEGER NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+	position    INTEGER NOT NULL,
+	kind        TEXT NOT NULL,
+	level       INTEGER NOT NULL DEFAULT 0,
+	text        TEXT NOT NULL DEFAULT '',
+	href        TEXT NOT NULL DEFAULT '',
+	src         TEXT NOT NULL DEFAULT '',
+	alt         TEXT NOT NULL DEFAULT '',
+	rows        TEXT,
+	PRIMARY KEY (document_id, position)
+);`
+
+// SQLiteSink stores documents in a SQLite database: a row of the documents table per
+// document and its blocks, in order, in the blocks table. Writing a document with the URL
+// of a stored one replaces it. The rows of table blocks are stored as JSON.
+//
+// The schema is created on the first write.
+type SQLiteSink struct {
+	db *sql.DB
+
+	mu     sync.Mutex
+	schema bool
+}
+
+// NewSQLiteSink returns a sink storing documents in db, which must be a SQLite database
+// opened with a driver such as github.com/mattn/go-sqlite3.
+func NewSQLiteSink(db *sql.DB) *SQLiteSink {
+	return &SQLiteSink{db: db}
+}
+
+func (s *SQLiteSink) Write(ctx context.Context, content *ExtractedContent) error {
+	// SQLite has a single writer, concurrent transactions would fail with SQLITE_BUSY
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.createSchema(ctx); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Pages without a URL are stored with a NULL one, which doesn't conflict
+	var url any
+	if content.URL != "" {
+		url = content.URL
+		_, err = tx.ExecContext(ctx, `DELETE FROM blocks WHERE document_id IN (SELECT id FROM documents WHERE url = ?)`, url)
+		if err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, `DELETE FROM documents WHERE url = ?`, url); err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `INSERT INTO documents (url, author) VALUES (?, ?)`, url, content.Author)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO blocks (document_id, position, kind, level, text, href, src, alt, rows) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for i, block := range content.Blocks {
+		var rows any
+		if block.Rows != nil {
+			data, err := json.Marshal(block.Rows)
+			if err != nil {
+				return err
+			}
+			rows = string(data)
+		}
+		_, err = stmt.ExecContext(ctx, id, i, string(block.Kind), block.Level, block.Text, block.Href, block.Src, block.Alt, rows)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Read returns the document stored with url.
+func (s *SQLiteSink) Read(ctx context.Context, url string) (*ExtractedContent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.createSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	content := &ExtractedContent{URL: url, Blocks: []Block{}}
+	var id int64
+	err := s.db.QueryRowContext(ctx, `SELECT id, author FROM documents WHERE url = ?`, url).Scan(&id, &content.Author)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT kind, level, text, href, src, alt, rows FROM blocks WHERE document_id = ? ORDER BY position`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var block Block
+		var tableRows sql.NullString
+		if err := rows.Scan(&block.Kind, &block.Level, &block.Text, &block.Href, &block.Src, &block.Alt, &tableRows); err != nil {
+			return nil, err
+		}
+		if tableRows.Valid {
+			if err := json.Unmarshal([]byte(tableRows.String), &block.Rows); err != nil {
+				return nil, fmt.Errorf("store: decoding table rows: %w", err)
+			}
+		}
+		content.Blocks = append(content.Blocks, block)
+	}
+	return content, rows.Err()
+}
+
+// Close closes the database.
+func (s *SQLiteSink) Close() error {
+	return s.db.Close()
+}
+
+// createSchema must be called with s.mu held.
+func (s *SQLiteSink) createSchema(ctx context.Context) error {
+	if s.schema {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, sqliteSchema); err != nil {
+		return fmt.Errorf("store: creating schema: %w", err)
+	}
+	s.schema = true
+	return nil
+}
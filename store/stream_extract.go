@@ -0,0 +1,306 @@
+package store
+
+import (
+	"io"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// unitElements are the elements StreamExtract extracts as a whole, like Extract does.
+var unitElements = map[string]bool{
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"p": true, "span": true, "li": true, "blockquote": true, "pre": true, "a": true, "img": true,
+}
+
+// closesParagraph are the start tags that end an open <p>.
+var closesParagraph = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "center": true,
+	"details": true, "dialog": true, "dir": true, "div": true, "dl": true, "fieldset": true,
+	"figcaption": true, "figure": true, "footer": true, "form": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hgroup": true, "hr": true,
+	"listing": true, "main": true, "menu": true, "nav": true, "ol": true, "p": true,
+	"plaintext": true, "pre": true, "section": true, "summary": true, "table": true, "ul": true,
+	"xmp": true,
+}
+
+// voidElements have no end tag and no children.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true,
+	"wbr": true,
+}
+
+// StreamExtract reads the HTML of r with the tokenizer of golang.org/x/net/html and calls
+// emit with its content blocks as soon as they are complete, without building the tree of
+// the whole document. It extracts what Extract does from the whole document, skipping
+// headers, footers, navigation, scripts and styles, except that the rows of a table are
+// emitted one by one as BlockTableRow blocks, and the author is not looked for.
+//
+// Only the element being extracted is kept in memory, such as a paragraph or a table row,
+// so memory does not grow with the document. An error returned by emit stops the
+// extraction and is returned as is.
+func StreamExtract(r io.Reader, emit func(Block) error) error {
+	x := &streamExtractor{emit: emit, content: &ExtractedContent{}}
+	z := html.NewTokenizer(r)
+	for {
+		tokenType := z.Next()
+		if tokenType == html.ErrorToken {
+			if err := z.Err(); err != io.EOF {
+				return err
+			}
+			return x.flush()
+		}
+		if err := x.token(z, tokenType); err != nil {
+			return err
+		}
+	}
+}
+
+// streamExtractor tracks the context of the tokens StreamExtract reads.
+type streamExtractor struct {
+	emit    func(Block) error
+	content *ExtractedContent
+
+	// skip is the skipped element being read, depth how many of them are open
+	skip  string
+	depth int
+	// tables is how many tables are open outside of a unit
+	tables int
+	unit   *streamUnit
+}
+
+func (x *streamExtractor) token(z *html.Tokenizer, tokenType html.TokenType) error {
+	var name string
+	var hasAttr bool
+	if tokenType == html.StartTagToken || tokenType == html.SelfClosingTagToken || tokenType == html.EndTagToken {
+		var n []byte
+		n, hasAttr = z.TagName()
+		if a := atom.Lookup(n); a != 0 {
+			name = a.String()
+		} else {
+			name = string(n)
+		}
+	}
+
+	if u := x.unit; u != nil {
+		done, consumed := u.add(z, tokenType, name, hasAttr)
+		if !done {
+			return nil
+		}
+		if err := x.flush(); err != nil {
+			return err
+		}
+		if consumed {
+			return nil
+		}
+	}
+
+	switch tokenType {
+	case html.StartTagToken, html.SelfClosingTagToken:
+		if x.skip != "" {
+			if name == x.skip {
+				x.depth++
+			}
+			return nil
+		}
+		if skippedElements[name] {
+			x.skip, x.depth = name, 1
+			return nil
+		}
+		if x.tables > 0 {
+			switch name {
+			case "table":
+				x.tables++
+			case "tr", "td", "th":
+				x.unit = newStreamUnit("tr")
+				if name == "tr" {
+					x.unit.root.Attr = tagAttrs(z, hasAttr)
+				} else {
+					x.unit.push(z, name, hasAttr)
+				}
+			}
+			return nil
+		}
+		switch {
+		case name == "table":
+			x.tables++
+		case unitElements[name]:
+			x.unit = newStreamUnit(name)
+			x.unit.root.Attr = tagAttrs(z, hasAttr)
+			if voidElements[name] {
+				return x.flush()
+			}
+		}
+	case html.EndTagToken:
+		if x.skip != "" {
+			if name == x.skip {
+				if x.depth--; x.depth == 0 {
+					x.skip = ""
+				}
+			}
+			return nil
+		}
+		if name == "table" && x.tables > 0 {
+			x.tables--
+		}
+	}
+	return nil
+}
+
+// streamUnit is an element being read to be extracted as a whole. Its tree is built from
+// the tokens as the HTML parser would for the elements Extract looks at, closing the
+// elements whose end tags are implied.
+type streamUnit struct {
+	root *html.Node
+	// open are the elements open in the unit, root first
+	open []*html.Node
+	// pre is set right after the start tag of a <pre>, whose leading newline is dropped
+	pre bool
+}
+
+func newStreamUnit(name string) *streamUnit {
+	root := &html.Node{Type: html.ElementNode, Data: name, DataAtom: atom.Lookup([]byte(name))}
+	return &streamUnit{root: root, open: []*html.Node{root}, pre: name == "pre"}
+}
+
+// add adds a token to the unit, or reports that the unit was done before it. consumed
+// reports whether the token was the end tag of the unit.
+func (u *streamUnit) add(z *html.Tokenizer, tokenType html.TokenType, name string, hasAttr bool) (done, consumed bool) {
+	pre := u.pre
+	u.pre = false
+
+	switch tokenType {
+	case html.TextToken:
+		text := z.Text()
+		if pre && len(text) > 0 && text[0] == '\n' {
+			text = text[1:]
+		}
+		if len(text) > 0 {
+			u.open[len(u.open)-1].AppendChild(&html.Node{Type: html.TextNode, Data: string(text)})
+		}
+	case html.StartTagToken, html.SelfClosingTagToken:
+		if u.closedBy(name) {
+			return true, false
+		}
+		u.push(z, name, hasAttr)
+	case html.EndTagToken:
+		if i := u.find(name); i > 0 {
+			u.open = u.open[:i]
+			return false, false
+		}
+		// The end of the unit, or of an element the unit is in
+		return true, name == u.root.Data
+	}
+	return false, false
+}
+
+// push adds the element of the start tag being read, closing the elements it implies the
+// end of.
+func (u *streamUnit) push(z *html.Tokenizer, name string, hasAttr bool) {
+	var closes string
+	switch {
+	case closesParagraph[name]:
+		closes = "p"
+	case name == "li":
+		closes = "li"
+	case name == "td" || name == "th":
+		closes = "td"
+	case name == "a":
+		closes = "a"
+	}
+	if closes != "" {
+		for i := len(u.open) - 1; i > 0; i-- {
+			data := u.open[i].Data
+			if data == closes || closes == "td" && data == "th" {
+				u.open = u.open[:i]
+				break
+			}
+			if data == "ul" || data == "ol" || data == "table" {
+				break
+			}
+		}
+	}
+
+	n := &html.Node{Type: html.ElementNode, Data: name, DataAtom: atom.Lookup([]byte(name)), Attr: tagAttrs(z, hasAttr)}
+	u.open[len(u.open)-1].AppendChild(n)
+	if !voidElements[name] {
+		u.open = append(u.open, n)
+		u.pre = name == "pre"
+	}
+}
+
+// find returns the index in the open elements of the innermost one named name, 0 if there
+// is none besides the root.
+func (u *streamUnit) find(name string) int {
+	for i := len(u.open) - 1; i > 0; i-- {
+		if u.open[i].Data == name {
+			return i
+		}
+	}
+	return 0
+}
+
+// closedBy reports whether the start tag of name ends the unit, as it would be by the HTML
+// parser.
+func (u *streamUnit) closedBy(name string) bool {
+	switch u.root.Data {
+	case "p":
+		return closesParagraph[name]
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		return len(u.open) == 1 && len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6'
+	case "li":
+		return name == "li" && u.find("ul") == 0 && u.find("ol") == 0
+	case "a":
+		return name == "a"
+	case "tr":
+		switch name {
+		case "tr", "tbody", "thead", "tfoot", "caption", "colgroup":
+			return u.find("table") == 0
+		}
+	}
+	return false
+}
+
+// tagAttrs returns the attributes of the start tag being read.
+func tagAttrs(z *html.Tokenizer, hasAttr bool) []html.Attribute {
+	var attrs []html.Attribute
+	for hasAttr {
+		var key, val []byte
+		key, val, hasAttr = z.TagAttr()
+		attrs = append(attrs, html.Attribute{Key: string(key), Val: string(val)})
+	}
+	return attrs
+}
+
+// flush extracts the unit being read, if any, and emits its blocks.
+func (x *streamExtractor) flush() error {
+	u := x.unit
+	if u == nil {
+		return nil
+	}
+	x.unit = nil
+
+	content := x.content
+	content.Blocks = content.Blocks[:0]
+	s := goquery.NewDocumentFromNode(u.root).Selection
+	if u.root.Data == "tr" {
+		var cells []string
+		s.ChildrenFiltered("th, td").Each(func(i int, cell *goquery.Selection) {
+			cells = append(cells, cleanText(cell.Text()))
+		})
+		if len(cells) > 0 {
+			content.Blocks = append(content.Blocks, Block{Kind: BlockTableRow, Rows: [][]string{cells}})
+		}
+	} else {
+		extract(s, content)
+	}
+
+	for _, block := range content.Blocks {
+		if err := x.emit(block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
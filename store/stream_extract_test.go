@@ -0,0 +1,153 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func streamBlocks(t *testing.T, r io.Reader) []Block {
+	t.Helper()
+	var blocks []Block
+	err := StreamExtract(r, func(block Block) error {
+		blocks = append(blocks, block)
+		return nil
+	})
+	assert.NoError(t, err)
+	return blocks
+}
+
+func TestStreamExtract(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "article.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	blocks := streamBlocks(t, f)
+
+	assert.Equal(t, []Block{
+		{Kind: BlockHeading, Level: 1, Text: "Understanding Go Channels"},
+		{Kind: BlockParagraph, Text: "Channels are the pipes that connect concurrent goroutines."},
+		{Kind: BlockLink, Href: "https://go.dev/doc/effective_go#goroutines", Text: "concurrent goroutines"},
+		{Kind: BlockImage, Src: "/img/pipes.png", Alt: "Pipes"},
+		{Kind: BlockHeading, Level: 2, Text: "Buffered channels"},
+		{Kind: BlockListItem, Text: "Unbuffered channels block"},
+		{Kind: BlockListItem, Text: "until a receiver is ready"},
+		{Kind: BlockListItem, Text: "Buffered channels block when full"},
+		{Kind: BlockBlockquote, Text: "Don't communicate by sharing memory; share memory by communicating."},
+		{Kind: BlockCode, Text: "ch := make(chan int, 3)\nch <- 1"},
+		{Kind: BlockTableRow, Rows: [][]string{{"Kind", "Blocks"}}},
+		{Kind: BlockTableRow, Rows: [][]string{{"unbuffered", "always"}}},
+		{Kind: BlockTableRow, Rows: [][]string{{"buffered", "when full"}}},
+		{Kind: BlockImage, Src: "/img/lazy.png", Alt: "Lazy"},
+	}, blocks)
+}
+
+func TestStreamExtractImpliedEndTags(t *testing.T) {
+	html := `<h2>Title</h2><p>One<p>Two<div><ul><li>A<li>B</ul></div><table><tr><td>1<td>2<tr><td>3</table>`
+
+	blocks := streamBlocks(t, strings.NewReader(html))
+
+	assert.Equal(t, []Block{
+		{Kind: BlockHeading, Level: 2, Text: "Title"},
+		{Kind: BlockParagraph, Text: "One"},
+		{Kind: BlockParagraph, Text: "Two"},
+		{Kind: BlockListItem, Text: "A"},
+		{Kind: BlockListItem, Text: "B"},
+		{Kind: BlockTableRow, Rows: [][]string{{"1", "2"}}},
+		{Kind: BlockTableRow, Rows: [][]string{{"3"}}},
+	}, blocks)
+}
+
+func TestStreamExtractSkipped(t *testing.T) {
+	html := `<nav><nav><p>Menu</p></nav><p>Still menu</p></nav><header><h1>Site</h1></header>
+<p>Content</p><script>document.write("<p>x</p>")</script><footer><p>Copyright</p></footer>`
+
+	blocks := streamBlocks(t, strings.NewReader(html))
+
+	assert.Equal(t, []Block{{Kind: BlockParagraph, Text: "Content"}}, blocks)
+}
+
+// countingReader counts the bytes read from the reader it wraps.
+type countingReader struct {
+	io.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += n
+	return n, err
+}
+
+func TestStreamExtractAbort(t *testing.T) {
+	doc := largeExtractDocument(4 << 20)
+	r := &countingReader{Reader: bytes.NewReader(doc)}
+	errStop := errors.New("stop")
+
+	calls := 0
+	err := StreamExtract(r, func(block Block) error {
+		calls++
+		if calls == 3 {
+			return errStop
+		}
+		return nil
+	})
+
+	assert.Equal(t, errStop, err)
+	assert.Equal(t, 3, calls)
+	assert.Less(t, r.n, len(doc)/100)
+}
+
+// largeExtractDocument returns about size bytes of HTML made of repeated article sections.
+func largeExtractDocument(size int) []byte {
+	section := `<section><h2>Release notes</h2>
+<p>The <b>parser</b> now handles <a href="/docs/parser">nested lists</a> and <code>pre</code> blocks.</p>
+<ul><li>First change</li><li>Second change with <em>emphasis</em></li></ul>
+<table><tr><th>Name</th><th>Value</th></tr><tr><td>size</td><td>42</td></tr></table>
+<pre><code>func main() {}
+</code></pre>
+<div><img src="/img/diagram.png" alt="Diagram"><p>Caption</p></div>
+</section>
+`
+	var b bytes.Buffer
+	b.WriteString("<!DOCTYPE html><html><head><title>Large</title></head><body><nav><a href=\"/\">Home</a></nav><main>")
+	for b.Len() < size {
+		b.WriteString(section)
+	}
+	b.WriteString("</main></body></html>")
+	return b.Bytes()
+}
+
+// Compare the goquery and streaming extractions of a 10 MB document
+func BenchmarkStreamExtract(b *testing.B) {
+	doc := largeExtractDocument(10 << 20)
+	b.Run("goquery", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(doc)))
+		for i := 0; i < b.N; i++ {
+			d, err := goquery.NewDocumentFromReader(bytes.NewReader(doc))
+			if err != nil {
+				b.Fatal(err)
+			}
+			Extract(d.Selection)
+		}
+	})
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(doc)))
+		for i := 0; i < b.N; i++ {
+			if err := StreamExtract(bytes.NewReader(doc), func(Block) error { return nil }); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
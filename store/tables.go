@@ -0,0 +1,266 @@
+package store
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxSpan caps colspan and rowspan, so that a bogus value cannot blow up a table.
+const maxSpan = 1000
+
+// Table is a data table of a page. Every row has as many cells as there are columns.
+type Table struct {
+	Caption string `json:"caption,omitempty"`
+
+	// Headers name the columns. Header rows spanning several levels are joined, e.g.
+	// "Population 2020". Nil when the table has no header row.
+	Headers []string `json:"headers,omitempty"`
+
+	Rows [][]string `json:"rows"`
+}
+
+// ExtractTables returns the data tables of doc in document order. Tables used for layout
+// are skipped: those with role="presentation", those holding other tables and those with
+// a single row or column.
+//
+// Headers are taken from thead or else from the leading rows made of th cells only. Cells
+// spanning several columns are repeated in each of them, and cells spanning several rows are
+// carried down, so that every value stays in its column.
+func ExtractTables(doc *goquery.Document) []Table {
+	tables := []Table{}
+	doc.Find("table").Each(func(i int, s *goquery.Selection) {
+		if table, ok := extractTable(s); ok {
+			tables = append(tables, table)
+		}
+	})
+	return tables
+}
+
+// ToMaps returns the rows as maps from header to value, or nil for a table without headers.
+// An empty header is named after its column, "column3" for the third, and a repeated one
+// gets the column number as suffix, "Total_4".
+func (t Table) ToMaps() []map[string]string {
+	if t.Headers == nil {
+		return nil
+	}
+	keys := make([]string, len(t.Headers))
+	seen := map[string]bool{}
+	for i, header := range t.Headers {
+		key := header
+		if key == "" {
+			key = fmt.Sprintf("column%d", i+1)
+		}
+		if seen[key] {
+			key = fmt.Sprintf("%s_%d", key, i+1)
+		}
+		seen[key] = true
+		keys[i] = key
+	}
+
+	maps := make([]map[string]string, len(t.Rows))
+	for i, row := range t.Rows {
+		m := make(map[string]string, len(keys))
+		for j, key := range keys {
+			if j < len(row) {
+				m[key] = row[j]
+			}
+		}
+		maps[i] = m
+	}
+	return maps
+}
+
+// WriteCSV writes the table as CSV, starting with the headers if there are any.
+func (t Table) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if t.Headers != nil {
+		if err := writer.Write(t.Headers); err != nil {
+			return err
+		}
+	}
+	if err := writer.WriteAll(t.Rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// tableCell is a cell of a row before spans are expanded.
+type tableCell struct {
+	text    string
+	header  bool
+	colspan int
+	rowspan int
+}
+
+// tableRow is a row of a table before spans are expanded.
+type tableRow struct {
+	cells []tableCell
+	head  bool
+}
+
+func extractTable(s *goquery.Selection) (Table, bool) {
+	role := strings.ToLower(s.AttrOr("role", ""))
+	if role == "presentation" || role == "none" || s.Find("table").Length() > 0 {
+		return Table{}, false
+	}
+
+	var rows []tableRow
+	s.Find("tr").FilterFunction(func(i int, tr *goquery.Selection) bool {
+		return tr.Closest("table").IsSelection(s)
+	}).Each(func(i int, tr *goquery.Selection) {
+		row := tableRow{head: goquery.NodeName(tr.Parent()) == "thead"}
+		tr.ChildrenFiltered("th, td").Each(func(j int, cell *goquery.Selection) {
+			row.cells = append(row.cells, tableCell{
+				text:    cleanText(cell.Text()),
+				header:  goquery.NodeName(cell) == "th",
+				colspan: span(cell, "colspan"),
+				rowspan: span(cell, "rowspan"),
+			})
+		})
+		if len(row.cells) > 0 {
+			rows = append(rows, row)
+		}
+	})
+
+	grid := expandSpans(rows)
+	width := 0
+	for _, cells := range grid {
+		if len(cells) > width {
+			width = len(cells)
+		}
+	}
+	if len(grid) < 2 || width < 2 {
+		return Table{}, false
+	}
+	for i := range grid {
+		for len(grid[i]) < width {
+			grid[i] = append(grid[i], "")
+		}
+	}
+
+	// Header rows come from thead or, without one, from the leading rows of th cells
+	headerRows := 0
+	hasHead := false
+	for _, row := range rows {
+		hasHead = hasHead || row.head
+	}
+	for _, row := range rows {
+		if hasHead && !row.head || !hasHead && !allHeaders(row) {
+			break
+		}
+		headerRows++
+	}
+	if headerRows == len(grid) {
+		headerRows = 0
+	}
+
+	table := Table{Caption: cleanText(s.ChildrenFiltered("caption").First().Text())}
+	if headerRows > 0 {
+		table.Headers = joinHeaders(grid[:headerRows], width)
+	}
+	table.Rows = [][]string{}
+	for _, cells := range grid[headerRows:] {
+		if strings.Join(cells, "") != "" {
+			table.Rows = append(table.Rows, cells)
+		}
+	}
+	if len(table.Rows) == 0 {
+		return Table{}, false
+	}
+	return table, true
+}
+
+// expandSpans lays the cells of rows out on a grid, repeating a cell in every column and
+// row it spans.
+func expandSpans(rows []tableRow) [][]string {
+	// carried holds the cells spanning down into the next rows, by column
+	type carry struct {
+		text string
+		rows int
+	}
+	carried := map[int]*carry{}
+
+	grid := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		var cells []string
+		col := 0
+		fill := func() {
+			for c := carried[col]; c != nil && c.rows > 0; c = carried[col] {
+				cells = append(cells, c.text)
+				c.rows--
+				col++
+			}
+		}
+		for _, cell := range row.cells {
+			fill()
+			for i := 0; i < cell.colspan; i++ {
+				cells = append(cells, cell.text)
+				if cell.rowspan > 1 {
+					carried[col] = &carry{text: cell.text, rows: cell.rowspan - 1}
+				}
+				col++
+			}
+		}
+		fill()
+		// Cells spanning down past the last cell of the row, with gaps left empty
+		last := -1
+		for c, carry := range carried {
+			if c >= col && carry.rows > 0 && c > last {
+				last = c
+			}
+		}
+		for col <= last {
+			if c := carried[col]; c != nil && c.rows > 0 {
+				fill()
+				continue
+			}
+			cells = append(cells, "")
+			col++
+		}
+		grid = append(grid, cells)
+	}
+	return grid
+}
+
+// joinHeaders merges stacked header rows into one header per column. A value repeated by a
+// span is only used once.
+func joinHeaders(rows [][]string, width int) []string {
+	headers := make([]string, width)
+	for col := 0; col < width; col++ {
+		var parts []string
+		for _, row := range rows {
+			text := row[col]
+			if text != "" && (len(parts) == 0 || parts[len(parts)-1] != text) {
+				parts = append(parts, text)
+			}
+		}
+		headers[col] = strings.Join(parts, " ")
+	}
+	return headers
+}
+
+func allHeaders(row tableRow) bool {
+	for _, cell := range row.cells {
+		if !cell.header {
+			return false
+		}
+	}
+	return true
+}
+
+// span returns the colspan or rowspan attribute of cell, 1 if missing or invalid.
+func span(cell *goquery.Selection, attr string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(cell.AttrOr(attr, "")))
+	if err != nil || n < 1 {
+		return 1
+	}
+	if n > maxSpan {
+		return maxSpan
+	}
+	return n
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractTables(t *testing.T) {
+	doc := loadFixture(t, "tables.html")
+
+	tables := ExtractTables(doc)
+
+	assert.Equal(t, []Table{
+		{
+			Caption: "Population by region",
+			Headers: []string{"Region", "Country", "Population 2010", "Population 2020"},
+			Rows: [][]string{
+				{"Europe", "Germany", "80,284,000", "83,240,000"},
+				{"Europe", "France", "65,027,000", "67,391,000"},
+				{"Asia", "Japan", "n/a", "n/a"},
+			},
+		},
+		{
+			Headers: []string{"City", "Area (km²)"},
+			Rows:    [][]string{{"Berlin", "891.8"}, {"Paris", "105.4"}},
+		},
+		{
+			Rows: [][]string{{"a", "1"}, {"b", "2"}},
+		},
+	}, tables)
+}
+
+func TestExtractTablesTrailingRowspan(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<table>
+<tr><th>Name</th><th>Note</th><th>Unit</th></tr>
+<tr><td>x</td><td></td><td rowspan="3">kg</td></tr>
+<tr><td>y</td></tr>
+<tr><td>z</td><td>estimated</td></tr>
+</table>`))
+	assert.NoError(t, err)
+
+	tables := ExtractTables(doc)
+
+	assert.Len(t, tables, 1)
+	assert.Equal(t, [][]string{{"x", "", "kg"}, {"y", "", "kg"}, {"z", "estimated", "kg"}}, tables[0].Rows)
+}
+
+func TestTableToMaps(t *testing.T) {
+	table := Table{
+		Headers: []string{"Name", "", "Total", "Total"},
+		Rows:    [][]string{{"a", "b", "1", "2"}, {"c"}},
+	}
+
+	assert.Equal(t, []map[string]string{
+		{"Name": "a", "column2": "b", "Total": "1", "Total_4": "2"},
+		{"Name": "c"},
+	}, table.ToMaps())
+	assert.Nil(t, Table{Rows: [][]string{{"a"}}}.ToMaps())
+}
+
+func TestTableWriteCSV(t *testing.T) {
+	var b bytes.Buffer
+	table := Table{Headers: []string{"City", "Population"}, Rows: [][]string{{"Berlin", "3,645,000"}}}
+
+	assert.NoError(t, table.WriteCSV(&b))
+	assert.Equal(t, "City,Population\nBerlin,\"3,645,000\"\n", b.String())
+}